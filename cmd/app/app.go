@@ -3,21 +3,50 @@ package app
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth" // Load all auth plugins
+	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/jetstack/version-checker/pkg/admin"
 	"github.com/jetstack/version-checker/pkg/api"
 	"github.com/jetstack/version-checker/pkg/client"
 	"github.com/jetstack/version-checker/pkg/controller"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
+	"github.com/jetstack/version-checker/pkg/controller/nodeagent"
+	"github.com/jetstack/version-checker/pkg/controller/resource"
+	"github.com/jetstack/version-checker/pkg/controller/self"
+	"github.com/jetstack/version-checker/pkg/export"
+	"github.com/jetstack/version-checker/pkg/imageversion"
 	"github.com/jetstack/version-checker/pkg/metrics"
+	"github.com/jetstack/version-checker/pkg/results"
+	"github.com/jetstack/version-checker/pkg/version/baseimage"
+	"github.com/jetstack/version-checker/pkg/version/manifest"
+	"github.com/jetstack/version-checker/pkg/version/signature"
+	"github.com/jetstack/version-checker/pkg/webhook"
 )
 
 const (
 	helpOutput = "Kubernetes utility for exposing used image versions compared to the latest version, as metrics."
+
+	// modeController checks the images of pods from the API server.
+	modeController = "controller"
+	// modeNodeAgent checks the images on the node from its container runtime.
+	modeNodeAgent = "node-agent"
 )
 
 func NewCommand(ctx context.Context) *cobra.Command {
@@ -36,37 +65,204 @@ func NewCommand(ctx context.Context) *cobra.Command {
 					opts.LogLevel, err)
 			}
 
-			nlog := logrus.New()
-			nlog.SetOutput(os.Stdout)
-			nlog.SetLevel(logLevel)
-			log := logrus.NewEntry(nlog)
+			switch webhook.Mode(opts.webhookMode) {
+			case webhook.ModeWarn, webhook.ModeReject:
+				opts.Webhook.Mode = webhook.Mode(opts.webhookMode)
+			default:
+				return fmt.Errorf("unknown --webhook-mode %q, must be one of %q or %q",
+					opts.webhookMode, webhook.ModeWarn, webhook.ModeReject)
+			}
 
-			restConfig, err := opts.kubeConfigFlags.ToRESTConfig()
+			switch opts.Mode {
+			case modeController:
+			case modeNodeAgent:
+				if len(opts.NodeName) == 0 {
+					return fmt.Errorf("--node-name must be set with --mode=%s", modeNodeAgent)
+				}
+			default:
+				return fmt.Errorf("unknown --mode %q, must be one of %q or %q",
+					opts.Mode, modeController, modeNodeAgent)
+			}
+
+			containerStates, err := parseContainerStates(opts.CheckContainerStates)
 			if err != nil {
-				return fmt.Errorf("failed to build kubernetes rest config: %s", err)
+				return err
 			}
 
-			kubeClient, err := kubernetes.NewForConfig(restConfig)
+			if err := validatePreReleaseOrder(opts.PreReleaseOrder); err != nil {
+				return err
+			}
+
+			if err := validateAllowedRegistries(opts.AllowedRegistries); err != nil {
+				return err
+			}
+
+			if err := validateShard(opts.ShardCount, opts.ShardIndex); err != nil {
+				return err
+			}
+
+			opts.buildSuffix, err = parseBuildSuffix(opts.BuildSuffixRegex)
 			if err != nil {
-				return fmt.Errorf("failed to build kubernetes client: %s", err)
+				return err
 			}
 
-			metrics := metrics.New(log)
-			if err := metrics.Run(opts.MetricsServingAddress); err != nil {
-				return fmt.Errorf("failed to start metrics server: %s", err)
+			if err := validateReportOptions(opts); err != nil {
+				return err
+			}
+
+			defaultsConfigMap, err := parseConfigMap("defaults-configmap", opts.DefaultsConfigMap)
+			if err != nil {
+				return err
+			}
+
+			maintenanceConfigMap, err := parseConfigMap("maintenance-configmap", opts.MaintenanceConfigMap)
+			if err != nil {
+				return err
 			}
 
-			client, err := client.New(ctx, log, opts.Client)
+			deprecatedRepositoriesConfigMap, err := parseConfigMap("deprecated-repositories-configmap",
+				opts.DeprecatedRepositoriesConfigMap)
 			if err != nil {
-				return fmt.Errorf("failed to setup image registry clients: %s", err)
+				return err
+			}
+
+			remoteClusters, err := parseRemoteClusters(opts.ClusterName, opts.RemoteClusters)
+			if err != nil {
+				return err
+			}
+
+			var resourceFields []resource.Field
+			for _, f := range opts.ResourceImageFields {
+				field, err := resource.ParseField(f)
+				if err != nil {
+					return fmt.Errorf("failed to parse --resource-image-field: %s", err)
+				}
+				resourceFields = append(resourceFields, field)
+			}
+
+			nlog := logrus.New()
+			nlog.SetOutput(os.Stdout)
+			nlog.SetLevel(logLevel)
+			log := logrus.NewEntry(nlog)
+
+			metricsRegistry := prometheus.NewRegistry()
+			metricsRegistry.MustRegister(
+				collectors.NewGoCollector(),
+				collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+			)
+
+			// Draining the controllers and closing the servers share a single
+			// deadline, so the shutdown as a whole is bounded by the timeout.
+			// It starts once the context is cancelled, or on returning early.
+			shutdownCtx, startShutdown, cancelShutdown := shutdownContext(ctx, opts.ShutdownTimeout)
+			defer cancelShutdown()
+
+			metrics := metrics.New(log, metricsRegistry, metrics.Options{
+				OnlyExportOutdated: opts.OnlyExportOutdated,
+				RemovalGrace:       opts.MetricRemovalGrace,
+			})
+			if err := metrics.Run(opts.MetricsServingAddress); err != nil {
+				return fmt.Errorf("failed to start metrics server: %s", err)
 			}
 
 			defer func() {
-				if err := metrics.Shutdown(); err != nil {
+				startShutdown()
+				if err := metrics.Shutdown(shutdownCtx); err != nil {
 					log.Error(err)
 				}
 			}()
 
+			if len(opts.Webhook.ServingAddress) > 0 {
+				webhook := webhook.New(log, opts.Webhook)
+				if err := webhook.Run(); err != nil {
+					return fmt.Errorf("failed to start validating webhook server: %s", err)
+				}
+
+				defer func() {
+					startShutdown()
+					if err := webhook.Shutdown(shutdownCtx); err != nil {
+						log.Error(err)
+					}
+				}()
+			}
+
+			if len(opts.GRPCServingAddress) > 0 {
+				results := results.New(log, metrics)
+				if err := results.Run(opts.GRPCServingAddress); err != nil {
+					return fmt.Errorf("failed to start results gRPC API server: %s", err)
+				}
+
+				defer func() {
+					startShutdown()
+					if err := results.Shutdown(shutdownCtx); err != nil {
+						log.Error(err)
+					}
+				}()
+			}
+
+			client, err := newRegistryClient(ctx, log, opts, metrics)
+			if err != nil {
+				return err
+			}
+
+			var verifier *signature.Verifier
+			if len(opts.Signature.PublicKeyPath) > 0 || len(opts.Signature.FulcioRootPath) > 0 {
+				verifier, err = signature.New(log, client, opts.Signature, opts.CacheTimeout)
+				if err != nil {
+					return fmt.Errorf("failed to setup signature verification: %s", err)
+				}
+			}
+
+			var prober *manifest.Prober
+			if opts.StrictLatest {
+				prober = manifest.New(log, client, opts.CacheTimeout, metrics)
+			}
+
+			// Base images are only resolved for containers which check them, so
+			// the resolver is always available.
+			baseImageResolver := baseimage.New(log, client, opts.CacheTimeout)
+			go baseImageResolver.Run(opts.CacheTimeout / 2)
+
+			if opts.Mode == modeNodeAgent {
+				images, conn, err := nodeagent.NewImageServiceClient(opts.CRIEndpoint)
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+
+				searcher := controller.NewSearcher(controller.Options{
+					CacheTimeout:      opts.CacheTimeout,
+					SignatureVerifier: verifier,
+					ManifestProber:    prober,
+				}, client, log)
+				go searcher.Run(opts.CacheTimeout / 2)
+
+				agent := nodeagent.New(nodeagent.Options{
+					NodeName:        opts.NodeName,
+					ClusterName:     opts.ClusterName,
+					DefaultOS:       api.OS(opts.DefaultOS),
+					DefaultArch:     api.Architecture(opts.DefaultArch),
+					ExcludeArchs:    parseArchs(opts.ExcludeArchs),
+					PreReleaseOrder: opts.PreReleaseOrder,
+					BuildSuffix:     opts.buildSuffix,
+				}, metrics, images, checker.New(searcher, baseImageResolver), log)
+
+				log.Infof("checking the images of node %q from the container runtime at %q",
+					opts.NodeName, opts.CRIEndpoint)
+
+				return agent.Run(ctx, opts.CacheTimeout/2)
+			}
+
+			restConfig, err := opts.kubeConfigFlags.ToRESTConfig()
+			if err != nil {
+				return fmt.Errorf("failed to build kubernetes rest config: %s", err)
+			}
+
+			kubeClient, err := kubernetes.NewForConfig(restConfig)
+			if err != nil {
+				return fmt.Errorf("failed to build kubernetes client: %s", err)
+			}
+
 			defaultTestAllInfoMsg := fmt.Sprintf(`only containers with the annotation "%s/${my-container}=true" will be parsed`, api.EnableAnnotationKey)
 			if opts.DefaultTestAll {
 				defaultTestAllInfoMsg = fmt.Sprintf(`all containers will be tested, unless they have the annotation "%s/${my-container}=false"`, api.EnableAnnotationKey)
@@ -74,14 +270,477 @@ func NewCommand(ctx context.Context) *cobra.Command {
 
 			log.Infof("flag --test-all-containers=%t %s", opts.DefaultTestAll, defaultTestAllInfoMsg)
 
-			c := controller.New(opts.CacheTimeout, metrics,
-				client, kubeClient, log, opts.DefaultTestAll)
+			if opts.AdaptivePolling {
+				if opts.AdaptiveMinInterval <= 0 {
+					return fmt.Errorf("--adaptive-min-interval must be positive, got %s", opts.AdaptiveMinInterval)
+				}
+				if opts.AdaptiveMaxInterval < opts.AdaptiveMinInterval {
+					return fmt.Errorf("--adaptive-max-interval %s must be at least --adaptive-min-interval %s",
+						opts.AdaptiveMaxInterval, opts.AdaptiveMinInterval)
+				}
+			}
+
+			var imageProber *manifest.Prober
+			if opts.VerifyImageExistence {
+				imageProber = manifest.New(log, client, opts.CacheTimeout, nil)
+				go imageProber.Run(opts.CacheTimeout / 2)
+			}
+
+			controllerOpts := controller.Options{
+				CacheTimeout:    opts.CacheTimeout,
+				DefaultTestAll:  opts.DefaultTestAll,
+				DefaultOS:       api.OS(opts.DefaultOS),
+				DefaultArch:     api.Architecture(opts.DefaultArch),
+				ExcludeArchs:    parseArchs(opts.ExcludeArchs),
+				DefaultSeverity: api.Severity(opts.DefaultSeverity),
+				ContainerStates: containerStates,
+				MinPodAge:       opts.MinPodAge,
+				PreReleaseOrder: opts.PreReleaseOrder,
+				BuildSuffix:     opts.buildSuffix,
+
+				DefaultsConfigMap:    defaultsConfigMap,
+				MaintenanceConfigMap: maintenanceConfigMap,
+
+				DeprecatedRepositoriesConfigMap: deprecatedRepositoriesConfigMap,
+
+				DisabledContainerMetric: opts.DisabledContainerMetric,
+
+				UnparseableCurrent: controller.UnparseableCurrent(opts.UnparseableCurrent),
+
+				AllowedRegistries:         opts.AllowedRegistries,
+				BlockDisallowedRegistries: opts.BlockDisallowedRegistries,
+
+				Shard: controller.Shard{Index: opts.ShardIndex, Count: opts.ShardCount},
+
+				RequeueBackoffBase:     opts.RequeueBackoffBase,
+				RequeueBackoffMax:      opts.RequeueBackoffMax,
+				NoVersionRequeuePeriod: opts.NoVersionRequeuePeriod,
+				NoVersionBackoff:       opts.NoVersionBackoff,
+
+				NamespaceCredentialsSecret:  opts.NamespaceCredentialsSecret,
+				NamespaceCredentialsSecrets: opts.NamespaceCredentialsSecrets,
+
+				SignatureVerifier: verifier,
+				ManifestProber:    prober,
+				ImageProber:       imageProber,
+				BaseImageResolver: baseImageResolver,
+
+				ClusterName:       opts.ClusterName,
+				HealthCheckPeriod: opts.ClusterHealthCheckPeriod,
+			}
+			if opts.AdaptivePolling {
+				controllerOpts.AdaptiveMinInterval = opts.AdaptiveMinInterval
+				controllerOpts.AdaptiveMaxInterval = opts.AdaptiveMaxInterval
+			}
+
+			// Lookups are shared between clusters, resources, and the self
+			// check, so that each image is only looked up once.
+			if len(remoteClusters) > 0 || len(resourceFields) > 0 || opts.CheckSelf {
+				searcher := controller.NewSearcher(controllerOpts, client, log)
+				go searcher.Run(opts.CacheTimeout / 2)
+				controllerOpts.Searcher = searcher
+			}
+
+			controllers := controller.Group{
+				controller.New(controllerOpts, metrics, client, kubeClient, log),
+			}
+
+			for _, cluster := range remoteClusters {
+				kubeClient, err := cluster.kubeClient()
+				if err != nil {
+					return err
+				}
+
+				clusterOpts := controllerOpts
+				clusterOpts.ClusterName = cluster.name
+				controllers = append(controllers, controller.New(clusterOpts, metrics, client, kubeClient, log))
+
+				log.Infof("checking remote cluster %q", cluster.name)
+			}
+
+			if opts.EnableAdminEndpoints {
+				admin := admin.New(log, opts.Admin, controllers)
+				if err := admin.Run(); err != nil {
+					return fmt.Errorf("failed to start admin server: %s", err)
+				}
+
+				defer func() {
+					startShutdown()
+					if err := admin.Shutdown(shutdownCtx); err != nil {
+						log.Error(err)
+					}
+				}()
+			}
+
+			if len(resourceFields) > 0 {
+				dynamicClient, err := dynamic.NewForConfig(restConfig)
+				if err != nil {
+					return fmt.Errorf("failed to build kubernetes dynamic client: %s", err)
+				}
+
+				watcher := resource.New(resource.Options{
+					ClusterName:     opts.ClusterName,
+					Fields:          resourceFields,
+					DefaultOS:       api.OS(opts.DefaultOS),
+					DefaultArch:     api.Architecture(opts.DefaultArch),
+					ExcludeArchs:    parseArchs(opts.ExcludeArchs),
+					PreReleaseOrder: opts.PreReleaseOrder,
+					BuildSuffix:     opts.buildSuffix,
+				}, metrics, dynamicClient, checker.New(controllerOpts.Searcher, baseImageResolver), log)
+
+				go func() {
+					if err := watcher.Run(ctx, opts.CacheTimeout/2); err != nil {
+						log.Errorf("failed to run resource watcher: %s", err)
+					}
+				}()
+			}
+
+			if opts.CheckSelf {
+				selfCheck := self.New(self.Options{
+					ClusterName:     opts.ClusterName,
+					PodName:         opts.SelfPodName,
+					PodNamespace:    opts.SelfPodNamespace,
+					ContainerName:   opts.SelfContainerName,
+					DefaultOS:       api.OS(opts.DefaultOS),
+					DefaultArch:     api.Architecture(opts.DefaultArch),
+					ExcludeArchs:    parseArchs(opts.ExcludeArchs),
+					PreReleaseOrder: opts.PreReleaseOrder,
+					BuildSuffix:     opts.buildSuffix,
+				}, metrics, kubeClient, checker.New(controllerOpts.Searcher, baseImageResolver), log)
 
-			return c.Run(ctx, opts.CacheTimeout/2)
+				// Failing to determine the own pod only disables the self check.
+				go func() {
+					if err := selfCheck.Run(ctx, opts.CacheTimeout/2); err != nil {
+						log.Errorf("failed to run self check: %s", err)
+					}
+				}()
+			}
+
+			if len(opts.Export.Destination) > 0 {
+				if opts.ExportInterval <= 0 {
+					return fmt.Errorf("--export-interval must be positive, got %s", opts.ExportInterval)
+				}
+
+				opts.Export.Format = export.Format(opts.exportFormat)
+				opts.Export.ShardIndex, opts.Export.ShardCount = opts.ShardIndex, opts.ShardCount
+				exporter, err := export.New(ctx, log, opts.Export, metrics)
+				if err != nil {
+					return fmt.Errorf("failed to setup results export: %s", err)
+				}
+
+				// Failed exports are only logged, and never stop checks.
+				go exporter.Run(ctx, opts.ExportInterval)
+			}
+
+			if opts.PublishCRD {
+				if opts.PublishCRDInterval <= 0 {
+					return fmt.Errorf("--publish-crd-interval must be positive, got %s", opts.PublishCRDInterval)
+				}
+
+				dynamicClient, err := dynamic.NewForConfig(restConfig)
+				if err != nil {
+					return fmt.Errorf("failed to build kubernetes dynamic client: %s", err)
+				}
+
+				// Only the results of the local cluster are published, since
+				// remote namespaces may not exist locally.
+				// Each replica only garbage collects the ImageVersions of its
+				// shard.
+				shard := controller.Shard{Index: opts.ShardIndex, Count: opts.ShardCount}
+				publisher := imageversion.New(log, dynamicClient, metrics, opts.ClusterName, shard.Contains)
+				go publisher.Run(ctx, opts.PublishCRDInterval)
+			}
+
+			if len(opts.Kafka.Brokers) > 0 {
+				opts.Kafka.Events = export.KafkaEvents(opts.kafkaEvents)
+				producer, err := export.NewKafkaProducer(log, opts.Kafka, metrics)
+				if err != nil {
+					return fmt.Errorf("failed to setup Kafka producer: %s", err)
+				}
+
+				// Events are produced asynchronously, and never block checks.
+				go producer.Run(ctx)
+			}
+
+			return controllers.Run(ctx, opts.CacheTimeout/2, shutdownCtx)
 		},
 	}
 
 	opts.addFlags(cmd)
+	cmd.AddCommand(newCheckCommand(ctx))
 
 	return cmd
 }
+
+// parseContainerStates will parse the given container states to check,
+// returning an error if any are unknown.
+func parseContainerStates(states []string) ([]controller.ContainerState, error) {
+	var containerStates []controller.ContainerState
+	for _, state := range states {
+		if !slices.Contains(controller.ContainerStates, controller.ContainerState(state)) {
+			return nil, fmt.Errorf("unknown --check-container-states value %q, must be one of %v",
+				state, controller.ContainerStates)
+		}
+		containerStates = append(containerStates, controller.ContainerState(state))
+	}
+
+	return containerStates, nil
+}
+
+// newRegistryClient will return the image registry client of the given
+// options, with their image URL rewrite rules and registry headers. Metrics
+// may be nil.
+func newRegistryClient(ctx context.Context, log *logrus.Entry, opts *Options, metrics *metrics.Metrics) (*client.Client, error) {
+	for _, rule := range opts.ImageURLRewrites {
+		rewriteRule, err := client.ParseRewriteRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse --image-url-rewrite: %s", err)
+		}
+		opts.Client.RewriteRules = append(opts.Client.RewriteRules, rewriteRule)
+	}
+
+	var err error
+	opts.Client.RegistryHeaders, err = parseRegistryHeaders(opts.RegistryHeaders, opts.RegistryHeaderFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Client.Metrics = metrics
+	imageClient, err := client.New(ctx, log, opts.Client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup image registry clients: %s", err)
+	}
+
+	return imageClient, nil
+}
+
+// validateReportOptions will return an error if the default severity or the
+// unparseable current action of the given options are unknown.
+func validateReportOptions(opts *Options) error {
+	if !slices.Contains(api.Severities, api.Severity(opts.DefaultSeverity)) {
+		return fmt.Errorf("unknown --default-severity %q, must be one of %v",
+			opts.DefaultSeverity, api.Severities)
+	}
+
+	if !slices.Contains(controller.UnparseableCurrents, controller.UnparseableCurrent(opts.UnparseableCurrent)) {
+		return fmt.Errorf("unknown --unparseable-current %q, must be one of %v",
+			opts.UnparseableCurrent, controller.UnparseableCurrents)
+	}
+
+	return nil
+}
+
+// parseBuildSuffix will compile the given build suffix regex, returning nil if
+// it is empty.
+func parseBuildSuffix(regex string) (*regexp.Regexp, error) {
+	if len(regex) == 0 {
+		return nil, nil
+	}
+
+	buildSuffix, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse --build-suffix-regex %q: %s", regex, err)
+	}
+
+	return buildSuffix, nil
+}
+
+// validatePreReleaseOrder will return an error if any of the given
+// pre-release identifiers are empty, contain a dot, or are duplicated.
+func validatePreReleaseOrder(order []string) error {
+	seen := make(map[string]bool)
+	for _, identifier := range order {
+		if len(identifier) == 0 || strings.Contains(identifier, ".") {
+			return fmt.Errorf("invalid --prerelease-order identifier %q, must be a single non-empty identifier", identifier)
+		}
+		if seen[identifier] {
+			return fmt.Errorf("duplicate --prerelease-order identifier %q", identifier)
+		}
+		seen[identifier] = true
+	}
+
+	return nil
+}
+
+// validateAllowedRegistries will return an error if any of the given allowed
+// registries are empty, or are invalid wildcard patterns.
+func validateAllowedRegistries(registries []string) error {
+	for _, registry := range registries {
+		if len(registry) == 0 {
+			return fmt.Errorf("invalid --allowed-registries registry %q, must be a non-empty host", registry)
+		}
+		if _, err := path.Match(registry, ""); err != nil {
+			return fmt.Errorf("invalid --allowed-registries registry %q: %s", registry, err)
+		}
+	}
+
+	return nil
+}
+
+// validateShard will return an error if the given shard count is less than 1,
+// or the shard index is not of the shards.
+func validateShard(count, index int) error {
+	if count < 1 {
+		return fmt.Errorf("invalid --shard-count %d, must be at least 1", count)
+	}
+	if index < 0 || index >= count {
+		return fmt.Errorf("invalid --shard-index %d, must be from 0 to %d", index, count-1)
+	}
+
+	return nil
+}
+
+// parseConfigMap will parse the given ConfigMap of the named flag, of the form
+// <namespace>/<name>. No ConfigMap is returned if empty.
+func parseConfigMap(flag, configMap string) (types.NamespacedName, error) {
+	if len(configMap) == 0 {
+		return types.NamespacedName{}, nil
+	}
+
+	namespace, name, ok := strings.Cut(configMap, "/")
+	if !ok || len(namespace) == 0 || len(name) == 0 || strings.Contains(name, "/") {
+		return types.NamespacedName{}, fmt.Errorf("--%s %q must be of the form <namespace>/<name>", flag, configMap)
+	}
+
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// parseRegistryHeaders will parse the given static registry headers, of the
+// form <host>:<header>=<value>, and header files, of the form
+// <host>:<header>=<path>, whose values are read from the file.
+func parseRegistryHeaders(headers, headerFiles []string) (map[string]http.Header, error) {
+	registryHeaders := make(map[string]http.Header)
+	add := func(host, name, value string) {
+		if registryHeaders[host] == nil {
+			registryHeaders[host] = make(http.Header)
+		}
+		registryHeaders[host].Add(name, value)
+	}
+
+	for _, header := range headers {
+		host, name, value, err := parseRegistryHeader("registry-header", "value", header)
+		if err != nil {
+			return nil, err
+		}
+		add(host, name, value)
+	}
+
+	for _, headerFile := range headerFiles {
+		host, name, path, err := parseRegistryHeader("registry-header-file", "path", headerFile)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --registry-header-file %q for header %q of host %q: %s",
+				path, name, host, err)
+		}
+		add(host, name, strings.TrimSpace(string(value)))
+	}
+
+	return registryHeaders, nil
+}
+
+// parseRegistryHeader will parse a static registry header of the form
+// <host>:<header>=<value>. The value is never included in errors, since it is
+// often a secret.
+func parseRegistryHeader(flag, valueName, header string) (string, string, string, error) {
+	hostHeader, value, ok := strings.Cut(header, "=")
+	if !ok {
+		return "", "", "", fmt.Errorf("--%s must be of the form <host>:<header>=<%s>", flag, valueName)
+	}
+
+	i := strings.LastIndex(hostHeader, ":")
+	if i <= 0 || i == len(hostHeader)-1 || len(value) == 0 {
+		return "", "", "", fmt.Errorf("--%s %q must be of the form <host>:<header>=<%s>", flag, hostHeader+"=...", valueName)
+	}
+
+	return hostHeader[:i], hostHeader[i+1:], value, nil
+}
+
+// parseArchs will return the given architectures.
+func parseArchs(archs []string) []api.Architecture {
+	var parsed []api.Architecture
+	for _, arch := range archs {
+		parsed = append(parsed, api.Architecture(arch))
+	}
+
+	return parsed
+}
+
+// remoteCluster is a remote cluster to check, in addition to the local
+// cluster.
+type remoteCluster struct {
+	name       string
+	kubeconfig string
+	context    string
+}
+
+// parseRemoteClusters will parse the given remote clusters, of the form
+// <name>=<kubeconfig>[:<context>]. Cluster names must be unique, including
+// against the name of the local cluster.
+func parseRemoteClusters(localName string, clusters []string) ([]remoteCluster, error) {
+	names := map[string]bool{localName: true}
+
+	var remoteClusters []remoteCluster
+	for _, cluster := range clusters {
+		name, kubeconfig, ok := strings.Cut(cluster, "=")
+		if !ok || len(name) == 0 || len(kubeconfig) == 0 {
+			return nil, fmt.Errorf("--remote-cluster %q must be of the form <name>=<kubeconfig>[:<context>]", cluster)
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("--remote-cluster %q has a duplicate cluster name %q", cluster, name)
+		}
+		names[name] = true
+
+		kubeconfig, kubeContext, _ := strings.Cut(kubeconfig, ":")
+		remoteClusters = append(remoteClusters, remoteCluster{
+			name:       name,
+			kubeconfig: kubeconfig,
+			context:    kubeContext,
+		})
+	}
+
+	return remoteClusters, nil
+}
+
+// shutdownContext returns a context which is cancelled once the timeout has
+// passed since the shutdown was started, either by the given context being
+// cancelled or by calling start, so that every phase of the shutdown shares
+// the same deadline.
+func shutdownContext(ctx context.Context, timeout time.Duration) (context.Context, func(), context.CancelFunc) {
+	shutdownCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	var once sync.Once
+	start := func() {
+		once.Do(func() {
+			time.AfterFunc(timeout, cancel)
+		})
+	}
+	stop := context.AfterFunc(ctx, start)
+
+	return shutdownCtx, start, func() {
+		stop()
+		cancel()
+	}
+}
+
+// kubeClient will build a kubernetes client for the remote cluster.
+func (r remoteCluster) kubeClient() (kubernetes.Interface, error) {
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: r.kubeconfig},
+		&clientcmd.ConfigOverrides{CurrentContext: r.context},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kubernetes rest config for cluster %q: %s", r.name, err)
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kubernetes client for cluster %q: %s", r.name, err)
+	}
+
+	return kubeClient, nil
+}
@@ -0,0 +1,349 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestParseRemoteClusters(t *testing.T) {
+	tests := map[string]struct {
+		localName string
+		clusters  []string
+		exp       []remoteCluster
+		expErr    string
+	}{
+		"no remote clusters should return none": {},
+		"remote clusters with and without a context should be parsed": {
+			clusters: []string{
+				"workload-1=/etc/kubeconfigs/workload-1",
+				"workload-2=/etc/kubeconfigs/fleet:workload-2",
+			},
+			exp: []remoteCluster{
+				{name: "workload-1", kubeconfig: "/etc/kubeconfigs/workload-1"},
+				{name: "workload-2", kubeconfig: "/etc/kubeconfigs/fleet", context: "workload-2"},
+			},
+		},
+		"remote cluster without a kubeconfig should error": {
+			clusters: []string{"workload-1"},
+			expErr:   `--remote-cluster "workload-1" must be of the form <name>=<kubeconfig>[:<context>]`,
+		},
+		"remote cluster without a name should error": {
+			clusters: []string{"=/etc/kubeconfig"},
+			expErr:   `--remote-cluster "=/etc/kubeconfig" must be of the form <name>=<kubeconfig>[:<context>]`,
+		},
+		"duplicate remote cluster names should error": {
+			clusters: []string{"workload-1=/a", "workload-1=/b"},
+			expErr:   `--remote-cluster "workload-1=/b" has a duplicate cluster name "workload-1"`,
+		},
+		"remote cluster with the local cluster name should error": {
+			localName: "central",
+			clusters:  []string{"central=/a"},
+			expErr:    `--remote-cluster "central=/a" has a duplicate cluster name "central"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			clusters, err := parseRemoteClusters(test.localName, test.clusters)
+			if len(test.expErr) > 0 {
+				if err == nil || err.Error() != test.expErr {
+					t.Errorf("unexpected error, exp=%q got=%v", test.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(test.exp, clusters) {
+				t.Errorf("unexpected clusters, exp=%+v got=%+v", test.exp, clusters)
+			}
+		})
+	}
+}
+
+func TestValidatePreReleaseOrder(t *testing.T) {
+	tests := map[string]struct {
+		order  []string
+		expErr string
+	}{
+		"no order should be valid": {},
+		"an order of identifiers should be valid": {
+			order: []string{"dev", "alpha", "beta", "rc"},
+		},
+		"an empty identifier should error": {
+			order:  []string{"dev", ""},
+			expErr: `invalid --prerelease-order identifier "", must be a single non-empty identifier`,
+		},
+		"a dot separated identifier should error": {
+			order:  []string{"dev.1"},
+			expErr: `invalid --prerelease-order identifier "dev.1", must be a single non-empty identifier`,
+		},
+		"a duplicate identifier should error": {
+			order:  []string{"dev", "beta", "dev"},
+			expErr: `duplicate --prerelease-order identifier "dev"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validatePreReleaseOrder(test.order)
+			if len(test.expErr) > 0 {
+				if err == nil || err.Error() != test.expErr {
+					t.Errorf("unexpected error, exp=%q got=%v", test.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestValidateAllowedRegistries(t *testing.T) {
+	tests := map[string]struct {
+		registries []string
+		expErr     string
+	}{
+		"no registries should be valid": {},
+		"hosts and wildcards should be valid": {
+			registries: []string{"docker.io", "*.corp", "registry.corp:5000"},
+		},
+		"an empty registry should error": {
+			registries: []string{"docker.io", ""},
+			expErr:     `invalid --allowed-registries registry "", must be a non-empty host`,
+		},
+		"an invalid wildcard should error": {
+			registries: []string{"[corp"},
+			expErr:     `invalid --allowed-registries registry "[corp": syntax error in pattern`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateAllowedRegistries(test.registries)
+			if len(test.expErr) > 0 {
+				if err == nil || err.Error() != test.expErr {
+					t.Errorf("unexpected error, exp=%q got=%v", test.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestParseConfigMap(t *testing.T) {
+	tests := map[string]struct {
+		configMap string
+		exp       types.NamespacedName
+		expErr    string
+	}{
+		"no ConfigMap should return none": {},
+		"a namespaced ConfigMap should be parsed": {
+			configMap: "version-checker/defaults",
+			exp:       types.NamespacedName{Namespace: "version-checker", Name: "defaults"},
+		},
+		"a ConfigMap without a namespace should error": {
+			configMap: "defaults",
+			expErr:    `--defaults-configmap "defaults" must be of the form <namespace>/<name>`,
+		},
+		"a ConfigMap with an empty name should error": {
+			configMap: "version-checker/",
+			expErr:    `--defaults-configmap "version-checker/" must be of the form <namespace>/<name>`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			configMap, err := parseConfigMap("defaults-configmap", test.configMap)
+			if len(test.expErr) > 0 {
+				if err == nil || err.Error() != test.expErr {
+					t.Errorf("unexpected error, exp=%q got=%v", test.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if configMap != test.exp {
+				t.Errorf("unexpected ConfigMap, exp=%+v got=%+v", test.exp, configMap)
+			}
+		})
+	}
+}
+
+func TestParseRegistryHeaders(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "api-key")
+	if err := os.WriteFile(keyPath, []byte("file-secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		headers     []string
+		headerFiles []string
+		exp         map[string]http.Header
+		expErr      string
+	}{
+		"no headers should return none": {
+			exp: map[string]http.Header{},
+		},
+		"headers and header files should be parsed by host": {
+			headers: []string{
+				"proxy.corp:X-Api-Key=secret",
+				"proxy.corp:X-Tenant=a=b",
+				"registry.corp:5000:X-Api-Key=other",
+			},
+			headerFiles: []string{"files.corp:x-api-key=" + keyPath},
+			exp: map[string]http.Header{
+				"proxy.corp": {
+					"X-Api-Key": []string{"secret"},
+					"X-Tenant":  []string{"a=b"},
+				},
+				"registry.corp:5000": {
+					"X-Api-Key": []string{"other"},
+				},
+				"files.corp": {
+					"X-Api-Key": []string{"file-secret"},
+				},
+			},
+		},
+		"header without a value should error": {
+			headers: []string{"proxy.corp:X-Api-Key"},
+			expErr:  "--registry-header must be of the form <host>:<header>=<value>",
+		},
+		"header without a host should error without the value": {
+			headers: []string{"X-Api-Key=secret"},
+			expErr:  `--registry-header "X-Api-Key=..." must be of the form <host>:<header>=<value>`,
+		},
+		"header without a name should error without the value": {
+			headers: []string{"proxy.corp:=secret"},
+			expErr:  `--registry-header "proxy.corp:=..." must be of the form <host>:<header>=<value>`,
+		},
+		"header file which does not exist should error": {
+			headerFiles: []string{"files.corp:X-Api-Key=" + filepath.Join(dir, "missing")},
+			expErr:      "failed to read --registry-header-file",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			headers, err := parseRegistryHeaders(test.headers, test.headerFiles)
+			if len(test.expErr) > 0 {
+				if err == nil || !strings.HasPrefix(err.Error(), test.expErr) {
+					t.Errorf("unexpected error, exp=%q got=%v", test.expErr, err)
+				}
+				if err != nil && strings.Contains(err.Error(), "secret") {
+					t.Errorf("error should not contain the header value: %s", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(test.exp, headers) {
+				t.Errorf("unexpected headers, exp=%+v got=%+v", test.exp, headers)
+			}
+		})
+	}
+}
+
+func TestValidateShard(t *testing.T) {
+	tests := map[string]struct {
+		count, index int
+		expErr       string
+	}{
+		"a single shard should be valid": {
+			count: 1,
+		},
+		"the last shard should be valid": {
+			count: 4, index: 3,
+		},
+		"no shards should error": {
+			count:  0,
+			expErr: "invalid --shard-count 0, must be at least 1",
+		},
+		"an index past the last shard should error": {
+			count: 4, index: 4,
+			expErr: "invalid --shard-index 4, must be from 0 to 3",
+		},
+		"a negative index should error": {
+			count: 4, index: -1,
+			expErr: "invalid --shard-index -1, must be from 0 to 3",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := validateShard(test.count, test.index)
+			if len(test.expErr) > 0 {
+				if err == nil || err.Error() != test.expErr {
+					t.Errorf("unexpected error, exp=%q got=%v", test.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestShutdownContext(t *testing.T) {
+	const timeout = 200 * time.Millisecond
+
+	t.Run("should be done the timeout after the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		shutdownCtx, start, cancelShutdown := shutdownContext(ctx, timeout)
+		defer cancelShutdown()
+
+		select {
+		case <-shutdownCtx.Done():
+			t.Fatal("expected shutdown context to not be done before the context is cancelled")
+		case <-time.After(2 * timeout):
+		}
+
+		cancel()
+		cancelled := time.Now()
+
+		// Starting again, as each phase of the shutdown does, should not
+		// extend the deadline
+		time.Sleep(timeout / 2)
+		start()
+
+		<-shutdownCtx.Done()
+		if elapsed := time.Since(cancelled); elapsed < timeout || elapsed > timeout*3/2 {
+			t.Errorf("expected shutdown context to be done after %s, got=%s", timeout, elapsed)
+		}
+	})
+
+	t.Run("should be done the timeout after starting, without the context being cancelled", func(t *testing.T) {
+		shutdownCtx, start, cancelShutdown := shutdownContext(context.Background(), timeout)
+		defer cancelShutdown()
+
+		start()
+		started := time.Now()
+
+		<-shutdownCtx.Done()
+		if elapsed := time.Since(started); elapsed < timeout {
+			t.Errorf("expected shutdown context to be done after %s, got=%s", timeout, elapsed)
+		}
+	})
+}
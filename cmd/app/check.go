@@ -0,0 +1,331 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cliflag "k8s.io/component-base/cli/flag"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/controller"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
+	"github.com/jetstack/version-checker/pkg/controller/options"
+	"github.com/jetstack/version-checker/pkg/version/baseimage"
+	"github.com/jetstack/version-checker/pkg/version/manifest"
+	"github.com/jetstack/version-checker/pkg/version/signature"
+)
+
+const (
+	checkHelpOutput = "Check a single image against the registry once, as it would be checked " +
+		"for a container with the given annotations, and print the result."
+
+	// checkContainerName is the name of the container that annotations are
+	// given for, where the container is not named in the annotation key.
+	checkContainerName = "check"
+)
+
+// checkOptions are the options of the check subcommand.
+type checkOptions struct {
+	*Options
+
+	Image       string
+	Digest      string
+	Annotations []string
+}
+
+func newCheckCommand(ctx context.Context) *cobra.Command {
+	opts := &checkOptions{Options: new(Options)}
+
+	cmd := &cobra.Command{
+		Use:   "check --image <image> [--annotation <key>=<value>...]",
+		Short: checkHelpOutput,
+		Long:  checkHelpOutput,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			opts.complete()
+
+			if len(opts.Image) == 0 {
+				return errors.New("--image must be set")
+			}
+
+			logLevel, err := logrus.ParseLevel(opts.LogLevel)
+			if err != nil {
+				return fmt.Errorf("failed to parse --log-level %q: %s",
+					opts.LogLevel, err)
+			}
+
+			if err := validatePreReleaseOrder(opts.PreReleaseOrder); err != nil {
+				return err
+			}
+
+			opts.buildSuffix, err = parseBuildSuffix(opts.BuildSuffixRegex)
+			if err != nil {
+				return err
+			}
+
+			if err := validateReportOptions(opts.Options); err != nil {
+				return err
+			}
+
+			annotations, err := parseCheckAnnotations(checkContainerName, opts.Annotations)
+			if err != nil {
+				return err
+			}
+
+			// Logs are written to stderr, so that the result can be read from
+			// stdout.
+			nlog := logrus.New()
+			nlog.SetOutput(os.Stderr)
+			nlog.SetLevel(logLevel)
+			log := logrus.NewEntry(nlog)
+
+			// Images are checked with the same registry clients and credentials
+			// as the controller.
+			client, err := newRegistryClient(ctx, log, opts.Options, nil)
+			if err != nil {
+				return err
+			}
+
+			var verifier *signature.Verifier
+			if len(opts.Signature.PublicKeyPath) > 0 || len(opts.Signature.FulcioRootPath) > 0 {
+				verifier, err = signature.New(log, client, opts.Signature, opts.CacheTimeout)
+				if err != nil {
+					return fmt.Errorf("failed to setup signature verification: %s", err)
+				}
+			}
+
+			digest := opts.Digest
+			if len(digest) == 0 {
+				digest, err = imageDigest(ctx, client, opts.Image)
+				if err != nil {
+					return err
+				}
+			}
+
+			var prober *manifest.Prober
+			if opts.StrictLatest {
+				prober = manifest.New(log, client, opts.CacheTimeout, nil)
+			}
+
+			searcher := controller.NewSearcher(controller.Options{
+				CacheTimeout:      opts.CacheTimeout,
+				SignatureVerifier: verifier,
+				ManifestProber:    prober,
+			}, client, log)
+			checker := checker.New(searcher, baseimage.New(log, client, opts.CacheTimeout))
+
+			pod, container := checkPod(opts.Image, digest, annotations)
+
+			builder := options.New(pod.Annotations)
+			if errs := builder.Validate([]string{container.Name}); len(errs) > 0 {
+				return fmt.Errorf("invalid annotations: %s", strings.Join(errs, ", "))
+			}
+
+			checkOpts, err := builder.Options(container.Name)
+			if err != nil {
+				return fmt.Errorf("failed to build options from annotations: %s", err)
+			}
+
+			controllerOpts := &controller.Options{
+				DefaultOS:       api.OS(opts.DefaultOS),
+				DefaultArch:     api.Architecture(opts.DefaultArch),
+				ExcludeArchs:    parseArchs(opts.ExcludeArchs),
+				DefaultSeverity: api.Severity(opts.DefaultSeverity),
+				PreReleaseOrder: opts.PreReleaseOrder,
+				BuildSuffix:     opts.buildSuffix,
+			}
+			controllerOpts.ApplyDefaults(checkOpts)
+
+			result, err := checker.Container(ctx, log, pod, container, checkOpts)
+			if err != nil {
+				return fmt.Errorf("failed to check image %q: %s", opts.Image, err)
+			}
+
+			if result.IsCurrentUnparseable {
+				report, err := controller.UnparseableCurrent(opts.UnparseableCurrent).Apply(result)
+				if err != nil {
+					return fmt.Errorf("failed to check image %q: %s", opts.Image, err)
+				}
+				if !report {
+					log.Infof("skipping image where current version %q cannot be parsed", result.CurrentVersion)
+					return nil
+				}
+			}
+
+			printCheckResult(cmd.OutOrStdout(), result, checkOpts.Severity)
+
+			return nil
+		},
+	}
+
+	opts.addFlags(cmd)
+
+	return cmd
+}
+
+func (o *checkOptions) addFlags(cmd *cobra.Command) {
+	var nfs cliflag.NamedFlagSets
+
+	o.addCheckFlags(nfs.FlagSet("Check"))
+	o.addAuthFlags(nfs.FlagSet("Auth"))
+
+	addNamedFlagSets(cmd, nfs)
+}
+
+func (o *checkOptions) addCheckFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Image,
+		"image", "",
+		"The image to check, as it would be given in a container spec, e.g. "+
+			"quay.io/jetstack/version-checker:v0.2.0.")
+
+	fs.StringVar(&o.Digest,
+		"digest", "",
+		"The digest of the running image, as it would be reported in the container "+
+			"status. Resolved from the registry if empty.")
+
+	fs.StringArrayVar(&o.Annotations,
+		"annotation", []string{},
+		"An annotation of the form <key>=<value> to check the image with, e.g. "+
+			fmt.Sprintf(`"%s=true". The container name may be omitted from the `, api.UseSHAAnnotationKey)+
+			"key. May be given multiple times.")
+
+	fs.DurationVarP(&o.CacheTimeout,
+		"image-cache-timeout", "c", time.Minute*30,
+		"The time for an image version in the cache to be considered fresh.")
+
+	o.addSearchFlags(fs)
+
+	fs.StringVarP(&o.LogLevel,
+		"log-level", "v", "info",
+		"Log level (debug, info, warn, error, fatal, panic).")
+}
+
+// parseCheckAnnotations will parse the given annotations, of the form
+// <key>=<value>, for the given container. Keys which do not name a container
+// are given for the container.
+func parseCheckAnnotations(containerName string, annotations []string) (map[string]string, error) {
+	parsed := make(map[string]string)
+	for _, annotation := range annotations {
+		key, value, ok := strings.Cut(annotation, "=")
+		if !ok || len(key) == 0 {
+			return nil, fmt.Errorf("--annotation %q must be of the form <key>=<value>", annotation)
+		}
+
+		if !strings.Contains(key, "/") {
+			key = key + "/" + containerName
+		}
+
+		parsed[key] = value
+	}
+
+	return parsed, nil
+}
+
+// imageDigest will resolve the digest of the given image from the registry
+// its tags are listed from, with the credentials of the client for its host.
+func imageDigest(ctx context.Context, client *client.Client, image string) (string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse --image %q: %s", image, err)
+	}
+
+	if digest, ok := ref.(name.Digest); ok {
+		return digest.DigestStr(), nil
+	}
+
+	imageURL, tag := image, ""
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		imageURL, tag = image[:i], image[i:]
+	}
+
+	ref, err = name.ParseReference(client.ResolveImageURL(imageURL) + tag)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse --image %q: %s", image, err)
+	}
+
+	desc, err := remote.Head(ref,
+		remote.WithAuthFromKeychain(client.Keychain()),
+		remote.WithContext(ctx),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve digest of image %q, it may be given with --digest: %s", image, err)
+	}
+
+	return desc.Digest.String(), nil
+}
+
+// checkPod returns a pod with a single container running the given image and
+// digest, with the given annotations.
+func checkPod(image, digest string, annotations map[string]string) (*corev1.Pod, *corev1.Container) {
+	imageURL, _, _ := strings.Cut(image, "@")
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        checkContainerName,
+			Annotations: annotations,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  checkContainerName,
+					Image: image,
+				},
+			},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:    checkContainerName,
+					ImageID: imageURL + "@" + digest,
+				},
+			},
+		},
+	}
+
+	return pod, &pod.Spec.Containers[0]
+}
+
+// printCheckResult will print the given check result, and the severity of
+// the image being outdated.
+func printCheckResult(w io.Writer, result *checker.Result, severity api.Severity) {
+	fmt.Fprintf(w, "Image:           %s\n", result.ImageURL)
+	fmt.Fprintf(w, "Current Version: %s\n", result.CurrentVersion)
+	fmt.Fprintf(w, "Latest Version:  %s\n", result.LatestVersion)
+	fmt.Fprintf(w, "Is Latest:       %t\n", result.IsLatest)
+	fmt.Fprintf(w, "Severity:        %s\n", severity)
+	fmt.Fprintf(w, "Platform:        %s\n", platform(result))
+
+	if len(result.AbsoluteLatestVersion) > 0 {
+		fmt.Fprintf(w, "Absolute Latest: %s (is latest: %t)\n",
+			result.AbsoluteLatestVersion, result.IsAbsoluteLatest)
+	}
+
+	if result.BaseImage != nil {
+		fmt.Fprintf(w, "Base Image:      %s %s -> %s (is latest: %t)\n",
+			result.BaseImage.ImageURL, result.BaseImage.CurrentVersion,
+			result.BaseImage.LatestVersion, result.BaseImage.IsLatest)
+	}
+}
+
+// platform returns the OS/architecture of the given result, and the source of
+// it, if known.
+func platform(result *checker.Result) string {
+	if len(result.OS) == 0 && len(result.Architecture) == 0 {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("%s/%s (%s)", result.OS, result.Architecture, result.PlatformSource)
+}
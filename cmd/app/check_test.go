@@ -0,0 +1,216 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/client/selfhosted"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
+)
+
+func TestParseCheckAnnotations(t *testing.T) {
+	tests := map[string]struct {
+		annotations []string
+		exp         map[string]string
+		expErr      string
+	}{
+		"no annotations should return none": {
+			exp: map[string]string{},
+		},
+		"keys without a container should be given for the container": {
+			annotations: []string{
+				"use-metadata.version-checker.io=true",
+				"match-regex.version-checker.io=^v\\d+=\\d+$",
+			},
+			exp: map[string]string{
+				"use-metadata.version-checker.io/check": "true",
+				"match-regex.version-checker.io/check":  "^v\\d+=\\d+$",
+			},
+		},
+		"keys with a container should be kept": {
+			annotations: []string{"enable.version-checker.io/other=true"},
+			exp: map[string]string{
+				"enable.version-checker.io/other": "true",
+			},
+		},
+		"annotation without a value should error": {
+			annotations: []string{"use-sha.version-checker.io"},
+			expErr:      `--annotation "use-sha.version-checker.io" must be of the form <key>=<value>`,
+		},
+		"annotation without a key should error": {
+			annotations: []string{"=true"},
+			expErr:      `--annotation "=true" must be of the form <key>=<value>`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			annotations, err := parseCheckAnnotations("check", test.annotations)
+			if len(test.expErr) > 0 {
+				if err == nil || err.Error() != test.expErr {
+					t.Errorf("unexpected error, exp=%q got=%v", test.expErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(test.exp, annotations) {
+				t.Errorf("unexpected annotations, exp=%+v got=%+v", test.exp, annotations)
+			}
+		})
+	}
+}
+
+func TestPrintCheckResult(t *testing.T) {
+	tests := map[string]struct {
+		result   *checker.Result
+		severity api.Severity
+		exp      string
+	}{
+		"result without a platform should print unknown": {
+			result: &checker.Result{
+				ImageURL:       "quay.io/jetstack/version-checker",
+				CurrentVersion: "v0.1.0",
+				LatestVersion:  "v0.2.0",
+			},
+			severity: api.SeverityWarning,
+			exp: `Image:           quay.io/jetstack/version-checker
+Current Version: v0.1.0
+Latest Version:  v0.2.0
+Is Latest:       false
+Severity:        warning
+Platform:        unknown
+`,
+		},
+		"result with a platform, absolute latest and base image should print all": {
+			result: &checker.Result{
+				ImageURL:              "quay.io/jetstack/version-checker",
+				CurrentVersion:        "v0.2.0",
+				LatestVersion:         "v0.2.0",
+				IsLatest:              true,
+				OS:                    "linux",
+				Architecture:          "amd64",
+				PlatformSource:        checker.PlatformSourceRegistry,
+				AbsoluteLatestVersion: "v1.0.0",
+				BaseImage: &checker.Result{
+					ImageURL:       "docker.io/library/alpine",
+					CurrentVersion: "3.19",
+					LatestVersion:  "3.20",
+				},
+			},
+			severity: api.SeverityCritical,
+			exp: `Image:           quay.io/jetstack/version-checker
+Current Version: v0.2.0
+Latest Version:  v0.2.0
+Is Latest:       true
+Severity:        critical
+Platform:        linux/amd64 (registry)
+Absolute Latest: v1.0.0 (is latest: false)
+Base Image:      docker.io/library/alpine 3.19 -> 3.20 (is latest: false)
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printCheckResult(&buf, test.result, test.severity)
+			if buf.String() != test.exp {
+				t.Errorf("unexpected output, exp=%q got=%q", test.exp, buf.String())
+			}
+		})
+	}
+}
+
+func TestImageDigest(t *testing.T) {
+	reg := registry.New()
+	authorized := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorized && r.Header.Get("Authorization") != "Bearer registry-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorized = false
+	ref, err := name.ParseReference(u.Host + "/foo/bar:v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+	authorized = true
+
+	// The digest is resolved from the rewritten registry, with its token
+	imageClient, err := client.New(context.TODO(), logrus.NewEntry(logrus.New()), client.Options{
+		Selfhosted: map[string]*selfhosted.Options{
+			"registry": {Host: server.URL, Bearer: "registry-token"},
+		},
+		RewriteRules: []client.RewriteRule{
+			{Regex: regexp.MustCompile(`^example\.com/(.+)$`), Replacement: u.Host + "/$1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		image string
+		exp   string
+	}{
+		"image with a digest should return it": {
+			image: "example.com/foo/bar@sha256:" + digest.Hex,
+			exp:   digest.String(),
+		},
+		"image with a tag should be resolved from the rewritten registry": {
+			image: "example.com/foo/bar:v1.0.0",
+			exp:   digest.String(),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := imageDigest(context.TODO(), imageClient, test.image)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != test.exp {
+				t.Errorf("unexpected digest, exp=%q got=%q", test.exp, got)
+			}
+		})
+	}
+}
@@ -13,9 +13,16 @@ import (
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	cliflag "k8s.io/component-base/cli/flag"
 
+	"github.com/jetstack/version-checker/pkg/admin"
 	"github.com/jetstack/version-checker/pkg/api"
 	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
 	"github.com/jetstack/version-checker/pkg/client/selfhosted"
+	"github.com/jetstack/version-checker/pkg/controller/nodeagent"
+	"github.com/jetstack/version-checker/pkg/controller/self"
+	"github.com/jetstack/version-checker/pkg/export"
+	"github.com/jetstack/version-checker/pkg/version/signature"
+	"github.com/jetstack/version-checker/pkg/webhook"
 )
 
 const (
@@ -40,6 +47,20 @@ const (
 
 	envQuayToken = "QUAY_TOKEN"
 
+	envAdminToken = "ADMIN_TOKEN"
+
+	envKafkaUsername = "KAFKA_USERNAME"
+	envKafkaPassword = "KAFKA_PASSWORD"
+
+	envNodeName = "NODE_NAME"
+
+	envPodName      = "POD_NAME"
+	envPodNamespace = "POD_NAMESPACE"
+
+	// serviceAccountNamespacePath is the path of the namespace of the
+	// service account, which is the namespace of the pod.
+	serviceAccountNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
 	envSelfhostedPrefix    = "SELFHOSTED"
 	envSelfhostedUsername  = "USERNAME"
 	envSelfhostedPassword  = "PASSWORD"
@@ -48,6 +69,10 @@ const (
 	envSelfhostedTokenPath = "TOKEN_PATH"
 	envSelfhostedInsecure  = "INSECURE"
 	envSelfhostedCAPath    = "CA_PATH"
+
+	envSelfhostedServerName     = "SERVER_NAME"
+	envSelfhostedPageTokenPath  = "PAGE_TOKEN_PATH"
+	envSelfhostedPageTokenParam = "PAGE_TOKEN_PARAM"
 )
 
 var (
@@ -58,17 +83,102 @@ var (
 	selfhostedTokenReg    = regexp.MustCompile("^VERSION_CHECKER_SELFHOSTED_TOKEN_(.*)")
 	selfhostedCAPath      = regexp.MustCompile("^VERSION_CHECKER_SELFHOSTED_CA_PATH_(.*)")
 	selfhostedInsecureReg = regexp.MustCompile("^VERSION_CHECKER_SELFHOSTED_INSECURE_(.*)")
+
+	selfhostedServerNameReg     = regexp.MustCompile("^VERSION_CHECKER_SELFHOSTED_SERVER_NAME_(.*)")
+	selfhostedPageTokenPathReg  = regexp.MustCompile("^VERSION_CHECKER_SELFHOSTED_PAGE_TOKEN_PATH_(.*)")
+	selfhostedPageTokenParamReg = regexp.MustCompile("^VERSION_CHECKER_SELFHOSTED_PAGE_TOKEN_PARAM_(.*)")
 )
 
 // Options is a struct to hold options for the version-checker.
 type Options struct {
+	Mode                  string
 	MetricsServingAddress string
+	OnlyExportOutdated    bool
+	MetricRemovalGrace    time.Duration
+	GRPCServingAddress    string
 	DefaultTestAll        bool
 	CacheTimeout          time.Duration
+	ShutdownTimeout       time.Duration
 	LogLevel              string
+	DefaultOS             string
+	DefaultArch           string
+	DefaultSeverity       string
+	ExcludeArchs          []string
+	CheckContainerStates  []string
+	MinPodAge             time.Duration
+	ImageURLRewrites      []string
+	PreReleaseOrder       []string
+	BuildSuffixRegex      string
+	DefaultsConfigMap     string
+	MaintenanceConfigMap  string
+	ResourceImageFields   []string
+
+	DisabledContainerMetric bool
+
+	UnparseableCurrent string
+
+	DeprecatedRepositoriesConfigMap string
+
+	AllowedRegistries         []string
+	BlockDisallowedRegistries bool
+
+	ShardCount int
+	ShardIndex int
+
+	CheckSelf         bool
+	SelfPodName       string
+	SelfPodNamespace  string
+	SelfContainerName string
+
+	ClusterName              string
+	RemoteClusters           []string
+	ClusterHealthCheckPeriod time.Duration
+
+	RequeueBackoffBase     time.Duration
+	RequeueBackoffMax      time.Duration
+	NoVersionRequeuePeriod time.Duration
+	NoVersionBackoff       time.Duration
+
+	AdaptivePolling     bool
+	AdaptiveMinInterval time.Duration
+	AdaptiveMaxInterval time.Duration
+
+	NamespaceCredentialsSecret  string
+	NamespaceCredentialsSecrets map[string]string
+
+	Webhook     webhook.Options
+	webhookMode string
+
+	Signature signature.Options
+
+	StrictLatest bool
+
+	VerifyImageExistence bool
+
+	Export         export.Options
+	ExportInterval time.Duration
+	exportFormat   string
+
+	PublishCRD         bool
+	PublishCRDInterval time.Duration
+
+	Kafka       export.KafkaOptions
+	kafkaEvents string
+
+	EnableAdminEndpoints bool
+	Admin                admin.Options
+
+	NodeName    string
+	CRIEndpoint string
+
+	buildSuffix *regexp.Regexp
 
 	kubeConfigFlags *genericclioptions.ConfigFlags
 	selfhosted      selfhosted.Options
+	vault           credentials.VaultOptions
+
+	RegistryHeaders     []string
+	RegistryHeaderFiles []string
 
 	Client client.Options
 }
@@ -78,9 +188,16 @@ func (o *Options) addFlags(cmd *cobra.Command) {
 
 	o.addAppFlags(nfs.FlagSet("App"))
 	o.addAuthFlags(nfs.FlagSet("Auth"))
+	o.addNodeAgentFlags(nfs.FlagSet("Node Agent"))
 	o.kubeConfigFlags = genericclioptions.NewConfigFlags(true)
 	o.kubeConfigFlags.AddFlags(nfs.FlagSet("Kubernetes"))
 
+	addNamedFlagSets(cmd, nfs)
+}
+
+// addNamedFlagSets will add the given flag sets to the command, printing each
+// as its own section of the usage and help output.
+func addNamedFlagSets(cmd *cobra.Command, nfs cliflag.NamedFlagSets) {
 	usageFmt := "Usage:\n  %s\n"
 	cmd.SetUsageFunc(func(cmd *cobra.Command) error {
 		fmt.Fprintf(cmd.OutOrStderr(), usageFmt, cmd.UseLine())
@@ -100,10 +217,29 @@ func (o *Options) addFlags(cmd *cobra.Command) {
 }
 
 func (o *Options) addAppFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.Mode,
+		"mode", modeController,
+		fmt.Sprintf("The mode to run in (%s, %s). In %s mode the images of pods are "+
+			"checked from the API server. In %s mode, run as a DaemonSet, the images on "+
+			"the node are checked from its container runtime.",
+			modeController, modeNodeAgent, modeController, modeNodeAgent))
+
 	fs.StringVarP(&o.MetricsServingAddress,
 		"metrics-serving-address", "m", "0.0.0.0:8080",
 		"Address to serve metrics on at the /metrics path.")
 
+	fs.BoolVar(&o.OnlyExportOutdated,
+		"only-export-outdated", false,
+		"If enabled, only export the per container version metrics of containers which are "+
+			"not using the latest version, to reduce cardinality. The absence of a container's "+
+			"metrics means it is using the latest version.")
+
+	fs.DurationVar(&o.MetricRemovalGrace,
+		"metric-removal-grace", 0,
+		"How long to keep the metrics of removed containers before removing them, so "+
+			"that containers which reappear within it, such as while restarting during a "+
+			"rollout, have no gaps in their metrics. Metrics are removed immediately if 0.")
+
 	fs.BoolVarP(&o.DefaultTestAll,
 		"test-all-containers", "a", false,
 		"If enabled, all containers will be tested, unless they have the "+
@@ -114,12 +250,474 @@ func (o *Options) addAppFlags(fs *pflag.FlagSet) {
 		"The time for an image version in the cache to be considered fresh. Images "+
 			"will be rechecked after this interval.")
 
+	o.addSearchFlags(fs)
+
+	fs.StringVar(&o.DefaultsConfigMap,
+		"defaults-configmap", "",
+		"ConfigMap of default options for all containers, of the form <namespace>/<name>. "+
+			"Its keys are annotation keys without a container name, e.g. "+
+			fmt.Sprintf(`"%s", and are overridden by the annotations of containers. `, api.PinMajorAnnotationKey)+
+			"The ConfigMap is watched, and all pods are rechecked when it changes.")
+
+	fs.StringVar(&o.MaintenanceConfigMap,
+		"maintenance-configmap", "",
+		"ConfigMap of registry maintenance windows, of the form <namespace>/<name>. "+
+			`Its keys are registry hosts, or "*" for all registries, and its values are `+
+			`"true" to pause checks until changed, or a window of the form <start>/<end> in RFC 3339. `+
+			"Checks of images from paused registries are skipped, keeping their previous results, "+
+			"and all pods are rechecked once a window ends.")
+
+	fs.StringVar(&o.DeprecatedRepositoriesConfigMap,
+		"deprecated-repositories-configmap", "",
+		"ConfigMap of deprecated image repositories, of the form <namespace>/<name>. "+
+			"Its keys are the reasons repositories are deprecated, and its values are "+
+			"whitespace separated patterns of repositories, which may contain wildcards, "+
+			"e.g. docker.io/bitnami/*. Containers of images from deprecated repositories, "+
+			"regardless of their tag, are exposed with the version_checker_deprecated_repository "+
+			"metric. The ConfigMap is watched, and all pods are rechecked when it changes.")
+
+	fs.StringArrayVar(&o.ResourceImageFields,
+		"resource-image-field", []string{},
+		"Field of resources referencing an image to check, such as the image an operator "+
+			"deploys from a custom resource, of the form <group>/<version>/<resource>=<jsonpath>, "+
+			"e.g. apps.example.com/v1/sidecars={.spec.image}. The group is empty for core "+
+			"resources. Resources are watched in the local cluster, and their images exposed by "+
+			"the version_checker_resource_image_is_latest_version metric. May be given multiple times.")
+
+	fs.BoolVar(&o.CheckSelf,
+		"check-self", false,
+		"If enabled, the image of version-checker's own container will be checked, and "+
+			"exposed by the version_checker_self_is_latest_version metric.")
+
+	fs.StringVar(&o.SelfPodName,
+		"self-pod-name", "",
+		fmt.Sprintf(
+			"The name of version-checker's own pod, checked by --check-self, such as from the "+
+				"downward API (%s_%s). Defaults to the hostname.",
+			envPrefix, envPodName,
+		))
+
+	fs.StringVar(&o.SelfPodNamespace,
+		"self-pod-namespace", "",
+		fmt.Sprintf(
+			"The namespace of version-checker's own pod, checked by --check-self, such as from "+
+				"the downward API (%s_%s). Defaults to the namespace of the service account.",
+			envPrefix, envPodNamespace,
+		))
+
+	fs.StringVar(&o.SelfContainerName,
+		"self-container-name", self.DefaultContainerName,
+		"The name of version-checker's own container, checked by --check-self. The only "+
+			"container of the pod is checked if it has no container of this name.")
+
+	fs.StringSliceVar(&o.CheckContainerStates,
+		"check-container-states", []string{},
+		"Only check containers which are in one of the given states (waiting, running, "+
+			"ready, terminated). Containers of pods being deleted are terminated. All "+
+			"containers are checked if empty.")
+
+	fs.DurationVar(&o.MinPodAge,
+		"min-pod-age", 0,
+		"Only check pods which have existed for at least this duration since they were "+
+			"created, skipping short-lived pods such as of Jobs. Younger pods are checked "+
+			"once they reach this age. All pods are checked if 0.")
+
+	fs.BoolVar(&o.DisabledContainerMetric,
+		"disabled-container-metric", false,
+		"Expose containers whose version check is disabled with the "+
+			"version_checker_check_disabled metric, rather than only removing their "+
+			"version check metrics.")
+
+	fs.StringSliceVar(&o.AllowedRegistries,
+		"allowed-registries", []string{},
+		"The registry hosts images are allowed to be from, which may contain wildcards, "+
+			"e.g. docker.io,*.corp. Containers of images from other registries are exposed "+
+			"with the version_checker_disallowed_registry metric. Images without a "+
+			"registry host are docker.io. All registries are allowed if empty.")
+
+	fs.BoolVar(&o.BlockDisallowedRegistries,
+		"block-disallowed-registries", false,
+		"Don't check the versions of containers of images from registries not in "+
+			"--allowed-registries, only exposing them as disallowed.")
+
+	fs.IntVar(&o.ShardCount,
+		"shard-count", 1,
+		"The number of shards the namespaces of clusters are partitioned between by "+
+			"the hash of their name, so that the pods of large clusters may be checked "+
+			"by as many replicas, each with a different --shard-index. Pods of other "+
+			"shards are only held by their names. All namespaces are checked if 1.")
+
+	fs.IntVar(&o.ShardIndex,
+		"shard-index", 0,
+		"The index of the shard of namespaces whose pods are checked, from 0 to "+
+			"--shard-count minus 1, such as the ordinal of a StatefulSet pod.")
+
+	fs.StringToIntVar(&o.Client.RegistryConcurrency,
+		"registry-concurrency", map[string]int{},
+		"The maximum number of concurrent requests for image tags to each registry "+
+			"host, e.g. docker.io=2,harbor.corp=20. Images without a registry host are "+
+			"docker.io. Hosts not listed are limited by --default-registry-concurrency.")
+
+	fs.IntVar(&o.Client.DefaultRegistryConcurrency,
+		"default-registry-concurrency", 0,
+		"The maximum number of concurrent requests for image tags to registry hosts "+
+			"not listed in --registry-concurrency. Unlimited if 0.")
+
+	fs.IntVar(&o.Client.ManifestConcurrency,
+		"manifest-concurrency", 1,
+		"The number of manifests of the tags of an image fetched concurrently from "+
+			"selfhosted registries, to resolve the digest and platform of each tag. No "+
+			"more than the registry concurrency of the host.")
+
+	fs.StringVar(&o.ClusterName,
+		"cluster-name", "",
+		"The name of the cluster given by the kubeconfig flags, which is set as the "+
+			"cluster label of metrics.")
+
+	fs.StringArrayVar(&o.RemoteClusters,
+		"remote-cluster", []string{},
+		"A remote cluster to check, in addition to the cluster given by the kubeconfig "+
+			"flags, of the form <name>=<kubeconfig>[:<context>]. The name is set as the "+
+			"cluster label of metrics. May be given multiple times.")
+
+	fs.DurationVar(&o.ClusterHealthCheckPeriod,
+		"cluster-health-check-period", time.Second*30,
+		"How often the API server of each cluster is checked to be reachable, exposed "+
+			"as the version_checker_cluster_up metric. Disabled if 0.")
+
+	fs.DurationVar(&o.ShutdownTimeout,
+		"shutdown-timeout", time.Second*20,
+		"The time to wait for in-flight image checks to complete, and for the "+
+			"servers to close, in total, once a shutdown signal has been received.")
+
+	fs.DurationVar(&o.RequeueBackoffBase,
+		"requeue-backoff-base", time.Second,
+		"The initial backoff to requeue pods which failed to be checked with a "+
+			"transient error, such as a registry request failure. The backoff doubles "+
+			"on each consecutive failure.")
+
+	fs.DurationVar(&o.RequeueBackoffMax,
+		"requeue-backoff-max", time.Minute*5,
+		"The maximum backoff to requeue pods which failed to be checked with a "+
+			"transient error.")
+
+	fs.DurationVar(&o.NoVersionRequeuePeriod,
+		"no-version-requeue-period", time.Hour,
+		"The time to wait before checking pods again, where no version could be found "+
+			"meeting the search criteria. Pods which failed with a permanent error, such "+
+			"as invalid annotations, are not checked again until they are updated.")
+
+	fs.DurationVar(&o.NoVersionBackoff,
+		"no-version-backoff", 0,
+		"The time to wait before checking containers again, where no version could be "+
+			"found meeting the search criteria, however often their pod is synced. The "+
+			"error is logged once per backoff, and containers are checked again as soon "+
+			"as their image or options change. Disabled if 0.")
+
+	fs.BoolVar(&o.AdaptivePolling,
+		"adaptive-polling", false,
+		"Check pods again after an interval adapted to how often the results of their "+
+			"containers change, rather than after half of --image-cache-timeout. The "+
+			"interval starts at --adaptive-min-interval, doubles every 3 checks a result "+
+			"is unchanged up to --adaptive-max-interval, resets once it changes, and is "+
+			"jittered by 10%. Exposed as the version_checker_pod_check_interval_seconds "+
+			"metric.")
+
+	fs.DurationVar(&o.AdaptiveMinInterval,
+		"adaptive-min-interval", time.Minute*30,
+		"The interval to check pods again with --adaptive-polling, where a result of "+
+			"their containers recently changed. Should be at least --image-cache-timeout, "+
+			"as image tags are cached for that long.")
+
+	fs.DurationVar(&o.AdaptiveMaxInterval,
+		"adaptive-max-interval", time.Hour*6,
+		"The maximum interval to check pods again with --adaptive-polling, where the "+
+			"results of their containers have not changed for a long time.")
+
 	fs.StringVarP(&o.LogLevel,
 		"log-level", "v", "info",
 		"Log level (debug, info, warn, error, fatal, panic).")
+
+	fs.StringVar(&o.GRPCServingAddress,
+		"grpc-addr", "",
+		"Address to serve the results gRPC API on, streaming the results of checks "+
+			"as they change. Disabled if empty.")
+
+	fs.StringVar(&o.Export.Destination,
+		"export-destination", "",
+		"Destination to periodically export a snapshot of all results to, either a "+
+			"local file path, written atomically, or an object of an S3 compatible bucket "+
+			"of the form s3://<bucket>/<key>. Disabled if empty.")
+
+	fs.StringVar(&o.exportFormat,
+		"export-format", string(export.FormatJSON),
+		fmt.Sprintf("Format to export results in (%s, %s).", export.FormatJSON, export.FormatCSV))
+
+	fs.DurationVar(&o.ExportInterval,
+		"export-interval", time.Hour,
+		"How often results are exported to --export-destination.")
+
+	fs.StringVar(&o.Export.S3Endpoint,
+		"export-s3-endpoint", "",
+		"Endpoint of the S3 compatible object store to export results to, such as "+
+			"https://minio.corp. Defaults to AWS S3 of the region. Requests are signed "+
+			"with the credentials of the AWS config.")
+
+	fs.StringVar(&o.Export.S3Region,
+		"export-s3-region", "",
+		"Region of the bucket to export results to. Defaults to the region of the AWS config.")
+
+	fs.BoolVar(&o.PublishCRD,
+		"publish-crd", false,
+		"If enabled, publish the result of every checked container as an ImageVersion "+
+			"custom resource in the namespace of its pod. The ImageVersion CRD must be installed.")
+
+	fs.DurationVar(&o.PublishCRDInterval,
+		"publish-crd-interval", 5*time.Minute,
+		"How often all ImageVersions are resynced with --publish-crd, updating when containers "+
+			"were last checked, and deleting those of removed pods.")
+
+	fs.StringVar(&o.Webhook.ServingAddress,
+		"webhook-serving-address", "",
+		"Address to serve the validating admission webhook on at the /validate path. "+
+			"The webhook is disabled if empty.")
+
+	fs.StringVar(&o.Webhook.CertFile,
+		"webhook-tls-cert-file", "",
+		"Path to the TLS certificate to serve the validating admission webhook with.")
+
+	fs.StringVar(&o.Webhook.KeyFile,
+		"webhook-tls-key-file", "",
+		"Path to the TLS private key to serve the validating admission webhook with.")
+
+	fs.StringVar(&o.webhookMode,
+		"webhook-mode", string(webhook.ModeWarn),
+		fmt.Sprintf("How the validating admission webhook responds to pods with invalid "+
+			"version-checker annotations (%s, %s). In %s mode pods are admitted with warnings.",
+			webhook.ModeWarn, webhook.ModeReject, webhook.ModeWarn))
+
+	fs.StringSliceVar(&o.Kafka.Brokers,
+		"kafka-brokers", []string{},
+		"Addresses of the Kafka brokers to produce result events to, of the form "+
+			"<host>:<port>, e.g. kafka-0:9092,kafka-1:9092. Disabled if empty.")
+
+	fs.StringVar(&o.Kafka.Topic,
+		"kafka-topic", "",
+		"Kafka topic to produce result events to, keyed by container.")
+
+	fs.StringVar(&o.kafkaEvents,
+		"kafka-events", string(export.KafkaEventsChanged),
+		fmt.Sprintf("Which results are produced to Kafka (%s, %s). %s produces an event "+
+			"when the result of a container changes, and %s on every check of a container. "+
+			"Removed containers are always produced.",
+			export.KafkaEventsChanged, export.KafkaEventsChecked, export.KafkaEventsChanged, export.KafkaEventsChecked))
+
+	fs.IntVar(&o.Kafka.Buffer,
+		"kafka-buffer", 1000,
+		"Number of result events buffered while being produced to Kafka. Events are "+
+			"dropped once the buffer is full, counted by "+
+			"version_checker_kafka_events_dropped_total, rather than blocking checks.")
+
+	fs.StringVar(&o.Kafka.SASLMechanism,
+		"kafka-sasl-mechanism", "",
+		fmt.Sprintf("SASL mechanism to authenticate with the Kafka brokers (%s, %s, %s). "+
+			"Unauthenticated if empty.",
+			export.SASLMechanismPlain, export.SASLMechanismSCRAMSHA256, export.SASLMechanismSCRAMSHA512))
+
+	fs.StringVar(&o.Kafka.Username,
+		"kafka-username", "",
+		fmt.Sprintf(
+			"Username to authenticate with the Kafka brokers with --kafka-sasl-mechanism (%s_%s).",
+			envPrefix, envKafkaUsername,
+		))
+
+	fs.StringVar(&o.Kafka.Password,
+		"kafka-password", "",
+		fmt.Sprintf(
+			"Password to authenticate with the Kafka brokers with --kafka-sasl-mechanism (%s_%s).",
+			envPrefix, envKafkaPassword,
+		))
+
+	fs.BoolVar(&o.Kafka.TLS,
+		"kafka-tls", false,
+		"If enabled, connect to the Kafka brokers with TLS.")
+
+	fs.StringVar(&o.Kafka.CAPath,
+		"kafka-ca-path", "",
+		"Path to the CA certificates to verify the Kafka brokers with, with --kafka-tls. "+
+			"The system roots are used if empty.")
+
+	fs.BoolVar(&o.EnableAdminEndpoints,
+		"enable-admin-endpoints", false,
+		"If enabled, serve admin endpoints, such as POST /recheck?namespace=&pod= and "+
+			"POST /recheck/all to immediately recheck pods.")
+
+	fs.StringVar(&o.Admin.ServingAddress,
+		"admin-serving-address", "0.0.0.0:8081",
+		"Address to serve the admin endpoints on, if enabled.")
+
+	fs.StringVar(&o.Admin.Token,
+		"admin-token", "",
+		fmt.Sprintf(
+			"Bearer token which requests to the admin endpoints must give. The admin "+
+				"endpoints are unauthenticated if empty (%s_%s).",
+			envPrefix, envAdminToken,
+		))
+}
+
+func (o *Options) addNodeAgentFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.NodeName,
+		"node-name", "",
+		fmt.Sprintf(
+			"The name of the node the node agent is running on, which is set as the node "+
+				"label of metrics, such as from the downward API (%s_%s).",
+			envPrefix, envNodeName,
+		))
+
+	fs.StringVar(&o.CRIEndpoint,
+		"cri-endpoint", nodeagent.DefaultCRIEndpoint,
+		"The endpoint of the CRI socket of the node's container runtime, which the node "+
+			"agent lists images from.")
+}
+
+// addSearchFlags adds the flags which configure how the latest version of an
+// image is searched for and reported, shared by the controller and the check
+// subcommand.
+func (o *Options) addSearchFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.DefaultSeverity,
+		"default-severity", string(api.SeverityWarning),
+		fmt.Sprintf("The severity of containers being outdated (%s, %s or %s), exposed as the severity label "+
+			`of metrics, unless overridden by the annotation "%s/${my-container}".`,
+			api.SeverityCritical, api.SeverityWarning, api.SeverityInfo, api.SeverityAnnotationKey))
+
+	fs.StringVar(&o.UnparseableCurrent,
+		"unparseable-current", "skip",
+		"The action for containers whose current version cannot be parsed by the version "+
+			"scheme of their check, such as a random string (outdated, skip or error). "+
+			"outdated exposes the container as not latest, with the latest version in the "+
+			"registry, skip removes the metrics of the container, and error fails the check "+
+			"of the container until the pod is updated.")
+
+	fs.StringVar(&o.DefaultOS,
+		"default-os", "",
+		"The OS to report for images where it can not be determined from the "+
+			fmt.Sprintf(`registry, unless overridden by the annotation "%s/${my-container}".`, api.DefaultOSAnnotationKey))
+
+	fs.StringVar(&o.DefaultArch,
+		"default-arch", "",
+		"The architecture to report for images where it can not be determined from the "+
+			fmt.Sprintf(`registry, unless overridden by the annotation "%s/${my-container}".`, api.DefaultArchAnnotationKey))
+
+	fs.StringSliceVar(&o.ExcludeArchs,
+		"exclude-arch", []string{},
+		"Architectures whose images are not considered, neither to select the latest "+
+			"version, nor to report its platform, e.g. s390x,ppc64le. Tags which only have "+
+			fmt.Sprintf(`images of these architectures are skipped, unless overridden by the annotation "%s/${my-container}".`,
+				api.ExcludeArchAnnotationKey))
+
+	fs.StringSliceVar(&o.PreReleaseOrder,
+		"prerelease-order", []string{},
+		"Ordered list of pre-release identifiers, from the lowest to the highest "+
+			"precedence, which overrides their lexical comparison, e.g. "+
+			"dev,alpha,beta,rc ranks 1.0.0-dev below 1.0.0-alpha. Identifiers which are "+
+			"not listed are compared according to semver.")
+
+	fs.StringVar(&o.BuildSuffixRegex,
+		"build-suffix-regex", "",
+		"Regex matching the whole pre-release of tags which are builds of a version, "+
+			"such as re-packagings of it, rather than pre-releases, e.g. [0-9]+ for "+
+			"1.2.3-1 and 1.2.3-2. Builds are ranked above the version, by the number of "+
+			"the first capture group, or otherwise the whole match.")
+
+	fs.StringArrayVar(&o.ImageURLRewrites,
+		"image-url-rewrite", []string{},
+		"Rewrite rule of the form <regex>=<replacement>, applied to the image URL "+
+			"(without tag) before its registry is selected. The regex must match the whole "+
+			"URL, and the replacement may reference capture groups such as $1. May be given "+
+			"multiple times, where the first matching rule is used.")
+
+	fs.StringVar(&o.Client.DockerHubMirror,
+		"docker-hub-mirror", "",
+		"Host of a Docker Hub mirror, such as a pull-through cache, which the tags of "+
+			"docker.io images are fetched from instead, after any --image-url-rewrite. "+
+			"Official images, such as nginx, are fetched from library/nginx. The mirror uses "+
+			"the credentials of the selfhosted registry of its host, if configured.")
+
+	fs.StringToStringVar(&o.Client.RegistryClients,
+		"registry-client", map[string]string{},
+		"Pin the registry client used for a host, e.g. harbor.corp=selfhosted,mirror.corp=fallback. "+
+			"Clients are acr, ecr, dockerhub, gcr, ghcr, quay, fallback, or selfhosted for the "+
+			"selfhosted registry of the host. Hosts which are not pinned use the first client "+
+			"matching the host: selfhosted registries from the most specific host, then acr, ecr, "+
+			"dockerhub, gcr, ghcr, quay, and otherwise fallback.")
+
+	fs.StringVar(&o.NamespaceCredentialsSecret,
+		"namespace-credentials-secret", "",
+		"Name of a kubernetes.io/dockerconfigjson secret in the namespace of each pod, with the "+
+			"registry credentials to check its images with. Registries without credentials in the "+
+			"secret, and namespaces without the secret, use the global credentials.")
+
+	fs.StringToStringVar(&o.NamespaceCredentialsSecrets,
+		"namespace-credentials", map[string]string{},
+		"Name of the registry credentials secret of individual namespaces, e.g. "+
+			"team-a=team-a-registry, over --namespace-credentials-secret.")
+
+	fs.BoolVar(&o.Client.IncludeArtifactTags,
+		"include-artifact-tags", false,
+		"If enabled, signature, attestation, and SBOM artifact tags, such as cosign's "+
+			"sha256-<digest>.sig, will not be filtered out of the tags considered as "+
+			"versions.")
+
+	fs.BoolVar(&o.StrictLatest,
+		"strict-latest", false,
+		"If enabled, the manifest of the latest tag must be fetched from the registry, "+
+			"falling back to the next highest tag if it is not found, such as for tags "+
+			"listed by an incomplete mirror. Manifests are fetched from the registry the "+
+			"tags are listed from, with its credentials.")
+
+	fs.BoolVar(&o.VerifyImageExistence,
+		"verify-image-existence", false,
+		"If enabled, the image reference of every checked container is resolved with the "+
+			"registry, exposing images whose manifest is not found, such as of deleted "+
+			"tags, with the image missing metric. Images whose repository is not found are "+
+			"always exposed. Manifests are fetched from the registry the tags are listed "+
+			"from, with its credentials.")
+
+	fs.StringVar(&o.Signature.PublicKeyPath,
+		"signature-public-key", "",
+		"Path to a PEM encoded public key, used to verify the cosign signatures of "+
+			"image tags for containers with the require-signature annotation.")
+
+	fs.StringVar(&o.Signature.FulcioRootPath,
+		"signature-fulcio-root", "",
+		"Path to PEM encoded root certificates, used to verify the certificates of "+
+			"keyless cosign signatures of image tags for containers with the "+
+			"require-signature annotation. Requires --signature-certificate-identity, "+
+			"--signature-certificate-oidc-issuer and --signature-rekor-public-key.")
+
+	fs.StringVar(&o.Signature.CertificateIdentity,
+		"signature-certificate-identity", "",
+		"The email or URI identity which the certificates of keyless cosign signatures "+
+			"must be issued to.")
+
+	fs.StringVar(&o.Signature.CertificateOIDCIssuer,
+		"signature-certificate-oidc-issuer", "",
+		"The OIDC issuer which the certificates of keyless cosign signatures must be "+
+			"issued by, such as https://token.actions.githubusercontent.com.")
+
+	fs.StringVar(&o.Signature.RekorPublicKeyPath,
+		"signature-rekor-public-key", "",
+		"Path to the PEM encoded public key of the Rekor transparency log, used to "+
+			"verify that keyless cosign signatures were included in the log.")
 }
 
 func (o *Options) addAuthFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&o.Client.WorkloadIdentity,
+		"workload-identity", false,
+		"If enabled, credentials for azure, aws and google cloud registries will be "+
+			"resolved from the ambient cloud workload identity, when no credentials "+
+			"for that registry are given.")
+
 	/// ACR
 	fs.StringVar(&o.Client.ACR.Username,
 		"acr-username", "",
@@ -248,7 +846,8 @@ func (o *Options) addAuthFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.selfhosted.Host,
 		"selfhosted-registry-host", "",
 		fmt.Sprintf(
-			"Full host of the selfhosted registry. Include http[s] scheme (%s_%s)",
+			"Full host of the selfhosted registry. Include http[s] scheme, and any port "+
+				"and path prefix of the API, e.g. https://registry.corp:8443/v2base (%s_%s)",
 			envPrefix, envSelfhostedHost,
 		))
 	fs.StringVar(&o.selfhosted.Host,
@@ -264,6 +863,63 @@ func (o *Options) addAuthFlags(fs *pflag.FlagSet) {
 				"THIS IS NOT RECOMMENDED AND IS INTENDED FOR DEBUGGING (%s_%s)",
 			envPrefix, envSelfhostedInsecure,
 		))
+	fs.StringVar(&o.selfhosted.ServerName,
+		"selfhosted-server-name", "",
+		fmt.Sprintf(
+			"Server name to verify the selfhosted registry's certificate against, "+
+				"and send as SNI, when the registry host's address does not match "+
+				"its certificate, such as behind a shared ingress (%s_%s_%s).",
+			envPrefix, envSelfhostedPrefix, envSelfhostedServerName,
+		))
+	fs.StringVar(&o.selfhosted.PageTokenPath,
+		"selfhosted-page-token-path", "",
+		fmt.Sprintf(
+			"Dot separated path to the cursor token of the next page in the "+
+				"selfhosted registry's tags list response, for registries which "+
+				"paginate with cursor tokens rather than Link headers (%s_%s_%s).",
+			envPrefix, envSelfhostedPrefix, envSelfhostedPageTokenPath,
+		))
+	fs.StringVar(&o.selfhosted.PageTokenParam,
+		"selfhosted-page-token-param", "",
+		fmt.Sprintf(
+			"Query parameter to send the cursor token of the next page as. "+
+				"Defaults to the last element of the page token path (%s_%s_%s).",
+			envPrefix, envSelfhostedPrefix, envSelfhostedPageTokenParam,
+		))
+	fs.StringArrayVar(&o.RegistryHeaders,
+		"registry-header", []string{},
+		"Static header of the form <host>:<header>=<value>, sent on all requests to "+
+			"the selfhosted registry of the host, e.g. proxy.corp:X-Api-Key=secret. Hosts "+
+			"without a selfhosted registry are added as one over https. May be given "+
+			"multiple times.")
+	fs.StringArrayVar(&o.RegistryHeaderFiles,
+		"registry-header-file", []string{},
+		"Static header of the form <host>:<header>=<path>, as --registry-header, whose "+
+			"value is read from the file at the path, such as a mounted secret. May be "+
+			"given multiple times.")
+	///
+
+	/// Vault
+	fs.StringVar(&o.vault.Address,
+		"vault-address", "",
+		"Address of the HashiCorp Vault server to resolve registry credentials from, "+
+			"e.g. https://vault.corp:8200. Disabled if empty.")
+	fs.StringVar(&o.vault.Role,
+		"vault-role", "",
+		"Role of the Vault Kubernetes auth method to log in as, with the service "+
+			"account token of version-checker.")
+	fs.StringVar(&o.vault.AuthMount,
+		"vault-auth-mount", credentials.DefaultVaultAuthMount,
+		"Mount path of the Vault Kubernetes auth method.")
+	fs.StringVar(&o.vault.ServiceAccountTokenPath,
+		"vault-service-account-token-path", credentials.DefaultVaultServiceAccountTokenPath,
+		"Path of the service account token to log in to Vault with.")
+	fs.StringToStringVar(&o.vault.Paths,
+		"vault-registry-path", map[string]string{},
+		"Path of the Vault secret holding the username and password, or token, of "+
+			"each registry host, e.g. harbor.corp=secret/data/registries/harbor. Hosts "+
+			"must be of an acr, ecr, gcr, or selfhosted registry, and fall back to "+
+			"their static credentials if Vault fails.")
 	///
 }
 
@@ -293,6 +949,16 @@ func (o *Options) complete() {
 		{envGHCRAccessToken, &o.Client.GHCR.Token},
 
 		{envQuayToken, &o.Client.Quay.Token},
+
+		{envAdminToken, &o.Admin.Token},
+
+		{envKafkaUsername, &o.Kafka.Username},
+		{envKafkaPassword, &o.Kafka.Password},
+
+		{envNodeName, &o.NodeName},
+
+		{envPodName, &o.SelfPodName},
+		{envPodNamespace, &o.SelfPodNamespace},
 	} {
 		for _, env := range envs {
 			if o.assignEnv(env, opt.key, opt.assign) {
@@ -302,6 +968,31 @@ func (o *Options) complete() {
 	}
 
 	o.assignSelfhosted(envs)
+
+	if len(o.vault.Address) > 0 {
+		o.Client.Vault = &o.vault
+	}
+
+	if o.CheckSelf {
+		o.completeSelfPod()
+	}
+}
+
+// completeSelfPod will default the name of version-checker's own pod to the
+// hostname, and its namespace to that of the service account, if not given.
+// Either is left empty if it can not be determined.
+func (o *Options) completeSelfPod() {
+	if len(o.SelfPodName) == 0 {
+		if hostname, err := os.Hostname(); err == nil {
+			o.SelfPodName = hostname
+		}
+	}
+
+	if len(o.SelfPodNamespace) == 0 {
+		if namespace, err := os.ReadFile(serviceAccountNamespacePath); err == nil {
+			o.SelfPodNamespace = strings.TrimSpace(string(namespace))
+		}
+	}
 }
 
 func (o *Options) assignEnv(env, key string, assign *string) bool {
@@ -329,37 +1020,54 @@ func (o *Options) assignSelfhosted(envs []string) {
 		}
 	}
 
-	regexActions := map[*regexp.Regexp]func(matches []string, value string){
-		selfhostedHostReg: func(matches []string, value string) {
+	// Regexes are matched in order, so that the token path is not mistaken for
+	// a token.
+	regexActions := []struct {
+		regex  *regexp.Regexp
+		action func(matches []string, value string)
+	}{
+		{selfhostedHostReg, func(matches []string, value string) {
 			initOptions(matches[1])
 			o.Client.Selfhosted[matches[1]].Host = value
-		},
-		selfhostedUsernameReg: func(matches []string, value string) {
+		}},
+		{selfhostedUsernameReg, func(matches []string, value string) {
 			initOptions(matches[1])
 			o.Client.Selfhosted[matches[1]].Username = value
-		},
-		selfhostedPasswordReg: func(matches []string, value string) {
+		}},
+		{selfhostedPasswordReg, func(matches []string, value string) {
 			initOptions(matches[1])
 			o.Client.Selfhosted[matches[1]].Password = value
-		},
-		selfhostedTokenPath: func(matches []string, value string) {
+		}},
+		{selfhostedTokenPath, func(matches []string, value string) {
 			initOptions(matches[1])
 			o.Client.Selfhosted[matches[1]].TokenPath = value
-		},
-		selfhostedTokenReg: func(matches []string, value string) {
+		}},
+		{selfhostedTokenReg, func(matches []string, value string) {
 			initOptions(matches[1])
 			o.Client.Selfhosted[matches[1]].Bearer = value
-		},
-		selfhostedInsecureReg: func(matches []string, value string) {
+		}},
+		{selfhostedInsecureReg, func(matches []string, value string) {
 			initOptions(matches[1])
 			if val, err := strconv.ParseBool(value); err == nil {
 				o.Client.Selfhosted[matches[1]].Insecure = val
 			}
-		},
-		selfhostedCAPath: func(matches []string, value string) {
+		}},
+		{selfhostedCAPath, func(matches []string, value string) {
 			initOptions(matches[1])
 			o.Client.Selfhosted[matches[1]].CAPath = value
-		},
+		}},
+		{selfhostedServerNameReg, func(matches []string, value string) {
+			initOptions(matches[1])
+			o.Client.Selfhosted[matches[1]].ServerName = value
+		}},
+		{selfhostedPageTokenPathReg, func(matches []string, value string) {
+			initOptions(matches[1])
+			o.Client.Selfhosted[matches[1]].PageTokenPath = value
+		}},
+		{selfhostedPageTokenParamReg, func(matches []string, value string) {
+			initOptions(matches[1])
+			o.Client.Selfhosted[matches[1]].PageTokenParam = value
+		}},
 	}
 
 	for _, env := range envs {
@@ -371,9 +1079,9 @@ func (o *Options) assignSelfhosted(envs []string) {
 		key := strings.ToUpper(pair[0])
 		value := pair[1]
 
-		for regex, action := range regexActions {
-			if matches := regex.FindStringSubmatch(key); len(matches) == 2 {
-				action(matches, value)
+		for _, regexAction := range regexActions {
+			if matches := regexAction.regex.FindStringSubmatch(key); len(matches) == 2 {
+				regexAction.action(matches, value)
 				break
 			}
 		}
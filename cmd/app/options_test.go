@@ -113,6 +113,7 @@ func TestComplete(t *testing.T) {
 				{"VERSION_CHECKER_SELFHOSTED_TOKEN_BUZZ", "my-buzz-token"},
 				{"VERSION_CHECKER_SELFHOSTED_INSECURE_BUZZ", "false"},
 				{"VERSION_CHECKER_SELFHOSTED_CA_PATH_BUZZ", "/var/run/secrets/buzz/ca.crt"},
+				{"VERSION_CHECKER_SELFHOSTED_SERVER_NAME_BUZZ", "registry.buzz.jetstack.io"},
 			},
 			expOptions: client.Options{
 				ACR: acr.Options{
@@ -156,12 +157,13 @@ func TestComplete(t *testing.T) {
 						Insecure: false,
 					},
 					"BUZZ": {
-						Host:     "buzz.docker.jetstack.io",
-						Username: "buzz.davidcollom",
-						Password: "buzz-password",
-						Bearer:   "my-buzz-token",
-						Insecure: false,
-						CAPath:   "/var/run/secrets/buzz/ca.crt",
+						Host:       "buzz.docker.jetstack.io",
+						Username:   "buzz.davidcollom",
+						Password:   "buzz-password",
+						Bearer:     "my-buzz-token",
+						Insecure:   false,
+						CAPath:     "/var/run/secrets/buzz/ca.crt",
+						ServerName: "registry.buzz.jetstack.io",
 					},
 				},
 			},
@@ -188,6 +190,30 @@ func TestComplete(t *testing.T) {
 	}
 }
 
+func TestCompleteSelfPod(t *testing.T) {
+	t.Setenv("VERSION_CHECKER_POD_NAME", "version-checker-abc")
+	t.Setenv("VERSION_CHECKER_POD_NAMESPACE", "monitoring")
+
+	o := &Options{CheckSelf: true}
+	o.complete()
+	if o.SelfPodName != "version-checker-abc" || o.SelfPodNamespace != "monitoring" {
+		t.Errorf("unexpected self pod from envs, got=%s/%s", o.SelfPodNamespace, o.SelfPodName)
+	}
+
+	// The pod name should default to the hostname
+	t.Setenv("VERSION_CHECKER_POD_NAME", "")
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o = &Options{CheckSelf: true}
+	o.complete()
+	if o.SelfPodName != hostname {
+		t.Errorf("unexpected self pod name, exp=%s got=%s", hostname, o.SelfPodName)
+	}
+}
+
 func TestAssignSelfhosted(t *testing.T) {
 	tests := map[string]struct {
 		envs       []string
@@ -267,6 +293,22 @@ func TestAssignSelfhosted(t *testing.T) {
 				},
 			},
 		},
+		"allow cursor token pagination": {
+			envs: []string{
+				"VERSION_CHECKER_SELFHOSTED_HOST_FOO=docker.joshvanl.com",
+				"VERSION_CHECKER_SELFHOSTED_PAGE_TOKEN_PATH_FOO=pagination.next_page",
+				"VERSION_CHECKER_SELFHOSTED_PAGE_TOKEN_PARAM_FOO=page",
+			},
+			expOptions: client.Options{
+				Selfhosted: map[string]*selfhosted.Options{
+					"FOO": {
+						Host:           "docker.joshvanl.com",
+						PageTokenPath:  "pagination.next_page",
+						PageTokenParam: "page",
+					},
+				},
+			},
+		},
 		"ignore keys with no values": {
 			envs: []string{
 				"VERSION_CHECKER_SELFHOSTED_HOST_FOO=docker.joshvanl.com",
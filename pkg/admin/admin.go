@@ -0,0 +1,166 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Options are the options for the admin server.
+type Options struct {
+	// ServingAddress is the address to serve the admin endpoints on.
+	ServingAddress string
+
+	// Token, if set, must be given as a bearer token by all requests.
+	Token string
+}
+
+// Rechecker requeues pods to be checked immediately.
+type Rechecker interface {
+	// Recheck will requeue the pods matching the given namespace and name,
+	// where empty matches all, returning the number of pods enqueued.
+	Recheck(namespace, name string) (int, error)
+}
+
+// Admin is a server of operational endpoints, such as forcing an immediate
+// recheck of pods.
+type Admin struct {
+	*http.Server
+
+	log       *logrus.Entry
+	opts      Options
+	rechecker Rechecker
+}
+
+// recheckResponse is the response of the recheck endpoints.
+type recheckResponse struct {
+	Enqueued int `json:"enqueued"`
+}
+
+func New(log *logrus.Entry, opts Options, rechecker Rechecker) *Admin {
+	return &Admin{
+		log:       log.WithField("module", "admin"),
+		opts:      opts,
+		rechecker: rechecker,
+	}
+}
+
+// Run will run the admin server.
+func (a *Admin) Run() error {
+	ln, err := net.Listen("tcp", a.opts.ServingAddress)
+	if err != nil {
+		return err
+	}
+
+	a.Server = &http.Server{
+		Addr:           ln.Addr().String(),
+		ReadTimeout:    8 * time.Second,
+		WriteTimeout:   8 * time.Second,
+		MaxHeaderBytes: 1 << 15, // 1 MiB
+		Handler:        a.Handler(),
+	}
+
+	go func() {
+		a.log.Infof("serving admin endpoints on %s", ln.Addr())
+		if len(a.opts.Token) == 0 {
+			a.log.Warn("admin endpoints are served without authentication, set --admin-token to require a token")
+		}
+
+		if err := a.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.log.Errorf("failed to serve admin endpoints: %s", err)
+			return
+		}
+	}()
+
+	return nil
+}
+
+// Handler returns the HTTP handler of the admin server.
+func (a *Admin) Handler() http.Handler {
+	router := http.NewServeMux()
+	router.Handle("/recheck", a.authenticate(http.HandlerFunc(a.recheckHandler)))
+	router.Handle("/recheck/all", a.authenticate(http.HandlerFunc(a.recheckAllHandler)))
+	return router
+}
+
+// Shutdown will gracefully stop the admin server, waiting for active
+// connections until the given context is done.
+func (a *Admin) Shutdown(ctx context.Context) error {
+	// If admin server is not started than exit early
+	if a.Server == nil {
+		return nil
+	}
+
+	a.log.Info("shutting down admin server...")
+
+	if err := a.Server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("admin server shutdown failed: %s", err)
+	}
+
+	a.log.Info("admin server gracefully stopped")
+
+	return nil
+}
+
+// authenticate will reject requests which do not give the configured bearer
+// token.
+func (a *Admin) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if len(a.opts.Token) > 0 {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(a.opts.Token)) != 1 {
+				http.Error(rw, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+func (a *Admin) recheckHandler(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	pod := r.URL.Query().Get("pod")
+
+	if len(namespace) == 0 {
+		http.Error(rw, `"namespace" must be given, use /recheck/all to recheck all pods`, http.StatusBadRequest)
+		return
+	}
+
+	a.recheck(rw, namespace, pod)
+}
+
+func (a *Admin) recheckAllHandler(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	a.recheck(rw, "", "")
+}
+
+func (a *Admin) recheck(rw http.ResponseWriter, namespace, pod string) {
+	enqueued, err := a.rechecker.Recheck(namespace, pod)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("failed to recheck pods: %s", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(recheckResponse{Enqueued: enqueued}); err != nil {
+		a.log.Errorf("failed to send recheck response: %s", err)
+	}
+}
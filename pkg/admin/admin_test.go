@@ -0,0 +1,133 @@
+package admin
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRechecker struct {
+	mu    sync.Mutex
+	calls [][2]string
+	err   error
+}
+
+func (f *fakeRechecker) Recheck(namespace, name string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, [2]string{namespace, name})
+	if f.err != nil {
+		return 0, f.err
+	}
+	return 3, nil
+}
+
+func TestHandler(t *testing.T) {
+	tests := map[string]struct {
+		method     string
+		target     string
+		token      string
+		err        error
+		expCode    int
+		expBody    string
+		expRecheck [][2]string
+	}{
+		"recheck a pod should return the number enqueued": {
+			method:     http.MethodPost,
+			target:     "/recheck?namespace=default&pod=my-pod",
+			token:      "secret",
+			expCode:    http.StatusOK,
+			expBody:    "{\"enqueued\":3}\n",
+			expRecheck: [][2]string{{"default", "my-pod"}},
+		},
+		"recheck a namespace should return the number enqueued": {
+			method:     http.MethodPost,
+			target:     "/recheck?namespace=default",
+			token:      "secret",
+			expCode:    http.StatusOK,
+			expBody:    "{\"enqueued\":3}\n",
+			expRecheck: [][2]string{{"default", ""}},
+		},
+		"recheck all should recheck all pods": {
+			method:     http.MethodPost,
+			target:     "/recheck/all",
+			token:      "secret",
+			expCode:    http.StatusOK,
+			expBody:    "{\"enqueued\":3}\n",
+			expRecheck: [][2]string{{"", ""}},
+		},
+		"recheck without a namespace should error": {
+			method:  http.MethodPost,
+			target:  "/recheck?pod=my-pod",
+			token:   "secret",
+			expCode: http.StatusBadRequest,
+			expBody: "\"namespace\" must be given, use /recheck/all to recheck all pods\n",
+		},
+		"recheck with GET should not be allowed": {
+			method:  http.MethodGet,
+			target:  "/recheck/all",
+			token:   "secret",
+			expCode: http.StatusMethodNotAllowed,
+			expBody: "method not allowed\n",
+		},
+		"recheck with the wrong token should be unauthorized": {
+			method:  http.MethodPost,
+			target:  "/recheck/all",
+			token:   "wrong",
+			expCode: http.StatusUnauthorized,
+			expBody: "unauthorized\n",
+		},
+		"recheck without a token should be unauthorized": {
+			method:  http.MethodPost,
+			target:  "/recheck/all",
+			expCode: http.StatusUnauthorized,
+			expBody: "unauthorized\n",
+		},
+		"recheck which fails should error": {
+			method:     http.MethodPost,
+			target:     "/recheck/all",
+			token:      "secret",
+			err:        errors.New("pod informer has not yet synced"),
+			expCode:    http.StatusServiceUnavailable,
+			expBody:    "failed to recheck pods: pod informer has not yet synced\n",
+			expRecheck: [][2]string{{"", ""}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rechecker := &fakeRechecker{err: test.err}
+			a := New(logrus.NewEntry(logrus.New()), Options{Token: "secret"}, rechecker)
+
+			req := httptest.NewRequest(test.method, test.target, nil)
+			if len(test.token) > 0 {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			rec := httptest.NewRecorder()
+			a.Handler().ServeHTTP(rec, req)
+
+			body, err := io.ReadAll(rec.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expCode, rec.Code)
+			assert.Equal(t, test.expBody, string(body))
+			assert.Equal(t, test.expRecheck, rechecker.calls)
+		})
+	}
+}
+
+func TestHandlerWithoutToken(t *testing.T) {
+	rechecker := new(fakeRechecker)
+	a := New(logrus.NewEntry(logrus.New()), Options{}, rechecker)
+
+	rec := httptest.NewRecorder()
+	a.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/recheck/all", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, rechecker.calls, 1)
+}
@@ -24,6 +24,11 @@ const (
 	// set. All other options are ignored when this is set.
 	MatchRegexAnnotationKey = "match-regex.version-checker.io"
 
+	// MatchGlobAnnotationKey will enforce that tags that are looked up must
+	// match this shell style glob pattern, e.g. "v1.*". Cannot be used with
+	// MatchRegexAnnotationKey.
+	MatchGlobAnnotationKey = "match-glob.version-checker.io"
+
 	// UseMetaDataAnnotationKey is defined as a tag containing anything after the
 	// patch digit.
 	// e.g. v1.0.1-gke.3 v1.0.1-alpha.0, v1.2.3.4...
@@ -37,6 +42,100 @@ const (
 
 	// PinPatchAnnotationKey will pin the patch version to check.
 	PinPatchAnnotationKey = "pin-patch.version-checker.io"
+
+	// DefaultOSAnnotationKey is used to set the OS reported for the container
+	// when it can not be determined from the registry.
+	DefaultOSAnnotationKey = "default-os.version-checker.io"
+
+	// DefaultArchAnnotationKey is used to set the architecture reported for the
+	// container when it can not be determined from the registry.
+	DefaultArchAnnotationKey = "default-arch.version-checker.io"
+
+	// ExcludeArchAnnotationKey is used to set a comma separated list of
+	// architectures, whose images are not considered. Tags which only publish
+	// images for these architectures are skipped.
+	ExcludeArchAnnotationKey = "exclude-arch.version-checker.io"
+
+	// VersionSchemeAnnotationKey is used to set the scheme used to compare
+	// image tags. Defaults to semver.
+	VersionSchemeAnnotationKey = "version-scheme.version-checker.io"
+
+	// DateLayoutAnnotationKey is used to set the Go time layout of the leading
+	// date of tags, when using the date-sha version scheme. Defaults to
+	// YYYYMMDD[HHMMSS].
+	DateLayoutAnnotationKey = "date-layout.version-checker.io"
+
+	// RequireSignatureAnnotationKey is used to only consider image tags with a
+	// valid cosign signature.
+	RequireSignatureAnnotationKey = "require-signature.version-checker.io"
+
+	// MaxVersionAnnotationKey is used to set a ceiling on the latest version
+	// of the image. Tags above this version are not considered the latest,
+	// though the absolute latest is still reported.
+	MaxVersionAnnotationKey = "max-version.version-checker.io"
+
+	// CheckBaseImageAnnotationKey is used to also check the base image
+	// declared by the org.opencontainers.image.base.name label of the
+	// container image, reporting whether it is the latest.
+	CheckBaseImageAnnotationKey = "check-base-image.version-checker.io"
+
+	// SeverityAnnotationKey is used to set the severity of the container
+	// being outdated, which is exposed as a metric label so that alerts can be
+	// routed by it.
+	SeverityAnnotationKey = "severity.version-checker.io"
+
+	// ExtraImagesAnnotationKey is used to set a comma separated list of image
+	// references the pod launches at runtime, which are not in its spec, to
+	// also be checked. Set on the pod without a container name.
+	ExtraImagesAnnotationKey = "extra-images.version-checker.io"
+)
+
+// Severity is the severity of a container being outdated.
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityWarning  Severity = "warning"
+	SeverityInfo     Severity = "info"
+)
+
+// Severities are all of the severities which can be set.
+var Severities = []Severity{
+	SeverityCritical,
+	SeverityWarning,
+	SeverityInfo,
+}
+
+// ArtifactType is the type of the artifact of a tag, given by the media type
+// of the config of its manifest.
+type ArtifactType string
+
+const (
+	// ArtifactTypeImage is a container image. Tags are images unless their
+	// registry reports otherwise.
+	ArtifactTypeImage ArtifactType = "image"
+	// ArtifactTypeHelmChart is a Helm chart, which has no platform.
+	ArtifactTypeHelmChart ArtifactType = "helm-chart"
+	// ArtifactTypeWasm is a WebAssembly module, which has no platform.
+	ArtifactTypeWasm ArtifactType = "wasm"
+	// ArtifactTypeOther is any other OCI artifact, which has no platform.
+	ArtifactTypeOther ArtifactType = "other"
+)
+
+// VersionScheme is the scheme used to compare image tags.
+type VersionScheme string
+
+const (
+	// VersionSchemeSemver compares tags as semantic versions.
+	VersionSchemeSemver VersionScheme = "semver"
+
+	// VersionSchemeDateSHA compares tags by a leading date, ignoring any suffix
+	// such as a commit SHA, e.g. 20240312-a1b2c3d.
+	VersionSchemeDateSHA VersionScheme = "date-sha"
+
+	// VersionSchemeEpoch compares tags by an optional leading epoch, then as
+	// semantic versions, e.g. 2_1.4.0. Tags without an epoch are of epoch 0.
+	VersionSchemeEpoch VersionScheme = "epoch"
 )
 
 // Options is used to describe what restrictions should be used for determining
@@ -48,6 +147,7 @@ type Options struct {
 	UseSHA bool `json:"use-sha,omitempty"`
 
 	MatchRegex *string `json:"match-regex,omitempty"`
+	MatchGlob  *string `json:"match-glob,omitempty"`
 
 	// UseMetaData defines whether tags with '-alpha', '-debian.0' etc. is
 	// permissible.
@@ -57,9 +157,61 @@ type Options struct {
 	PinMinor *int64 `json:"pin-minor,omitempty"`
 	PinPatch *int64 `json:"pin-patch,omitempty"`
 
+	// MaxVersion is the highest version which may be considered the latest.
+	MaxVersion *string `json:"max-version,omitempty"`
+
+	// DefaultOS and DefaultArch are reported when the registry does not
+	// report the platform of the image.
+	DefaultOS   OS           `json:"default-os,omitempty"`
+	DefaultArch Architecture `json:"default-arch,omitempty"`
+
+	// ExcludeArchs are the architectures whose images are not considered,
+	// neither to select the latest tag, nor to report its platform.
+	ExcludeArchs []Architecture `json:"exclude-archs,omitempty"`
+
+	// VersionScheme is the scheme used to compare tags, defaulting to semver.
+	// DateLayout is only used with the date-sha scheme.
+	VersionScheme VersionScheme `json:"version-scheme,omitempty"`
+	DateLayout    string        `json:"date-layout,omitempty"`
+
+	// RequireSignature will skip tags without a valid signature.
+	RequireSignature bool `json:"require-signature,omitempty"`
+
+	// PreReleaseOrder is an ordered list of pre-release identifiers, from the
+	// lowest to the highest precedence, overriding their lexical comparison.
+	PreReleaseOrder []string `json:"pre-release-order,omitempty"`
+
+	// BuildSuffix matches the pre-releases which are builds of a version,
+	// such as the 2 of 1.2.3-2, ranked above the version rather than below.
+	BuildSuffix *regexp.Regexp `json:"-"`
+
+	// CheckBaseImage will also check the base image declared by the labels of
+	// the image. It does not affect the search of the image itself.
+	CheckBaseImage bool `json:"-"`
+
+	// Severity is the severity of the container being outdated. It does not
+	// affect the search.
+	Severity Severity `json:"-"`
+
+	// Credentials, if set, are the credentials of the namespace of the
+	// container for the registry of the image, used over any global
+	// credentials.
+	Credentials *RegistryCredentials `json:"credentials,omitempty"`
+
 	RegexMatcher *regexp.Regexp `json:"-"`
 }
 
+// RegistryCredentials are the credentials to list the tags of an image with.
+type RegistryCredentials struct {
+	// Source identifies where the credentials are from, such as the
+	// namespace/name of their secret, so that searches with different
+	// credentials are cached separately.
+	Source string `json:"source"`
+
+	Username string `json:"-"`
+	Password string `json:"-"`
+}
+
 // ImageTag describes a container image tag.
 type ImageTag struct {
 	Tag          string       `json:"tag"`
@@ -67,6 +219,14 @@ type ImageTag struct {
 	Timestamp    time.Time    `json:"timestamp"`
 	OS           OS           `json:"os,omitempty"`
 	Architecture Architecture `json:"architecture,omitempty"`
+
+	// ArtifactType is the type of the artifact of the tag, if reported by the
+	// registry.
+	ArtifactType ArtifactType `json:"artifact_type,omitempty"`
+
+	// ManifestError is the error the manifest of the tag failed to be fetched
+	// with, if any, in which case the tag is only known by its name.
+	ManifestError string `json:"manifest_error,omitempty"`
 }
 
 type OS string
@@ -15,6 +15,7 @@ import (
 	jwt "github.com/golang-jwt/jwt/v5"
 
 	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
 	"github.com/jetstack/version-checker/pkg/client/util"
 )
 
@@ -26,6 +27,8 @@ type Client struct {
 	*http.Client
 	Options
 
+	credentials *credentials.Resolver
+
 	cacheMu         sync.Mutex
 	cachedACRClient map[string]*acrClient
 }
@@ -53,7 +56,7 @@ type ManifestResponse struct {
 	} `json:"manifests"`
 }
 
-func New(opts Options) (*Client, error) {
+func New(opts Options, creds *credentials.Resolver) (*Client, error) {
 	client := &http.Client{
 		Timeout: time.Second * 5,
 	}
@@ -66,6 +69,7 @@ func New(opts Options) (*Client, error) {
 	return &Client{
 		Options:         opts,
 		Client:          client,
+		credentials:     creds,
 		cachedACRClient: make(map[string]*acrClient),
 	}, nil
 }
@@ -140,34 +144,49 @@ func (c *Client) getManifestsWithClient(ctx context.Context, client *acrClient,
 		return nil, err
 	}
 
-	if resp.StatusCode != 200 {
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, fmt.Errorf("bad request for image host %s", host)
+			return nil, util.NewStatusError(resp.StatusCode, fmt.Errorf("bad request for image host %s", host))
 		}
-		return nil, fmt.Errorf("bad request for image host %s: %s", host, body)
+		return nil, util.NewStatusError(resp.StatusCode, fmt.Errorf("bad request for image host %s: %s", host, body))
 	}
 
 	return resp, nil
 }
 
+// getACRClient returns the client of the given host, cached until its token
+// expires. Credentials set by credentials.WithOverride are used by a client
+// which is not cached.
 func (c *Client) getACRClient(ctx context.Context, host string) (*acrClient, error) {
+	if creds, ok := credentials.Override(ctx); ok {
+		if len(creds.Token) > 0 {
+			return c.getAccessTokenClient(ctx, host, creds)
+		}
+		return c.getBasicAuthClient(host, creds)
+	}
+
 	c.cacheMu.Lock()
 	defer c.cacheMu.Unlock()
 
-	if client, ok := c.cachedACRClient[host]; ok && time.Now().After(client.tokenExpiry) {
+	if client, ok := c.cachedACRClient[host]; ok && time.Now().Before(client.tokenExpiry) {
 		return client, nil
 	}
 
-	var (
-		client *acrClient
-		err    error
-	)
+	creds, err := c.credentials.Credentials(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if creds == nil {
+		creds = new(credentials.Credentials)
+	}
 
-	if len(c.RefreshToken) > 0 {
-		client, err = c.getAccessTokenClient(ctx, host)
+	var client *acrClient
+	if len(creds.Token) > 0 {
+		client, err = c.getAccessTokenClient(ctx, host, creds)
 	} else {
-		client, err = c.getBasicAuthClient(host)
+		client, err = c.getBasicAuthClient(host, creds)
 	}
 	if err != nil {
 		return nil, err
@@ -178,17 +197,22 @@ func (c *Client) getACRClient(ctx context.Context, host string) (*acrClient, err
 	return client, nil
 }
 
-func (c *Client) getBasicAuthClient(_ string) (*acrClient, error) {
+func (c *Client) getBasicAuthClient(_ string, creds *credentials.Credentials) (*acrClient, error) {
 	client := autorest.NewClientWithUserAgent(userAgent)
-	client.Authorizer = autorest.NewBasicAuthorizer(c.Username, c.Password)
+	client.Authorizer = autorest.NewBasicAuthorizer(creds.Username, creds.Password)
+
+	tokenExpiry := creds.Expiry
+	if tokenExpiry.IsZero() {
+		tokenExpiry = time.Unix(1<<63-1, 0)
+	}
 
 	return &acrClient{
 		Client:      &client,
-		tokenExpiry: time.Unix(1<<63-1, 0),
+		tokenExpiry: tokenExpiry,
 	}, nil
 }
 
-func (c *Client) getAccessTokenClient(ctx context.Context, host string) (*acrClient, error) {
+func (c *Client) getAccessTokenClient(ctx context.Context, host string, creds *credentials.Credentials) (*acrClient, error) {
 	client := autorest.NewClientWithUserAgent(userAgent)
 	urlParameters := map[string]interface{}{
 		"url": "https://" + host,
@@ -196,7 +220,7 @@ func (c *Client) getAccessTokenClient(ctx context.Context, host string) (*acrCli
 
 	formDataParameters := map[string]interface{}{
 		"grant_type":    "refresh_token",
-		"refresh_token": c.RefreshToken,
+		"refresh_token": creds.Token,
 		"scope":         "repository:*:*",
 		"service":       host,
 	}
@@ -219,6 +243,12 @@ func (c *Client) getAccessTokenClient(ctx context.Context, host string) (*acrCli
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, util.NewStatusError(resp.StatusCode, fmt.Errorf("%s: failed to request access token: unexpected status code %d: %s",
+			host, resp.StatusCode, body))
+	}
+
 	var respToken AccessTokenResponse
 	if err := json.NewDecoder(resp.Body).Decode(&respToken); err != nil {
 		return nil, fmt.Errorf("%s: failed to decode access token response: %s",
@@ -231,7 +261,7 @@ func (c *Client) getAccessTokenClient(ctx context.Context, host string) (*acrCli
 	}
 
 	token := &adal.Token{
-		RefreshToken: c.RefreshToken,
+		RefreshToken: creds.Token,
 		AccessToken:  respToken.AccessToken,
 	}
 
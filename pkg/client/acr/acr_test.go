@@ -0,0 +1,60 @@
+package acr
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jetstack/version-checker/pkg/client/util"
+)
+
+func TestGetManifestsStatusErrors(t *testing.T) {
+	tests := map[string]struct {
+		statusCode    int
+		expNotFound   bool
+		expAuthDenied bool
+	}{
+		"a repository which does not exist should be not found": {
+			statusCode:  http.StatusNotFound,
+			expNotFound: true,
+		},
+		"an unauthorized repository should be denied": {
+			statusCode:    http.StatusUnauthorized,
+			expAuthDenied: true,
+		},
+		"a forbidden repository should be denied": {
+			statusCode:    http.StatusForbidden,
+			expAuthDenied: true,
+		},
+		"a server error should be neither": {
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(test.statusCode)
+				_, _ = w.Write([]byte(`{"errors": "httperror"}`))
+			}))
+			defer server.Close()
+
+			u, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			client := &acrClient{Client: &autorest.Client{Sender: server.Client()}}
+
+			_, err = new(Client).getManifestsWithClient(context.TODO(), client, u.Host, "jetstack", "version-checker")
+			assert.EqualError(t, err, fmt.Sprintf(`bad request for image host %s: {"errors": "httperror"}`, u.Host))
+			assert.Equal(t, test.expNotFound, util.IsNotFound(err))
+			assert.Equal(t, test.expAuthDenied, util.IsAuthDenied(err))
+		})
+	}
+}
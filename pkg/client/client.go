@@ -2,20 +2,29 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/jetstack/version-checker/pkg/api"
 	"github.com/jetstack/version-checker/pkg/client/acr"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
 	"github.com/jetstack/version-checker/pkg/client/docker"
 	"github.com/jetstack/version-checker/pkg/client/ecr"
 	"github.com/jetstack/version-checker/pkg/client/fallback"
 	"github.com/jetstack/version-checker/pkg/client/gcr"
 	"github.com/jetstack/version-checker/pkg/client/ghcr"
+	"github.com/jetstack/version-checker/pkg/client/oci"
 	"github.com/jetstack/version-checker/pkg/client/quay"
 	"github.com/jetstack/version-checker/pkg/client/selfhosted"
+	"github.com/jetstack/version-checker/pkg/metrics"
 )
 
 // ImageClient represents a image registry client that can list available tags
@@ -37,11 +46,66 @@ type ImageClient interface {
 	Tags(ctx context.Context, host, repo, image string) ([]api.ImageTag, error)
 }
 
+// usesOverrideCredentials returns true if the given registry client lists
+// tags with the credentials set by credentials.WithOverride, being the
+// clients which authenticate with a username and password.
+func usesOverrideCredentials(client ImageClient) bool {
+	switch client.(type) {
+	case *acr.Client, *docker.Client, *fallback.Client, *gcr.Client, *selfhosted.Client:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestLimitedClient is an ImageClient which limits each of its own
+// requests to the registry host, such as the manifest requests of each tag,
+// so is not limited around the whole of Tags.
+type requestLimitedClient interface {
+	LimitsRequests() bool
+}
+
 // Client is a container image registry client to list tags of given image
 // URLs.
 type Client struct {
+	log *logrus.Entry
+
 	clients        []ImageClient
 	fallbackClient ImageClient
+	ociClient      *oci.Client
+	pinnedClients  map[string]ImageClient
+	rewriteRules   []RewriteRule
+	limiter        *hostLimiter
+	credentials    *credentials.Resolver
+
+	dockerHubMirror string
+
+	includeArtifactTags bool
+}
+
+const (
+	// selfhostedClientName is the client name which pins a host to the
+	// selfhosted registry of the host.
+	selfhostedClientName = "selfhosted"
+)
+
+var (
+	// artifactTagRegex matches the tags of OCI referrer artifacts, such as
+	// signatures and attestations, which follow the sha256-<digest>.<type>
+	// convention, including the OCI 1.1 referrers tag schema fallback.
+	artifactTagRegex = regexp.MustCompile(`^sha(256|512)-[a-fA-F0-9]+(\.[a-zA-Z0-9_-]+)?$`)
+
+	// artifactTagSuffixes are the tag suffixes used by cosign for signature,
+	// attestation, and SBOM artifacts.
+	artifactTagSuffixes = []string{".sig", ".att", ".sbom"}
+)
+
+// RewriteRule rewrites image URLs matching the regex, before the registry
+// client is selected. The replacement may reference capture groups of the
+// regex, such as $1.
+type RewriteRule struct {
+	Regex       *regexp.Regexp
+	Replacement string
 }
 
 // Options used to configure client authentication.
@@ -53,10 +117,72 @@ type Options struct {
 	Docker     docker.Options
 	Quay       quay.Options
 	Selfhosted map[string]*selfhosted.Options
+
+	// RegistryHeaders are static headers sent on all requests to the
+	// selfhosted registry of each host. Hosts without a selfhosted registry
+	// are added as one, over https.
+	RegistryHeaders map[string]http.Header
+
+	// RegistryClients pins the client used for each registry host, by the
+	// client name, such as harbor.corp=selfhosted or mirror.corp=fallback,
+	// over the host matching of the clients. Hosts which are not pinned use
+	// the first client whose host matches, in the order: selfhosted
+	// registries, from the most specific host, then acr, ecr, dockerhub, gcr,
+	// ghcr, quay, and otherwise fallback.
+	RegistryClients map[string]string
+
+	// RewriteRules are applied to image URLs in order, where the first
+	// matching rule is used.
+	RewriteRules []RewriteRule
+
+	// DockerHubMirror, if set, is the host of a Docker Hub mirror, such as a
+	// pull-through cache, which the tags of Docker Hub images are fetched
+	// from instead, after any rewrite rules. Official images are under
+	// library/. The mirror is queried as a selfhosted registry if one is
+	// configured for its host, so uses its credentials.
+	DockerHubMirror string
+
+	// IncludeArtifactTags will not filter out signature, attestation, and SBOM
+	// artifact tags from the returned tags.
+	IncludeArtifactTags bool
+
+	// WorkloadIdentity will resolve ACR, ECR, and GCR credentials from the
+	// ambient cloud workload identity, when no static credentials are given.
+	WorkloadIdentity bool
+
+	// Vault, if set, will resolve the credentials of the ACR, ECR, GCR, and
+	// selfhosted registry hosts with a secret path from HashiCorp Vault, over
+	// any other credentials. Hosts with static credentials fall back to them
+	// if Vault fails.
+	Vault *credentials.VaultOptions
+
+	// RegistryConcurrency is the maximum number of concurrent requests for
+	// tags, by registry host. Hosts which are not listed are limited to
+	// DefaultRegistryConcurrency, where 0 is unlimited.
+	RegistryConcurrency        map[string]int
+	DefaultRegistryConcurrency int
+
+	// ManifestConcurrency is the number of manifests of the tags of an image
+	// fetched concurrently by selfhosted registry clients, no more than the
+	// concurrency limit of the registry host. Manifests are fetched one at a
+	// time if 1 or less.
+	ManifestConcurrency int
+
+	// Metrics, if set, is used to expose the in-flight requests to each
+	// registry host, and the conditional manifest requests of selfhosted
+	// registries which were not modified.
+	Metrics *metrics.Metrics
 }
 
 func New(ctx context.Context, log *logrus.Entry, opts Options) (*Client, error) {
-	acrClient, err := acr.New(opts.ACR)
+	dockerHubMirror, err := parseDockerHubMirror(opts.DockerHubMirror)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := credentials.NewResolver()
+
+	acrClient, err := acr.New(opts.ACR, creds)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create acr client: %s", err)
 	}
@@ -65,8 +191,30 @@ func New(ctx context.Context, log *logrus.Entry, opts Options) (*Client, error)
 		return nil, fmt.Errorf("failed to create docker client: %s", err)
 	}
 
+	ecrClient := ecr.New(opts.ECR, creds)
+	gcrClient := gcr.New(opts.GCR, creds)
+
+	allSelfhostedOpts := selfhostedOptions(opts.Selfhosted, opts.RegistryHeaders)
+	if opts.Vault != nil {
+		if err := registerVaultProviders(log, creds, opts, allSelfhostedOpts, acrClient, ecrClient, gcrClient); err != nil {
+			return nil, err
+		}
+	}
+
+	limiter, err := newHostLimiter(opts.RegistryConcurrency, opts.DefaultRegistryConcurrency, opts.Metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestWorkers := func(host string) int {
+		return limiter.manifestWorkers(host, opts.ManifestConcurrency)
+	}
+
 	var selfhostedClients []ImageClient
-	for _, sOpts := range opts.Selfhosted {
+	for _, sOpts := range allSelfhostedOpts {
+		sOpts.Metrics = opts.Metrics
+		sOpts.ManifestWorkers = manifestWorkers
+		sOpts.Acquire = limiter.acquire
 		sClient, err := selfhosted.New(ctx, log, sOpts)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create selfhosted client %q: %s",
@@ -76,60 +224,545 @@ func New(ctx context.Context, log *logrus.Entry, opts Options) (*Client, error)
 		selfhostedClients = append(selfhostedClients, sClient)
 	}
 
-	fallbackClient, err := fallback.New(ctx, log)
+	fallbackClient, err := fallback.New(ctx, log, &selfhosted.Options{
+		ManifestWorkers: manifestWorkers,
+		Acquire:         limiter.acquire,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create fallback client: %s", err)
 	}
 
+	ociClient, err := oci.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oci client: %s", err)
+	}
+
+	registerCredentialProviders(creds, opts, acrClient, ecrClient, gcrClient)
+
 	c := &Client{
+		log:          log.WithField("module", "client"),
+		rewriteRules: opts.RewriteRules,
+		limiter:      limiter,
+		credentials:  creds,
+
+		dockerHubMirror: dockerHubMirror,
+
+		includeArtifactTags: opts.IncludeArtifactTags,
 		clients: append(
 			selfhostedClients,
 			acrClient,
-			ecr.New(opts.ECR),
+			ecrClient,
 			dockerClient,
-			gcr.New(opts.GCR),
+			gcrClient,
 			ghcr.New(opts.GHCR),
 			quay.New(opts.Quay),
 		),
 		fallbackClient: fallbackClient,
+		ociClient:      ociClient,
 	}
 
 	for _, client := range append(c.clients, fallbackClient) {
 		log.Debugf("registered client %q", client.Name())
 	}
 
+	c.pinnedClients, err = pinClients(opts.RegistryClients, selfhostedClients,
+		slices.Concat(c.clients[len(selfhostedClients):], []ImageClient{fallbackClient}))
+	if err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
 
+// pinClients returns the client pinned to each host, by the name of the
+// client. The name selfhosted pins the selfhosted registry whose host
+// matches, since selfhosted clients are named by their URL.
+func pinClients(registryClients map[string]string, selfhostedClients, namedClients []ImageClient) (map[string]ImageClient, error) {
+	var names []string
+	for _, client := range namedClients {
+		names = append(names, client.Name())
+	}
+
+	pinned := make(map[string]ImageClient)
+	for host, name := range registryClients {
+		if len(host) == 0 {
+			return nil, fmt.Errorf("registry client %q must be given for a host", name)
+		}
+
+		if name == selfhostedClientName {
+			i := slices.IndexFunc(selfhostedClients, func(client ImageClient) bool { return client.IsHost(host) })
+			if i < 0 {
+				return nil, fmt.Errorf("registry client %q given for host %q, which has no selfhosted registry", name, host)
+			}
+			pinned[host] = selfhostedClients[i]
+			continue
+		}
+
+		i := slices.Index(names, name)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown registry client %q for host %q, must be one of %s, %s",
+				name, host, selfhostedClientName, strings.Join(names, ", "))
+		}
+		pinned[host] = namedClients[i]
+	}
+
+	return pinned, nil
+}
+
+// selfhostedOptions returns a copy of the options of each selfhosted
+// registry, with the static headers of its host. Hosts with headers but no
+// selfhosted registry are added as a selfhosted registry over https. The
+// options are ordered by the precedence of their host matching, from the
+// longest, most specific, host.
+func selfhostedOptions(selfhostedOpts map[string]*selfhosted.Options, headers map[string]http.Header) []*selfhosted.Options {
+	var (
+		allOpts []*selfhosted.Options
+		matched = make(map[string]bool)
+	)
+
+	for _, sOpts := range selfhostedOpts {
+		sOpts := *sOpts
+		if u, err := url.Parse(sOpts.Host); err == nil && len(headers[u.Host]) > 0 {
+			sOpts.Headers = headers[u.Host]
+			matched[u.Host] = true
+		}
+
+		allOpts = append(allOpts, &sOpts)
+	}
+
+	hosts := make([]string, 0, len(headers))
+	for host := range headers {
+		if !matched[host] {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		allOpts = append(allOpts, &selfhosted.Options{
+			Host:    "https://" + host,
+			Headers: headers[host],
+		})
+	}
+
+	// Hosts match any subdomain, so the most specific host must match first.
+	sort.SliceStable(allOpts, func(i, j int) bool {
+		hostI, hostJ := selfhostedHost(allOpts[i].Host), selfhostedHost(allOpts[j].Host)
+		if len(hostI) != len(hostJ) {
+			return len(hostI) > len(hostJ)
+		}
+		return allOpts[i].Host < allOpts[j].Host
+	})
+
+	return allOpts
+}
+
+// selfhostedHost returns the host of the given selfhosted registry URL, or
+// the URL if it can not be parsed.
+func selfhostedHost(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && len(u.Host) > 0 {
+		return u.Host
+	}
+
+	return rawURL
+}
+
+// registerCredentialProviders will register the credential providers for
+// each cloud registry client, by their hosts. Static credentials take
+// precedence over the ambient cloud workload identity.
+func registerCredentialProviders(creds *credentials.Resolver, opts Options, acrClient *acr.Client, ecrClient *ecr.Client, gcrClient *gcr.Client) {
+	switch static := acrStaticProvider(opts.ACR); {
+	case static != nil:
+		creds.Register(acrClient.IsHost, static)
+	case opts.WorkloadIdentity:
+		creds.Register(acrClient.IsHost, credentials.NewAzure())
+	}
+
+	switch static := ecrStaticProvider(opts.ECR); {
+	// The IAM role is assumed through the AWS default credential chain.
+	case len(opts.ECR.IamRoleArn) > 0:
+		creds.Register(ecrClient.IsHost, credentials.NewAWS(ecr.Region))
+	case static != nil:
+		creds.Register(ecrClient.IsHost, static)
+	case opts.WorkloadIdentity:
+		creds.Register(ecrClient.IsHost, credentials.NewAWS(ecr.Region))
+	}
+
+	switch static := gcrStaticProvider(opts.GCR); {
+	case static != nil:
+		creds.Register(gcrClient.IsHost, static)
+	case opts.WorkloadIdentity:
+		creds.Register(gcrClient.IsHost, credentials.NewGCP())
+	}
+}
+
+// registerVaultProviders will register the Vault credential provider for
+// each host with a secret path, before any other provider so it takes
+// precedence. Hosts with static credentials fall back to them if Vault
+// fails. Selfhosted registries with a secret path resolve their credentials
+// through the resolver.
+func registerVaultProviders(log *logrus.Entry, creds *credentials.Resolver, opts Options, selfhostedOpts []*selfhosted.Options,
+	acrClient *acr.Client, ecrClient *ecr.Client, gcrClient *gcr.Client) error {
+	if len(opts.Vault.Role) == 0 {
+		return errors.New("vault role must be given to resolve credentials from vault")
+	}
+	if len(opts.Vault.Paths) == 0 {
+		return errors.New("vault secret path must be given for at least one registry host")
+	}
+
+	vault := credentials.NewVault(*opts.Vault)
+	log = log.WithField("module", "credentials")
+
+	hosts := make([]string, 0, len(opts.Vault.Paths))
+	for host := range opts.Vault.Paths {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		var static credentials.Provider
+		switch {
+		case acrClient.IsHost(host):
+			static = acrStaticProvider(opts.ACR)
+		case ecrClient.IsHost(host):
+			static = ecrStaticProvider(opts.ECR)
+		case gcrClient.IsHost(host):
+			static = gcrStaticProvider(opts.GCR)
+		default:
+			sOpts := selfhostedHostOptions(selfhostedOpts, host)
+			if sOpts == nil {
+				return fmt.Errorf("vault secret path given for host %q, which is not an acr, ecr, gcr, or selfhosted registry", host)
+			}
+			sOpts.Credentials = creds
+			static = selfhostedStaticProvider(sOpts)
+		}
+
+		var provider credentials.Provider = vault
+		if static != nil {
+			provider = credentials.NewFallback(log, vault, static)
+		}
+
+		creds.Register(func(h string) bool { return h == host }, provider)
+	}
+
+	return nil
+}
+
+// selfhostedHostOptions returns the options of the selfhosted registry whose
+// host URL is of the given host, or nil if there is none.
+func selfhostedHostOptions(selfhostedOpts []*selfhosted.Options, host string) *selfhosted.Options {
+	for _, sOpts := range selfhostedOpts {
+		if u, err := url.Parse(sOpts.Host); err == nil && u.Host == host {
+			return sOpts
+		}
+	}
+
+	return nil
+}
+
+// acrStaticProvider returns the provider of the static ACR credentials, or
+// nil if none are given.
+func acrStaticProvider(opts acr.Options) credentials.Provider {
+	switch {
+	case len(opts.RefreshToken) > 0:
+		return credentials.NewStatic("acr", credentials.Credentials{
+			Token: opts.RefreshToken,
+		})
+	case len(opts.Username) > 0 || len(opts.Password) > 0:
+		return credentials.NewStatic("acr", credentials.Credentials{
+			Username: opts.Username,
+			Password: opts.Password,
+		})
+	}
+
+	return nil
+}
+
+// ecrStaticProvider returns the provider of the static ECR credentials, or
+// nil if none are given.
+func ecrStaticProvider(opts ecr.Options) credentials.Provider {
+	if len(opts.AccessKeyID) == 0 && len(opts.SecretAccessKey) == 0 && len(opts.SessionToken) == 0 {
+		return nil
+	}
+
+	return credentials.NewStatic("ecr", credentials.Credentials{
+		Username: opts.AccessKeyID,
+		Password: opts.SecretAccessKey,
+		Token:    opts.SessionToken,
+	})
+}
+
+// gcrStaticProvider returns the provider of the static GCR credentials, or
+// nil if none are given.
+func gcrStaticProvider(opts gcr.Options) credentials.Provider {
+	if len(opts.Token) == 0 {
+		return nil
+	}
+
+	return credentials.NewStatic("gcr", credentials.Credentials{
+		Username: "oauth2accesstoken",
+		Password: opts.Token,
+	})
+}
+
+// selfhostedStaticProvider returns the provider of the static credentials of
+// the selfhosted registry, or nil if none are given.
+func selfhostedStaticProvider(opts *selfhosted.Options) credentials.Provider {
+	if len(opts.Username) == 0 && len(opts.Password) == 0 && len(opts.Bearer) == 0 {
+		return nil
+	}
+
+	return credentials.NewStatic("selfhosted", credentials.Credentials{
+		Username: opts.Username,
+		Password: opts.Password,
+		Token:    opts.Bearer,
+	})
+}
+
+// ParseRewriteRule will parse a rewrite rule of the form
+// "<regex>=<replacement>". The regex is anchored to match the full image
+// URL.
+func ParseRewriteRule(rule string) (RewriteRule, error) {
+	regex, replacement, ok := strings.Cut(rule, "=")
+	if !ok || len(regex) == 0 {
+		return RewriteRule{}, fmt.Errorf("rewrite rule %q must be of the form <regex>=<replacement>", rule)
+	}
+
+	reg, err := regexp.Compile("^(?:" + regex + ")$")
+	if err != nil {
+		return RewriteRule{}, fmt.Errorf("failed to compile rewrite rule regex %q: %s", regex, err)
+	}
+
+	return RewriteRule{
+		Regex:       reg,
+		Replacement: replacement,
+	}, nil
+}
+
+// parseDockerHubMirror will validate the host of the Docker Hub mirror,
+// returning it without any trailing slash. The host must be a registry host,
+// so that mirrored images are not mistaken for Docker Hub images.
+func parseDockerHubMirror(mirror string) (string, error) {
+	mirror = strings.TrimSuffix(mirror, "/")
+	if len(mirror) == 0 {
+		return "", nil
+	}
+
+	if strings.Contains(mirror, "/") {
+		return "", fmt.Errorf("docker hub mirror %q must be a host, without a scheme or path", mirror)
+	}
+
+	if host, _ := splitImageURL(mirror + "/image"); len(host) == 0 || dockerHubHosts[host] {
+		return "", fmt.Errorf("docker hub mirror %q must be the host of a registry other than docker hub", mirror)
+	}
+
+	return mirror, nil
+}
+
 // Tags returns the full list of image tags available, for a given image URL.
 func (c *Client) Tags(ctx context.Context, imageURL string) ([]api.ImageTag, error) {
+	imageURL = c.rewriteImageURL(imageURL)
 	client, host, path := c.fromImageURL(imageURL)
+
+	return c.clientTags(ctx, client, host, path)
+}
+
+// TagsWithCredentials returns the full list of image tags available for a
+// given image URL, listed with the given credentials over those of the
+// registry client of the host. Registry clients which authenticate with a
+// username and password list the tags with them, while the tags of other
+// registries are listed with the OCI distribution API, so are only reported by
+// name.
+func (c *Client) TagsWithCredentials(ctx context.Context, imageURL string, creds *api.RegistryCredentials) ([]api.ImageTag, error) {
+	imageURL = c.rewriteImageURL(imageURL)
+	client, host, path := c.fromImageURL(imageURL)
+
+	if usesOverrideCredentials(client) {
+		ctx = credentials.WithOverride(ctx, &credentials.Credentials{
+			Username: creds.Username,
+			Password: creds.Password,
+		})
+		return c.clientTags(ctx, client, host, path)
+	}
+
 	repo, image := client.RepoImageFromPath(path)
-	return client.Tags(ctx, host, repo, image)
+
+	release, err := c.limiter.acquire(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	registryHost := host
+	if len(registryHost) == 0 {
+		registryHost = dockerHubHost
+	}
+
+	tags, err := c.ociClient.TagsWithAuth(ctx, registryHost, repo, image, creds.Username, creds.Password)
+	if err != nil || c.includeArtifactTags {
+		return tags, err
+	}
+
+	return filterArtifactTags(tags), nil
+}
+
+// clientTags returns the tags of the image at the given path of the host,
+// listed by the given registry client under the concurrency limit of the host.
+func (c *Client) clientTags(ctx context.Context, client ImageClient, host, path string) ([]api.ImageTag, error) {
+	repo, image := client.RepoImageFromPath(path)
+
+	if limited, ok := client.(requestLimitedClient); !ok || !limited.LimitsRequests() {
+		release, err := c.limiter.acquire(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	tags, err := client.Tags(ctx, host, repo, image)
+	if err != nil || c.includeArtifactTags {
+		return tags, err
+	}
+
+	return filterArtifactTags(tags), nil
+}
+
+// RegistryHost returns the registry host the tags of the given image URL are
+// fetched from, after any rewrite rules. Images without a registry host are
+// docker.io.
+func (c *Client) RegistryHost(imageURL string) string {
+	_, host, _ := c.fromImageURL(c.rewriteImageURL(imageURL))
+	if len(host) == 0 {
+		return dockerHubHost
+	}
+
+	return host
+}
+
+// ResolveImageURL returns the image URL the tags of the given image URL are
+// fetched from, after any rewrite rules and Docker Hub mirror.
+func (c *Client) ResolveImageURL(imageURL string) string {
+	return c.rewriteImageURL(imageURL)
+}
+
+// filterArtifactTags will return the given tags without any signature,
+// attestation, or SBOM artifact tags, so they are not mistaken for versions.
+func filterArtifactTags(tags []api.ImageTag) []api.ImageTag {
+	var filtered []api.ImageTag
+	for _, tag := range tags {
+		if !isArtifactTag(tag.Tag) {
+			filtered = append(filtered, tag)
+		}
+	}
+
+	return filtered
+}
+
+// isArtifactTag returns true if the given tag is a referrer artifact tag,
+// rather than a version of the image.
+func isArtifactTag(tag string) bool {
+	if artifactTagRegex.MatchString(tag) {
+		return true
+	}
+
+	for _, suffix := range artifactTagSuffixes {
+		if strings.HasSuffix(tag, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rewriteImageURL will rewrite the image URL with the first matching rewrite
+// rule, then redirect Docker Hub images to the Docker Hub mirror, if any. The
+// image URL is returned unchanged if no rule matches and it is not mirrored.
+func (c *Client) rewriteImageURL(imageURL string) string {
+	rewritten := c.mirrorDockerHub(c.applyRewriteRules(imageURL))
+	if rewritten != imageURL && c.log != nil {
+		c.log.Debugf("rewrote image URL %s -> %s", imageURL, rewritten)
+	}
+
+	return rewritten
+}
+
+// applyRewriteRules will rewrite the image URL with the first matching
+// rewrite rule.
+func (c *Client) applyRewriteRules(imageURL string) string {
+	for _, rule := range c.rewriteRules {
+		if rule.Regex.MatchString(imageURL) {
+			return rule.Regex.ReplaceAllString(imageURL, rule.Replacement)
+		}
+	}
+
+	return imageURL
+}
+
+// mirrorDockerHub will redirect the given image URL to the Docker Hub mirror,
+// if it is a Docker Hub image. Official images, without a repository, are
+// under library/ of the mirror.
+func (c *Client) mirrorDockerHub(imageURL string) string {
+	if len(c.dockerHubMirror) == 0 {
+		return imageURL
+	}
+
+	host, path := splitImageURL(imageURL)
+	if !dockerHubHosts[host] {
+		return imageURL
+	}
+
+	if !strings.Contains(path, "/") {
+		path = "library/" + path
+	}
+
+	return c.dockerHubMirror + "/" + path
+}
+
+// splitImageURL will split the given image URL into its registry host and
+// path. The host is empty for images which do not reference a registry.
+func splitImageURL(imageURL string) (string, string) {
+	if !strings.Contains(imageURL, ".") && !strings.Contains(imageURL, ":") {
+		return "", imageURL
+	}
+
+	split := strings.SplitN(imageURL, "/", 2)
+	if len(split) < 2 {
+		return "", imageURL
+	}
+
+	return split[0], split[1]
 }
 
 // fromImageURL will return the appropriate registry client for a given
 // image URL, and the host + path to search.
 func (c *Client) fromImageURL(imageURL string) (ImageClient, string, string) {
-	var host, path string
+	host, path := splitImageURL(imageURL)
 
-	if strings.Contains(imageURL, ".") || strings.Contains(imageURL, ":") {
-		split := strings.SplitN(imageURL, "/", 2)
-		if len(split) < 2 {
-			path = imageURL
-		} else {
-			host, path = split[0], split[1]
-		}
-	} else {
-		path = imageURL
+	pinnedHost := host
+	if len(pinnedHost) == 0 {
+		pinnedHost = dockerHubHost
+	}
+	if client, ok := c.pinnedClients[pinnedHost]; ok {
+		c.logSelectedClient(client, host, "pinned")
+		return client, host, path
 	}
 
 	for _, client := range c.clients {
 		if client.IsHost(host) {
+			c.logSelectedClient(client, host, "host match")
 			return client, host, path
 		}
 	}
 
 	// fall back to selfhosted with no path split
+	c.logSelectedClient(c.fallbackClient, host, "fallback")
 	return c.fallbackClient, host, path
 }
+
+// logSelectedClient will log the client selected for the given host, and
+// why, at debug.
+func (c *Client) logSelectedClient(client ImageClient, host, reason string) {
+	if c.log != nil {
+		c.log.Debugf("selected client %q for host %q (%s)", client.Name(), host, reason)
+	}
+}
@@ -2,12 +2,23 @@ package client
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
 
+	"github.com/jetstack/version-checker/pkg/api"
 	"github.com/jetstack/version-checker/pkg/client/acr"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
 	"github.com/jetstack/version-checker/pkg/client/docker"
 	"github.com/jetstack/version-checker/pkg/client/ecr"
 	"github.com/jetstack/version-checker/pkg/client/fallback"
@@ -15,6 +26,7 @@ import (
 	"github.com/jetstack/version-checker/pkg/client/ghcr"
 	"github.com/jetstack/version-checker/pkg/client/quay"
 	"github.com/jetstack/version-checker/pkg/client/selfhosted"
+	"github.com/jetstack/version-checker/pkg/metrics"
 )
 
 func TestFromImageURL(t *testing.T) {
@@ -197,3 +209,842 @@ func TestFromImageURL(t *testing.T) {
 		})
 	}
 }
+
+func TestRewriteImageURL(t *testing.T) {
+	var rules []RewriteRule
+	for _, rule := range []string{
+		`docker\.io/library/(.+)=harbor.corp/proxy/library/$1`,
+		`(?:docker\.io/)?([^./]+)=harbor.corp/proxy/library/$1`,
+		`quay\.io/(.+)=harbor.corp/proxy/quay/$1`,
+		`quay\.io/jetstack/(.+)=never.used/$1`,
+	} {
+		rewriteRule, err := ParseRewriteRule(rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules = append(rules, rewriteRule)
+	}
+
+	handler, err := New(context.TODO(), logrus.NewEntry(logrus.New()), Options{
+		RewriteRules: rules,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		url    string
+		expURL string
+	}{
+		"no matching rule should pass through": {
+			url:    "gcr.io/jetstack/version-checker",
+			expURL: "gcr.io/jetstack/version-checker",
+		},
+		"rule should only match the full URL": {
+			url:    "mirror.docker.io/library/nginx",
+			expURL: "mirror.docker.io/library/nginx",
+		},
+		"host and path should be rewritten": {
+			url:    "docker.io/library/nginx",
+			expURL: "harbor.corp/proxy/library/nginx",
+		},
+		"short docker image should be rewritten": {
+			url:    "nginx",
+			expURL: "harbor.corp/proxy/library/nginx",
+		},
+		"first matching rule should win": {
+			url:    "quay.io/jetstack/cert-manager-controller",
+			expURL: "harbor.corp/proxy/quay/jetstack/cert-manager-controller",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if url := handler.rewriteImageURL(test.url); url != test.expURL {
+				t.Errorf("unexpected rewritten url, exp=%s got=%s",
+					test.expURL, url)
+			}
+		})
+	}
+
+	// Rewritten URLs should select the client of the rewritten host
+	client, host, path := handler.fromImageURL(handler.rewriteImageURL("docker.io/library/nginx"))
+	if reflect.TypeOf(client) != reflect.TypeOf(new(fallback.Client)) || host != "harbor.corp" || path != "proxy/library/nginx" {
+		t.Errorf("unexpected client for rewritten url, got=%v %s %s",
+			reflect.TypeOf(client), host, path)
+	}
+
+	// The registry host should be of the rewritten URL, where images without a
+	// host are docker.io
+	for url, expHost := range map[string]string{
+		"nginx":                           "harbor.corp",
+		"jetstack/version-checker":        "docker.io",
+		"gcr.io/jetstack/version-checker": "gcr.io",
+	} {
+		if host := handler.RegistryHost(url); host != expHost {
+			t.Errorf("%s: unexpected registry host, exp=%s got=%s", url, expHost, host)
+		}
+	}
+}
+
+func TestParseRewriteRule(t *testing.T) {
+	tests := map[string]struct {
+		rule   string
+		expErr string
+	}{
+		"valid rule should parse": {
+			rule: `docker\.io/(.+)=mirror.corp/$1`,
+		},
+		"empty replacement should parse": {
+			rule: `docker\.io/(.+)=`,
+		},
+		"no separator should error": {
+			rule:   `docker\.io/(.+)`,
+			expErr: `rewrite rule "docker\\.io/(.+)" must be of the form <regex>=<replacement>`,
+		},
+		"empty regex should error": {
+			rule:   `=mirror.corp`,
+			expErr: `rewrite rule "=mirror.corp" must be of the form <regex>=<replacement>`,
+		},
+		"invalid regex should error": {
+			rule:   `docker\.io/(.+=mirror.corp/$1`,
+			expErr: "failed to compile rewrite rule regex \"docker\\\\.io/(.+\": error parsing regexp: missing closing ): `^(?:docker\\.io/(.+)$`",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseRewriteRule(test.rule)
+			if len(test.expErr) == 0 && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if len(test.expErr) > 0 && (err == nil || err.Error() != test.expErr) {
+				t.Errorf("unexpected error, exp=%s got=%v", test.expErr, err)
+			}
+		})
+	}
+}
+
+func TestDockerHubMirror(t *testing.T) {
+	// The mirror should be queried with its own credentials
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer mirror-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/v2/library/nginx/tags/list":
+			_, _ = w.Write([]byte(`{"tags":["1.25.0"]}`))
+		case "/v2/library/nginx/manifests/1.25.0":
+			w.Header().Set("Docker-Content-Digest", "sha256:1250")
+			_, _ = w.Write([]byte(`{"architecture":"amd64","history":[{"v1Compatibility":"{\"created\":\"2023-08-27T12:00:00Z\"}"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	mirror := strings.TrimPrefix(server.URL, "http://")
+	handler, err := New(context.TODO(), logrus.NewEntry(logrus.New()), Options{
+		DockerHubMirror: mirror,
+		Selfhosted: map[string]*selfhosted.Options{
+			"mirror": {
+				Host:   server.URL,
+				Bearer: "mirror-token",
+			},
+		},
+		RewriteRules: []RewriteRule{
+			{Regex: regexp.MustCompile(`^quay\.io/(.+)$`), Replacement: "docker.io/$1"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		url    string
+		expURL string
+	}{
+		"official images should be under library": {
+			url:    "nginx",
+			expURL: mirror + "/library/nginx",
+		},
+		"docker.io images should be mirrored": {
+			url:    "docker.io/jetstack/version-checker",
+			expURL: mirror + "/jetstack/version-checker",
+		},
+		"registry-1.docker.io images should be mirrored": {
+			url:    "registry-1.docker.io/library/nginx",
+			expURL: mirror + "/library/nginx",
+		},
+		"rewritten images should be mirrored": {
+			url:    "quay.io/jetstack/version-checker",
+			expURL: mirror + "/jetstack/version-checker",
+		},
+		"other registries should not be mirrored": {
+			url:    "gcr.io/jetstack/version-checker",
+			expURL: "gcr.io/jetstack/version-checker",
+		},
+		"docker hub subdomains should not be mirrored": {
+			url:    "mirror.docker.io/library/nginx",
+			expURL: "mirror.docker.io/library/nginx",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if url := handler.rewriteImageURL(test.url); url != test.expURL {
+				t.Errorf("unexpected rewritten url, exp=%s got=%s",
+					test.expURL, url)
+			}
+		})
+	}
+
+	if host := handler.RegistryHost("nginx"); host != mirror {
+		t.Errorf("unexpected registry host, exp=%s got=%s", mirror, host)
+	}
+
+	tags, err := handler.Tags(context.TODO(), "nginx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].Tag != "1.25.0" || tags[0].SHA != "sha256:1250" {
+		t.Errorf("unexpected tags from mirror, got=%+v", tags)
+	}
+	if !reflect.DeepEqual(paths, []string{
+		"/v2/library/nginx/tags/list",
+		"/v2/library/nginx/manifests/1.25.0",
+		"/v2/library/nginx/manifests/1.25.0",
+	}) {
+		t.Errorf("unexpected requests to mirror, got=%v", paths)
+	}
+}
+
+func TestTagsWithCredentials(t *testing.T) {
+	// The registry only serves tags and manifests to the namespace credentials
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/token" {
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"username": "tenant", "password": "secret"}` {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token":"tenant-token"}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer tenant-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/repo/image/tags/list":
+			_, _ = w.Write([]byte(`{"tags":["latest"]}`))
+		case "/v2/repo/image/manifests/latest":
+			w.Header().Set("Docker-Content-Digest", "sha256:latest")
+			_, _ = w.Write([]byte(`{"architecture":"amd64","history":[{"v1Compatibility":"{\"created\":\"2023-08-27T12:00:00Z\"}"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	handler, err := New(context.TODO(), logrus.NewEntry(logrus.New()), Options{
+		Selfhosted: map[string]*selfhosted.Options{
+			"registry": {
+				Host:   server.URL,
+				Bearer: "global-token",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	imageURL := strings.TrimPrefix(server.URL, "http://") + "/repo/image"
+	if _, err := handler.Tags(context.TODO(), imageURL); err == nil {
+		t.Error("expected tags to fail with the global credentials")
+	}
+
+	// Tags listed by the registry client have the digest of the tag, for
+	// containers using SHAs.
+	tags, err := handler.TagsWithCredentials(context.TODO(), imageURL, &api.RegistryCredentials{
+		Source:   "tenant/registry",
+		Username: "tenant",
+		Password: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expTags := []api.ImageTag{{
+		Tag:          "latest",
+		SHA:          "sha256:latest",
+		Timestamp:    time.Date(2023, 8, 27, 12, 0, 0, 0, time.UTC),
+		Architecture: "amd64",
+	}}
+	if !reflect.DeepEqual(expTags, tags) {
+		t.Errorf("unexpected tags, exp=%+v got=%+v", expTags, tags)
+	}
+}
+
+func TestUsesOverrideCredentials(t *testing.T) {
+	tests := map[string]struct {
+		client ImageClient
+		exp    bool
+	}{
+		"selfhosted": {client: new(selfhosted.Client), exp: true},
+		"fallback":   {client: new(fallback.Client), exp: true},
+		"docker":     {client: new(docker.Client), exp: true},
+		"acr":        {client: new(acr.Client), exp: true},
+		"gcr":        {client: new(gcr.Client), exp: true},
+		"ecr":        {client: new(ecr.Client), exp: false},
+		"ghcr":       {client: new(ghcr.Client), exp: false},
+		"quay":       {client: new(quay.Client), exp: false},
+		"unknown":    {client: new(fakeClient), exp: false},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if uses := usesOverrideCredentials(test.client); uses != test.exp {
+				t.Errorf("unexpected uses override credentials, exp=%t got=%t", test.exp, uses)
+			}
+		})
+	}
+}
+
+func TestParseDockerHubMirror(t *testing.T) {
+	tests := map[string]struct {
+		mirror    string
+		expMirror string
+		expErr    string
+	}{
+		"no mirror should be empty": {},
+		"host should parse": {
+			mirror:    "mirror.corp",
+			expMirror: "mirror.corp",
+		},
+		"host with port and trailing slash should parse": {
+			mirror:    "localhost:5000/",
+			expMirror: "localhost:5000",
+		},
+		"scheme should error": {
+			mirror: "https://mirror.corp",
+			expErr: `docker hub mirror "https://mirror.corp" must be a host, without a scheme or path`,
+		},
+		"path should error": {
+			mirror: "harbor.corp/dockerhub",
+			expErr: `docker hub mirror "harbor.corp/dockerhub" must be a host, without a scheme or path`,
+		},
+		"name without a registry host should error": {
+			mirror: "mirror",
+			expErr: `docker hub mirror "mirror" must be the host of a registry other than docker hub`,
+		},
+		"docker hub should error": {
+			mirror: "registry-1.docker.io",
+			expErr: `docker hub mirror "registry-1.docker.io" must be the host of a registry other than docker hub`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			mirror, err := parseDockerHubMirror(test.mirror)
+			if len(test.expErr) == 0 && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if len(test.expErr) > 0 && (err == nil || err.Error() != test.expErr) {
+				t.Errorf("unexpected error, exp=%s got=%v", test.expErr, err)
+			}
+			if mirror != test.expMirror {
+				t.Errorf("unexpected mirror, exp=%s got=%s", test.expMirror, mirror)
+			}
+		})
+	}
+}
+
+type fakeClient struct {
+	tags []api.ImageTag
+}
+
+func (f *fakeClient) Name() string                                   { return "fake" }
+func (f *fakeClient) IsHost(_ string) bool                           { return true }
+func (f *fakeClient) RepoImageFromPath(path string) (string, string) { return "", path }
+func (f *fakeClient) Tags(_ context.Context, _, _, _ string) ([]api.ImageTag, error) {
+	return f.tags, nil
+}
+
+func TestTagsArtifactFiltering(t *testing.T) {
+	digest := "sha256-0b3f0bca5c7ccc1c2636ad0d1d1b8af4c7871362e89d0d244bcbbe0ee303d3de"
+	fake := &fakeClient{
+		tags: []api.ImageTag{
+			{Tag: "v1.0.0"},
+			{Tag: digest + ".sig"},
+			{Tag: digest + ".att"},
+			{Tag: digest + ".sbom"},
+			{Tag: digest},
+			{Tag: "v1.1.0"},
+			{Tag: "v1.1.0.sig"},
+			{Tag: "sha256-foo"},
+		},
+	}
+
+	tests := map[string]struct {
+		include bool
+		expTags []string
+	}{
+		"artifact tags should be filtered by default": {
+			include: false,
+			expTags: []string{"v1.0.0", "v1.1.0", "sha256-foo"},
+		},
+		"artifact tags should be included if enabled": {
+			include: true,
+			expTags: []string{
+				"v1.0.0", digest + ".sig", digest + ".att", digest + ".sbom",
+				digest, "v1.1.0", "v1.1.0.sig", "sha256-foo",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Client{
+				clients:             []ImageClient{fake},
+				fallbackClient:      fake,
+				includeArtifactTags: test.include,
+			}
+
+			tags, err := c.Tags(context.TODO(), "quay.io/example/image")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var gotTags []string
+			for _, tag := range tags {
+				gotTags = append(gotTags, tag.Tag)
+			}
+
+			if !reflect.DeepEqual(test.expTags, gotTags) {
+				t.Errorf("unexpected tags, exp=%v got=%v", test.expTags, gotTags)
+			}
+		})
+	}
+}
+
+// blockingClient blocks requests for tags until released, recording the
+// maximum number of concurrent requests per host.
+type blockingClient struct {
+	fakeClient
+
+	mu          sync.Mutex
+	inFlight    map[string]int
+	maxInFlight map[string]int
+	release     chan struct{}
+}
+
+func (b *blockingClient) Tags(_ context.Context, host, _, _ string) ([]api.ImageTag, error) {
+	if len(host) == 0 {
+		host = "docker.io"
+	}
+
+	b.mu.Lock()
+	b.inFlight[host]++
+	b.maxInFlight[host] = max(b.maxInFlight[host], b.inFlight[host])
+	b.mu.Unlock()
+
+	<-b.release
+
+	b.mu.Lock()
+	b.inFlight[host]--
+	b.mu.Unlock()
+
+	return nil, nil
+}
+
+func TestTagsRegistryConcurrency(t *testing.T) {
+	blocking := &blockingClient{
+		inFlight:    make(map[string]int),
+		maxInFlight: make(map[string]int),
+		release:     make(chan struct{}),
+	}
+
+	reg := prometheus.NewRegistry()
+	m := metrics.New(logrus.NewEntry(logrus.New()), reg, metrics.Options{})
+	limiter, err := newHostLimiter(map[string]int{"docker.io": 2, "harbor.corp": 5}, 3, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{
+		clients:        []ImageClient{blocking},
+		fallbackClient: blocking,
+		limiter:        limiter,
+	}
+
+	images := []string{
+		"nginx",
+		"docker.io/library/nginx",
+		"harbor.corp/team/image",
+		"quay.io/jetstack/version-checker",
+	}
+
+	var wg sync.WaitGroup
+	for _, image := range images {
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := c.Tags(context.TODO(), image); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+	}
+
+	// Wait for the limits to be saturated, before releasing all requests
+	expInFlight := map[string]float64{"docker.io": 2, "harbor.corp": 5, "quay.io": 3}
+	for host, exp := range expInFlight {
+		if err := wait.PollUntilContextTimeout(context.TODO(), time.Millisecond, time.Second*5, true, func(context.Context) (bool, error) {
+			return registryInFlight(t, reg, host) == exp, nil
+		}); err != nil {
+			t.Errorf("%s: expected %v in-flight requests, got=%v", host,
+				exp, registryInFlight(t, reg, host))
+		}
+	}
+
+	close(blocking.release)
+	wg.Wait()
+
+	// Requests for images without a host share the docker.io limit
+	for host, exp := range expInFlight {
+		if got := blocking.maxInFlight[host]; float64(got) != exp {
+			t.Errorf("%s: expected %v concurrent requests, got=%d", host, exp, got)
+		}
+	}
+
+	for host := range expInFlight {
+		if got := registryInFlight(t, reg, host); got != 0 {
+			t.Errorf("%s: expected no in-flight requests, got=%v", host, got)
+		}
+	}
+}
+
+// registryInFlight returns the in-flight requests to the given host, gathered
+// from the registry.
+func registryInFlight(t *testing.T, reg *prometheus.Registry, host string) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != "version_checker_registry_requests_in_flight" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "host" && label.GetValue() == host {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+
+	return 0
+}
+
+func TestNewHostLimiter(t *testing.T) {
+	tests := map[string]struct {
+		limits       map[string]int
+		defaultLimit int
+		expErr       string
+	}{
+		"valid limits should not error": {
+			limits:       map[string]int{"docker.io": 2},
+			defaultLimit: 0,
+		},
+		"zero host limit should error": {
+			limits: map[string]int{"docker.io": 0},
+			expErr: `registry concurrency for "docker.io" must be positive, got 0`,
+		},
+		"negative default limit should error": {
+			defaultLimit: -1,
+			expErr:       "default registry concurrency must not be negative, got -1",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := newHostLimiter(test.limits, test.defaultLimit, nil)
+			if len(test.expErr) == 0 && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if len(test.expErr) > 0 && (err == nil || err.Error() != test.expErr) {
+				t.Errorf("unexpected error, exp=%q got=%v", test.expErr, err)
+			}
+		})
+	}
+}
+
+func TestHostLimiterContextCancelled(t *testing.T) {
+	limiter, err := newHostLimiter(map[string]int{"docker.io": 1}, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release, err := limiter.acquire(context.TODO(), "docker.io")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	if _, err := limiter.acquire(ctx, ""); err == nil {
+		t.Error("expected error waiting for a saturated host with a cancelled context")
+	}
+}
+
+func TestHostLimiterManifestWorkers(t *testing.T) {
+	limiter, err := newHostLimiter(map[string]int{"docker.io": 2, "harbor.corp": 20}, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]struct {
+		limiter     *hostLimiter
+		host        string
+		concurrency int
+		expWorkers  int
+	}{
+		"concurrency should be limited by the host": {
+			limiter:     limiter,
+			host:        "docker.io",
+			concurrency: 8,
+			expWorkers:  2,
+		},
+		"images without a host should be limited by docker.io": {
+			limiter:     limiter,
+			host:        "",
+			concurrency: 8,
+			expWorkers:  2,
+		},
+		"concurrency below the host limit should be used": {
+			limiter:     limiter,
+			host:        "harbor.corp",
+			concurrency: 8,
+			expWorkers:  8,
+		},
+		"unlimited hosts should use the concurrency": {
+			limiter:     limiter,
+			host:        "quay.io",
+			concurrency: 8,
+			expWorkers:  8,
+		},
+		"concurrency below 1 should fetch one at a time": {
+			limiter:     limiter,
+			host:        "quay.io",
+			concurrency: 0,
+			expWorkers:  1,
+		},
+		"no limiter should use the concurrency": {
+			host:        "docker.io",
+			concurrency: 8,
+			expWorkers:  8,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if workers := test.limiter.manifestWorkers(test.host, test.concurrency); workers != test.expWorkers {
+				t.Errorf("unexpected manifest workers, exp=%d got=%d", test.expWorkers, workers)
+			}
+		})
+	}
+}
+
+func TestRegistryClients(t *testing.T) {
+	selfhostedOpts := map[string]*selfhosted.Options{
+		"harbor":    {Host: "https://harbor.corp"},
+		"eu-harbor": {Host: "https://eu.harbor.corp"},
+		"quay":      {Host: "https://quay.corp"},
+	}
+
+	// Selection should not depend on the order selfhosted registries are
+	// configured in.
+	for range 10 {
+		handler, err := New(context.TODO(), logrus.NewEntry(logrus.New()), Options{
+			Selfhosted: selfhostedOpts,
+			RegistryClients: map[string]string{
+				"harbor.corp": "fallback",
+				"docker.io":   "fallback",
+				"quay.io":     "quay",
+				"ghcr.corp":   "ghcr",
+				"quay.corp":   "selfhosted",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for url, expClient := range map[string]string{
+			"eu.harbor.corp/library/nginx":    "https://eu.harbor.corp",
+			"us.harbor.corp/library/nginx":    "https://harbor.corp",
+			"harbor.corp/library/nginx":       "fallback",
+			"nginx":                           "fallback",
+			"quay.io/jetstack/cert-manager":   "quay",
+			"ghcr.corp/jetstack/cert-manager": "ghcr",
+			"quay.corp/jetstack/cert-manager": "https://quay.corp",
+			"gcr.io/jetstack/cert-manager":    "gcr",
+		} {
+			if client, _, _ := handler.fromImageURL(url); client.Name() != expClient {
+				t.Errorf("%s: unexpected client, exp=%s got=%s", url, expClient, client.Name())
+			}
+		}
+	}
+
+	for registryClients, expErr := range map[string]string{
+		"harbor.corp=nexus":      `unknown registry client "nexus" for host "harbor.corp", must be one of selfhosted, acr, ecr, dockerhub, gcr, ghcr, quay, fallback`,
+		"gitlab.corp=selfhosted": `registry client "selfhosted" given for host "gitlab.corp", which has no selfhosted registry`,
+		"=quay":                  `registry client "quay" must be given for a host`,
+	} {
+		host, name, _ := strings.Cut(registryClients, "=")
+		_, err := New(context.TODO(), logrus.NewEntry(logrus.New()), Options{
+			Selfhosted:      selfhostedOpts,
+			RegistryClients: map[string]string{host: name},
+		})
+		if err == nil || err.Error() != expErr {
+			t.Errorf("%s: unexpected error, exp=%s got=%v", registryClients, expErr, err)
+		}
+	}
+}
+
+func TestSelfhostedOptions(t *testing.T) {
+	apiKey := http.Header{"X-Api-Key": []string{"secret"}}
+
+	tests := map[string]struct {
+		selfhosted map[string]*selfhosted.Options
+		headers    map[string]http.Header
+		exp        []*selfhosted.Options
+	}{
+		"no headers should return the selfhosted options": {
+			selfhosted: map[string]*selfhosted.Options{
+				"internal": {Host: "https://registry.corp"},
+			},
+			exp: []*selfhosted.Options{
+				{Host: "https://registry.corp"},
+			},
+		},
+		"headers of a selfhosted host should be added to its options": {
+			selfhosted: map[string]*selfhosted.Options{
+				"internal": {Host: "https://registry.corp:5000", Username: "user"},
+			},
+			headers: map[string]http.Header{"registry.corp:5000": apiKey},
+			exp: []*selfhosted.Options{
+				{Host: "https://registry.corp:5000", Username: "user", Headers: apiKey},
+			},
+		},
+		"more specific hosts should be ordered first": {
+			selfhosted: map[string]*selfhosted.Options{
+				"b":  {Host: "https://b.corp"},
+				"a":  {Host: "https://a.corp"},
+				"eu": {Host: "https://eu.b.corp"},
+			},
+			exp: []*selfhosted.Options{
+				{Host: "https://eu.b.corp"},
+				{Host: "https://a.corp"},
+				{Host: "https://b.corp"},
+			},
+		},
+		"headers of other hosts should be added as selfhosted registries": {
+			selfhosted: map[string]*selfhosted.Options{
+				"internal": {Host: "https://registry.corp"},
+			},
+			headers: map[string]http.Header{
+				"proxy-b.corp": apiKey,
+				"proxy-a.corp": apiKey,
+			},
+			exp: []*selfhosted.Options{
+				{Host: "https://registry.corp"},
+				{Host: "https://proxy-a.corp", Headers: apiKey},
+				{Host: "https://proxy-b.corp", Headers: apiKey},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := selfhostedOptions(test.selfhosted, test.headers)
+			if !reflect.DeepEqual(test.exp, opts) {
+				t.Errorf("unexpected options, exp=%+v got=%+v", test.exp, opts)
+			}
+			for _, sOpts := range test.selfhosted {
+				if sOpts.Headers != nil {
+					t.Errorf("selfhosted options should not be modified: %+v", sOpts)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterVaultProviders(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+
+	tests := map[string]struct {
+		vault  *credentials.VaultOptions
+		expErr string
+	}{
+		"no role should error": {
+			vault:  &credentials.VaultOptions{Paths: map[string]string{"registry.corp:5000": "secret/registry"}},
+			expErr: "vault role must be given to resolve credentials from vault",
+		},
+		"no paths should error": {
+			vault:  &credentials.VaultOptions{Role: "version-checker"},
+			expErr: "vault secret path must be given for at least one registry host",
+		},
+		"hosts of other registries should error": {
+			vault: &credentials.VaultOptions{Role: "version-checker", Paths: map[string]string{
+				"docker.io": "secret/docker",
+			}},
+			expErr: `vault secret path given for host "docker.io", which is not an acr, ecr, gcr, or selfhosted registry`,
+		},
+		"selfhosted and cloud registry hosts should resolve through the resolver": {
+			vault: &credentials.VaultOptions{Role: "version-checker", Paths: map[string]string{
+				"registry.corp:5000": "secret/registry",
+				"myreg.azurecr.io":   "secret/acr",
+			}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			acrClient, err := acr.New(acr.Options{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sOpts := []*selfhosted.Options{{Host: "https://registry.corp:5000", Username: "user"}}
+			err = registerVaultProviders(log, credentials.NewResolver(), Options{Vault: test.vault}, sOpts,
+				acrClient, ecr.New(ecr.Options{}, nil), gcr.New(gcr.Options{}, nil))
+			if len(test.expErr) > 0 {
+				if err == nil || err.Error() != test.expErr {
+					t.Errorf("unexpected error, exp=%q got=%v", test.expErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if sOpts[0].Credentials == nil {
+				t.Errorf("expected the selfhosted registry to resolve credentials")
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+const (
+	// dockerHubHost is the host of images which do not reference a registry.
+	dockerHubHost = "docker.io"
+)
+
+// dockerHubHosts are the hosts of Docker Hub images, including images which
+// do not reference a registry.
+var dockerHubHosts = map[string]bool{
+	"":                     true,
+	dockerHubHost:          true,
+	"index.docker.io":      true,
+	"registry-1.docker.io": true,
+}
+
+// hostLimiter limits the number of concurrent requests for the tags of
+// images, per registry host.
+type hostLimiter struct {
+	mu sync.Mutex
+
+	// limits are the concurrency limits of hosts, where hosts without a limit
+	// use the defaultLimit. A limit of 0 is unlimited.
+	limits       map[string]int
+	defaultLimit int
+
+	semaphores map[string]chan struct{}
+	inFlight   map[string]int
+
+	metrics *metrics.Metrics
+}
+
+func newHostLimiter(limits map[string]int, defaultLimit int, metrics *metrics.Metrics) (*hostLimiter, error) {
+	if defaultLimit < 0 {
+		return nil, fmt.Errorf("default registry concurrency must not be negative, got %d", defaultLimit)
+	}
+
+	for host, limit := range limits {
+		if limit <= 0 {
+			return nil, fmt.Errorf("registry concurrency for %q must be positive, got %d", host, limit)
+		}
+	}
+
+	return &hostLimiter{
+		limits:       limits,
+		defaultLimit: defaultLimit,
+		semaphores:   make(map[string]chan struct{}),
+		inFlight:     make(map[string]int),
+		metrics:      metrics,
+	}, nil
+}
+
+// acquire will block until a request to the given host may be made, or the
+// context is done. The returned func must be called once the request is
+// complete.
+func (h *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	if h == nil {
+		return func() {}, nil
+	}
+
+	if len(host) == 0 {
+		host = dockerHubHost
+	}
+
+	if sem := h.semaphore(host); sem != nil {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to wait for concurrency limit of registry %q: %s", host, ctx.Err())
+		}
+	}
+
+	h.addInFlight(host, 1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			h.addInFlight(host, -1)
+			if sem := h.semaphore(host); sem != nil {
+				<-sem
+			}
+		})
+	}, nil
+}
+
+// manifestWorkers returns the number of manifests of the tags of an image on
+// the given host which may be fetched concurrently, being the given
+// concurrency, no more than the concurrency limit of the host, and at least
+// 1.
+func (h *hostLimiter) manifestWorkers(host string, concurrency int) int {
+	if len(host) == 0 {
+		host = dockerHubHost
+	}
+
+	if h != nil {
+		if sem := h.semaphore(host); sem != nil {
+			concurrency = min(concurrency, cap(sem))
+		}
+	}
+
+	return max(concurrency, 1)
+}
+
+// semaphore returns the semaphore of the given host, or nil if the host is
+// unlimited.
+func (h *hostLimiter) semaphore(host string) chan struct{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if sem, ok := h.semaphores[host]; ok {
+		return sem
+	}
+
+	limit, ok := h.limits[host]
+	if !ok {
+		limit = h.defaultLimit
+	}
+
+	var sem chan struct{}
+	if limit > 0 {
+		sem = make(chan struct{}, limit)
+	}
+	h.semaphores[host] = sem
+
+	return sem
+}
+
+// addInFlight will add delta to the in-flight requests of the given host.
+func (h *hostLimiter) addInFlight(host string, delta int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.inFlight[host] += delta
+	if h.metrics != nil {
+		h.metrics.SetRegistryRequestsInFlight(host, h.inFlight[host])
+	}
+}
@@ -0,0 +1,53 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// AWS is a Provider which resolves credentials from the AWS default
+// credential chain, such as IRSA, EKS pod identity, or the instance role.
+// The returned Username, Password, and Token are the access key ID, secret
+// access key, and session token respectively.
+type AWS struct {
+	region func(host string) string
+}
+
+// NewAWS returns a new AWS provider, where region returns the AWS region of
+// a registry host.
+func NewAWS(region func(host string) string) *AWS {
+	return &AWS{
+		region: region,
+	}
+}
+
+func (a *AWS) Name() string {
+	return "aws"
+}
+
+func (a *AWS) Credentials(ctx context.Context, host string) (*Credentials, error) {
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(a.region(host)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %s", err)
+	}
+
+	awsCreds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve aws credentials: %s", err)
+	}
+
+	creds := &Credentials{
+		Username: awsCreds.AccessKeyID,
+		Password: awsCreds.SecretAccessKey,
+		Token:    awsCreds.SessionToken,
+	}
+	if awsCreds.CanExpire {
+		creds.Expiry = awsCreds.Expires
+	}
+
+	return creds, nil
+}
@@ -0,0 +1,59 @@
+package credentials
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSCredentials(t *testing.T) {
+	expiration := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/creds", r.URL.Path)
+		assert.Equal(t, "test-auth-token", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{
+			"AccessKeyId": "test-access-key",
+			"SecretAccessKey": "test-secret-key",
+			"Token": "test-session-token",
+			"Expiration": "` + expiration.Format(time.RFC3339) + `"
+		}`))
+	}))
+	defer server.Close()
+
+	// Isolate the default credential chain from the environment, so only the
+	// faked container credentials endpoint is used.
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", server.URL+"/creds")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "test-auth-token")
+
+	var gotHost string
+	provider := NewAWS(func(host string) string {
+		gotHost = host
+		return "eu-west-1"
+	})
+
+	creds, err := provider.Credentials(context.Background(), "123.dkr.ecr.eu-west-1.amazonaws.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, "123.dkr.ecr.eu-west-1.amazonaws.com", gotHost)
+	assert.Equal(t, "test-access-key", creds.Username)
+	assert.Equal(t, "test-secret-key", creds.Password)
+	assert.Equal(t, "test-session-token", creds.Token)
+	// The AWS credential cache reports the expiry early by its own window.
+	assert.WithinDuration(t, expiration, creds.Expiry, 10*time.Minute)
+}
@@ -0,0 +1,127 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// The environment variables set by the AKS workload identity webhook.
+	azureClientIDEnv      = "AZURE_CLIENT_ID"
+	azureTenantIDEnv      = "AZURE_TENANT_ID"
+	azureTokenFileEnv     = "AZURE_FEDERATED_TOKEN_FILE"
+	azureAuthorityHostEnv = "AZURE_AUTHORITY_HOST"
+
+	azureAuthorityHost = "https://login.microsoftonline.com/"
+	azureACRScope      = "https://containerregistry.azure.net/.default"
+)
+
+// Azure is a Provider which resolves ACR refresh tokens from the AKS
+// workload identity. The federated service account token is exchanged for an
+// Azure AD access token, which is then exchanged with the registry for a
+// refresh token.
+type Azure struct {
+	*http.Client
+}
+
+type azureTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+func NewAzure() *Azure {
+	return &Azure{
+		Client: &http.Client{
+			Timeout: time.Second * 5,
+		},
+	}
+}
+
+func (a *Azure) Name() string {
+	return "azure"
+}
+
+func (a *Azure) Credentials(ctx context.Context, host string) (*Credentials, error) {
+	clientID, tenantID, tokenFile := os.Getenv(azureClientIDEnv), os.Getenv(azureTenantIDEnv), os.Getenv(azureTokenFileEnv)
+	if len(clientID) == 0 || len(tenantID) == 0 || len(tokenFile) == 0 {
+		return nil, fmt.Errorf("workload identity not configured, %s, %s and %s must be set",
+			azureClientIDEnv, azureTenantIDEnv, azureTokenFileEnv)
+	}
+
+	assertion, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read federated token: %s", err)
+	}
+
+	authorityHost := azureAuthorityHost
+	if env := os.Getenv(azureAuthorityHostEnv); len(env) > 0 {
+		authorityHost = env
+	}
+
+	aadToken, err := a.postForm(ctx, strings.TrimSuffix(authorityHost, "/")+"/"+tenantID+"/oauth2/v2.0/token", url.Values{
+		"client_id":             {clientID},
+		"scope":                 {azureACRScope},
+		"grant_type":            {"client_credentials"},
+		"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+		"client_assertion":      {strings.TrimSpace(string(assertion))},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to request azure ad access token: %s", err)
+	}
+	if len(aadToken.AccessToken) == 0 {
+		return nil, errors.New("azure ad token response contained no access token")
+	}
+
+	acrToken, err := a.postForm(ctx, "https://"+host+"/oauth2/exchange", url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {host},
+		"tenant":       {tenantID},
+		"access_token": {aadToken.AccessToken},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange access token for refresh token: %s", err)
+	}
+	if len(acrToken.RefreshToken) == 0 {
+		return nil, errors.New("exchange response contained no refresh token")
+	}
+
+	return &Credentials{
+		Token:  acrToken.RefreshToken,
+		Expiry: time.Now().Add(time.Duration(aadToken.ExpiresIn) * time.Second),
+	}, nil
+}
+
+func (a *Azure) postForm(ctx context.Context, url string, form url.Values) (*azureTokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, body)
+	}
+
+	var token azureTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %s", err)
+	}
+
+	return &token, nil
+}
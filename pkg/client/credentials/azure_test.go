@@ -0,0 +1,67 @@
+package credentials
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzureCredentials(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+
+		switch r.URL.Path {
+		case "/test-tenant/oauth2/v2.0/token":
+			assert.Equal(t, "test-client", r.PostForm.Get("client_id"))
+			assert.Equal(t, "federated-token", r.PostForm.Get("client_assertion"))
+			assert.Equal(t, azureACRScope, r.PostForm.Get("scope"))
+			_, _ = w.Write([]byte(`{"access_token":"aad-token","expires_in":3600}`))
+
+		case "/oauth2/exchange":
+			assert.Equal(t, "access_token", r.PostForm.Get("grant_type"))
+			assert.Equal(t, "aad-token", r.PostForm.Get("access_token"))
+			assert.Equal(t, "test-tenant", r.PostForm.Get("tenant"))
+			assert.Equal(t, r.Host, r.PostForm.Get("service"))
+			_, _ = w.Write([]byte(`{"refresh_token":"acr-refresh-token"}`))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("federated-token\n"), 0600))
+
+	t.Setenv(azureClientIDEnv, "test-client")
+	t.Setenv(azureTenantIDEnv, "test-tenant")
+	t.Setenv(azureTokenFileEnv, tokenFile)
+	t.Setenv(azureAuthorityHostEnv, server.URL+"/")
+
+	provider := NewAzure()
+	provider.Client = server.Client()
+
+	creds, err := provider.Credentials(context.Background(), strings.TrimPrefix(server.URL, "https://"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "acr-refresh-token", creds.Token)
+	assert.Empty(t, creds.Username)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), creds.Expiry, time.Minute)
+}
+
+func TestAzureCredentialsNotConfigured(t *testing.T) {
+	t.Setenv(azureClientIDEnv, "")
+	t.Setenv(azureTenantIDEnv, "")
+	t.Setenv(azureTokenFileEnv, "")
+
+	_, err := NewAzure().Credentials(context.Background(), "test.azurecr.io")
+	assert.EqualError(t, err, "workload identity not configured, AZURE_CLIENT_ID, AZURE_TENANT_ID and AZURE_FEDERATED_TOKEN_FILE must be set")
+}
@@ -0,0 +1,212 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// expiryWindow is how long before credentials expire that they will be
+	// resolved again.
+	expiryWindow = time.Minute
+
+	// fallbackExpiry is how long credentials of a fallback provider are used
+	// for, before the provider is tried again.
+	fallbackExpiry = time.Minute * 5
+)
+
+// Credentials are used to authenticate with an image registry host. Depending
+// on the provider, either the Username and Password, or the Token are set.
+type Credentials struct {
+	Username string
+	Password string
+	Token    string
+
+	// Expiry is when the credentials expire. Zero if they don't expire.
+	Expiry time.Time
+}
+
+// Provider resolves the credentials to use for an image registry host.
+type Provider interface {
+	// Name returns the name of the provider.
+	Name() string
+
+	// Credentials will return the credentials to use for the given host.
+	Credentials(ctx context.Context, host string) (*Credentials, error)
+}
+
+// Resolver resolves credentials for image registry hosts, by selecting the
+// first registered provider for the host. Credentials are cached per host
+// until they expire.
+type Resolver struct {
+	mu        sync.Mutex
+	providers []hostProvider
+	cache     map[string]*hostCredentials
+}
+
+// hostCredentials are the cached credentials of a host. Its lock is held
+// while the credentials of the host are resolved, so that concurrent requests
+// for the host wait on a single resolve, without blocking the requests for
+// other hosts.
+type hostCredentials struct {
+	mu    sync.Mutex
+	creds *Credentials
+}
+
+// overrideKey is the context key of the credentials set by WithOverride.
+type overrideKey struct{}
+
+// WithOverride returns a context whose credentials are resolved for any host
+// over those of the registered providers, such as the credentials of the
+// namespace of a container.
+func WithOverride(ctx context.Context, creds *Credentials) context.Context {
+	return context.WithValue(ctx, overrideKey{}, creds)
+}
+
+// Override returns the credentials of the given context set by WithOverride,
+// if any.
+func Override(ctx context.Context) (*Credentials, bool) {
+	creds, ok := ctx.Value(overrideKey{}).(*Credentials)
+	return creds, ok && creds != nil
+}
+
+type hostProvider struct {
+	isHost func(host string) bool
+	Provider
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{
+		cache: make(map[string]*hostCredentials),
+	}
+}
+
+// Register will register the provider to be used for any hosts that isHost
+// returns true for. Providers are selected in the order they are
+// registered.
+func (r *Resolver) Register(isHost func(host string) bool, provider Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.providers = append(r.providers, hostProvider{
+		isHost:   isHost,
+		Provider: provider,
+	})
+}
+
+// Credentials will return the credentials for the given host. Credentials
+// set on the context by WithOverride are returned over any provider, including
+// by a nil Resolver. If no provider is registered for the host, nil is
+// returned.
+func (r *Resolver) Credentials(ctx context.Context, host string) (*Credentials, error) {
+	if creds, ok := Override(ctx); ok {
+		return creds, nil
+	}
+
+	if r == nil {
+		return nil, nil
+	}
+
+	r.mu.Lock()
+	cached, ok := r.cache[host]
+	if !ok {
+		cached = new(hostCredentials)
+		r.cache[host] = cached
+	}
+	providers := r.providers
+	r.mu.Unlock()
+
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+
+	if creds := cached.creds; creds != nil &&
+		(creds.Expiry.IsZero() || time.Now().Add(expiryWindow).Before(creds.Expiry)) {
+		return creds, nil
+	}
+
+	for _, provider := range providers {
+		if !provider.isHost(host) {
+			continue
+		}
+
+		creds, err := provider.Credentials(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s credentials for %s: %s",
+				provider.Name(), host, err)
+		}
+
+		cached.creds = creds
+
+		return creds, nil
+	}
+
+	return nil, nil
+}
+
+// Static is a Provider which always returns the same credentials.
+type Static struct {
+	name  string
+	creds Credentials
+}
+
+func NewStatic(name string, creds Credentials) *Static {
+	return &Static{
+		name:  name,
+		creds: creds,
+	}
+}
+
+func (s *Static) Name() string {
+	return s.name
+}
+
+func (s *Static) Credentials(_ context.Context, _ string) (*Credentials, error) {
+	creds := s.creds
+	return &creds, nil
+}
+
+// Fallback is a Provider which resolves credentials from a provider, falling
+// back to another provider, such as static credentials, if it fails. Fallback
+// credentials expire after fallbackExpiry, so the provider is tried again.
+type Fallback struct {
+	log      *logrus.Entry
+	provider Provider
+	fallback Provider
+}
+
+func NewFallback(log *logrus.Entry, provider, fallback Provider) *Fallback {
+	return &Fallback{
+		log:      log,
+		provider: provider,
+		fallback: fallback,
+	}
+}
+
+func (f *Fallback) Name() string {
+	return f.provider.Name()
+}
+
+func (f *Fallback) Credentials(ctx context.Context, host string) (*Credentials, error) {
+	creds, err := f.provider.Credentials(ctx, host)
+	if err == nil {
+		return creds, nil
+	}
+
+	f.log.Warnf("failed to resolve %s credentials for %s, falling back to %s credentials: %s",
+		f.provider.Name(), host, f.fallback.Name(), err)
+
+	creds, err = f.fallback.Credentials(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiry := time.Now().Add(fallbackExpiry); creds.Expiry.IsZero() || expiry.Before(creds.Expiry) {
+		creds.Expiry = expiry
+	}
+
+	return creds, nil
+}
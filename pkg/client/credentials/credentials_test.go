@@ -0,0 +1,202 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProvider struct {
+	calls  int
+	expiry time.Time
+	err    error
+}
+
+func (f *fakeProvider) Name() string {
+	return "fake"
+}
+
+func (f *fakeProvider) Credentials(_ context.Context, host string) (*Credentials, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &Credentials{Token: host, Expiry: f.expiry}, nil
+}
+
+func isSuffix(suffix string) func(string) bool {
+	return func(host string) bool {
+		return strings.HasSuffix(host, suffix)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("nil resolver should return no credentials", func(t *testing.T) {
+		var r *Resolver
+		creds, err := r.Credentials(ctx, "example.com")
+		assert.NoError(t, err)
+		assert.Nil(t, creds)
+	})
+
+	t.Run("host with no provider should return no credentials", func(t *testing.T) {
+		r := NewResolver()
+		r.Register(isSuffix(".azurecr.io"), new(fakeProvider))
+
+		creds, err := r.Credentials(ctx, "gcr.io")
+		assert.NoError(t, err)
+		assert.Nil(t, creds)
+	})
+
+	t.Run("first provider for host should be selected", func(t *testing.T) {
+		r := NewResolver()
+		first, second := new(fakeProvider), new(fakeProvider)
+		r.Register(isSuffix(".gcr.io"), NewStatic("static", Credentials{Username: "a", Password: "b"}))
+		r.Register(isSuffix(".azurecr.io"), first)
+		r.Register(isSuffix(".azurecr.io"), second)
+
+		creds, err := r.Credentials(ctx, "foo.azurecr.io")
+		require.NoError(t, err)
+		assert.Equal(t, &Credentials{Token: "foo.azurecr.io"}, creds)
+		assert.Equal(t, 1, first.calls)
+		assert.Equal(t, 0, second.calls)
+
+		creds, err = r.Credentials(ctx, "eu.gcr.io")
+		require.NoError(t, err)
+		assert.Equal(t, &Credentials{Username: "a", Password: "b"}, creds)
+	})
+
+	t.Run("credentials should be cached until they expire", func(t *testing.T) {
+		r := NewResolver()
+		noExpiry := new(fakeProvider)
+		expiring := &fakeProvider{expiry: time.Now().Add(time.Second)}
+		r.Register(isSuffix("a.io"), noExpiry)
+		r.Register(isSuffix("b.io"), expiring)
+
+		for i := 0; i < 3; i++ {
+			_, err := r.Credentials(ctx, "a.io")
+			require.NoError(t, err)
+			_, err = r.Credentials(ctx, "b.io")
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, 1, noExpiry.calls)
+		// Credentials expiring within the expiry window are resolved again.
+		assert.Equal(t, 3, expiring.calls)
+	})
+
+	t.Run("override credentials should be used over any provider, and not cached", func(t *testing.T) {
+		r := NewResolver()
+		provider := new(fakeProvider)
+		r.Register(isSuffix("a.io"), provider)
+
+		override := &Credentials{Username: "tenant", Password: "secret"}
+		creds, err := r.Credentials(WithOverride(ctx, override), "a.io")
+		require.NoError(t, err)
+		assert.Equal(t, override, creds)
+		assert.Equal(t, 0, provider.calls)
+
+		var nilResolver *Resolver
+		creds, err = nilResolver.Credentials(WithOverride(ctx, override), "b.io")
+		require.NoError(t, err)
+		assert.Equal(t, override, creds)
+
+		creds, err = r.Credentials(ctx, "a.io")
+		require.NoError(t, err)
+		assert.Equal(t, &Credentials{Token: "a.io"}, creds)
+	})
+
+	t.Run("provider errors should be returned and not cached", func(t *testing.T) {
+		r := NewResolver()
+		provider := &fakeProvider{err: errors.New("foo")}
+		r.Register(isSuffix("a.io"), provider)
+
+		_, err := r.Credentials(ctx, "a.io")
+		assert.EqualError(t, err, "failed to resolve fake credentials for a.io: foo")
+
+		_, err = r.Credentials(ctx, "a.io")
+		assert.Error(t, err)
+		assert.Equal(t, 2, provider.calls)
+	})
+}
+
+// blockingProvider is a provider which blocks resolving the credentials of
+// hosts until release is closed.
+type blockingProvider struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (b *blockingProvider) Name() string {
+	return "blocking"
+}
+
+func (b *blockingProvider) Credentials(_ context.Context, host string) (*Credentials, error) {
+	b.calls.Add(1)
+	<-b.release
+	return &Credentials{Token: host}, nil
+}
+
+func TestResolverConcurrent(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewResolver()
+	blocking := &blockingProvider{release: make(chan struct{})}
+	r.Register(isSuffix("slow.io"), blocking)
+	r.Register(isSuffix("fast.io"), new(fakeProvider))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			creds, err := r.Credentials(ctx, "slow.io")
+			assert.NoError(t, err)
+			assert.Equal(t, &Credentials{Token: "slow.io"}, creds)
+		}()
+	}
+
+	// Other hosts should be resolved while a host is being resolved
+	require.Eventually(t, func() bool {
+		return blocking.calls.Load() == 1
+	}, time.Second, time.Millisecond)
+	creds, err := r.Credentials(ctx, "fast.io")
+	require.NoError(t, err)
+	assert.Equal(t, &Credentials{Token: "fast.io"}, creds)
+
+	// Concurrent requests for the same host should wait on a single resolve
+	close(blocking.release)
+	wg.Wait()
+	assert.Equal(t, int32(1), blocking.calls.Load())
+}
+
+func TestFallback(t *testing.T) {
+	ctx := context.Background()
+	log := logrus.NewEntry(logrus.New())
+	static := NewStatic("static", Credentials{Username: "a", Password: "b"})
+
+	t.Run("provider credentials should be used if resolved", func(t *testing.T) {
+		provider := new(fakeProvider)
+		creds, err := NewFallback(log, provider, static).Credentials(ctx, "a.io")
+		require.NoError(t, err)
+		assert.Equal(t, &Credentials{Token: "a.io"}, creds)
+	})
+
+	t.Run("fallback credentials should be used, and expire, if the provider fails", func(t *testing.T) {
+		provider := &fakeProvider{err: errors.New("foo")}
+		creds, err := NewFallback(log, provider, static).Credentials(ctx, "a.io")
+		require.NoError(t, err)
+		assert.Equal(t, "a", creds.Username)
+		assert.Equal(t, "b", creds.Password)
+		assert.WithinDuration(t, time.Now().Add(fallbackExpiry), creds.Expiry, time.Second)
+	})
+}
@@ -0,0 +1,98 @@
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// dockerHubHosts are the hosts Docker Hub credentials may be configured for,
+// which are all normalised to docker.io.
+var dockerHubHosts = map[string]bool{
+	"docker.io":            true,
+	"index.docker.io":      true,
+	"registry-1.docker.io": true,
+}
+
+// DockerConfig are the credentials of registry hosts, as configured by a
+// Docker config file, such as of a kubernetes.io/dockerconfigjson secret.
+type DockerConfig map[string]Credentials
+
+type dockerConfigAuth struct {
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	Auth          string `json:"auth"`
+	RegistryToken string `json:"registrytoken"`
+}
+
+// ParseDockerConfig will parse the given Docker config, either of the
+// .dockerconfigjson format, or of the legacy .dockercfg format without the
+// auths key.
+func ParseDockerConfig(data []byte) (DockerConfig, error) {
+	var config struct {
+		Auths map[string]dockerConfigAuth `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse docker config: %s", err)
+	}
+
+	auths := config.Auths
+	if auths == nil {
+		if err := json.Unmarshal(data, &auths); err != nil {
+			return nil, fmt.Errorf("failed to parse docker config: %s", err)
+		}
+	}
+
+	dockerConfig := make(DockerConfig)
+	for host, auth := range auths {
+		creds := Credentials{
+			Username: auth.Username,
+			Password: auth.Password,
+			Token:    auth.RegistryToken,
+		}
+
+		if len(auth.Auth) > 0 {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode auth of %q: %s", host, err)
+			}
+
+			username, password, ok := strings.Cut(string(decoded), ":")
+			if !ok {
+				return nil, fmt.Errorf("auth of %q must be of the form <username>:<password>", host)
+			}
+			creds.Username, creds.Password = username, password
+		}
+
+		dockerConfig[normaliseHost(host)] = creds
+	}
+
+	return dockerConfig, nil
+}
+
+// Credentials returns the credentials of the given registry host, or nil if
+// there are none.
+func (d DockerConfig) Credentials(host string) *Credentials {
+	creds, ok := d[normaliseHost(host)]
+	if !ok {
+		return nil
+	}
+
+	return &creds
+}
+
+// normaliseHost returns the registry host of the given Docker config key,
+// which may be a URL, such as https://index.docker.io/v1/.
+func normaliseHost(host string) string {
+	if _, after, ok := strings.Cut(host, "://"); ok {
+		host = after
+	}
+	host, _, _ = strings.Cut(host, "/")
+
+	if len(host) == 0 || dockerHubHosts[host] {
+		return "docker.io"
+	}
+
+	return host
+}
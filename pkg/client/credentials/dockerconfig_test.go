@@ -0,0 +1,68 @@
+package credentials
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDockerConfig(t *testing.T) {
+	tests := map[string]struct {
+		config string
+		host   string
+		exp    *Credentials
+		expErr string
+	}{
+		"username and password should be used": {
+			config: `{"auths":{"registry.corp":{"username":"user","password":"pass"}}}`,
+			host:   "registry.corp",
+			exp:    &Credentials{Username: "user", Password: "pass"},
+		},
+		"auth should be decoded": {
+			config: `{"auths":{"registry.corp":{"auth":"dXNlcjpwYXNz"}}}`,
+			host:   "registry.corp",
+			exp:    &Credentials{Username: "user", Password: "pass"},
+		},
+		"hosts given as URLs should match": {
+			config: `{"auths":{"https://registry.corp:8443/v2/":{"username":"user","password":"pass"}}}`,
+			host:   "registry.corp:8443",
+			exp:    &Credentials{Username: "user", Password: "pass"},
+		},
+		"docker hub hosts should match docker.io": {
+			config: `{"auths":{"https://index.docker.io/v1/":{"username":"user","password":"pass"}}}`,
+			host:   "docker.io",
+			exp:    &Credentials{Username: "user", Password: "pass"},
+		},
+		"legacy configs without auths should be parsed": {
+			config: `{"registry.corp":{"username":"user","password":"pass"}}`,
+			host:   "registry.corp",
+			exp:    &Credentials{Username: "user", Password: "pass"},
+		},
+		"other hosts should have no credentials": {
+			config: `{"auths":{"registry.corp":{"username":"user","password":"pass"}}}`,
+			host:   "other.corp",
+		},
+		"invalid auth should error": {
+			config: `{"auths":{"registry.corp":{"auth":"dXNlcg=="}}}`,
+			expErr: `auth of "registry.corp" must be of the form <username>:<password>`,
+		},
+		"invalid json should error": {
+			config: `{`,
+			expErr: "failed to parse docker config: unexpected end of JSON input",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config, err := ParseDockerConfig([]byte(test.config))
+			if len(test.expErr) > 0 {
+				assert.EqualError(t, err, test.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.exp, config.Credentials(test.host))
+		})
+	}
+}
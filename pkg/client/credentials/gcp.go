@@ -0,0 +1,83 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// gcpMetadataHostEnv is used to override the GCP metadata server host.
+	gcpMetadataHostEnv = "GCE_METADATA_HOST"
+	gcpMetadataHost    = "metadata.google.internal"
+	gcpTokenPath       = "/computeMetadata/v1/instance/service-accounts/default/token"
+
+	// gcpUsername is the username registries expect when authenticating with
+	// an OAuth2 access token.
+	gcpUsername = "oauth2accesstoken"
+)
+
+// GCP is a Provider which resolves credentials from the GKE workload
+// identity, or the attached service account, via the metadata server.
+type GCP struct {
+	*http.Client
+	tokenURL string
+}
+
+type gcpTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func NewGCP() *GCP {
+	host := gcpMetadataHost
+	if env := os.Getenv(gcpMetadataHostEnv); len(env) > 0 {
+		host = env
+	}
+
+	return &GCP{
+		Client: &http.Client{
+			Timeout: time.Second * 5,
+		},
+		tokenURL: "http://" + host + gcpTokenPath,
+	}
+}
+
+func (g *GCP) Name() string {
+	return "gcp"
+}
+
+func (g *GCP) Credentials(ctx context.Context, _ string) (*Credentials, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.tokenURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := g.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request access token: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status code %d requesting access token: %s",
+			resp.StatusCode, body)
+	}
+
+	var token gcpTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("failed to decode access token response: %s", err)
+	}
+
+	return &Credentials{
+		Username: gcpUsername,
+		Password: token.AccessToken,
+		Expiry:   time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}, nil
+}
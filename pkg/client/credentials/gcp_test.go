@@ -0,0 +1,47 @@
+package credentials
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGCPCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, gcpTokenPath, r.URL.Path)
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"test-token","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer server.Close()
+
+	t.Setenv(gcpMetadataHostEnv, strings.TrimPrefix(server.URL, "http://"))
+
+	creds, err := NewGCP().Credentials(context.Background(), "gcr.io")
+	require.NoError(t, err)
+
+	assert.Equal(t, "oauth2accesstoken", creds.Username)
+	assert.Equal(t, "test-token", creds.Password)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), creds.Expiry, time.Minute)
+}
+
+func TestGCPCredentialsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("not found"))
+	}))
+	defer server.Close()
+
+	t.Setenv(gcpMetadataHostEnv, strings.TrimPrefix(server.URL, "http://"))
+
+	_, err := NewGCP().Credentials(context.Background(), "gcr.io")
+	assert.EqualError(t, err, "unexpected status code 404 requesting access token: not found")
+}
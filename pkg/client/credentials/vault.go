@@ -0,0 +1,243 @@
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultVaultAuthMount is the default mount path of the Vault Kubernetes
+	// auth method.
+	DefaultVaultAuthMount = "kubernetes"
+
+	// DefaultVaultServiceAccountTokenPath is the path of the projected
+	// service account token, used to log in to Vault.
+	DefaultVaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	// The keys of registry credentials in Vault secrets.
+	vaultUsernameKey = "username"
+	vaultPasswordKey = "password"
+	vaultTokenKey    = "token"
+)
+
+// VaultOptions are used to configure the Vault provider.
+type VaultOptions struct {
+	// Address is the address of the Vault server, e.g.
+	// https://vault.corp:8200.
+	Address string
+
+	// Role is the role of the Vault Kubernetes auth method to log in as.
+	Role string
+
+	// AuthMount is the mount path of the Kubernetes auth method. Defaults to
+	// DefaultVaultAuthMount.
+	AuthMount string
+
+	// ServiceAccountTokenPath is the path of the service account token to log
+	// in with. Defaults to DefaultVaultServiceAccountTokenPath.
+	ServiceAccountTokenPath string
+
+	// Paths are the paths of the secret holding the credentials of each
+	// registry host, e.g. secret/data/registries/harbor.
+	Paths map[string]string
+}
+
+// Vault is a Provider which resolves credentials from secrets in HashiCorp
+// Vault, logging in with the Kubernetes auth method. The Vault token is
+// reused until its lease expires, and credentials expire with the lease of
+// their secret, or with the Vault token for secrets without a lease, such as
+// in the KV version 2 engine.
+type Vault struct {
+	*http.Client
+	opts VaultOptions
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+type vaultLoginResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int64  `json:"lease_duration"`
+	} `json:"auth"`
+}
+
+type vaultSecretResponse struct {
+	LeaseDuration int64                      `json:"lease_duration"`
+	Data          map[string]json.RawMessage `json:"data"`
+}
+
+func NewVault(opts VaultOptions) *Vault {
+	if len(opts.AuthMount) == 0 {
+		opts.AuthMount = DefaultVaultAuthMount
+	}
+	if len(opts.ServiceAccountTokenPath) == 0 {
+		opts.ServiceAccountTokenPath = DefaultVaultServiceAccountTokenPath
+	}
+	opts.Address = strings.TrimSuffix(opts.Address, "/")
+
+	return &Vault{
+		Client: &http.Client{
+			Timeout: time.Second * 5,
+		},
+		opts: opts,
+	}
+}
+
+func (v *Vault) Name() string {
+	return "vault"
+}
+
+// IsHost returns true if a secret path is configured for the given host.
+func (v *Vault) IsHost(host string) bool {
+	_, ok := v.opts.Paths[host]
+	return ok
+}
+
+func (v *Vault) Credentials(ctx context.Context, host string) (*Credentials, error) {
+	path, ok := v.opts.Paths[host]
+	if !ok {
+		return nil, fmt.Errorf("no vault secret path configured for host %q", host)
+	}
+
+	token, tokenExpiry, err := v.login(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		v.opts.Address+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	var secret vaultSecretResponse
+	if err := v.do(req, &secret); err != nil {
+		return nil, fmt.Errorf("failed to read secret %q: %s", path, err)
+	}
+
+	data, err := vaultSecretData(secret.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode secret %q: %s", path, err)
+	}
+
+	creds := &Credentials{
+		Username: data[vaultUsernameKey],
+		Password: data[vaultPasswordKey],
+		Token:    data[vaultTokenKey],
+		Expiry:   tokenExpiry,
+	}
+	if len(creds.Token) == 0 && len(creds.Username) == 0 && len(creds.Password) == 0 {
+		return nil, fmt.Errorf("secret %q has no %s, %s or %s",
+			path, vaultUsernameKey, vaultPasswordKey, vaultTokenKey)
+	}
+
+	if secret.LeaseDuration > 0 {
+		if expiry := time.Now().Add(time.Duration(secret.LeaseDuration) * time.Second); creds.Expiry.IsZero() || expiry.Before(creds.Expiry) {
+			creds.Expiry = expiry
+		}
+	}
+
+	return creds, nil
+}
+
+// login will return the Vault token, and when it expires, logging in with
+// the service account token if there is no token or it is about to expire.
+func (v *Vault) login(ctx context.Context) (string, time.Time, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.token) > 0 &&
+		(v.tokenExpiry.IsZero() || time.Now().Add(expiryWindow).Before(v.tokenExpiry)) {
+		return v.token, v.tokenExpiry, nil
+	}
+
+	jwt, err := os.ReadFile(v.opts.ServiceAccountTokenPath)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to read service account token: %s", err)
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"role": v.opts.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		v.opts.Address+"/v1/auth/"+strings.Trim(v.opts.AuthMount, "/")+"/login", bytes.NewReader(body))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var login vaultLoginResponse
+	if err := v.do(req, &login); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to log in as role %q: %s", v.opts.Role, err)
+	}
+	if len(login.Auth.ClientToken) == 0 {
+		return "", time.Time{}, fmt.Errorf("no token returned logging in as role %q", v.opts.Role)
+	}
+
+	v.token = login.Auth.ClientToken
+	v.tokenExpiry = time.Time{}
+	if login.Auth.LeaseDuration > 0 {
+		v.tokenExpiry = time.Now().Add(time.Duration(login.Auth.LeaseDuration) * time.Second)
+	}
+
+	return v.token, v.tokenExpiry, nil
+}
+
+// do will send the request to Vault, decoding the response into obj.
+func (v *Vault) do(req *http.Request, obj interface{}) error {
+	resp, err := v.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(obj); err != nil {
+		return fmt.Errorf("failed to decode response: %s", err)
+	}
+
+	return nil
+}
+
+// vaultSecretData returns the string values of the given secret data. The
+// values of secrets of the KV version 2 engine are nested under data.
+func vaultSecretData(raw map[string]json.RawMessage) (map[string]string, error) {
+	if nested, ok := raw["data"]; ok {
+		if _, ok := raw["metadata"]; ok {
+			raw = nil
+			if err := json.Unmarshal(nested, &raw); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	data := make(map[string]string)
+	for key, value := range raw {
+		var s string
+		if err := json.Unmarshal(value, &s); err == nil {
+			data[key] = s
+		}
+	}
+
+	return data, nil
+}
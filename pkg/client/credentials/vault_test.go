@@ -0,0 +1,123 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVaultServer(t *testing.T, logins *int, secrets map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/kubernetes/login" {
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			if body["role"] != "version-checker" || body["jwt"] != "sa-token" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+
+			*logins++
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"vault-token","lease_duration":3600}}`))
+			return
+		}
+
+		secret, ok := secrets[r.URL.Path]
+		if !ok || r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(secret))
+	}))
+}
+
+func TestVaultCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("sa-token\n"), 0600))
+
+	var logins int
+	server := newVaultServer(t, &logins, map[string]string{
+		"/v1/secret/data/harbor": `{"lease_duration":0,"data":{"data":{"username":"robot","password":"secret"},"metadata":{"version":2}}}`,
+		"/v1/kv/quay":            `{"lease_duration":600,"data":{"token":"quay-token"}}`,
+		"/v1/kv/empty":           `{"lease_duration":600,"data":{"user":"robot"}}`,
+	})
+	defer server.Close()
+
+	vault := NewVault(VaultOptions{
+		Address:                 server.URL + "/",
+		Role:                    "version-checker",
+		ServiceAccountTokenPath: tokenPath,
+		Paths: map[string]string{
+			"harbor.corp": "secret/data/harbor",
+			"quay.corp":   "/kv/quay",
+			"empty.corp":  "kv/empty",
+			"gone.corp":   "kv/gone",
+		},
+	})
+
+	assert.True(t, vault.IsHost("harbor.corp"))
+	assert.False(t, vault.IsHost("docker.io"))
+
+	t.Run("kv version 2 secrets should expire with the vault token", func(t *testing.T) {
+		creds, err := vault.Credentials(ctx, "harbor.corp")
+		require.NoError(t, err)
+		assert.Equal(t, "robot", creds.Username)
+		assert.Equal(t, "secret", creds.Password)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), creds.Expiry, time.Minute)
+	})
+
+	t.Run("secrets with a lease should expire with the lease", func(t *testing.T) {
+		creds, err := vault.Credentials(ctx, "quay.corp")
+		require.NoError(t, err)
+		assert.Equal(t, "quay-token", creds.Token)
+		assert.WithinDuration(t, time.Now().Add(time.Minute*10), creds.Expiry, time.Minute)
+	})
+
+	t.Run("secrets without credentials should error", func(t *testing.T) {
+		_, err := vault.Credentials(ctx, "empty.corp")
+		assert.EqualError(t, err, `secret "kv/empty" has no username, password or token`)
+	})
+
+	t.Run("missing secrets should error", func(t *testing.T) {
+		_, err := vault.Credentials(ctx, "gone.corp")
+		assert.EqualError(t, err, `failed to read secret "kv/gone": unexpected status code 404: {"errors":[]}`)
+	})
+
+	t.Run("hosts without a path should error", func(t *testing.T) {
+		_, err := vault.Credentials(ctx, "docker.io")
+		assert.EqualError(t, err, `no vault secret path configured for host "docker.io"`)
+	})
+
+	// The vault token should be reused until it expires
+	assert.Equal(t, 1, logins)
+}
+
+func TestVaultCredentialsLoginError(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte("other-token"), 0600))
+
+	var logins int
+	server := newVaultServer(t, &logins, nil)
+	defer server.Close()
+
+	vault := NewVault(VaultOptions{
+		Address:                 server.URL,
+		Role:                    "version-checker",
+		ServiceAccountTokenPath: tokenPath,
+		Paths:                   map[string]string{"harbor.corp": "secret/data/harbor"},
+	})
+
+	_, err := vault.Credentials(context.Background(), "harbor.corp")
+	assert.EqualError(t, err, `failed to log in as role "version-checker": unexpected status code 403: {"errors":["permission denied"]}`)
+}
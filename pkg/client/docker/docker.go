@@ -10,7 +10,11 @@ import (
 	"strings"
 	"time"
 
+	"k8s.io/apimachinery/pkg/util/wait"
+
 	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
+	"github.com/jetstack/version-checker/pkg/client/util"
 )
 
 const (
@@ -27,6 +31,8 @@ type Options struct {
 type Client struct {
 	*http.Client
 	Options
+
+	pageBackoff wait.Backoff
 }
 
 type AuthResponse struct {
@@ -69,8 +75,9 @@ func New(ctx context.Context, opts Options) (*Client, error) {
 	}
 
 	return &Client{
-		Options: opts,
-		Client:  client,
+		Options:     opts,
+		Client:      client,
+		pageBackoff: util.DefaultPageBackoff,
 	}, nil
 }
 
@@ -78,14 +85,28 @@ func (c *Client) Name() string {
 	return "dockerhub"
 }
 
+// Tags will list the tags of the given image. Credentials set by
+// credentials.WithOverride are logged in with over those of the client.
 func (c *Client) Tags(ctx context.Context, _, repo, image string) ([]api.ImageTag, error) {
-	url := fmt.Sprintf(lookupURL, repo, image)
+	if creds, ok := credentials.Override(ctx); ok && len(creds.Token) == 0 {
+		token, err := basicAuthSetup(ctx, c.Client, Options{Username: creds.Username, Password: creds.Password})
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup auth for override credentials: %s", err)
+		}
+		ctx = credentials.WithOverride(ctx, &credentials.Credentials{Token: token})
+	}
+
+	return c.listTags(ctx, fmt.Sprintf(lookupURL, repo, image))
+}
 
+// listTags will list all image tags, following the next page URLs from the
+// given URL.
+func (c *Client) listTags(ctx context.Context, url string) ([]api.ImageTag, error) {
 	var tags []api.ImageTag
-	for url != "" {
+	err := util.Paginate(ctx, c.pageBackoff, url, func(ctx context.Context, url string) (string, bool, error) {
 		response, err := c.doRequest(ctx, url)
 		if err != nil {
-			return nil, err
+			return "", false, err
 		}
 
 		for _, result := range response.Results {
@@ -98,7 +119,7 @@ func (c *Client) Tags(ctx context.Context, _, repo, image string) ([]api.ImageTa
 			if len(result.Timestamp) > 0 {
 				timestamp, err = time.Parse(time.RFC3339Nano, result.Timestamp)
 				if err != nil {
-					return nil, fmt.Errorf("failed to parse image timestamp: %s", err)
+					return "", false, fmt.Errorf("failed to parse image timestamp: %s", err)
 				}
 			}
 
@@ -118,7 +139,10 @@ func (c *Client) Tags(ctx context.Context, _, repo, image string) ([]api.ImageTa
 			}
 		}
 
-		url = response.Next
+		return response.Next, response.Next != "", nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return tags, nil
@@ -132,13 +156,15 @@ func (c *Client) doRequest(ctx context.Context, url string) (*TagResponse, error
 
 	req.URL.Scheme = "https"
 	req = req.WithContext(ctx)
-	if len(c.Token) > 0 {
+	if creds, ok := credentials.Override(ctx); ok {
+		req.Header.Add("Authorization", "Bearer "+creds.Token)
+	} else if len(c.Token) > 0 {
 		req.Header.Add("Authorization", "Bearer "+c.Token)
 	}
 
 	resp, err := c.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get docker image: %s", err)
+		return nil, fmt.Errorf("failed to get docker image: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -147,6 +173,15 @@ func (c *Client) doRequest(ctx context.Context, url string) (*TagResponse, error
 		return nil, err
 	}
 
+	if util.IsRetriableStatusCode(resp.StatusCode) {
+		return nil, util.NewRetriableError(fmt.Errorf("unexpected status code %d for image tags response: %s",
+			resp.StatusCode, body))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, util.NewStatusError(resp.StatusCode, fmt.Errorf("unexpected status code %d for image tags response: %s",
+			resp.StatusCode, body))
+	}
+
 	response := new(TagResponse)
 	if err := json.Unmarshal(body, response); err != nil {
 		return nil, fmt.Errorf("unexpected image tags response: %s", body)
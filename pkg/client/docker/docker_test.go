@@ -0,0 +1,115 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/jetstack/version-checker/pkg/client/util"
+)
+
+func TestListTagsRetriesPage(t *testing.T) {
+	var (
+		server   *httptest.Server
+		attempts = make(map[string]int)
+	)
+
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		attempts[page]++
+
+		switch page {
+		case "1":
+			fmt.Fprintf(w, `{"next": "%s/tags?page=2", "results": [{"name": "v1.0.0", "images": [{"digest": "sha:1"}]}]}`, server.URL)
+		case "2":
+			// Fail the middle page transiently, on its first attempt
+			if attempts[page] == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte("service unavailable"))
+				return
+			}
+			fmt.Fprintf(w, `{"next": "%s/tags?page=3", "results": [{"name": "v2.0.0", "images": [{"digest": "sha:2"}]}]}`, server.URL)
+		case "3":
+			_, _ = w.Write([]byte(`{"next": "", "results": [{"name": "v3.0.0", "images": [{"digest": "sha:3"}]}]}`))
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{
+		Client:      server.Client(),
+		pageBackoff: wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 1},
+	}
+
+	tags, err := client.listTags(context.TODO(), server.URL+"/tags?page=1")
+	require.NoError(t, err)
+
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Tag)
+	}
+	assert.Equal(t, []string{"v1.0.0", "v2.0.0", "v3.0.0"}, names)
+	assert.Equal(t, map[string]int{"1": 1, "2": 2, "3": 1}, attempts)
+}
+
+func TestListTagsExhaustsRetries(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &Client{
+		Client:      server.Client(),
+		pageBackoff: wait.Backoff{Steps: 2, Duration: time.Millisecond, Factor: 1},
+	}
+
+	_, err := client.listTags(context.TODO(), server.URL+"/tags?page=1")
+	assert.EqualError(t, err, "page failed after 2 retries: unexpected status code 503 for image tags response: ")
+}
+
+func TestListTagsStatusErrors(t *testing.T) {
+	tests := map[string]struct {
+		statusCode    int
+		expNotFound   bool
+		expAuthDenied bool
+	}{
+		"a repository which does not exist should be not found": {
+			statusCode:  http.StatusNotFound,
+			expNotFound: true,
+		},
+		"an unauthorized repository should be denied": {
+			statusCode:    http.StatusUnauthorized,
+			expAuthDenied: true,
+		},
+		"a forbidden repository should be denied": {
+			statusCode:    http.StatusForbidden,
+			expAuthDenied: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(test.statusCode)
+				_, _ = w.Write([]byte(`{"message": "httperror"}`))
+			}))
+			defer server.Close()
+
+			client := &Client{
+				Client:      server.Client(),
+				pageBackoff: wait.Backoff{Steps: 2, Duration: time.Millisecond, Factor: 1},
+			}
+
+			_, err := client.listTags(context.TODO(), server.URL+"/tags?page=1")
+			assert.EqualError(t, err, fmt.Sprintf(`unexpected status code %d for image tags response: {"message": "httperror"}`, test.statusCode))
+			assert.Equal(t, test.expNotFound, util.IsNotFound(err))
+			assert.Equal(t, test.expAuthDenied, util.IsAuthDenied(err))
+		})
+	}
+}
@@ -2,14 +2,18 @@ package ecr
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
+	awscredentials "github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecr/types"
 
 	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
 	"github.com/jetstack/version-checker/pkg/client/util"
 )
 
@@ -17,6 +21,8 @@ type Client struct {
 	Config aws.Config
 
 	Options
+
+	credentials *credentials.Resolver
 }
 
 type Options struct {
@@ -26,9 +32,10 @@ type Options struct {
 	SessionToken    string
 }
 
-func New(opts Options) *Client {
+func New(opts Options, creds *credentials.Resolver) *Client {
 	return &Client{
-		Options: opts,
+		Options:     opts,
+		credentials: creds,
 	}
 }
 
@@ -45,7 +52,7 @@ func (c *Client) Tags(ctx context.Context, host, repo, image string) ([]api.Imag
 	id := matches[1]
 	region := matches[3]
 
-	client, err := c.createClient(ctx, region)
+	client, err := c.createClient(ctx, host, region)
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct ecr client for image host %s: %s",
 			host, err)
@@ -58,8 +65,13 @@ func (c *Client) Tags(ctx context.Context, host, repo, image string) ([]api.Imag
 		RegistryId:     aws.String(id),
 	})
 
+	// ECR responds 400 for repositories which do not exist
+	var notFound *types.RepositoryNotFoundException
+	if errors.As(err, &notFound) {
+		return nil, util.NewStatusError(http.StatusNotFound, fmt.Errorf("failed to describe images: %s", err))
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to describe images: %s", err)
+		return nil, fmt.Errorf("failed to describe images: %w", err)
 	}
 
 	var tags []api.ImageTag
@@ -86,20 +98,19 @@ func (c *Client) Tags(ctx context.Context, host, repo, image string) ([]api.Imag
 	return tags, nil
 }
 
-func (c *Client) createClient(ctx context.Context, region string) (*ecr.Client, error) {
-	var cfg aws.Config
-	var err error
-
-	if c.IamRoleArn != "" {
-		cfg, err = config.LoadDefaultConfig(ctx,
-			config.WithRegion(region),
-		)
-	} else {
-		cfg, err = config.LoadDefaultConfig(ctx,
-			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(c.AccessKeyID, c.SecretAccessKey, c.SessionToken)),
-			config.WithRegion(region),
-		)
+func (c *Client) createClient(ctx context.Context, host, region string) (*ecr.Client, error) {
+	creds, err := c.credentials.Credentials(ctx, host)
+	if err != nil {
+		return nil, err
 	}
+	if creds == nil {
+		creds = new(credentials.Credentials)
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx,
+		config.WithCredentialsProvider(awscredentials.NewStaticCredentialsProvider(creds.Username, creds.Password, creds.Token)),
+		config.WithRegion(region),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct aws credentials: %s", err)
 	}
@@ -13,6 +13,17 @@ func (c *Client) IsHost(host string) bool {
 	return ecrPattern.MatchString(host)
 }
 
+// Region returns the AWS region of the given ECR host, or an empty string if
+// the host is not an ECR host.
+func Region(host string) string {
+	matches := ecrPattern.FindStringSubmatch(host)
+	if len(matches) < 4 {
+		return ""
+	}
+
+	return matches[3]
+}
+
 func (c *Client) RepoImageFromPath(path string) (string, string) {
 	lastIndex := strings.LastIndex(path, "/")
 
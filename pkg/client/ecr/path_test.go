@@ -106,3 +106,36 @@ func TestRepoImage(t *testing.T) {
 		})
 	}
 }
+
+func TestRegion(t *testing.T) {
+	tests := map[string]struct {
+		host      string
+		expRegion string
+	}{
+		"an empty host should be empty": {
+			host:      "",
+			expRegion: "",
+		},
+		"non ecr host should be empty": {
+			host:      "gcr.io",
+			expRegion: "",
+		},
+		"ecr host should return region": {
+			host:      "000000000000.dkr.ecr.eu-west-2.amazonaws.com",
+			expRegion: "eu-west-2",
+		},
+		"fips ecr host in china should return region": {
+			host:      "000000000000.dkr.ecr-fips.cn-north-1.amazonaws.com.cn",
+			expRegion: "cn-north-1",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if region := Region(test.host); region != test.expRegion {
+				t.Errorf("%s: unexpected region, exp=%s got=%s",
+					test.host, test.expRegion, region)
+			}
+		})
+	}
+}
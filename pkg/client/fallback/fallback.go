@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
 	"github.com/jetstack/version-checker/pkg/client/oci"
 	"github.com/jetstack/version-checker/pkg/client/selfhosted"
 	"github.com/sirupsen/logrus"
@@ -14,8 +15,8 @@ type Client struct {
 	OCI        *oci.Client
 }
 
-func New(ctx context.Context, log *logrus.Entry) (*Client, error) {
-	sh, err := selfhosted.New(ctx, log, new(selfhosted.Options))
+func New(ctx context.Context, log *logrus.Entry, opts *selfhosted.Options) (*Client, error) {
+	sh, err := selfhosted.New(ctx, log, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -39,9 +40,28 @@ func (c *Client) Tags(ctx context.Context, host, repo, image string) ([]api.Imag
 	if tags, err := c.SelfHosted.Tags(ctx, host, repo, image); err == nil {
 		return tags, err
 	}
+
+	if c.SelfHosted.Acquire != nil {
+		release, err := c.SelfHosted.Acquire(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	if creds, ok := credentials.Override(ctx); ok {
+		return c.OCI.TagsWithAuth(ctx, host, repo, image, creds.Username, creds.Password)
+	}
+
 	return c.OCI.Tags(ctx, host, repo, image)
 }
 
+// LimitsRequests returns true if the requests of the client are limited by
+// the Acquire of the selfhosted client, including those of the OCI client.
+func (c *Client) LimitsRequests() bool {
+	return c.SelfHosted.LimitsRequests()
+}
+
 func (c *Client) IsHost(_ string) bool {
 	return true
 }
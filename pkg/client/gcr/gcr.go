@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
+	"github.com/jetstack/version-checker/pkg/client/util"
 )
 
 const (
@@ -23,6 +25,8 @@ type Options struct {
 type Client struct {
 	*http.Client
 	Options
+
+	credentials *credentials.Resolver
 }
 
 type Response struct {
@@ -34,12 +38,13 @@ type ManifestItem struct {
 	TimeCreated string   `json:"timeCreatedMs"`
 }
 
-func New(opts Options) *Client {
+func New(opts Options, creds *credentials.Resolver) *Client {
 	return &Client{
 		Options: opts,
 		Client: &http.Client{
 			Timeout: time.Second * 5,
 		},
+		credentials: creds,
 	}
 }
 
@@ -51,7 +56,7 @@ func (c *Client) Tags(ctx context.Context, host, repo, image string) ([]api.Imag
 	image = c.constructImageName(repo, image)
 	url := fmt.Sprintf(lookupURL, host, image)
 
-	req, err := c.buildRequest(ctx, url)
+	req, err := c.buildRequest(ctx, host, url)
 	if err != nil {
 		return nil, err
 	}
@@ -67,6 +72,11 @@ func (c *Client) Tags(ctx context.Context, host, repo, image string) ([]api.Imag
 		return nil, err
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, util.NewStatusError(resp.StatusCode, fmt.Errorf("unexpected status code %d for image tags response: %s",
+			resp.StatusCode, body))
+	}
+
 	var response Response
 	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, err
@@ -82,14 +92,19 @@ func (c *Client) constructImageName(repo, image string) string {
 	return image
 }
 
-func (c *Client) buildRequest(ctx context.Context, url string) (*http.Request, error) {
+func (c *Client) buildRequest(ctx context.Context, host, url string) (*http.Request, error) {
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(c.Token) > 0 {
-		req.SetBasicAuth("oauth2accesstoken", c.Token)
+	creds, err := c.credentials.Credentials(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if creds != nil {
+		req.SetBasicAuth(creds.Username, creds.Password)
 	}
 
 	return req.WithContext(ctx), nil
@@ -9,6 +9,7 @@ import (
 	"github.com/gofri/go-github-ratelimit/github_ratelimit"
 	"github.com/google/go-github/v62/github"
 	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client/util"
 )
 
 type Options struct {
@@ -56,6 +57,9 @@ func (c *Client) Tags(ctx context.Context, _, owner, repo string) ([]api.ImageTa
 	var tags []api.ImageTag
 	for {
 		versions, resp, err := getAllVersions(ctx, owner, "container", repo, opts)
+		if err != nil && resp != nil && resp.Response != nil {
+			return nil, util.NewStatusError(resp.StatusCode, fmt.Errorf("getting versions: %w", err))
+		}
 		if err != nil {
 			return nil, fmt.Errorf("getting versions: %w", err)
 		}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+
+	"github.com/jetstack/version-checker/pkg/client/acr"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
+	"github.com/jetstack/version-checker/pkg/client/docker"
+	"github.com/jetstack/version-checker/pkg/client/gcr"
+	"github.com/jetstack/version-checker/pkg/client/selfhosted"
+)
+
+const (
+	// acrTokenUsername is the username ACR refresh tokens are authenticated
+	// with as a password.
+	acrTokenUsername = "00000000-0000-0000-0000-000000000000"
+)
+
+// keychain is an authn.Keychain which authenticates with the registry of a
+// resource by the credentials of the registry client of its host, falling
+// back to the default keychain for hosts without credentials.
+type keychain struct {
+	client *Client
+}
+
+// Keychain returns a keychain for go-containerregistry remote requests, such
+// as of image manifests and configs, which authenticates with the same
+// credentials as the registry clients listing tags, including credentials set
+// by credentials.WithOverride on the request context. Repositories should be
+// referenced by the image URL returned by ResolveImageURL.
+func (c *Client) Keychain() authn.Keychain {
+	return &keychain{client: c}
+}
+
+func (k *keychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	return k.ResolveContext(context.Background(), target)
+}
+
+func (k *keychain) ResolveContext(ctx context.Context, target authn.Resource) (authn.Authenticator, error) {
+	creds, err := k.credentials(ctx, target.RegistryStr())
+	if err != nil {
+		return nil, err
+	}
+
+	if creds == nil {
+		return authn.Resolve(ctx, authn.DefaultKeychain, target)
+	}
+
+	if len(creds.Username) == 0 && len(creds.Password) == 0 {
+		return authn.FromConfig(authn.AuthConfig{RegistryToken: creds.Token}), nil
+	}
+
+	return authn.FromConfig(authn.AuthConfig{
+		Username: creds.Username,
+		Password: creds.Password,
+	}), nil
+}
+
+// credentials returns the credentials the registry client of the given host
+// authenticates with, or nil if there are none.
+func (k *keychain) credentials(ctx context.Context, host string) (*credentials.Credentials, error) {
+	if creds, ok := credentials.Override(ctx); ok {
+		return creds, nil
+	}
+
+	client, _, _ := k.client.fromImageURL(host + "/image")
+	switch client := client.(type) {
+	case *selfhosted.Client:
+		token, err := client.Token(ctx)
+		if err != nil || len(token) == 0 {
+			return nil, err
+		}
+		return &credentials.Credentials{Token: token}, nil
+
+	case *docker.Client:
+		if len(client.Username) == 0 && len(client.Password) == 0 {
+			return nil, nil
+		}
+		return &credentials.Credentials{Username: client.Username, Password: client.Password}, nil
+
+	case *acr.Client, *gcr.Client:
+		creds, err := k.client.credentials.Credentials(ctx, host)
+		if err != nil || creds == nil {
+			return nil, err
+		}
+		if _, ok := client.(*acr.Client); ok && len(creds.Token) > 0 {
+			return &credentials.Credentials{Username: acrTokenUsername, Password: creds.Token}, nil
+		}
+		return creds, nil
+	}
+
+	return nil, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/jetstack/version-checker/pkg/api"
@@ -44,12 +45,34 @@ func (c *Client) Tags(ctx context.Context, host, repo, image string) ([]api.Imag
 		return nil, fmt.Errorf("listing tags: %w", err)
 	}
 
+	return imageTags(bareTags), nil
+}
+
+// TagsWithAuth lists all the tags in the specified repository, authenticating
+// with the given username and password
+func (c *Client) TagsWithAuth(ctx context.Context, host, repo, image, username, password string) ([]api.ImageTag, error) {
+	reg, err := name.NewRegistry(host)
+	if err != nil {
+		return nil, fmt.Errorf("parsing registry host: %w", err)
+	}
+
+	auth := authn.FromConfig(authn.AuthConfig{Username: username, Password: password})
+	bareTags, err := remote.List(reg.Repo(repo, image), remote.WithContext(ctx), remote.WithAuth(auth))
+	if err != nil {
+		return nil, fmt.Errorf("listing tags: %w", err)
+	}
+
+	return imageTags(bareTags), nil
+}
+
+// imageTags returns the image tags of the given tag names
+func imageTags(bareTags []string) []api.ImageTag {
 	var tags []api.ImageTag
 	for _, t := range bareTags {
 		tags = append(tags, api.ImageTag{Tag: t})
 	}
 
-	return tags, nil
+	return tags
 }
 
 // IsHost always returns true because it supports any host
@@ -3,6 +3,7 @@ package oci
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
@@ -151,6 +152,56 @@ func TestClientTags(t *testing.T) {
 	}
 }
 
+func TestClientTagsWithAuth(t *testing.T) {
+	ctx := context.Background()
+
+	reg := registry.New()
+	r := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// Writes are not authenticated, to set up the repository
+		if username, password, ok := req.BasicAuth(); req.Method == http.MethodGet &&
+			(!ok || username != "user" || password != "pass") {
+			w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reg.ServeHTTP(w, req)
+	}))
+	t.Cleanup(r.Close)
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		t.Fatalf("unexpected error parsing registry url: %s", err)
+	}
+
+	repo, err := name.NewRepository(fmt.Sprintf("%s/foo/bar", u.Host))
+	if err != nil {
+		t.Fatalf("unexpected error parsing repo: %s", err)
+	}
+	if err := remote.Write(repo.Tag("a"), empty.Image); err != nil {
+		t.Fatalf("unexpected error writing image to tag: %s", err)
+	}
+
+	c, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error creating client: %s", err)
+	}
+
+	if _, err := c.Tags(ctx, u.Host, "foo", "bar"); err == nil {
+		t.Errorf("unexpected nil error listing tags without credentials")
+	}
+
+	if _, err := c.TagsWithAuth(ctx, u.Host, "foo", "bar", "user", "wrong"); err == nil {
+		t.Errorf("unexpected nil error listing tags with the wrong credentials")
+	}
+
+	gotTags, err := c.TagsWithAuth(ctx, u.Host, "foo", "bar", "user", "pass")
+	if err != nil {
+		t.Fatalf("unexpected error listing tags: %s", err)
+	}
+	if diff := cmp.Diff([]api.ImageTag{{Tag: "a"}}, gotTags); diff != "" {
+		t.Errorf("unexpected tags:\n%s", diff)
+	}
+}
+
 func TestClientRepoImageFromPath(t *testing.T) {
 	tests := map[string]struct {
 		path              string
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
@@ -169,6 +170,12 @@ func (c *Client) makeRequest(ctx context.Context, url string, obj interface{}) e
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return util.NewStatusError(resp.StatusCode, fmt.Errorf("unexpected status code %d for quay call %q: %s",
+			resp.StatusCode, url, body))
+	}
+
 	if err := json.NewDecoder(resp.Body).Decode(obj); err != nil {
 		return err
 	}
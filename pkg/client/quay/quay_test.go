@@ -0,0 +1,60 @@
+package quay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jetstack/version-checker/pkg/client/util"
+)
+
+func TestMakeRequestStatusErrors(t *testing.T) {
+	tests := map[string]struct {
+		statusCode    int
+		expNotFound   bool
+		expAuthDenied bool
+	}{
+		"a repository which does not exist should be not found": {
+			statusCode:  http.StatusNotFound,
+			expNotFound: true,
+		},
+		"an unauthorized repository should be denied": {
+			statusCode:    http.StatusUnauthorized,
+			expAuthDenied: true,
+		},
+		"a forbidden repository should be denied": {
+			statusCode:    http.StatusForbidden,
+			expAuthDenied: true,
+		},
+		"a server error should be neither": {
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(test.statusCode)
+				_, _ = w.Write([]byte(`{"error_message": "httperror"}`))
+			}))
+			defer server.Close()
+
+			client := New(Options{})
+			client.HTTPClient = server.Client()
+			client.RetryMax = 0
+			client.ErrorHandler = retryablehttp.PassthroughErrorHandler
+
+			url := server.URL + "/api/v1/repository/jetstack/version-checker/tag/?page=1"
+			var resp responseTag
+			err := client.makeRequest(context.TODO(), url, &resp)
+			assert.EqualError(t, err, fmt.Sprintf(`unexpected status code %d for quay call %q: {"error_message": "httperror"}`, test.statusCode, url))
+			assert.Equal(t, test.expNotFound, util.IsNotFound(err))
+			assert.Equal(t, test.expAuthDenied, util.IsAuthDenied(err))
+		})
+	}
+}
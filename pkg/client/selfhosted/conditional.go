@@ -0,0 +1,150 @@
+package selfhosted
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	selfhostederrors "github.com/jetstack/version-checker/pkg/client/selfhosted/errors"
+)
+
+// manifestCacheSize is the number of manifest responses cached by each
+// client, by default.
+const manifestCacheSize = 10000
+
+// manifestCache stores the last manifest response of each manifest URL and
+// Accept header, with the validator the registry returned for it. Once full,
+// the least recently used manifest is evicted, so that the manifests of tags
+// and images which are no longer checked are not kept forever.
+type manifestCache struct {
+	mu sync.Mutex
+	// size is the maximum number of cached manifests, or manifestCacheSize
+	// if 0.
+	size      int
+	manifests map[string]*list.Element
+	// lru orders the cached manifests from most to least recently used.
+	lru *list.List
+}
+
+type cachedManifest struct {
+	key       string
+	validator string
+	header    http.Header
+	body      []byte
+}
+
+func (m *manifestCache) get(key string) (cachedManifest, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.manifests[key]
+	if !ok {
+		return cachedManifest{}, false
+	}
+
+	m.lru.MoveToFront(elem)
+	return elem.Value.(cachedManifest), true
+}
+
+func (m *manifestCache) set(key string, cached cachedManifest) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.manifests == nil {
+		m.manifests = make(map[string]*list.Element)
+		m.lru = list.New()
+	}
+
+	cached.key = key
+	if elem, ok := m.manifests[key]; ok {
+		elem.Value = cached
+		m.lru.MoveToFront(elem)
+		return
+	}
+	m.manifests[key] = m.lru.PushFront(cached)
+
+	size := m.size
+	if size <= 0 {
+		size = manifestCacheSize
+	}
+	for m.lru.Len() > size {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.manifests, oldest.Value.(cachedManifest).key)
+	}
+}
+
+// manifestValidator returns the validator to send as If-None-Match on later
+// requests for the manifest, preferring the ETag over the content digest.
+// Returns empty if the registry returned neither.
+func manifestValidator(header http.Header) string {
+	if etag := header.Get("ETag"); len(etag) > 0 {
+		return etag
+	}
+
+	if digest := header.Get("Docker-Content-Digest"); len(digest) > 0 {
+		return fmt.Sprintf("%q", digest)
+	}
+
+	return ""
+}
+
+// doManifestRequest will request the manifest at the given URL, as
+// doRequest. If the manifest was previously fetched with a validator, the
+// request is made conditional on the manifest having changed, and the cached
+// manifest is reused if the registry responds that it is not modified.
+func (c *Client) doManifestRequest(ctx context.Context, url, header string, obj interface{}) (http.Header, error) {
+	key := header + " " + url
+	cached, isCached := c.manifests.get(key)
+
+	req, err := c.newRequest(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+	if isCached {
+		req.Header.Set("If-None-Match", cached.validator)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker image: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	respHeader := resp.Header
+	switch {
+	case resp.StatusCode == http.StatusNotModified && isCached:
+		c.log.Debugf("%s: manifest not modified, using cached manifest", req.URL)
+		if c.Metrics != nil {
+			c.Metrics.IncConditionalHits(c.Name())
+		}
+		respHeader, body = cached.header, cached.body
+
+	case resp.StatusCode != http.StatusOK:
+		return nil, selfhostederrors.NewHTTPError(resp.StatusCode, body)
+
+	default:
+		if validator := manifestValidator(resp.Header); len(validator) > 0 {
+			c.manifests.set(key, cachedManifest{
+				validator: validator,
+				header:    resp.Header,
+				body:      body,
+			})
+		}
+	}
+
+	if err := json.Unmarshal(body, obj); err != nil {
+		return nil, fmt.Errorf("unexpected %s response: %s", req.URL, body)
+	}
+
+	return respHeader, nil
+}
@@ -0,0 +1,156 @@
+package selfhosted
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func TestDoManifestRequest(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	ctx := context.Background()
+
+	reg := prometheus.NewRegistry()
+	m := metrics.New(log, reg, metrics.Options{})
+
+	newClient := func() *Client {
+		return &Client{
+			Client: &http.Client{},
+			Options: &Options{
+				Host:    "testhost",
+				Metrics: m,
+			},
+			log:        log,
+			httpScheme: "http",
+		}
+	}
+
+	t.Run("not modified manifests should reuse the cached manifest", func(t *testing.T) {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if r.Header.Get("If-None-Match") == `"sha256:abcdef"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			assert.Empty(t, r.Header.Get("If-None-Match"))
+			w.Header().Set("ETag", `"sha256:abcdef"`)
+			w.Header().Set("Docker-Content-Digest", "sha256:abcdef")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"architecture":"amd64"}`))
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		client := newClient()
+		manifestURL := h.Host + "/v2/repo/image/manifests/v1"
+
+		for range 2 {
+			var manifest ManifestResponse
+			header, err := client.doManifestRequest(ctx, manifestURL, dockerAPIv2Header, &manifest)
+			assert.NoError(t, err)
+			assert.Equal(t, "sha256:abcdef", header.Get("Docker-Content-Digest"))
+			assert.Equal(t, "amd64", string(manifest.Architecture))
+		}
+
+		assert.Equal(t, 2, requests)
+		assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP version_checker_conditional_hits_total Number of manifest requests answered as not modified by the registry, reusing the cached manifest
+# TYPE version_checker_conditional_hits_total counter
+version_checker_conditional_hits_total{host="testhost"} 1
+`), "version_checker_conditional_hits_total"))
+	})
+
+	t.Run("the content digest should be used without an ETag", func(t *testing.T) {
+		var ifNoneMatch []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+			w.Header().Set("Docker-Content-Digest", "sha256:abcdef")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		client := newClient()
+		for range 2 {
+			_, err := client.doManifestRequest(ctx, h.Host+"/v2/repo/image/manifests/v1", dockerAPIv2Header, new(ManifestResponse))
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, []string{"", `"sha256:abcdef"`}, ifNoneMatch)
+	})
+
+	t.Run("manifests without a validator should always be fetched", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Empty(t, r.Header.Get("If-None-Match"))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		client := newClient()
+		for range 2 {
+			_, err := client.doManifestRequest(ctx, h.Host+"/v2/repo/image/manifests/v1", dockerAPIv2Header, new(ManifestResponse))
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("the least recently used manifest should be evicted once the cache is full", func(t *testing.T) {
+		ifNoneMatch := make(map[string][]string)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ifNoneMatch[r.URL.Path] = append(ifNoneMatch[r.URL.Path], r.Header.Get("If-None-Match"))
+			w.Header().Set("ETag", `"`+r.URL.Path+`"`)
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		client := newClient()
+		client.manifests.size = 2
+		for _, tag := range []string{"v1", "v2", "v1", "v3", "v1", "v2"} {
+			_, err := client.doManifestRequest(ctx, h.Host+"/v2/repo/image/manifests/"+tag, dockerAPIv2Header, new(ManifestResponse))
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, map[string][]string{
+			"/v2/repo/image/manifests/v1": {"", `"/v2/repo/image/manifests/v1"`, `"/v2/repo/image/manifests/v1"`},
+			"/v2/repo/image/manifests/v2": {"", ""},
+			"/v2/repo/image/manifests/v3": {""},
+		}, ifNoneMatch)
+		assert.Equal(t, 2, client.manifests.lru.Len())
+	})
+
+	t.Run("not modified without a cached manifest should error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotModified)
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		_, err = newClient().doManifestRequest(ctx, h.Host+"/v2/repo/image/manifests/v1", dockerAPIv2Header, new(ManifestResponse))
+		assert.Error(t, err)
+	})
+}
@@ -8,10 +8,18 @@ import (
 )
 
 const (
-	// Regex template to be used to check "isHost".
-	hostRegTemplate = `^.*%s$`
+	// Regex template to be used to check "isHost", of the host name and
+	// port.
+	hostRegTemplate = `^.*%s%s$`
 )
 
+// defaultPorts are the ports of each scheme which may be omitted from image
+// references.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
 func (c *Client) IsHost(host string) bool {
 	return c.hostRegex.MatchString(host)
 }
@@ -31,18 +39,29 @@ func (c *Client) RepoImageFromPath(path string) (string, string) {
 	return path, ""
 }
 
-func parseURL(rawurl string) (*regexp.Regexp, string, error) {
+// parseURL will parse the given registry URL, returning the regex matching
+// its host and port, its scheme, and the path prefix of its API, without a
+// trailing slash. Hosts without a port, or with the default port of the
+// scheme, match with or without the default port.
+func parseURL(rawurl string) (*regexp.Regexp, string, string, error) {
 	parsedURL, err := url.Parse(rawurl)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed parsing host %q: %s", rawurl, err)
+		return nil, "", "", fmt.Errorf("failed parsing host %q: %s", rawurl, err)
+	}
+
+	port := parsedURL.Port()
+	if defaultPort, ok := defaultPorts[parsedURL.Scheme]; ok && (len(port) == 0 || port == defaultPort) {
+		port = fmt.Sprintf("(:%s)?", defaultPort)
+	} else if len(port) > 0 {
+		port = ":" + port
 	}
 
-	hostRegTemplate := fmt.Sprintf(hostRegTemplate, parsedURL.Host)
+	hostRegTemplate := fmt.Sprintf(hostRegTemplate, regexp.QuoteMeta(parsedURL.Hostname()), port)
 	hostRegex, err := regexp.Compile(hostRegTemplate)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to parse regex: %s for host %q: %s",
+		return nil, "", "", fmt.Errorf("failed to parse regex: %s for host %q: %s",
 			hostRegTemplate, parsedURL.Host, err)
 	}
 
-	return hostRegex, parsedURL.Scheme, nil
+	return hostRegex, parsedURL.Scheme, strings.TrimSuffix(parsedURL.Path, "/"), nil
 }
@@ -85,6 +85,69 @@ func TestIsHost(t *testing.T) {
 	}
 }
 
+func TestIsHostPort(t *testing.T) {
+	tests := map[string]struct {
+		registryHost string
+		host         string
+		expIs        bool
+	}{
+		"a host without a port should match without a port": {
+			registryHost: "https://registry.corp",
+			host:         "registry.corp",
+			expIs:        true,
+		},
+		"a host without a port should match the default port": {
+			registryHost: "https://registry.corp",
+			host:         "registry.corp:443",
+			expIs:        true,
+		},
+		"a host without a port should not match another port": {
+			registryHost: "https://registry.corp",
+			host:         "registry.corp:8443",
+			expIs:        false,
+		},
+		"a host with the default port should match without a port": {
+			registryHost: "http://registry.corp:80",
+			host:         "registry.corp",
+			expIs:        true,
+		},
+		"a host with a port should match the port": {
+			registryHost: "https://registry.corp:8443/v2base",
+			host:         "registry.corp:8443",
+			expIs:        true,
+		},
+		"a host with a port should not match without the port": {
+			registryHost: "https://registry.corp:8443/v2base",
+			host:         "registry.corp",
+			expIs:        false,
+		},
+		"a host with a port should not match another port": {
+			registryHost: "https://registry.corp:8443",
+			host:         "registry.corp:5000",
+			expIs:        false,
+		},
+		"dots of the host should not match any character": {
+			registryHost: "https://registry.corp",
+			host:         "registryxcorp",
+			expIs:        false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			handler, err := New(context.TODO(), logrus.NewEntry(logrus.New()), &Options{Host: test.registryHost})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if isHost := handler.IsHost(test.host); isHost != test.expIs {
+				t.Errorf("%s: unexpected IsHost, exp=%t got=%t",
+					test.host, test.expIs, isHost)
+			}
+		})
+	}
+}
+
 func TestRepoImage(t *testing.T) {
 	tests := map[string]struct {
 		path              string
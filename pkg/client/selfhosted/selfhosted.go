@@ -1,6 +1,7 @@
 package selfhosted
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -9,16 +10,22 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
 	selfhostederrors "github.com/jetstack/version-checker/pkg/client/selfhosted/errors"
 	"github.com/jetstack/version-checker/pkg/client/util"
+	"github.com/jetstack/version-checker/pkg/metrics"
 )
 
 const (
@@ -32,6 +39,13 @@ const (
 	// HTTP headers to request API version
 	dockerAPIv1Header = "application/vnd.docker.distribution.manifest.v1+json"
 	dockerAPIv2Header = "application/vnd.docker.distribution.manifest.v2+json"
+	ociManifestHeader = "application/vnd.oci.image.manifest.v1+json"
+
+	// Media types of the config of manifests, identifying the type of the
+	// artifact.
+	dockerConfigMediaType     = "application/vnd.docker.container.image.v1+json"
+	ociConfigMediaType        = "application/vnd.oci.image.config.v1+json"
+	helmConfigMediaTypePrefix = "application/vnd.cncf.helm.config"
 )
 
 type Options struct {
@@ -42,6 +56,44 @@ type Options struct {
 	TokenPath string
 	Insecure  bool
 	CAPath    string
+
+	// ServerName overrides the server name used to verify the certificate of
+	// the registry, and sent as SNI, for registries which are connected to on
+	// an address their certificate is not valid for.
+	ServerName string
+
+	// PageTokenPath is the dot separated path to the cursor token of the next
+	// page in the tags list response, for registries which paginate with
+	// cursor tokens rather than Link headers.
+	PageTokenPath string
+	// PageTokenParam is the query parameter the cursor token is sent as.
+	// Defaults to the last element of PageTokenPath.
+	PageTokenParam string
+
+	// Headers are static headers sent on all requests to the registry, such
+	// as the API key of a registry with non-standard authentication.
+	Headers http.Header
+
+	// Metrics, if set, is used to count the manifest requests answered as
+	// not modified.
+	Metrics *metrics.Metrics
+
+	// Credentials, if set, resolves the credentials of the registry by the
+	// host and port of Host, which are used over the static credentials.
+	// Usernames and passwords are exchanged for a token with the token
+	// endpoint.
+	Credentials *credentials.Resolver
+
+	// ManifestWorkers, if set, returns the number of manifests of the tags
+	// of an image on the given host which are fetched concurrently. The
+	// manifests are fetched one at a time if unset.
+	ManifestWorkers func(host string) int
+
+	// Acquire, if set, blocks until a request to the given host may be made,
+	// returning the func to call once the request is complete. The tags list,
+	// and the manifests of each tag, are requested under it, so that the
+	// concurrency limit of the host holds across manifest workers.
+	Acquire func(ctx context.Context, host string) (func(), error)
 }
 
 type Client struct {
@@ -50,8 +102,22 @@ type Client struct {
 
 	log *logrus.Entry
 
-	hostRegex  *regexp.Regexp
-	httpScheme string
+	hostRegex   *regexp.Regexp
+	httpScheme  string
+	pathPrefix  string
+	pageBackoff wait.Backoff
+
+	// manifests caches manifest responses, to make conditional requests
+	// for manifests which have already been fetched.
+	manifests manifestCache
+
+	// credentialsHost is the host credentials are resolved for. The
+	// resolved credentials are cached with the token they were exchanged
+	// for.
+	credentialsHost string
+	credentialsMu   sync.Mutex
+	resolvedCreds   *credentials.Credentials
+	resolvedBearer  string
 }
 
 type AuthResponse struct {
@@ -66,6 +132,13 @@ type ManifestResponse struct {
 	Digest       string
 	Architecture api.Architecture `json:"architecture"`
 	History      []History        `json:"history"`
+
+	// ArtifactType and Config are of schema v2 and OCI manifests, where the
+	// artifact type is only set for OCI artifacts.
+	ArtifactType string `json:"artifactType"`
+	Config       struct {
+		MediaType string `json:"mediaType"`
+	} `json:"config"`
 }
 
 type History struct {
@@ -73,7 +146,9 @@ type History struct {
 }
 
 type V1Compatibility struct {
-	Created time.Time `json:"created,omitempty"`
+	Created      time.Time        `json:"created,omitempty"`
+	OS           api.OS           `json:"os,omitempty"`
+	Architecture api.Architecture `json:"architecture,omitempty"`
 }
 
 func New(ctx context.Context, log *logrus.Entry, opts *Options) (*Client, error) {
@@ -81,8 +156,19 @@ func New(ctx context.Context, log *logrus.Entry, opts *Options) (*Client, error)
 		Client: &http.Client{
 			Timeout: time.Second * 10,
 		},
-		Options: opts,
-		log:     log.WithField("client", opts.Host),
+		Options:     opts,
+		log:         log.WithField("client", opts.Host),
+		pageBackoff: util.DefaultPageBackoff,
+	}
+
+	if len(opts.Headers) > 0 {
+		// Only the names are logged, since values are often secrets.
+		names := make([]string, 0, len(opts.Headers))
+		for name := range opts.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		client.log.Debugf("sending static headers %v on all requests", names)
 	}
 
 	if err := configureHost(ctx, client, opts); err != nil {
@@ -101,12 +187,16 @@ func configureHost(ctx context.Context, client *Client, opts *Options) error {
 		return nil
 	}
 
-	hostRegex, scheme, err := parseURL(opts.Host)
+	hostRegex, scheme, pathPrefix, err := parseURL(opts.Host)
 	if err != nil {
 		return fmt.Errorf("failed parsing url: %s", err)
 	}
 	client.hostRegex = hostRegex
 	client.httpScheme = scheme
+	client.pathPrefix = pathPrefix
+	if u, err := url.Parse(opts.Host); err == nil {
+		client.credentialsHost = u.Host
+	}
 
 	if err := configureAuth(ctx, client, opts); err != nil {
 		return err
@@ -124,12 +214,7 @@ func configureAuth(ctx context.Context, client *Client, opts *Options) error {
 		return errors.New("cannot specify Bearer token as well as username/password")
 	}
 
-	tokenPath := opts.TokenPath
-	if tokenPath == "" {
-		tokenPath = defaultTokenPath
-	}
-
-	token, err := client.setupBasicAuth(ctx, opts.Host, tokenPath)
+	token, err := client.setupBasicAuth(ctx, opts.Host, client.tokenPath())
 	if httpErr, ok := selfhostederrors.IsHTTPError(err); ok {
 		return fmt.Errorf("failed to setup token auth (%d): %s",
 			httpErr.StatusCode, httpErr.Body)
@@ -148,7 +233,7 @@ func configureTLS(client *Client, opts *Options) error {
 	}
 
 	if client.httpScheme == "https" {
-		tlsConfig, err := newTLSConfig(opts.Insecure, opts.CAPath)
+		tlsConfig, err := newTLSConfig(opts.Insecure, opts.CAPath, opts.ServerName)
 		if err != nil {
 			return err
 		}
@@ -171,83 +256,379 @@ func (c *Client) Name() string {
 	return c.Host
 }
 
+// LimitsRequests returns true if the requests of the client are limited by
+// Acquire, rather than around the whole of Tags.
+func (c *Client) LimitsRequests() bool {
+	return c.Acquire != nil
+}
+
 // Tags will fetch the image tags from a given image URL. It must first query
 // the tags that are available, then query the 2.1 and 2.2 API endpoints to
-// gather the image digest and created time.
+// gather the image digest and created time. The manifests of tags are fetched
+// by a pool of ManifestWorkers, with the tags returned in the order they were
+// listed regardless of the order their manifests are fetched in. The API is
+// requested under the path prefix of the registry host, if any.
 func (c *Client) Tags(ctx context.Context, host, repo, image string) ([]api.ImageTag, error) {
 	path := util.JoinRepoImage(repo, image)
-	tagURL := fmt.Sprintf(tagsPath, host, path)
 
-	var tagResponse TagResponse
-	if _, err := c.doRequest(ctx, tagURL, "", &tagResponse); err != nil {
+	ctx, err := c.exchangeOverride(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	var tags []api.ImageTag
-	for _, tag := range tagResponse.Tags {
-		manifestURL := fmt.Sprintf(manifestPath, host, path, tag)
+	workers := 1
+	if c.ManifestWorkers != nil {
+		workers = max(c.ManifestWorkers(host), 1)
+	}
+	acquire := func() (func(), error) {
+		if c.Acquire == nil {
+			return func() {}, nil
+		}
+		return c.Acquire(ctx, host)
+	}
+	host += c.pathPrefix
 
-		var manifestResponse ManifestResponse
-		_, err := c.doRequest(ctx, manifestURL, dockerAPIv1Header, &manifestResponse)
+	release, err := acquire()
+	if err != nil {
+		return nil, err
+	}
+	tagNames, err := c.listTags(ctx, host, path)
+	release()
+	if err != nil {
+		return nil, err
+	}
 
-		if httpErr, ok := selfhostederrors.IsHTTPError(err); ok {
-			c.log.Errorf("%s: failed to get manifest response for tag, skipping (%d): %s",
-				manifestURL, httpErr.StatusCode, httpErr.Body)
-			continue
+	results := make([]*api.ImageTag, len(tagNames))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, tag := range tagNames {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
 		}
-		if err != nil {
-			return nil, err
+		if ctx.Err() != nil {
+			break
 		}
 
-		var timestamp time.Time
-		for _, v1History := range manifestResponse.History {
-			data := V1Compatibility{}
-			if err := json.Unmarshal([]byte(v1History.V1Compatibility), &data); err != nil {
-				return nil, err
-			}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
 
-			if !data.Created.IsZero() {
-				timestamp = data.Created
-				// Each layer has its own created timestamp. We just want a general reference.
-				// Take the first and step out the loop
-				break
+			release, err := acquire()
+			if err != nil {
+				return
 			}
+			defer release()
+
+			results[i] = c.tagManifest(ctx, host, path, tag)
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get manifests of tags: %s", err)
+	}
+
+	var tags []api.ImageTag
+	for _, tag := range results {
+		if tag != nil {
+			tags = append(tags, *tag)
 		}
+	}
 
-		header, err := c.doRequest(ctx, manifestURL, dockerAPIv2Header, new(ManifestResponse))
-		if httpErr, ok := selfhostederrors.IsHTTPError(err); ok {
+	return tags, nil
+}
+
+// tagManifest will return the given tag, with the digest, created time, and
+// platform of its manifest. Nil is returned for tags the registry fails to
+// serve a manifest for, which are skipped. Tags whose manifest fails to be
+// fetched otherwise are returned by their name only, with the error, so that
+// the failure only affects the result of images for which the tag is the
+// latest.
+func (c *Client) tagManifest(ctx context.Context, host, path, tag string) *api.ImageTag {
+	manifestURL := fmt.Sprintf(manifestPath, host, path, tag)
+	nameOnly := func(err error) *api.ImageTag {
+		c.log.Errorf("%s: failed to get manifest for tag, using its name only: %s", manifestURL, err)
+		return &api.ImageTag{Tag: tag, ManifestError: err.Error()}
+	}
+
+	var manifestResponse ManifestResponse
+	v1Header, err := c.doManifestRequest(ctx, manifestURL, dockerAPIv1Header, &manifestResponse)
+
+	httpErr, ok := selfhostederrors.IsHTTPError(err)
+	switch {
+	// Artifacts which are not images, such as Helm charts, have no schema
+	// v1 manifest, so are only requested as v2 manifests.
+	case ok && isUnsupportedManifest(httpErr.StatusCode):
+		c.log.Debugf("%s: registry does not serve a schema v1 manifest (%d), using v2 manifest",
+			manifestURL, httpErr.StatusCode)
+		v1Header = nil
+	case ok:
+		c.log.Errorf("%s: failed to get manifest response for tag, skipping (%d): %s",
+			manifestURL, httpErr.StatusCode, httpErr.Body)
+		return nil
+	case err != nil:
+		return nameOnly(err)
+	}
+
+	timestamp, imageOS, arch, err := v1Platform(&manifestResponse)
+	if err != nil {
+		return nameOnly(err)
+	}
+
+	var v2Manifest ManifestResponse
+	header, err := c.doManifestRequest(ctx, manifestURL, dockerAPIv2Header+", "+ociManifestHeader, &v2Manifest)
+	if httpErr, ok := selfhostederrors.IsHTTPError(err); ok {
+		if !isUnsupportedManifest(httpErr.StatusCode) || v1Header == nil {
 			c.log.Errorf("%s: failed to get manifest sha response for tag, skipping (%d): %s",
 				manifestURL, httpErr.StatusCode, httpErr.Body)
-			continue
+			return nil
 		}
+
+		// Legacy registries which only serve schema v1 manifests fail v2
+		// negotiation, so fall back to the digest of the v1 manifest.
+		c.log.Debugf("%s: registry does not serve v2 manifests (%d), using schema v1 manifest",
+			manifestURL, httpErr.StatusCode)
+		header = v1Header
+	} else if err != nil {
+		return nameOnly(err)
+	}
+
+	return &api.ImageTag{
+		Tag:          tag,
+		SHA:          header.Get("Docker-Content-Digest"),
+		Timestamp:    timestamp,
+		OS:           imageOS,
+		Architecture: arch,
+		ArtifactType: artifactType(&v2Manifest),
+	}
+}
+
+// listTags will list the tags of the given image, following every page of
+// the listing. Pages are followed by the Link header, or by the cursor token
+// of the response body if a PageTokenPath is configured.
+func (c *Client) listTags(ctx context.Context, host, path string) ([]string, error) {
+	tagURL := fmt.Sprintf(tagsPath, host, path)
+
+	var (
+		tags []string
+		seen = map[string]bool{tagURL: true}
+	)
+
+	err := util.Paginate(ctx, c.pageBackoff, tagURL, func(ctx context.Context, tagURL string) (string, bool, error) {
+		var body json.RawMessage
+		header, err := c.doRequest(ctx, tagURL, "", &body)
 		if err != nil {
-			return nil, err
+			return "", false, err
 		}
 
-		tags = append(tags, api.ImageTag{
-			Tag:          tag,
-			SHA:          header.Get("Docker-Content-Digest"),
-			Timestamp:    timestamp,
-			Architecture: manifestResponse.Architecture,
-		})
+		var tagResponse TagResponse
+		if err := json.Unmarshal(body, &tagResponse); err != nil {
+			return "", false, fmt.Errorf("unexpected %s response: %s", tagURL, body)
+		}
+		tags = append(tags, tagResponse.Tags...)
+
+		next, err := c.nextTagsPage(tagURL, header, body)
+		if err != nil || len(next) == 0 {
+			return "", false, err
+		}
+
+		// Guard against registries which return the same cursor forever.
+		if seen[next] {
+			return "", false, fmt.Errorf("%s: tags list returned an already fetched page: %s", tagURL, next)
+		}
+		seen[next] = true
+
+		return next, true, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return tags, nil
 }
 
-func (c *Client) doRequest(ctx context.Context, url, header string, obj interface{}) (http.Header, error) {
-	url = fmt.Sprintf("%s://%s", c.httpScheme, url)
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+// nextTagsPage returns the URL, without scheme, of the page of tags after the
+// given page, or empty if the given page is the last.
+func (c *Client) nextTagsPage(tagURL string, header http.Header, body []byte) (string, error) {
+	current, err := url.Parse(fmt.Sprintf("%s://%s", c.httpScheme, tagURL))
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	req = req.WithContext(ctx)
-	if len(c.Bearer) > 0 {
-		req.Header.Add("Authorization", "Bearer "+c.Bearer)
+	if link := nextLink(header); len(link) > 0 {
+		ref, err := url.Parse(link)
+		if err != nil {
+			return "", fmt.Errorf("%s: failed to parse next Link %q: %s", tagURL, link, err)
+		}
+
+		// Requests carry the credentials and headers of the registry, so are
+		// never sent to another host.
+		next := current.ResolveReference(ref)
+		if next.Host != current.Host {
+			return "", fmt.Errorf("%s: next Link %q is not of the registry host %q", tagURL, link, current.Host)
+		}
+		return next.Host + next.RequestURI(), nil
 	}
-	if len(header) > 0 {
-		req.Header.Set("Accept", header)
+
+	if len(c.PageTokenPath) == 0 {
+		return "", nil
+	}
+
+	token, err := lookupPageToken(body, c.PageTokenPath)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", tagURL, err)
+	}
+	if len(token) == 0 {
+		return "", nil
+	}
+
+	param := c.PageTokenParam
+	if len(param) == 0 {
+		param = c.PageTokenPath[strings.LastIndex(c.PageTokenPath, ".")+1:]
+	}
+
+	query := current.Query()
+	query.Set(param, token)
+	current.RawQuery = query.Encode()
+
+	return current.Host + current.RequestURI(), nil
+}
+
+// nextLink returns the target of the RFC 5988 Link header with the "next"
+// relation, or empty if there is none.
+func nextLink(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, params, _ := strings.Cut(link, ";")
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range strings.Split(params, ";") {
+				key, rels, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(key, "rel") {
+					continue
+				}
+
+				for _, rel := range strings.Fields(strings.Trim(rels, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						return strings.TrimSuffix(strings.TrimPrefix(target, "<"), ">")
+					}
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// lookupPageToken returns the cursor token at the given dot separated path of
+// the JSON body. An empty token is returned if the path is not present, or
+// is null.
+func lookupPageToken(body []byte, path string) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return "", fmt.Errorf("failed to decode page token: %s", err)
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return "", nil
+		}
+		value = obj[key]
+	}
+
+	switch token := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return token, nil
+	case json.Number:
+		return token.String(), nil
+	default:
+		return "", fmt.Errorf("page token at %q is not a string: %v", path, token)
+	}
+}
+
+// v1Platform returns the created time, OS and architecture of the given
+// schema v1 manifest. Schema v1 manifests record the architecture of the
+// image, and the history of each layer may record the created time, OS and
+// architecture, so the platform is often only partially known.
+func v1Platform(manifest *ManifestResponse) (time.Time, api.OS, api.Architecture, error) {
+	var (
+		timestamp time.Time
+		imageOS   api.OS
+		arch      = manifest.Architecture
+	)
+
+	for _, v1History := range manifest.History {
+		data := V1Compatibility{}
+		if err := json.Unmarshal([]byte(v1History.V1Compatibility), &data); err != nil {
+			return time.Time{}, "", "", err
+		}
+
+		if len(imageOS) == 0 {
+			imageOS = data.OS
+		}
+		if len(arch) == 0 {
+			arch = data.Architecture
+		}
+
+		if !data.Created.IsZero() {
+			timestamp = data.Created
+			// Each layer has its own created timestamp. We just want a general reference.
+			// Take the first and step out the loop
+			break
+		}
+	}
+
+	return timestamp, imageOS, arch, nil
+}
+
+// artifactType returns the type of the artifact of the given v2 manifest, by
+// its artifact type, or the media type of its config. Empty if the manifest
+// reports neither.
+func artifactType(manifest *ManifestResponse) api.ArtifactType {
+	mediaType := manifest.ArtifactType
+	if len(mediaType) == 0 {
+		mediaType = manifest.Config.MediaType
+	}
+
+	switch {
+	case len(mediaType) == 0:
+		return ""
+	case mediaType == dockerConfigMediaType, mediaType == ociConfigMediaType:
+		return api.ArtifactTypeImage
+	case strings.HasPrefix(mediaType, helmConfigMediaTypePrefix):
+		return api.ArtifactTypeHelmChart
+	case strings.Contains(mediaType, "wasm"):
+		return api.ArtifactTypeWasm
+	default:
+		return api.ArtifactTypeOther
+	}
+}
+
+// isUnsupportedManifest returns true if the given status code is a registry
+// rejecting the requested manifest media type.
+func isUnsupportedManifest(statusCode int) bool {
+	return statusCode == http.StatusNotFound ||
+		statusCode == http.StatusNotAcceptable ||
+		statusCode == http.StatusUnsupportedMediaType
+}
+
+func (c *Client) doRequest(ctx context.Context, url, header string, obj interface{}) (http.Header, error) {
+	req, err := c.newRequest(ctx, url, header)
+	if err != nil {
+		return nil, err
 	}
 
 	resp, err := c.Do(req)
@@ -267,16 +648,120 @@ func (c *Client) doRequest(ctx context.Context, url, header string, obj interfac
 	}
 
 	if err := json.Unmarshal(body, obj); err != nil {
-		return nil, fmt.Errorf("unexpected %s response: %s", url, body)
+		return nil, fmt.Errorf("unexpected %s response: %s", req.URL, body)
 	}
 
 	return resp.Header, nil
 }
 
+// newRequest returns a GET request to the given URL of the registry, with the
+// given Accept header if not empty.
+func (c *Client) newRequest(ctx context.Context, url, header string) (*http.Request, error) {
+	url = fmt.Sprintf("%s://%s", c.httpScheme, url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+	bearer, err := c.bearer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(bearer) > 0 {
+		req.Header.Add("Authorization", "Bearer "+bearer)
+	}
+	if len(header) > 0 {
+		req.Header.Set("Accept", header)
+	}
+	c.setHeaders(req)
+
+	return req, nil
+}
+
+// tokenPath returns the path of the token endpoint of the registry.
+func (c *Client) tokenPath() string {
+	if c.TokenPath == "" {
+		return defaultTokenPath
+	}
+
+	return c.TokenPath
+}
+
+// Token returns the token requests to the registry are authenticated with,
+// or empty if there is none.
+func (c *Client) Token(ctx context.Context) (string, error) {
+	return c.bearer(ctx)
+}
+
+// exchangeOverride will return the given context, with the username and
+// password of any credentials set by credentials.WithOverride exchanged for a
+// token, so that they are exchanged once for all requests of the tags.
+func (c *Client) exchangeOverride(ctx context.Context) (context.Context, error) {
+	creds, ok := credentials.Override(ctx)
+	if !ok || len(creds.Token) > 0 {
+		return ctx, nil
+	}
+
+	token, err := c.exchangeToken(ctx, c.Host, c.tokenPath(), creds.Username, creds.Password)
+	if httpErr, ok := selfhostederrors.IsHTTPError(err); ok {
+		return nil, fmt.Errorf("failed to setup token auth for override credentials (%d): %s",
+			httpErr.StatusCode, httpErr.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup token auth for override credentials: %s", err)
+	}
+
+	return credentials.WithOverride(ctx, &credentials.Credentials{Token: token}), nil
+}
+
+// bearer returns the token to authenticate requests with. Credentials
+// resolved for the registry, or set on the context by
+// credentials.WithOverride, are used over the static token, where usernames
+// and passwords are exchanged for a token once per resolved credentials.
+func (c *Client) bearer(ctx context.Context) (string, error) {
+	creds, err := c.Credentials.Credentials(ctx, c.credentialsHost)
+	if err != nil {
+		return "", err
+	}
+	if creds == nil {
+		return c.Bearer, nil
+	}
+	if len(creds.Token) > 0 {
+		return creds.Token, nil
+	}
+
+	c.credentialsMu.Lock()
+	defer c.credentialsMu.Unlock()
+
+	if c.resolvedCreds == creds {
+		return c.resolvedBearer, nil
+	}
+
+	token, err := c.exchangeToken(ctx, c.Host, c.tokenPath(), creds.Username, creds.Password)
+	if httpErr, ok := selfhostederrors.IsHTTPError(err); ok {
+		return "", fmt.Errorf("failed to setup token auth (%d): %s",
+			httpErr.StatusCode, httpErr.Body)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to setup token auth: %s", err)
+	}
+
+	c.resolvedCreds, c.resolvedBearer = creds, token
+
+	return token, nil
+}
+
 func (c *Client) setupBasicAuth(ctx context.Context, url, tokenPath string) (string, error) {
+	return c.exchangeToken(ctx, url, tokenPath, c.Username, c.Password)
+}
+
+// exchangeToken will exchange the given username and password for a token
+// with the token endpoint of the registry.
+func (c *Client) exchangeToken(ctx context.Context, url, tokenPath, username, password string) (string, error) {
 	upReader := strings.NewReader(
 		fmt.Sprintf(`{"username": "%s", "password": "%s"}`,
-			c.Username, c.Password,
+			username, password,
 		),
 	)
 
@@ -289,6 +774,7 @@ func (c *Client) setupBasicAuth(ctx context.Context, url, tokenPath string) (str
 
 	req.Header.Set("Content-Type", "application/json")
 	req = req.WithContext(ctx)
+	c.setHeaders(req)
 
 	resp, err := c.Do(req)
 	if err != nil {
@@ -314,7 +800,18 @@ func (c *Client) setupBasicAuth(ctx context.Context, url, tokenPath string) (str
 	return response.Token, nil
 }
 
-func newTLSConfig(insecure bool, CAPath string) (*tls.Config, error) {
+// setHeaders will set the static headers of the registry on the request,
+// replacing any of the same name.
+func (c *Client) setHeaders(req *http.Request) {
+	for name, values := range c.Headers {
+		req.Header.Del(name)
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+}
+
+func newTLSConfig(insecure bool, CAPath, serverName string) (*tls.Config, error) {
 	// Load system CA Certs and/or create a new CertPool
 	rootCAs, _ := x509.SystemCertPool()
 	if rootCAs == nil {
@@ -332,5 +829,6 @@ func newTLSConfig(insecure bool, CAPath string) (*tls.Config, error) {
 	return &tls.Config{
 		InsecureSkipVerify: insecure,
 		RootCAs:            rootCAs,
+		ServerName:         serverName,
 	}, nil
 }
@@ -2,17 +2,34 @@ package selfhosted
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"fmt"
+	"math/big"
+	mathrand "math/rand"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
 	selfhostederrors "github.com/jetstack/version-checker/pkg/client/selfhosted/errors"
 )
 
@@ -152,6 +169,341 @@ func TestTags(t *testing.T) {
 		assert.Equal(t, "v2.0.0", tags[1].Tag)
 	})
 
+	t.Run("requests the API under the path prefix of the host", func(t *testing.T) {
+		var paths []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			switch r.URL.Path {
+			case "/v2base/v2/repo/image/tags/list":
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"tags":["v1.0.0"]}`))
+			case "/v2base/v2/repo/image/manifests/v1.0.0":
+				w.Header().Add("Docker-Content-Digest", "sha256:abcdef")
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer server.Close()
+
+		// The test server listens on a non-standard port
+		client, err := New(ctx, log, &Options{Host: server.URL + "/v2base/"})
+		assert.NoError(t, err)
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+		assert.True(t, client.IsHost(h.Host))
+
+		tags, err := client.Tags(ctx, h.Host, "repo", "image")
+		assert.NoError(t, err)
+		assert.Equal(t, []api.ImageTag{{Tag: "v1.0.0", SHA: "sha256:abcdef"}}, tags)
+		assert.Equal(t, []string{
+			"/v2base/v2/repo/image/tags/list",
+			"/v2base/v2/repo/image/manifests/v1.0.0",
+			"/v2base/v2/repo/image/manifests/v1.0.0",
+		}, paths)
+	})
+
+	t.Run("falls back to schema v1 manifests when v2 is not served", func(t *testing.T) {
+		client := &Client{
+			Client: &http.Client{},
+			log:    log,
+			Options: &Options{
+				Host: "testregistry.com",
+			},
+			httpScheme: "http",
+		}
+
+		manifestV1, err := os.ReadFile("testdata/manifest_v1.json")
+		assert.NoError(t, err)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v2/repo/image/tags/list":
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"tags":["v1.2.0","v1.3.0"]}`))
+			case "/v2/repo/image/manifests/v1.2.0", "/v2/repo/image/manifests/v1.3.0":
+				if r.Header.Get("Accept") != dockerAPIv1Header {
+					if r.URL.Path == "/v2/repo/image/manifests/v1.2.0" {
+						w.WriteHeader(http.StatusNotFound)
+						_, _ = w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"manifest unknown"}]}`))
+					} else {
+						w.WriteHeader(http.StatusNotAcceptable)
+					}
+					return
+				}
+				w.Header().Add("Docker-Content-Digest", "sha256:fedcba")
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write(manifestV1)
+			}
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		tags, err := client.Tags(ctx, h.Host, "repo", "image")
+
+		assert.NoError(t, err)
+		assert.Len(t, tags, 2)
+		for _, tag := range tags {
+			assert.Equal(t, "sha256:fedcba", tag.SHA)
+			assert.Equal(t, api.OS("linux"), tag.OS)
+			assert.Equal(t, api.Architecture("arm64"), tag.Architecture)
+			assert.WithinDuration(t, time.Date(2019, 3, 7, 22, 19, 46, 815331171, time.UTC), tag.Timestamp, 0)
+		}
+	})
+
+	t.Run("skips tags when the v2 manifest fails for other reasons", func(t *testing.T) {
+		client := &Client{
+			Client: &http.Client{},
+			log:    log,
+			Options: &Options{
+				Host: "testregistry.com",
+			},
+			httpScheme: "http",
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v2/repo/image/tags/list":
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"tags":["v1.0.0"]}`))
+			case "/v2/repo/image/manifests/v1.0.0":
+				if r.Header.Get("Accept") != dockerAPIv1Header {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"architecture":"amd64"}`))
+			}
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		tags, err := client.Tags(ctx, h.Host, "repo", "image")
+
+		assert.NoError(t, err)
+		assert.Empty(t, tags)
+	})
+
+	t.Run("detects the artifact type of OCI artifacts without a schema v1 manifest", func(t *testing.T) {
+		client := &Client{
+			Client: &http.Client{},
+			log:    log,
+			Options: &Options{
+				Host: "testregistry.com",
+			},
+			httpScheme: "http",
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v2/charts/cert-manager/tags/list":
+				_, _ = w.Write([]byte(`{"tags":["v1.14.0","v1.15.0"]}`))
+			case "/v2/charts/cert-manager/manifests/v1.14.0", "/v2/charts/cert-manager/manifests/v1.15.0":
+				if !strings.Contains(r.Header.Get("Accept"), ociManifestHeader) {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"errors":[{"code":"MANIFEST_UNKNOWN","message":"OCI manifest found, but accept header does not support OCI manifests"}]}`))
+					return
+				}
+				w.Header().Add("Docker-Content-Digest", "sha256:chart")
+				_, _ = w.Write([]byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json","config":{"mediaType":"application/vnd.cncf.helm.config.v1+json"}}`))
+			}
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		tags, err := client.Tags(ctx, h.Host, "charts", "cert-manager")
+		assert.NoError(t, err)
+		assert.Equal(t, []api.ImageTag{
+			{Tag: "v1.14.0", SHA: "sha256:chart", ArtifactType: api.ArtifactTypeHelmChart},
+			{Tag: "v1.15.0", SHA: "sha256:chart", ArtifactType: api.ArtifactTypeHelmChart},
+		}, tags)
+	})
+
+	t.Run("follows Link header pagination", func(t *testing.T) {
+		client := &Client{
+			Client: &http.Client{},
+			log:    log,
+			Options: &Options{
+				Host: "testregistry.com",
+			},
+			httpScheme: "http",
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v2/repo/image/tags/list":
+				switch r.URL.Query().Get("last") {
+				case "":
+					w.Header().Set("Link", `</v2/repo/image/tags/list?last=v1.0.0&n=500>; rel="next"`)
+					_, _ = w.Write([]byte(`{"tags":["v1.0.0"]}`))
+				case "v1.0.0":
+					_, _ = w.Write([]byte(`{"tags":["v2.0.0"]}`))
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			default:
+				_, _ = w.Write([]byte(`{}`))
+			}
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		tags, err := client.Tags(ctx, h.Host, "repo", "image")
+
+		assert.NoError(t, err)
+		assert.Len(t, tags, 2)
+		assert.Equal(t, "v1.0.0", tags[0].Tag)
+		assert.Equal(t, "v2.0.0", tags[1].Tag)
+	})
+
+	t.Run("error on a Link header of another host", func(t *testing.T) {
+		client := &Client{
+			Client: &http.Client{},
+			log:    log,
+			Options: &Options{
+				Host:   "testregistry.com",
+				Bearer: "registry-token",
+			},
+			httpScheme: "http",
+		}
+
+		var leaked bool
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			leaked = true
+			_, _ = w.Write([]byte(`{"tags":["v2.0.0"]}`))
+		}))
+		defer other.Close()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Link", `<`+other.URL+`/v2/repo/image/tags/list?last=v1.0.0>; rel="next"`)
+			_, _ = w.Write([]byte(`{"tags":["v1.0.0"]}`))
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		tags, err := client.Tags(ctx, h.Host, "repo", "image")
+		assert.Nil(t, tags)
+		assert.ErrorContains(t, err, "is not of the registry host")
+		assert.False(t, leaked, "expected no request to the other host")
+	})
+
+	t.Run("follows cursor token pagination", func(t *testing.T) {
+		client := &Client{
+			Client: &http.Client{},
+			log:    log,
+			Options: &Options{
+				Host:          "testregistry.com",
+				PageTokenPath: "pagination.next_page",
+			},
+			httpScheme: "http",
+		}
+
+		pages := map[string]string{
+			"":      `{"tags":["v1.0.0","v1.1.0"],"pagination":{"next_page":"abc"}}`,
+			"abc":   `{"tags":["v2.0.0"],"pagination":{"next_page":"def=="}}`,
+			"def==": `{"tags":["v3.0.0"],"pagination":{"next_page":null}}`,
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v2/repo/image/tags/list":
+				assert.Equal(t, "500", r.URL.Query().Get("n"))
+				page, ok := pages[r.URL.Query().Get("next_page")]
+				if !ok {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				_, _ = w.Write([]byte(page))
+			default:
+				_, _ = w.Write([]byte(`{}`))
+			}
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		tags, err := client.Tags(ctx, h.Host, "repo", "image")
+
+		assert.NoError(t, err)
+		var names []string
+		for _, tag := range tags {
+			names = append(names, tag.Tag)
+		}
+		assert.Equal(t, []string{"v1.0.0", "v1.1.0", "v2.0.0", "v3.0.0"}, names)
+	})
+
+	t.Run("sends the cursor token as the configured parameter", func(t *testing.T) {
+		client := &Client{
+			Client: &http.Client{},
+			log:    log,
+			Options: &Options{
+				Host:           "testregistry.com",
+				PageTokenPath:  "next",
+				PageTokenParam: "cursor",
+			},
+			httpScheme: "http",
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/v2/repo/image/tags/list":
+				if r.URL.Query().Get("cursor") == "2" {
+					_, _ = w.Write([]byte(`{"tags":["v2.0.0"]}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"tags":["v1.0.0"],"next":2}`))
+			default:
+				_, _ = w.Write([]byte(`{}`))
+			}
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		tags, err := client.Tags(ctx, h.Host, "repo", "image")
+
+		assert.NoError(t, err)
+		assert.Len(t, tags, 2)
+	})
+
+	t.Run("error on a repeated cursor token", func(t *testing.T) {
+		client := &Client{
+			Client: &http.Client{},
+			log:    log,
+			Options: &Options{
+				Host:          "testregistry.com",
+				PageTokenPath: "next_page",
+			},
+			httpScheme: "http",
+		}
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"tags":["v1.0.0"],"next_page":"abc"}`))
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		tags, err := client.Tags(ctx, h.Host, "repo", "image")
+		assert.Nil(t, tags)
+		assert.ErrorContains(t, err, "already fetched page")
+	})
+
 	t.Run("error fetching tags", func(t *testing.T) {
 		client := &Client{
 			Client: &http.Client{},
@@ -172,6 +524,199 @@ func TestTags(t *testing.T) {
 	})
 }
 
+func TestTagsConcurrent(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	ctx := context.Background()
+
+	var tagNames []string
+	for i := 0; i < 50; i++ {
+		tagNames = append(tagNames, fmt.Sprintf("v1.%d.0", i))
+	}
+	tagsList, err := json.Marshal(map[string][]string{"tags": tagNames})
+	require.NoError(t, err)
+
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v2/repo/image/tags/list" {
+			_, _ = w.Write(tagsList)
+			return
+		}
+
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			peak := maxInFlight.Load()
+			if n <= peak || maxInFlight.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+
+		// Manifests are served in a random order, so that they are completed
+		// out of the order the tags are listed in.
+		time.Sleep(time.Duration(mathrand.Intn(5)) * time.Millisecond)
+
+		tag := strings.TrimPrefix(r.URL.Path, "/v2/repo/image/manifests/")
+		switch tag {
+		case "v1.10.0":
+			w.WriteHeader(http.StatusNotFound)
+		case "v1.20.0":
+			// Close the connection without a response, failing the request
+			// without an HTTP error.
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+		default:
+			w.Header().Add("Docker-Content-Digest", "sha256:"+tag)
+			_, _ = w.Write([]byte(`{"architecture":"amd64"}`))
+		}
+	}))
+	defer server.Close()
+
+	h, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	tagsOfWorkers := func(workers int) []api.ImageTag {
+		client := &Client{
+			Client: &http.Client{},
+			log:    log,
+			Options: &Options{
+				Host:            "testregistry.com",
+				ManifestWorkers: func(string) int { return workers },
+			},
+			httpScheme: "http",
+		}
+
+		tags, err := client.Tags(ctx, h.Host, "repo", "image")
+		require.NoError(t, err)
+
+		// The error of the failed manifest depends on the transport
+		for i := range tags {
+			if tags[i].Tag == "v1.20.0" {
+				assert.NotEmpty(t, tags[i].ManifestError)
+				tags[i].ManifestError = "failed"
+			}
+		}
+
+		return tags
+	}
+
+	sequential := tagsOfWorkers(1)
+	assert.Equal(t, int32(1), maxInFlight.Load())
+
+	var expTags []api.ImageTag
+	for _, tag := range tagNames {
+		switch tag {
+		case "v1.10.0":
+		case "v1.20.0":
+			expTags = append(expTags, api.ImageTag{Tag: tag, ManifestError: "failed"})
+		default:
+			expTags = append(expTags, api.ImageTag{Tag: tag, SHA: "sha256:" + tag, Architecture: "amd64"})
+		}
+	}
+	assert.Equal(t, expTags, sequential)
+
+	for i := 0; i < 5; i++ {
+		maxInFlight.Store(0)
+		assert.Equal(t, sequential, tagsOfWorkers(8))
+		assert.LessOrEqual(t, maxInFlight.Load(), int32(8))
+	}
+	assert.Greater(t, maxInFlight.Load(), int32(1))
+
+	t.Run("each request should be acquired from the host", func(t *testing.T) {
+		var acquired, released atomic.Int32
+		sem := make(chan struct{}, 2)
+		client := &Client{
+			Client: &http.Client{},
+			log:    log,
+			Options: &Options{
+				Host:            "testregistry.com",
+				ManifestWorkers: func(string) int { return 8 },
+				Acquire: func(_ context.Context, host string) (func(), error) {
+					assert.Equal(t, h.Host, host)
+					sem <- struct{}{}
+					acquired.Add(1)
+					return func() {
+						released.Add(1)
+						<-sem
+					}, nil
+				},
+			},
+			httpScheme: "http",
+		}
+		assert.True(t, client.LimitsRequests())
+
+		maxInFlight.Store(0)
+		tags, err := client.Tags(ctx, h.Host, "repo", "image")
+		require.NoError(t, err)
+		assert.Len(t, tags, len(tagNames)-1)
+
+		// The tags list, and the manifests of each tag
+		assert.Equal(t, int32(len(tagNames)+1), acquired.Load())
+		assert.Equal(t, acquired.Load(), released.Load())
+		assert.LessOrEqual(t, maxInFlight.Load(), int32(2))
+	})
+
+	t.Run("error on a cancelled context", func(t *testing.T) {
+		client := &Client{
+			Client: &http.Client{},
+			log:    log,
+			Options: &Options{
+				Host:            "testregistry.com",
+				ManifestWorkers: func(string) int { return 8 },
+			},
+			httpScheme: "http",
+		}
+
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+
+		tags, err := client.Tags(ctx, h.Host, "repo", "image")
+		assert.Nil(t, tags)
+		assert.Error(t, err)
+	})
+}
+
+func TestArtifactType(t *testing.T) {
+	tests := map[string]struct {
+		manifest string
+		exp      api.ArtifactType
+	}{
+		"no config should be unknown": {
+			manifest: `{"schemaVersion":2}`,
+			exp:      "",
+		},
+		"docker image config should be an image": {
+			manifest: `{"config":{"mediaType":"application/vnd.docker.container.image.v1+json"}}`,
+			exp:      api.ArtifactTypeImage,
+		},
+		"oci image config should be an image": {
+			manifest: `{"config":{"mediaType":"application/vnd.oci.image.config.v1+json"}}`,
+			exp:      api.ArtifactTypeImage,
+		},
+		"helm config should be a helm chart": {
+			manifest: `{"config":{"mediaType":"application/vnd.cncf.helm.config.v1+json"}}`,
+			exp:      api.ArtifactTypeHelmChart,
+		},
+		"wasm config should be a wasm module": {
+			manifest: `{"config":{"mediaType":"application/vnd.wasm.config.v0+json"}}`,
+			exp:      api.ArtifactTypeWasm,
+		},
+		"artifact type should take precedence over the config": {
+			manifest: `{"artifactType":"application/vnd.example.sbom","config":{"mediaType":"application/vnd.oci.empty.v1+json"}}`,
+			exp:      api.ArtifactTypeOther,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var manifest ManifestResponse
+			require.NoError(t, json.Unmarshal([]byte(test.manifest), &manifest))
+			assert.Equal(t, test.exp, artifactType(&manifest))
+		})
+	}
+}
+
 func TestDoRequest(t *testing.T) {
 	log := logrus.NewEntry(logrus.New())
 	ctx := context.Background()
@@ -243,6 +788,34 @@ func TestDoRequest(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "unexpected")
 	})
+
+	t.Run("static headers are sent", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "secret", r.Header.Get("X-Api-Key"))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"tags":["v1"]}`))
+		}))
+		defer server.Close()
+
+		h, err := url.Parse(server.URL)
+		assert.NoError(t, err)
+
+		client := &Client{
+			Client: &http.Client{},
+			Options: &Options{
+				Host:    "testhost",
+				Headers: http.Header{"X-Api-Key": []string{"secret"}},
+			},
+			log:        log,
+			httpScheme: "http",
+		}
+
+		var tagResponse TagResponse
+		_, err = client.doRequest(ctx, h.Host+"/v2/repo/image/tags/list", "", &tagResponse)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"v1"}, tagResponse.Tags)
+	})
 }
 
 func TestSetupBasicAuth(t *testing.T) {
@@ -318,6 +891,66 @@ func TestSetupBasicAuth(t *testing.T) {
 	})
 }
 
+func TestBearer(t *testing.T) {
+	ctx := context.Background()
+	log := logrus.NewEntry(logrus.New())
+
+	t.Run("static token is used without resolved credentials", func(t *testing.T) {
+		client := &Client{Options: &Options{Bearer: "static"}, log: log}
+
+		bearer, err := client.bearer(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "static", bearer)
+	})
+
+	t.Run("resolved token is used over the static token", func(t *testing.T) {
+		creds := credentials.NewResolver()
+		creds.Register(func(host string) bool { return host == "registry.corp:5000" },
+			credentials.NewStatic("test", credentials.Credentials{Token: "resolved"}))
+
+		client := &Client{
+			Options:         &Options{Bearer: "static", Credentials: creds},
+			log:             log,
+			credentialsHost: "registry.corp:5000",
+		}
+
+		bearer, err := client.bearer(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "resolved", bearer)
+	})
+
+	t.Run("resolved username and password are exchanged for a token once", func(t *testing.T) {
+		var exchanges int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v2/token", r.URL.Path)
+			exchanges++
+			_, _ = w.Write([]byte(`{"token":"exchanged"}`))
+		}))
+		defer server.Close()
+
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		creds := credentials.NewResolver()
+		creds.Register(func(host string) bool { return host == u.Host },
+			credentials.NewStatic("test", credentials.Credentials{Username: "user", Password: "pass"}))
+
+		client := &Client{
+			Client:          server.Client(),
+			Options:         &Options{Host: server.URL, Credentials: creds},
+			log:             log,
+			credentialsHost: u.Host,
+		}
+
+		for range 2 {
+			bearer, err := client.bearer(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, "exchanged", bearer)
+		}
+		assert.Equal(t, 1, exchanges)
+	})
+}
+
 func TestNewTLSConfig(t *testing.T) {
 	t.Run("successful TLS config creation with valid CA path", func(t *testing.T) {
 		caFile, err := os.CreateTemp("", "ca.pem")
@@ -337,23 +970,80 @@ tQIDAQAB
 		err = caFile.Close()
 		assert.NoError(t, err)
 
-		tlsConfig, err := newTLSConfig(false, caFile.Name())
+		tlsConfig, err := newTLSConfig(false, caFile.Name(), "")
 		assert.NoError(t, err)
 		assert.NotNil(t, tlsConfig)
 		assert.False(t, tlsConfig.InsecureSkipVerify)
 	})
 
 	t.Run("successful TLS config creation with empty CA path", func(t *testing.T) {
-		tlsConfig, err := newTLSConfig(true, "")
+		tlsConfig, err := newTLSConfig(true, "", "")
 		assert.NoError(t, err)
 		assert.NotNil(t, tlsConfig)
 		assert.True(t, tlsConfig.InsecureSkipVerify)
 	})
 
 	t.Run("error on invalid CA path", func(t *testing.T) {
-		tlsConfig, err := newTLSConfig(false, "/invalid/path")
+		tlsConfig, err := newTLSConfig(false, "/invalid/path", "")
 		assert.Nil(t, tlsConfig)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to append")
 	})
 }
+
+func TestNewTLSConfigServerName(t *testing.T) {
+	// The certificate of the server is only valid for registry.corp, not the
+	// address it is connected to.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "registry.corp"},
+		DNSNames:              []string{"registry.corp"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+
+	var serverName string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverName = r.TLS.ServerName
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	t.Run("certificates not valid for the address should fail verification", func(t *testing.T) {
+		tlsConfig, err := newTLSConfig(false, caPath, "")
+		require.NoError(t, err)
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		_, err = client.Get(server.URL)
+		assert.ErrorContains(t, err, "failed to verify certificate")
+	})
+
+	t.Run("certificates valid for the server name should pass verification", func(t *testing.T) {
+		tlsConfig, err := newTLSConfig(false, caPath, "registry.corp")
+		require.NoError(t, err)
+		assert.Equal(t, "registry.corp", tlsConfig.ServerName)
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "registry.corp", serverName)
+	})
+}
@@ -0,0 +1,105 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var (
+	// DefaultPageBackoff is the backoff used to retry a page of a paginated
+	// listing which failed with a retriable error.
+	DefaultPageBackoff = wait.Backoff{
+		Steps:    4,
+		Duration: time.Millisecond * 500,
+		Factor:   2,
+		Jitter:   0.1,
+		Cap:      time.Second * 10,
+	}
+)
+
+// RetriableError is an error from a request which failed transiently, and
+// may succeed if retried.
+type RetriableError struct {
+	Err error
+}
+
+func NewRetriableError(err error) error {
+	return &RetriableError{Err: err}
+}
+
+func (r *RetriableError) Error() string {
+	return r.Err.Error()
+}
+
+func (r *RetriableError) Unwrap() error {
+	return r.Err
+}
+
+// IsRetriable returns true if the given error is a RetriableError, or a
+// network timeout.
+func IsRetriable(err error) bool {
+	var retriableErr *RetriableError
+	if errors.As(err, &retriableErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// IsRetriableStatusCode returns true if the given HTTP status code is for a
+// transient failure.
+func IsRetriableStatusCode(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// Paginate will call fetch for each page, starting from the given page,
+// until fetch reports there are no more pages. A page which fails with a
+// retriable error is retried with the given backoff, without losing the
+// pages already fetched. The listing only fails if a page exhausts its
+// retries, or fails with an error which is not retriable.
+func Paginate[T any](ctx context.Context, backoff wait.Backoff, page T,
+	fetch func(ctx context.Context, page T) (next T, more bool, err error)) error {
+	for {
+		next, more, err := fetchPage(ctx, backoff, page, fetch)
+		if err != nil {
+			return err
+		}
+
+		if !more {
+			return nil
+		}
+
+		page = next
+	}
+}
+
+// fetchPage will fetch a single page, retrying with the given backoff on
+// retriable errors.
+func fetchPage[T any](ctx context.Context, backoff wait.Backoff, page T,
+	fetch func(ctx context.Context, page T) (T, bool, error)) (T, bool, error) {
+	retries := backoff.Steps
+
+	for attempt := 0; ; attempt++ {
+		next, more, err := fetch(ctx, page)
+		if err == nil || !IsRetriable(err) {
+			return next, more, err
+		}
+
+		if attempt >= retries {
+			return next, false, fmt.Errorf("page failed after %d retries: %w", retries, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return next, false, ctx.Err()
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestPaginate(t *testing.T) {
+	backoff := wait.Backoff{Steps: 2, Duration: time.Millisecond, Factor: 1}
+	errTransient := NewRetriableError(errors.New("503"))
+
+	tests := map[string]struct {
+		// failures is the errors returned by each page, before it succeeds.
+		failures map[int][]error
+		expPages []int
+		expErr   string
+	}{
+		"all pages succeeding should fetch all pages": {
+			expPages: []int{1, 2, 3, 4},
+		},
+		"a retriable failure mid-pagination should retry just that page": {
+			failures: map[int][]error{3: {errTransient, errTransient}},
+			expPages: []int{1, 2, 3, 4},
+		},
+		"a page exhausting its retries should fail": {
+			failures: map[int][]error{2: {errTransient, errTransient, errTransient}},
+			expPages: []int{1},
+			expErr:   "page failed after 2 retries: 503",
+		},
+		"a non-retriable failure should fail without retrying": {
+			failures: map[int][]error{2: {errors.New("404")}},
+			expPages: []int{1},
+			expErr:   "404",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var (
+				pages    []int
+				attempts = make(map[int]int)
+			)
+
+			err := Paginate(context.TODO(), backoff, 1, func(_ context.Context, page int) (int, bool, error) {
+				attempts[page]++
+				if failures := test.failures[page]; attempts[page] <= len(failures) {
+					return 0, false, failures[attempts[page]-1]
+				}
+
+				pages = append(pages, page)
+				return page + 1, page < 4, nil
+			})
+
+			if len(test.expErr) == 0 && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(test.expErr) > 0 && (err == nil || err.Error() != test.expErr) {
+				t.Fatalf("unexpected error, exp=%s got=%v", test.expErr, err)
+			}
+
+			if len(pages) != len(test.expPages) {
+				t.Fatalf("unexpected pages fetched, exp=%v got=%v", test.expPages, pages)
+			}
+			for i := range pages {
+				if pages[i] != test.expPages[i] {
+					t.Fatalf("unexpected pages fetched, exp=%v got=%v", test.expPages, pages)
+				}
+			}
+		})
+	}
+}
+
+func TestPaginateContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	backoff := wait.Backoff{Steps: 5, Duration: time.Hour, Factor: 1}
+
+	err := Paginate(ctx, backoff, 1, func(_ context.Context, _ int) (int, bool, error) {
+		cancel()
+		return 0, false, NewRetriableError(errors.New("503"))
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context cancelled error, got=%v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+
+	selfhostederrors "github.com/jetstack/version-checker/pkg/client/selfhosted/errors"
+)
+
+// StatusError is an error from a registry which responded with an
+// unexpected HTTP status code.
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func NewStatusError(statusCode int, err error) error {
+	return &StatusError{StatusCode: statusCode, Err: err}
+}
+
+func (s *StatusError) Error() string {
+	return s.Err.Error()
+}
+
+func (s *StatusError) Unwrap() error {
+	return s.Err
+}
+
+// StatusCode returns the HTTP status code the registry responded with for the
+// given error, if the error is of a registry response.
+func StatusCode(err error) (int, bool) {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode, true
+	}
+
+	var httpErr *selfhostederrors.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode, true
+	}
+
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) {
+		return transportErr.StatusCode, true
+	}
+
+	// Such as the response errors of AWS
+	var responseErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &responseErr) {
+		return responseErr.HTTPStatusCode(), true
+	}
+
+	return 0, false
+}
+
+// IsNotFound returns true if the given error is of the registry responding
+// that the repository or reference was not found.
+func IsNotFound(err error) bool {
+	statusCode, ok := StatusCode(err)
+	return ok && statusCode == http.StatusNotFound
+}
+
+// IsAuthDenied returns true if the given error is of the registry denying
+// access, as it was not authenticated or not authorized.
+func IsAuthDenied(err error) bool {
+	statusCode, ok := StatusCode(err)
+	return ok && (statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden)
+}
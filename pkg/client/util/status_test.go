@@ -0,0 +1,93 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+
+	selfhostederrors "github.com/jetstack/version-checker/pkg/client/selfhosted/errors"
+)
+
+// responseError is an error reporting its HTTP status code, such as the
+// response errors of AWS.
+type responseError struct {
+	statusCode int
+}
+
+func (r *responseError) Error() string {
+	return fmt.Sprintf("response error %d", r.statusCode)
+}
+
+func (r *responseError) HTTPStatusCode() int {
+	return r.statusCode
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := map[string]struct {
+		err           error
+		expStatusCode int
+		expOK         bool
+		expNotFound   bool
+		expAuthDenied bool
+		expRetriable  bool
+	}{
+		"no error should have no status code": {
+			err: nil,
+		},
+		"an error without a response should have no status code": {
+			err: errors.New("connection refused"),
+		},
+		"a wrapped status error should be not found": {
+			err:           fmt.Errorf("failed to get tags: %w", NewStatusError(http.StatusNotFound, errors.New("not found"))),
+			expStatusCode: http.StatusNotFound,
+			expOK:         true,
+			expNotFound:   true,
+		},
+		"a self-hosted error should be not found": {
+			err:           fmt.Errorf("failed to get tags: %w", selfhostederrors.NewHTTPError(http.StatusNotFound, []byte("NAME_UNKNOWN"))),
+			expStatusCode: http.StatusNotFound,
+			expOK:         true,
+			expNotFound:   true,
+		},
+		"a transport error should be unauthorized": {
+			err:           fmt.Errorf("listing tags: %w", &transport.Error{StatusCode: http.StatusUnauthorized}),
+			expStatusCode: http.StatusUnauthorized,
+			expOK:         true,
+			expAuthDenied: true,
+		},
+		"a response error should be forbidden": {
+			err:           fmt.Errorf("failed to describe images: %w", &responseError{statusCode: http.StatusForbidden}),
+			expStatusCode: http.StatusForbidden,
+			expOK:         true,
+			expAuthDenied: true,
+		},
+		"a retriable status error should be neither not found or denied": {
+			err:           NewRetriableError(NewStatusError(http.StatusServiceUnavailable, errors.New("unavailable"))),
+			expStatusCode: http.StatusServiceUnavailable,
+			expOK:         true,
+			expRetriable:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			statusCode, ok := StatusCode(test.err)
+			if statusCode != test.expStatusCode || ok != test.expOK {
+				t.Errorf("unexpected status code, exp=%d %t got=%d %t",
+					test.expStatusCode, test.expOK, statusCode, ok)
+			}
+			if notFound := IsNotFound(test.err); notFound != test.expNotFound {
+				t.Errorf("unexpected not found, exp=%t got=%t", test.expNotFound, notFound)
+			}
+			if authDenied := IsAuthDenied(test.err); authDenied != test.expAuthDenied {
+				t.Errorf("unexpected auth denied, exp=%t got=%t", test.expAuthDenied, authDenied)
+			}
+			if retriable := IsRetriable(test.err); retriable != test.expRetriable {
+				t.Errorf("unexpected retriable, exp=%t got=%t", test.expRetriable, retriable)
+			}
+		})
+	}
+}
@@ -9,24 +9,90 @@ import (
 
 	"github.com/jetstack/version-checker/pkg/api"
 	"github.com/jetstack/version-checker/pkg/controller/search"
+	"github.com/jetstack/version-checker/pkg/version/baseimage"
+	"github.com/jetstack/version-checker/pkg/version/datesha"
+	"github.com/jetstack/version-checker/pkg/version/epoch"
 	"github.com/jetstack/version-checker/pkg/version/semver"
 	"github.com/sirupsen/logrus"
 )
 
 type Checker struct {
-	search search.Searcher
+	search     search.Searcher
+	baseImages BaseImageResolver
 }
 
+// BaseImageResolver resolves the base image declared by the labels of an
+// image.
+type BaseImageResolver interface {
+	BaseImage(ctx context.Context, imageRef string) (*baseimage.BaseImage, error)
+}
+
+const (
+	// PlatformSourceRegistry is used when the platform of the image was
+	// reported by the registry.
+	PlatformSourceRegistry = "registry"
+	// PlatformSourceDefault is used when the platform of the image could not
+	// be determined, and the configured default was used instead.
+	PlatformSourceDefault = "default"
+)
+
 type Result struct {
 	CurrentVersion string
 	LatestVersion  string
 	IsLatest       bool
 	ImageURL       string
+
+	// LatestSHA is the digest of the latest version, if known, used to detect
+	// its tag being pushed again with a different digest.
+	LatestSHA string
+
+	OS             api.OS
+	Architecture   api.Architecture
+	PlatformSource string
+
+	// ArtifactType is the type of the artifact of the latest version, if
+	// reported by the registry. Artifacts are otherwise images.
+	ArtifactType api.ArtifactType
+
+	// PinnedByDigest is true if the container image reference includes a
+	// digest.
+	PinnedByDigest bool
+
+	// AbsoluteLatestVersion is the latest version ignoring any max version
+	// ceiling, and IsAbsoluteLatest whether the current version is at least
+	// it. Only set when a max version is set.
+	AbsoluteLatestVersion string
+	IsAbsoluteLatest      bool
+
+	// IsAheadOfRegistry is true if the current version is greater than the
+	// latest version in the registry, such as a locally built image. The
+	// current version is then not latest.
+	IsAheadOfRegistry bool
+
+	// IsCurrentUnparseable is true if the current version could not be parsed
+	// by the version scheme of the check, such as a random string, so was not
+	// meaningfully compared to the latest version. Versions behind are then
+	// not counted. Never set when comparing by digest.
+	IsCurrentUnparseable bool
+
+	// VersionsBehind is the number of distinct versions greater than the
+	// current version, up to and including the latest version, considered
+	// with the options of the check. Only set for semver versions, and not
+	// for base images.
+	VersionsBehind *int
+
+	// BaseImage is the result of the base image declared by the labels of the
+	// image. Only set when checking the base image is enabled, and the image
+	// declares one.
+	BaseImage *Result
 }
 
-func New(search search.Searcher) *Checker {
+// New returns a new Checker. The base image resolver may be nil, where base
+// images are not checked.
+func New(search search.Searcher, baseImages BaseImageResolver) *Checker {
 	return &Checker{
-		search: search,
+		search:     search,
+		baseImages: baseImages,
 	}
 }
 
@@ -40,6 +106,7 @@ func (c *Checker) Container(ctx context.Context, log *logrus.Entry, pod *corev1.
 
 	imageURL, currentTag, currentSHA := urlTagSHAFromImage(container.Image)
 	usingSHA, usingTag := len(currentSHA) > 0, len(currentTag) > 0
+	runningImageURL := imageURL
 
 	if c.isLatestOrEmptyTag(currentTag) {
 		c.handleLatestOrEmptyTag(log, currentTag, currentSHA, opts)
@@ -48,11 +115,163 @@ func (c *Checker) Container(ctx context.Context, log *logrus.Entry, pod *corev1.
 
 	imageURL = c.overrideImageURL(log, imageURL, opts)
 
+	var (
+		result *Result
+		err    error
+	)
+	switch {
+	case opts.UseSHA:
+		result, err = c.handleSHA(ctx, imageURL, statusSHA, opts, usingTag, currentTag)
+	case opts.VersionScheme == api.VersionSchemeDateSHA:
+		result, err = c.handleDateSHA(ctx, imageURL, statusSHA, currentTag, usingSHA, opts)
+	case opts.VersionScheme == api.VersionSchemeEpoch:
+		result, err = c.handleEpoch(ctx, imageURL, statusSHA, currentTag, usingSHA, opts)
+	default:
+		result, err = c.handleSemver(ctx, imageURL, statusSHA, currentTag, usingSHA, opts)
+		if err == nil && !result.IsCurrentUnparseable {
+			err = c.setVersionsBehind(ctx, imageURL, currentTag, result, opts)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	setDefaultPlatform(result, opts)
+	result.PinnedByDigest = usingSHA
+
+	if result.IsAheadOfRegistry {
+		log.Warnf("current version %q is ahead of the latest version %q in the registry, and may be a locally built image",
+			result.CurrentVersion, result.LatestVersion)
+	}
+
+	if opts.CheckBaseImage {
+		result.BaseImage = c.baseImage(ctx, log, runningImageURL, statusSHA, opts)
+	}
+
+	return result, nil
+}
+
+// Image will check the given image reference, which is not running, such as
+// an image a resource is configured to deploy. Images are compared by the
+// digest of the reference, and so must have a version tag if not pinned by a
+// digest.
+func (c *Checker) Image(ctx context.Context, log *logrus.Entry, image string, opts *api.Options) (*Result, error) {
+	imageURL, currentTag, currentSHA := urlTagSHAFromImage(image)
+	usingSHA := len(currentSHA) > 0
+
+	if c.isLatestOrEmptyTag(currentTag) {
+		if !usingSHA {
+			return nil, fmt.Errorf("image must have a version tag, or a digest, to be checked")
+		}
+		c.handleLatestOrEmptyTag(log, currentTag, currentSHA, opts)
+	}
+
+	imageURL = c.overrideImageURL(log, imageURL, opts)
+
+	var (
+		result *Result
+		err    error
+	)
 	if opts.UseSHA {
-		return c.handleSHA(ctx, imageURL, statusSHA, opts, usingTag, currentTag)
+		result, err = c.handleSHA(ctx, imageURL, currentSHA, opts, false, currentTag)
+	} else {
+		result, err = c.handleSemver(ctx, imageURL, currentSHA, currentTag, usingSHA, opts)
+		if err == nil && !result.IsCurrentUnparseable {
+			err = c.setVersionsBehind(ctx, imageURL, currentTag, result, opts)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	setDefaultPlatform(result, opts)
+	result.PinnedByDigest = usingSHA
+
+	return result, nil
+}
+
+// baseImage will return the result of the base image declared by the labels
+// of the running image, compared to the latest upstream. Nil is returned if
+// the image does not declare a base image, or it could not be checked, so
+// that the result of the image itself is still reported.
+func (c *Checker) baseImage(ctx context.Context, log *logrus.Entry, imageURL, statusSHA string, opts *api.Options) *Result {
+	log = log.WithField("module", "checker")
+
+	if c.baseImages == nil {
+		log.Debug("base image resolution is not configured, skipping base image check")
+		return nil
+	}
+
+	base, err := c.baseImages.BaseImage(ctx, imageURL+"@"+statusSHA)
+	if err != nil {
+		log.Errorf("failed to resolve base image: %s", err)
+		return nil
+	}
+	if base == nil {
+		log.Debug("image does not declare a base image, skipping base image check")
+		return nil
+	}
+
+	baseURL, baseTag, baseSHA := urlTagSHAFromImage(base.Name)
+	usingSHA, usingTag := len(baseSHA) > 0, len(baseTag) > 0
+	if !usingSHA {
+		baseSHA = base.Digest
+	}
+
+	// The base image is not configured by the annotations of the container,
+	// other than its platform, pre-release order and build suffix.
+	baseOpts := &api.Options{
+		DefaultOS:       opts.DefaultOS,
+		DefaultArch:     opts.DefaultArch,
+		PreReleaseOrder: opts.PreReleaseOrder,
+		BuildSuffix:     opts.BuildSuffix,
+	}
+	if c.isLatestOrEmptyTag(baseTag) {
+		baseOpts.UseSHA = true
+		usingTag = false
+	}
+
+	var result *Result
+	switch {
+	case baseOpts.UseSHA && len(baseSHA) == 0:
+		log.Debugf("base image %q has no version or digest to compare, skipping base image check", base.Name)
+		return nil
+	case baseOpts.UseSHA:
+		result, err = c.handleSHA(ctx, baseURL, baseSHA, baseOpts, usingTag, baseTag)
+	default:
+		result, err = c.handleSemver(ctx, baseURL, baseSHA, baseTag, usingSHA, baseOpts)
+	}
+	if err != nil {
+		log.Errorf("failed to check base image %q: %s", base.Name, err)
+		return nil
+	}
+
+	return result
+}
+
+// setDefaultPlatform will fall back to the configured default OS and
+// architecture, where they have not been reported by the registry. The
+// platform source is left empty if the platform is not known at all.
+// Artifacts which are not images, such as Helm charts, have no platform.
+func setDefaultPlatform(result *Result, opts *api.Options) {
+	if len(result.ArtifactType) > 0 && result.ArtifactType != api.ArtifactTypeImage {
+		result.OS, result.Architecture = "", ""
+		return
+	}
+
+	if len(result.OS) > 0 || len(result.Architecture) > 0 {
+		result.PlatformSource = PlatformSourceRegistry
 	}
 
-	return c.handleSemver(ctx, imageURL, statusSHA, currentTag, usingSHA, opts)
+	if len(result.OS) == 0 && len(opts.DefaultOS) > 0 {
+		result.OS = opts.DefaultOS
+		result.PlatformSource = PlatformSourceDefault
+	}
+
+	if len(result.Architecture) == 0 && len(opts.DefaultArch) > 0 {
+		result.Architecture = opts.DefaultArch
+		result.PlatformSource = PlatformSourceDefault
+	}
 }
 
 func (c *Checker) handleLatestOrEmptyTag(log *logrus.Entry, currentTag, currentSHA string, opts *api.Options) {
@@ -81,13 +300,28 @@ func (c *Checker) handleSHA(ctx context.Context, imageURL, statusSHA string, opt
 	return result, nil
 }
 
+// semverParser returns the parser of tags with the given options.
+func semverParser(opts *api.Options) semver.Parser {
+	return semver.Parser{
+		PreReleaseOrder: opts.PreReleaseOrder,
+		BuildSuffix:     opts.BuildSuffix,
+	}
+}
+
 func (c *Checker) handleSemver(ctx context.Context, imageURL, statusSHA, currentTag string, usingSHA bool, opts *api.Options) (*Result, error) {
-	currentImage := semver.Parse(currentTag)
+	currentImage := semverParser(opts).Parse(currentTag)
 	latestImage, isLatest, err := c.isLatestSemver(ctx, imageURL, statusSHA, currentImage, opts)
 	if err != nil {
 		return nil, err
 	}
 
+	// A current version greater than the latest is not in the registry, such
+	// as a locally built image, so is ahead of the registry rather than latest.
+	isAhead := isLatest && semverParser(opts).Parse(latestImage.Tag).LessThan(currentImage)
+	if isAhead {
+		isLatest = false
+	}
+
 	latestVersion := latestImage.Tag
 	if usingSHA && !strings.Contains(latestVersion, "@") && latestImage.SHA != "" {
 		latestVersion = fmt.Sprintf("%s@%s", latestVersion, latestImage.SHA)
@@ -97,11 +331,160 @@ func (c *Checker) handleSemver(ctx context.Context, imageURL, statusSHA, current
 		currentTag = fmt.Sprintf("%s@%s", currentTag, statusSHA)
 	}
 
+	result := &Result{
+		CurrentVersion: currentTag,
+		LatestVersion:  latestVersion,
+		IsLatest:       isLatest,
+		ImageURL:       imageURL,
+		LatestSHA:      latestImage.SHA,
+		OS:             latestImage.OS,
+		Architecture:   latestImage.Architecture,
+		ArtifactType:   latestImage.ArtifactType,
+
+		IsAheadOfRegistry:    isAhead,
+		IsCurrentUnparseable: !currentImage.Valid(),
+	}
+
+	if opts.MaxVersion != nil {
+		result.AbsoluteLatestVersion, result.IsAbsoluteLatest, err = c.absoluteLatestSemver(ctx, imageURL, currentImage, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		// The max version ceiling does not limit the versions in the
+		// registry, so the current version is only ahead of the registry if
+		// greater than the absolute latest.
+		if isAhead && !semverParser(opts).Parse(result.AbsoluteLatestVersion).LessThan(currentImage) {
+			result.IsLatest, result.IsAheadOfRegistry = true, false
+		}
+	}
+
+	return result, nil
+}
+
+// setVersionsBehind will set the number of versions the current tag is behind
+// the latest version of the given semver result.
+func (c *Checker) setVersionsBehind(ctx context.Context, imageURL, currentTag string, result *Result, opts *api.Options) error {
+	latestTag, _, _ := strings.Cut(result.LatestVersion, "@")
+
+	versionsBehind, err := c.search.VersionsBehind(ctx, imageURL, currentTag, latestTag, opts)
+	if err != nil {
+		return fmt.Errorf("failed to count versions behind: %s", err)
+	}
+	result.VersionsBehind = &versionsBehind
+
+	return nil
+}
+
+// absoluteLatestSemver will return the latest version, and whether the
+// current image is at least it, ignoring the max version ceiling of the given
+// options.
+func (c *Checker) absoluteLatestSemver(ctx context.Context, imageURL string, currentImage *semver.SemVer, opts *api.Options) (string, bool, error) {
+	absoluteOpts := *opts
+	absoluteOpts.MaxVersion = nil
+
+	latestImage, err := c.search.LatestImage(ctx, imageURL, &absoluteOpts)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to find absolute latest version: %s", err)
+	}
+
+	return latestImage.Tag, !currentImage.LessThan(semver.Parse(latestImage.Tag)), nil
+}
+
+// handleDateSHA will compare the current tag against the latest by their
+// leading dates. Tags are reported in full, including any suffix.
+func (c *Checker) handleDateSHA(ctx context.Context, imageURL, statusSHA, currentTag string, usingSHA bool, opts *api.Options) (*Result, error) {
+	latestImage, err := c.search.LatestImage(ctx, imageURL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	latestVersion := latestImage.Tag
+	latestImageV, _ := datesha.Parse(latestImage.Tag, opts.DateLayout)
+
+	// Tags of the same date, but with a different suffix, are different builds
+	// so the current tag is only latest if it is the same tag, or later.
+	// A later dated tag than the latest is not in the registry, such as a
+	// locally built image, so is ahead of the registry rather than latest.
+	currentImageV, ok := datesha.Parse(currentTag, opts.DateLayout)
+	isLatest := currentTag == latestImage.Tag
+	isAhead := ok && latestImageV != nil && latestImageV.LessThan(currentImageV)
+
+	// If using the same tag, but the SHA has been updated upstream, make not
+	// latest
+	if currentTag == latestImage.Tag && statusSHA != latestImage.SHA && latestImage.SHA != "" {
+		isLatest = false
+		latestVersion = fmt.Sprintf("%s@%s", latestVersion, latestImage.SHA)
+	}
+
+	if usingSHA && !strings.Contains(latestVersion, "@") && latestImage.SHA != "" {
+		latestVersion = fmt.Sprintf("%s@%s", latestVersion, latestImage.SHA)
+	}
+
+	if strings.Contains(latestVersion, "@") {
+		currentTag = fmt.Sprintf("%s@%s", currentTag, statusSHA)
+	}
+
 	return &Result{
 		CurrentVersion: currentTag,
 		LatestVersion:  latestVersion,
 		IsLatest:       isLatest,
 		ImageURL:       imageURL,
+		LatestSHA:      latestImage.SHA,
+		OS:             latestImage.OS,
+		Architecture:   latestImage.Architecture,
+		ArtifactType:   latestImage.ArtifactType,
+
+		IsAheadOfRegistry:    isAhead,
+		IsCurrentUnparseable: !ok,
+	}, nil
+}
+
+// handleEpoch will compare the current tag against the latest by their
+// leading epochs, then their semver versions. Tags are reported in full,
+// including the epoch.
+func (c *Checker) handleEpoch(ctx context.Context, imageURL, statusSHA, currentTag string, usingSHA bool, opts *api.Options) (*Result, error) {
+	latestImage, err := c.search.LatestImage(ctx, imageURL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	latestVersion := latestImage.Tag
+	currentImageV := epoch.Parse(currentTag, semverParser(opts))
+	latestImageV := epoch.Parse(latestImage.Tag, semverParser(opts))
+
+	// A current version greater than the latest is not in the registry, such
+	// as a locally built image, so is ahead of the registry rather than latest.
+	isAhead := latestImageV.LessThan(currentImageV)
+	isLatest := !isAhead && !currentImageV.LessThan(latestImageV)
+
+	// If using the same version, but the SHA has been updated upstream, make
+	// not latest
+	if currentImageV.Equal(latestImageV) && statusSHA != latestImage.SHA && latestImage.SHA != "" {
+		isLatest = false
+		latestVersion = fmt.Sprintf("%s@%s", latestVersion, latestImage.SHA)
+	}
+
+	if usingSHA && !strings.Contains(latestVersion, "@") && latestImage.SHA != "" {
+		latestVersion = fmt.Sprintf("%s@%s", latestVersion, latestImage.SHA)
+	}
+
+	if strings.Contains(latestVersion, "@") {
+		currentTag = fmt.Sprintf("%s@%s", currentTag, statusSHA)
+	}
+
+	return &Result{
+		CurrentVersion: currentTag,
+		LatestVersion:  latestVersion,
+		IsLatest:       isLatest,
+		ImageURL:       imageURL,
+		LatestSHA:      latestImage.SHA,
+		OS:             latestImage.OS,
+		Architecture:   latestImage.Architecture,
+		ArtifactType:   latestImage.ArtifactType,
+
+		IsAheadOfRegistry:    isAhead,
+		IsCurrentUnparseable: !currentImageV.Version().Valid(),
 	}, nil
 }
 
@@ -159,8 +542,8 @@ func (c *Checker) isLatestSemver(ctx context.Context, imageURL, currentSHA strin
 	}
 
 	// If using the same image version, but the SHA has been updated upstream,
-	// make not latest
-	if currentImage.Equal(latestImageV) && currentSHA != latestImage.SHA && latestImage.SHA != "" {
+	// make not latest. The SHA may not be known for base images.
+	if currentImage.Equal(latestImageV) && currentSHA != "" && currentSHA != latestImage.SHA && latestImage.SHA != "" {
 		isLatest = false
 		latestImage.Tag = fmt.Sprintf("%s@%s", latestImage.Tag, latestImage.SHA)
 	}
@@ -186,6 +569,10 @@ func (c *Checker) isLatestSHA(ctx context.Context, imageURL, currentSHA string,
 		LatestVersion:  latestVersion,
 		IsLatest:       isLatest,
 		ImageURL:       imageURL,
+		LatestSHA:      latestImage.SHA,
+		OS:             latestImage.OS,
+		Architecture:   latestImage.Architecture,
+		ArtifactType:   latestImage.ArtifactType,
 	}, nil
 }
 
@@ -2,6 +2,7 @@ package checker
 
 import (
 	"context"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -10,6 +11,7 @@ import (
 
 	"github.com/jetstack/version-checker/pkg/api"
 	"github.com/jetstack/version-checker/pkg/controller/internal/fake/search"
+	"github.com/jetstack/version-checker/pkg/version/baseimage"
 	"github.com/jetstack/version-checker/pkg/version/semver"
 )
 
@@ -39,8 +41,10 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "v0.2.0@sha:123",
 				LatestVersion:  "v0.2.0@sha:456",
+				LatestSHA:      "sha:456",
 				ImageURL:       "localhost:5000/version-checker",
 				IsLatest:       false,
+				VersionsBehind: intp(0),
 			},
 		},
 		"if v0.2.0 is latest version, but same sha, then latest": {
@@ -54,8 +58,10 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "v0.2.0",
 				LatestVersion:  "v0.2.0",
+				LatestSHA:      "sha:123",
 				ImageURL:       "localhost:5000/version-checker",
 				IsLatest:       true,
+				VersionsBehind: intp(0),
 			},
 		},
 		"if v0.2.0@sha:123 is wrong sha, then not latest": {
@@ -69,8 +75,11 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "v0.2.0@sha:123",
 				LatestVersion:  "v0.2.0@sha:456",
+				LatestSHA:      "sha:456",
 				ImageURL:       "localhost:5000/version-checker",
+				PinnedByDigest: true,
 				IsLatest:       false,
+				VersionsBehind: intp(0),
 			},
 		},
 		"if v0.2.0@sha:123 is correct sha, then latest": {
@@ -84,8 +93,11 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "v0.2.0@sha:123",
 				LatestVersion:  "v0.2.0@sha:123",
+				LatestSHA:      "sha:123",
 				ImageURL:       "localhost:5000/version-checker",
+				PinnedByDigest: true,
 				IsLatest:       true,
+				VersionsBehind: intp(0),
 			},
 		},
 		"if empty is not latest version, then return false": {
@@ -99,6 +111,7 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "sha:123",
 				LatestVersion:  "v0.2.0@sha:456",
+				LatestSHA:      "sha:456",
 				ImageURL:       "localhost:5000/version-checker",
 				IsLatest:       false,
 			},
@@ -114,6 +127,7 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "sha:123",
 				LatestVersion:  "sha:123",
+				LatestSHA:      "sha:123",
 				ImageURL:       "localhost:5000/version-checker",
 				IsLatest:       true,
 			},
@@ -129,6 +143,7 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "sha:123",
 				LatestVersion:  "v0.2.0@sha:456",
+				LatestSHA:      "sha:456",
 				ImageURL:       "localhost:5000/version-checker",
 				IsLatest:       false,
 			},
@@ -144,6 +159,7 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "sha:123",
 				LatestVersion:  "sha:123",
+				LatestSHA:      "sha:123",
 				ImageURL:       "localhost:5000/version-checker",
 				IsLatest:       true,
 			},
@@ -161,6 +177,7 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "sha:123",
 				LatestVersion:  "sha:123",
+				LatestSHA:      "sha:123",
 				ImageURL:       "quay.io/jetstack/version-checker",
 				IsLatest:       true,
 			},
@@ -178,6 +195,7 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "v0.2.0@sha:123",
 				LatestVersion:  "v0.2.0@sha:456",
+				LatestSHA:      "sha:456",
 				ImageURL:       "localhost:5000/version-checker",
 				IsLatest:       false,
 			},
@@ -195,6 +213,7 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "v0.2.0@sha:123",
 				LatestVersion:  "v0.2.0@sha:123",
+				LatestSHA:      "sha:123",
 				ImageURL:       "localhost:5000/version-checker",
 				IsLatest:       true,
 			},
@@ -210,7 +229,9 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "sha:123",
 				LatestVersion:  "v0.2.0@sha:456",
+				LatestSHA:      "sha:456",
 				ImageURL:       "localhost:5000/joshvanl/version-checker",
+				PinnedByDigest: true,
 				IsLatest:       false,
 			},
 		},
@@ -225,7 +246,9 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "sha:123",
 				LatestVersion:  "sha:456",
+				LatestSHA:      "sha:456",
 				ImageURL:       "localhost:5000/joshvanl/version-checker",
+				PinnedByDigest: true,
 				IsLatest:       false,
 			},
 		},
@@ -240,10 +263,311 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "sha:123",
 				LatestVersion:  "v0.2.0@sha:123",
+				LatestSHA:      "sha:123",
 				ImageURL:       "localhost:5000/joshvanl/version-checker",
+				PinnedByDigest: true,
 				IsLatest:       true,
 			},
 		},
+		"if registry reports platform, use it over the defaults": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:v0.2.0",
+			opts: &api.Options{
+				DefaultOS:   "windows",
+				DefaultArch: "arm64",
+			},
+			searchResp: &api.ImageTag{
+				Tag:          "v0.2.0",
+				SHA:          "sha:123",
+				OS:           "linux",
+				Architecture: "amd64",
+			},
+			expResult: &Result{
+				CurrentVersion: "v0.2.0",
+				LatestVersion:  "v0.2.0",
+				LatestSHA:      "sha:123",
+				ImageURL:       "localhost:5000/version-checker",
+				IsLatest:       true,
+				OS:             "linux",
+				Architecture:   "amd64",
+				PlatformSource: PlatformSourceRegistry,
+				VersionsBehind: intp(0),
+			},
+		},
+		"if registry doesn't report platform, use the defaults": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:v0.2.0",
+			opts: &api.Options{
+				DefaultOS:   "linux",
+				DefaultArch: "arm64",
+			},
+			searchResp: &api.ImageTag{
+				Tag: "v0.2.0",
+				SHA: "sha:123",
+			},
+			expResult: &Result{
+				CurrentVersion: "v0.2.0",
+				LatestVersion:  "v0.2.0",
+				LatestSHA:      "sha:123",
+				ImageURL:       "localhost:5000/version-checker",
+				IsLatest:       true,
+				OS:             "linux",
+				Architecture:   "arm64",
+				PlatformSource: PlatformSourceDefault,
+				VersionsBehind: intp(0),
+			},
+		},
+		"artifacts which are not images should not have a platform": {
+			statusSHA: "localhost:5000/charts/cert-manager@sha:123",
+			imageURL:  "localhost:5000/charts/cert-manager:v1.14.0",
+			opts: &api.Options{
+				DefaultOS:   "linux",
+				DefaultArch: "arm64",
+			},
+			searchResp: &api.ImageTag{
+				Tag:          "v1.15.0",
+				SHA:          "sha:456",
+				Architecture: "amd64",
+				ArtifactType: api.ArtifactTypeHelmChart,
+			},
+			expResult: &Result{
+				CurrentVersion: "v1.14.0",
+				LatestVersion:  "v1.15.0",
+				LatestSHA:      "sha:456",
+				ImageURL:       "localhost:5000/charts/cert-manager",
+				IsLatest:       false,
+				ArtifactType:   api.ArtifactTypeHelmChart,
+				VersionsBehind: intp(0),
+			},
+		},
+		"if registry only reports architecture, default the os": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/joshvanl/version-checker@sha:123",
+			opts: &api.Options{
+				DefaultOS: "linux",
+			},
+			searchResp: &api.ImageTag{
+				Tag:          "",
+				SHA:          "sha:123",
+				Architecture: "amd64",
+			},
+			expResult: &Result{
+				CurrentVersion: "sha:123",
+				LatestVersion:  "sha:123",
+				LatestSHA:      "sha:123",
+				ImageURL:       "localhost:5000/joshvanl/version-checker",
+				PinnedByDigest: true,
+				IsLatest:       true,
+				OS:             "linux",
+				Architecture:   "amd64",
+				PlatformSource: PlatformSourceDefault,
+			},
+		},
+		"if date-sha tag has a later date, then not latest": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:20240312-a1b2c3d",
+			opts:      &api.Options{VersionScheme: api.VersionSchemeDateSHA},
+			searchResp: &api.ImageTag{
+				Tag: "20240401-e4f5a6b",
+				SHA: "sha:456",
+			},
+			expResult: &Result{
+				CurrentVersion: "20240312-a1b2c3d",
+				LatestVersion:  "20240401-e4f5a6b",
+				LatestSHA:      "sha:456",
+				ImageURL:       "localhost:5000/version-checker",
+				IsLatest:       false,
+			},
+		},
+		"if epoch tag has the latest epoch and version, then latest": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:1_1.9.0",
+			opts:      &api.Options{VersionScheme: api.VersionSchemeEpoch},
+			searchResp: &api.ImageTag{
+				Tag: "1_1.9.0",
+				SHA: "sha:123",
+			},
+			expResult: &Result{
+				CurrentVersion: "1_1.9.0",
+				LatestVersion:  "1_1.9.0",
+				LatestSHA:      "sha:123",
+				ImageURL:       "localhost:5000/version-checker",
+				IsLatest:       true,
+			},
+		},
+		"if epoch tag has a lower epoch than the latest, then not latest": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:2.5.0",
+			opts:      &api.Options{VersionScheme: api.VersionSchemeEpoch},
+			searchResp: &api.ImageTag{
+				Tag: "1_1.0.0",
+				SHA: "sha:456",
+			},
+			expResult: &Result{
+				CurrentVersion: "2.5.0",
+				LatestVersion:  "1_1.0.0",
+				LatestSHA:      "sha:456",
+				ImageURL:       "localhost:5000/version-checker",
+				IsLatest:       false,
+			},
+		},
+		"if epoch tag has a greater epoch than the latest, then ahead of registry and not latest": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:2_1.4.0",
+			opts:      &api.Options{VersionScheme: api.VersionSchemeEpoch},
+			searchResp: &api.ImageTag{
+				Tag: "1_1.9.0",
+				SHA: "sha:456",
+			},
+			expResult: &Result{
+				CurrentVersion:    "2_1.4.0",
+				LatestVersion:     "1_1.9.0",
+				LatestSHA:         "sha:456",
+				ImageURL:          "localhost:5000/version-checker",
+				IsLatest:          false,
+				IsAheadOfRegistry: true,
+			},
+		},
+		"if v9.9.9 is greater than the latest version, then ahead of registry and not latest": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:v9.9.9",
+			opts:      new(api.Options),
+			searchResp: &api.ImageTag{
+				Tag: "v0.2.0",
+				SHA: "sha:456",
+			},
+			expResult: &Result{
+				CurrentVersion:    "v9.9.9",
+				LatestVersion:     "v0.2.0",
+				LatestSHA:         "sha:456",
+				ImageURL:          "localhost:5000/version-checker",
+				IsLatest:          false,
+				IsAheadOfRegistry: true,
+				VersionsBehind:    intp(0),
+			},
+		},
+		"if date-sha tag has a later date than the latest, then ahead of registry and not latest": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:20240501-a1b2c3d",
+			opts:      &api.Options{VersionScheme: api.VersionSchemeDateSHA},
+			searchResp: &api.ImageTag{
+				Tag: "20240401-e4f5a6b",
+				SHA: "sha:456",
+			},
+			expResult: &Result{
+				CurrentVersion:    "20240501-a1b2c3d",
+				LatestVersion:     "20240401-e4f5a6b",
+				LatestSHA:         "sha:456",
+				ImageURL:          "localhost:5000/version-checker",
+				IsLatest:          false,
+				IsAheadOfRegistry: true,
+			},
+		},
+		"if date-sha tag is the same tag and sha, then latest": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:20240312-a1b2c3d",
+			opts:      &api.Options{VersionScheme: api.VersionSchemeDateSHA},
+			searchResp: &api.ImageTag{
+				Tag: "20240312-a1b2c3d",
+				SHA: "sha:123",
+			},
+			expResult: &Result{
+				CurrentVersion: "20240312-a1b2c3d",
+				LatestVersion:  "20240312-a1b2c3d",
+				LatestSHA:      "sha:123",
+				ImageURL:       "localhost:5000/version-checker",
+				IsLatest:       true,
+			},
+		},
+		"if date-sha tag has the same date but a different suffix, then not latest": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:2024.03.12-a1b2c3d",
+			opts: &api.Options{
+				VersionScheme: api.VersionSchemeDateSHA,
+				DateLayout:    "2006.01.02",
+			},
+			searchResp: &api.ImageTag{
+				Tag: "2024.03.12-e4f5a6b",
+				SHA: "sha:456",
+			},
+			expResult: &Result{
+				CurrentVersion: "2024.03.12-a1b2c3d",
+				LatestVersion:  "2024.03.12-e4f5a6b",
+				LatestSHA:      "sha:456",
+				ImageURL:       "localhost:5000/version-checker",
+				IsLatest:       false,
+			},
+		},
+		"if date-sha tag has no leading date, then not latest": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:main-a1b2c3d",
+			opts:      &api.Options{VersionScheme: api.VersionSchemeDateSHA},
+			searchResp: &api.ImageTag{
+				Tag: "20240312-e4f5a6b",
+				SHA: "sha:456",
+			},
+			expResult: &Result{
+				CurrentVersion: "main-a1b2c3d",
+				LatestVersion:  "20240312-e4f5a6b",
+				LatestSHA:      "sha:456",
+				ImageURL:       "localhost:5000/version-checker",
+				IsLatest:       false,
+
+				IsCurrentUnparseable: true,
+			},
+		},
+		"if semver tag is not a version, then unparseable without versions behind": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:stable",
+			opts:      new(api.Options),
+			searchResp: &api.ImageTag{
+				Tag: "v0.2.0",
+				SHA: "sha:456",
+			},
+			expResult: &Result{
+				CurrentVersion: "stable",
+				LatestVersion:  "v0.2.0",
+				LatestSHA:      "sha:456",
+				ImageURL:       "localhost:5000/version-checker",
+				IsLatest:       false,
+
+				IsCurrentUnparseable: true,
+			},
+		},
+		"if epoch tag is not a version, then unparseable": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:stable",
+			opts:      &api.Options{VersionScheme: api.VersionSchemeEpoch},
+			searchResp: &api.ImageTag{
+				Tag: "1_1.9.0",
+				SHA: "sha:456",
+			},
+			expResult: &Result{
+				CurrentVersion: "stable",
+				LatestVersion:  "1_1.9.0",
+				LatestSHA:      "sha:456",
+				ImageURL:       "localhost:5000/version-checker",
+				IsLatest:       false,
+
+				IsCurrentUnparseable: true,
+			},
+		},
+		"if date-sha tag is the same tag, but different sha, then not latest": {
+			statusSHA: "localhost:5000/version-checker@sha:123",
+			imageURL:  "localhost:5000/version-checker:20240312-a1b2c3d",
+			opts:      &api.Options{VersionScheme: api.VersionSchemeDateSHA},
+			searchResp: &api.ImageTag{
+				Tag: "20240312-a1b2c3d",
+				SHA: "sha:456",
+			},
+			expResult: &Result{
+				CurrentVersion: "20240312-a1b2c3d@sha:123",
+				LatestVersion:  "20240312-a1b2c3d@sha:456",
+				LatestSHA:      "sha:456",
+				ImageURL:       "localhost:5000/version-checker",
+				IsLatest:       false,
+			},
+		},
 		"if using sha and is latest, return true and no tag if non exists": {
 			statusSHA: "localhost:5000/version-checker@sha:123",
 			imageURL:  "localhost:5000/joshvanl/version-checker@sha:123",
@@ -255,7 +579,9 @@ func TestContainer(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "sha:123",
 				LatestVersion:  "sha:123",
+				LatestSHA:      "sha:123",
 				ImageURL:       "localhost:5000/joshvanl/version-checker",
+				PinnedByDigest: true,
 				IsLatest:       true,
 			},
 		},
@@ -263,7 +589,130 @@ func TestContainer(t *testing.T) {
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			checker := New(search.New().With(test.searchResp, nil))
+			checker := New(search.New().With(test.searchResp, nil), nil)
+			pod := &corev1.Pod{
+				Status: corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:    "test-name",
+							ImageID: test.statusSHA,
+						},
+					},
+				},
+			}
+			container := &corev1.Container{
+				Name:  "test-name",
+				Image: test.imageURL,
+			}
+
+			result, err := checker.Container(context.TODO(), logrus.NewEntry(logrus.New()), pod, container, test.opts)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(test.expResult, result) {
+				t.Errorf("got unexpected result, exp=%#+v got=%#+v",
+					test.expResult, result)
+			}
+		})
+	}
+}
+
+func TestContainerMaxVersion(t *testing.T) {
+	// The search responds with the recommended latest when the ceiling is
+	// set, and the absolute latest without it.
+	fakeSearch := search.New().WithFunc(func(opts *api.Options) (*api.ImageTag, error) {
+		if opts.MaxVersion != nil {
+			return &api.ImageTag{Tag: "v3.4.0", SHA: "sha:340"}, nil
+		}
+		return &api.ImageTag{Tag: "v3.6.1", SHA: "sha:361"}, nil
+	})
+
+	tests := map[string]struct {
+		imageURL  string
+		statusSHA string
+		opts      *api.Options
+		expResult *Result
+	}{
+		"below the ceiling should not be latest or absolute latest": {
+			imageURL:  "docker.io/jetstack/version-checker:v3.3.0",
+			statusSHA: "docker.io/jetstack/version-checker@sha:330",
+			opts:      &api.Options{MaxVersion: stringp("3.4.0")},
+			expResult: &Result{
+				CurrentVersion:        "v3.3.0",
+				LatestVersion:         "v3.4.0",
+				LatestSHA:             "sha:340",
+				IsLatest:              false,
+				ImageURL:              "docker.io/jetstack/version-checker",
+				AbsoluteLatestVersion: "v3.6.1",
+				IsAbsoluteLatest:      false,
+				VersionsBehind:        intp(0),
+			},
+		},
+		"at the ceiling should be latest but not absolute latest": {
+			imageURL:  "docker.io/jetstack/version-checker:v3.4.0",
+			statusSHA: "docker.io/jetstack/version-checker@sha:340",
+			opts:      &api.Options{MaxVersion: stringp("3.4.0")},
+			expResult: &Result{
+				CurrentVersion:        "v3.4.0",
+				LatestVersion:         "v3.4.0",
+				LatestSHA:             "sha:340",
+				IsLatest:              true,
+				ImageURL:              "docker.io/jetstack/version-checker",
+				AbsoluteLatestVersion: "v3.6.1",
+				IsAbsoluteLatest:      false,
+				VersionsBehind:        intp(0),
+			},
+		},
+		"at the absolute latest should be both": {
+			imageURL:  "docker.io/jetstack/version-checker:v3.6.1",
+			statusSHA: "docker.io/jetstack/version-checker@sha:361",
+			opts:      &api.Options{MaxVersion: stringp("3.4.0")},
+			expResult: &Result{
+				CurrentVersion:        "v3.6.1",
+				LatestVersion:         "v3.4.0",
+				LatestSHA:             "sha:340",
+				IsLatest:              true,
+				ImageURL:              "docker.io/jetstack/version-checker",
+				AbsoluteLatestVersion: "v3.6.1",
+				IsAbsoluteLatest:      true,
+				VersionsBehind:        intp(0),
+			},
+		},
+		"ahead of the absolute latest should be ahead of registry": {
+			imageURL:  "docker.io/jetstack/version-checker:v9.9.9",
+			statusSHA: "docker.io/jetstack/version-checker@sha:999",
+			opts:      &api.Options{MaxVersion: stringp("3.4.0")},
+			expResult: &Result{
+				CurrentVersion:        "v9.9.9",
+				LatestVersion:         "v3.4.0",
+				LatestSHA:             "sha:340",
+				IsLatest:              false,
+				ImageURL:              "docker.io/jetstack/version-checker",
+				AbsoluteLatestVersion: "v3.6.1",
+				IsAbsoluteLatest:      true,
+				IsAheadOfRegistry:     true,
+				VersionsBehind:        intp(0),
+			},
+		},
+		"without a ceiling the absolute latest should not be set": {
+			imageURL:  "docker.io/jetstack/version-checker:v3.3.0",
+			statusSHA: "docker.io/jetstack/version-checker@sha:330",
+			opts:      &api.Options{},
+			expResult: &Result{
+				CurrentVersion: "v3.3.0",
+				LatestVersion:  "v3.6.1",
+				LatestSHA:      "sha:361",
+				IsLatest:       false,
+				ImageURL:       "docker.io/jetstack/version-checker",
+				VersionsBehind: intp(0),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			checker := New(fakeSearch, nil)
 			pod := &corev1.Pod{
 				Status: corev1.PodStatus{
 					ContainerStatuses: []corev1.ContainerStatus{
@@ -292,6 +741,289 @@ func TestContainer(t *testing.T) {
 	}
 }
 
+func TestContainerVersionsBehind(t *testing.T) {
+	tests := map[string]struct {
+		imageURL          string
+		opts              *api.Options
+		versionsBehindErr error
+		expVersionsBehind *int
+		expErr            bool
+	}{
+		"semver versions should be counted": {
+			imageURL:          "docker.io/jetstack/version-checker:v0.1.0",
+			opts:              &api.Options{},
+			expVersionsBehind: intp(3),
+		},
+		"digests should not be counted": {
+			imageURL: "docker.io/jetstack/version-checker:latest",
+			opts:     &api.Options{},
+		},
+		"dates should not be counted": {
+			imageURL: "docker.io/jetstack/version-checker:20240101-abcdef",
+			opts:     &api.Options{VersionScheme: api.VersionSchemeDateSHA},
+		},
+		"failing to count should error": {
+			imageURL:          "docker.io/jetstack/version-checker:v0.1.0",
+			opts:              &api.Options{},
+			versionsBehindErr: errors.New("registry unavailable"),
+			expErr:            true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			fakeSearch := search.New().
+				With(&api.ImageTag{Tag: "v0.4.0", SHA: "sha:040"}, nil).
+				WithVersionsBehind(3, test.versionsBehindErr)
+			checker := New(fakeSearch, nil)
+			pod := &corev1.Pod{
+				Status: corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:    "test-name",
+							ImageID: "docker.io/jetstack/version-checker@sha:010",
+						},
+					},
+				},
+			}
+			container := &corev1.Container{
+				Name:  "test-name",
+				Image: test.imageURL,
+			}
+
+			result, err := checker.Container(context.TODO(), logrus.NewEntry(logrus.New()), pod, container, test.opts)
+			if test.expErr {
+				if err == nil {
+					t.Errorf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(test.expVersionsBehind, result.VersionsBehind) {
+				t.Errorf("got unexpected versions behind, exp=%v got=%v",
+					test.expVersionsBehind, result.VersionsBehind)
+			}
+		})
+	}
+}
+
+func TestImage(t *testing.T) {
+	tests := map[string]struct {
+		image     string
+		expResult *Result
+		expErr    bool
+	}{
+		"older versions should not be latest": {
+			image: "quay.io/jetstack/version-checker:v0.1.0",
+			expResult: &Result{
+				CurrentVersion: "v0.1.0",
+				LatestVersion:  "v0.4.0",
+				LatestSHA:      "sha:040",
+				ImageURL:       "quay.io/jetstack/version-checker",
+				VersionsBehind: intp(0),
+			},
+		},
+		"the same version should be latest, without a digest to compare": {
+			image: "quay.io/jetstack/version-checker:v0.4.0",
+			expResult: &Result{
+				CurrentVersion: "v0.4.0",
+				LatestVersion:  "v0.4.0",
+				LatestSHA:      "sha:040",
+				IsLatest:       true,
+				ImageURL:       "quay.io/jetstack/version-checker",
+				VersionsBehind: intp(0),
+			},
+		},
+		"latest tags should be compared by digest": {
+			image: "quay.io/jetstack/version-checker:latest@sha:040",
+			expResult: &Result{
+				CurrentVersion: "sha:040",
+				LatestVersion:  "v0.4.0@sha:040",
+				LatestSHA:      "sha:040",
+				IsLatest:       true,
+				ImageURL:       "quay.io/jetstack/version-checker",
+				PinnedByDigest: true,
+			},
+		},
+		"latest tags without a digest should error": {
+			image:  "quay.io/jetstack/version-checker",
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			checker := New(search.New().With(&api.ImageTag{Tag: "v0.4.0", SHA: "sha:040"}, nil), nil)
+
+			result, err := checker.Image(context.TODO(), logrus.NewEntry(logrus.New()), test.image, &api.Options{})
+			if test.expErr {
+				if err == nil {
+					t.Errorf("expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(test.expResult, result) {
+				t.Errorf("got unexpected result, exp=%#+v got=%#+v",
+					test.expResult, result)
+			}
+		})
+	}
+}
+
+// fakeBaseImages resolves base images by image reference, erroring for
+// unknown references.
+type fakeBaseImages map[string]*baseimage.BaseImage
+
+func (f fakeBaseImages) BaseImage(_ context.Context, imageRef string) (*baseimage.BaseImage, error) {
+	baseImage, ok := f[imageRef]
+	if !ok {
+		return nil, errors.New("image not found")
+	}
+	return baseImage, nil
+}
+
+func TestContainerBaseImage(t *testing.T) {
+	fakeSearch := search.New().WithImageFunc(func(imageURL string, opts *api.Options) (*api.ImageTag, error) {
+		switch imageURL {
+		case "docker.io/jetstack/version-checker":
+			return &api.ImageTag{Tag: "v0.2.0", SHA: "sha:app"}, nil
+		case "docker.io/library/alpine":
+			return &api.ImageTag{Tag: "3.20", SHA: "sha:320"}, nil
+		case "docker.io/library/debian":
+			return &api.ImageTag{Tag: "latest", SHA: "sha:debian-new"}, nil
+		}
+		return nil, errors.New("not found")
+	})
+
+	appResult := func(baseImage *Result) *Result {
+		return &Result{
+			CurrentVersion: "v0.2.0",
+			LatestVersion:  "v0.2.0",
+			LatestSHA:      "sha:app",
+			IsLatest:       true,
+			ImageURL:       "docker.io/jetstack/version-checker",
+			VersionsBehind: intp(0),
+			BaseImage:      baseImage,
+		}
+	}
+
+	tests := map[string]struct {
+		baseImages fakeBaseImages
+		opts       *api.Options
+		expResult  *Result
+	}{
+		"base image should not be checked if not enabled": {
+			baseImages: fakeBaseImages{
+				"docker.io/jetstack/version-checker@sha:app": {Name: "docker.io/library/alpine:3.19"},
+			},
+			opts:      &api.Options{},
+			expResult: appResult(nil),
+		},
+		"outdated base image should not be latest": {
+			baseImages: fakeBaseImages{
+				"docker.io/jetstack/version-checker@sha:app": {Name: "docker.io/library/alpine:3.19"},
+			},
+			opts: &api.Options{CheckBaseImage: true},
+			expResult: appResult(&Result{
+				CurrentVersion: "3.19",
+				LatestVersion:  "3.20",
+				LatestSHA:      "sha:320",
+				IsLatest:       false,
+				ImageURL:       "docker.io/library/alpine",
+			}),
+		},
+		"latest base image should be latest": {
+			baseImages: fakeBaseImages{
+				"docker.io/jetstack/version-checker@sha:app": {Name: "docker.io/library/alpine:3.20", Digest: "sha:320"},
+			},
+			opts: &api.Options{CheckBaseImage: true},
+			expResult: appResult(&Result{
+				CurrentVersion: "3.20",
+				LatestVersion:  "3.20",
+				LatestSHA:      "sha:320",
+				IsLatest:       true,
+				ImageURL:       "docker.io/library/alpine",
+			}),
+		},
+		"base image with a latest tag should be compared by digest": {
+			baseImages: fakeBaseImages{
+				"docker.io/jetstack/version-checker@sha:app": {Name: "docker.io/library/debian:latest", Digest: "sha:debian-old"},
+			},
+			opts: &api.Options{CheckBaseImage: true},
+			expResult: appResult(&Result{
+				CurrentVersion: "sha:debian-old",
+				LatestVersion:  "latest@sha:debian-new",
+				LatestSHA:      "sha:debian-new",
+				IsLatest:       false,
+				ImageURL:       "docker.io/library/debian",
+			}),
+		},
+		"base image with a latest tag but no digest should not be checked": {
+			baseImages: fakeBaseImages{
+				"docker.io/jetstack/version-checker@sha:app": {Name: "docker.io/library/debian:latest"},
+			},
+			opts:      &api.Options{CheckBaseImage: true},
+			expResult: appResult(nil),
+		},
+		"image without a base image should not check it": {
+			baseImages: fakeBaseImages{
+				"docker.io/jetstack/version-checker@sha:app": nil,
+			},
+			opts:      &api.Options{CheckBaseImage: true},
+			expResult: appResult(nil),
+		},
+		"failing to resolve the base image should still report the image": {
+			baseImages: fakeBaseImages{},
+			opts:       &api.Options{CheckBaseImage: true},
+			expResult:  appResult(nil),
+		},
+		"failing to check the base image should still report the image": {
+			baseImages: fakeBaseImages{
+				"docker.io/jetstack/version-checker@sha:app": {Name: "docker.io/library/busybox:1.36"},
+			},
+			opts:      &api.Options{CheckBaseImage: true},
+			expResult: appResult(nil),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			checker := New(fakeSearch, test.baseImages)
+			pod := &corev1.Pod{
+				Status: corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:    "test-name",
+							ImageID: "docker.io/jetstack/version-checker@sha:app",
+						},
+					},
+				},
+			}
+			container := &corev1.Container{
+				Name:  "test-name",
+				Image: "docker.io/jetstack/version-checker:v0.2.0",
+			}
+
+			result, err := checker.Container(context.TODO(), logrus.NewEntry(logrus.New()), pod, container, test.opts)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(test.expResult, result) {
+				t.Errorf("got unexpected result, exp=%#+v got=%#+v",
+					test.expResult, result)
+			}
+		})
+	}
+}
+
 func TestContainerStatusImageSHA(t *testing.T) {
 	tests := map[string]struct {
 		status []corev1.ContainerStatus
@@ -398,7 +1130,7 @@ func TestIsLatestOrEmptyTag(t *testing.T) {
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			checker := New(search.New())
+			checker := New(search.New(), nil)
 			if is := checker.isLatestOrEmptyTag(test.tag); is != test.expIs {
 				t.Errorf("unexpected isLatestOrEmptyTag exp=%t got=%t",
 					test.expIs, is)
@@ -475,7 +1207,7 @@ func TestIsLatestSemver(t *testing.T) {
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			checker := New(search.New().With(test.searchResp, nil))
+			checker := New(search.New().With(test.searchResp, nil), nil)
 			latestImage, isLatest, err := checker.isLatestSemver(context.TODO(), test.imageURL, test.currentSHA, test.currentImage, nil)
 			if err != nil {
 				t.Fatal(err)
@@ -509,6 +1241,7 @@ func TestIsLatestSHA(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "123",
 				LatestVersion:  "456",
+				LatestSHA:      "456",
 				IsLatest:       false,
 				ImageURL:       "docker.io",
 			},
@@ -522,6 +1255,7 @@ func TestIsLatestSHA(t *testing.T) {
 			expResult: &Result{
 				CurrentVersion: "123",
 				LatestVersion:  "123",
+				LatestSHA:      "123",
 				IsLatest:       true,
 				ImageURL:       "docker.io",
 			},
@@ -530,7 +1264,7 @@ func TestIsLatestSHA(t *testing.T) {
 
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			checker := New(search.New().With(test.searchResp, nil))
+			checker := New(search.New().With(test.searchResp, nil), nil)
 			result, err := checker.isLatestSHA(context.TODO(), test.imageURL, test.currentSHA, nil)
 			if err != nil {
 				t.Fatal(err)
@@ -594,7 +1328,6 @@ func TestURLAndTagFromImage(t *testing.T) {
 			version: "",
 			sha:     "sha:123",
 		},
-
 		"url in image with version, return sha and version": {
 			image:   "localhost:5000/version-checker:v0.1@sha:123",
 			url:     "localhost:5000/version-checker",
@@ -615,6 +1348,10 @@ func TestURLAndTagFromImage(t *testing.T) {
 	}
 }
 
+func intp(i int) *int {
+	return &i
+}
+
 func stringp(s string) *string {
 	return &s
 }
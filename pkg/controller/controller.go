@@ -3,12 +3,15 @@ package controller
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	corev1listers "k8s.io/client-go/listers/core/v1"
@@ -16,12 +19,16 @@ import (
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/utils/clock"
 
+	"github.com/jetstack/version-checker/pkg/api"
 	"github.com/jetstack/version-checker/pkg/client"
 	"github.com/jetstack/version-checker/pkg/controller/checker"
 	"github.com/jetstack/version-checker/pkg/controller/scheduler"
 	"github.com/jetstack/version-checker/pkg/controller/search"
 	"github.com/jetstack/version-checker/pkg/metrics"
 	"github.com/jetstack/version-checker/pkg/version"
+	"github.com/jetstack/version-checker/pkg/version/baseimage"
+	"github.com/jetstack/version-checker/pkg/version/manifest"
+	"github.com/jetstack/version-checker/pkg/version/signature"
 )
 
 const (
@@ -33,6 +40,9 @@ const (
 type Controller struct {
 	log *logrus.Entry
 
+	// cluster is the name of the cluster being checked.
+	cluster string
+
 	kubeClient         kubernetes.Interface
 	podLister          corev1listers.PodLister
 	workqueue          workqueue.TypedRateLimitingInterface[any]
@@ -41,78 +51,478 @@ type Controller struct {
 	metrics *metrics.Metrics
 	checker *checker.Checker
 
-	defaultTestAll bool
+	// runSearch is whether the search garbage collectors are run by the
+	// controller, rather than being shared.
+	runSearch bool
+
+	healthCheckPeriod time.Duration
+
+	defaultTestAll  bool
+	containerStates map[ContainerState]bool
+	minPodAge       time.Duration
+
+	// optionDefaults are the defaults of the options of containers which are
+	// not set by annotations.
+	optionDefaults Options
+
+	disabledContainerMetric bool
+
+	unparseableCurrent UnparseableCurrent
+
+	// allowedRegistries are the registry hosts images may be from, where
+	// others are exposed as disallowed, and are not checked if
+	// blockDisallowedRegistries is set. All registries are allowed if empty.
+	allowedRegistries         []string
+	blockDisallowedRegistries bool
+
+	// shard is the shard of namespaces whose pods are checked.
+	shard Shard
+
+	// defaults are the default options of containers, loaded from the
+	// defaults ConfigMap if set.
+	defaultsConfigMap types.NamespacedName
+	defaultsMu        sync.RWMutex
+	defaults          map[string]string
+
+	// maintenance are the maintenance windows of registries, loaded from the
+	// maintenance ConfigMap if set, and paused is whether they were active
+	// when last evaluated.
+	maintenanceConfigMap types.NamespacedName
+	maintenanceMu        sync.RWMutex
+	maintenance          map[string]maintenanceWindow
+	paused               map[string]bool
+
+	// deprecatedRepositories are the patterns of deprecated image
+	// repositories, loaded from the deprecated repositories ConfigMap if set.
+	deprecatedRepositoriesConfigMap types.NamespacedName
+	deprecatedMu                    sync.RWMutex
+	deprecatedRepositories          []deprecatedRepository
+
+	// registryHost returns the registry host of an image URL.
+	registryHost func(imageURL string) string
+
+	// imageExists, if set, returns whether the image reference of a container
+	// can be resolved by its registry.
+	imageExists func(ctx context.Context, reference string) (bool, error)
+
+	// credentials are the registry credentials loaded from the credentials
+	// secrets of namespaces, cached until the cache timeout.
+	namespaceCredentialsSecret  string
+	namespaceCredentialsSecrets map[string]string
+	credentialsCacheTimeout     time.Duration
+	credentialsMu               sync.Mutex
+	credentials                 map[string]*namespaceCredentials
+
+	noVersionRequeuePeriod time.Duration
+
+	// adaptive, if set, are the check intervals of containers, adapted to how
+	// often their results change, which pods are rechecked after.
+	adaptive *scheduler.AdaptiveIntervals
+
+	// noVersion are the containers where no version was found, which are not
+	// checked again until the backoff expires, or their image or options
+	// change.
+	noVersionBackoff time.Duration
+	noVersionMu      sync.Mutex
+	noVersion        map[string]noVersionEntry
+
+	// tagDigests are the digests last seen of the latest tags of containers,
+	// to detect tags being pushed again.
+	tagDigests tagDigests
+
+	// extraImages are the names of the extra images last synced of each pod,
+	// by pod key, so that their results are removed once no longer listed.
+	extraMu     sync.Mutex
+	extraImages map[string][]string
+
+	// held are the pods which informer resyncs should not requeue, until the
+	// given time.
+	heldMu sync.Mutex
+	held   map[string]time.Time
+
+	// synced is closed once the pod informer has synced.
+	synced chan struct{}
+}
+
+// Options are used to configure the behaviour of the Controller.
+type Options struct {
+	// CacheTimeout is the time for an image version in the cache to be
+	// considered fresh.
+	CacheTimeout time.Duration
+
+	// DefaultTestAll will test all containers, unless explicitly disabled.
+	DefaultTestAll bool
+
+	// DefaultOS and DefaultArch are reported for images where the registry
+	// does not report the platform, and no annotation is set.
+	DefaultOS   api.OS
+	DefaultArch api.Architecture
+
+	// ExcludeArchs are the architectures whose images are not considered,
+	// where no annotation is set.
+	ExcludeArchs []api.Architecture
+
+	// DefaultSeverity is the severity of containers being outdated, where no
+	// annotation is set.
+	DefaultSeverity api.Severity
+
+	// DisabledContainerMetric exposes containers whose version check is
+	// disabled with the check disabled metric, rather than only removing
+	// their version check metrics.
+	DisabledContainerMetric bool
+
+	// UnparseableCurrent is the action taken for containers whose current
+	// version cannot be parsed by the version scheme of their check.
+	// Containers are skipped if empty.
+	UnparseableCurrent UnparseableCurrent
+
+	// ContainerStates are the states a container must be in to be checked. All
+	// containers are checked if empty.
+	ContainerStates []ContainerState
+
+	// MinPodAge is the age pods must reach since their creation to be checked,
+	// so that short-lived pods are skipped. All pods are checked if 0.
+	MinPodAge time.Duration
+
+	// AllowedRegistries are the registry hosts images may be from, which may
+	// contain wildcards such as *.corp. Containers of images from other
+	// registries are exposed with the disallowed registry metric, and are not
+	// checked if BlockDisallowedRegistries is set. All registries are allowed
+	// if empty.
+	AllowedRegistries         []string
+	BlockDisallowedRegistries bool
+
+	// Shard is the shard of namespaces whose pods are checked, so that the
+	// pods of large clusters can be divided between replicas. Pods of other
+	// namespaces are only held by their identity. All namespaces are checked
+	// if the shard count is 1 or less.
+	Shard Shard
+
+	// PreReleaseOrder is an ordered list of pre-release identifiers, from the
+	// lowest to the highest precedence, which overrides their lexical
+	// comparison.
+	PreReleaseOrder []string
+
+	// BuildSuffix, if set, matches the pre-releases which are builds of a
+	// version, ranked above the version rather than below.
+	BuildSuffix *regexp.Regexp
+
+	// DefaultsConfigMap, if set, is the ConfigMap of default options for all
+	// containers, keyed by annotation key without a container name, which are
+	// overridden by annotations. The ConfigMap is watched for changes.
+	DefaultsConfigMap types.NamespacedName
+
+	// MaintenanceConfigMap, if set, is the ConfigMap of maintenance windows,
+	// keyed by registry host, during which the checks of images from the
+	// registry are paused. The ConfigMap is watched for changes.
+	MaintenanceConfigMap types.NamespacedName
+
+	// DeprecatedRepositoriesConfigMap, if set, is the ConfigMap of deprecated
+	// image repositories, keyed by the reason they are deprecated, with
+	// values of repository patterns. Containers of images from deprecated
+	// repositories are exposed regardless of their tag. The ConfigMap is
+	// watched for changes.
+	DeprecatedRepositoriesConfigMap types.NamespacedName
+
+	// NamespaceCredentialsSecret, if set, is the name of the secret in the
+	// namespace of each pod with the registry credentials to check its images
+	// with, of the kubernetes.io/dockerconfigjson type, and
+	// NamespaceCredentialsSecrets the secret of individual namespaces, which
+	// takes precedence. The global credentials are used for registries
+	// without namespace credentials.
+	NamespaceCredentialsSecret  string
+	NamespaceCredentialsSecrets map[string]string
+
+	// RequeueBackoffBase and RequeueBackoffMax are the initial and maximum
+	// exponential backoff used to requeue pods which failed with a transient
+	// error.
+	RequeueBackoffBase time.Duration
+	RequeueBackoffMax  time.Duration
+
+	// NoVersionRequeuePeriod is the time to wait before requeueing pods where
+	// no version was found meeting the search criteria.
+	NoVersionRequeuePeriod time.Duration
+
+	// NoVersionBackoff is the time to wait before checking containers again,
+	// where no version was found meeting the search criteria, regardless of
+	// how often their pod is synced. Disabled if 0.
+	NoVersionBackoff time.Duration
+
+	// AdaptiveMinInterval and AdaptiveMaxInterval, if set, enable adaptive
+	// polling, where pods are rechecked after the shortest interval of their
+	// containers between these bounds, adapted to how often their results
+	// change, rather than after half of the cache timeout.
+	AdaptiveMinInterval time.Duration
+	AdaptiveMaxInterval time.Duration
+
+	// SignatureVerifier is used to verify the signatures of tags for
+	// containers which require them. May be nil if not configured.
+	SignatureVerifier *signature.Verifier
+
+	// ManifestProber, if set, is used to only select latest tags whose
+	// manifest can be fetched, falling back to the next highest tag.
+	ManifestProber *manifest.Prober
+
+	// ImageProber, if set, is used to verify that the image references of
+	// containers can be resolved, exposing those which cannot as missing. The
+	// caller is responsible for running it.
+	ImageProber *manifest.Prober
+
+	// BaseImageResolver is used to resolve the base images of containers
+	// which check them. May be nil, where base images are not checked.
+	BaseImageResolver *baseimage.Resolver
+
+	// ClusterName is the name of the cluster being checked, which is set as
+	// the cluster label of metrics.
+	ClusterName string
+
+	// Searcher, if set, is used to search for the latest images, so that
+	// lookups are shared between the controllers of multiple clusters. The
+	// caller is responsible for running it.
+	Searcher search.Searcher
+
+	// HealthCheckPeriod is how often the cluster API server is checked to be
+	// reachable. Disabled if 0.
+	HealthCheckPeriod time.Duration
+}
+
+// ApplyDefaults will set the options of a container, built from its
+// annotations, to the defaults of these options where they are not set by
+// annotations.
+func (o *Options) ApplyDefaults(opts *api.Options) {
+	if len(opts.DefaultOS) == 0 {
+		opts.DefaultOS = o.DefaultOS
+	}
+	if len(opts.DefaultArch) == 0 {
+		opts.DefaultArch = o.DefaultArch
+	}
+	if len(opts.ExcludeArchs) == 0 {
+		opts.ExcludeArchs = o.ExcludeArchs
+	}
+	if len(opts.Severity) == 0 {
+		opts.Severity = o.DefaultSeverity
+	}
+	opts.PreReleaseOrder = o.PreReleaseOrder
+	opts.BuildSuffix = o.BuildSuffix
 }
 
 func New(
-	cacheTimeout time.Duration,
+	opts Options,
 	metrics *metrics.Metrics,
 	imageClient *client.Client,
 	kubeClient kubernetes.Interface,
 	log *logrus.Entry,
-	defaultTestAll bool,
 ) *Controller {
-	workqueue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[any]())
+	workqueue := workqueue.NewTypedRateLimitingQueue(workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[any](opts.RequeueBackoffBase, opts.RequeueBackoffMax),
+		// Overall rate limit, as used by the default controller rate limiter
+		&workqueue.TypedBucketRateLimiter[any]{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	))
 	scheduledWorkQueue := scheduler.NewScheduledWorkQueue(clock.RealClock{}, workqueue.Add)
 
 	log = log.WithField("module", "controller")
-	versionGetter := version.New(log, imageClient, cacheTimeout)
-	search := search.New(log, cacheTimeout, versionGetter)
+	if len(opts.ClusterName) > 0 {
+		log = log.WithField("cluster", opts.ClusterName)
+	}
+
+	searcher, runSearch := opts.Searcher, false
+	if searcher == nil {
+		searcher, runSearch = NewSearcher(opts, imageClient, log), true
+	}
+
+	var baseImages checker.BaseImageResolver
+	if opts.BaseImageResolver != nil {
+		baseImages = opts.BaseImageResolver
+	}
+
+	var containerStates map[ContainerState]bool
+	if len(opts.ContainerStates) > 0 {
+		containerStates = make(map[ContainerState]bool)
+		for _, state := range opts.ContainerStates {
+			containerStates[state] = true
+		}
+	}
+
+	var adaptive *scheduler.AdaptiveIntervals
+	if opts.AdaptiveMaxInterval > 0 {
+		adaptive = scheduler.NewAdaptiveIntervals(opts.AdaptiveMinInterval, opts.AdaptiveMaxInterval)
+	}
 
 	c := &Controller{
 		log:                log,
+		cluster:            opts.ClusterName,
 		kubeClient:         kubeClient,
 		workqueue:          workqueue,
 		scheduledWorkQueue: scheduledWorkQueue,
 		metrics:            metrics,
-		checker:            checker.New(search),
-		defaultTestAll:     defaultTestAll,
+		checker:            checker.New(searcher, baseImages),
+		runSearch:          runSearch,
+		healthCheckPeriod:  opts.HealthCheckPeriod,
+		defaultTestAll:     opts.DefaultTestAll,
+		containerStates:    containerStates,
+		minPodAge:          opts.MinPodAge,
+		defaultsConfigMap:  opts.DefaultsConfigMap,
+
+		optionDefaults: Options{
+			DefaultOS:       opts.DefaultOS,
+			DefaultArch:     opts.DefaultArch,
+			ExcludeArchs:    opts.ExcludeArchs,
+			DefaultSeverity: opts.DefaultSeverity,
+			PreReleaseOrder: opts.PreReleaseOrder,
+			BuildSuffix:     opts.BuildSuffix,
+		},
+
+		disabledContainerMetric: opts.DisabledContainerMetric,
+
+		unparseableCurrent: opts.UnparseableCurrent,
+
+		allowedRegistries:         opts.AllowedRegistries,
+		blockDisallowedRegistries: opts.BlockDisallowedRegistries,
+
+		shard: opts.Shard,
+
+		maintenanceConfigMap: opts.MaintenanceConfigMap,
+		registryHost:         imageClient.RegistryHost,
+
+		deprecatedRepositoriesConfigMap: opts.DeprecatedRepositoriesConfigMap,
+
+		namespaceCredentialsSecret:  opts.NamespaceCredentialsSecret,
+		namespaceCredentialsSecrets: opts.NamespaceCredentialsSecrets,
+		credentialsCacheTimeout:     opts.CacheTimeout,
+		credentials:                 make(map[string]*namespaceCredentials),
+
+		noVersionRequeuePeriod: opts.NoVersionRequeuePeriod,
+		adaptive:               adaptive,
+		held:                   make(map[string]time.Time),
+		synced:                 make(chan struct{}),
+
+		noVersionBackoff: opts.NoVersionBackoff,
+		noVersion:        make(map[string]noVersionEntry),
+	}
+	if opts.ImageProber != nil {
+		c.imageExists = opts.ImageProber.ReferenceReachable
 	}
 
 	return c
 }
 
-// Run is a blocking func that will run the controller.
-func (c *Controller) Run(ctx context.Context, cacheRefreshRate time.Duration) error {
+// NewSearcher returns a new Searcher, which searches for the latest images
+// using the given image client.
+func NewSearcher(opts Options, imageClient *client.Client, log *logrus.Entry) search.Searcher {
+	versionGetter := version.New(log, imageClient, opts.CacheTimeout, opts.SignatureVerifier, opts.ManifestProber)
+	return search.New(log, opts.CacheTimeout, versionGetter)
+}
+
+// Run is a blocking func that will run the controller. Once the context is
+// cancelled, no new work is accepted and in-flight checks are given until
+// shutdownCtx is done to complete before they are cancelled.
+func (c *Controller) Run(ctx context.Context, cacheRefreshRate time.Duration, shutdownCtx context.Context) error {
 	defer c.workqueue.ShutDown()
 
-	sharedInformerFactory := informers.NewSharedInformerFactoryWithOptions(c.kubeClient, time.Second*30)
+	// Workers are given their own context so that in-flight checks are not
+	// aborted as soon as a shutdown is requested.
+	workerCtx, cancelWorkers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWorkers()
+
+	var informerOpts []informers.SharedInformerOption
+	if c.shard.Count > 1 {
+		c.log.Infof("checking pods of namespaces of shard %d of %d", c.shard.Index, c.shard.Count)
+		informerOpts = append(informerOpts, informers.WithTransform(c.shardTransform))
+	}
+
+	sharedInformerFactory := informers.NewSharedInformerFactoryWithOptions(c.kubeClient, time.Second*30, informerOpts...)
 	c.podLister = sharedInformerFactory.Core().V1().Pods().Lister()
 	podInformer := sharedInformerFactory.Core().V1().Pods().Informer()
 	_, err := podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: c.addObject,
-		UpdateFunc: func(old, new interface{}) {
-			if key, err := cache.MetaNamespaceKeyFunc(old); err == nil {
-				c.scheduledWorkQueue.Forget(key)
-			}
-			c.addObject(new)
-		},
+		AddFunc:    c.addObject,
+		UpdateFunc: c.updateObject,
 		DeleteFunc: c.deleteObject,
 	})
 	if err != nil {
 		return fmt.Errorf("error creating podInformer: %s", err)
 	}
 
+	// The defaults are synced first, so that pods are not checked without
+	// them.
+	if len(c.defaultsConfigMap.Name) > 0 {
+		if err := c.runDefaultsInformer(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Maintenance windows are synced first, so that paused registries are
+	// not checked.
+	if len(c.maintenanceConfigMap.Name) > 0 {
+		if err := c.runMaintenanceInformer(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Deprecated repositories are synced first, so that pods are not first
+	// checked without them.
+	if len(c.deprecatedRepositoriesConfigMap.Name) > 0 {
+		if err := c.runDeprecatedRepositoriesInformer(ctx); err != nil {
+			return err
+		}
+	}
+
 	c.log.Info("starting control loop")
 	sharedInformerFactory.Start(ctx.Done())
 	if !cache.WaitForCacheSync(ctx.Done(), podInformer.HasSynced) {
 		return fmt.Errorf("error waiting for informer caches to sync")
 	}
+	close(c.synced)
 
 	c.log.Info("starting workers")
 	// Launch 10 workers to process pod resources
+	var wg sync.WaitGroup
 	for i := 0; i < numWorkers; i++ {
-		go wait.Until(func() { c.runWorker(ctx, cacheRefreshRate) }, time.Second, ctx.Done())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.runWorker(workerCtx, cacheRefreshRate)
+		}()
 	}
 
 	// Start image tag garbage collector
-	go c.checker.Search().Run(cacheRefreshRate)
+	if c.runSearch {
+		go c.checker.Search().Run(cacheRefreshRate)
+	}
+
+	if c.healthCheckPeriod > 0 {
+		go c.runHealthCheck(ctx, c.healthCheckPeriod)
+	}
 
 	<-ctx.Done()
 
+	c.shutdown(shutdownCtx, &wg, cancelWorkers)
+
 	return nil
 }
 
+// shutdown stops the workqueue from accepting new items, and waits for the
+// workers to finish their in-flight items. If the context is done first, the
+// in-flight checks are cancelled.
+func (c *Controller) shutdown(ctx context.Context, wg *sync.WaitGroup, cancelWorkers context.CancelFunc) {
+	c.log.Info("shutting down, waiting for in-flight checks to complete")
+
+	drained := make(chan struct{})
+	go func() {
+		c.workqueue.ShutDownWithDrain()
+		wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		c.log.Info("all in-flight checks completed")
+	case <-ctx.Done():
+		c.log.Warn("in-flight checks did not complete within the shutdown timeout, cancelling")
+		cancelWorkers()
+		c.workqueue.ShutDown()
+	}
+}
+
 // runWorker is a long-running function that will continually call the
 // processNextWorkItem function in order to read and process a message on the
 // workqueue.
@@ -125,7 +535,15 @@ func (c *Controller) runWorker(ctx context.Context, searchReschedule time.Durati
 
 		key, ok := obj.(string)
 		if !ok {
-			return
+			c.workqueue.Done(obj)
+			continue
+		}
+
+		// Once shutting down, only finish what is already in-flight rather
+		// than working through the rest of the queue.
+		if c.workqueue.ShuttingDown() {
+			c.workqueue.Done(key)
+			continue
 		}
 
 		if err := c.processNextWorkItem(ctx, key, searchReschedule); err != nil {
@@ -139,23 +557,67 @@ func (c *Controller) addObject(obj interface{}) {
 	if err != nil {
 		return
 	}
+	if namespace, _, err := cache.SplitMetaNamespaceKey(key); err != nil || !c.shard.Contains(namespace) {
+		return
+	}
 	c.workqueue.AddRateLimited(key)
 }
 
+// updateObject will requeue the given updated pod, replacing any scheduled
+// recheck, unless it is an informer resync of a held pod.
+func (c *Controller) updateObject(old, new interface{}) {
+	if c.isResyncHeld(old, new) {
+		return
+	}
+	if key, err := cache.MetaNamespaceKeyFunc(old); err == nil {
+		c.scheduledWorkQueue.Forget(key)
+	}
+	c.addObject(new)
+}
+
 func (c *Controller) deleteObject(obj interface{}) {
 	pod, ok := obj.(*corev1.Pod)
-	if !ok {
+	if !ok || !c.shard.Contains(pod.Namespace) {
 		return
 	}
 
+	if key, err := cache.MetaNamespaceKeyFunc(pod); err == nil {
+		c.releaseResync(key)
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		c.log.Debugf("removing deleted pod init containers from metrics: %s/%s/%s",
+			pod.Namespace, pod.Name, container.Name)
+		c.forgetContainer(pod, container.Name, "init")
+	}
+
 	for _, container := range pod.Spec.Containers {
 		c.log.Debugf("removing deleted pod containers from metrics: %s/%s/%s",
 			pod.Namespace, pod.Name, container.Name)
-		c.metrics.RemoveImage(pod.Namespace, pod.Name, container.Name, "init")
-		c.metrics.RemoveImage(pod.Namespace, pod.Name, container.Name, "container")
+		c.forgetContainer(pod, container.Name, "container")
+	}
+
+	for _, name := range c.setExtraImages(pod, nil) {
+		c.forgetContainer(pod, name, extraContainerType)
 	}
 }
 
+// forgetContainer will remove the result of the given container of the pod
+// from metrics, and forget any state kept of it.
+func (c *Controller) forgetContainer(pod *corev1.Pod, containerName, containerType string) {
+	c.metrics.RemoveImage(c.cluster, pod.Namespace, pod.Name, containerName, containerType)
+
+	key := containerKey(pod, containerName, containerType)
+	c.releaseNoVersion(key)
+	if c.adaptive != nil {
+		c.adaptive.Forget(key)
+	}
+	c.tagDigests.forget(key)
+	c.metrics.RemoveDisallowedRegistry(c.cluster, pod.Namespace, pod.Name, containerName, containerType)
+	c.metrics.RemoveDeprecatedRepository(c.cluster, pod.Namespace, pod.Name, containerName, containerType)
+	c.metrics.RemoveImageMissing(c.cluster, pod.Namespace, pod.Name, containerName, containerType)
+}
+
 // processNextWorkItem will read a single work item off the workqueue and
 // attempt to process it, by calling the syncHandler.
 func (c *Controller) processNextWorkItem(ctx context.Context, key string, searchReschedule time.Duration) error {
@@ -175,14 +637,54 @@ func (c *Controller) processNextWorkItem(ctx context.Context, key string, search
 		return err
 	}
 
-	if err := c.sync(ctx, pod); err != nil {
-		c.scheduledWorkQueue.Add(pod, time.Second*20)
-		return fmt.Errorf("error syncing '%s/%s': %s, requeuing",
-			pod.Name, pod.Namespace, err)
+	// Pods which are too young are checked once they reach the minimum age,
+	// without being requeued by informer resyncs in the meantime.
+	if age := time.Since(pod.CreationTimestamp.Time); age < c.minPodAge {
+		c.log.Debugf("skipping pod %s/%s younger than the minimum pod age, checking in %s",
+			pod.Namespace, pod.Name, c.minPodAge-age)
+		c.workqueue.Forget(key)
+		c.holdResync(key, pod.CreationTimestamp.Add(c.minPodAge))
+		c.scheduledWorkQueue.Add(key, c.minPodAge-age)
+		return nil
 	}
 
-	// Check the image tag again after the cache timeout.
-	c.scheduledWorkQueue.Add(key, searchReschedule)
+	err = c.sync(ctx, pod)
+	if err == nil {
+		c.workqueue.Forget(key)
 
-	return nil
+		// Check the image tag again after the cache timeout, or the adapted
+		// interval of the pod. Adapted intervals are usually longer than the
+		// informer resync, so resyncs are held until the recheck, otherwise the
+		// pod would be checked on every resync once the cache expires.
+		interval := c.recheckInterval(pod, searchReschedule)
+		if c.adaptive != nil {
+			c.holdResync(key, time.Now().Add(interval))
+		} else {
+			c.releaseResync(key)
+		}
+		c.scheduledWorkQueue.Add(key, interval)
+
+		return nil
+	}
+
+	switch kindOfError(err) {
+	case errorKindNoVersion:
+		c.workqueue.Forget(key)
+		c.holdResync(key, time.Now().Add(c.noVersionRequeuePeriod))
+		c.scheduledWorkQueue.Add(key, c.noVersionRequeuePeriod)
+		return fmt.Errorf("error syncing '%s/%s': %s, requeuing in %s",
+			pod.Name, pod.Namespace, err, c.noVersionRequeuePeriod)
+
+	case errorKindPermanent:
+		c.workqueue.Forget(key)
+		c.holdResync(key, time.Time{})
+		return fmt.Errorf("error syncing '%s/%s': %s, not requeuing until the pod is updated",
+			pod.Name, pod.Namespace, err)
+
+	default:
+		c.releaseResync(key)
+		c.workqueue.AddRateLimited(key)
+		return fmt.Errorf("error syncing '%s/%s': %s, requeuing with backoff",
+			pod.Name, pod.Namespace, err)
+	}
 }
@@ -2,17 +2,28 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 
 	"github.com/jetstack/version-checker/pkg/client"
@@ -21,16 +32,21 @@ import (
 
 var testLogger = logrus.NewEntry(logrus.New())
 
+var testOptions = Options{
+	CacheTimeout:   5 * time.Minute,
+	DefaultTestAll: true,
+}
+
 func init() {
 	testLogger.Logger.SetOutput(io.Discard)
 }
 
 func TestNewController(t *testing.T) {
 	kubeClient := fake.NewSimpleClientset()
-	metrics := &metrics.Metrics{}
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
 	imageClient := &client.Client{}
 
-	controller := New(5*time.Minute, metrics, imageClient, kubeClient, testLogger, true)
+	controller := New(testOptions, metrics, imageClient, kubeClient, testLogger)
 
 	assert.NotNil(t, controller)
 	assert.Equal(t, controller.defaultTestAll, true)
@@ -41,15 +57,18 @@ func TestNewController(t *testing.T) {
 
 func TestRun(t *testing.T) {
 	kubeClient := fake.NewSimpleClientset()
-	metrics := &metrics.Metrics{}
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
 	imageClient := &client.Client{}
-	controller := New(5*time.Minute, metrics, imageClient, kubeClient, testLogger, true)
+	controller := New(testOptions, metrics, imageClient, kubeClient, testLogger)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Run the controller in a separate goroutine so the test doesn't block indefinitely
 	go func() {
-		err := controller.Run(ctx, 30*time.Second)
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelShutdown()
+
+		err := controller.Run(ctx, 30*time.Second, shutdownCtx)
 		assert.NoError(t, err)
 	}()
 
@@ -68,11 +87,56 @@ func TestRun(t *testing.T) {
 	assert.NotNil(t, controller.scheduledWorkQueue, "ScheduledWorkQueue should be initialized")
 }
 
+func TestRunShutdownTimeout(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
+	imageClient := &client.Client{}
+	controller := New(testOptions, metrics, imageClient, kubeClient, testLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelShutdown()
+
+	done := make(chan error)
+	go func() {
+		done <- controller.Run(ctx, 30*time.Second, shutdownCtx)
+	}()
+
+	time.Sleep(500 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("controller did not shut down within the shutdown timeout")
+	}
+
+	assert.True(t, controller.workqueue.ShuttingDown(), "Workqueue should no longer accept items")
+}
+
+func TestRunWorkerSkipsQueuedItemsOnShutdown(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
+	imageClient := &client.Client{}
+	controller := New(testOptions, metrics, imageClient, kubeClient, testLogger)
+
+	controller.workqueue.Add("default/queued-pod")
+	controller.workqueue.ShutDown()
+
+	// Should return without attempting to sync the queued item, which would
+	// panic as no pod lister has been configured.
+	controller.runWorker(context.Background(), 30*time.Second)
+
+	assert.Equal(t, 0, controller.workqueue.Len())
+}
+
 func TestAddObject(t *testing.T) {
 	kubeClient := fake.NewSimpleClientset()
-	metrics := &metrics.Metrics{}
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
 	imageClient := &client.Client{}
-	controller := New(5*time.Minute, metrics, imageClient, kubeClient, testLogger, true)
+	controller := New(testOptions, metrics, imageClient, kubeClient, testLogger)
 
 	obj := &corev1.Pod{}
 	controller.addObject(obj)
@@ -97,9 +161,9 @@ func TestAddObject(t *testing.T) {
 
 func TestDeleteObject(t *testing.T) {
 	kubeClient := fake.NewSimpleClientset()
-	metrics := &metrics.Metrics{}
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
 	imageClient := &client.Client{}
-	controller := New(5*time.Minute, metrics, imageClient, kubeClient, testLogger, true)
+	controller := New(testOptions, metrics, imageClient, kubeClient, testLogger)
 
 	pod := &corev1.Pod{
 		Spec: corev1.PodSpec{
@@ -116,11 +180,60 @@ func TestDeleteObject(t *testing.T) {
 	assert.NotNil(t, pod)
 }
 
+func TestDeleteObjectInitContainers(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.New(testLogger, reg, metrics.Options{})
+	opts := testOptions
+	opts.NoVersionBackoff = time.Hour
+	controller := New(opts, m, &client.Client{}, fake.NewSimpleClientset(), testLogger)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init-container"}},
+			Containers:     []corev1.Container{{Name: "main-container"}},
+		},
+	}
+
+	for _, container := range []struct{ name, containerType string }{
+		{"init-container", "init"},
+		{"main-container", "container"},
+	} {
+		m.AddImage(metrics.Entry{
+			Namespace:      pod.Namespace,
+			Pod:            pod.Name,
+			Container:      container.name,
+			ContainerType:  container.containerType,
+			ImageURL:       "nginx",
+			CurrentVersion: "1.0.0",
+			LatestVersion:  "1.1.0",
+			LastChecked:    time.Now(),
+		})
+		m.SetImageMissing("", pod.Namespace, pod.Name, container.name, container.containerType,
+			"nginx:1.0.0", missingReference)
+		controller.noVersion[containerKey(pod, container.name, container.containerType)] = noVersionEntry{}
+	}
+
+	controller.deleteObject(pod)
+
+	// The series and state of both the init and app containers should be
+	// removed
+	assert.Empty(t, m.Entries())
+	assert.Empty(t, controller.noVersion)
+	for _, name := range []string{
+		"version_checker_is_latest_version",
+		"version_checker_last_checked_timestamp",
+		"version_checker_image_missing",
+	} {
+		assert.Equal(t, 0, testutil.CollectAndCount(reg, name), name)
+	}
+}
+
 func TestProcessNextWorkItem(t *testing.T) {
 	kubeClient := fake.NewSimpleClientset()
-	metrics := &metrics.Metrics{}
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
 	imageClient := &client.Client{}
-	controller := New(5*time.Minute, metrics, imageClient, kubeClient, testLogger, true)
+	controller := New(testOptions, metrics, imageClient, kubeClient, testLogger)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -153,3 +266,423 @@ func TestProcessNextWorkItem(t *testing.T) {
 	err = controller.processNextWorkItem(ctx, "default/test-pod", 30*time.Second)
 	assert.NoError(t, err)
 }
+
+func TestProcessNextWorkItemPermanentError(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
+	imageClient := &client.Client{}
+	controller := New(testOptions, metrics, imageClient, kubeClient, testLogger)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-pod",
+			Namespace:       "default",
+			ResourceVersion: "1",
+			Annotations: map[string]string{
+				"pin-patch.version-checker.io/test-container": "1",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "test-container", Image: "nginx:1.0.0"},
+			},
+		},
+	}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.NoError(t, indexer.Add(pod))
+	controller.podLister = corev1listers.NewPodLister(indexer)
+
+	controller.workqueue.AddRateLimited("default/test-pod")
+	key, _ := controller.workqueue.Get()
+
+	err := controller.processNextWorkItem(context.Background(), key.(string), 30*time.Second)
+	assert.ErrorContains(t, err, "not requeuing until the pod is updated")
+
+	// Should not be requeued, and resyncs of the same pod should be held
+	assert.Equal(t, 0, controller.workqueue.NumRequeues(key))
+	assert.Equal(t, 0, controller.workqueue.Len())
+	assert.True(t, controller.isResyncHeld(pod, pod))
+
+	// Updates to the pod should not be held
+	updated := pod.DeepCopy()
+	updated.ResourceVersion = "2"
+	assert.False(t, controller.isResyncHeld(pod, updated))
+
+	// Deleting the pod should release it
+	controller.deleteObject(pod)
+	assert.False(t, controller.isResyncHeld(pod, pod))
+}
+
+// recordingScheduledWorkQueue records the items added to it, by the
+// duration they were scheduled in.
+type recordingScheduledWorkQueue struct {
+	added map[interface{}]time.Duration
+}
+
+func (r *recordingScheduledWorkQueue) Add(obj interface{}, duration time.Duration) {
+	r.added[obj] = duration
+}
+
+func (r *recordingScheduledWorkQueue) Forget(obj interface{}) {
+	delete(r.added, obj)
+}
+
+func TestProcessNextWorkItemSemverNoVersion(t *testing.T) {
+	host, imageClient := newTestRegistry(t, "foo", "v1.0.0", "v1.1.0")
+
+	opts := testOptions
+	opts.NoVersionRequeuePeriod = time.Hour
+	controller := New(opts, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), imageClient, fake.NewSimpleClientset(), testLogger)
+	scheduled := &recordingScheduledWorkQueue{added: make(map[interface{}]time.Duration)}
+	controller.scheduledWorkQueue = scheduled
+
+	// No tag of the image matches the regex
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-pod",
+			Namespace:       "default",
+			ResourceVersion: "1",
+			Annotations: map[string]string{
+				"match-regex.version-checker.io/test-container": "^nomatch$",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "test-container", Image: host + "/foo:v1.0.0"},
+			},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "test-container", ImageID: host + "/foo@sha256:abc"},
+			},
+		},
+	}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.NoError(t, indexer.Add(pod))
+	controller.podLister = corev1listers.NewPodLister(indexer)
+
+	controller.workqueue.AddRateLimited("default/test-pod")
+	key, _ := controller.workqueue.Get()
+
+	err := controller.processNextWorkItem(context.Background(), key.(string), 30*time.Second)
+	assert.ErrorContains(t, err, "requeuing in 1h0m0s")
+
+	// Should be scheduled after the no version requeue period, rather than
+	// rate limited
+	assert.Equal(t, map[interface{}]time.Duration{"default/test-pod": time.Hour}, scheduled.added)
+	assert.Equal(t, 0, controller.workqueue.NumRequeues(key))
+	assert.Equal(t, 0, controller.workqueue.Len())
+	assert.True(t, controller.isResyncHeld(pod, pod))
+}
+
+func TestProcessNextWorkItemMinPodAge(t *testing.T) {
+	opts := testOptions
+	opts.MinPodAge = time.Hour
+	controller := New(opts, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), &client.Client{}, fake.NewSimpleClientset(), testLogger)
+
+	// The pod has invalid annotations, so fails to sync if it is checked
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "test-pod",
+			Namespace:         "default",
+			ResourceVersion:   "1",
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Minute)),
+			Annotations: map[string]string{
+				"pin-patch.version-checker.io/test-container": "1",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "test-container", Image: "nginx:1.0.0"},
+			},
+		},
+	}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.NoError(t, indexer.Add(pod))
+	controller.podLister = corev1listers.NewPodLister(indexer)
+
+	// Young pods should not be checked, and resyncs held until they are old
+	// enough
+	assert.NoError(t, controller.processNextWorkItem(context.Background(), "default/test-pod", 30*time.Second))
+	assert.True(t, controller.isResyncHeld(pod, pod))
+
+	// Pods older than the minimum age should be checked
+	pod = pod.DeepCopy()
+	pod.CreationTimestamp = metav1.NewTime(time.Now().Add(-time.Hour * 2))
+	assert.NoError(t, indexer.Update(pod))
+	assert.ErrorContains(t, controller.processNextWorkItem(context.Background(), "default/test-pod", 30*time.Second),
+		"not requeuing until the pod is updated")
+}
+
+func TestProcessNextWorkItemAdaptiveHoldsResync(t *testing.T) {
+	opts := testOptions
+	opts.AdaptiveMinInterval = time.Hour
+	opts.AdaptiveMaxInterval = time.Hour * 8
+	controller := New(opts, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), &client.Client{}, fake.NewSimpleClientset(), testLogger)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-pod",
+			Namespace:       "default",
+			ResourceVersion: "1",
+		},
+	}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	assert.NoError(t, indexer.Add(pod))
+	controller.podLister = corev1listers.NewPodLister(indexer)
+
+	assert.NoError(t, controller.processNextWorkItem(context.Background(), "default/test-pod", 30*time.Second))
+
+	// Informer resyncs within the interval should not requeue the pod
+	controller.updateObject(pod, pod)
+	assert.Equal(t, 0, controller.workqueue.Len())
+
+	// Updates to the pod should requeue it
+	updated := pod.DeepCopy()
+	updated.ResourceVersion = "2"
+	controller.updateObject(pod, updated)
+	assert.Equal(t, 1, controller.workqueue.Len())
+}
+
+func TestIsResyncHeld(t *testing.T) {
+	controller := New(testOptions, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), &client.Client{}, fake.NewSimpleClientset(), testLogger)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "test-pod",
+			Namespace:       "default",
+			ResourceVersion: "1",
+		},
+	}
+
+	assert.False(t, controller.isResyncHeld(pod, pod), "pods not held should not be held")
+
+	controller.holdResync("default/test-pod", time.Now().Add(time.Hour))
+	assert.True(t, controller.isResyncHeld(pod, pod), "pods in a quiet period should be held")
+
+	controller.holdResync("default/test-pod", time.Now().Add(-time.Second))
+	assert.False(t, controller.isResyncHeld(pod, pod), "pods after a quiet period should not be held")
+
+	controller.holdResync("default/test-pod", time.Time{})
+	assert.True(t, controller.isResyncHeld(pod, pod), "pods held until updated should be held")
+
+	controller.releaseResync("default/test-pod")
+	assert.False(t, controller.isResyncHeld(pod, pod), "released pods should not be held")
+}
+
+func TestRecheckInterval(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init"}},
+			Containers:     []corev1.Container{{Name: "app"}, {Name: "sidecar"}},
+		},
+	}
+
+	disabled := New(testOptions, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), &client.Client{}, fake.NewSimpleClientset(), testLogger)
+	assert.Equal(t, time.Minute, disabled.recheckInterval(pod, time.Minute), "pods should use the given interval without adaptive polling")
+
+	opts := testOptions
+	opts.AdaptiveMinInterval = time.Hour
+	opts.AdaptiveMaxInterval = time.Hour * 8
+	controller := New(opts, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), &client.Client{}, fake.NewSimpleClientset(), testLogger)
+	assert.Equal(t, time.Minute, controller.recheckInterval(pod, time.Minute), "pods without checked containers should use the given interval")
+
+	// Back off the app container to 2h, leaving the sidecar at 1h.
+	for i := 0; i < 4; i++ {
+		controller.adaptive.Observe(containerKey(pod, "app", "container"), "v1")
+	}
+	controller.adaptive.Observe(containerKey(pod, "sidecar", "container"), "v1")
+	assert.InDelta(t, time.Hour, controller.recheckInterval(pod, time.Minute), float64(time.Minute*6), "pods should use the jittered shortest interval of their containers")
+
+	controller.deleteObject(pod)
+	assert.Equal(t, time.Minute, controller.recheckInterval(pod, time.Minute), "deleted pods should have their intervals forgotten")
+}
+
+func TestMostRetriedKind(t *testing.T) {
+	permanent := newSyncError(errorKindPermanent, errors.New("permanent"))
+	noVersion := newSyncError(errorKindNoVersion, errors.New("no version"))
+	transient := newSyncError(errorKindTransient, errors.New("transient"))
+
+	tests := map[string]struct {
+		errs    []error
+		expKind errorKind
+	}{
+		"only permanent errors should be permanent": {
+			errs:    []error{permanent, permanent},
+			expKind: errorKindPermanent,
+		},
+		"no version should take precedence over permanent": {
+			errs:    []error{permanent, noVersion},
+			expKind: errorKindNoVersion,
+		},
+		"transient should take precedence over all": {
+			errs:    []error{permanent, transient, noVersion},
+			expKind: errorKindTransient,
+		},
+		"unclassified errors should be transient": {
+			errs:    []error{permanent, errors.New("unknown")},
+			expKind: errorKindTransient,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expKind, mostRetriedKind(test.errs))
+		})
+	}
+
+	// The kind should be found through wrapped errors
+	assert.Equal(t, errorKindNoVersion, kindOfError(fmt.Errorf("wrapped: %w", noVersion)))
+}
+
+func TestRecheck(t *testing.T) {
+	opts := testOptions
+	opts.NoVersionBackoff = time.Hour
+	controller := New(opts, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), &client.Client{}, fake.NewSimpleClientset(), testLogger)
+
+	_, err := controller.Recheck("", "")
+	assert.EqualError(t, err, "pod informer has not yet synced")
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, pod := range []*corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "init-container"}},
+				Containers:     []corev1.Container{{Name: "test-container"}},
+			},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pod-3", Namespace: "other"}},
+	} {
+		assert.NoError(t, indexer.Add(pod))
+	}
+	controller.podLister = corev1listers.NewPodLister(indexer)
+	close(controller.synced)
+
+	// Held pods should be released, so their resyncs are processed again
+	controller.holdResync("default/pod-1", time.Time{})
+
+	// Containers backed off for no version found should be released, so they
+	// are searched again
+	controller.noVersion["default/pod-1/init/init-container"] = noVersionEntry{until: time.Now().Add(time.Hour)}
+	controller.noVersion["default/pod-1/container/test-container"] = noVersionEntry{until: time.Now().Add(time.Hour)}
+
+	tests := map[string]struct {
+		namespace, name string
+		expEnqueued     int
+	}{
+		"a single pod":       {namespace: "default", name: "pod-1", expEnqueued: 1},
+		"a missing pod":      {namespace: "default", name: "pod-4", expEnqueued: 0},
+		"a namespace":        {namespace: "default", expEnqueued: 2},
+		"an empty namespace": {namespace: "empty", expEnqueued: 0},
+		"all pods":           {expEnqueued: 3},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			enqueued, err := controller.Recheck(test.namespace, test.name)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expEnqueued, enqueued)
+		})
+	}
+
+	// Concurrent rechecks should not enqueue pods more than once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := controller.Recheck("", "")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, controller.workqueue.Len())
+	pod, err := controller.podLister.Pods("default").Get("pod-1")
+	assert.NoError(t, err)
+	assert.False(t, controller.isResyncHeld(pod, pod))
+	assert.Empty(t, controller.noVersion)
+}
+
+func TestGroupRecheck(t *testing.T) {
+	newController := func(cluster string, synced bool) *Controller {
+		opts := testOptions
+		opts.ClusterName = cluster
+		controller := New(opts, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), &client.Client{}, fake.NewSimpleClientset(), testLogger)
+
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		assert.NoError(t, indexer.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}))
+		controller.podLister = corev1listers.NewPodLister(indexer)
+		if synced {
+			close(controller.synced)
+		}
+
+		return controller
+	}
+
+	// Clusters which have not synced should not stop the others from being
+	// rechecked
+	group := Group{newController("", true), newController("workload-1", false), newController("workload-2", true)}
+	enqueued, err := group.Recheck("default", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, enqueued)
+
+	group = Group{newController("workload-1", false), newController("workload-2", false)}
+	_, err = group.Recheck("default", "")
+	assert.EqualError(t, err, "cluster \"workload-1\": pod informer has not yet synced\ncluster \"workload-2\": pod informer has not yet synced")
+}
+
+func TestRunHealthCheck(t *testing.T) {
+	// The API server hangs while failing, which should time out
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte(`{"major": "1", "minor": "30"}`))
+	}))
+	defer server.Close()
+
+	kubeClient, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	assert.NoError(t, err)
+	reg := prometheus.NewRegistry()
+
+	opts := testOptions
+	opts.ClusterName = "workload-1"
+	controller := New(opts, metrics.New(testLogger, reg, metrics.Options{}), &client.Client{}, kubeClient, testLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go controller.runHealthCheck(ctx, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool { return clusterUp(t, reg) == 1 }, 5*time.Second, 10*time.Millisecond)
+	failing.Store(true)
+	assert.Eventually(t, func() bool { return clusterUp(t, reg) == 0 }, 5*time.Second, 10*time.Millisecond)
+	failing.Store(false)
+	assert.Eventually(t, func() bool { return clusterUp(t, reg) == 1 }, 5*time.Second, 10*time.Millisecond)
+}
+
+// clusterUp returns the value of the cluster up metric, gathered from the
+// registry.
+func clusterUp(t *testing.T, reg *prometheus.Registry) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	assert.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() == "version_checker_cluster_up" && len(family.GetMetric()) > 0 {
+			return family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client/credentials"
+)
+
+// namespaceCredentials are the registry credentials of a namespace, loaded
+// from its credentials secret, until they expire.
+type namespaceCredentials struct {
+	config credentials.DockerConfig
+	expiry time.Time
+}
+
+// credentialsSecret returns the name of the registry credentials secret of
+// the given namespace, or an empty string if it has none. Secrets configured
+// for the namespace take precedence over the secret of all namespaces.
+func (c *Controller) credentialsSecret(namespace string) string {
+	if name, ok := c.namespaceCredentialsSecrets[namespace]; ok {
+		return name
+	}
+
+	return c.namespaceCredentialsSecret
+}
+
+// registryCredentials returns the credentials of the given namespace for the
+// registry of the given image, or nil if the namespace has none for the
+// registry, where the global credentials are used.
+func (c *Controller) registryCredentials(ctx context.Context, namespace, imageURL string) (*api.RegistryCredentials, error) {
+	name := c.credentialsSecret(namespace)
+	if len(name) == 0 {
+		return nil, nil
+	}
+
+	config, err := c.namespaceDockerConfig(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	creds := config.Credentials(c.registryHost(imageURL))
+	if creds == nil {
+		return nil, nil
+	}
+
+	// Secrets of the same name may have different credentials in other
+	// clusters.
+	source := namespace + "/" + name
+	if len(c.cluster) > 0 {
+		source = c.cluster + "/" + source
+	}
+
+	return &api.RegistryCredentials{
+		Source:   source,
+		Username: creds.Username,
+		Password: creds.Password,
+	}, nil
+}
+
+// namespaceDockerConfig returns the registry credentials of the given
+// secret, cached until the cache timeout. Secrets which do not exist have no
+// credentials.
+func (c *Controller) namespaceDockerConfig(ctx context.Context, namespace, name string) (credentials.DockerConfig, error) {
+	key := namespace + "/" + name
+
+	c.credentialsMu.Lock()
+	defer c.credentialsMu.Unlock()
+
+	if creds, ok := c.credentials[key]; ok && time.Now().Before(creds.expiry) {
+		return creds.config, nil
+	}
+
+	secret, err := c.kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get registry credentials secret %q: %s", key, err)
+	}
+
+	var config credentials.DockerConfig
+	if err == nil {
+		data, ok := secret.Data[corev1.DockerConfigJsonKey]
+		if !ok {
+			data, ok = secret.Data[corev1.DockerConfigKey]
+		}
+		if !ok {
+			return nil, fmt.Errorf("registry credentials secret %q has no %s or %s key",
+				key, corev1.DockerConfigJsonKey, corev1.DockerConfigKey)
+		}
+
+		config, err = credentials.ParseDockerConfig(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse registry credentials secret %q: %s", key, err)
+		}
+	}
+
+	c.credentials[key] = &namespaceCredentials{
+		config: config,
+		expiry: time.Now().Add(c.credentialsCacheTimeout),
+	}
+
+	return config, nil
+}
@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func credentialsSecret(namespace, name, config string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(config)},
+	}
+}
+
+func TestRegistryCredentials(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(
+		credentialsSecret("team-a", "registry", `{"auths":{"harbor.corp":{"username":"team-a","password":"a"}}}`),
+		credentialsSecret("team-a", "team-a-registry", `{"auths":{"harbor.corp":{"username":"team-a-override","password":"a"}}}`),
+		credentialsSecret("team-b", "registry", `{"auths":{"harbor.corp":{"username":"team-b","password":"b"}}}`),
+		credentialsSecret("team-c", "registry", `{`),
+	)
+
+	tests := map[string]struct {
+		opts      Options
+		namespace string
+		imageURL  string
+		expCreds  *api.RegistryCredentials
+		expErr    string
+	}{
+		"no secret configured should use the global credentials": {
+			namespace: "team-a",
+			imageURL:  "harbor.corp/app:v1.0.0",
+		},
+		"the secret of all namespaces should be used": {
+			opts:      Options{NamespaceCredentialsSecret: "registry"},
+			namespace: "team-b",
+			imageURL:  "harbor.corp/app:v1.0.0",
+			expCreds:  &api.RegistryCredentials{Source: "team-b/registry", Username: "team-b", Password: "b"},
+		},
+		"the secret of the namespace should take precedence": {
+			opts: Options{
+				NamespaceCredentialsSecret:  "registry",
+				NamespaceCredentialsSecrets: map[string]string{"team-a": "team-a-registry"},
+			},
+			namespace: "team-a",
+			imageURL:  "harbor.corp/app:v1.0.0",
+			expCreds:  &api.RegistryCredentials{Source: "team-a/team-a-registry", Username: "team-a-override", Password: "a"},
+		},
+		"the secret of the namespace should be used without a secret of all namespaces": {
+			opts:      Options{NamespaceCredentialsSecrets: map[string]string{"team-a": "team-a-registry"}},
+			namespace: "team-b",
+			imageURL:  "harbor.corp/app:v1.0.0",
+		},
+		"registries without credentials in the secret should use the global credentials": {
+			opts:      Options{NamespaceCredentialsSecret: "registry"},
+			namespace: "team-a",
+			imageURL:  "quay.io/app:v1.0.0",
+		},
+		"namespaces without the secret should use the global credentials": {
+			opts:      Options{NamespaceCredentialsSecret: "registry"},
+			namespace: "team-d",
+			imageURL:  "harbor.corp/app:v1.0.0",
+		},
+		"the cluster should be part of the source": {
+			opts:      Options{NamespaceCredentialsSecret: "registry", ClusterName: "remote"},
+			namespace: "team-b",
+			imageURL:  "harbor.corp/app:v1.0.0",
+			expCreds:  &api.RegistryCredentials{Source: "remote/team-b/registry", Username: "team-b", Password: "b"},
+		},
+		"invalid secrets should error": {
+			opts:      Options{NamespaceCredentialsSecret: "registry"},
+			namespace: "team-c",
+			imageURL:  "harbor.corp/app:v1.0.0",
+			expErr:    `failed to parse registry credentials secret "team-c/registry": failed to parse docker config: unexpected end of JSON input`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.opts.CacheTimeout = testOptions.CacheTimeout
+			c := New(test.opts, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}),
+				&client.Client{}, kubeClient, testLogger)
+
+			creds, err := c.registryCredentials(context.Background(), test.namespace, test.imageURL)
+			if len(test.expErr) > 0 {
+				assert.EqualError(t, err, test.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expCreds, creds)
+		})
+	}
+}
+
+func TestRegistryCredentialsCached(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewSimpleClientset(
+		credentialsSecret("team-a", "registry", `{"auths":{"harbor.corp":{"username":"team-a","password":"a"}}}`),
+	)
+
+	c := New(Options{CacheTimeout: testOptions.CacheTimeout, NamespaceCredentialsSecret: "registry"},
+		metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), &client.Client{}, kubeClient, testLogger)
+
+	creds, err := c.registryCredentials(ctx, "team-a", "harbor.corp/app:v1.0.0")
+	require.NoError(t, err)
+	require.NotNil(t, creds)
+
+	// Secrets should only be fetched again once the cache times out.
+	require.NoError(t, kubeClient.CoreV1().Secrets("team-a").Delete(ctx, "registry", metav1.DeleteOptions{}))
+
+	creds, err = c.registryCredentials(ctx, "team-a", "harbor.corp/app:v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", creds.Username)
+}
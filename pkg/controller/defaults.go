@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/jetstack/version-checker/pkg/controller/options"
+)
+
+// runDefaultsInformer will watch the ConfigMap of default options, keeping
+// the defaults of containers up to date until the context is cancelled. It
+// returns once the ConfigMap has been synced.
+func (c *Controller) runDefaultsInformer(ctx context.Context) error {
+	if err := c.runConfigMapInformer(ctx, c.defaultsConfigMap, c.setDefaults); err != nil {
+		return fmt.Errorf("error running defaults ConfigMap informer: %s", err)
+	}
+
+	return nil
+}
+
+// runConfigMapInformer will watch the given ConfigMap, calling set with its
+// data when it is added or updated, and nil when it is deleted, until the
+// context is cancelled. It returns once the ConfigMap has been synced.
+func (c *Controller) runConfigMapInformer(ctx context.Context, configMap types.NamespacedName, set func(map[string]string)) error {
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(c.kubeClient, time.Minute*5,
+		informers.WithNamespace(configMap.Namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", configMap.Name).String()
+		}),
+	)
+
+	informer := informerFactory.Core().V1().ConfigMaps().Informer()
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if cm, ok := obj.(*corev1.ConfigMap); ok {
+				set(cm.Data)
+			}
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			if cm, ok := obj.(*corev1.ConfigMap); ok {
+				set(cm.Data)
+			}
+		},
+		DeleteFunc: func(interface{}) {
+			set(nil)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating informer: %s", err)
+	}
+
+	informerFactory.Start(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), informer.HasSynced) {
+		return fmt.Errorf("error waiting for informer cache to sync")
+	}
+
+	return nil
+}
+
+// setDefaults will set the default options of containers. Invalid defaults
+// are ignored, keeping the previous defaults. All pods are rechecked if the
+// defaults changed after the pod informer has synced.
+func (c *Controller) setDefaults(defaults map[string]string) {
+	if problems := options.ValidateDefaults(defaults); len(problems) > 0 {
+		c.log.Errorf("ignoring invalid default options in ConfigMap %s: %s",
+			c.defaultsConfigMap, strings.Join(problems, ", "))
+		return
+	}
+
+	c.defaultsMu.Lock()
+	changed := !maps.Equal(c.defaults, defaults)
+	c.defaults = maps.Clone(defaults)
+	c.defaultsMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	c.log.Infof("loaded %d default options from ConfigMap %s", len(defaults), c.defaultsConfigMap)
+
+	select {
+	case <-c.synced:
+		if _, err := c.Recheck("", ""); err != nil {
+			c.log.Errorf("failed to recheck pods with the updated default options: %s", err)
+		}
+	default:
+		// All pods are checked once the pod informer has synced.
+	}
+}
+
+// defaultOptions returns the default options of containers.
+func (c *Controller) defaultOptions() map[string]string {
+	c.defaultsMu.RLock()
+	defer c.defaultsMu.RUnlock()
+
+	return c.defaults
+}
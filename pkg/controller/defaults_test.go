@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func TestDefaultsConfigMap(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "defaults", Namespace: "version-checker"},
+		Data: map[string]string{
+			api.PinMajorAnnotationKey: "1",
+		},
+	}
+	kubeClient := fake.NewSimpleClientset(configMap)
+
+	opts := testOptions
+	opts.DefaultsConfigMap = types.NamespacedName{Namespace: "version-checker", Name: "defaults"}
+	controller := New(opts, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), &client.Client{}, kubeClient, testLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, controller.runDefaultsInformer(ctx))
+	assert.Equal(t, map[string]string{api.PinMajorAnnotationKey: "1"}, controller.defaultOptions())
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, indexer.Add(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"}}))
+	controller.podLister = corev1listers.NewPodLister(indexer)
+	close(controller.synced)
+
+	// Updates should be reloaded, rechecking all pods
+	configMap.Data = map[string]string{api.PinMajorAnnotationKey: "2"}
+	_, err := kubeClient.CoreV1().ConfigMaps("version-checker").Update(ctx, configMap, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return controller.defaultOptions()[api.PinMajorAnnotationKey] == "2" &&
+			controller.workqueue.Len() == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	// Invalid defaults should be ignored, keeping the previous defaults
+	controller.setDefaults(map[string]string{api.PinMajorAnnotationKey: "foo"})
+	assert.Equal(t, map[string]string{api.PinMajorAnnotationKey: "2"}, controller.defaultOptions())
+
+	// Deleting the ConfigMap should remove the defaults
+	require.NoError(t, kubeClient.CoreV1().ConfigMaps("version-checker").Delete(ctx, "defaults", metav1.DeleteOptions{}))
+
+	assert.Eventually(t, func() bool {
+		return len(controller.defaultOptions()) == 0
+	}, time.Second*5, time.Millisecond*10)
+}
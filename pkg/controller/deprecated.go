@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// deprecatedRepository is a pattern of deprecated image repositories, and the
+// reason they are deprecated.
+type deprecatedRepository struct {
+	pattern, reason string
+}
+
+// parseDeprecatedRepositories will parse the given deprecated repositories
+// ConfigMap data, keyed by the reason the repositories are deprecated, with
+// values of whitespace separated repository patterns, which may contain
+// wildcards such as docker.io/bitnami/*. Patterns are returned in order of
+// their reason, then their order in the value.
+func parseDeprecatedRepositories(data map[string]string) ([]deprecatedRepository, error) {
+	reasons := make([]string, 0, len(data))
+	for reason := range data {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	var (
+		problems     []string
+		repositories []deprecatedRepository
+	)
+	for _, reason := range reasons {
+		for _, pattern := range strings.Fields(data[reason]) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid pattern %q: %s", reason, pattern, err))
+				continue
+			}
+
+			repositories = append(repositories, deprecatedRepository{pattern: pattern, reason: reason})
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, ", "))
+	}
+
+	return repositories, nil
+}
+
+// imageRepository returns the repository of the given image, of its registry
+// host and path without a tag or digest, as pulled by the container runtime
+// rather than after any rewrite rules, such as docker.io/library/nginx for
+// nginx:1.27. Images which fail to parse have no repository.
+func imageRepository(image string) string {
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return ""
+	}
+
+	return imageRegistry(image) + "/" + ref.Context().RepositoryStr()
+}
+
+// runDeprecatedRepositoriesInformer will watch the ConfigMap of deprecated
+// repositories, keeping them up to date until the context is cancelled. It
+// returns once the ConfigMap has been synced.
+func (c *Controller) runDeprecatedRepositoriesInformer(ctx context.Context) error {
+	if err := c.runConfigMapInformer(ctx, c.deprecatedRepositoriesConfigMap, c.setDeprecatedRepositories); err != nil {
+		return fmt.Errorf("error running deprecated repositories ConfigMap informer: %s", err)
+	}
+
+	return nil
+}
+
+// setDeprecatedRepositories will set the deprecated repositories. Invalid
+// patterns are ignored, keeping the previous repositories. All pods are
+// rechecked after the pod informer has synced, so that their containers are
+// matched against the updated repositories.
+func (c *Controller) setDeprecatedRepositories(data map[string]string) {
+	repositories, err := parseDeprecatedRepositories(data)
+	if err != nil {
+		c.log.Errorf("ignoring invalid deprecated repositories in ConfigMap %s: %s",
+			c.deprecatedRepositoriesConfigMap, err)
+		return
+	}
+
+	c.deprecatedMu.Lock()
+	c.deprecatedRepositories = repositories
+	c.deprecatedMu.Unlock()
+
+	c.log.Infof("loaded %d deprecated repository patterns from ConfigMap %s",
+		len(repositories), c.deprecatedRepositoriesConfigMap)
+
+	select {
+	case <-c.synced:
+		if _, err := c.Recheck("", ""); err != nil {
+			c.log.Errorf("failed to recheck pods with the updated deprecated repositories: %s", err)
+		}
+	default:
+		// All pods are checked once the pod informer has synced.
+	}
+}
+
+// deprecationReason returns the reason the given repository is deprecated,
+// of the first pattern it matches, and false if it matches none.
+func (c *Controller) deprecationReason(repository string) (string, bool) {
+	c.deprecatedMu.RLock()
+	defer c.deprecatedMu.RUnlock()
+
+	for _, deprecated := range c.deprecatedRepositories {
+		if ok, _ := path.Match(deprecated.pattern, repository); ok {
+			return deprecated.reason, true
+		}
+	}
+
+	return "", false
+}
+
+// checkDeprecatedRepository will expose whether the image of the given
+// container is from a deprecated repository, regardless of its tag.
+func (c *Controller) checkDeprecatedRepository(pod *corev1.Pod, container *corev1.Container, containerType string) {
+	if len(c.deprecatedRepositoriesConfigMap.Name) == 0 {
+		return
+	}
+
+	repository := imageRepository(container.Image)
+	if reason, ok := c.deprecationReason(repository); ok && len(repository) > 0 {
+		c.metrics.SetDeprecatedRepository(c.cluster, pod.Namespace, pod.Name, container.Name, containerType,
+			repository, reason)
+		return
+	}
+
+	c.metrics.RemoveDeprecatedRepository(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func TestParseDeprecatedRepositories(t *testing.T) {
+	tests := map[string]struct {
+		data    map[string]string
+		expRepo []deprecatedRepository
+		expErr  string
+	}{
+		"no data should return no repositories": {},
+		"patterns should be ordered by reason": {
+			data: map[string]string{
+				"eol":     "quay.io/coreos/etcd",
+				"bitnami": " docker.io/bitnami/*\n  ghcr.io/bitnami/* ",
+			},
+			expRepo: []deprecatedRepository{
+				{pattern: "docker.io/bitnami/*", reason: "bitnami"},
+				{pattern: "ghcr.io/bitnami/*", reason: "bitnami"},
+				{pattern: "quay.io/coreos/etcd", reason: "eol"},
+			},
+		},
+		"invalid patterns should error": {
+			data:   map[string]string{"eol": "quay.io/[coreos"},
+			expErr: `eol: invalid pattern "quay.io/[coreos": syntax error in pattern`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			repositories, err := parseDeprecatedRepositories(test.data)
+			if len(test.expErr) > 0 {
+				assert.EqualError(t, err, test.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expRepo, repositories)
+		})
+	}
+}
+
+func TestImageRepository(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	tests := map[string]string{
+		"nginx:1.27":                               "docker.io/library/nginx",
+		"index.docker.io/bitnami/redis:7.2":        "docker.io/bitnami/redis",
+		"quay.io/coreos/etcd@" + digest:            "quay.io/coreos/etcd",
+		"localhost:5000/team/app:v1.0.0@" + digest: "localhost:5000/team/app",
+		"Invalid:Image":                            "",
+	}
+
+	for image, exp := range tests {
+		assert.Equal(t, exp, imageRepository(image), image)
+	}
+}
+
+const deprecatedRepositoryHelp = `
+# HELP version_checker_deprecated_repository Set for containers whose image is from a deprecated repository, regardless of its tag
+# TYPE version_checker_deprecated_repository gauge
+`
+
+func TestDeprecatedRepositories(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	kubeClient := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "deprecated", Namespace: "version-checker"},
+		Data:       map[string]string{"bitnami-catalog-eol": "docker.io/bitnami/*"},
+	})
+
+	opts := testOptions
+	opts.DeprecatedRepositoriesConfigMap = types.NamespacedName{Namespace: "version-checker", Name: "deprecated"}
+	controller := New(opts, metrics.New(testLogger, reg, metrics.Options{}), &client.Client{}, kubeClient, testLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, controller.runDeprecatedRepositoriesInformer(ctx))
+
+	// Containers should be matched by their repository, regardless of their
+	// tag, or whether they are checked
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "redis", Image: "bitnami/redis:7.2"},
+				{Name: "app", Image: "quay.io/team/app:v1.0.0"},
+			},
+		},
+	}
+	require.NoError(t, controller.sync(ctx, pod))
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(deprecatedRepositoryHelp+`
+version_checker_deprecated_repository{cluster="",container="redis",container_type="container",namespace="default",pod="pod-1",reason="bitnami-catalog-eol",repository="docker.io/bitnami/redis"} 1
+`), "version_checker_deprecated_repository"))
+
+	// Invalid patterns should be ignored, keeping the previous repositories
+	controller.setDeprecatedRepositories(map[string]string{"eol": "quay.io/[team"})
+	reason, ok := controller.deprecationReason("docker.io/bitnami/redis")
+	assert.True(t, ok)
+	assert.Equal(t, "bitnami-catalog-eol", reason)
+
+	// Repositories which are no longer deprecated should be removed
+	controller.setDeprecatedRepositories(map[string]string{"team-migration": "quay.io/team/*"})
+	require.NoError(t, controller.sync(ctx, pod))
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(deprecatedRepositoryHelp+`
+version_checker_deprecated_repository{cluster="",container="app",container_type="container",namespace="default",pod="pod-1",reason="team-migration",repository="quay.io/team/app"} 1
+`), "version_checker_deprecated_repository"))
+
+	// Deleted pods should be removed
+	controller.deleteObject(pod)
+	count, err := testutil.GatherAndCount(reg, "version_checker_deprecated_repository")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
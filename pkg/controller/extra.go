@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"context"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/jetstack/version-checker/pkg/controller/options"
+)
+
+// extraContainerType is the container type of the extra images of pods, which
+// are listed by the extra images annotation rather than the pod spec.
+const extraContainerType = "extra"
+
+// syncExtraImages will check the extra images listed by the annotation of the
+// given pod as synthetic containers, removing the results of extra images
+// which are no longer listed. Invalid entries are logged and skipped.
+func (c *Controller) syncExtraImages(ctx context.Context, log *logrus.Entry, builder *options.Builder, pod *corev1.Pod) []error {
+	images, problems := options.ExtraImages(pod.Annotations)
+	for _, problem := range problems {
+		log.Warnf("skipping extra image: %s", problem)
+	}
+
+	names := make([]string, len(images))
+	for i, image := range images {
+		names[i] = image.Name
+	}
+	for _, name := range c.setExtraImages(pod, names) {
+		c.forgetContainer(pod, name, extraContainerType)
+	}
+
+	var errs []error
+	for _, image := range images {
+		container := &corev1.Container{Name: image.Name, Image: image.Image}
+		if err := c.syncContainer(ctx, log, builder, pod, container, extraContainerType); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// setExtraImages will set the names of the extra images of the given pod,
+// returning the names previously set which no longer are.
+func (c *Controller) setExtraImages(pod *corev1.Pod, names []string) []string {
+	key := pod.Namespace + "/" + pod.Name
+
+	c.extraMu.Lock()
+	defer c.extraMu.Unlock()
+
+	var removed []string
+	for _, name := range c.extraImages[key] {
+		if !slices.Contains(names, name) {
+			removed = append(removed, name)
+		}
+	}
+
+	if len(names) == 0 {
+		delete(c.extraImages, key)
+		return removed
+	}
+
+	if c.extraImages == nil {
+		c.extraImages = make(map[string][]string)
+	}
+	c.extraImages[key] = names
+
+	return removed
+}
+
+// extraImageNames returns the names of the extra images last synced of the
+// given pod.
+func (c *Controller) extraImageNames(pod *corev1.Pod) []string {
+	c.extraMu.Lock()
+	defer c.extraMu.Unlock()
+
+	return c.extraImages[pod.Namespace+"/"+pod.Name]
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Group is a set of controllers, each checking a different cluster. The
+// controllers are run independently, so that the failure of one cluster does
+// not affect the checks of the others.
+type Group []*Controller
+
+// Run is a blocking func that will run all controllers until the context is
+// cancelled, sharing the shutdown context. An error is returned once all
+// controllers have stopped, if any failed.
+func (g Group) Run(ctx context.Context, cacheRefreshRate time.Duration, shutdownCtx context.Context) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for _, c := range g {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := c.Run(ctx, cacheRefreshRate, shutdownCtx); err != nil {
+				c.log.Errorf("controller failed: %s", err)
+
+				mu.Lock()
+				errs = append(errs, c.clusterError(err))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// Recheck will immediately requeue the matching pods of every cluster,
+// returning the total number of pods enqueued. Clusters which fail are
+// skipped, where an error is only returned if all clusters failed.
+func (g Group) Recheck(namespace, name string) (int, error) {
+	var (
+		enqueued int
+		errs     []error
+	)
+
+	for _, c := range g {
+		n, err := c.Recheck(namespace, name)
+		if err != nil {
+			c.log.Errorf("failed to recheck pods: %s", err)
+			errs = append(errs, c.clusterError(err))
+			continue
+		}
+
+		enqueued += n
+	}
+
+	if len(errs) > 0 && len(errs) == len(g) {
+		return 0, errors.Join(errs...)
+	}
+
+	return enqueued, nil
+}
+
+// clusterError returns the given error, prefixed with the name of the cluster
+// if set.
+func (c *Controller) clusterError(err error) error {
+	if len(c.cluster) == 0 {
+		return err
+	}
+
+	return fmt.Errorf("cluster %q: %s", c.cluster, err)
+}
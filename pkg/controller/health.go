@@ -0,0 +1,29 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// runHealthCheck will check the cluster API server is reachable every period,
+// until the context is cancelled. Failures are logged and exposed as metrics,
+// without affecting the checks of other clusters. Each check times out after
+// the period, so an unresponsive API server is reported as unreachable.
+func (c *Controller) runHealthCheck(ctx context.Context, period time.Duration) {
+	up := true
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		err := c.kubeClient.Discovery().RESTClient().Get().
+			AbsPath("/version").Timeout(period).Do(ctx).Error()
+		switch {
+		case err != nil && up:
+			c.log.Errorf("cluster API server is unreachable: %s", err)
+		case err == nil && !up:
+			c.log.Info("cluster API server is reachable again")
+		}
+
+		up = err == nil
+		c.metrics.SetClusterUp(c.cluster, up)
+	}, period)
+}
@@ -11,26 +11,56 @@ import (
 var _ search.Searcher = &FakeSearch{}
 
 type FakeSearch struct {
-	latestImageF func() (*api.ImageTag, error)
+	latestImageF func(string, *api.Options) (*api.ImageTag, error)
+
+	versionsBehind    int
+	versionsBehindErr error
 }
 
 func New() *FakeSearch {
 	return &FakeSearch{
-		latestImageF: func() (*api.ImageTag, error) {
+		latestImageF: func(string, *api.Options) (*api.ImageTag, error) {
 			return nil, nil
 		},
 	}
 }
 
 func (f *FakeSearch) With(image *api.ImageTag, err error) *FakeSearch {
-	f.latestImageF = func() (*api.ImageTag, error) {
+	f.latestImageF = func(string, *api.Options) (*api.ImageTag, error) {
 		return image, err
 	}
 	return f
 }
 
-func (f *FakeSearch) LatestImage(context.Context, string, *api.Options) (*api.ImageTag, error) {
-	return f.latestImageF()
+// WithFunc will respond with the result of the given func, for the options
+// of each search.
+func (f *FakeSearch) WithFunc(latestImageF func(opts *api.Options) (*api.ImageTag, error)) *FakeSearch {
+	f.latestImageF = func(_ string, opts *api.Options) (*api.ImageTag, error) {
+		return latestImageF(opts)
+	}
+	return f
+}
+
+// WithImageFunc will respond with the result of the given func, for the image
+// URL and options of each search.
+func (f *FakeSearch) WithImageFunc(latestImageF func(imageURL string, opts *api.Options) (*api.ImageTag, error)) *FakeSearch {
+	f.latestImageF = latestImageF
+	return f
+}
+
+// WithVersionsBehind will respond with the given number of versions behind,
+// or error, for every image.
+func (f *FakeSearch) WithVersionsBehind(versionsBehind int, err error) *FakeSearch {
+	f.versionsBehind, f.versionsBehindErr = versionsBehind, err
+	return f
+}
+
+func (f *FakeSearch) LatestImage(_ context.Context, imageURL string, opts *api.Options) (*api.ImageTag, error) {
+	return f.latestImageF(imageURL, opts)
+}
+
+func (f *FakeSearch) VersionsBehind(context.Context, string, string, string, *api.Options) (int, error) {
+	return f.versionsBehind, f.versionsBehindErr
 }
 
 func (f *FakeSearch) Run(time.Duration) {
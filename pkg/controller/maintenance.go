@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// maintenanceAllRegistries is the maintenance ConfigMap key which pauses
+	// the checks of images from all registries.
+	maintenanceAllRegistries = "*"
+
+	// maintenancePeriod is how often maintenance windows are evaluated, to
+	// expose whether checks are paused and resume checks once they end.
+	maintenancePeriod = time.Second * 30
+)
+
+// maintenanceWindow is a window during which the checks of images from a
+// registry are paused. A zero start or end is unbounded.
+type maintenanceWindow struct {
+	start, end time.Time
+}
+
+// active returns whether the window is active at the given time.
+func (w maintenanceWindow) active(now time.Time) bool {
+	return (w.start.IsZero() || !now.Before(w.start)) &&
+		(w.end.IsZero() || now.Before(w.end))
+}
+
+// parseMaintenanceWindows will parse the given maintenance ConfigMap data,
+// keyed by registry host, or * for all registries. Values are either a
+// boolean, where true pauses checks until changed, or a window of the form
+// <start>/<end> in RFC 3339. Registries which are not paused are not
+// returned.
+func parseMaintenanceWindows(data map[string]string) (map[string]maintenanceWindow, error) {
+	var problems []string
+	windows := make(map[string]maintenanceWindow)
+	for registry, value := range data {
+		value = strings.TrimSpace(value)
+
+		if paused, err := strconv.ParseBool(value); err == nil {
+			if paused {
+				windows[registry] = maintenanceWindow{}
+			}
+			continue
+		}
+
+		start, end, ok := strings.Cut(value, "/")
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: %q must be a boolean or of the form <start>/<end>", registry, value))
+			continue
+		}
+
+		var window maintenanceWindow
+		var err error
+		if window.start, err = time.Parse(time.RFC3339, start); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid start: %s", registry, err))
+			continue
+		}
+		if window.end, err = time.Parse(time.RFC3339, end); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: invalid end: %s", registry, err))
+			continue
+		}
+		if !window.end.After(window.start) {
+			problems = append(problems, fmt.Sprintf("%s: end %q must be after start %q", registry, end, start))
+			continue
+		}
+
+		windows[registry] = window
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("%s", strings.Join(problems, ", "))
+	}
+
+	return windows, nil
+}
+
+// runMaintenanceInformer will watch the ConfigMap of maintenance windows, and
+// evaluate the windows every maintenancePeriod, until the context is
+// cancelled. It returns once the ConfigMap has been synced.
+func (c *Controller) runMaintenanceInformer(ctx context.Context) error {
+	if err := c.runConfigMapInformer(ctx, c.maintenanceConfigMap, c.setMaintenance); err != nil {
+		return fmt.Errorf("error running maintenance ConfigMap informer: %s", err)
+	}
+
+	go wait.UntilWithContext(ctx, func(context.Context) {
+		c.updatePaused(time.Now())
+	}, maintenancePeriod)
+
+	return nil
+}
+
+// setMaintenance will set the maintenance windows of registries. Invalid
+// windows are ignored, keeping the previous windows.
+func (c *Controller) setMaintenance(data map[string]string) {
+	windows, err := parseMaintenanceWindows(data)
+	if err != nil {
+		c.log.Errorf("ignoring invalid maintenance windows in ConfigMap %s: %s",
+			c.maintenanceConfigMap, err)
+		return
+	}
+
+	c.maintenanceMu.Lock()
+	c.maintenance = windows
+	c.maintenanceMu.Unlock()
+
+	c.updatePaused(time.Now())
+}
+
+// updatePaused will expose whether the checks of each registry with a
+// maintenance window are paused at the given time. All pods are rechecked
+// once the checks of any registry resume, after the pod informer has synced.
+func (c *Controller) updatePaused(now time.Time) {
+	c.maintenanceMu.Lock()
+	var resumed bool
+	paused := make(map[string]bool)
+	for registry, window := range c.maintenance {
+		paused[registry] = window.active(now)
+		if paused[registry] != c.paused[registry] {
+			if paused[registry] {
+				c.log.Infof("pausing checks of registry %q for maintenance", registry)
+			} else {
+				c.log.Infof("resuming checks of registry %q after maintenance", registry)
+				resumed = true
+			}
+		}
+		c.metrics.SetPaused(c.cluster, registry, paused[registry])
+	}
+
+	for registry, wasPaused := range c.paused {
+		if _, ok := paused[registry]; !ok {
+			if wasPaused {
+				c.log.Infof("resuming checks of registry %q after maintenance", registry)
+				resumed = true
+			}
+			c.metrics.RemovePaused(c.cluster, registry)
+		}
+	}
+	c.paused = paused
+	c.maintenanceMu.Unlock()
+
+	if !resumed {
+		return
+	}
+
+	select {
+	case <-c.synced:
+		if _, err := c.Recheck("", ""); err != nil {
+			c.log.Errorf("failed to recheck pods after maintenance: %s", err)
+		}
+	default:
+		// All pods are checked once the pod informer has synced.
+	}
+}
+
+// isPaused returns whether the checks of the given image are paused by a
+// maintenance window of its registry, or of all registries.
+func (c *Controller) isPaused(imageURL string) bool {
+	c.maintenanceMu.RLock()
+	defer c.maintenanceMu.RUnlock()
+
+	if len(c.maintenance) == 0 {
+		return false
+	}
+
+	now := time.Now()
+	if window, ok := c.maintenance[maintenanceAllRegistries]; ok && window.active(now) {
+		return true
+	}
+
+	window, ok := c.maintenance[c.registryHost(imageURL)]
+	return ok && window.active(now)
+}
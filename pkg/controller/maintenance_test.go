@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func TestParseMaintenanceWindows(t *testing.T) {
+	start := time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 6, 2, 2, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		data       map[string]string
+		expWindows map[string]maintenanceWindow
+		expErr     bool
+	}{
+		"no data should return no windows": {
+			expWindows: map[string]maintenanceWindow{},
+		},
+		"booleans should pause until changed, or not pause": {
+			data:       map[string]string{"quay.io": "true", "docker.io": "false"},
+			expWindows: map[string]maintenanceWindow{"quay.io": {}},
+		},
+		"windows should be parsed": {
+			data:       map[string]string{"*": " 2024-06-01T22:00:00Z/2024-06-02T02:00:00Z "},
+			expWindows: map[string]maintenanceWindow{"*": {start: start, end: end}},
+		},
+		"invalid values should error": {
+			data:   map[string]string{"quay.io": "tonight"},
+			expErr: true,
+		},
+		"invalid times should error": {
+			data:   map[string]string{"quay.io": "2024-06-01/2024-06-02"},
+			expErr: true,
+		},
+		"windows ending before they start should error": {
+			data:   map[string]string{"quay.io": "2024-06-02T02:00:00Z/2024-06-01T22:00:00Z"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			windows, err := parseMaintenanceWindows(test.data)
+			if test.expErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expWindows, windows)
+		})
+	}
+}
+
+func TestMaintenanceWindowActive(t *testing.T) {
+	start := time.Date(2024, 6, 1, 22, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 6, 2, 2, 0, 0, 0, time.UTC)
+	window := maintenanceWindow{start: start, end: end}
+
+	assert.False(t, window.active(start.Add(-time.Second)))
+	assert.True(t, window.active(start))
+	assert.True(t, window.active(end.Add(-time.Second)))
+	assert.False(t, window.active(end))
+	assert.True(t, maintenanceWindow{}.active(end))
+}
+
+const pausedHelp = `
+# HELP version_checker_paused Whether checks of images from the registry are paused by a maintenance window, where * is all registries
+# TYPE version_checker_paused gauge
+`
+
+func TestMaintenance(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	kubeClient := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "maintenance", Namespace: "version-checker"},
+		Data:       map[string]string{"quay.io": "true"},
+	})
+
+	opts := testOptions
+	opts.ClusterName = "cluster-1"
+	opts.MaintenanceConfigMap = types.NamespacedName{Namespace: "version-checker", Name: "maintenance"}
+	controller := New(opts, metrics.New(testLogger, reg, metrics.Options{}), &client.Client{}, kubeClient, testLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.NoError(t, controller.runMaintenanceInformer(ctx))
+	assert.True(t, controller.isPaused("quay.io/jetstack/version-checker:v0.9.0"))
+	assert.False(t, controller.isPaused("jetstack/version-checker:v0.9.0"))
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(pausedHelp+`
+version_checker_paused{cluster="cluster-1",registry="quay.io"} 1
+`), "version_checker_paused"))
+
+	// Checks of paused registries should be skipped, keeping previous results
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "quay.io/jetstack/version-checker:v0.9.0"}},
+		},
+	}
+	require.NoError(t, controller.sync(ctx, pod))
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, indexer.Add(pod))
+	controller.podLister = corev1listers.NewPodLister(indexer)
+	close(controller.synced)
+
+	// Invalid windows should be ignored, keeping the previous windows
+	controller.setMaintenance(map[string]string{"quay.io": "tonight"})
+	assert.True(t, controller.isPaused("quay.io/jetstack/version-checker:v0.9.0"))
+
+	// Windows which have ended should resume checks, rechecking all pods
+	controller.setMaintenance(map[string]string{
+		"*": time.Now().Add(-time.Hour).Format(time.RFC3339) + "/" + time.Now().Add(-time.Minute).Format(time.RFC3339),
+	})
+	assert.False(t, controller.isPaused("quay.io/jetstack/version-checker:v0.9.0"))
+	assert.Equal(t, 1, controller.workqueue.Len())
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(pausedHelp+`
+version_checker_paused{cluster="cluster-1",registry="*"} 0
+`), "version_checker_paused"))
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/jetstack/version-checker/pkg/client/util"
+	versionerrors "github.com/jetstack/version-checker/pkg/version/errors"
+)
+
+// Reasons the image of a container is exposed as missing.
+const (
+	missingRepository = "repository_not_found"
+	missingReference  = "reference_not_found"
+)
+
+// checkImageMissing will expose whether the image of the given container is
+// missing from its registry, by the error of checking it, and by resolving
+// its reference if image existence is verified. Images are only exposed as
+// missing once the registry responds that the repository or reference was
+// not found. Whether they exist is unknown if access is denied, or the check
+// fails otherwise, such as transiently, so any previous result is kept.
+func (c *Controller) checkImageMissing(ctx context.Context, log *logrus.Entry, pod *corev1.Pod,
+	container *corev1.Container, containerType string, checkErr error) {
+	switch {
+	case util.IsNotFound(checkErr):
+		log.Warnf("repository of image %q was not found by the registry", container.Image)
+		c.metrics.SetImageMissing(c.cluster, pod.Namespace, pod.Name, container.Name, containerType,
+			container.Image, missingRepository)
+		return
+
+	case util.IsAuthDenied(checkErr):
+		log.Debugf("access to the repository of image %q was denied, unable to verify it exists", container.Image)
+		return
+
+	// The tags of the repository were listed, including repositories without
+	// any tags, so the repository exists.
+	case checkErr == nil, versionerrors.IsNoVersionFound(checkErr), versionerrors.IsNoSignatureFound(checkErr),
+		errors.Is(checkErr, errUnparseableCurrent):
+
+	default:
+		return
+	}
+
+	if c.imageExists == nil {
+		c.metrics.RemoveImageMissing(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+		return
+	}
+
+	exists, err := c.imageExists(ctx, container.Image)
+	if err != nil {
+		log.Debugf("failed to verify image %q exists: %s", container.Image, err)
+		return
+	}
+	if !exists {
+		log.Warnf("image %q was not found by the registry", container.Image)
+		c.metrics.SetImageMissing(c.cluster, pod.Namespace, pod.Name, container.Name, containerType,
+			container.Image, missingReference)
+		return
+	}
+
+	c.metrics.RemoveImageMissing(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+}
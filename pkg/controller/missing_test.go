@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client/util"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
+	fakesearch "github.com/jetstack/version-checker/pkg/controller/internal/fake/search"
+	"github.com/jetstack/version-checker/pkg/controller/options"
+	"github.com/jetstack/version-checker/pkg/metrics"
+	versionerrors "github.com/jetstack/version-checker/pkg/version/errors"
+)
+
+const imageMissingHelp = `
+# HELP version_checker_image_missing Set for containers whose image repository, or image reference, was not found by the registry
+# TYPE version_checker_image_missing gauge
+`
+
+func TestController_SyncContainer_ImageMissing(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "main-container", ImageID: "localhost:5000/foo@sha256:def"},
+			},
+		},
+	}
+
+	series := func(reason string) string {
+		return `version_checker_image_missing{cluster="",container="main-container",container_type="container",image="localhost:5000/foo:v1.0.0",namespace="default",pod="test-pod",reason="` + reason + `"} 1
+`
+	}
+
+	tests := map[string]struct {
+		searchErr   error
+		imageExists func(ctx context.Context, reference string) (bool, error)
+		expSeries   string
+	}{
+		"a repository which is not found should be missing": {
+			searchErr: util.NewStatusError(http.StatusNotFound, errors.New("NAME_UNKNOWN")),
+			expSeries: series(missingRepository),
+		},
+		"a repository which is not found should be missing without resolving the reference": {
+			searchErr: util.NewStatusError(http.StatusNotFound, errors.New("NAME_UNKNOWN")),
+			imageExists: func(context.Context, string) (bool, error) {
+				return false, errors.New("should not be called")
+			},
+			expSeries: series(missingRepository),
+		},
+		"an unauthorized repository should keep the previous result": {
+			searchErr: util.NewStatusError(http.StatusUnauthorized, errors.New("UNAUTHORIZED")),
+			expSeries: series(missingReference),
+		},
+		"a forbidden repository should keep the previous result": {
+			searchErr: util.NewStatusError(http.StatusForbidden, errors.New("DENIED")),
+			expSeries: series(missingReference),
+		},
+		"a transient failure should keep the previous result": {
+			searchErr: util.NewRetriableError(util.NewStatusError(http.StatusServiceUnavailable, errors.New("unavailable"))),
+			expSeries: series(missingReference),
+		},
+		"a repository without tags should not be missing": {
+			searchErr: versionerrors.NewVersionErrorNotFound("no tags found for given image URL"),
+		},
+		"a found repository should not be missing, without verifying the reference": {},
+		"a reference which is not found should be missing": {
+			imageExists: func(context.Context, string) (bool, error) {
+				return false, nil
+			},
+			expSeries: series(missingReference),
+		},
+		"a reference which is found should not be missing": {
+			imageExists: func(_ context.Context, reference string) (bool, error) {
+				return reference == "localhost:5000/foo:v1.0.0", nil
+			},
+		},
+		"a reference which access is denied to should keep the previous result": {
+			imageExists: func(context.Context, string) (bool, error) {
+				return false, util.NewStatusError(http.StatusUnauthorized, errors.New("UNAUTHORIZED"))
+			},
+			expSeries: series(missingReference),
+		},
+		"a reference which fails to be resolved should keep the previous result": {
+			imageExists: func(context.Context, string) (bool, error) {
+				return false, errors.New("DENIED")
+			},
+			expSeries: series(missingReference),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			searcher := fakesearch.New().WithFunc(func(*api.Options) (*api.ImageTag, error) {
+				if test.searchErr != nil {
+					return nil, test.searchErr
+				}
+				return &api.ImageTag{Tag: "v1.1.0", SHA: "sha256:abc"}, nil
+			})
+
+			reg := prometheus.NewRegistry()
+			controller := &Controller{
+				log:            log,
+				checker:        checker.New(searcher, nil),
+				metrics:        metrics.New(testLogger, reg, metrics.Options{}),
+				defaultTestAll: true,
+				noVersion:      make(map[string]noVersionEntry),
+				imageExists:    test.imageExists,
+			}
+
+			// The previous result of the container
+			controller.metrics.SetImageMissing("", "default", "test-pod", "main-container", "container",
+				"localhost:5000/foo:v1.0.0", missingReference)
+
+			container := &corev1.Container{Name: "main-container", Image: "localhost:5000/foo:v1.0.0"}
+			_ = controller.syncContainer(context.Background(), log, options.New(nil), pod, container, "container")
+
+			if len(test.expSeries) > 0 {
+				assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(imageMissingHelp+test.expSeries),
+					"version_checker_image_missing"))
+			} else {
+				assert.Equal(t, 0, testutil.CollectAndCount(reg, "version_checker_image_missing"))
+			}
+
+			// Removed containers should no longer be missing
+			controller.forgetContainer(pod, container.Name, "container")
+			assert.Equal(t, 0, testutil.CollectAndCount(reg, "version_checker_image_missing"))
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package controller
+
+import "sync"
+
+// tagDigests are the digests last seen of the latest tags of checked images,
+// and the containers tracking each tag, so that tags pushed again with a
+// different digest are detected. Tags are forgotten once no container tracks
+// them, bounding the state to the images of existing containers. The zero
+// value is ready to use.
+type tagDigests struct {
+	mu sync.Mutex
+
+	// tags are the tracked tags, by image, tag and platform.
+	tags map[string]*tagDigest
+
+	// containers are the tags tracked by each container, by container key.
+	containers map[string]string
+}
+
+// tagDigest is the digest last seen of a tag, and the containers tracking it.
+type tagDigest struct {
+	sha        string
+	containers map[string]struct{}
+}
+
+// observe will record the given digest of the latest tag of the given
+// container, returning the digest previously seen of the tag, and true if it
+// was different. Digests are seen per resolved platform of the tag, as the
+// digest of a tag differs between the images of its platforms.
+func (t *tagDigests) observe(containerKey, imageURL, tag, platform, sha string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tags == nil {
+		t.tags = make(map[string]*tagDigest)
+		t.containers = make(map[string]string)
+	}
+
+	index := imageURL + ":" + tag + "|" + platform
+	if previous, ok := t.containers[containerKey]; ok && previous != index {
+		t.untrack(containerKey, previous)
+	}
+	t.containers[containerKey] = index
+
+	digest, ok := t.tags[index]
+	if !ok {
+		t.tags[index] = &tagDigest{
+			sha:        sha,
+			containers: map[string]struct{}{containerKey: {}},
+		}
+		return "", false
+	}
+
+	digest.containers[containerKey] = struct{}{}
+	if digest.sha == sha {
+		return "", false
+	}
+
+	previous := digest.sha
+	digest.sha = sha
+
+	return previous, true
+}
+
+// forget will stop the given container tracking its latest tag, forgetting
+// the tag once no container tracks it.
+func (t *tagDigests) forget(containerKey string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if index, ok := t.containers[containerKey]; ok {
+		t.untrack(containerKey, index)
+		delete(t.containers, containerKey)
+	}
+}
+
+func (t *tagDigests) untrack(containerKey, index string) {
+	digest, ok := t.tags[index]
+	if !ok {
+		return
+	}
+
+	delete(digest.containers, containerKey)
+	if len(digest.containers) == 0 {
+		delete(t.tags, index)
+	}
+}
@@ -0,0 +1,30 @@
+package nodeagent
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+// DefaultCRIEndpoint is the endpoint of the containerd CRI socket.
+const DefaultCRIEndpoint = "unix:///run/containerd/containerd.sock"
+
+// NewImageServiceClient returns a client of the CRI image service at the
+// given endpoint, such as DefaultCRIEndpoint. Endpoints without a scheme are
+// treated as the path of a unix socket. The returned connection must be closed
+// once done.
+func NewImageServiceClient(endpoint string) (runtimeapi.ImageServiceClient, *grpc.ClientConn, error) {
+	if strings.HasPrefix(endpoint, "/") {
+		endpoint = "unix://" + endpoint
+	}
+
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to container runtime %q: %s", endpoint, err)
+	}
+
+	return runtimeapi.NewImageServiceClient(conn), conn, nil
+}
@@ -0,0 +1,200 @@
+package nodeagent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+// Options are used to configure the node agent.
+type Options struct {
+	// NodeName is the name of the node the agent is running on.
+	NodeName string
+
+	// ClusterName is the name of the cluster the node is in, exposed as the
+	// cluster label of metrics.
+	ClusterName string
+
+	DefaultOS       api.OS
+	DefaultArch     api.Architecture
+	ExcludeArchs    []api.Architecture
+	PreReleaseOrder []string
+	BuildSuffix     *regexp.Regexp
+}
+
+// Agent checks the images on a node, as listed by the container runtime of
+// the node, rather than the images of the pods running on it.
+type Agent struct {
+	log *logrus.Entry
+
+	images  runtimeapi.ImageServiceClient
+	checker *checker.Checker
+	metrics *metrics.Metrics
+
+	opts Options
+
+	// references are the image references checked by the last check.
+	references map[string]bool
+}
+
+// New returns a new node agent, checking the images listed by the given
+// container runtime image service.
+func New(opts Options, metrics *metrics.Metrics, images runtimeapi.ImageServiceClient,
+	checker *checker.Checker, log *logrus.Entry) *Agent {
+	return &Agent{
+		log:        log.WithField("module", "node-agent").WithField("node", opts.NodeName),
+		images:     images,
+		checker:    checker,
+		metrics:    metrics,
+		opts:       opts,
+		references: make(map[string]bool),
+	}
+}
+
+// Run is a blocking func that will check the images on the node every
+// period, until the context is cancelled.
+func (a *Agent) Run(ctx context.Context, period time.Duration) error {
+	a.log.Info("starting node agent")
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		if err := a.check(ctx); err != nil {
+			a.log.Error(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			a.log.Info("shutting down node agent")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// check will check every tagged image on the node, removing the results of
+// images which are no longer on the node.
+func (a *Agent) check(ctx context.Context) error {
+	resp, err := a.images.ListImages(ctx, &runtimeapi.ListImagesRequest{})
+	if err != nil {
+		return fmt.Errorf("failed to list images from container runtime: %s", err)
+	}
+
+	references := make(map[string]bool)
+	for _, image := range resp.Images {
+		for _, reference := range image.RepoTags {
+			digest, ok := repoDigest(reference, image.RepoDigests)
+			if !ok {
+				a.log.Debugf("%s: image has no repo digest, skipping", reference)
+				continue
+			}
+
+			references[reference] = true
+			if err := a.checkImage(ctx, reference, digest); err != nil {
+				a.log.Errorf("%s: failed to check image: %s", reference, err)
+			}
+		}
+	}
+
+	for reference := range a.references {
+		if !references[reference] {
+			a.log.Debugf("%s: image removed from node", reference)
+			a.metrics.RemoveNodeImage(a.opts.ClusterName, a.opts.NodeName, reference)
+		}
+	}
+	a.references = references
+
+	return nil
+}
+
+// checkImage will check the given image reference, with the digest it was
+// pulled as.
+func (a *Agent) checkImage(ctx context.Context, reference, digest string) error {
+	pod, container := imagePod(reference, digest)
+
+	result, err := a.checker.Container(ctx, a.log.WithField("image", reference), pod, container, &api.Options{
+		DefaultOS:       a.opts.DefaultOS,
+		DefaultArch:     a.opts.DefaultArch,
+		ExcludeArchs:    a.opts.ExcludeArchs,
+		PreReleaseOrder: a.opts.PreReleaseOrder,
+		BuildSuffix:     a.opts.BuildSuffix,
+	})
+	if err != nil {
+		return err
+	}
+
+	a.metrics.AddNodeImage(metrics.NodeImageEntry{
+		Cluster:        a.opts.ClusterName,
+		Node:           a.opts.NodeName,
+		Reference:      reference,
+		ImageURL:       result.ImageURL,
+		IsLatest:       result.IsLatest,
+		CurrentVersion: result.CurrentVersion,
+		LatestVersion:  result.LatestVersion,
+		OS:             string(result.OS),
+		Arch:           string(result.Architecture),
+	})
+
+	return nil
+}
+
+// repoDigest returns the digest of the given tagged image reference, from the
+// repo digests of its image.
+func repoDigest(reference string, repoDigests []string) (string, bool) {
+	repo := repository(reference)
+	for _, repoDigest := range repoDigests {
+		if digestRepo, digest, ok := strings.Cut(repoDigest, "@"); ok && digestRepo == repo {
+			return digest, true
+		}
+	}
+
+	return "", false
+}
+
+// repository returns the given tagged image reference without its tag.
+func repository(reference string) string {
+	if i := strings.LastIndex(reference, ":"); i > strings.LastIndex(reference, "/") {
+		return reference[:i]
+	}
+	return reference
+}
+
+// imagePod returns a pod with a single container running the given image
+// reference and digest, as the checker expects.
+func imagePod(reference, digest string) (*corev1.Pod, *corev1.Container) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "node-image",
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  "node-image",
+					Image: reference,
+				},
+			},
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					Name:    "node-image",
+					ImageID: repository(reference) + "@" + digest,
+				},
+			},
+		},
+	}
+
+	return pod, &pod.Spec.Containers[0]
+}
@@ -0,0 +1,107 @@
+package nodeagent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
+	"github.com/jetstack/version-checker/pkg/controller/internal/fake/search"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+type fakeImageService struct {
+	runtimeapi.ImageServiceClient
+	images []*runtimeapi.Image
+}
+
+func (f *fakeImageService) ListImages(context.Context, *runtimeapi.ListImagesRequest, ...grpc.CallOption) (*runtimeapi.ListImagesResponse, error) {
+	return &runtimeapi.ListImagesResponse{Images: f.images}, nil
+}
+
+func TestCheck(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	reg := prometheus.NewRegistry()
+	m := metrics.New(log, reg, metrics.Options{})
+
+	images := &fakeImageService{
+		images: []*runtimeapi.Image{
+			{
+				Id:          "sha256:config-1",
+				RepoTags:    []string{"mirror.corp/library/nginx:1.25.0", "mirror.corp/library/nginx:1.25"},
+				RepoDigests: []string{"mirror.corp/library/nginx@sha256:125"},
+			},
+			{
+				Id:          "sha256:config-2",
+				RepoTags:    []string{"mirror.corp:5000/jetstack/version-checker:v0.9.0"},
+				RepoDigests: []string{"mirror.corp:5000/jetstack/version-checker@sha256:090"},
+			},
+			// Images without a repo digest, or a tag, cannot be checked
+			{Id: "sha256:config-3", RepoTags: []string{"local/built:dev"}},
+			{Id: "sha256:config-4", RepoDigests: []string{"mirror.corp/library/busybox@sha256:136"}},
+		},
+	}
+
+	searcher := search.New().WithImageFunc(func(imageURL string, _ *api.Options) (*api.ImageTag, error) {
+		if imageURL == "mirror.corp/library/nginx" {
+			return &api.ImageTag{Tag: "1.25.0", SHA: "sha256:125"}, nil
+		}
+		return &api.ImageTag{Tag: "v0.10.0", SHA: "sha256:0100"}, nil
+	})
+
+	agent := New(Options{NodeName: "node-1"}, m, images, checker.New(searcher, nil), log)
+	require.NoError(t, agent.check(context.Background()))
+
+	assert.Equal(t, 3, testutil.CollectAndCount(reg, "version_checker_node_image_is_latest_version"))
+	assert.Equal(t, map[string]bool{
+		"mirror.corp/library/nginx:1.25.0":                 true,
+		"mirror.corp/library/nginx:1.25":                   true,
+		"mirror.corp:5000/jetstack/version-checker:v0.9.0": true,
+	}, agent.references)
+
+	// Images removed from the node should have their results removed
+	images.images = images.images[1:2]
+	require.NoError(t, agent.check(context.Background()))
+	assert.Equal(t, 1, testutil.CollectAndCount(reg, "version_checker_node_image_is_latest_version"))
+}
+
+func TestRepoDigest(t *testing.T) {
+	tests := map[string]struct {
+		reference   string
+		repoDigests []string
+		expDigest   string
+		expOK       bool
+	}{
+		"no repo digests should not be found": {
+			reference: "docker.io/library/nginx:1.25",
+		},
+		"repo digest of the same repository should be found": {
+			reference:   "docker.io/library/nginx:1.25",
+			repoDigests: []string{"quay.io/library/nginx@sha256:abc", "docker.io/library/nginx@sha256:def"},
+			expDigest:   "sha256:def",
+			expOK:       true,
+		},
+		"repository with a port should be found": {
+			reference:   "mirror.corp:5000/nginx:1.25",
+			repoDigests: []string{"mirror.corp:5000/nginx@sha256:abc"},
+			expDigest:   "sha256:abc",
+			expOK:       true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			digest, ok := repoDigest(test.reference, test.repoDigests)
+			assert.Equal(t, test.expDigest, digest)
+			assert.Equal(t, test.expOK, ok)
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package options
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/jetstack/version-checker/pkg/api"
+)
+
+// extraImagePrefix is the prefix of the synthetic container names of extra
+// images, so that they don't collide with containers in the pod spec.
+const extraImagePrefix = "extra-"
+
+// ExtraImage is an image listed by the extra images annotation of a pod, which
+// is launched at runtime, and so not in the pod spec.
+type ExtraImage struct {
+	// Name is the synthetic container name of the image, being the last
+	// element of its repository prefixed with "extra-", and suffixed with a
+	// number if more than one image has the same repository name.
+	Name string
+
+	// Image is the image reference.
+	Image string
+}
+
+// ExtraImages will return the extra images listed by the given pod
+// annotations, in order, and the problems found with any entries, which are
+// skipped. Images must have a version tag, or a digest, to be checked.
+func ExtraImages(annotations map[string]string) ([]ExtraImage, []string) {
+	value, ok := annotations[api.ExtraImagesAnnotationKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var (
+		images   []ExtraImage
+		problems []string
+	)
+	seen := make(map[string]bool)
+	names := make(map[string]int)
+
+	for _, image := range strings.Split(value, ",") {
+		image = strings.TrimSpace(image)
+		if len(image) == 0 || seen[image] {
+			continue
+		}
+
+		ref, err := name.ParseReference(image)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid image %q in %q: %s", image, api.ExtraImagesAnnotationKey, err))
+			continue
+		}
+		if tag, ok := ref.(name.Tag); ok && tag.TagStr() == "latest" {
+			problems = append(problems, fmt.Sprintf("image %q in %q must have a version tag, or a digest",
+				image, api.ExtraImagesAnnotationKey))
+			continue
+		}
+		seen[image] = true
+
+		containerName := extraImagePrefix + path.Base(ref.Context().RepositoryStr())
+		names[containerName]++
+		if n := names[containerName]; n > 1 {
+			containerName += "-" + strconv.Itoa(n)
+		}
+
+		images = append(images, ExtraImage{Name: containerName, Image: image})
+	}
+
+	return images, problems
+}
@@ -0,0 +1,60 @@
+package options
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// compileGlob will compile the given shell style glob pattern into a regex
+// which must match the whole tag. Supports the syntax of path.Match, being
+// '*', '?', '[...]' character classes and '\' escapes.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	// Validate the whole pattern, since path.Match will otherwise only report
+	// a bad pattern when it is reached during matching.
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, err
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			re.WriteString(".*")
+
+		case '?':
+			re.WriteString(".")
+
+		case '\\':
+			i++
+			re.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+
+		case '[':
+			re.WriteString("[")
+			i++
+			if glob[i] == '^' {
+				re.WriteString("^")
+				i++
+			}
+
+			for ; glob[i] != ']'; i++ {
+				// Range dashes are left as is, since QuoteMeta does not escape them.
+				if glob[i] == '\\' {
+					i++
+				}
+				re.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+			}
+
+			re.WriteString("]")
+
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	re.WriteString("$")
+
+	return regexp.Compile(re.String())
+}
@@ -4,23 +4,76 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/jetstack/version-checker/pkg/api"
 )
 
+const (
+	// annotationDomain is the domain all version-checker annotation keys are
+	// suffixed with.
+	annotationDomain = "version-checker.io"
+)
+
+var (
+	// knownAnnotationKeys are the container annotation keys understood by the
+	// Builder, and whether they only accept a boolean value.
+	knownAnnotationKeys = map[string]bool{
+		api.EnableAnnotationKey:      true,
+		api.OverrideURLAnnotationKey: false,
+		api.UseSHAAnnotationKey:      true,
+		api.MatchRegexAnnotationKey:  false,
+		api.MatchGlobAnnotationKey:   false,
+		api.UseMetaDataAnnotationKey: true,
+		api.PinMajorAnnotationKey:    false,
+		api.PinMinorAnnotationKey:    false,
+		api.PinPatchAnnotationKey:    false,
+		api.DefaultOSAnnotationKey:   false,
+		api.DefaultArchAnnotationKey: false,
+		api.ExcludeArchAnnotationKey: false,
+
+		api.VersionSchemeAnnotationKey: false,
+		api.DateLayoutAnnotationKey:    false,
+
+		api.RequireSignatureAnnotationKey: true,
+		api.MaxVersionAnnotationKey:       false,
+		api.CheckBaseImageAnnotationKey:   true,
+		api.SeverityAnnotationKey:         false,
+	}
+)
+
+// maxVersionRegex matches a version ceiling, which must be numeric.
+var maxVersionRegex = regexp.MustCompile(`^v?[0-9]+(\.[0-9]+){0,2}$`)
+
 // Builder is a struct for building container search options.
 type Builder struct {
+	// ans are the annotations of the pod. Annotation keys without a container
+	// name are the options of all containers of the pod, which are overridden
+	// by the annotations of each container.
 	ans map[string]string
+
+	// defaults are the default options of all containers, keyed by annotation
+	// key without a container name, which are overridden by annotations.
+	defaults map[string]string
 }
 
 type optionsHandler func(name string, opts *api.Options, setNonSha *bool, errs *[]string) error
 
 // New contructs a new Builder.
 func New(annotations map[string]string) *Builder {
+	return NewWithDefaults(nil, annotations)
+}
+
+// NewWithDefaults constructs a new Builder, where the defaults are used for
+// any option not set by the container or pod annotations. Defaults are keyed
+// by annotation key, without a container name.
+func NewWithDefaults(defaults, annotations map[string]string) *Builder {
 	return &Builder{
-		ans: annotations,
+		ans:      annotations,
+		defaults: defaults,
 	}
 }
 
@@ -38,10 +91,18 @@ func (b *Builder) Options(name string) (*api.Options, error) {
 		b.handleSHAOption,
 		b.handleMetadataOption,
 		b.handleRegexOption,
+		b.handleGlobOption,
 		b.handlePinMajorOption,
 		b.handlePinMinorOption,
 		b.handlePinPatchOption,
 		b.handleOverrideURLOption,
+		b.handleDefaultPlatformOption,
+		b.handleExcludeArchOption,
+		b.handleVersionSchemeOption,
+		b.handleRequireSignatureOption,
+		b.handleMaxVersionOption,
+		b.handleCheckBaseImageOption,
+		b.handleSeverityOption,
 	}
 
 	// Execute each handler
@@ -56,6 +117,22 @@ func (b *Builder) Options(name string) (*api.Options, error) {
 		errs = append(errs, fmt.Sprintf("cannot define %q with any semver options", b.index(name, api.UseSHAAnnotationKey)))
 	}
 
+	// Ensure the date-sha scheme is not used with SHA or semver options
+	if opts.VersionScheme == api.VersionSchemeDateSHA &&
+		(opts.UseSHA || opts.UseMetaData || opts.PinMajor != nil || opts.MaxVersion != nil) {
+		errs = append(errs, fmt.Sprintf("cannot define %q as %q with %q or any semver options other than %q",
+			b.index(name, api.VersionSchemeAnnotationKey), api.VersionSchemeDateSHA,
+			b.index(name, api.UseSHAAnnotationKey), b.index(name, api.MatchRegexAnnotationKey)))
+	}
+
+	// Ensure the epoch scheme is not used with SHA or a max version, which
+	// has no epoch
+	if opts.VersionScheme == api.VersionSchemeEpoch && (opts.UseSHA || opts.MaxVersion != nil) {
+		errs = append(errs, fmt.Sprintf("cannot define %q as %q with %q or %q",
+			b.index(name, api.VersionSchemeAnnotationKey), api.VersionSchemeEpoch,
+			b.index(name, api.UseSHAAnnotationKey), b.index(name, api.MaxVersionAnnotationKey)))
+	}
+
 	if len(errs) > 0 {
 		return nil, errors.New(strings.Join(errs, ", "))
 	}
@@ -63,14 +140,14 @@ func (b *Builder) Options(name string) (*api.Options, error) {
 	return &opts, nil
 }
 func (b *Builder) handleSHAOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
-	if useSHA, ok := b.ans[b.index(name, api.UseSHAAnnotationKey)]; ok && useSHA == "true" {
+	if useSHA, ok := b.value(name, api.UseSHAAnnotationKey); ok && useSHA == "true" {
 		opts.UseSHA = true
 	}
 	return nil
 }
 
 func (b *Builder) handleMetadataOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
-	if useMetaData, ok := b.ans[b.index(name, api.UseMetaDataAnnotationKey)]; ok && useMetaData == "true" {
+	if useMetaData, ok := b.value(name, api.UseMetaDataAnnotationKey); ok && useMetaData == "true" {
 		*setNonSha = true
 		opts.UseMetaData = true
 	}
@@ -78,7 +155,7 @@ func (b *Builder) handleMetadataOption(name string, opts *api.Options, setNonSha
 }
 
 func (b *Builder) handleRegexOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
-	if matchRegex, ok := b.ans[b.index(name, api.MatchRegexAnnotationKey)]; ok {
+	if matchRegex, ok := b.value(name, api.MatchRegexAnnotationKey); ok {
 		*setNonSha = true
 		opts.MatchRegex = &matchRegex
 
@@ -92,8 +169,32 @@ func (b *Builder) handleRegexOption(name string, opts *api.Options, setNonSha *b
 	return nil
 }
 
+func (b *Builder) handleGlobOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
+	matchGlob, ok := b.value(name, api.MatchGlobAnnotationKey)
+	if !ok {
+		return nil
+	}
+
+	*setNonSha = true
+	opts.MatchGlob = &matchGlob
+
+	if opts.MatchRegex != nil {
+		return fmt.Errorf("cannot define %q with %q",
+			b.index(name, api.MatchGlobAnnotationKey), b.index(name, api.MatchRegexAnnotationKey))
+	}
+
+	globMatcher, err := compileGlob(matchGlob)
+	if err != nil {
+		return fmt.Errorf("failed to compile glob at annotation %q: %s", b.index(name, api.MatchGlobAnnotationKey), err)
+	}
+
+	opts.RegexMatcher = globMatcher
+
+	return nil
+}
+
 func (b *Builder) handlePinMajorOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
-	if pinMajor, ok := b.ans[b.index(name, api.PinMajorAnnotationKey)]; ok {
+	if pinMajor, ok := b.value(name, api.PinMajorAnnotationKey); ok {
 		*setNonSha = true
 		ma, err := strconv.ParseInt(pinMajor, 10, 64)
 		if err != nil {
@@ -106,7 +207,7 @@ func (b *Builder) handlePinMajorOption(name string, opts *api.Options, setNonSha
 }
 
 func (b *Builder) handlePinMinorOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
-	if pinMinor, ok := b.ans[b.index(name, api.PinMinorAnnotationKey)]; ok {
+	if pinMinor, ok := b.value(name, api.PinMinorAnnotationKey); ok {
 		*setNonSha = true
 		if opts.PinMajor == nil {
 			*errs = append(*errs, fmt.Sprintf("unable to set %q without setting %q", b.index(name, api.PinMinorAnnotationKey), b.index(name, api.PinMajorAnnotationKey)))
@@ -123,7 +224,7 @@ func (b *Builder) handlePinMinorOption(name string, opts *api.Options, setNonSha
 }
 
 func (b *Builder) handlePinPatchOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
-	if pinPatch, ok := b.ans[b.index(name, api.PinPatchAnnotationKey)]; ok {
+	if pinPatch, ok := b.value(name, api.PinPatchAnnotationKey); ok {
 		*setNonSha = true
 		if opts.PinMajor == nil || opts.PinMinor == nil {
 			*errs = append(*errs, fmt.Sprintf("unable to set %q without setting %q and %q", b.index(name, api.PinPatchAnnotationKey), b.index(name, api.PinMinorAnnotationKey), b.index(name, api.PinMajorAnnotationKey)))
@@ -140,16 +241,115 @@ func (b *Builder) handlePinPatchOption(name string, opts *api.Options, setNonSha
 }
 
 func (b *Builder) handleOverrideURLOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
-	if overrideURL, ok := b.ans[b.index(name, api.OverrideURLAnnotationKey)]; ok {
+	if overrideURL, ok := b.value(name, api.OverrideURLAnnotationKey); ok {
 		opts.OverrideURL = &overrideURL
 	}
 	return nil
 }
 
+func (b *Builder) handleRequireSignatureOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
+	if requireSignature, ok := b.value(name, api.RequireSignatureAnnotationKey); ok && requireSignature == "true" {
+		opts.RequireSignature = true
+	}
+	return nil
+}
+
+func (b *Builder) handleMaxVersionOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
+	maxVersion, ok := b.value(name, api.MaxVersionAnnotationKey)
+	if !ok {
+		return nil
+	}
+
+	*setNonSha = true
+
+	if !maxVersionRegex.MatchString(maxVersion) {
+		return fmt.Errorf("invalid max version %q at annotation %q, must be a version such as \"3.4.0\"",
+			maxVersion, b.index(name, api.MaxVersionAnnotationKey))
+	}
+
+	opts.MaxVersion = &maxVersion
+
+	return nil
+}
+
+func (b *Builder) handleCheckBaseImageOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
+	if checkBaseImage, ok := b.value(name, api.CheckBaseImageAnnotationKey); ok && checkBaseImage == "true" {
+		opts.CheckBaseImage = true
+	}
+	return nil
+}
+
+func (b *Builder) handleSeverityOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
+	severity, ok := b.value(name, api.SeverityAnnotationKey)
+	if !ok {
+		return nil
+	}
+
+	if !slices.Contains(api.Severities, api.Severity(severity)) {
+		return fmt.Errorf("unknown severity %q at annotation %q, must be one of %q",
+			severity, b.index(name, api.SeverityAnnotationKey), api.Severities)
+	}
+	opts.Severity = api.Severity(severity)
+
+	return nil
+}
+
+func (b *Builder) handleDefaultPlatformOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
+	if defaultOS, ok := b.value(name, api.DefaultOSAnnotationKey); ok {
+		opts.DefaultOS = api.OS(defaultOS)
+	}
+	if defaultArch, ok := b.value(name, api.DefaultArchAnnotationKey); ok {
+		opts.DefaultArch = api.Architecture(defaultArch)
+	}
+	return nil
+}
+
+func (b *Builder) handleExcludeArchOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
+	excludeArchs, ok := b.value(name, api.ExcludeArchAnnotationKey)
+	if !ok {
+		return nil
+	}
+
+	for _, arch := range strings.Split(excludeArchs, ",") {
+		arch = strings.TrimSpace(arch)
+		if len(arch) == 0 {
+			return fmt.Errorf("invalid architectures %q at annotation %q, must be a comma separated list of architectures",
+				excludeArchs, b.index(name, api.ExcludeArchAnnotationKey))
+		}
+		opts.ExcludeArchs = append(opts.ExcludeArchs, api.Architecture(arch))
+	}
+
+	return nil
+}
+
+func (b *Builder) handleVersionSchemeOption(name string, opts *api.Options, setNonSha *bool, errs *[]string) error {
+	if scheme, ok := b.value(name, api.VersionSchemeAnnotationKey); ok {
+		switch api.VersionScheme(scheme) {
+		case api.VersionSchemeSemver, api.VersionSchemeDateSHA, api.VersionSchemeEpoch:
+			opts.VersionScheme = api.VersionScheme(scheme)
+		default:
+			*errs = append(*errs, fmt.Sprintf("unknown version scheme %q at annotation %q, must be %q, %q or %q",
+				scheme, b.index(name, api.VersionSchemeAnnotationKey),
+				api.VersionSchemeSemver, api.VersionSchemeDateSHA, api.VersionSchemeEpoch))
+		}
+	}
+
+	if dateLayout, ok := b.value(name, api.DateLayoutAnnotationKey); ok {
+		if opts.VersionScheme != api.VersionSchemeDateSHA {
+			*errs = append(*errs, fmt.Sprintf("unable to set %q without setting %q to %q",
+				b.index(name, api.DateLayoutAnnotationKey), b.index(name, api.VersionSchemeAnnotationKey), api.VersionSchemeDateSHA))
+		} else {
+			opts.DateLayout = dateLayout
+		}
+	}
+	return nil
+}
+
 // IsEnabled will return whether the container has the enabled annotation set.
 // Will fall back to default, if not set true/false.
 func (b *Builder) IsEnabled(defaultEnabled bool, name string) bool {
-	switch b.ans[b.index(name, api.EnableAnnotationKey)] {
+	enabled, _ := b.value(name, api.EnableAnnotationKey)
+	switch enabled {
 	case "true":
 		return true
 	case "false":
@@ -159,7 +359,175 @@ func (b *Builder) IsEnabled(defaultEnabled bool, name string) bool {
 	}
 }
 
-// index returns the annotation index give the API annotaion key.
+// Validate will return the problems found with the version-checker
+// annotations, given the names of the containers in the pod, including those
+// of its extra images. Unknown annotation keys, annotations for containers
+// which don't exist, invalid boolean values, invalid extra images, and
+// options which fail to build are reported. Annotation keys without a
+// container name are options of all containers of the pod.
+func (b *Builder) Validate(containerNames []string) []string {
+	var problems []string
+
+	containers := make(map[string]bool)
+	for _, name := range containerNames {
+		containers[name] = true
+	}
+
+	keys := make([]string, 0, len(b.ans))
+	for key := range b.ans {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		// The extra images annotation is of the pod, rather than a container.
+		if key == api.ExtraImagesAnnotationKey {
+			_, extraProblems := ExtraImages(b.ans)
+			problems = append(problems, extraProblems...)
+			continue
+		}
+
+		annotationKey, containerName, ok := strings.Cut(key, "/")
+		if annotationKey != annotationDomain && !strings.HasSuffix(annotationKey, "."+annotationDomain) {
+			continue
+		}
+
+		isBool, known := knownAnnotationKeys[annotationKey]
+		switch {
+		case !known:
+			problems = append(problems, fmt.Sprintf("unknown annotation %q", key))
+		case ok && !containers[containerName]:
+			problems = append(problems, fmt.Sprintf("annotation %q does not match any container in the pod", key))
+		case isBool && b.ans[key] != "true" && b.ans[key] != "false":
+			problems = append(problems, fmt.Sprintf("annotation %q must be \"true\" or \"false\", got %q", key, b.ans[key]))
+		}
+	}
+
+	for _, name := range containerNames {
+		if _, err := b.Options(name); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	return problems
+}
+
+// ValidateDefaults will return the problems found with the given default
+// options. Unknown keys, keys which name a container, invalid boolean values,
+// and options which fail to build are reported.
+func ValidateDefaults(defaults map[string]string) []string {
+	var problems []string
+
+	keys := make([]string, 0, len(defaults))
+	for key := range defaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		isBool, known := knownAnnotationKeys[key]
+		switch {
+		case !known:
+			problems = append(problems, fmt.Sprintf("unknown default option %q", key))
+		case isBool && defaults[key] != "true" && defaults[key] != "false":
+			problems = append(problems, fmt.Sprintf("default option %q must be \"true\" or \"false\", got %q", key, defaults[key]))
+		}
+	}
+
+	if _, err := NewWithDefaults(defaults, nil).Options(""); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	return problems
+}
+
+// value returns the value of the given annotation key for the container,
+// falling back to the annotation of the pod without a container name, and
+// then to the default, if not set. Pod annotations and defaults are used
+// unless they conflict with the annotations of the container, or defaults
+// with those of the pod.
+func (b *Builder) value(containerName, annotationName string) (string, bool) {
+	if value, ok := b.ans[b.index(containerName, annotationName)]; ok {
+		return value, true
+	}
+
+	if value, ok := b.ans[annotationName]; ok && !b.conflictsWithDefault(containerName, annotationName, false) {
+		return value, true
+	}
+
+	value, ok := b.defaults[annotationName]
+	if !ok || b.conflictsWithDefault(containerName, annotationName, true) {
+		return "", false
+	}
+
+	return value, true
+}
+
+// conflictsWithDefault returns true if the container sets its own mode of
+// matching versions, which the default of the given annotation key conflicts
+// with, such as a default regex for a container using SHAs. Defaults of one
+// mode are then dropped, rather than failing every container of another. If
+// withPod, the annotations of the pod without a container name, which aren't
+// themselves dropped, are considered alongside those of the container.
+func (b *Builder) conflictsWithDefault(containerName, annotationName string, withPod bool) bool {
+	lookup := func(key string) (string, bool) {
+		if value, ok := b.ans[b.index(containerName, key)]; ok {
+			return value, true
+		}
+		if withPod {
+			if value, ok := b.ans[key]; ok && !b.conflictsWithDefault(containerName, key, false) {
+				return value, true
+			}
+		}
+		return "", false
+	}
+
+	set := func(keys ...string) bool {
+		for _, key := range keys {
+			if _, ok := lookup(key); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	useSHAValue, _ := lookup(api.UseSHAAnnotationKey)
+	schemeValue, _ := lookup(api.VersionSchemeAnnotationKey)
+	useSHA := useSHAValue == "true"
+	scheme := api.VersionScheme(schemeValue)
+
+	switch annotationName {
+	case api.UseSHAAnnotationKey:
+		return set(api.MatchRegexAnnotationKey, api.MatchGlobAnnotationKey,
+			api.VersionSchemeAnnotationKey, api.DateLayoutAnnotationKey,
+			api.UseMetaDataAnnotationKey, api.PinMajorAnnotationKey, api.PinMinorAnnotationKey,
+			api.PinPatchAnnotationKey, api.MaxVersionAnnotationKey)
+
+	case api.MatchRegexAnnotationKey, api.MatchGlobAnnotationKey:
+		return useSHA || set(api.MatchRegexAnnotationKey, api.MatchGlobAnnotationKey)
+
+	case api.VersionSchemeAnnotationKey:
+		return useSHA
+
+	case api.DateLayoutAnnotationKey:
+		return useSHA || set(api.VersionSchemeAnnotationKey)
+
+	case api.UseMetaDataAnnotationKey, api.PinMajorAnnotationKey, api.PinMinorAnnotationKey, api.PinPatchAnnotationKey:
+		return useSHA || scheme == api.VersionSchemeDateSHA
+
+	case api.MaxVersionAnnotationKey:
+		return useSHA || scheme == api.VersionSchemeDateSHA || scheme == api.VersionSchemeEpoch
+	}
+
+	return false
+}
+
+// index returns the annotation index give the API annotaion key. The key is
+// returned as is if no container is named, as for default options.
 func (b *Builder) index(containerName, annotationName string) string {
+	if len(containerName) == 0 {
+		return annotationName
+	}
+
 	return annotationName + "/" + containerName
 }
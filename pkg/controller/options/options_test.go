@@ -101,6 +101,43 @@ func TestBuild(t *testing.T) {
 			},
 			expErr: "",
 		},
+		"output options for glob": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.MatchGlobAnnotationKey + "/test-name": "v1.*",
+			},
+			expOptions: &api.Options{
+				MatchGlob:    stringp("v1.*"),
+				RegexMatcher: regexp.MustCompile(`^v1\..*$`),
+			},
+			expErr: "",
+		},
+		"cannot use glob with regex": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.MatchGlobAnnotationKey + "/test-name":  "v1.*",
+				api.MatchRegexAnnotationKey + "/test-name": `^v1\.`,
+			},
+			expOptions: nil,
+			expErr:     `cannot define "match-glob.version-checker.io/test-name" with "match-regex.version-checker.io/test-name"`,
+		},
+		"invalid glob should error": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.MatchGlobAnnotationKey + "/test-name": "v1.[",
+			},
+			expOptions: nil,
+			expErr:     `failed to compile glob at annotation "match-glob.version-checker.io/test-name": syntax error in pattern`,
+		},
+		"cannot use sha with non sha options (glob)": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.MatchGlobAnnotationKey + "/test-name": "v1.*",
+				api.UseSHAAnnotationKey + "/test-name":    "true",
+			},
+			expOptions: nil,
+			expErr:     `cannot define "use-sha.version-checker.io/test-name" with any semver options`,
+		},
 		"output options for sha": {
 			containerName: "test-name",
 			annotations: map[string]string{
@@ -111,6 +148,170 @@ func TestBuild(t *testing.T) {
 			},
 			expErr: "",
 		},
+		"output options for require signature": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.RequireSignatureAnnotationKey + "/test-name": "true",
+				api.PinMajorAnnotationKey + "/test-name":         "1",
+			},
+			expOptions: &api.Options{
+				RequireSignature: true,
+				PinMajor:         int64p(1),
+			},
+			expErr: "",
+		},
+		"output options for check base image": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.CheckBaseImageAnnotationKey + "/test-name": "true",
+				api.UseSHAAnnotationKey + "/test-name":         "true",
+			},
+			expOptions: &api.Options{
+				CheckBaseImage: true,
+				UseSHA:         true,
+			},
+			expErr: "",
+		},
+		"output options for max version with pins": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.MaxVersionAnnotationKey + "/test-name": "3.4.0",
+				api.PinMajorAnnotationKey + "/test-name":   "3",
+			},
+			expOptions: &api.Options{
+				MaxVersion: stringp("3.4.0"),
+				PinMajor:   int64p(3),
+			},
+			expErr: "",
+		},
+		"invalid max version should error": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.MaxVersionAnnotationKey + "/test-name": "3.4.0-rc.1",
+			},
+			expOptions: nil,
+			expErr:     `invalid max version "3.4.0-rc.1" at annotation "max-version.version-checker.io/test-name", must be a version such as "3.4.0"`,
+		},
+		"cannot use sha with non sha options (max version)": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.MaxVersionAnnotationKey + "/test-name": "3.4.0",
+				api.UseSHAAnnotationKey + "/test-name":     "true",
+			},
+			expOptions: nil,
+			expErr:     `cannot define "use-sha.version-checker.io/test-name" with any semver options`,
+		},
+		"output options for default os and arch": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.DefaultOSAnnotationKey + "/test-name":   "linux",
+				api.DefaultArchAnnotationKey + "/test-name": "arm64",
+				api.UseSHAAnnotationKey + "/test-name":      "true",
+			},
+			expOptions: &api.Options{
+				UseSHA:      true,
+				DefaultOS:   "linux",
+				DefaultArch: "arm64",
+			},
+			expErr: "",
+		},
+		"output options for excluded architectures": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.ExcludeArchAnnotationKey + "/test-name": "s390x, ppc64le",
+			},
+			expOptions: &api.Options{
+				ExcludeArchs: []api.Architecture{"s390x", "ppc64le"},
+			},
+			expErr: "",
+		},
+		"should error on an empty excluded architecture": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.ExcludeArchAnnotationKey + "/test-name": "s390x,",
+			},
+			expOptions: nil,
+			expErr:     `invalid architectures "s390x," at annotation "exclude-arch.version-checker.io/test-name", must be a comma separated list of architectures`,
+		},
+		"output options for date-sha version scheme with date layout and regex": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.VersionSchemeAnnotationKey + "/test-name": "date-sha",
+				api.DateLayoutAnnotationKey + "/test-name":    "2006.01.02",
+				api.MatchRegexAnnotationKey + "/test-name":    `-[a-f0-9]+$`,
+			},
+			expOptions: &api.Options{
+				VersionScheme: api.VersionSchemeDateSHA,
+				DateLayout:    "2006.01.02",
+				MatchRegex:    stringp(`-[a-f0-9]+$`),
+				RegexMatcher:  regexp.MustCompile(`-[a-f0-9]+$`),
+			},
+			expErr: "",
+		},
+		"epoch version scheme with pins should be used": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.VersionSchemeAnnotationKey + "/test-name": "epoch",
+				api.PinMajorAnnotationKey + "/test-name":      "1",
+			},
+			expOptions: &api.Options{
+				VersionScheme: api.VersionSchemeEpoch,
+				PinMajor:      int64p(1),
+			},
+			expErr: "",
+		},
+		"epoch version scheme with a max version should error": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.VersionSchemeAnnotationKey + "/test-name": "epoch",
+				api.MaxVersionAnnotationKey + "/test-name":    "2.0.0",
+			},
+			expOptions: nil,
+			expErr:     `cannot define "version-scheme.version-checker.io/test-name" as "epoch" with "use-sha.version-checker.io/test-name" or "max-version.version-checker.io/test-name"`,
+		},
+		"unknown version scheme should error": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.VersionSchemeAnnotationKey + "/test-name": "calver",
+			},
+			expOptions: nil,
+			expErr:     `unknown version scheme "calver" at annotation "version-scheme.version-checker.io/test-name", must be "semver", "date-sha" or "epoch"`,
+		},
+		"date layout without date-sha version scheme should error": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.DateLayoutAnnotationKey + "/test-name": "20060102",
+			},
+			expOptions: nil,
+			expErr:     `unable to set "date-layout.version-checker.io/test-name" without setting "version-scheme.version-checker.io/test-name" to "date-sha"`,
+		},
+		"date-sha version scheme with semver pins should error": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.VersionSchemeAnnotationKey + "/test-name": "date-sha",
+				api.PinMajorAnnotationKey + "/test-name":      "1",
+			},
+			expOptions: nil,
+			expErr:     `cannot define "version-scheme.version-checker.io/test-name" as "date-sha" with "use-sha.version-checker.io/test-name" or any semver options other than "match-regex.version-checker.io/test-name"`,
+		},
+		"a valid severity should be set": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.SeverityAnnotationKey + "/test-name": "critical",
+			},
+			expOptions: &api.Options{
+				Severity: api.SeverityCritical,
+			},
+			expErr: "",
+		},
+		"unknown severity should error": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.SeverityAnnotationKey + "/test-name": "urgent",
+			},
+			expOptions: nil,
+			expErr:     `unknown severity "urgent" at annotation "severity.version-checker.io/test-name", must be one of ["critical" "warning" "info"]`,
+		},
 		"bool options that don't have 'true' and nothing": {
 			containerName: "test-name",
 			annotations: map[string]string{
@@ -143,6 +344,74 @@ func TestBuild(t *testing.T) {
 	}
 }
 
+func TestCompileGlob(t *testing.T) {
+	tests := map[string]struct {
+		glob      string
+		matches   []string
+		noMatches []string
+	}{
+		"star matches everything": {
+			glob:    "*",
+			matches: []string{"", "latest", "v1.2.3", "1.2.3-debian-r3"},
+		},
+		"minor line": {
+			glob:      "1.*",
+			matches:   []string{"1.0", "1.2.3", "1.2.3-alpha.0"},
+			noMatches: []string{"11.2.3", "v1.2.3", "2.1.3", "1"},
+		},
+		"dotted wildcards": {
+			glob:      "v2.*.*",
+			matches:   []string{"v2.1.0", "v2.10.3"},
+			noMatches: []string{"v2.1", "2.1.0", "v3.1.0"},
+		},
+		"dots are literal": {
+			glob:      "1.2",
+			matches:   []string{"1.2"},
+			noMatches: []string{"102", "1.2.3"},
+		},
+		"question mark matches a single character": {
+			glob:      "v1.?",
+			matches:   []string{"v1.0", "v1.9"},
+			noMatches: []string{"v1.", "v1.10"},
+		},
+		"character classes": {
+			glob:      "v[0-9].[^0]*",
+			matches:   []string{"v1.1", "v2.5.0"},
+			noMatches: []string{"v1.0", "va.1"},
+		},
+		"escaped wildcard": {
+			glob:      `v1\*`,
+			matches:   []string{"v1*"},
+			noMatches: []string{"v1.0"},
+		},
+		"regex characters are literal": {
+			glob:      "v1+(debian)",
+			matches:   []string{"v1+(debian)"},
+			noMatches: []string{"v11debian"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			re, err := compileGlob(test.glob)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			for _, tag := range test.matches {
+				if !re.MatchString(tag) {
+					t.Errorf("expected %q to match %q", test.glob, tag)
+				}
+			}
+			for _, tag := range test.noMatches {
+				if re.MatchString(tag) {
+					t.Errorf("expected %q to not match %q", test.glob, tag)
+				}
+			}
+		})
+	}
+}
+
 func TestIsEnabled(t *testing.T) {
 	tests := map[string]struct {
 		containerName string
@@ -239,6 +508,155 @@ func TestIsEnabled(t *testing.T) {
 	}
 }
 
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		containerNames []string
+		annotations    map[string]string
+		expProblems    []string
+	}{
+		"no annotations should have no problems": {
+			containerNames: []string{"test-name"},
+			annotations:    map[string]string{},
+			expProblems:    nil,
+		},
+		"valid annotations and unrelated annotations should have no problems": {
+			containerNames: []string{"test-name", "init-name"},
+			annotations: map[string]string{
+				api.EnableAnnotationKey + "/test-name":   "true",
+				api.PinMajorAnnotationKey + "/test-name": "1",
+				api.UseSHAAnnotationKey + "/init-name":   "true",
+				"example.com/test-name":                  "foo",
+				"not-version-checker.io/test-name":       "foo",
+			},
+			expProblems: nil,
+		},
+		"misspelled annotation key should be a problem": {
+			containerNames: []string{"test-name"},
+			annotations: map[string]string{
+				"pin-majour.version-checker.io/test-name": "1",
+			},
+			expProblems: []string{
+				`unknown annotation "pin-majour.version-checker.io/test-name"`,
+			},
+		},
+		"annotation for a container not in the pod should be a problem": {
+			containerNames: []string{"test-name"},
+			annotations: map[string]string{
+				api.EnableAnnotationKey + "/other-name": "true",
+			},
+			expProblems: []string{
+				`annotation "enable.version-checker.io/other-name" does not match any container in the pod`,
+			},
+		},
+		"pod annotations without a container should be validated as options of all containers": {
+			containerNames: []string{"test-name"},
+			annotations: map[string]string{
+				api.EnableAnnotationKey:   "yes",
+				api.PinMajorAnnotationKey: "foo",
+			},
+			expProblems: []string{
+				`annotation "enable.version-checker.io" must be "true" or "false", got "yes"`,
+				`failed to parse pin-major.version-checker.io/test-name: strconv.ParseInt: parsing "foo": invalid syntax`,
+			},
+		},
+		"non boolean values should be a problem": {
+			containerNames: []string{"test-name"},
+			annotations: map[string]string{
+				api.EnableAnnotationKey + "/test-name":      "yes",
+				api.UseMetaDataAnnotationKey + "/test-name": "1",
+			},
+			expProblems: []string{
+				`annotation "enable.version-checker.io/test-name" must be "true" or "false", got "yes"`,
+				`annotation "use-metadata.version-checker.io/test-name" must be "true" or "false", got "1"`,
+			},
+		},
+		"options which fail to build should be a problem": {
+			containerNames: []string{"test-name"},
+			annotations: map[string]string{
+				api.PinMajorAnnotationKey + "/test-name": "foo",
+			},
+			expProblems: []string{
+				`failed to parse pin-major.version-checker.io/test-name: strconv.ParseInt: parsing "foo": invalid syntax`,
+			},
+		},
+		"extra images should be validated, and match annotations of their names": {
+			containerNames: []string{"test-name", "extra-worker"},
+			annotations: map[string]string{
+				api.ExtraImagesAnnotationKey:                "quay.io/corp/worker:v1.0.0,worker",
+				api.PinMajorAnnotationKey + "/extra-worker": "1",
+			},
+			expProblems: []string{
+				`image "worker" in "extra-images.version-checker.io" must have a version tag, or a digest`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			problems := New(test.annotations).Validate(test.containerNames)
+			if !reflect.DeepEqual(problems, test.expProblems) {
+				t.Errorf("unexpected problems, exp=%q got=%q",
+					test.expProblems, problems)
+			}
+		})
+	}
+}
+
+func TestExtraImages(t *testing.T) {
+	sha := "0000000000000000000000000000000000000000000000000000000000000000"
+
+	tests := map[string]struct {
+		annotation  *string
+		expImages   []ExtraImage
+		expProblems []string
+	}{
+		"no annotation should have no images": {},
+		"images should be named after their repository": {
+			annotation: stringp("app:1, quay.io/corp/api:v2.0.0,registry.corp:5000/web@sha256:" + sha),
+			expImages: []ExtraImage{
+				{Name: "extra-app", Image: "app:1"},
+				{Name: "extra-api", Image: "quay.io/corp/api:v2.0.0"},
+				{Name: "extra-web", Image: "registry.corp:5000/web@sha256:" + sha},
+			},
+		},
+		"images of the same repository name should be numbered, and duplicates skipped": {
+			annotation: stringp("app:1,corp/app:2,app:1,,"),
+			expImages: []ExtraImage{
+				{Name: "extra-app", Image: "app:1"},
+				{Name: "extra-app-2", Image: "corp/app:2"},
+			},
+		},
+		"invalid images should be skipped, keeping the valid images": {
+			annotation: stringp("app:1,API:2,web,db:latest"),
+			expImages: []ExtraImage{
+				{Name: "extra-app", Image: "app:1"},
+			},
+			expProblems: []string{
+				`invalid image "API:2" in "extra-images.version-checker.io": could not parse reference: API:2`,
+				`image "web" in "extra-images.version-checker.io" must have a version tag, or a digest`,
+				`image "db:latest" in "extra-images.version-checker.io" must have a version tag, or a digest`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			annotations := map[string]string{}
+			if test.annotation != nil {
+				annotations[api.ExtraImagesAnnotationKey] = *test.annotation
+			}
+
+			images, problems := ExtraImages(annotations)
+			if !reflect.DeepEqual(images, test.expImages) {
+				t.Errorf("unexpected images, exp=%v got=%v", test.expImages, images)
+			}
+			if !reflect.DeepEqual(problems, test.expProblems) {
+				t.Errorf("unexpected problems, exp=%q got=%q", test.expProblems, problems)
+			}
+		})
+	}
+}
+
 func int64p(i int64) *int64 {
 	return &i
 }
@@ -246,3 +664,293 @@ func int64p(i int64) *int64 {
 func stringp(s string) *string {
 	return &s
 }
+
+func TestBuildWithDefaults(t *testing.T) {
+	defaults := map[string]string{
+		api.PinMajorAnnotationKey:    "1",
+		api.MatchRegexAnnotationKey:  `^v\d+\.\d+\.\d+$`,
+		api.UseMetaDataAnnotationKey: "true",
+	}
+
+	tests := map[string]struct {
+		containerName string
+		annotations   map[string]string
+		expOptions    *api.Options
+		expErr        string
+	}{
+		"no annotations should use the defaults": {
+			containerName: "test-name",
+			annotations:   nil,
+			expOptions: &api.Options{
+				MatchRegex:   stringp(`^v\d+\.\d+\.\d+$`),
+				RegexMatcher: regexp.MustCompile(`^v\d+\.\d+\.\d+$`),
+				UseMetaData:  true,
+				PinMajor:     int64p(1),
+			},
+		},
+		"container annotations should override the defaults": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.PinMajorAnnotationKey + "/test-name":    "2",
+				api.MatchRegexAnnotationKey + "/test-name":  `^v\d+$`,
+				api.UseMetaDataAnnotationKey + "/test-name": "false",
+			},
+			expOptions: &api.Options{
+				MatchRegex:   stringp(`^v\d+$`),
+				RegexMatcher: regexp.MustCompile(`^v\d+$`),
+				PinMajor:     int64p(2),
+			},
+		},
+		"annotations of other containers should not override the defaults": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.PinMajorAnnotationKey + "/other-name": "2",
+			},
+			expOptions: &api.Options{
+				MatchRegex:   stringp(`^v\d+\.\d+\.\d+$`),
+				RegexMatcher: regexp.MustCompile(`^v\d+\.\d+\.\d+$`),
+				UseMetaData:  true,
+				PinMajor:     int64p(1),
+			},
+		},
+		"containers using SHAs should drop the conflicting defaults": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.UseSHAAnnotationKey + "/test-name": "true",
+			},
+			expOptions: &api.Options{
+				UseSHA: true,
+			},
+		},
+		"containers matching a glob should drop the default regex": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.MatchGlobAnnotationKey + "/test-name": "v1.*",
+			},
+			expOptions: &api.Options{
+				MatchGlob:    stringp("v1.*"),
+				RegexMatcher: regexp.MustCompile(`^v1\..*$`),
+				UseMetaData:  true,
+				PinMajor:     int64p(1),
+			},
+		},
+		"containers of the date-sha scheme should drop the default semver options": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.VersionSchemeAnnotationKey + "/test-name": string(api.VersionSchemeDateSHA),
+			},
+			expOptions: &api.Options{
+				MatchRegex:    stringp(`^v\d+\.\d+\.\d+$`),
+				RegexMatcher:  regexp.MustCompile(`^v\d+\.\d+\.\d+$`),
+				VersionScheme: api.VersionSchemeDateSHA,
+			},
+		},
+		"pod annotations should override the defaults": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.PinMajorAnnotationKey:    "2",
+				api.UseMetaDataAnnotationKey: "false",
+			},
+			expOptions: &api.Options{
+				MatchRegex:   stringp(`^v\d+\.\d+\.\d+$`),
+				RegexMatcher: regexp.MustCompile(`^v\d+\.\d+\.\d+$`),
+				PinMajor:     int64p(2),
+			},
+		},
+		"container annotations should override the pod annotations": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.PinMajorAnnotationKey:                "2",
+				api.PinMajorAnnotationKey + "/test-name": "3",
+			},
+			expOptions: &api.Options{
+				MatchRegex:   stringp(`^v\d+\.\d+\.\d+$`),
+				RegexMatcher: regexp.MustCompile(`^v\d+\.\d+\.\d+$`),
+				UseMetaData:  true,
+				PinMajor:     int64p(3),
+			},
+		},
+		"pods using SHAs should drop the conflicting defaults": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.UseSHAAnnotationKey: "true",
+			},
+			expOptions: &api.Options{
+				UseSHA: true,
+			},
+		},
+		"containers matching a regex should drop the pod SHAs": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.UseSHAAnnotationKey:                    "true",
+				api.MatchRegexAnnotationKey + "/test-name": `^v\d+$`,
+			},
+			expOptions: &api.Options{
+				MatchRegex:   stringp(`^v\d+$`),
+				RegexMatcher: regexp.MustCompile(`^v\d+$`),
+				UseMetaData:  true,
+				PinMajor:     int64p(1),
+			},
+		},
+		"pod annotations which conflict with each other should still error": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.UseSHAAnnotationKey:   "true",
+				api.PinMajorAnnotationKey: "2",
+			},
+			expErr: `cannot define "use-sha.version-checker.io/test-name" with any semver options`,
+		},
+		"container annotations which conflict with each other should still error": {
+			containerName: "test-name",
+			annotations: map[string]string{
+				api.UseSHAAnnotationKey + "/test-name":   "true",
+				api.PinMajorAnnotationKey + "/test-name": "2",
+			},
+			expErr: `cannot define "use-sha.version-checker.io/test-name" with any semver options`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			options, err := NewWithDefaults(defaults, test.annotations).Options(test.containerName)
+			if len(test.expErr) > 0 {
+				if err == nil || err.Error() != test.expErr {
+					t.Errorf("unexpected error, exp=%s got=%v",
+						test.expErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			if !reflect.DeepEqual(test.expOptions, options) {
+				t.Errorf("unexpected options for %s=%v exp=%#+v got=%#+v",
+					test.containerName, test.annotations, test.expOptions, options)
+			}
+		})
+	}
+
+	t.Run("default modes should be dropped by containers of another mode", func(t *testing.T) {
+		tests := map[string]struct {
+			defaults    map[string]string
+			annotations map[string]string
+			expOptions  *api.Options
+		}{
+			"default SHAs should be dropped by a container regex": {
+				defaults:    map[string]string{api.UseSHAAnnotationKey: "true"},
+				annotations: map[string]string{api.MatchRegexAnnotationKey + "/test-name": `^v\d+$`},
+				expOptions:  &api.Options{MatchRegex: stringp(`^v\d+$`), RegexMatcher: regexp.MustCompile(`^v\d+$`)},
+			},
+			"default SHAs should be dropped by a container pin": {
+				defaults:    map[string]string{api.UseSHAAnnotationKey: "true"},
+				annotations: map[string]string{api.PinMajorAnnotationKey + "/test-name": "1"},
+				expOptions:  &api.Options{PinMajor: int64p(1)},
+			},
+			"a default date layout should be dropped by a container scheme": {
+				defaults: map[string]string{
+					api.VersionSchemeAnnotationKey: string(api.VersionSchemeDateSHA),
+					api.DateLayoutAnnotationKey:    "20060102",
+				},
+				annotations: map[string]string{api.VersionSchemeAnnotationKey + "/test-name": string(api.VersionSchemeSemver)},
+				expOptions:  &api.Options{VersionScheme: api.VersionSchemeSemver},
+			},
+			"a default scheme should be kept by a container date layout": {
+				defaults:    map[string]string{api.VersionSchemeAnnotationKey: string(api.VersionSchemeDateSHA)},
+				annotations: map[string]string{api.DateLayoutAnnotationKey + "/test-name": "20060102"},
+				expOptions:  &api.Options{VersionScheme: api.VersionSchemeDateSHA, DateLayout: "20060102"},
+			},
+			"a default max version should be dropped by a container of the epoch scheme": {
+				defaults:    map[string]string{api.MaxVersionAnnotationKey: "2"},
+				annotations: map[string]string{api.VersionSchemeAnnotationKey + "/test-name": string(api.VersionSchemeEpoch)},
+				expOptions:  &api.Options{VersionScheme: api.VersionSchemeEpoch},
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				options, err := NewWithDefaults(test.defaults, test.annotations).Options("test-name")
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if !reflect.DeepEqual(test.expOptions, options) {
+					t.Errorf("unexpected options, exp=%#+v got=%#+v", test.expOptions, options)
+				}
+			})
+		}
+	})
+
+	t.Run("enabled default should be overridden by the container annotation", func(t *testing.T) {
+		builder := NewWithDefaults(map[string]string{api.EnableAnnotationKey: "true"}, map[string]string{
+			api.EnableAnnotationKey + "/disabled-name": "false",
+		})
+
+		if !builder.IsEnabled(false, "test-name") {
+			t.Error("expected container without annotation to be enabled by default")
+		}
+		if builder.IsEnabled(false, "disabled-name") {
+			t.Error("expected container with annotation to be disabled")
+		}
+	})
+
+	t.Run("enabled default should be overridden by the pod annotation, and it by the container annotation", func(t *testing.T) {
+		builder := NewWithDefaults(map[string]string{api.EnableAnnotationKey: "true"}, map[string]string{
+			api.EnableAnnotationKey:                   "false",
+			api.EnableAnnotationKey + "/enabled-name": "true",
+		})
+
+		if builder.IsEnabled(false, "test-name") {
+			t.Error("expected container without annotation to be disabled by the pod")
+		}
+		if !builder.IsEnabled(false, "enabled-name") {
+			t.Error("expected container with annotation to be enabled")
+		}
+	})
+}
+
+func TestValidateDefaults(t *testing.T) {
+	tests := map[string]struct {
+		defaults    map[string]string
+		expProblems []string
+	}{
+		"no defaults should have no problems": {
+			defaults:    nil,
+			expProblems: nil,
+		},
+		"valid defaults should have no problems": {
+			defaults: map[string]string{
+				api.PinMajorAnnotationKey:    "1",
+				api.UseMetaDataAnnotationKey: "true",
+			},
+			expProblems: nil,
+		},
+		"unknown and container keys should be a problem": {
+			defaults: map[string]string{
+				"pin-majour.version-checker.io":        "1",
+				api.PinMajorAnnotationKey + "/my-name": "1",
+			},
+			expProblems: []string{
+				`unknown default option "pin-major.version-checker.io/my-name"`,
+				`unknown default option "pin-majour.version-checker.io"`,
+			},
+		},
+		"non boolean values and options which fail to build should be a problem": {
+			defaults: map[string]string{
+				api.UseSHAAnnotationKey:   "yes",
+				api.PinMajorAnnotationKey: "foo",
+			},
+			expProblems: []string{
+				`default option "use-sha.version-checker.io" must be "true" or "false", got "yes"`,
+				`failed to parse pin-major.version-checker.io: strconv.ParseInt: parsing "foo": invalid syntax`,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			problems := ValidateDefaults(test.defaults)
+			if !reflect.DeepEqual(problems, test.expProblems) {
+				t.Errorf("unexpected problems, exp=%q got=%q",
+					test.expProblems, problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Recheck will immediately requeue the pods matching the given namespace and
+// name, returning the number of pods enqueued. An empty name matches all pods
+// in the namespace, and an empty namespace matches all pods. Pods are
+// requeued regardless of any backoff, held resyncs, or no version found
+// backoffs of their containers, so that they are checked promptly after a
+// registry outage or credential rotation. Pods of namespaces of other shards
+// are not requeued. Safe to call concurrently.
+func (c *Controller) Recheck(namespace, name string) (int, error) {
+	select {
+	case <-c.synced:
+	default:
+		return 0, errors.New("pod informer has not yet synced")
+	}
+
+	var pods []*corev1.Pod
+	switch {
+	case len(name) > 0:
+		pod, err := c.podLister.Pods(namespace).Get(name)
+		if apierrors.IsNotFound(err) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to get pod %s/%s: %s", namespace, name, err)
+		}
+		pods = append(pods, pod)
+
+	case len(namespace) > 0:
+		var err error
+		pods, err = c.podLister.Pods(namespace).List(labels.Everything())
+		if err != nil {
+			return 0, fmt.Errorf("failed to list pods in namespace %q: %s", namespace, err)
+		}
+
+	default:
+		var err error
+		pods, err = c.podLister.List(labels.Everything())
+		if err != nil {
+			return 0, fmt.Errorf("failed to list pods: %s", err)
+		}
+	}
+
+	var enqueued int
+	for _, pod := range pods {
+		if !c.shard.Contains(pod.Namespace) {
+			continue
+		}
+
+		key, err := cache.MetaNamespaceKeyFunc(pod)
+		if err != nil {
+			continue
+		}
+
+		c.scheduledWorkQueue.Forget(key)
+		c.releaseResync(key)
+		for _, containerKey := range c.containerKeys(pod) {
+			c.releaseNoVersion(containerKey)
+		}
+		c.workqueue.Forget(key)
+		c.workqueue.Add(key)
+		enqueued++
+	}
+
+	c.log.Infof("requeued %d pods for recheck", enqueued)
+
+	return enqueued, nil
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"path"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// imageRegistry returns the registry host of the given image, as pulled by
+// the container runtime rather than after any rewrite rules, being docker.io
+// for images without a registry host. Images which fail to parse have no
+// registry host, so are never allowed.
+func imageRegistry(image string) string {
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return ""
+	}
+
+	if registry := ref.Context().RegistryStr(); registry != name.DefaultRegistry {
+		return registry
+	}
+
+	return "docker.io"
+}
+
+// isAllowedRegistry returns whether the given registry host matches any of
+// the allowed registries, which may contain wildcards such as *.corp. All
+// registries are allowed if none are configured.
+func (c *Controller) isAllowedRegistry(registry string) bool {
+	if len(c.allowedRegistries) == 0 {
+		return true
+	}
+
+	for _, pattern := range c.allowedRegistries {
+		if ok, _ := path.Match(pattern, registry); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkAllowedRegistry will expose whether the image of the given container
+// is from a registry which is not allowed, returning false if so.
+func (c *Controller) checkAllowedRegistry(pod *corev1.Pod, container *corev1.Container, containerType string) bool {
+	if len(c.allowedRegistries) == 0 {
+		return true
+	}
+
+	registry := imageRegistry(container.Image)
+	if c.isAllowedRegistry(registry) {
+		c.metrics.RemoveDisallowedRegistry(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+		return true
+	}
+
+	c.metrics.SetDisallowedRegistry(c.cluster, pod.Namespace, pod.Name, container.Name, containerType,
+		container.Image, registry)
+
+	return false
+}
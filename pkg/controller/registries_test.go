@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
+	fakesearch "github.com/jetstack/version-checker/pkg/controller/internal/fake/search"
+	"github.com/jetstack/version-checker/pkg/controller/options"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func TestIsAllowedRegistry(t *testing.T) {
+	tests := map[string]struct {
+		allowed    []string
+		image      string
+		expAllowed bool
+	}{
+		"all registries should be allowed without allowed registries": {
+			image:      "quay.io/jetstack/version-checker:v0.9.0",
+			expAllowed: true,
+		},
+		"listed registries should be allowed": {
+			allowed:    []string{"quay.io"},
+			image:      "quay.io/jetstack/version-checker:v0.9.0",
+			expAllowed: true,
+		},
+		"images without a registry host should be docker.io": {
+			allowed:    []string{"docker.io"},
+			image:      "nginx:1.27",
+			expAllowed: true,
+		},
+		"images of docker hub users without a registry host should be docker.io": {
+			allowed:    []string{"docker.io"},
+			image:      "jetstack/version-checker:v0.9.0",
+			expAllowed: true,
+		},
+		"wildcards should match registries": {
+			allowed:    []string{"*.corp"},
+			image:      "harbor.corp/team/app@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			expAllowed: true,
+		},
+		"registry ports should be matched": {
+			allowed:    []string{"*.corp"},
+			image:      "harbor.corp:5000/team/app:v1.0.0",
+			expAllowed: false,
+		},
+		"registries not listed should not be allowed": {
+			allowed:    []string{"docker.io", "*.corp"},
+			image:      "ghcr.io/team/app:v1.0.0",
+			expAllowed: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Controller{allowedRegistries: test.allowed}
+			assert.Equal(t, test.expAllowed, c.isAllowedRegistry(imageRegistry(test.image)))
+		})
+	}
+}
+
+func TestSyncContainerDisallowedRegistry(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	reg := prometheus.NewRegistry()
+
+	var searches int
+	searcher := fakesearch.New().WithFunc(func(*api.Options) (*api.ImageTag, error) {
+		searches++
+		return &api.ImageTag{Tag: "v1.0.0", SHA: "sha256:abc"}, nil
+	})
+
+	controller := &Controller{
+		log:               log,
+		checker:           checker.New(searcher, nil),
+		metrics:           metrics.New(testLogger, reg, metrics.Options{}),
+		defaultTestAll:    true,
+		allowedRegistries: []string{"*.corp"},
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "app", ImageID: "ghcr.io/team/app@sha256:abc"},
+			},
+		},
+	}
+	container := &corev1.Container{Name: "app", Image: "ghcr.io/team/app:v1.0.0"}
+
+	sync := func() {
+		assert.NoError(t, controller.syncContainer(context.Background(), log, options.New(nil), pod, container, "container"))
+	}
+
+	const help = `
+# HELP version_checker_disallowed_registry Set for containers whose image is from a registry which is not allowed
+# TYPE version_checker_disallowed_registry gauge
+`
+
+	// Disallowed registries should be exposed, and still checked
+	sync()
+	assert.Equal(t, 1, searches)
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(help+`
+version_checker_disallowed_registry{cluster="",container="app",container_type="container",image="ghcr.io/team/app:v1.0.0",namespace="default",pod="test-pod",registry="ghcr.io"} 1
+`), "version_checker_disallowed_registry"))
+
+	// Blocked registries should not be checked, removing previous results
+	controller.blockDisallowedRegistries = true
+	sync()
+	assert.Equal(t, 1, searches)
+	assert.Empty(t, controller.metrics.Entries())
+	assert.Equal(t, 1, testutil.CollectAndCount(reg, "version_checker_disallowed_registry"))
+
+	// Images from allowed registries should remove the series
+	container.Image = "harbor.corp/team/app:v1.0.0"
+	sync()
+	assert.Equal(t, 2, searches)
+	assert.Equal(t, 0, testutil.CollectAndCount(reg, "version_checker_disallowed_registry"))
+}
@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/controller/scheduler"
+)
+
+// errorKind classifies a sync error, to decide how the pod is requeued.
+type errorKind int
+
+const (
+	// errorKindTransient errors, such as registry request failures, are
+	// retried with exponential backoff.
+	errorKindTransient errorKind = iota
+
+	// errorKindNoVersion errors, where no version matched the search
+	// criteria, are retried after a long quiet period.
+	errorKindNoVersion
+
+	// errorKindPermanent errors, such as invalid annotations, are not retried
+	// until the pod is updated.
+	errorKindPermanent
+)
+
+// syncError is an error syncing a pod, of a given kind.
+type syncError struct {
+	kind errorKind
+	err  error
+}
+
+func newSyncError(kind errorKind, err error) error {
+	return &syncError{kind: kind, err: err}
+}
+
+func (s *syncError) Error() string {
+	return s.err.Error()
+}
+
+func (s *syncError) Unwrap() error {
+	return s.err
+}
+
+// kindOfError returns the kind of the given error. Errors which have not
+// been classified are transient.
+func kindOfError(err error) errorKind {
+	var syncErr *syncError
+	if errors.As(err, &syncErr) {
+		return syncErr.kind
+	}
+
+	return errorKindTransient
+}
+
+// mostRetriedKind returns the kind of the given errors which is retried the
+// soonest, so that a pod with a transient error is not held back by a
+// permanent error of another container.
+func mostRetriedKind(errs []error) errorKind {
+	kind := errorKindPermanent
+	for _, err := range errs {
+		kind = min(kind, kindOfError(err))
+	}
+
+	return kind
+}
+
+// holdResync will stop informer resyncs from requeueing the given pod, until
+// the given time. A zero time holds the pod until it is updated.
+func (c *Controller) holdResync(key string, until time.Time) {
+	c.heldMu.Lock()
+	defer c.heldMu.Unlock()
+	c.held[key] = until
+}
+
+// releaseResync will allow informer resyncs to requeue the given pod again.
+func (c *Controller) releaseResync(key string) {
+	c.heldMu.Lock()
+	defer c.heldMu.Unlock()
+	delete(c.held, key)
+}
+
+// isResyncHeld returns true if the given update is an informer resync of a
+// pod which is being held, rather than a change to the pod.
+func (c *Controller) isResyncHeld(old, new interface{}) bool {
+	oldPod, ok := old.(*corev1.Pod)
+	if !ok {
+		return false
+	}
+	newPod, ok := new.(*corev1.Pod)
+	if !ok || oldPod.ResourceVersion != newPod.ResourceVersion {
+		return false
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(newPod)
+	if err != nil {
+		return false
+	}
+
+	c.heldMu.Lock()
+	defer c.heldMu.Unlock()
+
+	until, ok := c.held[key]
+	if !ok {
+		return false
+	}
+
+	return until.IsZero() || time.Now().Before(until)
+}
+
+// noVersionEntry is a container where no version was found, with the image
+// and options it was checked with.
+type noVersionEntry struct {
+	fingerprint string
+	until       time.Time
+}
+
+// containerKey returns the key of the given container of the pod, in the no
+// version found cache and adaptive intervals.
+func containerKey(pod *corev1.Pod, containerName, containerType string) string {
+	return strings.Join([]string{pod.Namespace, pod.Name, containerType, containerName}, "/")
+}
+
+// containerKeys returns the keys of all containers of the pod, including its
+// init containers and extra images.
+func (c *Controller) containerKeys(pod *corev1.Pod) []string {
+	var keys []string
+	for _, container := range pod.Spec.InitContainers {
+		keys = append(keys, containerKey(pod, container.Name, "init"))
+	}
+	for _, container := range pod.Spec.Containers {
+		keys = append(keys, containerKey(pod, container.Name, "container"))
+	}
+	for _, name := range c.extraImageNames(pod) {
+		keys = append(keys, containerKey(pod, name, extraContainerType))
+	}
+	return keys
+}
+
+// noVersionFingerprint returns the fingerprint of the image and options a
+// container is checked with, so that changes to either are checked again.
+func noVersionFingerprint(image string, opts *api.Options) string {
+	data, err := json.Marshal(opts)
+	if err != nil {
+		// Never match, so the container is always checked
+		return ""
+	}
+
+	return image + "\n" + string(data)
+}
+
+// isNoVersionBackoff returns true if no version was found for the given
+// container, with the same fingerprint, within the backoff.
+func (c *Controller) isNoVersionBackoff(key, fingerprint string) bool {
+	if c.noVersionBackoff <= 0 || len(fingerprint) == 0 {
+		return false
+	}
+
+	c.noVersionMu.Lock()
+	defer c.noVersionMu.Unlock()
+
+	entry, ok := c.noVersion[key]
+	if !ok {
+		return false
+	}
+	if entry.fingerprint != fingerprint || !time.Now().Before(entry.until) {
+		delete(c.noVersion, key)
+		return false
+	}
+
+	return true
+}
+
+// backoffNoVersion will stop the given container from being checked again
+// until the backoff expires, or its fingerprint changes.
+func (c *Controller) backoffNoVersion(key, fingerprint string) {
+	if c.noVersionBackoff <= 0 || len(fingerprint) == 0 {
+		return
+	}
+
+	c.noVersionMu.Lock()
+	defer c.noVersionMu.Unlock()
+	c.noVersion[key] = noVersionEntry{
+		fingerprint: fingerprint,
+		until:       time.Now().Add(c.noVersionBackoff),
+	}
+}
+
+// releaseNoVersion will allow the given container to be checked again.
+func (c *Controller) releaseNoVersion(key string) {
+	if c.noVersionBackoff <= 0 {
+		return
+	}
+
+	c.noVersionMu.Lock()
+	defer c.noVersionMu.Unlock()
+	delete(c.noVersion, key)
+}
+
+// recheckInterval returns the interval until the given pod is checked again,
+// after it was checked successfully. With adaptive polling, this is the
+// jittered shortest interval of its containers, and otherwise the given
+// interval, which is also used for pods without checked containers.
+func (c *Controller) recheckInterval(pod *corev1.Pod, interval time.Duration) time.Duration {
+	if c.adaptive == nil {
+		return interval
+	}
+
+	var shortest time.Duration
+	for _, key := range c.containerKeys(pod) {
+		if d, ok := c.adaptive.Interval(key); ok && (shortest == 0 || d < shortest) {
+			shortest = d
+		}
+	}
+	if shortest == 0 {
+		return interval
+	}
+
+	interval = scheduler.Jitter(shortest)
+	c.metrics.ObserveCheckInterval(c.cluster, interval)
+
+	return interval
+}
@@ -0,0 +1,83 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// Field is a field of a resource referencing images, given by a JSONPath.
+type Field struct {
+	Resource schema.GroupVersionResource
+	Path     string
+
+	jsonPath *jsonpath.JSONPath
+}
+
+// ParseField will parse a field of the form <group>/<version>/<resource>=<path>,
+// where the group is empty for the core group, and the path is a JSONPath
+// template such as {.spec.image}. Paths without braces are wrapped in them.
+func ParseField(field string) (Field, error) {
+	gvr, path, ok := strings.Cut(field, "=")
+	if !ok || len(path) == 0 {
+		return Field{}, fmt.Errorf("field %q must be of the form <group>/<version>/<resource>=<path>", field)
+	}
+
+	parts := strings.Split(gvr, "/")
+	if len(parts) != 3 || len(parts[1]) == 0 || len(parts[2]) == 0 {
+		return Field{}, fmt.Errorf("resource %q of field must be of the form <group>/<version>/<resource>", gvr)
+	}
+
+	if !strings.HasPrefix(path, "{") {
+		path = "{" + path + "}"
+	}
+
+	jsonPath := jsonpath.New(gvr).AllowMissingKeys(true)
+	if err := jsonPath.Parse(path); err != nil {
+		return Field{}, fmt.Errorf("failed to parse path %q of field: %s", path, err)
+	}
+
+	return Field{
+		Resource: schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]},
+		Path:     path,
+		jsonPath: jsonPath,
+	}, nil
+}
+
+// String returns the field in the form it was parsed from.
+func (f Field) String() string {
+	return fmt.Sprintf("%s/%s/%s=%s", f.Resource.Group, f.Resource.Version, f.Resource.Resource, f.Path)
+}
+
+// images returns the non-empty image references of the field of the given
+// object. No references are returned if the path is absent. Values which are
+// not strings return an error.
+func (f Field) images(obj *unstructured.Unstructured) ([]string, error) {
+	results, err := f.jsonPath.FindResults(obj.UnstructuredContent())
+	if err != nil {
+		return nil, err
+	}
+
+	var images []string
+	for _, result := range results {
+		for _, value := range result {
+			if !value.IsValid() || !value.CanInterface() {
+				continue
+			}
+
+			image, ok := value.Interface().(string)
+			if !ok {
+				return nil, fmt.Errorf("value of path %q is not a string: %v", f.Path, value.Interface())
+			}
+
+			if image = strings.TrimSpace(image); len(image) > 0 {
+				images = append(images, image)
+			}
+		}
+	}
+
+	return images, nil
+}
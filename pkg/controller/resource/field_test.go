@@ -0,0 +1,109 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseField(t *testing.T) {
+	tests := map[string]struct {
+		field       string
+		expResource schema.GroupVersionResource
+		expPath     string
+		expErr      string
+	}{
+		"custom resource field should be parsed": {
+			field:       "apps.example.com/v1/sidecars={.spec.image}",
+			expResource: schema.GroupVersionResource{Group: "apps.example.com", Version: "v1", Resource: "sidecars"},
+			expPath:     "{.spec.image}",
+		},
+		"core group and paths without braces should be parsed": {
+			field:       "/v1/configmaps=.data.image",
+			expResource: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			expPath:     "{.data.image}",
+		},
+		"no path should error": {
+			field:  "apps.example.com/v1/sidecars",
+			expErr: `field "apps.example.com/v1/sidecars" must be of the form <group>/<version>/<resource>=<path>`,
+		},
+		"no version should error": {
+			field:  "sidecars={.spec.image}",
+			expErr: `resource "sidecars" of field must be of the form <group>/<version>/<resource>`,
+		},
+		"invalid path should error": {
+			field:  "apps.example.com/v1/sidecars={.spec[}",
+			expErr: `failed to parse path "{.spec[}" of field`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			field, err := ParseField(test.field)
+			if len(test.expErr) > 0 {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expResource, field.Resource)
+			assert.Equal(t, test.expPath, field.Path)
+		})
+	}
+}
+
+func TestFieldImages(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"image":    "quay.io/jetstack/version-checker:v0.9.0",
+			"replicas": int64(2),
+			"sidecars": []interface{}{
+				map[string]interface{}{"image": "nginx:1.25.0"},
+				map[string]interface{}{"image": " "},
+				map[string]interface{}{"name": "no-image"},
+			},
+		},
+	}}
+
+	tests := map[string]struct {
+		path      string
+		expImages []string
+		expErr    bool
+	}{
+		"a single image should be returned": {
+			path:      "{.spec.image}",
+			expImages: []string{"quay.io/jetstack/version-checker:v0.9.0"},
+		},
+		"images of lists should be returned, skipping empty images": {
+			path:      "{.spec.sidecars[*].image}",
+			expImages: []string{"nginx:1.25.0"},
+		},
+		"absent paths should return no images": {
+			path: "{.spec.missing.image}",
+		},
+		"values which are not strings should error": {
+			path:   "{.spec.replicas}",
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			field, err := ParseField("apps.example.com/v1/sidecars=" + test.path)
+			require.NoError(t, err)
+
+			images, err := field.images(obj)
+			if test.expErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expImages, images)
+		})
+	}
+}
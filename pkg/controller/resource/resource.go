@@ -0,0 +1,227 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+// Options are used to configure the resource watcher.
+type Options struct {
+	// ClusterName is the name of the cluster the resources are in, exposed
+	// as the cluster label of metrics.
+	ClusterName string
+
+	// Fields are the fields of resources referencing the images to check.
+	Fields []Field
+
+	DefaultOS       api.OS
+	DefaultArch     api.Architecture
+	ExcludeArchs    []api.Architecture
+	PreReleaseOrder []string
+	BuildSuffix     *regexp.Regexp
+}
+
+// Watcher checks the images referenced by fields of resources, such as the
+// images operators are configured to deploy by custom resources, before any
+// pods are running them.
+type Watcher struct {
+	log *logrus.Entry
+
+	client  dynamic.Interface
+	checker *checker.Checker
+	metrics *metrics.Metrics
+
+	opts Options
+
+	workqueue workqueue.TypedInterface[key]
+	indexers  []cache.Indexer
+
+	// references are the image references checked for each resource field,
+	// by the last check of it.
+	references map[key]map[string]bool
+}
+
+// key identifies a resource field, by the index of the field and the
+// namespace/name key of the resource.
+type key struct {
+	field int
+	name  string
+}
+
+// New returns a new resource watcher, watching the given fields of resources
+// with the dynamic client.
+func New(opts Options, metrics *metrics.Metrics, client dynamic.Interface,
+	checker *checker.Checker, log *logrus.Entry) *Watcher {
+	return &Watcher{
+		log:        log.WithField("module", "resource"),
+		client:     client,
+		checker:    checker,
+		metrics:    metrics,
+		opts:       opts,
+		workqueue:  workqueue.NewTyped[key](),
+		references: make(map[key]map[string]bool),
+	}
+}
+
+// Run is a blocking func that will watch the resources of each field,
+// checking their images as they change, and every resync period, until the
+// context is cancelled. Resources which are not served by the API server are
+// retried by their informer, without blocking the other fields.
+func (w *Watcher) Run(ctx context.Context, resyncPeriod time.Duration) error {
+	w.log.Info("starting resource watcher")
+	defer w.workqueue.ShutDown()
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(w.client, resyncPeriod)
+	for i, field := range w.opts.Fields {
+		informer := factory.ForResource(field.Resource).Informer()
+		w.indexers = append(w.indexers, informer.GetIndexer())
+
+		_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { w.enqueue(i, obj) },
+			UpdateFunc: func(_, obj interface{}) { w.enqueue(i, obj) },
+			DeleteFunc: func(obj interface{}) { w.enqueue(i, obj) },
+		})
+		if err != nil {
+			return fmt.Errorf("error creating informer for field %s: %s", field, err)
+		}
+
+		w.log.Infof("checking the images of field %s", field)
+	}
+
+	factory.Start(ctx.Done())
+	go wait.UntilWithContext(ctx, w.runWorker, time.Second)
+
+	<-ctx.Done()
+	w.log.Info("shutting down resource watcher")
+
+	return nil
+}
+
+// enqueue will add the resource of the given field to the workqueue.
+func (w *Watcher) enqueue(field int, obj interface{}) {
+	name, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		w.log.Errorf("failed to get key of resource: %s", err)
+		return
+	}
+
+	w.workqueue.Add(key{field: field, name: name})
+}
+
+// runWorker will process the workqueue until it is shut down.
+func (w *Watcher) runWorker(ctx context.Context) {
+	for {
+		k, shutdown := w.workqueue.Get()
+		if shutdown {
+			return
+		}
+
+		w.sync(ctx, k)
+		w.workqueue.Done(k)
+	}
+}
+
+// sync will check the images of the given resource field, removing the
+// results of images which are no longer referenced, or of resources which
+// have been deleted.
+func (w *Watcher) sync(ctx context.Context, k key) {
+	field := w.opts.Fields[k.field]
+	log := w.log.WithField("field", field.String()).WithField("resource", k.name)
+
+	var images []string
+	obj, exists, err := w.indexers[k.field].GetByKey(k.name)
+	if err != nil {
+		log.Errorf("failed to get resource: %s", err)
+		return
+	}
+
+	if exists {
+		// Invalid fields are logged and treated as referencing no images.
+		images, err = field.images(obj.(*unstructured.Unstructured))
+		if err != nil {
+			log.Errorf("failed to get images of field: %s", err)
+		}
+		if len(images) == 0 {
+			log.Debug("field references no images")
+		}
+	}
+
+	references := make(map[string]bool)
+	for _, image := range images {
+		if references[image] {
+			continue
+		}
+		references[image] = true
+
+		imageLog := log.WithField("image", image)
+		if err := w.checkImage(ctx, imageLog, k, image); err != nil {
+			imageLog.Errorf("failed to check image: %s", err)
+		}
+	}
+
+	for reference := range w.references[k] {
+		if !references[reference] {
+			w.metrics.RemoveResourceImage(w.entry(k, reference))
+		}
+	}
+
+	if len(references) == 0 {
+		delete(w.references, k)
+		return
+	}
+	w.references[k] = references
+}
+
+// checkImage will check the given image reference of the resource field.
+func (w *Watcher) checkImage(ctx context.Context, log *logrus.Entry, k key, reference string) error {
+	result, err := w.checker.Image(ctx, log, reference, &api.Options{
+		DefaultOS:       w.opts.DefaultOS,
+		DefaultArch:     w.opts.DefaultArch,
+		ExcludeArchs:    w.opts.ExcludeArchs,
+		PreReleaseOrder: w.opts.PreReleaseOrder,
+		BuildSuffix:     w.opts.BuildSuffix,
+	})
+	if err != nil {
+		return err
+	}
+
+	entry := w.entry(k, reference)
+	entry.ImageURL = result.ImageURL
+	entry.IsLatest = result.IsLatest
+	entry.CurrentVersion = result.CurrentVersion
+	entry.LatestVersion = result.LatestVersion
+	w.metrics.AddResourceImage(entry)
+
+	return nil
+}
+
+// entry returns the identifying fields of the metrics entry of the given
+// image reference of the resource field.
+func (w *Watcher) entry(k key, reference string) metrics.ResourceImageEntry {
+	field := w.opts.Fields[k.field]
+	namespace, name, _ := cache.SplitMetaNamespaceKey(k.name)
+
+	return metrics.ResourceImageEntry{
+		Cluster:   w.opts.ClusterName,
+		Group:     field.Resource.Group,
+		Resource:  field.Resource.Resource,
+		Namespace: namespace,
+		Name:      name,
+		Path:      field.Path,
+		Reference: reference,
+	}
+}
@@ -0,0 +1,83 @@
+package resource
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
+	"github.com/jetstack/version-checker/pkg/controller/internal/fake/search"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func TestSync(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	reg := prometheus.NewRegistry()
+	m := metrics.New(log, reg, metrics.Options{})
+
+	field, err := ParseField("apps.example.com/v1/sidecars={.spec.sidecars[*].image}")
+	require.NoError(t, err)
+
+	searcher := search.New().WithImageFunc(func(imageURL string, _ *api.Options) (*api.ImageTag, error) {
+		return &api.ImageTag{Tag: "1.25.0", SHA: "sha256:125"}, nil
+	})
+
+	watcher := New(Options{Fields: []Field{field}}, m, nil, checker.New(searcher, nil), log)
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	watcher.indexers = []cache.Indexer{indexer}
+
+	sidecars := func(images ...interface{}) *unstructured.Unstructured {
+		var containers []interface{}
+		for _, image := range images {
+			containers = append(containers, map[string]interface{}{"image": image})
+		}
+
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{"sidecars": containers},
+		}}
+		obj.SetNamespace("default")
+		obj.SetName("injector")
+		return obj
+	}
+
+	ctx := context.Background()
+	k := key{field: 0, name: "default/injector"}
+
+	// Images without a version tag or digest cannot be checked
+	require.NoError(t, indexer.Add(sidecars("nginx:1.24.0", "nginx:1.25.0", "nginx:1.24.0", "envoy")))
+	watcher.sync(ctx, k)
+	assert.Equal(t, 2, testutil.CollectAndCount(reg, "version_checker_resource_image_is_latest_version"))
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP version_checker_resource_image_is_latest_version Where the image referenced by the field of a resource, such as a custom resource, is the latest upstream registry version
+# TYPE version_checker_resource_image_is_latest_version gauge
+version_checker_resource_image_is_latest_version{cluster="",current_version="1.24.0",group="apps.example.com",image="nginx",latest_version="1.25.0",name="injector",namespace="default",path="{.spec.sidecars[*].image}",resource="sidecars"} 0
+version_checker_resource_image_is_latest_version{cluster="",current_version="1.25.0",group="apps.example.com",image="nginx",latest_version="1.25.0",name="injector",namespace="default",path="{.spec.sidecars[*].image}",resource="sidecars"} 1
+`), "version_checker_resource_image_is_latest_version"))
+
+	// Images no longer referenced should have their results removed
+	require.NoError(t, indexer.Update(sidecars("nginx:1.25.0")))
+	watcher.sync(ctx, k)
+	assert.Equal(t, 1, testutil.CollectAndCount(reg, "version_checker_resource_image_is_latest_version"))
+
+	// Invalid fields should be treated as referencing no images
+	require.NoError(t, indexer.Update(sidecars(int64(1))))
+	watcher.sync(ctx, k)
+	assert.Equal(t, 0, testutil.CollectAndCount(reg, "version_checker_resource_image_is_latest_version"))
+
+	// Deleted resources should have their results removed
+	require.NoError(t, indexer.Update(sidecars("nginx:1.25.0")))
+	watcher.sync(ctx, k)
+	require.NoError(t, indexer.Delete(sidecars()))
+	watcher.sync(ctx, k)
+	assert.Equal(t, 0, testutil.CollectAndCount(reg, "version_checker_resource_image_is_latest_version"))
+	assert.Empty(t, watcher.references)
+}
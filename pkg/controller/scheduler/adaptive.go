@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// unchangedChecks is the number of checks an item's result must be
+	// unchanged for, before its interval is backed off.
+	unchangedChecks = 3
+
+	// jitterFactor is the maximum fraction intervals are randomly shortened
+	// or lengthened by, so that items checked together spread out over time.
+	jitterFactor = 0.1
+)
+
+// AdaptiveIntervals are the check intervals of items, adapted to how often
+// their result changes. Items whose result has not changed for a number of
+// checks have their interval doubled, up to the maximum interval, and items
+// whose result just changed are checked again after the minimum interval.
+// Safe for concurrent use.
+type AdaptiveIntervals struct {
+	min, max time.Duration
+
+	mu    sync.Mutex
+	items map[string]*adaptiveItem
+}
+
+// adaptiveItem is the state of an item, of its last result, current interval,
+// and the number of checks its result has been unchanged for since the
+// interval last changed.
+type adaptiveItem struct {
+	result    string
+	interval  time.Duration
+	unchanged int
+}
+
+// NewAdaptiveIntervals returns new adaptive intervals, between the given
+// minimum and maximum intervals.
+func NewAdaptiveIntervals(min, max time.Duration) *AdaptiveIntervals {
+	return &AdaptiveIntervals{
+		min:   min,
+		max:   max,
+		items: make(map[string]*adaptiveItem),
+	}
+}
+
+// Observe will record the result of a check of the given item, adapting its
+// interval. New items start at the minimum interval.
+func (a *AdaptiveIntervals) Observe(key, result string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	item, ok := a.items[key]
+	if !ok || item.result != result {
+		a.items[key] = &adaptiveItem{result: result, interval: a.min}
+		return
+	}
+
+	item.unchanged++
+	if item.unchanged < unchangedChecks {
+		return
+	}
+
+	item.unchanged = 0
+	item.interval *= 2
+	if item.interval > a.max {
+		item.interval = a.max
+	}
+}
+
+// Interval returns the current interval of the given item, and false if it
+// has not been observed.
+func (a *AdaptiveIntervals) Interval(key string) (time.Duration, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	item, ok := a.items[key]
+	if !ok {
+		return 0, false
+	}
+
+	return item.interval, true
+}
+
+// Forget will remove the state of the given item.
+func (a *AdaptiveIntervals) Forget(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.items, key)
+}
+
+// Jitter returns the given interval, randomly shortened or lengthened by up
+// to a tenth.
+func Jitter(interval time.Duration) time.Duration {
+	return time.Duration(float64(interval) * (1 + jitterFactor*(2*rand.Float64()-1)))
+}
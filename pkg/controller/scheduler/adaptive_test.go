@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveIntervals(t *testing.T) {
+	tests := map[string]struct {
+		results []string
+		exp     time.Duration
+	}{
+		"new items should start at the minimum interval": {
+			results: []string{"v1"},
+			exp:     time.Minute,
+		},
+		"unchanged results should keep the interval until enough checks": {
+			results: []string{"v1", "v1", "v1"},
+			exp:     time.Minute,
+		},
+		"unchanged results should double the interval": {
+			results: []string{"v1", "v1", "v1", "v1"},
+			exp:     time.Minute * 2,
+		},
+		"unchanged results should keep doubling the interval": {
+			results: []string{"v1", "v1", "v1", "v1", "v1", "v1", "v1"},
+			exp:     time.Minute * 4,
+		},
+		"the interval should not exceed the maximum interval": {
+			results: []string{"v1", "v1", "v1", "v1", "v1", "v1", "v1", "v1", "v1", "v1", "v1", "v1", "v1"},
+			exp:     time.Minute * 5,
+		},
+		"changed results should reset to the minimum interval": {
+			results: []string{"v1", "v1", "v1", "v1", "v1", "v1", "v1", "v2"},
+			exp:     time.Minute,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			a := NewAdaptiveIntervals(time.Minute, time.Minute*5)
+			for _, result := range test.results {
+				a.Observe("pod/container", result)
+			}
+
+			interval, ok := a.Interval("pod/container")
+			if !ok {
+				t.Fatal("expected item to have an interval")
+			}
+			if interval != test.exp {
+				t.Errorf("unexpected interval, exp=%s got=%s", test.exp, interval)
+			}
+		})
+	}
+}
+
+func TestAdaptiveIntervalsForget(t *testing.T) {
+	a := NewAdaptiveIntervals(time.Minute, time.Minute*5)
+	if _, ok := a.Interval("pod/container"); ok {
+		t.Error("expected unobserved item to have no interval")
+	}
+
+	a.Observe("pod/container", "v1")
+	a.Forget("pod/container")
+	if _, ok := a.Interval("pod/container"); ok {
+		t.Error("expected forgotten item to have no interval")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		interval := Jitter(time.Hour)
+		if interval < time.Minute*54 || interval > time.Minute*66 {
+			t.Fatalf("expected jittered interval within 10%% of 1h, got=%s", interval)
+		}
+	}
+}
@@ -18,6 +18,7 @@ import (
 type Searcher interface {
 	Run(time.Duration)
 	LatestImage(context.Context, string, *api.Options) (*api.ImageTag, error)
+	VersionsBehind(ctx context.Context, imageURL, currentTag, latestTag string, opts *api.Options) (int, error)
 }
 
 // Search is the implementation for the searching and caching of image URLs.
@@ -66,6 +67,13 @@ func (s *Search) LatestImage(ctx context.Context, imageURL string, opts *api.Opt
 	return lastestImage.(*api.ImageTag), nil
 }
 
+// VersionsBehind will return the number of versions of the image between the
+// current and latest tags, using semver. Not cached, since the tags of the
+// image are.
+func (s *Search) VersionsBehind(ctx context.Context, imageURL, currentTag, latestTag string, opts *api.Options) (int, error) {
+	return s.versionGetter.VersionsBehind(ctx, imageURL, currentTag, latestTag, opts)
+}
+
 // Run will run the search and image cache garbage collectors.
 func (s *Search) Run(refreshRate time.Duration) {
 	go s.versionGetter.Run(refreshRate)
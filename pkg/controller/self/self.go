@@ -0,0 +1,162 @@
+package self
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
+	"github.com/jetstack/version-checker/pkg/controller/options"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+// DefaultContainerName is the name of the version-checker container in its
+// own pod, as deployed by the chart.
+const DefaultContainerName = "version-checker"
+
+// Options are used to configure the self check.
+type Options struct {
+	// ClusterName is the name of the cluster version-checker is running in,
+	// exposed as the cluster label of metrics.
+	ClusterName string
+
+	// PodName and PodNamespace are of version-checker's own pod, such as from
+	// the downward API.
+	PodName      string
+	PodNamespace string
+
+	// ContainerName is the name of the version-checker container in its own
+	// pod. The only container of the pod is used if there is no container of
+	// this name.
+	ContainerName string
+
+	DefaultOS       api.OS
+	DefaultArch     api.Architecture
+	ExcludeArchs    []api.Architecture
+	PreReleaseOrder []string
+	BuildSuffix     *regexp.Regexp
+}
+
+// Self checks the image version-checker itself is running, so it is known
+// when version-checker should be upgraded.
+type Self struct {
+	log *logrus.Entry
+
+	client  kubernetes.Interface
+	checker *checker.Checker
+	metrics *metrics.Metrics
+
+	opts Options
+}
+
+// New returns a new self check, getting version-checker's own pod with the
+// given client.
+func New(opts Options, metrics *metrics.Metrics, client kubernetes.Interface,
+	checker *checker.Checker, log *logrus.Entry) *Self {
+	if len(opts.ContainerName) == 0 {
+		opts.ContainerName = DefaultContainerName
+	}
+
+	return &Self{
+		log: log.WithField("module", "self").
+			WithField("pod", opts.PodNamespace+"/"+opts.PodName),
+		client:  client,
+		checker: checker,
+		metrics: metrics,
+		opts:    opts,
+	}
+}
+
+// Run is a blocking func that will check version-checker's own image every
+// period, until the context is cancelled. The result is removed while the
+// image can not be checked.
+func (s *Self) Run(ctx context.Context, period time.Duration) error {
+	if len(s.opts.PodName) == 0 || len(s.opts.PodNamespace) == 0 {
+		return errors.New("the name and namespace of version-checker's own pod must be known to check itself")
+	}
+
+	s.log.Info("starting self check")
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		if err := s.check(ctx); err != nil {
+			s.log.Errorf("failed to check own image: %s", err)
+			s.metrics.RemoveSelf()
+		}
+
+		select {
+		case <-ctx.Done():
+			s.log.Info("shutting down self check")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// check will check the image of version-checker's own container, with the
+// options of any annotations of its pod.
+func (s *Self) check(ctx context.Context) error {
+	pod, err := s.client.CoreV1().Pods(s.opts.PodNamespace).Get(ctx, s.opts.PodName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get own pod: %s", err)
+	}
+
+	container, err := s.container(pod)
+	if err != nil {
+		return err
+	}
+
+	opts, err := options.New(pod.Annotations).Options(container.Name)
+	if err != nil {
+		return fmt.Errorf("failed to build options from annotations: %s", err)
+	}
+	if len(opts.DefaultOS) == 0 {
+		opts.DefaultOS = s.opts.DefaultOS
+	}
+	if len(opts.DefaultArch) == 0 {
+		opts.DefaultArch = s.opts.DefaultArch
+	}
+	if len(opts.ExcludeArchs) == 0 {
+		opts.ExcludeArchs = s.opts.ExcludeArchs
+	}
+	opts.PreReleaseOrder = s.opts.PreReleaseOrder
+	opts.BuildSuffix = s.opts.BuildSuffix
+
+	result, err := s.checker.Container(ctx, s.log.WithField("container", container.Name), pod, container, opts)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return fmt.Errorf("container %q has no image ID to check", container.Name)
+	}
+
+	s.metrics.SetSelf(s.opts.ClusterName, pod.Namespace, pod.Name,
+		result.ImageURL, result.CurrentVersion, result.LatestVersion, result.IsLatest)
+
+	return nil
+}
+
+// container returns version-checker's own container of the pod.
+func (s *Self) container(pod *corev1.Pod) (*corev1.Container, error) {
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == s.opts.ContainerName {
+			return &pod.Spec.Containers[i], nil
+		}
+	}
+
+	if len(pod.Spec.Containers) == 1 {
+		return &pod.Spec.Containers[0], nil
+	}
+
+	return nil, fmt.Errorf("pod has no container %q", s.opts.ContainerName)
+}
@@ -0,0 +1,104 @@
+package self
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
+	"github.com/jetstack/version-checker/pkg/controller/internal/fake/search"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func selfPod(containers ...string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "version-checker-abc",
+			Namespace: "monitoring",
+		},
+	}
+
+	for _, name := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+			Name:  name,
+			Image: "quay.io/jetstack/version-checker:v0.9.0",
+		})
+		pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{
+			Name:    name,
+			ImageID: "quay.io/jetstack/version-checker@sha256:090",
+		})
+	}
+
+	return pod
+}
+
+func TestCheck(t *testing.T) {
+	searcher := search.New().WithImageFunc(func(imageURL string, _ *api.Options) (*api.ImageTag, error) {
+		return &api.ImageTag{Tag: "v0.10.0", SHA: "sha256:0100"}, nil
+	})
+
+	tests := map[string]struct {
+		pod    *corev1.Pod
+		opts   Options
+		expErr string
+	}{
+		"the version-checker container should be checked": {
+			pod: selfPod("sidecar", "version-checker"),
+		},
+		"the only container should be checked": {
+			pod: selfPod("controller"),
+		},
+		"the configured container should be checked": {
+			pod:  selfPod("sidecar", "controller"),
+			opts: Options{ContainerName: "controller"},
+		},
+		"pods without the container should error": {
+			pod:    selfPod("sidecar", "controller"),
+			expErr: `pod has no container "version-checker"`,
+		},
+		"missing pods should error": {
+			pod:    &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "monitoring"}},
+			expErr: `failed to get own pod: pods "version-checker-abc" not found`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			log := logrus.NewEntry(logrus.New())
+			reg := prometheus.NewRegistry()
+			m := metrics.New(log, reg, metrics.Options{})
+
+			test.opts.PodName = "version-checker-abc"
+			test.opts.PodNamespace = "monitoring"
+			self := New(test.opts, m, fake.NewSimpleClientset(test.pod), checker.New(searcher, nil), log)
+
+			err := self.check(context.Background())
+			if len(test.expErr) > 0 {
+				assert.EqualError(t, err, test.expErr)
+				assert.Equal(t, 0, testutil.CollectAndCount(reg, "version_checker_self_is_latest_version"))
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, 1, testutil.CollectAndCount(reg, "version_checker_self_is_latest_version"))
+		})
+	}
+}
+
+func TestRunUnknownPod(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	m := metrics.New(log, prometheus.NewRegistry(), metrics.Options{})
+
+	self := New(Options{PodNamespace: "monitoring"}, m, fake.NewSimpleClientset(), checker.New(search.New(), nil), log)
+	assert.EqualError(t, self.Run(context.Background(), 0),
+		"the name and namespace of version-checker's own pod must be known to check itself")
+}
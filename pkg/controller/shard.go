@@ -0,0 +1,56 @@
+package controller
+
+import (
+	"hash/fnv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Shard is the shard of namespaces a controller checks the pods of, of the
+// given number of shards the namespaces of the cluster are partitioned
+// between. Namespaces are assigned to shards by the FNV-1a hash of their
+// name modulo the number of shards, so that every replica assigns them the
+// same without coordination. All namespaces are checked if Count is 1 or
+// less.
+type Shard struct {
+	Index int
+	Count int
+}
+
+// Contains returns true if the given namespace is of the shard.
+func (s Shard) Contains(namespace string) bool {
+	if s.Count <= 1 {
+		return true
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(namespace))
+	return int(h.Sum32()%uint32(s.Count)) == s.Index
+}
+
+// shardTransform will strip pods of namespaces not of the shard down to their
+// identity, before they are stored by the pod informer, so that the cache
+// only holds the full pods of the shard.
+func (c *Controller) shardTransform(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return obj, nil
+	}
+
+	// Managed fields are never used, and are often larger than the spec.
+	pod.ManagedFields = nil
+
+	if c.shard.Contains(pod.Namespace) {
+		return pod, nil
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       pod.Namespace,
+			Name:            pod.Name,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+		},
+	}, nil
+}
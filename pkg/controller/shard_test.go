@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func TestShardContains(t *testing.T) {
+	assert.True(t, Shard{}.Contains("team-a"), "the zero shard should contain all namespaces")
+	assert.True(t, Shard{Index: 0, Count: 1}.Contains("team-a"), "a single shard should contain all namespaces")
+
+	counts := make([]int, 4)
+	for i := 0; i < 100; i++ {
+		namespace := fmt.Sprintf("team-%d", i)
+
+		var shards int
+		for index := range counts {
+			if (Shard{Index: index, Count: len(counts)}).Contains(namespace) {
+				counts[index]++
+				shards++
+			}
+		}
+		assert.Equal(t, 1, shards, "namespace %q should be of exactly one shard", namespace)
+	}
+
+	for index, count := range counts {
+		assert.NotZero(t, count, "shard %d should contain namespaces", index)
+	}
+}
+
+func TestShardTransform(t *testing.T) {
+	shard := Shard{Index: 0, Count: 2}
+	c := New(Options{CacheTimeout: testOptions.CacheTimeout, Shard: shard},
+		metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}),
+		&client.Client{}, fake.NewSimpleClientset(), testLogger)
+
+	var owned, other string
+	for i := 0; len(owned) == 0 || len(other) == 0; i++ {
+		namespace := fmt.Sprintf("team-%d", i)
+		if shard.Contains(namespace) {
+			owned = namespace
+		} else {
+			other = namespace
+		}
+	}
+
+	pod := func(namespace string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       namespace,
+				Name:            "app",
+				UID:             "uid",
+				ResourceVersion: "1",
+				Labels:          map[string]string{"app": "app"},
+				ManagedFields:   []metav1.ManagedFieldsEntry{{Manager: "kubelet"}},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "app:v1.0.0"}},
+			},
+		}
+	}
+
+	obj, err := c.shardTransform(pod(owned))
+	require.NoError(t, err)
+	exp := pod(owned)
+	exp.ManagedFields = nil
+	assert.Equal(t, exp, obj, "pods of the shard should be kept")
+
+	obj, err = c.shardTransform(pod(other))
+	require.NoError(t, err)
+	assert.Equal(t, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: other, Name: "app", UID: "uid", ResourceVersion: "1"},
+	}, obj, "pods of other shards should be stripped")
+
+	c.addObject(pod(other))
+	assert.Zero(t, c.workqueue.Len(), "pods of other shards should not be queued")
+	c.addObject(pod(owned))
+	assert.Equal(t, 1, c.workqueue.Len(), "pods of the shard should be queued")
+}
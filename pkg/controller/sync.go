@@ -2,38 +2,122 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/controller/checker"
 	"github.com/jetstack/version-checker/pkg/controller/options"
+	"github.com/jetstack/version-checker/pkg/metrics"
 	versionerrors "github.com/jetstack/version-checker/pkg/version/errors"
 )
 
+// ContainerState is the state of a container, used to filter which
+// containers are checked.
+type ContainerState string
+
+const (
+	ContainerStateWaiting    ContainerState = "waiting"
+	ContainerStateRunning    ContainerState = "running"
+	ContainerStateReady      ContainerState = "ready"
+	ContainerStateTerminated ContainerState = "terminated"
+)
+
+// ContainerStates are all of the container states which can be filtered on.
+var ContainerStates = []ContainerState{
+	ContainerStateWaiting,
+	ContainerStateRunning,
+	ContainerStateReady,
+	ContainerStateTerminated,
+}
+
+// UnparseableCurrent is the action taken for containers whose current version
+// cannot be parsed by the version scheme of their check, such as a random
+// string.
+type UnparseableCurrent string
+
+const (
+	// UnparseableCurrentOutdated exposes the container as not latest, with the
+	// latest version in the registry.
+	UnparseableCurrentOutdated UnparseableCurrent = "outdated"
+	// UnparseableCurrentSkip removes the metrics of the container.
+	UnparseableCurrentSkip UnparseableCurrent = "skip"
+	// UnparseableCurrentError fails the check of the container, keeping any
+	// previous result.
+	UnparseableCurrentError UnparseableCurrent = "error"
+)
+
+// UnparseableCurrents are all of the actions for unparseable current
+// versions.
+var UnparseableCurrents = []UnparseableCurrent{
+	UnparseableCurrentOutdated,
+	UnparseableCurrentSkip,
+	UnparseableCurrentError,
+}
+
+// Apply will apply the action to the given result, whose current version
+// cannot be parsed. Returns false if the result should not be reported, or
+// an error with the error action.
+func (u UnparseableCurrent) Apply(result *checker.Result) (bool, error) {
+	switch u {
+	case UnparseableCurrentOutdated:
+		result.IsLatest, result.IsAheadOfRegistry = false, false
+		if len(result.AbsoluteLatestVersion) > 0 {
+			result.IsAbsoluteLatest = false
+		}
+		return true, nil
+	case UnparseableCurrentError:
+		return false, errUnparseableCurrent
+	default:
+		return false, nil
+	}
+}
+
+// errUnparseableCurrent is returned checking containers whose current version
+// cannot be parsed, with the error action.
+var errUnparseableCurrent = errors.New("current version cannot be parsed by the version scheme")
+
 // sync will enqueue a given pod to run against the version checker.
 func (c *Controller) sync(ctx context.Context, pod *corev1.Pod) error {
 	log := c.log.WithField("name", pod.Name).WithField("namespace", pod.Namespace)
 
-	builder := options.New(pod.Annotations)
+	// Observed for pods which fail to be checked too, so that slow and failing
+	// pods are visible.
+	start := time.Now()
+	defer func() {
+		c.metrics.ObservePodCheckDuration(c.cluster, pod.Namespace, time.Since(start))
+	}()
+
+	builder := options.NewWithDefaults(c.defaultOptions(), pod.Annotations)
 
-	var errs []string
+	var errs []error
 	for _, container := range pod.Spec.InitContainers {
 		if err := c.syncContainer(ctx, log, builder, pod, &container, "init"); err != nil {
-			errs = append(errs, err.Error())
+			errs = append(errs, err)
 		}
 	}
 	for _, container := range pod.Spec.Containers {
 		if err := c.syncContainer(ctx, log, builder, pod, &container, "container"); err != nil {
-			errs = append(errs, err.Error())
+			errs = append(errs, err)
 		}
 	}
+	errs = append(errs, c.syncExtraImages(ctx, log, builder, pod)...)
 
 	if len(errs) > 0 {
-		return fmt.Errorf("failed to sync pod %s/%s: %s",
-			pod.Namespace, pod.Name, strings.Join(errs, ","))
+		errStrs := make([]string, len(errs))
+		for i, err := range errs {
+			errStrs[i] = err.Error()
+		}
+
+		return newSyncError(mostRetriedKind(errs), fmt.Errorf("failed to sync pod %s/%s: %s",
+			pod.Namespace, pod.Name, strings.Join(errStrs, ",")))
 	}
 
 	return nil
@@ -42,30 +126,99 @@ func (c *Controller) sync(ctx context.Context, pod *corev1.Pod) error {
 // syncContainer will enqueue a given container to check the version.
 func (c *Controller) syncContainer(ctx context.Context, log *logrus.Entry, builder *options.Builder, pod *corev1.Pod,
 	container *corev1.Container, containerType string) error {
+	// Deprecated repositories are exposed whether or not containers are
+	// checked
+	c.checkDeprecatedRepository(pod, container, containerType)
+
+	// Containers of disallowed registries are exposed whether or not they are
+	// checked, and are not checked at all if blocked
+	if !c.checkAllowedRegistry(pod, container, containerType) && c.blockDisallowedRegistries {
+		log.WithField("container", container.Name).Debug("skipping container of a disallowed registry")
+		c.metrics.RemoveImage(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+		c.metrics.RemoveImageMissing(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+		return nil
+	}
+
 	// If not enabled, exit early
 	if !builder.IsEnabled(c.defaultTestAll, container.Name) {
-		c.metrics.RemoveImage(pod.Namespace, pod.Name, container.Name, containerType)
+		if c.disabledContainerMetric {
+			c.metrics.SetCheckDisabled(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+		} else {
+			c.metrics.RemoveImage(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+		}
+		c.metrics.RemoveImageMissing(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+		return nil
+	}
+
+	// If not in a state to be checked, remove any previous result and exit
+	// early
+	if !c.isCheckedState(pod, container.Name, containerType) {
+		log.WithField("container", container.Name).Debug("skipping container not in a checked state")
+		c.metrics.RemoveImage(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+		c.metrics.RemoveImageMissing(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+		return nil
+	}
+
+	// If the registry is under maintenance, keep the previous result and exit
+	// early
+	if c.isPaused(container.Image) {
+		log.WithField("container", container.Name).Debug("skipping container of a registry under maintenance")
 		return nil
 	}
 
 	opts, err := builder.Options(container.Name)
 	if err != nil {
-		return fmt.Errorf("failed to build options from annotations for %q: %s",
-			container.Name, err)
+		return newSyncError(errorKindPermanent, fmt.Errorf("failed to build options from annotations for %q: %s",
+			container.Name, err))
 	}
 
+	c.optionDefaults.ApplyDefaults(opts)
+
 	log = log.WithField("container", container.Name)
+
+	opts.Credentials, err = c.registryCredentials(ctx, pod.Namespace, container.Image)
+	if err != nil {
+		log.Warnf("failed to get namespace registry credentials, using global credentials: %s", err)
+	}
+
+	// If no version was found within the backoff, with the same image and
+	// options, keep the previous result and exit early, without logging the
+	// error again
+	noVersionKey := containerKey(pod, container.Name, containerType)
+	fingerprint := noVersionFingerprint(container.Image, opts)
+	if c.isNoVersionBackoff(noVersionKey, fingerprint) {
+		log.Debug("skipping container where no version was found within the backoff")
+		return nil
+	}
+
 	log.Debug("processing container image")
 
 	err = c.checkContainer(ctx, log, pod, container, containerType, opts)
-	// Don't re-sync, if no version found meeting search criteria
+	c.checkImageMissing(ctx, log, pod, container, containerType, err)
+
+	// Only re-sync after a quiet period, if no version found meeting search
+	// criteria
+	if versionerrors.IsNoSignatureFound(err) {
+		c.backoffNoVersion(noVersionKey, fingerprint)
+		return newSyncError(errorKindNoVersion, fmt.Errorf("failed to find signed version for container image %q: %s",
+			container.Name, err))
+	}
 	if versionerrors.IsNoVersionFound(err) {
-		log.Error(err.Error())
-		return nil
+		c.backoffNoVersion(noVersionKey, fingerprint)
+		return newSyncError(errorKindNoVersion, fmt.Errorf("failed to find version for container image %q: %s",
+			container.Name, err))
+	}
+
+	c.releaseNoVersion(noVersionKey)
+	// The current version is of the pod spec, so will not be parseable until
+	// the pod is updated
+	if errors.Is(err, errUnparseableCurrent) {
+		return newSyncError(errorKindPermanent, fmt.Errorf("failed to check container image %q: %s",
+			container.Name, err))
 	}
 	if err != nil {
-		return fmt.Errorf("failed to check container image %q: %s",
-			container.Name, err)
+		return newSyncError(errorKindTransient, fmt.Errorf("failed to check container image %q: %s",
+			container.Name, err))
 	}
 
 	return nil
@@ -75,7 +228,17 @@ func (c *Controller) syncContainer(ctx context.Context, log *logrus.Entry, build
 // metrics according to the result.
 func (c *Controller) checkContainer(ctx context.Context, log *logrus.Entry, pod *corev1.Pod,
 	container *corev1.Container, containerType string, opts *api.Options) error {
-	result, err := c.checker.Container(ctx, log, pod, container, opts)
+	var (
+		result *checker.Result
+		err    error
+	)
+	if containerType == extraContainerType {
+		// Extra images are not in the pod spec, so have no status to compare
+		// by, and are checked by their reference.
+		result, err = c.checker.Image(ctx, log, container.Image, opts)
+	} else {
+		result, err = c.checker.Container(ctx, log, pod, container, opts)
+	}
 	if err != nil {
 		return err
 	}
@@ -85,6 +248,35 @@ func (c *Controller) checkContainer(ctx context.Context, log *logrus.Entry, pod
 		return nil
 	}
 
+	if result.IsCurrentUnparseable {
+		report, err := c.unparseableCurrent.Apply(result)
+		if err != nil {
+			return err
+		}
+		if !report {
+			log.Infof("skipping container where current version %q cannot be parsed", result.CurrentVersion)
+			c.metrics.RemoveImage(c.cluster, pod.Namespace, pod.Name, container.Name, containerType)
+			return nil
+		}
+		log.Debugf("current version %q cannot be parsed, exposing as outdated", result.CurrentVersion)
+	}
+
+	key := containerKey(pod, container.Name, containerType)
+	if c.adaptive != nil {
+		c.adaptive.Observe(key, result.ImageURL+":"+result.CurrentVersion+"->"+result.LatestVersion)
+	}
+
+	// Report the latest tag being pushed again with a different digest, which
+	// otherwise goes unnoticed if the tag is mutable.
+	if tag := latestTag(result); len(tag) > 0 && len(result.LatestSHA) > 0 {
+		if previous, mutated := c.tagDigests.observe(key, result.ImageURL, tag,
+			string(result.OS)+"/"+string(result.Architecture), result.LatestSHA); mutated {
+			log.Warnf("tag %s:%s was pushed again, changing digest from %s to %s",
+				result.ImageURL, tag, previous, result.LatestSHA)
+			c.metrics.IncTagMutated(c.cluster, result.ImageURL, tag)
+		}
+	}
+
 	if result.IsLatest {
 		log.Debugf("image is latest %s:%s",
 			result.ImageURL, result.CurrentVersion)
@@ -93,11 +285,139 @@ func (c *Controller) checkContainer(ctx context.Context, log *logrus.Entry, pod
 			result.ImageURL, result.CurrentVersion, result.LatestVersion)
 	}
 
-	c.metrics.AddImage(pod.Namespace, pod.Name,
-		container.Name, containerType,
-		result.ImageURL, result.IsLatest,
-		result.CurrentVersion, result.LatestVersion,
-	)
+	c.metrics.AddImage(metrics.Entry{
+		Cluster:        c.cluster,
+		Namespace:      pod.Namespace,
+		Pod:            pod.Name,
+		Container:      container.Name,
+		ContainerType:  containerType,
+		Workload:       podWorkload(pod),
+		ImageURL:       result.ImageURL,
+		IsLatest:       result.IsLatest,
+		CurrentVersion: result.CurrentVersion,
+		LatestVersion:  result.LatestVersion,
+		OS:             string(result.OS),
+		Arch:           string(result.Architecture),
+		PlatformSource: result.PlatformSource,
+		ArtifactType:   string(artifactType(result)),
+		PinnedByDigest: result.PinnedByDigest,
+		Severity:       string(opts.Severity),
+
+		AbsoluteLatestVersion: result.AbsoluteLatestVersion,
+		IsAbsoluteLatest:      result.IsAbsoluteLatest,
+
+		IsAheadOfRegistry: result.IsAheadOfRegistry,
+		VersionsBehind:    result.VersionsBehind,
+
+		BaseImage: baseImageEntry(result.BaseImage),
+
+		LastChecked: time.Now(),
+	})
 
 	return nil
 }
+
+// latestTag returns the tag of the latest version of the given result, without
+// any digest, or an empty string if the latest version is only a digest.
+func latestTag(result *checker.Result) string {
+	if result.LatestVersion == result.LatestSHA {
+		return ""
+	}
+
+	tag, _, _ := strings.Cut(result.LatestVersion, "@")
+	return tag
+}
+
+// podWorkload returns the workload the given pod is of, by its controller,
+// where the pod template hash is stripped from ReplicaSets of Deployments, so
+// that the pods of every revision are of the same workload. Pods without a
+// controller are of no workload.
+func podWorkload(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return ""
+	}
+
+	name := owner.Name
+	if hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok && owner.Kind == "ReplicaSet" {
+		name = strings.TrimSuffix(name, "-"+hash)
+	}
+
+	return owner.Kind + "/" + name
+}
+
+// artifactType returns the artifact type of the given result, which is an
+// image unless reported otherwise by the registry.
+func artifactType(result *checker.Result) api.ArtifactType {
+	if len(result.ArtifactType) == 0 {
+		return api.ArtifactTypeImage
+	}
+
+	return result.ArtifactType
+}
+
+// baseImageEntry returns the metrics entry of the given base image result, or
+// nil if the base image was not checked.
+func baseImageEntry(result *checker.Result) *metrics.BaseImageEntry {
+	if result == nil {
+		return nil
+	}
+
+	return &metrics.BaseImageEntry{
+		ImageURL:       result.ImageURL,
+		IsLatest:       result.IsLatest,
+		CurrentVersion: result.CurrentVersion,
+		LatestVersion:  result.LatestVersion,
+	}
+}
+
+// isCheckedState returns true if the given container is in one of the
+// container states configured to be checked.
+func (c *Controller) isCheckedState(pod *corev1.Pod, containerName, containerType string) bool {
+	// Extra images have no container status, so are always checked.
+	if len(c.containerStates) == 0 || containerType == extraContainerType {
+		return true
+	}
+
+	for _, state := range containerStates(pod, containerName, containerType) {
+		if c.containerStates[state] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containerStates returns the states the given container is in, from its
+// container status. Containers without a status yet are waiting, and
+// containers of pods being deleted are terminated.
+func containerStates(pod *corev1.Pod, containerName, containerType string) []ContainerState {
+	if pod.DeletionTimestamp != nil {
+		return []ContainerState{ContainerStateTerminated}
+	}
+
+	statuses := pod.Status.ContainerStatuses
+	if containerType == "init" {
+		statuses = pod.Status.InitContainerStatuses
+	}
+
+	for _, status := range statuses {
+		if status.Name != containerName {
+			continue
+		}
+
+		switch {
+		case status.State.Running != nil:
+			if status.Ready {
+				return []ContainerState{ContainerStateRunning, ContainerStateReady}
+			}
+			return []ContainerState{ContainerStateRunning}
+		case status.State.Terminated != nil:
+			return []ContainerState{ContainerStateTerminated}
+		default:
+			return []ContainerState{ContainerStateWaiting}
+		}
+	}
+
+	return []ContainerState{ContainerStateWaiting}
+}
@@ -2,9 +2,21 @@ package controller
 
 import (
 	"context"
+	"io"
+	stdlog "log"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
@@ -12,20 +24,23 @@ import (
 
 	"github.com/jetstack/version-checker/pkg/api"
 	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/client/selfhosted"
 	"github.com/jetstack/version-checker/pkg/controller/checker"
+	fakesearch "github.com/jetstack/version-checker/pkg/controller/internal/fake/search"
 	"github.com/jetstack/version-checker/pkg/controller/options"
 	"github.com/jetstack/version-checker/pkg/controller/search"
 	"github.com/jetstack/version-checker/pkg/metrics"
 	"github.com/jetstack/version-checker/pkg/version"
+	versionerrors "github.com/jetstack/version-checker/pkg/version/errors"
 )
 
 // Test for the sync method.
 func TestController_Sync(t *testing.T) {
 	log := logrus.NewEntry(logrus.New())
-	metrics := &metrics.Metrics{}
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
 	imageClient := &client.Client{}
-	searcher := search.New(log, 5*time.Minute, version.New(log, imageClient, 5*time.Minute))
-	checker := checker.New(searcher)
+	searcher := search.New(log, 5*time.Minute, version.New(log, imageClient, 5*time.Minute, nil, nil))
+	checker := checker.New(searcher, nil)
 
 	controller := &Controller{
 		log:            log,
@@ -56,10 +71,10 @@ func TestController_Sync(t *testing.T) {
 // Test for the syncContainer method.
 func TestController_SyncContainer(t *testing.T) {
 	log := logrus.NewEntry(logrus.New())
-	metrics := &metrics.Metrics{}
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
 	imageClient := &client.Client{}
-	searcher := search.New(log, 5*time.Minute, version.New(log, imageClient, 5*time.Minute))
-	checker := checker.New(searcher)
+	searcher := search.New(log, 5*time.Minute, version.New(log, imageClient, 5*time.Minute, nil, nil))
+	checker := checker.New(searcher, nil)
 
 	controller := &Controller{
 		log:            log,
@@ -87,10 +102,10 @@ func TestController_SyncContainer(t *testing.T) {
 // Test for the checkContainer method.
 func TestController_CheckContainer(t *testing.T) {
 	log := logrus.NewEntry(logrus.New())
-	metrics := &metrics.Metrics{}
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
 	imageClient := &client.Client{}
-	searcher := search.New(log, 5*time.Minute, version.New(log, imageClient, 5*time.Minute))
-	checker := checker.New(searcher)
+	searcher := search.New(log, 5*time.Minute, version.New(log, imageClient, 5*time.Minute, nil, nil))
+	checker := checker.New(searcher, nil)
 
 	controller := &Controller{
 		log:            log,
@@ -115,10 +130,10 @@ func TestController_CheckContainer(t *testing.T) {
 // Example of testing syncContainer when version is not found.
 func TestController_SyncContainer_NoVersionFound(t *testing.T) {
 	log := logrus.NewEntry(logrus.New())
-	metrics := &metrics.Metrics{}
+	metrics := metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{})
 	imageClient := &client.Client{}
-	searcher := search.New(log, 5*time.Minute, version.New(log, imageClient, 5*time.Minute))
-	checker := checker.New(searcher)
+	searcher := search.New(log, 5*time.Minute, version.New(log, imageClient, 5*time.Minute, nil, nil))
+	checker := checker.New(searcher, nil)
 
 	controller := &Controller{
 		log:            log,
@@ -141,3 +156,588 @@ func TestController_SyncContainer_NoVersionFound(t *testing.T) {
 	err := controller.syncContainer(context.Background(), log, builder, pod, container, "container")
 	assert.NoError(t, err) // We expect no error because IsNoVersionFound is handled gracefully
 }
+
+func TestController_IsCheckedState(t *testing.T) {
+	now := metav1.Now()
+
+	tests := map[string]struct {
+		states        []ContainerState
+		pod           *corev1.Pod
+		containerType string
+		expChecked    bool
+	}{
+		"no states configured should check all containers": {
+			states:        nil,
+			pod:           &corev1.Pod{},
+			containerType: "container",
+			expChecked:    true,
+		},
+		"container without a status should be waiting": {
+			states:        []ContainerState{ContainerStateWaiting},
+			pod:           &corev1.Pod{},
+			containerType: "container",
+			expChecked:    true,
+		},
+		"container without a status should not be running": {
+			states:        []ContainerState{ContainerStateRunning},
+			pod:           &corev1.Pod{},
+			containerType: "container",
+			expChecked:    false,
+		},
+		"crash looping container should not be running": {
+			states: []ContainerState{ContainerStateRunning},
+			pod: &corev1.Pod{
+				Status: corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name: "test-container",
+							State: corev1.ContainerState{
+								Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+							},
+						},
+					},
+				},
+			},
+			containerType: "container",
+			expChecked:    false,
+		},
+		"running container should be running but not ready": {
+			states: []ContainerState{ContainerStateReady},
+			pod: &corev1.Pod{
+				Status: corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:  "test-container",
+							State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+						},
+					},
+				},
+			},
+			containerType: "container",
+			expChecked:    false,
+		},
+		"ready container should be ready": {
+			states: []ContainerState{ContainerStateReady},
+			pod: &corev1.Pod{
+				Status: corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:  "other-container",
+							State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{}},
+						},
+						{
+							Name:  "test-container",
+							Ready: true,
+							State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+						},
+					},
+				},
+			},
+			containerType: "container",
+			expChecked:    true,
+		},
+		"ready container of a pod being deleted should be terminated": {
+			states: []ContainerState{ContainerStateRunning, ContainerStateReady},
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &now},
+				Status: corev1.PodStatus{
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:  "test-container",
+							Ready: true,
+							State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+						},
+					},
+				},
+			},
+			containerType: "container",
+			expChecked:    false,
+		},
+		"init container should match init container statuses": {
+			states: []ContainerState{ContainerStateTerminated},
+			pod: &corev1.Pod{
+				Status: corev1.PodStatus{
+					InitContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:  "test-container",
+							State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}},
+						},
+					},
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name:  "test-container",
+							State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+						},
+					},
+				},
+			},
+			containerType: "init",
+			expChecked:    true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			controller := New(Options{ContainerStates: test.states}, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), &client.Client{}, nil, logrus.NewEntry(logrus.New()))
+			assert.Equal(t, test.expChecked, controller.isCheckedState(test.pod, "test-container", test.containerType))
+		})
+	}
+}
+
+// Test that containers not in a checked state are skipped.
+func TestController_SyncContainer_SkippedState(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	controller := New(Options{
+		DefaultTestAll:  true,
+		ContainerStates: []ContainerState{ContainerStateRunning},
+	}, metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}), &client.Client{}, nil, log)
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+		},
+	}
+	container := &corev1.Container{Name: "main-container", Image: "localhost:0/foo:v1.0.0"}
+
+	err := controller.syncContainer(context.Background(), log, options.New(nil), pod, container, "container")
+	assert.NoError(t, err)
+}
+
+// Test that containers where no version was found are not checked again
+// within the backoff, unless their image or options change.
+func TestController_SyncContainer_NoVersionBackoff(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+
+	var searches int
+	searcher := fakesearch.New().WithFunc(func(*api.Options) (*api.ImageTag, error) {
+		searches++
+		return nil, versionerrors.NewVersionErrorNotFound("no tag matches")
+	})
+
+	controller := &Controller{
+		log:              log,
+		checker:          checker.New(searcher, nil),
+		metrics:          metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}),
+		defaultTestAll:   true,
+		noVersionBackoff: time.Hour,
+		noVersion:        make(map[string]noVersionEntry),
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "main-container", ImageID: "localhost:5000/foo@sha256:abc"},
+			},
+		},
+	}
+	container := &corev1.Container{Name: "main-container", Image: "localhost:5000/foo:v1.0.0"}
+
+	sync := func(annotations map[string]string) error {
+		return controller.syncContainer(context.Background(), log, options.New(annotations), pod, container, "container")
+	}
+
+	err := sync(nil)
+	assert.ErrorContains(t, err, "no tag matches")
+	assert.Equal(t, errorKindNoVersion, kindOfError(err))
+	assert.Equal(t, 1, searches)
+
+	// Within the backoff, the container should not be checked again
+	assert.NoError(t, sync(nil))
+	assert.Equal(t, 1, searches)
+
+	// Changing the options should check the container again
+	assert.Error(t, sync(map[string]string{"match-regex.version-checker.io/main-container": "^v1"}))
+	assert.Equal(t, 2, searches)
+	assert.NoError(t, sync(map[string]string{"match-regex.version-checker.io/main-container": "^v1"}))
+	assert.Equal(t, 2, searches)
+
+	// Changing the image should check the container again
+	container.Image = "localhost:5000/foo:v1.1.0"
+	assert.Error(t, sync(nil))
+	assert.Equal(t, 3, searches)
+
+	// Once the backoff expires, the container should be checked again
+	key := containerKey(pod, container.Name, "container")
+	controller.noVersion[key] = noVersionEntry{fingerprint: controller.noVersion[key].fingerprint}
+	assert.Error(t, sync(nil))
+	assert.Equal(t, 4, searches)
+
+	// Deleting the pod should forget the container
+	controller.deleteObject(&corev1.Pod{ObjectMeta: pod.ObjectMeta, Spec: corev1.PodSpec{Containers: []corev1.Container{*container}}})
+	assert.Empty(t, controller.noVersion)
+}
+
+// newTestRegistry returns the host of a registry serving an image of the
+// given repository for each tag, and a client of it.
+func newTestRegistry(t *testing.T, repo string, tags ...string) (string, *client.Client) {
+	server := httptest.NewServer(registry.New(registry.Logger(stdlog.New(io.Discard, "", 0))))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	assert.NoError(t, err)
+
+	img, err := random.Image(64, 1)
+	assert.NoError(t, err)
+	for _, tag := range tags {
+		ref, err := name.NewTag(u.Host + "/" + repo + ":" + tag)
+		assert.NoError(t, err)
+		assert.NoError(t, remote.Write(ref, img))
+	}
+
+	imageClient, err := client.New(context.Background(), testLogger, client.Options{
+		Selfhosted: map[string]*selfhosted.Options{
+			"registry": {Host: server.URL},
+		},
+	})
+	assert.NoError(t, err)
+
+	return u.Host, imageClient
+}
+
+func TestController_SyncContainer_NoVersionBackoffSemver(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	host, imageClient := newTestRegistry(t, "foo", "v1.0.0", "v1.1.0")
+
+	controller := &Controller{
+		log:              log,
+		checker:          checker.New(search.New(log, 5*time.Minute, version.New(log, imageClient, 5*time.Minute, nil, nil)), nil),
+		metrics:          metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}),
+		defaultTestAll:   true,
+		noVersionBackoff: time.Hour,
+		noVersion:        make(map[string]noVersionEntry),
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "main-container", ImageID: host + "/foo@sha256:abc"},
+			},
+		},
+	}
+	container := &corev1.Container{Name: "main-container", Image: host + "/foo:v1.0.0"}
+	builder := options.New(map[string]string{"match-regex.version-checker.io/main-container": "^nomatch$"})
+
+	// A semver search which no tag matches should be no version found, and
+	// backed off
+	err := controller.syncContainer(context.Background(), log, builder, pod, container, "container")
+	assert.ErrorContains(t, err, "no tags found with these option constraints")
+	assert.Equal(t, errorKindNoVersion, kindOfError(err))
+	assert.Contains(t, controller.noVersion, containerKey(pod, container.Name, "container"))
+
+	assert.NoError(t, controller.syncContainer(context.Background(), log, builder, pod, container, "container"))
+}
+
+func TestController_CheckContainer_TagMutated(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	reg := prometheus.NewRegistry()
+
+	sha := "sha256:abc"
+	searcher := fakesearch.New().WithFunc(func(*api.Options) (*api.ImageTag, error) {
+		return &api.ImageTag{Tag: "v1.1.0", SHA: sha}, nil
+	})
+
+	controller := &Controller{
+		log:            log,
+		checker:        checker.New(searcher, nil),
+		metrics:        metrics.New(testLogger, reg, metrics.Options{}),
+		defaultTestAll: true,
+		cluster:        "cluster-1",
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "main-container", ImageID: "localhost:5000/foo@sha256:def"},
+			},
+		},
+	}
+	container := &corev1.Container{Name: "main-container", Image: "localhost:5000/foo:v1.0.0"}
+
+	check := func() {
+		assert.NoError(t, controller.checkContainer(context.Background(), log, pod, container, "container", &api.Options{}))
+	}
+
+	// The first check and an unchanged digest should not count as mutated
+	check()
+	check()
+	assert.Equal(t, 0, testutil.CollectAndCount(reg, "version_checker_tag_mutated_total"))
+
+	// The same tag resolving to a different digest should count as mutated
+	sha = "sha256:123"
+	check()
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP version_checker_tag_mutated_total Number of times the digest of the latest tag of an image changed between checks, without the tag changing
+# TYPE version_checker_tag_mutated_total counter
+version_checker_tag_mutated_total{cluster="cluster-1",image="localhost:5000/foo",tag="v1.1.0"} 1
+`), "version_checker_tag_mutated_total"))
+
+	// Deleting the pod should forget the tag
+	controller.deleteObject(&corev1.Pod{ObjectMeta: pod.ObjectMeta, Spec: corev1.PodSpec{Containers: []corev1.Container{*container}}})
+	assert.Empty(t, controller.tagDigests.tags)
+	assert.Empty(t, controller.tagDigests.containers)
+}
+
+func TestController_CheckContainer_TagMutatedPlatforms(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	reg := prometheus.NewRegistry()
+
+	// The digest of the tag is of the image of the selected architecture
+	searcher := fakesearch.New().WithFunc(func(opts *api.Options) (*api.ImageTag, error) {
+		if slices.Contains(opts.ExcludeArchs, "amd64") {
+			return &api.ImageTag{Tag: "v1.1.0", SHA: "sha256:arm64", OS: "linux", Architecture: "arm64"}, nil
+		}
+		return &api.ImageTag{Tag: "v1.1.0", SHA: "sha256:amd64", OS: "linux", Architecture: "amd64"}, nil
+	})
+
+	controller := &Controller{
+		log:            log,
+		checker:        checker.New(searcher, nil),
+		metrics:        metrics.New(testLogger, reg, metrics.Options{}),
+		defaultTestAll: true,
+		cluster:        "cluster-1",
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "amd64-container", ImageID: "localhost:5000/foo@sha256:def"},
+				{Name: "arm64-container", ImageID: "localhost:5000/foo@sha256:def"},
+			},
+		},
+	}
+	amd64 := &corev1.Container{Name: "amd64-container", Image: "localhost:5000/foo:v1.0.0"}
+	arm64 := &corev1.Container{Name: "arm64-container", Image: "localhost:5000/foo:v1.0.0"}
+
+	// Containers of the same tag, resolving to the images of different
+	// architectures, should not count as mutated
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, controller.checkContainer(context.Background(), log, pod, amd64, "container", &api.Options{}))
+		assert.NoError(t, controller.checkContainer(context.Background(), log, pod, arm64, "container",
+			&api.Options{ExcludeArchs: []api.Architecture{"amd64"}}))
+	}
+	assert.Equal(t, 0, testutil.CollectAndCount(reg, "version_checker_tag_mutated_total"))
+	assert.Len(t, controller.tagDigests.tags, 2)
+}
+
+func TestController_SyncContainer_UnparseableCurrent(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+
+	searcher := fakesearch.New().WithFunc(func(*api.Options) (*api.ImageTag, error) {
+		return &api.ImageTag{Tag: "v1.1.0", SHA: "sha256:abc"}, nil
+	})
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+		},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "main-container", ImageID: "localhost:5000/foo@sha256:def"},
+			},
+		},
+	}
+
+	// The previous result of the container, from before its tag was changed
+	previous := metrics.Entry{
+		Namespace:      "default",
+		Pod:            "test-pod",
+		Container:      "main-container",
+		ContainerType:  "container",
+		ImageURL:       "localhost:5000/foo",
+		IsLatest:       true,
+		CurrentVersion: "v1.1.0",
+		LatestVersion:  "v1.1.0",
+	}
+
+	tests := map[string]struct {
+		unparseableCurrent UnparseableCurrent
+		image              string
+		expErr             bool
+		expEntries         []metrics.Entry
+	}{
+		"outdated should expose the container as not latest": {
+			unparseableCurrent: UnparseableCurrentOutdated,
+			image:              "localhost:5000/foo:stable",
+			expEntries: []metrics.Entry{
+				{CurrentVersion: "stable", LatestVersion: "v1.1.0", IsLatest: false},
+			},
+		},
+		"skip should remove the metrics of the container": {
+			unparseableCurrent: UnparseableCurrentSkip,
+			image:              "localhost:5000/foo:stable",
+		},
+		"empty should skip the container": {
+			image: "localhost:5000/foo:stable",
+		},
+		"error should fail the check permanently, keeping the previous result": {
+			unparseableCurrent: UnparseableCurrentError,
+			image:              "localhost:5000/foo:stable",
+			expErr:             true,
+			expEntries: []metrics.Entry{
+				{CurrentVersion: "v1.1.0", LatestVersion: "v1.1.0", IsLatest: true},
+			},
+		},
+		"parseable versions should be checked as usual": {
+			unparseableCurrent: UnparseableCurrentError,
+			image:              "localhost:5000/foo:v1.0.0",
+			expEntries: []metrics.Entry{
+				{CurrentVersion: "v1.0.0", LatestVersion: "v1.1.0", IsLatest: false},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			controller := &Controller{
+				log:                log,
+				checker:            checker.New(searcher, nil),
+				metrics:            metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}),
+				defaultTestAll:     true,
+				noVersion:          make(map[string]noVersionEntry),
+				unparseableCurrent: test.unparseableCurrent,
+			}
+			controller.metrics.AddImage(previous)
+
+			container := &corev1.Container{Name: "main-container", Image: test.image}
+			err := controller.syncContainer(context.Background(), log, options.New(nil), pod, container, "container")
+			if test.expErr {
+				assert.ErrorContains(t, err, "current version cannot be parsed")
+				assert.Equal(t, errorKindPermanent, kindOfError(err))
+			} else {
+				assert.NoError(t, err)
+			}
+
+			var entries []metrics.Entry
+			for _, entry := range controller.metrics.Entries() {
+				entries = append(entries, metrics.Entry{
+					CurrentVersion: entry.CurrentVersion,
+					LatestVersion:  entry.LatestVersion,
+					IsLatest:       entry.IsLatest,
+				})
+			}
+			assert.Equal(t, test.expEntries, entries)
+		})
+	}
+}
+
+func TestController_Sync_ExtraImages(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+
+	searcher := fakesearch.New().WithImageFunc(func(imageURL string, _ *api.Options) (*api.ImageTag, error) {
+		return &api.ImageTag{Tag: "v1.1.0", SHA: "sha256:" + imageURL}, nil
+	})
+
+	controller := &Controller{
+		log:            log,
+		checker:        checker.New(searcher, nil),
+		metrics:        metrics.New(testLogger, prometheus.NewRegistry(), metrics.Options{}),
+		defaultTestAll: true,
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+			Annotations: map[string]string{
+				api.ExtraImagesAnnotationKey:             "quay.io/corp/worker:v1.0.0,quay.io/corp/job:latest,registry.corp/tools:v1.1.0",
+				api.EnableAnnotationKey + "/extra-tools": "true",
+			},
+		},
+	}
+
+	// Invalid entries should be skipped, checking the valid images
+	assert.NoError(t, controller.sync(context.Background(), pod))
+
+	entries := controller.metrics.Entries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "extra", entries[0].ContainerType)
+		assert.Equal(t, "extra-tools", entries[0].Container)
+		assert.Equal(t, "registry.corp/tools", entries[0].ImageURL)
+		assert.True(t, entries[0].IsLatest)
+
+		assert.Equal(t, "extra", entries[1].ContainerType)
+		assert.Equal(t, "extra-worker", entries[1].Container)
+		assert.Equal(t, "quay.io/corp/worker", entries[1].ImageURL)
+		assert.Equal(t, "v1.0.0", entries[1].CurrentVersion)
+		assert.Equal(t, "v1.1.0", entries[1].LatestVersion)
+		assert.False(t, entries[1].IsLatest)
+	}
+
+	// Options annotations should apply to extra images by their name
+	pod.Annotations[api.EnableAnnotationKey+"/extra-tools"] = "false"
+	assert.NoError(t, controller.sync(context.Background(), pod))
+	entries = controller.metrics.Entries()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "extra-worker", entries[0].Container)
+	}
+
+	// Images no longer listed should be removed
+	pod.Annotations[api.ExtraImagesAnnotationKey] = "registry.corp/tools:v1.1.0"
+	assert.NoError(t, controller.sync(context.Background(), pod))
+	assert.Empty(t, controller.metrics.Entries())
+	assert.Equal(t, []string{"extra-tools"}, controller.extraImageNames(pod))
+
+	// Deleting the pod should forget its extra images
+	controller.deleteObject(pod)
+	assert.Empty(t, controller.extraImages)
+}
+
+func TestPodWorkload(t *testing.T) {
+	controller := true
+	owned := func(kind, name string, labels map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name + "-abcde",
+				Labels: labels,
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: kind, Name: name, Controller: &controller},
+				},
+			},
+		}
+	}
+
+	tests := map[string]struct {
+		pod         *corev1.Pod
+		expWorkload string
+	}{
+		"pods without a controller should be of no workload": {
+			pod: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod"}},
+		},
+		"pods of a Deployment should be of every revision": {
+			pod:         owned("ReplicaSet", "app-7d9f8b6c5", map[string]string{"pod-template-hash": "7d9f8b6c5"}),
+			expWorkload: "ReplicaSet/app",
+		},
+		"pods of a ReplicaSet without a template hash should be of the ReplicaSet": {
+			pod:         owned("ReplicaSet", "app", nil),
+			expWorkload: "ReplicaSet/app",
+		},
+		"pods of a StatefulSet should be of the StatefulSet": {
+			pod:         owned("StatefulSet", "db", map[string]string{"controller-revision-hash": "db-5f4d3c2b1"}),
+			expWorkload: "StatefulSet/db",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expWorkload, podWorkload(test.pod))
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+// Format is the format results are exported in.
+type Format string
+
+const (
+	// FormatJSON exports the results as a JSON array of objects.
+	FormatJSON Format = "json"
+
+	// FormatCSV exports the results as CSV, with a header row.
+	FormatCSV Format = "csv"
+)
+
+// Options are used to configure the exporter.
+type Options struct {
+	// Destination is where results are written to, either a local file path,
+	// or an object of an S3 compatible bucket of the form s3://<bucket>/<key>.
+	Destination string
+
+	// Format is the format results are exported in.
+	Format Format
+
+	// S3Endpoint is the endpoint of the S3 compatible object store, such as
+	// https://minio.corp. Defaults to AWS S3 of the region.
+	S3Endpoint string
+
+	// S3Region is the region of the bucket, which AWS credentials are signed
+	// for. Defaults to the region of the AWS config.
+	S3Region string
+
+	// ShardIndex and ShardCount are the shard of the replica, whose results
+	// are only of the namespaces of its shard. If more than 1 shard, each
+	// replica exports to its own destination, with the suffix
+	// .shard-<index>-of-<count> before any extension, so that the exports of
+	// replicas do not overwrite each other.
+	ShardIndex int
+	ShardCount int
+}
+
+// writer writes an export to its destination, replacing any previous export.
+type writer interface {
+	Write(ctx context.Context, data []byte) error
+}
+
+// Exporter periodically writes a snapshot of all results, so that there is a
+// historical record of results alongside the live metrics.
+type Exporter struct {
+	log *logrus.Entry
+
+	metrics *metrics.Metrics
+	format  Format
+	writer  writer
+}
+
+// record is a result, as it is exported.
+type record struct {
+	Cluster       string `json:"cluster"`
+	Namespace     string `json:"namespace"`
+	Pod           string `json:"pod"`
+	Container     string `json:"container"`
+	ContainerType string `json:"container_type"`
+
+	Image          string `json:"image"`
+	IsLatest       bool   `json:"is_latest"`
+	CurrentVersion string `json:"current_version"`
+	LatestVersion  string `json:"latest_version"`
+
+	OS             string `json:"os,omitempty"`
+	Arch           string `json:"arch,omitempty"`
+	PlatformSource string `json:"platform_source,omitempty"`
+	ArtifactType   string `json:"artifact_type,omitempty"`
+	PinnedByDigest bool   `json:"pinned_by_digest"`
+	Severity       string `json:"severity,omitempty"`
+
+	AbsoluteLatestVersion string `json:"absolute_latest_version,omitempty"`
+	IsAbsoluteLatest      bool   `json:"is_absolute_latest,omitempty"`
+	IsAheadOfRegistry     bool   `json:"is_ahead_of_registry"`
+	VersionsBehind        *int   `json:"versions_behind,omitempty"`
+
+	BaseImage *baseImageRecord `json:"base_image,omitempty"`
+
+	LastChecked time.Time `json:"last_checked"`
+}
+
+// baseImageRecord is the result of the base image of a container, as it is
+// exported.
+type baseImageRecord struct {
+	Image          string `json:"image"`
+	IsLatest       bool   `json:"is_latest"`
+	CurrentVersion string `json:"current_version"`
+	LatestVersion  string `json:"latest_version"`
+}
+
+// csvHeader is the header row of CSV exports, in the order of the columns of
+// csvRow.
+var csvHeader = []string{
+	"cluster", "namespace", "pod", "container", "container_type",
+	"image", "is_latest", "current_version", "latest_version",
+	"os", "arch", "platform_source", "artifact_type", "pinned_by_digest", "severity",
+	"absolute_latest_version", "is_absolute_latest", "is_ahead_of_registry", "versions_behind",
+	"base_image", "base_image_is_latest", "base_image_current_version", "base_image_latest_version",
+	"last_checked",
+}
+
+// New returns a new exporter of the results of the given metrics, according
+// to the options.
+func New(ctx context.Context, log *logrus.Entry, opts Options, metrics *metrics.Metrics) (*Exporter, error) {
+	switch opts.Format {
+	case FormatJSON, FormatCSV:
+	default:
+		return nil, fmt.Errorf("unknown export format %q, must be one of %s, %s",
+			opts.Format, FormatJSON, FormatCSV)
+	}
+
+	if opts.ShardCount > 1 {
+		opts.Destination = shardDestination(opts.Destination, opts.ShardIndex, opts.ShardCount)
+	}
+
+	var (
+		w   writer
+		err error
+	)
+	if strings.HasPrefix(opts.Destination, "s3://") {
+		w, err = newS3Writer(ctx, opts)
+	} else {
+		w, err = newFileWriter(opts.Destination)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Exporter{
+		log:     log.WithField("module", "export"),
+		metrics: metrics,
+		format:  opts.Format,
+		writer:  w,
+	}, nil
+}
+
+// Run is a blocking func that will export the results every period, until the
+// context is cancelled. Failed exports are logged, and retried at the next
+// period.
+func (e *Exporter) Run(ctx context.Context, period time.Duration) {
+	e.log.Infof("exporting results every %s", period)
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			e.log.Info("shutting down results exporter")
+			return
+		case <-ticker.C:
+		}
+
+		if err := e.export(ctx); err != nil {
+			e.log.Errorf("failed to export results: %s", err)
+		}
+	}
+}
+
+// export will write a snapshot of the current results.
+func (e *Exporter) export(ctx context.Context) error {
+	entries := e.metrics.Entries()
+
+	data, err := e.encode(entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode results: %s", err)
+	}
+
+	if err := e.writer.Write(ctx, data); err != nil {
+		return err
+	}
+
+	e.log.Debugf("exported %d results", len(entries))
+
+	return nil
+}
+
+// encode will encode the given results in the export format.
+func (e *Exporter) encode(entries []metrics.Entry) ([]byte, error) {
+	records := make([]record, len(entries))
+	for i, entry := range entries {
+		records[i] = newRecord(entry)
+	}
+
+	if e.format == FormatJSON {
+		return json.MarshalIndent(records, "", "  ")
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if err := w.Write(csvRow(r)); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+// shardDestination returns the destination of the export of the given shard,
+// with the suffix of the shard before any extension of the destination.
+func shardDestination(destination string, index, count int) string {
+	ext := path.Ext(destination)
+	return fmt.Sprintf("%s.shard-%d-of-%d%s", strings.TrimSuffix(destination, ext), index, count, ext)
+}
+
+func newRecord(entry metrics.Entry) record {
+	r := record{
+		Cluster:               entry.Cluster,
+		Namespace:             entry.Namespace,
+		Pod:                   entry.Pod,
+		Container:             entry.Container,
+		ContainerType:         entry.ContainerType,
+		Image:                 entry.ImageURL,
+		IsLatest:              entry.IsLatest,
+		CurrentVersion:        entry.CurrentVersion,
+		LatestVersion:         entry.LatestVersion,
+		OS:                    entry.OS,
+		Arch:                  entry.Arch,
+		PlatformSource:        entry.PlatformSource,
+		ArtifactType:          entry.ArtifactType,
+		PinnedByDigest:        entry.PinnedByDigest,
+		Severity:              entry.Severity,
+		AbsoluteLatestVersion: entry.AbsoluteLatestVersion,
+		IsAbsoluteLatest:      entry.IsAbsoluteLatest,
+		IsAheadOfRegistry:     entry.IsAheadOfRegistry,
+		VersionsBehind:        entry.VersionsBehind,
+		LastChecked:           entry.LastChecked.UTC(),
+	}
+
+	if entry.BaseImage != nil {
+		r.BaseImage = &baseImageRecord{
+			Image:          entry.BaseImage.ImageURL,
+			IsLatest:       entry.BaseImage.IsLatest,
+			CurrentVersion: entry.BaseImage.CurrentVersion,
+			LatestVersion:  entry.BaseImage.LatestVersion,
+		}
+	}
+
+	return r
+}
+
+// csvRow returns the columns of the given record, where unset optional
+// values are empty.
+func csvRow(r record) []string {
+	var versionsBehind string
+	if r.VersionsBehind != nil {
+		versionsBehind = strconv.Itoa(*r.VersionsBehind)
+	}
+
+	var baseImage baseImageRecord
+	var baseImageIsLatest string
+	if r.BaseImage != nil {
+		baseImage = *r.BaseImage
+		baseImageIsLatest = strconv.FormatBool(baseImage.IsLatest)
+	}
+
+	return []string{
+		r.Cluster, r.Namespace, r.Pod, r.Container, r.ContainerType,
+		r.Image, strconv.FormatBool(r.IsLatest), r.CurrentVersion, r.LatestVersion,
+		r.OS, r.Arch, r.PlatformSource, r.ArtifactType, strconv.FormatBool(r.PinnedByDigest), r.Severity,
+		r.AbsoluteLatestVersion, strconv.FormatBool(r.IsAbsoluteLatest), strconv.FormatBool(r.IsAheadOfRegistry), versionsBehind,
+		baseImage.Image, baseImageIsLatest, baseImage.CurrentVersion, baseImage.LatestVersion,
+		r.LastChecked.Format(time.RFC3339),
+	}
+}
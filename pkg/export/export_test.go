@@ -0,0 +1,242 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func testMetrics() *metrics.Metrics {
+	log := logrus.NewEntry(logrus.New())
+	m := metrics.New(log, prometheus.NewRegistry(), metrics.Options{})
+
+	versionsBehind := 2
+	m.AddImage(metrics.Entry{
+		Namespace:      "default",
+		Pod:            "nginx-abc",
+		Container:      "nginx",
+		ContainerType:  "container",
+		ImageURL:       "docker.io/library/nginx",
+		CurrentVersion: "1.25.0",
+		LatestVersion:  "1.27.0",
+		VersionsBehind: &versionsBehind,
+		LastChecked:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	m.AddImage(metrics.Entry{
+		Namespace:      "default",
+		Pod:            "nginx-abc",
+		Container:      "init",
+		ContainerType:  "init",
+		ImageURL:       "docker.io/library/busybox",
+		IsLatest:       true,
+		CurrentVersion: "1.36.1",
+		LatestVersion:  "1.36.1",
+		BaseImage: &metrics.BaseImageEntry{
+			ImageURL:       "docker.io/library/alpine",
+			CurrentVersion: "3.19",
+			LatestVersion:  "3.20",
+		},
+		LastChecked: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+
+	return m
+}
+
+func TestExportFile(t *testing.T) {
+	ctx := context.Background()
+	log := logrus.NewEntry(logrus.New())
+	dir := t.TempDir()
+
+	t.Run("json exports should contain every result", func(t *testing.T) {
+		path := filepath.Join(dir, "results.json")
+		exporter, err := New(ctx, log, Options{Destination: path, Format: FormatJSON}, testMetrics())
+		require.NoError(t, err)
+		require.NoError(t, exporter.export(ctx))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		var records []map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &records))
+		require.Len(t, records, 2)
+		assert.Equal(t, "init", records[0]["container"])
+		assert.Equal(t, "docker.io/library/alpine", records[0]["base_image"].(map[string]interface{})["image"])
+		assert.Equal(t, "nginx", records[1]["container"])
+		assert.Equal(t, "1.27.0", records[1]["latest_version"])
+		assert.Equal(t, float64(2), records[1]["versions_behind"])
+		assert.Equal(t, "2024-01-02T03:04:05Z", records[1]["last_checked"])
+	})
+
+	t.Run("csv exports should have a header and a row per result", func(t *testing.T) {
+		path := filepath.Join(dir, "results.csv")
+		exporter, err := New(ctx, log, Options{Destination: "file://" + path, Format: FormatCSV}, testMetrics())
+		require.NoError(t, err)
+		require.NoError(t, exporter.export(ctx))
+
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		rows, err := csv.NewReader(f).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		assert.Equal(t, csvHeader, rows[0])
+		assert.Equal(t, []string{
+			"", "default", "nginx-abc", "init", "init",
+			"docker.io/library/busybox", "true", "1.36.1", "1.36.1",
+			"", "", "", "", "false", "",
+			"", "false", "false", "",
+			"docker.io/library/alpine", "false", "3.19", "3.20",
+			"2024-01-02T03:04:05Z",
+		}, rows[1])
+	})
+
+	// Only the exports should remain, without any temporary files
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	t.Run("failed writes should error", func(t *testing.T) {
+		exporter, err := New(ctx, log, Options{Destination: filepath.Join(dir, "missing", "results.json"), Format: FormatJSON}, testMetrics())
+		require.NoError(t, err)
+		assert.ErrorContains(t, exporter.export(ctx), "failed to create temporary export file")
+	})
+}
+
+func TestShardDestination(t *testing.T) {
+	tests := map[string]struct {
+		destination string
+		expDest     string
+	}{
+		"file paths should have the shard before the extension": {
+			destination: "/var/lib/results.json",
+			expDest:     "/var/lib/results.shard-1-of-3.json",
+		},
+		"file paths without an extension should have the shard appended": {
+			destination: "/var/lib.d/results",
+			expDest:     "/var/lib.d/results.shard-1-of-3",
+		},
+		"s3 objects should have the shard before the extension": {
+			destination: "s3://bucket/reports/results.csv",
+			expDest:     "s3://bucket/reports/results.shard-1-of-3.csv",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expDest, shardDestination(test.destination, 1, 3))
+		})
+	}
+
+	t.Run("sharded replicas should export to their own file", func(t *testing.T) {
+		ctx := context.Background()
+		dir := t.TempDir()
+
+		exporter, err := New(ctx, logrus.NewEntry(logrus.New()), Options{
+			Destination: filepath.Join(dir, "results.json"),
+			Format:      FormatJSON,
+			ShardIndex:  2,
+			ShardCount:  3,
+		}, testMetrics())
+		require.NoError(t, err)
+		require.NoError(t, exporter.export(ctx))
+
+		_, err = os.Stat(filepath.Join(dir, "results.shard-2-of-3.json"))
+		assert.NoError(t, err)
+	})
+}
+
+func TestExportS3(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "access-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret-key")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+
+	var (
+		path, auth string
+		body       []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.URL.Path == "/denied/results.json" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("AccessDenied"))
+			return
+		}
+
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	log := logrus.NewEntry(logrus.New())
+
+	exporter, err := New(ctx, log, Options{
+		Destination: "s3://reports/version-checker/results.json",
+		Format:      FormatJSON,
+		S3Endpoint:  server.URL,
+		S3Region:    "eu-west-1",
+	}, testMetrics())
+	require.NoError(t, err)
+	require.NoError(t, exporter.export(ctx))
+
+	assert.Equal(t, "/reports/version-checker/results.json", path)
+	assert.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access-key/"), auth)
+	assert.Contains(t, auth, "/eu-west-1/s3/aws4_request")
+	assert.Contains(t, string(body), `"pod": "nginx-abc"`)
+
+	exporter, err = New(ctx, log, Options{
+		Destination: "s3://denied/results.json",
+		Format:      FormatJSON,
+		S3Endpoint:  server.URL,
+		S3Region:    "eu-west-1",
+	}, testMetrics())
+	require.NoError(t, err)
+	assert.ErrorContains(t, exporter.export(ctx), "unexpected status code 403: AccessDenied")
+}
+
+func TestNew(t *testing.T) {
+	tests := map[string]struct {
+		opts   Options
+		expErr string
+	}{
+		"unknown formats should error": {
+			opts:   Options{Destination: "results.xml", Format: "xml"},
+			expErr: `unknown export format "xml", must be one of json, csv`,
+		},
+		"empty file paths should error": {
+			opts:   Options{Destination: "file://", Format: FormatJSON},
+			expErr: "export destination must be a file path or s3://<bucket>/<key>",
+		},
+		"s3 destinations without a key should error": {
+			opts:   Options{Destination: "s3://reports", Format: FormatJSON},
+			expErr: `export destination "s3://reports" must be of the form s3://<bucket>/<key>`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(context.Background(), logrus.NewEntry(logrus.New()), test.opts, nil)
+			assert.EqualError(t, err, test.expErr)
+		})
+	}
+}
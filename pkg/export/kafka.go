@@ -0,0 +1,341 @@
+package export
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+// KafkaEvents is which results are produced as events to Kafka.
+type KafkaEvents string
+
+const (
+	// KafkaEventsChanged produces an event for every change to the result of
+	// a container, and its removal.
+	KafkaEventsChanged KafkaEvents = "changed"
+
+	// KafkaEventsChecked produces an event for every check of a container,
+	// whether or not its result changed, and its removal.
+	KafkaEventsChecked KafkaEvents = "checked"
+)
+
+// SASL mechanisms which may be used to authenticate with the Kafka brokers.
+const (
+	SASLMechanismPlain       = "plain"
+	SASLMechanismSCRAMSHA256 = "scram-sha-256"
+	SASLMechanismSCRAMSHA512 = "scram-sha-512"
+)
+
+const (
+	// kafkaSubscriberBuffer is the number of result events the producer may
+	// fall behind by before resubscribing. Events are only moved to the
+	// buffer of the producer, so it is never expected to fall behind.
+	kafkaSubscriberBuffer = 100
+
+	// kafkaBatchSize is the maximum number of buffered events produced in a
+	// single request.
+	kafkaBatchSize = 100
+
+	// Reasons of dropped events.
+	droppedBufferFull    = "buffer_full"
+	droppedProduceFailed = "produce_failed"
+	droppedFellBehind    = "fell_behind"
+)
+
+// KafkaOptions are used to configure the Kafka producer.
+type KafkaOptions struct {
+	// Brokers are the addresses of the Kafka brokers to bootstrap from, of the
+	// form <host>:<port>.
+	Brokers []string
+
+	// Topic is the topic events are produced to.
+	Topic string
+
+	// Events is which results are produced as events.
+	Events KafkaEvents
+
+	// Buffer is the number of events buffered while they are produced,
+	// beyond which events are dropped rather than blocking checks.
+	Buffer int
+
+	// SASLMechanism, if set, is the SASL mechanism used to authenticate with
+	// the brokers with Username and Password, one of plain, scram-sha-256 or
+	// scram-sha-512.
+	SASLMechanism string
+	Username      string
+	Password      string
+
+	// TLS connects to the brokers with TLS, verifying them with the CA
+	// certificates of CAPath if set, or the system roots otherwise.
+	TLS    bool
+	CAPath string
+}
+
+// messageWriter writes messages to the topic, such as a kafka.Writer.
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaProducer produces the results of checks as events to a Kafka topic, so
+// that results can be ingested as a stream rather than scraped. Events are
+// buffered and produced asynchronously, so that checks are never blocked on
+// Kafka, and are dropped when the buffer is full.
+type KafkaProducer struct {
+	log *logrus.Entry
+
+	metrics *metrics.Metrics
+	events  KafkaEvents
+	writer  messageWriter
+
+	// queue is the buffer of events waiting to be produced.
+	queue chan kafka.Message
+}
+
+// kafkaEvent is a result event, as it is produced. Snapshot events are of
+// the current results, produced on subscribing to them, such as after falling
+// behind, rather than of a check as it happened.
+type kafkaEvent struct {
+	Type      metrics.EventType `json:"type"`
+	Timestamp time.Time         `json:"timestamp"`
+	Cluster   string            `json:"cluster"`
+	Snapshot  bool              `json:"snapshot"`
+	Result    record            `json:"result"`
+}
+
+// NewKafkaProducer returns a new producer of the results of the given
+// metrics, according to the options.
+func NewKafkaProducer(log *logrus.Entry, opts KafkaOptions, metrics *metrics.Metrics) (*KafkaProducer, error) {
+	if len(opts.Brokers) == 0 {
+		return nil, errors.New("at least one Kafka broker must be set")
+	}
+	if len(opts.Topic) == 0 {
+		return nil, errors.New("the Kafka topic must be set")
+	}
+	switch opts.Events {
+	case KafkaEventsChanged, KafkaEventsChecked:
+	default:
+		return nil, fmt.Errorf("unknown Kafka events %q, must be one of %s, %s",
+			opts.Events, KafkaEventsChanged, KafkaEventsChecked)
+	}
+	if opts.Buffer <= 0 {
+		return nil, fmt.Errorf("the Kafka event buffer must be positive, got %d", opts.Buffer)
+	}
+
+	transport, err := newKafkaTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return newKafkaProducer(log, opts, metrics, &kafka.Writer{
+		Addr:  kafka.TCP(opts.Brokers...),
+		Topic: opts.Topic,
+		// Events are keyed by container, so that the events of a container
+		// are produced to the same partition, in order.
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchSize:    kafkaBatchSize,
+		BatchTimeout: 10 * time.Millisecond,
+		Transport:    transport,
+	}), nil
+}
+
+func newKafkaProducer(log *logrus.Entry, opts KafkaOptions, metrics *metrics.Metrics, writer messageWriter) *KafkaProducer {
+	return &KafkaProducer{
+		log:     log.WithField("module", "kafka"),
+		metrics: metrics,
+		events:  opts.Events,
+		writer:  writer,
+		queue:   make(chan kafka.Message, opts.Buffer),
+	}
+}
+
+// newKafkaTransport returns the transport to the brokers, authenticating and
+// connecting with TLS according to the options.
+func newKafkaTransport(opts KafkaOptions) (*kafka.Transport, error) {
+	transport := &kafka.Transport{}
+
+	var err error
+	switch opts.SASLMechanism {
+	case "":
+	case SASLMechanismPlain:
+		transport.SASL = plain.Mechanism{Username: opts.Username, Password: opts.Password}
+	case SASLMechanismSCRAMSHA256:
+		transport.SASL, err = scram.Mechanism(scram.SHA256, opts.Username, opts.Password)
+	case SASLMechanismSCRAMSHA512:
+		transport.SASL, err = scram.Mechanism(scram.SHA512, opts.Username, opts.Password)
+	default:
+		return nil, fmt.Errorf("unknown Kafka SASL mechanism %q, must be one of %s, %s, %s",
+			opts.SASLMechanism, SASLMechanismPlain, SASLMechanismSCRAMSHA256, SASLMechanismSCRAMSHA512)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup Kafka SASL mechanism %q: %s", opts.SASLMechanism, err)
+	}
+
+	if opts.TLS {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+		if len(opts.CAPath) > 0 {
+			ca, err := os.ReadFile(opts.CAPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read Kafka CA %q: %s", opts.CAPath, err)
+			}
+
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("failed to parse Kafka CA %q: no certificates found", opts.CAPath)
+			}
+			transport.TLS.RootCAs = pool
+		}
+	}
+
+	return transport, nil
+}
+
+// Run is a blocking func that will produce result events until the context
+// is cancelled. Events still buffered once the context is cancelled are not
+// produced.
+func (p *KafkaProducer) Run(ctx context.Context) {
+	p.log.Infof("producing %s result events to Kafka", p.events)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.produce(ctx)
+	}()
+
+	subscribe := p.metrics.Subscribe
+	if p.events == KafkaEventsChecked {
+		subscribe = p.metrics.SubscribeChecks
+	}
+
+	for {
+		events, unsubscribe := subscribe(kafkaSubscriberBuffer)
+		// Resubscribing produces the current results again, as snapshot
+		// events, in place of the events missed while behind.
+		if p.enqueue(ctx, events) {
+			p.log.Warn("fell too far behind results, resubscribing")
+		}
+		unsubscribe()
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	<-done
+	if err := p.writer.Close(); err != nil {
+		p.log.Errorf("failed to close Kafka producer: %s", err)
+	}
+	p.log.Info("shutting down Kafka producer")
+}
+
+// enqueue will buffer the given result events to be produced, dropping them
+// if the buffer is full, until the context is cancelled. Returns true if the
+// events channel was closed from falling behind, counting the event it was
+// closed rather than sent at as dropped.
+func (p *KafkaProducer) enqueue(ctx context.Context, events <-chan metrics.Event) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+
+		case event, ok := <-events:
+			if !ok {
+				p.metrics.IncKafkaEventsDropped(droppedFellBehind)
+				return true
+			}
+
+			msg, err := newKafkaMessage(event, time.Now())
+			if err != nil {
+				p.log.Errorf("failed to encode result event: %s", err)
+				continue
+			}
+
+			select {
+			case p.queue <- msg:
+			default:
+				p.log.Debugf("Kafka event buffer is full, dropping event of %s", msg.Key)
+				p.metrics.IncKafkaEventsDropped(droppedBufferFull)
+			}
+		}
+	}
+}
+
+// produce will produce the buffered events in batches, until the context is
+// cancelled. Events which fail to be produced, after the retries of the
+// writer, are dropped.
+func (p *KafkaProducer) produce(ctx context.Context) {
+	for {
+		var batch []kafka.Message
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-p.queue:
+			batch = append(batch, msg)
+		}
+
+		// Events buffered while the previous batch was produced are produced
+		// together.
+	fill:
+		for len(batch) < kafkaBatchSize {
+			select {
+			case msg := <-p.queue:
+				batch = append(batch, msg)
+			default:
+				break fill
+			}
+		}
+
+		if err := p.writer.WriteMessages(ctx, batch...); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			p.log.Errorf("failed to produce %d result events to Kafka: %s", len(batch), err)
+			for range batch {
+				p.metrics.IncKafkaEventsDropped(droppedProduceFailed)
+			}
+			continue
+		}
+
+		p.log.Debugf("produced %d result events to Kafka", len(batch))
+	}
+}
+
+// newKafkaMessage returns the message of the given result event, keyed by
+// its container.
+func newKafkaMessage(event metrics.Event, timestamp time.Time) (kafka.Message, error) {
+	entry := event.Entry
+
+	value, err := json.Marshal(kafkaEvent{
+		Type:      event.Type,
+		Timestamp: timestamp.UTC(),
+		Cluster:   entry.Cluster,
+		Snapshot:  event.Snapshot,
+		Result:    newRecord(entry),
+	})
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	key := strings.Join([]string{entry.Cluster, entry.Namespace, entry.Pod, entry.Container, entry.ContainerType}, "/")
+
+	return kafka.Message{
+		Key:   []byte(key),
+		Value: value,
+		Time:  timestamp,
+	}, nil
+}
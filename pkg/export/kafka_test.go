@@ -0,0 +1,315 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+// fakeWriter records the messages written to it, failing writes with err if
+// set.
+type fakeWriter struct {
+	mu       sync.Mutex
+	messages []kafka.Message
+	err      error
+}
+
+func (f *fakeWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	f.messages = append(f.messages, msgs...)
+	return nil
+}
+
+func (f *fakeWriter) Close() error {
+	return nil
+}
+
+func (f *fakeWriter) written() []kafka.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]kafka.Message(nil), f.messages...)
+}
+
+func (f *fakeWriter) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = nil
+}
+
+// lastType returns the type of the last event written, if any.
+func (f *fakeWriter) lastType(t *testing.T) metrics.EventType {
+	messages := f.written()
+	if len(messages) == 0 {
+		return ""
+	}
+
+	var event kafkaEvent
+	require.NoError(t, json.Unmarshal(messages[len(messages)-1].Value, &event))
+	return event.Type
+}
+
+func kafkaTestEntry(currentVersion string) metrics.Entry {
+	return metrics.Entry{
+		Cluster:        "cluster-1",
+		Namespace:      "default",
+		Pod:            "nginx-abc",
+		Container:      "nginx",
+		ContainerType:  "container",
+		ImageURL:       "docker.io/library/nginx",
+		CurrentVersion: currentVersion,
+		LatestVersion:  "1.27.0",
+		LastChecked:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestNewKafkaProducer(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	valid := KafkaOptions{
+		Brokers: []string{"kafka:9092"},
+		Topic:   "version-checker",
+		Events:  KafkaEventsChanged,
+		Buffer:  10,
+	}
+
+	tests := map[string]struct {
+		modify func(*KafkaOptions)
+		expErr string
+	}{
+		"valid options should not error": {
+			modify: func(*KafkaOptions) {},
+		},
+		"valid SASL mechanism should not error": {
+			modify: func(o *KafkaOptions) {
+				o.SASLMechanism, o.Username, o.Password = SASLMechanismSCRAMSHA512, "user", "pass"
+			},
+		},
+		"no brokers should error": {
+			modify: func(o *KafkaOptions) { o.Brokers = nil },
+			expErr: "at least one Kafka broker must be set",
+		},
+		"no topic should error": {
+			modify: func(o *KafkaOptions) { o.Topic = "" },
+			expErr: "the Kafka topic must be set",
+		},
+		"unknown events should error": {
+			modify: func(o *KafkaOptions) { o.Events = "all" },
+			expErr: `unknown Kafka events "all", must be one of changed, checked`,
+		},
+		"no buffer should error": {
+			modify: func(o *KafkaOptions) { o.Buffer = 0 },
+			expErr: "the Kafka event buffer must be positive, got 0",
+		},
+		"unknown SASL mechanism should error": {
+			modify: func(o *KafkaOptions) { o.SASLMechanism = "gssapi" },
+			expErr: `unknown Kafka SASL mechanism "gssapi", must be one of plain, scram-sha-256, scram-sha-512`,
+		},
+		"missing CA should error": {
+			modify: func(o *KafkaOptions) { o.TLS, o.CAPath = true, "/does/not/exist.pem" },
+			expErr: `failed to read Kafka CA "/does/not/exist.pem"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := valid
+			test.modify(&opts)
+
+			producer, err := NewKafkaProducer(log, opts, metrics.New(log, prometheus.NewRegistry(), metrics.Options{}))
+			if len(test.expErr) > 0 {
+				assert.ErrorContains(t, err, test.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotNil(t, producer)
+		})
+	}
+}
+
+func TestKafkaProducerRun(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+
+	tests := map[string]struct {
+		events   KafkaEvents
+		expTypes []metrics.EventType
+	}{
+		"changed should produce changes and removals": {
+			events:   KafkaEventsChanged,
+			expTypes: []metrics.EventType{metrics.EventTypeChecked, metrics.EventTypeChecked, metrics.EventTypeRemoved},
+		},
+		"checked should produce every check and removals": {
+			events: KafkaEventsChecked,
+			expTypes: []metrics.EventType{
+				metrics.EventTypeChecked, metrics.EventTypeChecked, metrics.EventTypeChecked, metrics.EventTypeRemoved,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			m := metrics.New(log, prometheus.NewRegistry(), metrics.Options{})
+			writer := new(fakeWriter)
+			producer := newKafkaProducer(log, KafkaOptions{Events: test.events, Buffer: 10}, m, writer)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				producer.Run(ctx)
+			}()
+
+			// Results are only published to the producer once it has
+			// subscribed.
+			require.Eventually(t, func() bool {
+				m.AddImage(kafkaTestEntry("1.25.0"))
+				return len(writer.written()) > 0
+			}, time.Second, time.Millisecond)
+			m.RemoveImage("cluster-1", "default", "nginx-abc", "nginx", "container")
+			require.Eventually(t, func() bool {
+				return writer.lastType(t) == metrics.EventTypeRemoved
+			}, time.Second, time.Millisecond)
+			writer.reset()
+
+			m.AddImage(kafkaTestEntry("1.25.0"))
+			m.AddImage(kafkaTestEntry("1.25.0"))
+			m.AddImage(kafkaTestEntry("1.26.0"))
+			m.RemoveImage("cluster-1", "default", "nginx-abc", "nginx", "container")
+
+			require.Eventually(t, func() bool {
+				return len(writer.written()) >= len(test.expTypes)
+			}, time.Second, time.Millisecond)
+			cancel()
+			<-done
+
+			var types []metrics.EventType
+			for _, msg := range writer.written() {
+				assert.Equal(t, "cluster-1/default/nginx-abc/nginx/container", string(msg.Key))
+
+				var event kafkaEvent
+				require.NoError(t, json.Unmarshal(msg.Value, &event))
+				assert.Equal(t, "cluster-1", event.Cluster)
+				assert.False(t, event.Timestamp.IsZero())
+				types = append(types, event.Type)
+			}
+			assert.Equal(t, test.expTypes, types)
+		})
+	}
+}
+
+func TestKafkaProducerSnapshot(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	m := metrics.New(log, prometheus.NewRegistry(), metrics.Options{})
+	m.AddImage(kafkaTestEntry("1.25.0"))
+
+	writer := new(fakeWriter)
+	producer := newKafkaProducer(log, KafkaOptions{Events: KafkaEventsChanged, Buffer: 10}, m, writer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		producer.Run(ctx)
+	}()
+
+	// The results before subscribing should be produced as snapshots, and
+	// later changes not.
+	require.Eventually(t, func() bool {
+		return len(writer.written()) > 0
+	}, time.Second, time.Millisecond)
+	m.AddImage(kafkaTestEntry("1.26.0"))
+	require.Eventually(t, func() bool {
+		return len(writer.written()) > 1
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	var snapshots []bool
+	for _, msg := range writer.written() {
+		var event kafkaEvent
+		require.NoError(t, json.Unmarshal(msg.Value, &event))
+		snapshots = append(snapshots, event.Snapshot)
+	}
+	assert.Equal(t, []bool{true, false}, snapshots)
+}
+
+func TestKafkaProducerBufferFull(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	reg := prometheus.NewRegistry()
+	m := metrics.New(log, reg, metrics.Options{})
+
+	// Without producing, the buffer fills and further events are dropped
+	// rather than blocking.
+	producer := newKafkaProducer(log, KafkaOptions{Events: KafkaEventsChanged, Buffer: 2}, m, new(fakeWriter))
+
+	events := make(chan metrics.Event, 5)
+	for _, version := range []string{"1.21.0", "1.22.0", "1.23.0", "1.24.0", "1.25.0"} {
+		events <- metrics.Event{Type: metrics.EventTypeChecked, Entry: kafkaTestEntry(version)}
+	}
+	close(events)
+
+	// Closing the events channel, as from falling behind, should count the
+	// event it was closed at as dropped.
+	assert.True(t, producer.enqueue(context.Background(), events))
+	assert.Len(t, producer.queue, 2)
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP version_checker_kafka_events_dropped_total Number of result events which were dropped rather than produced to Kafka, as the buffer of events was full, producing failed, or the producer fell behind results
+# TYPE version_checker_kafka_events_dropped_total counter
+version_checker_kafka_events_dropped_total{reason="buffer_full"} 3
+version_checker_kafka_events_dropped_total{reason="fell_behind"} 1
+`), "version_checker_kafka_events_dropped_total"))
+
+	// The oldest events should be kept
+	var event kafkaEvent
+	require.NoError(t, json.Unmarshal((<-producer.queue).Value, &event))
+	assert.Equal(t, "1.21.0", event.Result.CurrentVersion)
+}
+
+func TestKafkaProducerProduceFailed(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	reg := prometheus.NewRegistry()
+	m := metrics.New(log, reg, metrics.Options{})
+
+	writer := &fakeWriter{err: errors.New("broker unavailable")}
+	producer := newKafkaProducer(log, KafkaOptions{Events: KafkaEventsChanged, Buffer: 10}, m, writer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		producer.produce(ctx)
+	}()
+
+	msg, err := newKafkaMessage(metrics.Event{Type: metrics.EventTypeChecked, Entry: kafkaTestEntry("1.25.0")}, time.Now())
+	require.NoError(t, err)
+	producer.queue <- msg
+	producer.queue <- msg
+
+	// Failed events should be dropped, and producing continue
+	require.Eventually(t, func() bool {
+		return testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP version_checker_kafka_events_dropped_total Number of result events which were dropped rather than produced to Kafka, as the buffer of events was full, producing failed, or the producer fell behind results
+# TYPE version_checker_kafka_events_dropped_total counter
+version_checker_kafka_events_dropped_total{reason="produce_failed"} 2
+`), "version_checker_kafka_events_dropped_total") == nil
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
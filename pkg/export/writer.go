@@ -0,0 +1,147 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// fileWriter writes exports to a local file. Exports are written to a
+// temporary file which is renamed over the destination, so that readers
+// never see a partial export.
+type fileWriter struct {
+	path string
+}
+
+func newFileWriter(destination string) (*fileWriter, error) {
+	path := strings.TrimPrefix(destination, "file://")
+	if len(path) == 0 {
+		return nil, errors.New("export destination must be a file path or s3://<bucket>/<key>")
+	}
+
+	return &fileWriter{path: path}, nil
+}
+
+func (f *fileWriter) Write(_ context.Context, data []byte) error {
+	// The temporary file is created in the same directory, so that it can be
+	// renamed atomically.
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), "."+filepath.Base(f.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary export file: %s", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write export file %q: %s", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write export file %q: %s", tmp.Name(), err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to replace export file %q: %s", f.path, err)
+	}
+
+	return nil
+}
+
+// s3Writer writes exports to an object of an S3 compatible bucket, with a
+// single PUT request, which replaces the object atomically.
+type s3Writer struct {
+	client *http.Client
+
+	url    string
+	region string
+
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+}
+
+func newS3Writer(ctx context.Context, opts Options) (*s3Writer, error) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(opts.Destination, "s3://"), "/")
+	if len(bucket) == 0 || len(key) == 0 {
+		return nil, fmt.Errorf("export destination %q must be of the form s3://<bucket>/<key>", opts.Destination)
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %s", err)
+	}
+
+	region := opts.S3Region
+	if len(region) == 0 {
+		region = cfg.Region
+	}
+	if len(region) == 0 {
+		return nil, errors.New("the region of the export bucket must be set, or configured in the AWS config")
+	}
+
+	endpoint := opts.S3Endpoint
+	if len(endpoint) == 0 {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse export S3 endpoint %q: %s", endpoint, err)
+	}
+
+	// Path style URLs are used, since they are supported by all S3 compatible
+	// object stores.
+	return &s3Writer{
+		client:      &http.Client{Timeout: time.Minute},
+		url:         strings.TrimSuffix(endpoint, "/") + "/" + bucket + "/" + key,
+		region:      region,
+		credentials: cfg.Credentials,
+		signer:      v4.NewSigner(),
+	}, nil
+}
+
+func (s *s3Writer) Write(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %s", err)
+	}
+
+	sum := sha256.Sum256(data)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	if s.credentials != nil {
+		creds, err := s.credentials.Retrieve(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get AWS credentials: %s", err)
+		}
+
+		if err := s.signer.SignHTTP(ctx, creds, req, payloadHash, "s3", s.region, time.Now()); err != nil {
+			return fmt.Errorf("failed to sign export request: %s", err)
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload export to %q: %s", s.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("failed to upload export to %q: unexpected status code %d: %s",
+			s.url, resp.StatusCode, body)
+	}
+
+	return nil
+}
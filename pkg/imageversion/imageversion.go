@@ -0,0 +1,293 @@
+package imageversion
+
+import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+const (
+	// Kind is the kind of the custom resource results are published as.
+	Kind = "ImageVersion"
+
+	// managedByLabel is the label of the ImageVersions published by
+	// version-checker, so that only those are garbage collected.
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedBy      = "version-checker"
+
+	// subscriberBuffer is the number of result events the publisher may fall
+	// behind by before resubscribing.
+	subscriberBuffer = 1000
+)
+
+// GroupVersionResource is the resource of ImageVersions, as defined by the
+// CRD.
+var GroupVersionResource = schema.GroupVersionResource{
+	Group:    "version-checker.io",
+	Version:  "v1alpha1",
+	Resource: "imageversions",
+}
+
+// CRD is the manifest of the ImageVersion CustomResourceDefinition, which
+// must be installed in the cluster before results can be published. The Helm
+// chart has a copy of it, which must be updated with it.
+//
+//go:embed crd.yaml
+var CRD []byte
+
+// Publisher publishes the result of every checked container of the cluster
+// as an ImageVersion custom resource, in the namespace of its pod, so that
+// results can be read with kubectl and used by policies.
+type Publisher struct {
+	log *logrus.Entry
+
+	client  dynamic.Interface
+	metrics *metrics.Metrics
+
+	// clusterName is the name of the cluster whose results are published, so
+	// that results of remote clusters are not.
+	clusterName string
+
+	// ownsNamespace returns true if the ImageVersions of the given namespace
+	// are published by this replica, so that the ImageVersions of other
+	// shards are not garbage collected.
+	ownsNamespace func(namespace string) bool
+}
+
+// New returns a new Publisher of the results of the given cluster, publishing
+// ImageVersions with the given client. Only ImageVersions of the namespaces
+// ownsNamespace returns true for are garbage collected, such as those of the
+// shard of the replica. All namespaces are owned if nil.
+func New(log *logrus.Entry, client dynamic.Interface, metrics *metrics.Metrics, clusterName string,
+	ownsNamespace func(namespace string) bool) *Publisher {
+	if ownsNamespace == nil {
+		ownsNamespace = func(string) bool { return true }
+	}
+
+	return &Publisher{
+		log:           log.WithField("module", "imageversion"),
+		client:        client,
+		metrics:       metrics,
+		clusterName:   clusterName,
+		ownsNamespace: ownsNamespace,
+	}
+}
+
+// Run is a blocking func that will publish every change to the results, until
+// the context is cancelled. All ImageVersions are resynced every period, so
+// that they are updated with when containers were last checked, and those of
+// deleted pods are garbage collected.
+func (p *Publisher) Run(ctx context.Context, period time.Duration) {
+	p.log.Infof("publishing results as ImageVersions, resyncing every %s", period)
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		events, unsubscribe := p.metrics.Subscribe(subscriberBuffer)
+
+		// Subscribing sends every current result first, which are published
+		// before garbage collecting, so that a resubscribe is also a resync.
+		if err := p.handleEvents(ctx, events, ticker.C); err != nil {
+			p.log.Errorf("failed to publish results: %s", err)
+		}
+		unsubscribe()
+
+		select {
+		case <-ctx.Done():
+			p.log.Info("shutting down ImageVersion publisher")
+			return
+		default:
+		}
+	}
+}
+
+// handleEvents will publish the given result events, resyncing on every tick,
+// until the context is cancelled, or the events channel is closed from
+// falling behind.
+func (p *Publisher) handleEvents(ctx context.Context, events <-chan metrics.Event, resync <-chan time.Time) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-resync:
+			if err := p.resync(ctx); err != nil {
+				p.log.Errorf("failed to resync ImageVersions: %s", err)
+			}
+
+		case event, ok := <-events:
+			if !ok {
+				return errors.New("fell too far behind results, resubscribing")
+			}
+			if event.Entry.Cluster != p.clusterName {
+				continue
+			}
+
+			var err error
+			if event.Type == metrics.EventTypeRemoved {
+				err = p.delete(ctx, event.Entry.Namespace, objectName(event.Entry))
+			} else {
+				err = p.apply(ctx, event.Entry)
+			}
+			if err != nil {
+				p.log.Error(err)
+			}
+		}
+	}
+}
+
+// resync will publish the current results, and delete ImageVersions managed
+// by version-checker which no longer have a result, such as of deleted pods.
+// ImageVersions of namespaces which are not owned are published by the
+// replica of their shard, so are left alone.
+func (p *Publisher) resync(ctx context.Context) error {
+	published := make(map[string]bool)
+	for _, entry := range p.metrics.Entries() {
+		if entry.Cluster != p.clusterName {
+			continue
+		}
+
+		if err := p.apply(ctx, entry); err != nil {
+			p.log.Error(err)
+		}
+		published[entry.Namespace+"/"+objectName(entry)] = true
+	}
+
+	list, err := p.client.Resource(GroupVersionResource).List(ctx, metav1.ListOptions{
+		LabelSelector: managedByLabel + "=" + managedBy,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list ImageVersions: %s", err)
+	}
+
+	for _, obj := range list.Items {
+		if published[obj.GetNamespace()+"/"+obj.GetName()] || !p.ownsNamespace(obj.GetNamespace()) {
+			continue
+		}
+
+		if err := p.delete(ctx, obj.GetNamespace(), obj.GetName()); err != nil {
+			p.log.Error(err)
+		}
+	}
+
+	return nil
+}
+
+// apply will create or update the ImageVersion of the given result, only
+// writing if it has changed.
+func (p *Publisher) apply(ctx context.Context, entry metrics.Entry) error {
+	desired := newObject(entry)
+	client := p.client.Resource(GroupVersionResource).Namespace(entry.Namespace)
+
+	existing, err := client.Get(ctx, desired.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		// The status is ignored on create, so is set once created.
+		existing, err = client.Create(ctx, desired, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create ImageVersion %s/%s: %s", entry.Namespace, desired.GetName(), err)
+		}
+		p.log.Debugf("created ImageVersion %s/%s", entry.Namespace, desired.GetName())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get ImageVersion %s/%s: %s", entry.Namespace, desired.GetName(), err)
+	}
+
+	if !reflect.DeepEqual(existing.Object["spec"], desired.Object["spec"]) ||
+		!reflect.DeepEqual(existing.GetLabels(), desired.GetLabels()) {
+		existing.Object["spec"] = desired.Object["spec"]
+		existing.SetLabels(desired.GetLabels())
+		existing, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to update ImageVersion %s/%s: %s", entry.Namespace, desired.GetName(), err)
+		}
+	}
+
+	if !reflect.DeepEqual(existing.Object["status"], desired.Object["status"]) {
+		existing.Object["status"] = desired.Object["status"]
+		if _, err := client.UpdateStatus(ctx, existing, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update status of ImageVersion %s/%s: %s", entry.Namespace, desired.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// delete will delete the given ImageVersion, if it exists.
+func (p *Publisher) delete(ctx context.Context, namespace, name string) error {
+	err := p.client.Resource(GroupVersionResource).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete ImageVersion %s/%s: %s", namespace, name, err)
+	}
+
+	p.log.Debugf("deleted ImageVersion %s/%s", namespace, name)
+
+	return nil
+}
+
+// newObject returns the ImageVersion of the given result.
+func newObject(entry metrics.Entry) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"pod":           entry.Pod,
+			"container":     entry.Container,
+			"containerType": entry.ContainerType,
+			"image":         entry.ImageURL,
+		},
+		"status": map[string]interface{}{
+			"currentVersion": entry.CurrentVersion,
+			"latestVersion":  entry.LatestVersion,
+			"isLatest":       entry.IsLatest,
+		},
+	}}
+
+	if !entry.LastChecked.IsZero() {
+		obj.Object["status"].(map[string]interface{})["lastChecked"] = entry.LastChecked.UTC().Format(time.RFC3339)
+	}
+
+	obj.SetAPIVersion(GroupVersionResource.GroupVersion().String())
+	obj.SetKind(Kind)
+	obj.SetNamespace(entry.Namespace)
+	obj.SetName(objectName(entry))
+	obj.SetLabels(map[string]string{managedByLabel: managedBy})
+
+	return obj
+}
+
+// objectName returns the name of the ImageVersion of the given result, of
+// the form <pod>.<container>. Container names are unique within a pod,
+// regardless of their type. Names which would be too long are truncated, with
+// a hash of the full name appended so that they remain unique.
+func objectName(entry metrics.Entry) string {
+	name := entry.Pod + "." + entry.Container
+	if len(name) <= validation.DNS1123SubdomainMaxLength {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:8]
+
+	// The truncated name must still end with an alphanumeric character.
+	prefix := strings.TrimRight(name[:validation.DNS1123SubdomainMaxLength-len(hash)-1], ".-")
+
+	return prefix + "-" + hash
+}
@@ -0,0 +1,274 @@
+package imageversion
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func testPublisher(t *testing.T, objs ...runtime.Object) (*Publisher, *metrics.Metrics, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+
+	return testShardPublisher(t, nil, objs...)
+}
+
+func testShardPublisher(t *testing.T, ownsNamespace func(string) bool, objs ...runtime.Object) (*Publisher, *metrics.Metrics, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+
+	log := logrus.NewEntry(logrus.New())
+	m := metrics.New(log, prometheus.NewRegistry(), metrics.Options{})
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{GroupVersionResource: Kind + "List"}, objs...)
+
+	return New(log, client, m, "", ownsNamespace), m, client
+}
+
+func getImageVersion(t *testing.T, client *dynamicfake.FakeDynamicClient, namespace, name string) *unstructured.Unstructured {
+	t.Helper()
+
+	obj, err := client.Resource(GroupVersionResource).Namespace(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+
+	return obj
+}
+
+func TestCRD(t *testing.T) {
+	var crd struct {
+		Spec struct {
+			Group string
+			Names struct {
+				Kind   string
+				Plural string
+			}
+			Versions []struct {
+				Name string
+			}
+		}
+	}
+	require.NoError(t, yaml.Unmarshal(CRD, &crd))
+
+	assert.Equal(t, GroupVersionResource.Group, crd.Spec.Group)
+	assert.Equal(t, GroupVersionResource.Resource, crd.Spec.Names.Plural)
+	assert.Equal(t, Kind, crd.Spec.Names.Kind)
+	require.Len(t, crd.Spec.Versions, 1)
+	assert.Equal(t, GroupVersionResource.Version, crd.Spec.Versions[0].Name)
+}
+
+// TestChartCRD ensures the CRD of the Helm chart, which can't embed this
+// package's manifest, has the same schema.
+func TestChartCRD(t *testing.T) {
+	chartCRD, err := os.ReadFile("../../deploy/charts/version-checker/templates/crd.yaml")
+	require.NoError(t, err)
+
+	// Drop the template directives, and the labels they include
+	var lines []string
+	for _, line := range strings.Split(string(chartCRD), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "{{") {
+			lines = append(lines, line)
+		}
+	}
+
+	type manifest struct {
+		Metadata struct {
+			Name string
+		}
+		Spec map[string]interface{}
+	}
+
+	var exp, got manifest
+	require.NoError(t, yaml.Unmarshal(CRD, &exp))
+	require.NoError(t, yaml.Unmarshal([]byte(strings.Join(lines, "\n")), &got))
+
+	assert.Equal(t, exp.Metadata.Name, got.Metadata.Name)
+	assert.Equal(t, exp.Spec, got.Spec, "the chart CRD should match pkg/imageversion/crd.yaml")
+}
+
+func TestResync(t *testing.T) {
+	ctx := context.Background()
+
+	// An ImageVersion of a deleted pod, and one not managed by
+	// version-checker.
+	deleted := newObject(metrics.Entry{Namespace: "default", Pod: "deleted-abc", Container: "nginx"})
+	unmanaged := newObject(metrics.Entry{Namespace: "default", Pod: "other-abc", Container: "nginx"})
+	unmanaged.SetLabels(nil)
+
+	publisher, m, client := testPublisher(t, deleted, unmanaged)
+
+	lastChecked := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	m.AddImage(metrics.Entry{
+		Namespace:      "default",
+		Pod:            "nginx-abc",
+		Container:      "nginx",
+		ContainerType:  "container",
+		ImageURL:       "docker.io/library/nginx",
+		CurrentVersion: "1.25.0",
+		LatestVersion:  "1.27.0",
+		LastChecked:    lastChecked,
+	})
+	m.AddImage(metrics.Entry{
+		Cluster:        "remote",
+		Namespace:      "default",
+		Pod:            "remote-abc",
+		Container:      "nginx",
+		ContainerType:  "container",
+		ImageURL:       "docker.io/library/nginx",
+		CurrentVersion: "1.25.0",
+		LatestVersion:  "1.27.0",
+	})
+
+	require.NoError(t, publisher.resync(ctx))
+
+	obj := getImageVersion(t, client, "default", "nginx-abc.nginx")
+	assert.Equal(t, map[string]interface{}{
+		"pod":           "nginx-abc",
+		"container":     "nginx",
+		"containerType": "container",
+		"image":         "docker.io/library/nginx",
+	}, obj.Object["spec"])
+	assert.Equal(t, map[string]interface{}{
+		"currentVersion": "1.25.0",
+		"latestVersion":  "1.27.0",
+		"isLatest":       false,
+		"lastChecked":    "2024-01-02T03:04:05Z",
+	}, obj.Object["status"])
+
+	list, err := client.Resource(GroupVersionResource).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+
+	var names []string
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+	assert.ElementsMatch(t, []string{"nginx-abc.nginx", "other-abc.nginx"}, names,
+		"ImageVersions of deleted pods and remote clusters should not exist")
+
+	// Updated results should update the status.
+	m.AddImage(metrics.Entry{
+		Namespace:      "default",
+		Pod:            "nginx-abc",
+		Container:      "nginx",
+		ContainerType:  "container",
+		ImageURL:       "docker.io/library/nginx",
+		IsLatest:       true,
+		CurrentVersion: "1.27.0",
+		LatestVersion:  "1.27.0",
+		LastChecked:    lastChecked.Add(time.Hour),
+	})
+	require.NoError(t, publisher.resync(ctx))
+
+	obj = getImageVersion(t, client, "default", "nginx-abc.nginx")
+	assert.Equal(t, "1.27.0", obj.Object["status"].(map[string]interface{})["currentVersion"])
+	assert.Equal(t, true, obj.Object["status"].(map[string]interface{})["isLatest"])
+	assert.Equal(t, "2024-01-02T04:04:05Z", obj.Object["status"].(map[string]interface{})["lastChecked"])
+}
+
+func TestResyncShard(t *testing.T) {
+	ctx := context.Background()
+
+	// ImageVersions of deleted pods, of a namespace of this replica's shard
+	// and of another's.
+	owned := newObject(metrics.Entry{Namespace: "default", Pod: "deleted-abc", Container: "nginx"})
+	other := newObject(metrics.Entry{Namespace: "other", Pod: "nginx-abc", Container: "nginx"})
+
+	publisher, _, client := testShardPublisher(t, func(namespace string) bool {
+		return namespace == "default"
+	}, owned, other)
+
+	require.NoError(t, publisher.resync(ctx))
+
+	list, err := client.Resource(GroupVersionResource).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+
+	var names []string
+	for _, item := range list.Items {
+		names = append(names, item.GetNamespace()+"/"+item.GetName())
+	}
+	assert.Equal(t, []string{"other/nginx-abc.nginx"}, names,
+		"ImageVersions of the namespaces of other shards should not be garbage collected")
+}
+
+func TestHandleEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publisher, m, client := testPublisher(t)
+
+	events, unsubscribe := m.Subscribe(10)
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, publisher.handleEvents(ctx, events, nil))
+	}()
+
+	entry := metrics.Entry{
+		Namespace:      "default",
+		Pod:            "nginx-abc",
+		Container:      "nginx",
+		ContainerType:  "container",
+		ImageURL:       "docker.io/library/nginx",
+		IsLatest:       true,
+		CurrentVersion: "1.27.0",
+		LatestVersion:  "1.27.0",
+	}
+	m.AddImage(entry)
+
+	assert.Eventually(t, func() bool {
+		_, err := client.Resource(GroupVersionResource).Namespace("default").Get(ctx, "nginx-abc.nginx", metav1.GetOptions{})
+		return err == nil
+	}, time.Second*5, time.Millisecond*10, "checked containers should be published")
+
+	m.RemoveImage("", "default", "nginx-abc", "nginx", "container")
+
+	assert.Eventually(t, func() bool {
+		list, err := client.Resource(GroupVersionResource).List(ctx, metav1.ListOptions{})
+		return err == nil && len(list.Items) == 0
+	}, time.Second*5, time.Millisecond*10, "removed containers should be deleted")
+
+	cancel()
+	<-done
+}
+
+func TestObjectName(t *testing.T) {
+	tests := map[string]struct {
+		pod, container string
+		exp            string
+	}{
+		"names should be the pod and container": {
+			pod:       "nginx-abc",
+			container: "nginx",
+			exp:       "nginx-abc.nginx",
+		},
+		"long names should be truncated with a hash": {
+			pod:       strings.Repeat("a", 250),
+			container: "nginx",
+			exp:       strings.Repeat("a", 244) + "-",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			name := objectName(metrics.Entry{Pod: test.pod, Container: test.container})
+			assert.True(t, strings.HasPrefix(name, test.exp), name)
+			assert.LessOrEqual(t, len(name), validation.DNS1123SubdomainMaxLength)
+			assert.Empty(t, validation.IsDNS1123Subdomain(name))
+		})
+	}
+}
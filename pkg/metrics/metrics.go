@@ -2,9 +2,13 @@ package metrics
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"net/http"
+	"reflect"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -14,6 +18,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/utils/clock"
 )
 
 // Metrics is used to expose container image version checks as prometheus
@@ -21,44 +26,562 @@ import (
 type Metrics struct {
 	*http.Server
 
+	registry              *prometheus.Registry
 	containerImageVersion *prometheus.GaugeVec
+	lastCheckedTimestamp  *prometheus.GaugeVec
+	lastChangedTimestamp  *prometheus.GaugeVec
+	imagePinnedByDigest   *prometheus.GaugeVec
+	isAbsoluteLatest      *prometheus.GaugeVec
+	baseImageIsLatest     *prometheus.GaugeVec
+	aheadOfRegistry       *prometheus.GaugeVec
+	versionsBehind        *prometheus.GaugeVec
+	checkDisabled         *prometheus.GaugeVec
+	disallowedRegistry    *prometheus.GaugeVec
+	deprecatedRepository  *prometheus.GaugeVec
+	imageMissing          *prometheus.GaugeVec
+	registryInFlight      *prometheus.GaugeVec
+	clusterUp             *prometheus.GaugeVec
+	paused                *prometheus.GaugeVec
+	containersTracked     *prometheus.GaugeVec
+	podCheckDuration      *prometheus.HistogramVec
+	checkInterval         *prometheus.HistogramVec
+	conditionalHits       *prometheus.CounterVec
+	strictLatestFallbacks *prometheus.CounterVec
+	tagMutated            *prometheus.CounterVec
+	kafkaEventsDropped    *prometheus.CounterVec
+	nodeImageVersion      *prometheus.GaugeVec
+	resourceImageVersion  *prometheus.GaugeVec
+	selfImageVersion      *prometheus.GaugeVec
 	log                   *logrus.Entry
 
+	onlyExportOutdated bool
+
 	// container cache stores a cache of a container's current image, version,
 	// and the latest
-	containerCache map[string]cacheItem
+	containerCache map[string]Entry
 	mu             sync.Mutex
+
+	// tracked is the number of containers in the container cache, by cluster.
+	tracked map[string]int
+
+	// removalGrace is how long the removal of containers is delayed for, and
+	// pendingRemovals are the delayed removals, by container.
+	removalGrace    time.Duration
+	pendingRemovals map[string]*pendingRemoval
+	clock           clock.WithDelayedExecution
+
+	// nodeImages stores the results of the images on nodes, by cluster, node,
+	// and image reference.
+	nodeImages map[string]NodeImageEntry
+
+	// resourceImages stores the results of the images referenced by fields
+	// of resources, by cluster, resource, field path, and image reference.
+	resourceImages map[string]ResourceImageEntry
+
+	// selfLabels are the labels of the result of version-checker's own
+	// image, if any.
+	selfLabels prometheus.Labels
+
+	// subscribers are sent an event for every change to the container cache,
+	// and those true also for every check of a container whose result is
+	// unchanged.
+	subscribers map[chan Event]bool
+}
+
+// EventType is the type of change to the result of a container.
+type EventType string
+
+const (
+	// EventTypeChecked is for a container which has been checked.
+	EventTypeChecked EventType = "checked"
+
+	// EventTypeRemoved is for a container whose result has been removed.
+	EventTypeRemoved EventType = "removed"
+)
+
+// Event is a change to the result of a container.
+type Event struct {
+	Type  EventType
+	Entry Entry
+
+	// Snapshot is true for the events of the current results sent on
+	// subscribing, rather than of a change as it happened, such as the
+	// results replayed to a subscriber which resubscribed after falling
+	// behind.
+	Snapshot bool
+}
+
+// Entry is the result of a container image version check, as exposed by the
+// metrics.
+type Entry struct {
+	// Cluster is the name of the cluster the container is running in, which
+	// is empty for the local cluster unless named.
+	Cluster string
+
+	Namespace     string
+	Pod           string
+	Container     string
+	ContainerType string
+
+	// Workload, if set, is the workload the pod is of, such as the
+	// Deployment of a ReplicaSet, so that containers which reappear under a
+	// new pod of the workload replace those pending removal.
+	Workload string
+
+	ImageURL       string
+	IsLatest       bool
+	CurrentVersion string
+	LatestVersion  string
+
+	OS             string
+	Arch           string
+	PlatformSource string
+
+	// ArtifactType is the type of the artifact of the latest version, such as
+	// image or helm-chart.
+	ArtifactType string
+
+	// PinnedByDigest is whether the container image reference includes a
+	// digest.
+	PinnedByDigest bool
+
+	// Severity is the severity of the container being outdated, exposed as a
+	// label of whether it is the latest version.
+	Severity string
+
+	// AbsoluteLatestVersion is the latest version ignoring any max version
+	// ceiling, and IsAbsoluteLatest whether the current version is at least
+	// it. Only reported if AbsoluteLatestVersion is set.
+	AbsoluteLatestVersion string
+	IsAbsoluteLatest      bool
+
+	// IsAheadOfRegistry is whether the current version is greater than the
+	// latest version in the registry, such as a locally built image.
+	IsAheadOfRegistry bool
+
+	// VersionsBehind is the number of versions between the current and latest
+	// versions. Only reported if set, for semver versions.
+	VersionsBehind *int
+
+	// BaseImage is the result of the base image declared by the labels of the
+	// container image, if checked.
+	BaseImage *BaseImageEntry
+
+	// LastChecked is when the container was successfully checked.
+	LastChecked time.Time
+
+	// LastChanged is when the result of the container last changed, set to
+	// LastChecked when the entry is added with a changed result.
+	LastChanged time.Time
+}
+
+// BaseImageEntry is the result of a version check of the base image a
+// container image was built from.
+type BaseImageEntry struct {
+	ImageURL       string
+	IsLatest       bool
+	CurrentVersion string
+	LatestVersion  string
+}
+
+// NodeImageEntry is the result of a version check of an image on a node, as
+// listed by the container runtime of the node.
+type NodeImageEntry struct {
+	// Cluster is the name of the cluster the node is in, which is empty for
+	// the local cluster unless named.
+	Cluster string
+	Node    string
+
+	// Reference is the image reference listed by the container runtime, which
+	// identifies the image on the node.
+	Reference string
+
+	ImageURL       string
+	IsLatest       bool
+	CurrentVersion string
+	LatestVersion  string
+
+	OS   string
+	Arch string
 }
 
-type cacheItem struct {
-	image          string
-	currentVersion string
-	latestVersion  string
+// ResourceImageEntry is the result of a version check of an image referenced
+// by a field of a resource, such as the image an operator is configured to
+// deploy by a custom resource.
+type ResourceImageEntry struct {
+	// Cluster is the name of the cluster the resource is in, which is empty
+	// for the local cluster unless named.
+	Cluster string
+
+	Group     string
+	Resource  string
+	Namespace string
+	Name      string
+
+	// Path is the JSONPath of the field referencing the image.
+	Path string
+
+	// Reference is the image reference of the field.
+	Reference string
+
+	ImageURL       string
+	IsLatest       bool
+	CurrentVersion string
+	LatestVersion  string
+}
+
+// Options are used to configure which metrics are exposed.
+type Options struct {
+	// OnlyExportOutdated will only expose the per container version series
+	// of containers which are not using the latest version, to reduce
+	// cardinality. The absence of a container's series means it is latest.
+	// Whether images are pinned by digest, or ahead of their registry, is
+	// still exposed for every container.
+	OnlyExportOutdated bool
+
+	// RemovalGrace is how long the series of removed containers are kept for
+	// before they are removed, so that containers which are added again
+	// within it, such as while restarting during a rollout, have no gaps in
+	// their series. Series are removed immediately if 0.
+	RemovalGrace time.Duration
 }
 
-func New(log *logrus.Entry) *Metrics {
-	containerImageVersion := promauto.NewGaugeVec(
+// New returns a new Metrics, registering its metrics with the given
+// registry.
+func New(log *logrus.Entry, reg *prometheus.Registry, opts Options) *Metrics {
+	containerImageVersion := promauto.With(reg).NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "version_checker",
 			Name:      "is_latest_version",
 			Help:      "Where the container in use is using the latest upstream registry version",
 		},
 		[]string{
-			"namespace", "pod", "container", "container_type", "image", "current_version", "latest_version",
+			"cluster", "namespace", "pod", "container", "container_type", "image", "current_version", "latest_version",
+			"os", "arch", "platform_source", "severity", "artifact_type",
+		},
+	)
+	lastCheckedTimestamp := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "last_checked_timestamp",
+			Help:      "Timestamp in seconds of when the container image version was last successfully checked",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "container", "container_type",
+		},
+	)
+
+	lastChangedTimestamp := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "last_changed_timestamp",
+			Help:      "Timestamp in seconds of when the result of the container image version check last changed",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "container", "container_type",
+		},
+	)
+
+	imagePinnedByDigest := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "image_pinned_by_digest",
+			Help:      "Whether the container image reference is pinned by an immutable digest",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "container", "container_type",
+		},
+	)
+
+	isAbsoluteLatest := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "is_absolute_latest_version",
+			Help:      "Where the container in use is using the latest upstream registry version, ignoring any max version ceiling",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "container", "container_type", "image", "current_version", "latest_version",
+		},
+	)
+
+	baseImageIsLatest := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "base_image_is_latest_version",
+			Help:      "Where the base image declared by the container image is the latest upstream registry version",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "container", "container_type", "image", "base_image", "current_version", "latest_version",
+		},
+	)
+
+	aheadOfRegistry := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "current_ahead_of_registry",
+			Help:      "Whether the container is using a version greater than the latest upstream registry version, such as a locally built image",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "container", "container_type",
+		},
+	)
+
+	versionsBehind := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "versions_behind",
+			Help:      "Number of versions between the version the container is using and the latest upstream registry version, for semver versions",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "container", "container_type",
+		},
+	)
+
+	checkDisabled := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "check_disabled",
+			Help:      "Set for containers whose version check is disabled, in place of their version check series",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "container", "container_type",
+		},
+	)
+
+	disallowedRegistry := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "disallowed_registry",
+			Help:      "Set for containers whose image is from a registry which is not allowed",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "container", "container_type", "image", "registry",
+		},
+	)
+
+	deprecatedRepository := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "deprecated_repository",
+			Help:      "Set for containers whose image is from a deprecated repository, regardless of its tag",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "container", "container_type", "repository", "reason",
+		},
+	)
+
+	imageMissing := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "image_missing",
+			Help:      "Set for containers whose image repository, or image reference, was not found by the registry",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "container", "container_type", "image", "reason",
+		},
+	)
+
+	registryInFlight := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "registry_requests_in_flight",
+			Help:      "Number of in-flight requests for the tags of images, by registry host",
+		},
+		[]string{
+			"host",
+		},
+	)
+
+	clusterUp := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "cluster_up",
+			Help:      "Whether the API server of the cluster was reachable at the last health check",
+		},
+		[]string{
+			"cluster",
+		},
+	)
+
+	paused := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "paused",
+			Help:      "Whether checks of images from the registry are paused by a maintenance window, where * is all registries",
+		},
+		[]string{
+			"cluster",
+			"registry",
+		},
+	)
+
+	containersTracked := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "containers_tracked",
+			Help:      "Number of containers which have been checked, including those using the latest version",
+		},
+		[]string{
+			"cluster",
+		},
+	)
+
+	podCheckDuration := promauto.With(reg).NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "version_checker",
+			Name:      "pod_check_duration_seconds",
+			Help:      "Time in seconds taken to check all of the containers of a pod, including failed checks",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 15),
+		},
+		[]string{
+			"cluster", "namespace",
+		},
+	)
+
+	checkInterval := promauto.With(reg).NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "version_checker",
+			Name:      "pod_check_interval_seconds",
+			Help:      "Interval in seconds until pods are checked again, as adapted to how often their results change",
+			Buckets:   prometheus.ExponentialBuckets(60, 2, 12),
+		},
+		[]string{
+			"cluster",
+		},
+	)
+
+	conditionalHits := promauto.With(reg).NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "version_checker",
+			Name:      "conditional_hits_total",
+			Help:      "Number of manifest requests answered as not modified by the registry, reusing the cached manifest",
+		},
+		[]string{
+			"host",
+		},
+	)
+
+	strictLatestFallbacks := promauto.With(reg).NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "version_checker",
+			Name:      "strict_latest_fallbacks_total",
+			Help:      "Number of searches where the manifest of the latest tag could not be fetched, and a lower tag was selected",
+		},
+		[]string{
+			"host",
+		},
+	)
+
+	tagMutated := promauto.With(reg).NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "version_checker",
+			Name:      "tag_mutated_total",
+			Help:      "Number of times the digest of the latest tag of an image changed between checks, without the tag changing",
+		},
+		[]string{
+			"cluster",
+			"image",
+			"tag",
+		},
+	)
+
+	kafkaEventsDropped := promauto.With(reg).NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "version_checker",
+			Name:      "kafka_events_dropped_total",
+			Help:      "Number of result events which were dropped rather than produced to Kafka, as the buffer of events was full, producing failed, or the producer fell behind results",
+		},
+		[]string{
+			"reason",
+		},
+	)
+
+	nodeImageVersion := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "node_image_is_latest_version",
+			Help:      "Where the image on the node, as listed by its container runtime, is the latest upstream registry version",
+		},
+		[]string{
+			"cluster", "node", "image", "current_version", "latest_version", "os", "arch",
+		},
+	)
+
+	resourceImageVersion := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "resource_image_is_latest_version",
+			Help:      "Where the image referenced by the field of a resource, such as a custom resource, is the latest upstream registry version",
+		},
+		[]string{
+			"cluster", "group", "resource", "namespace", "name", "path", "image", "current_version", "latest_version",
+		},
+	)
+
+	selfImageVersion := promauto.With(reg).NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "version_checker",
+			Name:      "self_is_latest_version",
+			Help:      "Where the image of version-checker itself is the latest upstream registry version",
+		},
+		[]string{
+			"cluster", "namespace", "pod", "image", "current_version", "latest_version",
 		},
 	)
 
 	return &Metrics{
 		log:                   log.WithField("module", "metrics"),
+		registry:              reg,
 		containerImageVersion: containerImageVersion,
-		containerCache:        make(map[string]cacheItem),
+		lastCheckedTimestamp:  lastCheckedTimestamp,
+		lastChangedTimestamp:  lastChangedTimestamp,
+		imagePinnedByDigest:   imagePinnedByDigest,
+		isAbsoluteLatest:      isAbsoluteLatest,
+		baseImageIsLatest:     baseImageIsLatest,
+		aheadOfRegistry:       aheadOfRegistry,
+		versionsBehind:        versionsBehind,
+		checkDisabled:         checkDisabled,
+		disallowedRegistry:    disallowedRegistry,
+		deprecatedRepository:  deprecatedRepository,
+		imageMissing:          imageMissing,
+		registryInFlight:      registryInFlight,
+		clusterUp:             clusterUp,
+		paused:                paused,
+		containersTracked:     containersTracked,
+		podCheckDuration:      podCheckDuration,
+		checkInterval:         checkInterval,
+		conditionalHits:       conditionalHits,
+		strictLatestFallbacks: strictLatestFallbacks,
+		tagMutated:            tagMutated,
+		kafkaEventsDropped:    kafkaEventsDropped,
+		nodeImageVersion:      nodeImageVersion,
+		resourceImageVersion:  resourceImageVersion,
+		selfImageVersion:      selfImageVersion,
+		onlyExportOutdated:    opts.OnlyExportOutdated,
+		tracked:               make(map[string]int),
+		removalGrace:          opts.RemovalGrace,
+		pendingRemovals:       make(map[string]*pendingRemoval),
+		clock:                 clock.RealClock{},
+		containerCache:        make(map[string]Entry),
+		nodeImages:            make(map[string]NodeImageEntry),
+		resourceImages:        make(map[string]ResourceImageEntry),
+		subscribers:           make(map[chan Event]bool),
 	}
 }
 
+// metricsHandler returns the handler of the metrics endpoint. The OpenMetrics
+// format is served to scrapers which request it with the Accept header, and
+// the Prometheus text format otherwise.
+func (m *Metrics) metricsHandler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+}
+
 // Run will run the metrics server.
 func (m *Metrics) Run(servingAddress string) error {
 	router := http.NewServeMux()
-	router.Handle("/metrics", promhttp.Handler())
+	router.Handle("/metrics", m.metricsHandler())
 	router.Handle("/healthz", http.HandlerFunc(m.healthzAndReadyzHandler))
 	router.Handle("/readyz", http.HandlerFunc(m.healthzAndReadyzHandler))
 
@@ -78,7 +601,7 @@ func (m *Metrics) Run(servingAddress string) error {
 	go func() {
 		m.log.Infof("serving metrics on %s/metrics", ln.Addr())
 
-		if err := m.Serve(ln); err != nil {
+		if err := m.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			m.log.Errorf("failed to serve prometheus metrics: %s", err)
 			return
 		}
@@ -87,70 +610,715 @@ func (m *Metrics) Run(servingAddress string) error {
 	return nil
 }
 
-func (m *Metrics) AddImage(namespace, pod, container, containerType, imageURL string, isLatest bool, currentVersion, latestVersion string) {
-	// Remove old image url/version if it exists
-	m.RemoveImage(namespace, pod, container, containerType)
-
+// AddImage will expose the given container image version check result,
+// replacing any previous result for the same container. If the result is
+// unchanged, only when it was last checked is updated, and no event is
+// published.
+func (m *Metrics) AddImage(entry Entry) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	index := m.latestImageIndex(entry.Cluster, entry.Namespace, entry.Pod, entry.Container, entry.ContainerType)
+	m.cancelRemoval(index)
+	m.replaceRemovals(entry)
+
+	if previous, ok := m.containerCache[index]; ok && sameResult(previous, entry) {
+		previous.LastChecked = entry.LastChecked
+		m.containerCache[index] = previous
+
+		if !entry.LastChecked.IsZero() && (!m.onlyExportOutdated || isOutdated(entry)) {
+			labels := m.buildPartialLabels(entry.Cluster, entry.Namespace, entry.Pod, entry.Container, entry.ContainerType)
+			m.lastCheckedTimestamp.With(labels).Set(float64(entry.LastChecked.Unix()))
+		}
+
+		m.publishUnchanged(Event{Type: EventTypeChecked, Entry: previous})
+
+		return
+	}
+
+	entry.LastChanged = entry.LastChecked
+
+	// Remove old image url/version if it exists
+	m.removeImage(entry.Cluster, entry.Namespace, entry.Pod, entry.Container, entry.ContainerType)
+
+	m.exportPosture(entry)
+	if !m.onlyExportOutdated || isOutdated(entry) {
+		m.exportImage(entry)
+	}
+
+	m.containerCache[index] = entry
+
+	m.tracked[entry.Cluster]++
+	m.containersTracked.WithLabelValues(entry.Cluster).Set(float64(m.tracked[entry.Cluster]))
+
+	m.publish(Event{Type: EventTypeChecked, Entry: entry})
+}
+
+// exportPosture will expose the series of how the given container image is
+// referenced, which are exported for every container regardless of
+// OnlyExportOutdated, since they are not implied by it being latest. Must be
+// called with the lock held.
+func (m *Metrics) exportPosture(entry Entry) {
+	partialLabels := m.buildPartialLabels(entry.Cluster, entry.Namespace, entry.Pod, entry.Container, entry.ContainerType)
+
+	pinnedByDigestF := 0.0
+	if entry.PinnedByDigest {
+		pinnedByDigestF = 1.0
+	}
+	m.imagePinnedByDigest.With(partialLabels).Set(pinnedByDigestF)
+
+	aheadOfRegistryF := 0.0
+	if entry.IsAheadOfRegistry {
+		aheadOfRegistryF = 1.0
+	}
+	m.aheadOfRegistry.With(partialLabels).Set(aheadOfRegistryF)
+}
+
+// exportImage will expose the version series of the given container image
+// version check result. Must be called with the lock held.
+func (m *Metrics) exportImage(entry Entry) {
 	isLatestF := 0.0
-	if isLatest {
+	if entry.IsLatest {
 		isLatestF = 1.0
 	}
 
 	m.containerImageVersion.With(
-		m.buildLabels(namespace, pod, container, containerType, imageURL, currentVersion, latestVersion),
+		m.buildLabels(entry),
 	).Set(isLatestF)
 
-	index := m.latestImageIndex(namespace, pod, container, containerType)
-	m.containerCache[index] = cacheItem{
-		image:          imageURL,
-		currentVersion: currentVersion,
-		latestVersion:  latestVersion,
+	partialLabels := m.buildPartialLabels(entry.Cluster, entry.Namespace, entry.Pod, entry.Container, entry.ContainerType)
+
+	if entry.VersionsBehind != nil {
+		m.versionsBehind.With(partialLabels).Set(float64(*entry.VersionsBehind))
+	}
+
+	if len(entry.AbsoluteLatestVersion) > 0 {
+		isAbsoluteLatestF := 0.0
+		if entry.IsAbsoluteLatest {
+			isAbsoluteLatestF = 1.0
+		}
+
+		m.isAbsoluteLatest.With(prometheus.Labels{
+			"cluster":         entry.Cluster,
+			"namespace":       entry.Namespace,
+			"pod":             entry.Pod,
+			"container":       entry.Container,
+			"container_type":  entry.ContainerType,
+			"image":           entry.ImageURL,
+			"current_version": entry.CurrentVersion,
+			"latest_version":  entry.AbsoluteLatestVersion,
+		}).Set(isAbsoluteLatestF)
+	}
+
+	if entry.BaseImage != nil {
+		baseIsLatestF := 0.0
+		if entry.BaseImage.IsLatest {
+			baseIsLatestF = 1.0
+		}
+
+		m.baseImageIsLatest.With(prometheus.Labels{
+			"cluster":         entry.Cluster,
+			"namespace":       entry.Namespace,
+			"pod":             entry.Pod,
+			"container":       entry.Container,
+			"container_type":  entry.ContainerType,
+			"image":           entry.ImageURL,
+			"base_image":      entry.BaseImage.ImageURL,
+			"current_version": entry.BaseImage.CurrentVersion,
+			"latest_version":  entry.BaseImage.LatestVersion,
+		}).Set(baseIsLatestF)
+	}
+
+	if !entry.LastChecked.IsZero() {
+		m.lastCheckedTimestamp.With(partialLabels).Set(float64(entry.LastChecked.Unix()))
 	}
+	if !entry.LastChanged.IsZero() {
+		m.lastChangedTimestamp.With(partialLabels).Set(float64(entry.LastChanged.Unix()))
+	}
+}
+
+// sameResult returns true if the given entries are the same result, ignoring
+// when they were checked and changed.
+func sameResult(a, b Entry) bool {
+	a.LastChecked, b.LastChecked = time.Time{}, time.Time{}
+	a.LastChanged, b.LastChanged = time.Time{}, time.Time{}
+	return reflect.DeepEqual(a, b)
 }
 
-func (m *Metrics) RemoveImage(namespace, pod, container, containerType string) {
+// isOutdated returns true if the container of the given entry, or its checked
+// base image, is not using the latest version.
+func isOutdated(entry Entry) bool {
+	return !entry.IsLatest || (entry.BaseImage != nil && !entry.BaseImage.IsLatest)
+}
+
+// RemoveImage will remove the result of the given container. With a removal
+// grace period, the removal is delayed by it, and cancelled if the container
+// is added again in the meantime.
+func (m *Metrics) RemoveImage(cluster, namespace, pod, container, containerType string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	index := m.latestImageIndex(namespace, pod, container, containerType)
-	_, ok := m.containerCache[index]
-	if !ok {
+	index := m.latestImageIndex(cluster, namespace, pod, container, containerType)
+	if entry, ok := m.containerCache[index]; ok && m.removalGrace > 0 {
+		m.scheduleRemoval(index, entry)
 		return
 	}
 
-	m.containerImageVersion.DeletePartialMatch(
-		m.buildPartialLabels(namespace, pod),
-	)
-	delete(m.containerCache, index)
+	m.cancelRemoval(index)
+	if entry, ok := m.removeImage(cluster, namespace, pod, container, containerType); ok {
+		m.publish(Event{Type: EventTypeRemoved, Entry: entry})
+	}
+}
+
+// pendingRemoval is the delayed removal of a container, with the index of
+// its workload container, if its pod is of a workload.
+type pendingRemoval struct {
+	timer    clock.Timer
+	entry    Entry
+	workload string
+}
+
+// scheduleRemoval will remove the result of the given container once the
+// removal grace period has passed, unless the removal is cancelled first. A
+// removal already pending is not delayed further. Must be called with the
+// lock held.
+func (m *Metrics) scheduleRemoval(index string, entry Entry) {
+	if _, ok := m.pendingRemovals[index]; ok {
+		return
+	}
+
+	removal := &pendingRemoval{entry: entry, workload: workloadIndex(entry)}
+	removal.timer = m.clock.AfterFunc(m.removalGrace, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		// The removal may have been cancelled, and another scheduled, while
+		// waiting for the lock.
+		if m.pendingRemovals[index] != removal {
+			return
+		}
+		delete(m.pendingRemovals, index)
+		m.removePending(removal)
+	})
+	m.pendingRemovals[index] = removal
+}
+
+// cancelRemoval will cancel the pending removal of the given container, if
+// any. Must be called with the lock held.
+func (m *Metrics) cancelRemoval(index string) {
+	if removal, ok := m.pendingRemovals[index]; ok {
+		removal.timer.Stop()
+		delete(m.pendingRemovals, index)
+	}
+}
+
+// replaceRemovals will remove the containers pending removal which are the
+// same container of the same workload as the given entry, such as of the
+// previous pods of a rollout, now that the entry replaces them. Must be called
+// with the lock held.
+func (m *Metrics) replaceRemovals(entry Entry) {
+	workload := workloadIndex(entry)
+	if len(workload) == 0 {
+		return
+	}
+
+	for index, removal := range m.pendingRemovals {
+		if removal.workload != workload {
+			continue
+		}
+
+		removal.timer.Stop()
+		delete(m.pendingRemovals, index)
+		m.removePending(removal)
+	}
+}
+
+// removePending will remove the result of the container of the given pending
+// removal. Must be called with the lock held.
+func (m *Metrics) removePending(removal *pendingRemoval) {
+	e := removal.entry
+	if entry, ok := m.removeImage(e.Cluster, e.Namespace, e.Pod, e.Container, e.ContainerType); ok {
+		m.publish(Event{Type: EventTypeRemoved, Entry: entry})
+	}
+}
+
+// workloadIndex returns the index of the container of the given entry in its
+// workload, regardless of its pod, or empty if its pod is of no workload.
+func workloadIndex(entry Entry) string {
+	if len(entry.Workload) == 0 {
+		return ""
+	}
+
+	return strings.Join([]string{entry.Cluster, entry.Namespace, entry.Workload, entry.Container, entry.ContainerType}, "/")
+}
+
+// SetCheckDisabled will expose that the version check of the given container
+// is disabled, removing its version check series, so that it is
+// distinguishable from a container which no longer exists. The series is
+// removed once the container is checked again, or removed.
+func (m *Metrics) SetCheckDisabled(cluster, namespace, pod, container, containerType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.cancelRemoval(m.latestImageIndex(cluster, namespace, pod, container, containerType))
+
+	if entry, ok := m.removeImage(cluster, namespace, pod, container, containerType); ok {
+		m.publish(Event{Type: EventTypeRemoved, Entry: entry})
+	}
+
+	m.checkDisabled.With(m.buildPartialLabels(cluster, namespace, pod, container, containerType)).Set(1)
+}
+
+// SetDisallowedRegistry will expose that the image of the given container is
+// from a registry which is not allowed, replacing any previous series of the
+// container.
+func (m *Metrics) SetDisallowedRegistry(cluster, namespace, pod, container, containerType, image, registry string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	labels := m.buildPartialLabels(cluster, namespace, pod, container, containerType)
+	m.disallowedRegistry.DeletePartialMatch(labels)
+
+	labels["image"] = image
+	labels["registry"] = registry
+	m.disallowedRegistry.With(labels).Set(1)
+}
+
+// RemoveDisallowedRegistry will remove the disallowed registry series of the
+// given container, once its image is from an allowed registry, or it is
+// removed.
+func (m *Metrics) RemoveDisallowedRegistry(cluster, namespace, pod, container, containerType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.disallowedRegistry.DeletePartialMatch(m.buildPartialLabels(cluster, namespace, pod, container, containerType))
+}
+
+// SetDeprecatedRepository will expose that the image of the given container
+// is from a deprecated repository, for the given reason, replacing any
+// previous series of the container.
+func (m *Metrics) SetDeprecatedRepository(cluster, namespace, pod, container, containerType, repository, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	labels := m.buildPartialLabels(cluster, namespace, pod, container, containerType)
+	m.deprecatedRepository.DeletePartialMatch(labels)
+
+	labels["repository"] = repository
+	labels["reason"] = reason
+	m.deprecatedRepository.With(labels).Set(1)
+}
+
+// RemoveDeprecatedRepository will remove the deprecated repository series of
+// the given container, once its image is not from a deprecated repository, or
+// it is removed.
+func (m *Metrics) RemoveDeprecatedRepository(cluster, namespace, pod, container, containerType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deprecatedRepository.DeletePartialMatch(m.buildPartialLabels(cluster, namespace, pod, container, containerType))
+}
+
+// SetImageMissing will expose that the image of the given container was not
+// found by the registry, for the given reason, replacing any previous series
+// of the container.
+func (m *Metrics) SetImageMissing(cluster, namespace, pod, container, containerType, image, reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	labels := m.buildPartialLabels(cluster, namespace, pod, container, containerType)
+	m.imageMissing.DeletePartialMatch(labels)
+
+	labels["image"] = image
+	labels["reason"] = reason
+	m.imageMissing.With(labels).Set(1)
+}
+
+// RemoveImageMissing will remove the image missing series of the given
+// container, once its image is found, or it is removed.
+func (m *Metrics) RemoveImageMissing(cluster, namespace, pod, container, containerType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.imageMissing.DeletePartialMatch(m.buildPartialLabels(cluster, namespace, pod, container, containerType))
+}
+
+// AddNodeImage will expose the given result of an image on a node, replacing
+// any previous result for the same image reference on the node.
+func (m *Metrics) AddNodeImage(entry NodeImageEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeNodeImage(entry.Cluster, entry.Node, entry.Reference)
+
+	isLatestF := 0.0
+	if entry.IsLatest {
+		isLatestF = 1.0
+	}
+	m.nodeImageVersion.With(nodeImageLabels(entry)).Set(isLatestF)
+
+	m.nodeImages[nodeImageIndex(entry.Cluster, entry.Node, entry.Reference)] = entry
+}
+
+// RemoveNodeImage will remove the result of the given image reference on the
+// node, such as once it has been removed from the node.
+func (m *Metrics) RemoveNodeImage(cluster, node, reference string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeNodeImage(cluster, node, reference)
+}
+
+// removeNodeImage will remove the result of the given image reference on the
+// node. Must be called with the lock held.
+func (m *Metrics) removeNodeImage(cluster, node, reference string) {
+	index := nodeImageIndex(cluster, node, reference)
+	if entry, ok := m.nodeImages[index]; ok {
+		m.nodeImageVersion.Delete(nodeImageLabels(entry))
+		delete(m.nodeImages, index)
+	}
+}
+
+func nodeImageIndex(cluster, node, reference string) string {
+	return strings.Join([]string{cluster, node, reference}, "/")
+}
+
+func nodeImageLabels(entry NodeImageEntry) prometheus.Labels {
+	return prometheus.Labels{
+		"cluster":         entry.Cluster,
+		"node":            entry.Node,
+		"image":           entry.ImageURL,
+		"current_version": entry.CurrentVersion,
+		"latest_version":  entry.LatestVersion,
+		"os":              entry.OS,
+		"arch":            entry.Arch,
+	}
 }
 
-func (m *Metrics) latestImageIndex(namespace, pod, container, containerType string) string {
-	return strings.Join([]string{namespace, pod, container, containerType}, "")
+// AddResourceImage will expose the given result of an image referenced by the
+// field of a resource, replacing any previous result for the same image
+// reference of the field.
+func (m *Metrics) AddResourceImage(entry ResourceImageEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeResourceImage(resourceImageIndex(entry))
+
+	isLatestF := 0.0
+	if entry.IsLatest {
+		isLatestF = 1.0
+	}
+	m.resourceImageVersion.With(resourceImageLabels(entry)).Set(isLatestF)
+
+	m.resourceImages[resourceImageIndex(entry)] = entry
 }
 
-func (m *Metrics) buildLabels(namespace, pod, container, containerType, imageURL, currentVersion, latestVersion string) prometheus.Labels {
+// RemoveResourceImage will remove the result of the image reference of the
+// field of the resource given by the entry, such as once the resource has
+// been deleted. Only the identifying fields of the entry are used.
+func (m *Metrics) RemoveResourceImage(entry ResourceImageEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeResourceImage(resourceImageIndex(entry))
+}
+
+// removeResourceImage will remove the result of the given resource image
+// index. Must be called with the lock held.
+func (m *Metrics) removeResourceImage(index string) {
+	if entry, ok := m.resourceImages[index]; ok {
+		m.resourceImageVersion.Delete(resourceImageLabels(entry))
+		delete(m.resourceImages, index)
+	}
+}
+
+func resourceImageIndex(entry ResourceImageEntry) string {
+	return strings.Join([]string{
+		entry.Cluster, entry.Group, entry.Resource, entry.Namespace, entry.Name, entry.Path, entry.Reference,
+	}, "/")
+}
+
+func resourceImageLabels(entry ResourceImageEntry) prometheus.Labels {
 	return prometheus.Labels{
+		"cluster":         entry.Cluster,
+		"group":           entry.Group,
+		"resource":        entry.Resource,
+		"namespace":       entry.Namespace,
+		"name":            entry.Name,
+		"path":            entry.Path,
+		"image":           entry.ImageURL,
+		"current_version": entry.CurrentVersion,
+		"latest_version":  entry.LatestVersion,
+	}
+}
+
+// SetSelf will expose the result of the version check of version-checker's
+// own image, replacing any previous result.
+func (m *Metrics) SetSelf(cluster, namespace, pod, imageURL, currentVersion, latestVersion string, isLatest bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	labels := prometheus.Labels{
+		"cluster":         cluster,
 		"namespace":       namespace,
 		"pod":             pod,
-		"container_type":  containerType,
-		"container":       container,
 		"image":           imageURL,
 		"current_version": currentVersion,
 		"latest_version":  latestVersion,
 	}
+
+	isLatestF := 0.0
+	if isLatest {
+		isLatestF = 1.0
+	}
+
+	// The new result is set before the previous is removed, so that the
+	// series is never absent.
+	m.selfImageVersion.With(labels).Set(isLatestF)
+	if m.selfLabels != nil && !maps.Equal(m.selfLabels, labels) {
+		m.selfImageVersion.Delete(m.selfLabels)
+	}
+	m.selfLabels = labels
+}
+
+// RemoveSelf will remove the result of the version check of
+// version-checker's own image, such as when it can no longer be checked.
+func (m *Metrics) RemoveSelf() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.selfLabels != nil {
+		m.selfImageVersion.Delete(m.selfLabels)
+		m.selfLabels = nil
+	}
+}
+
+// SetRegistryRequestsInFlight will expose the number of in-flight requests
+// for the tags of images, to the given registry host.
+func (m *Metrics) SetRegistryRequestsInFlight(host string, inFlight int) {
+	m.registryInFlight.WithLabelValues(host).Set(float64(inFlight))
+}
+
+// SetClusterUp will expose whether the API server of the given cluster was
+// reachable.
+func (m *Metrics) SetClusterUp(cluster string, up bool) {
+	upF := 0.0
+	if up {
+		upF = 1.0
+	}
+	m.clusterUp.WithLabelValues(cluster).Set(upF)
+}
+
+// SetPaused will expose whether checks of images from the given registry of
+// the cluster are paused.
+func (m *Metrics) SetPaused(cluster, registry string, paused bool) {
+	pausedF := 0.0
+	if paused {
+		pausedF = 1.0
+	}
+	m.paused.WithLabelValues(cluster, registry).Set(pausedF)
+}
+
+// RemovePaused will remove whether checks of images from the given registry
+// of the cluster are paused, once it no longer has a maintenance window.
+func (m *Metrics) RemovePaused(cluster, registry string) {
+	m.paused.DeleteLabelValues(cluster, registry)
+}
+
+// ObservePodCheckDuration will observe the time taken to check all of the
+// containers of a pod in the given namespace.
+func (m *Metrics) ObservePodCheckDuration(cluster, namespace string, duration time.Duration) {
+	m.podCheckDuration.WithLabelValues(cluster, namespace).Observe(duration.Seconds())
+}
+
+// ObserveCheckInterval will observe the adapted interval until a pod is
+// checked again.
+func (m *Metrics) ObserveCheckInterval(cluster string, interval time.Duration) {
+	m.checkInterval.WithLabelValues(cluster).Observe(interval.Seconds())
+}
+
+// IncConditionalHits will count a manifest request to the given registry host
+// which was answered as not modified.
+func (m *Metrics) IncConditionalHits(host string) {
+	m.conditionalHits.WithLabelValues(host).Inc()
+}
+
+// IncStrictLatestFallbacks will count a search of an image from the given
+// registry host, where a lower tag was selected since the manifest of the
+// latest tag could not be fetched.
+func (m *Metrics) IncStrictLatestFallbacks(host string) {
+	m.strictLatestFallbacks.WithLabelValues(host).Inc()
+}
+
+// IncTagMutated will count the latest tag of the given image being pushed
+// again with a different digest.
+func (m *Metrics) IncTagMutated(cluster, imageURL, tag string) {
+	m.tagMutated.WithLabelValues(cluster, imageURL, tag).Inc()
+}
+
+// IncKafkaEventsDropped will count a result event which was dropped rather
+// than produced to Kafka, for the given reason.
+func (m *Metrics) IncKafkaEventsDropped(reason string) {
+	m.kafkaEventsDropped.WithLabelValues(reason).Inc()
+}
+
+// removeImage will remove the result of the given container, returning the
+// removed entry if it existed. Must be called with the lock held.
+func (m *Metrics) removeImage(cluster, namespace, pod, container, containerType string) (Entry, bool) {
+	index := m.latestImageIndex(cluster, namespace, pod, container, containerType)
+	labels := m.buildPartialLabels(cluster, namespace, pod, container, containerType)
+
+	// A container is either checked or disabled, so both are removed.
+	m.checkDisabled.Delete(labels)
+
+	entry, ok := m.containerCache[index]
+	if !ok {
+		return Entry{}, false
+	}
+
+	m.containerImageVersion.DeletePartialMatch(labels)
+	m.lastCheckedTimestamp.Delete(labels)
+	m.lastChangedTimestamp.Delete(labels)
+	m.imagePinnedByDigest.Delete(labels)
+	m.aheadOfRegistry.Delete(labels)
+	m.versionsBehind.Delete(labels)
+	m.isAbsoluteLatest.DeletePartialMatch(labels)
+	m.baseImageIsLatest.DeletePartialMatch(labels)
+	delete(m.containerCache, index)
+
+	m.tracked[cluster]--
+	m.containersTracked.WithLabelValues(cluster).Set(float64(m.tracked[cluster]))
+
+	return entry, true
 }
 
-func (m *Metrics) buildPartialLabels(namespace, pod string) prometheus.Labels {
+// Entries returns a snapshot of the current results of all containers, in
+// order of cluster, namespace, pod, container, and container type.
+func (m *Metrics) Entries() []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	indexes := make([]string, 0, len(m.containerCache))
+	for index := range m.containerCache {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+
+	entries := make([]Entry, 0, len(indexes))
+	for _, index := range indexes {
+		entries = append(entries, m.containerCache[index])
+	}
+
+	return entries
+}
+
+// Subscribe returns a channel which is sent a checked event for each current
+// result, followed by an event for every change to the results. Subscribers
+// which fall more than buffer events behind are unsubscribed, closing the
+// channel, rather than blocking checks. The returned func must be called to
+// unsubscribe once done.
+func (m *Metrics) Subscribe(buffer int) (<-chan Event, func()) {
+	return m.subscribe(buffer, false)
+}
+
+// SubscribeChecks is as Subscribe, but the channel is also sent a checked
+// event for every check of a container whose result is unchanged.
+func (m *Metrics) SubscribeChecks(buffer int) (<-chan Event, func()) {
+	return m.subscribe(buffer, true)
+}
+
+func (m *Metrics) subscribe(buffer int, unchanged bool) (<-chan Event, func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ch := make(chan Event, len(m.containerCache)+buffer)
+	for _, entry := range m.containerCache {
+		ch <- Event{Type: EventTypeChecked, Entry: entry, Snapshot: true}
+	}
+
+	m.subscribers[ch] = unchanged
+
+	return ch, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.unsubscribe(ch)
+	}
+}
+
+// publish will send the given event to all subscribers. Must be called with
+// the lock held.
+func (m *Metrics) publish(event Event) {
+	for ch := range m.subscribers {
+		m.send(ch, event)
+	}
+}
+
+// publishUnchanged will send the given event of a check whose result is
+// unchanged to the subscribers of all checks. Must be called with the lock
+// held.
+func (m *Metrics) publishUnchanged(event Event) {
+	for ch, unchanged := range m.subscribers {
+		if unchanged {
+			m.send(ch, event)
+		}
+	}
+}
+
+// send will send the given event to the given subscriber, unsubscribing it if
+// it has fallen too far behind. Must be called with the lock held.
+func (m *Metrics) send(ch chan Event, event Event) {
+	select {
+	case ch <- event:
+	default:
+		m.log.Warn("result subscriber fell too far behind, unsubscribing")
+		m.unsubscribe(ch)
+	}
+}
+
+// unsubscribe will remove and close the given subscriber channel, if still
+// subscribed. Must be called with the lock held.
+func (m *Metrics) unsubscribe(ch chan Event) {
+	if _, ok := m.subscribers[ch]; ok {
+		delete(m.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (m *Metrics) latestImageIndex(cluster, namespace, pod, container, containerType string) string {
+	return strings.Join([]string{cluster, namespace, pod, container, containerType}, "/")
+}
+
+func (m *Metrics) buildLabels(entry Entry) prometheus.Labels {
 	return prometheus.Labels{
-		"namespace": namespace,
-		"pod":       pod,
+		"cluster":         entry.Cluster,
+		"namespace":       entry.Namespace,
+		"pod":             entry.Pod,
+		"container_type":  entry.ContainerType,
+		"container":       entry.Container,
+		"image":           entry.ImageURL,
+		"current_version": entry.CurrentVersion,
+		"latest_version":  entry.LatestVersion,
+		"os":              entry.OS,
+		"arch":            entry.Arch,
+		"platform_source": entry.PlatformSource,
+		"severity":        entry.Severity,
+		"artifact_type":   entry.ArtifactType,
 	}
 }
 
-func (m *Metrics) Shutdown() error {
+func (m *Metrics) buildPartialLabels(cluster, namespace, pod, container, containerType string) prometheus.Labels {
+	return prometheus.Labels{
+		"cluster":        cluster,
+		"namespace":      namespace,
+		"pod":            pod,
+		"container":      container,
+		"container_type": containerType,
+	}
+}
+
+// Shutdown will gracefully stop the metrics server, waiting for active
+// connections until the given context is done.
+func (m *Metrics) Shutdown(ctx context.Context) error {
 	// If metrics server is not started than exit early
 	if m.Server == nil {
 		return nil
@@ -158,9 +1326,6 @@ func (m *Metrics) Shutdown() error {
 
 	m.log.Info("shutting down prometheus metrics server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-	defer cancel()
-
 	if err := m.Server.Shutdown(ctx); err != nil {
 		return fmt.Errorf("prometheus metrics server shutdown failed: %s", err)
 	}
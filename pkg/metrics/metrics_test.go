@@ -2,23 +2,31 @@ package metrics
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/sirupsen/logrus"
+	testingclock "k8s.io/utils/clock/testing"
 )
 
 func TestCache(t *testing.T) {
-	m := New(logrus.NewEntry(logrus.New()))
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
 
 	for i, typ := range []string{"init", "container"} {
-		version := fmt.Sprintf("0.1.%d", i)
-		m.AddImage("namespace", "pod", "container", typ, "url", true, version, version)
+		m.AddImage(testEntry(typ, fmt.Sprintf("0.1.%d", i)))
 	}
 
 	for i, typ := range []string{"init", "container"} {
-		version := fmt.Sprintf("0.1.%d", i)
-		mt, _ := m.containerImageVersion.GetMetricWith(m.buildLabels("namespace", "pod", "container", typ, "url", version, version))
+		mt, _ := m.containerImageVersion.GetMetricWith(m.buildLabels(testEntry(typ, fmt.Sprintf("0.1.%d", i))))
 		count := testutil.ToFloat64(mt)
 		if count != 1 {
 			t.Error("Should have added metric")
@@ -26,14 +34,817 @@ func TestCache(t *testing.T) {
 	}
 
 	for _, typ := range []string{"init", "container"} {
-		m.RemoveImage("namespace", "pod", "container", typ)
+		m.RemoveImage("", "namespace", "pod", "container", typ)
 	}
 	for i, typ := range []string{"init", "container"} {
-		version := fmt.Sprintf("0.1.%d", i)
-		mt, _ := m.containerImageVersion.GetMetricWith(m.buildLabels("namespace", "pod", "container", typ, "url", version, version))
+		mt, _ := m.containerImageVersion.GetMetricWith(m.buildLabels(testEntry(typ, fmt.Sprintf("0.1.%d", i))))
 		count := testutil.ToFloat64(mt)
 		if count != 0 {
 			t.Error("Should have removed metric")
 		}
 	}
 }
+
+func TestLastCheckedTimestamp(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	lastChecked := time.Unix(1700000000, 0)
+	for _, typ := range []string{"init", "container"} {
+		entry := testEntry(typ, "0.1.0")
+		entry.LastChecked = lastChecked
+		m.AddImage(entry)
+	}
+
+	if count := testutil.CollectAndCount(m.lastCheckedTimestamp); count != 2 {
+		t.Fatalf("expected 2 last checked timestamps, got=%d", count)
+	}
+
+	mt, err := m.lastCheckedTimestamp.GetMetricWith(m.buildPartialLabels("", "namespace", "pod", "container", "init"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts := testutil.ToFloat64(mt); ts != 1700000000 {
+		t.Errorf("unexpected last checked timestamp, exp=1700000000 got=%v", ts)
+	}
+
+	// Removing one container should not remove another container's metrics
+	m.RemoveImage("", "namespace", "pod", "container", "init")
+	if count := testutil.CollectAndCount(m.lastCheckedTimestamp); count != 1 {
+		t.Errorf("expected 1 last checked timestamp after removal, got=%d", count)
+	}
+	if count := testutil.CollectAndCount(m.containerImageVersion); count != 1 {
+		t.Errorf("expected 1 image version after removal, got=%d", count)
+	}
+}
+
+func TestImagePinnedByDigest(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	pinned := testEntry("container", "0.1.0")
+	pinned.PinnedByDigest = true
+	m.AddImage(pinned)
+	m.AddImage(testEntry("init", "0.1.0"))
+
+	for typ, exp := range map[string]float64{"container": 1, "init": 0} {
+		mt, err := m.imagePinnedByDigest.GetMetricWith(m.buildPartialLabels("", "namespace", "pod", "container", typ))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := testutil.ToFloat64(mt); v != exp {
+			t.Errorf("%s: unexpected pinned by digest, exp=%v got=%v", typ, exp, v)
+		}
+	}
+
+	m.RemoveImage("", "namespace", "pod", "container", "container")
+	m.RemoveImage("", "namespace", "pod", "container", "init")
+	if count := testutil.CollectAndCount(m.imagePinnedByDigest); count != 0 {
+		t.Errorf("expected pinned by digest to be removed, got=%d", count)
+	}
+}
+
+func TestCurrentAheadOfRegistry(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	ahead := testEntry("container", "9.9.9")
+	ahead.IsAheadOfRegistry = true
+	m.AddImage(ahead)
+	m.AddImage(testEntry("init", "0.1.0"))
+
+	for typ, exp := range map[string]float64{"container": 1, "init": 0} {
+		mt, err := m.aheadOfRegistry.GetMetricWith(m.buildPartialLabels("", "namespace", "pod", "container", typ))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := testutil.ToFloat64(mt); v != exp {
+			t.Errorf("%s: unexpected ahead of registry, exp=%v got=%v", typ, exp, v)
+		}
+	}
+
+	m.RemoveImage("", "namespace", "pod", "container", "container")
+	m.RemoveImage("", "namespace", "pod", "container", "init")
+	if count := testutil.CollectAndCount(m.aheadOfRegistry); count != 0 {
+		t.Errorf("expected ahead of registry to be removed, got=%d", count)
+	}
+}
+
+func TestVersionsBehind(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	// Versions behind are only reported if counted
+	m.AddImage(testEntry("uncounted", "0.1.0"))
+
+	versionsBehind := 3
+	entry := testEntry("counted", "0.1.0")
+	entry.VersionsBehind = &versionsBehind
+	m.AddImage(entry)
+
+	if count := testutil.CollectAndCount(m.versionsBehind); count != 1 {
+		t.Errorf("expected only the counted container to be reported, got=%d", count)
+	}
+	if v := testutil.ToFloat64(m.versionsBehind.With(m.buildPartialLabels("", "namespace", "pod", "container", "counted"))); v != 3 {
+		t.Errorf("unexpected versions behind, exp=3 got=%v", v)
+	}
+
+	m.RemoveImage("", "namespace", "pod", "container", "counted")
+	if count := testutil.CollectAndCount(m.versionsBehind); count != 0 {
+		t.Errorf("expected versions behind to be removed, got=%d", count)
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	entry := testEntry("container", "0.1.0")
+	entry.Severity = "warning"
+	m.AddImage(entry)
+
+	// Changing the severity should replace the series of the previous severity
+	entry.Severity = "critical"
+	m.AddImage(entry)
+
+	if count := testutil.CollectAndCount(m.containerImageVersion); count != 1 {
+		t.Errorf("expected a single series, got=%d", count)
+	}
+	mt, err := m.containerImageVersion.GetMetricWith(m.buildLabels(entry))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := testutil.ToFloat64(mt); v != 1 {
+		t.Errorf("unexpected is latest version with critical severity, exp=1 got=%v", v)
+	}
+}
+
+func TestCheckDisabled(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	m.AddImage(testEntry("container", "0.1.0"))
+
+	// Disabling the check should replace the version check series
+	m.SetCheckDisabled("", "namespace", "pod", "container", "container")
+	if count := testutil.CollectAndCount(m.containerImageVersion); count != 0 {
+		t.Errorf("expected version check series to be removed, got=%d", count)
+	}
+	if v := testutil.ToFloat64(m.checkDisabled.With(m.buildPartialLabels("", "namespace", "pod", "container", "container"))); v != 1 {
+		t.Errorf("unexpected check disabled, exp=1 got=%v", v)
+	}
+
+	// Enabling the check again should remove the check disabled series
+	m.AddImage(testEntry("container", "0.1.0"))
+	if count := testutil.CollectAndCount(m.checkDisabled); count != 0 {
+		t.Errorf("expected check disabled to be removed, got=%d", count)
+	}
+
+	// Removing the container should remove the check disabled series
+	m.SetCheckDisabled("", "namespace", "pod", "container", "container")
+	m.RemoveImage("", "namespace", "pod", "container", "container")
+	if count := testutil.CollectAndCount(m.checkDisabled); count != 0 {
+		t.Errorf("expected check disabled to be removed with the container, got=%d", count)
+	}
+}
+
+func TestIsAbsoluteLatest(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	// Only containers with an absolute latest version should be reported
+	m.AddImage(testEntry("init", "0.1.0"))
+	if count := testutil.CollectAndCount(m.isAbsoluteLatest); count != 0 {
+		t.Errorf("expected no absolute latest series, got=%d", count)
+	}
+
+	entry := testEntry("container", "0.1.0")
+	entry.AbsoluteLatestVersion = "0.3.0"
+	m.AddImage(entry)
+
+	mt, err := m.isAbsoluteLatest.GetMetricWith(prometheus.Labels{
+		"cluster": "", "namespace": "namespace", "pod": "pod", "container": "container", "container_type": "container",
+		"image": "url", "current_version": "0.1.0", "latest_version": "0.3.0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := testutil.ToFloat64(mt); v != 0 {
+		t.Errorf("unexpected absolute latest, exp=0 got=%v", v)
+	}
+
+	m.RemoveImage("", "namespace", "pod", "container", "container")
+	if count := testutil.CollectAndCount(m.isAbsoluteLatest); count != 0 {
+		t.Errorf("expected absolute latest to be removed, got=%d", count)
+	}
+}
+
+func TestBaseImageIsLatest(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	// Only containers with a checked base image should be reported
+	m.AddImage(testEntry("init", "0.1.0"))
+	if count := testutil.CollectAndCount(m.baseImageIsLatest); count != 0 {
+		t.Errorf("expected no base image series, got=%d", count)
+	}
+
+	entry := testEntry("container", "0.1.0")
+	entry.BaseImage = &BaseImageEntry{
+		ImageURL:       "docker.io/library/alpine",
+		IsLatest:       false,
+		CurrentVersion: "3.19",
+		LatestVersion:  "3.20",
+	}
+	m.AddImage(entry)
+
+	mt, err := m.baseImageIsLatest.GetMetricWith(prometheus.Labels{
+		"cluster": "", "namespace": "namespace", "pod": "pod", "container": "container", "container_type": "container",
+		"image": "url", "base_image": "docker.io/library/alpine", "current_version": "3.19", "latest_version": "3.20",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := testutil.ToFloat64(mt); v != 0 {
+		t.Errorf("unexpected base image is latest, exp=0 got=%v", v)
+	}
+
+	m.RemoveImage("", "namespace", "pod", "container", "container")
+	if count := testutil.CollectAndCount(m.baseImageIsLatest); count != 0 {
+		t.Errorf("expected base image to be removed, got=%d", count)
+	}
+
+	// Containers with an outdated base image are outdated, when only
+	// exporting outdated containers
+	m = New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{OnlyExportOutdated: true})
+	m.AddImage(entry)
+	if count := testutil.CollectAndCount(m.containerImageVersion); count != 1 {
+		t.Errorf("expected series for container with outdated base image, got=%d", count)
+	}
+}
+
+func testEntry(containerType, version string) Entry {
+	return Entry{
+		Namespace:      "namespace",
+		Pod:            "pod",
+		Container:      "container",
+		ContainerType:  containerType,
+		ImageURL:       "url",
+		IsLatest:       true,
+		CurrentVersion: version,
+		LatestVersion:  version,
+		OS:             "linux",
+		Arch:           "amd64",
+		PlatformSource: "default",
+	}
+}
+
+func TestEntries(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+	m.AddImage(testEntry("init", "0.1.0"))
+	m.AddImage(testEntry("container", "0.2.0"))
+
+	entries := m.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got=%d", len(entries))
+	}
+	// Entries should be ordered by their container type after the container
+	if entries[0].ContainerType != "container" || entries[1].ContainerType != "init" {
+		t.Errorf("unexpected order of entries, got=%s,%s", entries[0].ContainerType, entries[1].ContainerType)
+	}
+
+	m.RemoveImage("", "namespace", "pod", "container", "init")
+	if entries := m.Entries(); len(entries) != 1 {
+		t.Errorf("expected removed entries to not be returned, got=%d", len(entries))
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+	m.AddImage(testEntry("container", "0.1.0"))
+
+	events, unsubscribe := m.Subscribe(1)
+
+	for _, exp := range []Event{
+		// The current results should be sent on subscribing, as a snapshot
+		{Type: EventTypeChecked, Entry: testEntry("container", "0.1.0"), Snapshot: true},
+		// Replacing a result should only send a checked event
+		{Type: EventTypeChecked, Entry: testEntry("container", "0.2.0")},
+		{Type: EventTypeRemoved, Entry: testEntry("container", "0.2.0")},
+	} {
+		switch exp.Type {
+		case EventTypeChecked:
+			if exp.Entry.CurrentVersion != "0.1.0" {
+				m.AddImage(exp.Entry)
+			}
+		case EventTypeRemoved:
+			m.RemoveImage("", "namespace", "pod", "container", "container")
+		}
+
+		if event := <-events; event != exp {
+			t.Errorf("unexpected event, exp=%#+v got=%#+v", exp, event)
+		}
+	}
+
+	// Removing a result which does not exist should not send an event
+	m.RemoveImage("", "namespace", "pod", "container", "container")
+	if len(events) != 0 {
+		t.Errorf("expected no events, got=%d", len(events))
+	}
+
+	unsubscribe()
+	if _, ok := <-events; ok {
+		t.Error("expected channel to be closed after unsubscribing")
+	}
+	unsubscribe()
+}
+
+func TestSubscribeChecks(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	checks, unsubscribeChecks := m.SubscribeChecks(2)
+	defer unsubscribeChecks()
+	changes, unsubscribeChanges := m.Subscribe(2)
+	defer unsubscribeChanges()
+
+	// Checking a container again with an unchanged result should only send
+	// an event to the subscribers of all checks
+	m.AddImage(testEntry("container", "0.1.0"))
+	m.AddImage(testEntry("container", "0.1.0"))
+
+	exp := Event{Type: EventTypeChecked, Entry: testEntry("container", "0.1.0")}
+	for i := 0; i < 2; i++ {
+		if event := <-checks; event != exp {
+			t.Errorf("unexpected check event, exp=%#+v got=%#+v", exp, event)
+		}
+	}
+	if event := <-changes; event != exp {
+		t.Errorf("unexpected change event, exp=%#+v got=%#+v", exp, event)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no change events of unchanged results, got=%d", len(changes))
+	}
+}
+
+func TestNodeImage(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	entry := NodeImageEntry{
+		Node:           "node-1",
+		Reference:      "mirror.corp/nginx:1.25",
+		ImageURL:       "mirror.corp/nginx",
+		CurrentVersion: "1.25",
+		LatestVersion:  "1.27",
+	}
+	m.AddNodeImage(entry)
+
+	// Replacing a result should replace its series
+	entry.LatestVersion, entry.IsLatest = "1.25", true
+	m.AddNodeImage(entry)
+	if count := testutil.CollectAndCount(m.nodeImageVersion); count != 1 {
+		t.Errorf("expected 1 node image series, got=%d", count)
+	}
+	if v := testutil.ToFloat64(m.nodeImageVersion.With(nodeImageLabels(entry))); v != 1 {
+		t.Errorf("unexpected node image is latest, exp=1 got=%v", v)
+	}
+
+	// Removing the image of another node should not remove the result
+	m.RemoveNodeImage("", "node-2", "mirror.corp/nginx:1.25")
+	if count := testutil.CollectAndCount(m.nodeImageVersion); count != 1 {
+		t.Errorf("expected 1 node image series, got=%d", count)
+	}
+
+	m.RemoveNodeImage("", "node-1", "mirror.corp/nginx:1.25")
+	if count := testutil.CollectAndCount(m.nodeImageVersion); count != 0 {
+		t.Errorf("expected node image to be removed, got=%d", count)
+	}
+}
+
+func TestAddImageUnchanged(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	entry := testEntry("container", "0.1.0")
+	entry.LastChecked = time.Unix(1700000000, 0)
+	m.AddImage(entry)
+
+	events, unsubscribe := m.Subscribe(10)
+	defer unsubscribe()
+	<-events
+
+	labels := m.buildPartialLabels("", "namespace", "pod", "container", "container")
+	timestamps := func() (float64, float64) {
+		return testutil.ToFloat64(m.lastCheckedTimestamp.With(labels)),
+			testutil.ToFloat64(m.lastChangedTimestamp.With(labels))
+	}
+
+	// An unchanged result should only update when it was last checked
+	entry.LastChecked = time.Unix(1700000100, 0)
+	m.AddImage(entry)
+	if checked, changed := timestamps(); checked != 1700000100 || changed != 1700000000 {
+		t.Errorf("unexpected timestamps, exp=1700000100,1700000000 got=%v,%v", checked, changed)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events for an unchanged result, got=%d", len(events))
+	}
+	if count := testutil.ToFloat64(m.containersTracked.WithLabelValues("")); count != 1 {
+		t.Errorf("unexpected containers tracked, exp=1 got=%v", count)
+	}
+
+	// A changed result should update when it last changed
+	entry = testEntry("container", "0.2.0")
+	entry.LastChecked = time.Unix(1700000200, 0)
+	m.AddImage(entry)
+	if checked, changed := timestamps(); checked != 1700000200 || changed != 1700000200 {
+		t.Errorf("unexpected timestamps, exp=1700000200,1700000200 got=%v,%v", checked, changed)
+	}
+	if len(events) != 1 {
+		t.Errorf("expected an event for a changed result, got=%d", len(events))
+	}
+	if count := testutil.CollectAndCount(m.containerImageVersion); count != 1 {
+		t.Errorf("expected 1 image version, got=%d", count)
+	}
+}
+
+func TestRemovalGrace(t *testing.T) {
+	fakeClock := testingclock.NewFakeClock(time.Now())
+	newMetrics := func() *Metrics {
+		m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{RemovalGrace: time.Minute})
+		m.clock = fakeClock
+		return m
+	}
+
+	t.Run("removal should wait for the grace period", func(t *testing.T) {
+		m := newMetrics()
+		m.AddImage(testEntry("container", "0.1.0"))
+
+		m.RemoveImage("", "namespace", "pod", "container", "container")
+		if count := testutil.CollectAndCount(m.containerImageVersion); count != 1 {
+			t.Errorf("expected series to be kept during the grace period, got=%d", count)
+		}
+
+		fakeClock.Step(time.Minute)
+		if count := testutil.CollectAndCount(m.containerImageVersion); count != 0 {
+			t.Errorf("expected series to be removed after the grace period, got=%d", count)
+		}
+		if count := testutil.ToFloat64(m.containersTracked.WithLabelValues("")); count != 0 {
+			t.Errorf("unexpected containers tracked, exp=0 got=%v", count)
+		}
+	})
+
+	t.Run("removal should be cancelled if the container reappears", func(t *testing.T) {
+		m := newMetrics()
+		m.AddImage(testEntry("container", "0.1.0"))
+
+		m.RemoveImage("", "namespace", "pod", "container", "container")
+		fakeClock.Step(30 * time.Second)
+		m.AddImage(testEntry("container", "0.1.0"))
+		fakeClock.Step(time.Minute)
+
+		if count := testutil.CollectAndCount(m.containerImageVersion); count != 1 {
+			t.Errorf("expected series to be kept once the container reappeared, got=%d", count)
+		}
+
+		// The container should be removed again once it is truly gone
+		m.RemoveImage("", "namespace", "pod", "container", "container")
+		fakeClock.Step(time.Minute)
+		if count := testutil.CollectAndCount(m.containerImageVersion); count != 0 {
+			t.Errorf("expected series to be removed after the grace period, got=%d", count)
+		}
+	})
+
+	t.Run("removal should be replaced if the container reappears under a new pod of the workload", func(t *testing.T) {
+		m := newMetrics()
+		rolloutEntry := func(pod, workload string) Entry {
+			entry := testEntry("container", "0.1.0")
+			entry.Pod, entry.Workload = pod, workload
+			return entry
+		}
+		m.AddImage(rolloutEntry("app-7d9f8b6c5-abcde", "ReplicaSet/app"))
+		m.AddImage(rolloutEntry("other-5f4d3c2b1-vwxyz", "ReplicaSet/other"))
+
+		m.RemoveImage("", "namespace", "app-7d9f8b6c5-abcde", "container", "container")
+		m.RemoveImage("", "namespace", "other-5f4d3c2b1-vwxyz", "container", "container")
+		fakeClock.Step(30 * time.Second)
+
+		// The new pod of the rollout should replace the removed pod, without
+		// waiting for the grace period, while the pod of the other workload is
+		// kept for the grace period
+		m.AddImage(rolloutEntry("app-6c8e7a5b4-fghij", "ReplicaSet/app"))
+		var pods []string
+		for _, entry := range m.Entries() {
+			pods = append(pods, entry.Pod)
+		}
+		sort.Strings(pods)
+		if exp := []string{"app-6c8e7a5b4-fghij", "other-5f4d3c2b1-vwxyz"}; !reflect.DeepEqual(exp, pods) {
+			t.Errorf("unexpected pods once the rollout replaced the container, exp=%v got=%v", exp, pods)
+		}
+
+		fakeClock.Step(time.Minute)
+		pods = nil
+		for _, entry := range m.Entries() {
+			pods = append(pods, entry.Pod)
+		}
+		if exp := []string{"app-6c8e7a5b4-fghij"}; !reflect.DeepEqual(exp, pods) {
+			t.Errorf("unexpected pods after the grace period, exp=%v got=%v", exp, pods)
+		}
+	})
+
+	t.Run("repeated removals should not delay the removal", func(t *testing.T) {
+		m := newMetrics()
+		m.AddImage(testEntry("container", "0.1.0"))
+
+		m.RemoveImage("", "namespace", "pod", "container", "container")
+		fakeClock.Step(30 * time.Second)
+		m.RemoveImage("", "namespace", "pod", "container", "container")
+		fakeClock.Step(30 * time.Second)
+
+		if count := testutil.CollectAndCount(m.containerImageVersion); count != 0 {
+			t.Errorf("expected series to be removed after the first grace period, got=%d", count)
+		}
+	})
+
+	t.Run("disabling the check should remove immediately", func(t *testing.T) {
+		m := newMetrics()
+		m.AddImage(testEntry("container", "0.1.0"))
+
+		m.RemoveImage("", "namespace", "pod", "container", "container")
+		m.SetCheckDisabled("", "namespace", "pod", "container", "container")
+		if count := testutil.CollectAndCount(m.containerImageVersion); count != 0 {
+			t.Errorf("expected series to be removed, got=%d", count)
+		}
+
+		// The cancelled removal should not remove the check disabled series
+		fakeClock.Step(time.Minute)
+		if count := testutil.CollectAndCount(m.checkDisabled); count != 1 {
+			t.Errorf("expected check disabled to be kept, got=%d", count)
+		}
+	})
+}
+
+func TestRemovalGraceConcurrent(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{RemovalGrace: time.Millisecond})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.AddImage(testEntry("container", "0.1.0"))
+				m.RemoveImage("", "namespace", "pod", "container", "container")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The last removal should still remove the container
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.CollectAndCount(m.containerImageVersion) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected series to be removed after the grace period")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSubscribeSlowSubscriber(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	events, unsubscribe := m.Subscribe(1)
+	defer unsubscribe()
+
+	m.AddImage(testEntry("container", "0.1.0"))
+	m.AddImage(testEntry("container", "0.2.0"))
+
+	// The subscriber should be unsubscribed once its buffer is full, after
+	// receiving the buffered events
+	exp := Event{Type: EventTypeChecked, Entry: testEntry("container", "0.1.0")}
+	if event := <-events; event != exp {
+		t.Errorf("unexpected event, exp=%#+v got=%#+v", exp, event)
+	}
+	if _, ok := <-events; ok {
+		t.Error("expected channel to be closed for slow subscriber")
+	}
+}
+
+func TestSelf(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	m.SetSelf("", "monitoring", "version-checker-abc", "quay.io/jetstack/version-checker", "v0.9.0", "v0.10.0", false)
+
+	// A new result should replace the previous result
+	m.SetSelf("", "monitoring", "version-checker-abc", "quay.io/jetstack/version-checker", "v0.10.0", "v0.10.0", true)
+	if count := testutil.CollectAndCount(m.selfImageVersion); count != 1 {
+		t.Errorf("expected a single series, got=%d", count)
+	}
+	if v := testutil.ToFloat64(m.selfImageVersion.WithLabelValues("", "monitoring", "version-checker-abc",
+		"quay.io/jetstack/version-checker", "v0.10.0", "v0.10.0")); v != 1 {
+		t.Errorf("unexpected self is latest version, exp=1 got=%v", v)
+	}
+
+	m.RemoveSelf()
+	if count := testutil.CollectAndCount(m.selfImageVersion); count != 0 {
+		t.Errorf("expected self result to be removed, got=%d", count)
+	}
+}
+
+func TestSetRegistryRequestsInFlight(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	m.SetRegistryRequestsInFlight("docker.io", 2)
+	m.SetRegistryRequestsInFlight("harbor.corp", 5)
+	m.SetRegistryRequestsInFlight("docker.io", 1)
+
+	for host, exp := range map[string]float64{"docker.io": 1, "harbor.corp": 5} {
+		if v := testutil.ToFloat64(m.registryInFlight.WithLabelValues(host)); v != exp {
+			t.Errorf("%s: unexpected in-flight requests, exp=%v got=%v", host, exp, v)
+		}
+	}
+}
+
+func TestClusters(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	// The same container in different clusters should be separate results
+	for _, cluster := range []string{"", "workload-1"} {
+		entry := testEntry("container", "0.1.0")
+		entry.Cluster = cluster
+		m.AddImage(entry)
+	}
+
+	if count := testutil.CollectAndCount(m.containerImageVersion); count != 2 {
+		t.Errorf("expected a result per cluster, got=%d", count)
+	}
+
+	m.RemoveImage("workload-1", "namespace", "pod", "container", "container")
+
+	if count := testutil.CollectAndCount(m.containerImageVersion); count != 1 {
+		t.Errorf("expected only the removed cluster result to be removed, got=%d", count)
+	}
+	if _, err := m.containerImageVersion.GetMetricWith(m.buildLabels(testEntry("container", "0.1.0"))); err != nil {
+		t.Errorf("expected the local cluster result to remain: %s", err)
+	}
+
+	m.SetClusterUp("workload-1", true)
+	m.SetClusterUp("workload-2", false)
+	for cluster, exp := range map[string]float64{"workload-1": 1, "workload-2": 0} {
+		if v := testutil.ToFloat64(m.clusterUp.WithLabelValues(cluster)); v != exp {
+			t.Errorf("%s: unexpected cluster up, exp=%v got=%v", cluster, exp, v)
+		}
+	}
+}
+
+func TestSetPaused(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	m.SetPaused("", "quay.io", true)
+	m.SetPaused("", "*", false)
+	for registry, exp := range map[string]float64{"quay.io": 1, "*": 0} {
+		if v := testutil.ToFloat64(m.paused.WithLabelValues("", registry)); v != exp {
+			t.Errorf("%s: unexpected paused, exp=%v got=%v", registry, exp, v)
+		}
+	}
+
+	m.RemovePaused("", "quay.io")
+	if count := testutil.CollectAndCount(m.paused); count != 1 {
+		t.Errorf("expected only the removed registry to be removed, got=%d", count)
+	}
+}
+
+func TestOnlyExportOutdated(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{OnlyExportOutdated: true})
+
+	countSeries := func() int {
+		return testutil.CollectAndCount(m.containerImageVersion) +
+			testutil.CollectAndCount(m.lastCheckedTimestamp)
+	}
+	countPostureSeries := func() int {
+		return testutil.CollectAndCount(m.imagePinnedByDigest) +
+			testutil.CollectAndCount(m.aheadOfRegistry)
+	}
+
+	latest := testEntry("container", "0.1.0")
+	latest.LastChecked = time.Now()
+	latest.PinnedByDigest = true
+	m.AddImage(latest)
+	if count := countSeries(); count != 0 {
+		t.Errorf("expected no series for latest container, got=%d", count)
+	}
+	if count := countPostureSeries(); count != 2 {
+		t.Errorf("expected posture series for latest container, got=%d", count)
+	}
+	labels := m.buildPartialLabels("", "namespace", "pod", "container", "container")
+	if v := testutil.ToFloat64(m.imagePinnedByDigest.With(labels)); v != 1 {
+		t.Errorf("expected latest container to be pinned by digest, got=%v", v)
+	}
+
+	outdated := testEntry("init", "0.1.0")
+	outdated.IsLatest = false
+	outdated.LatestVersion = "0.2.0"
+	outdated.LastChecked = time.Now()
+	m.AddImage(outdated)
+	if count := countSeries(); count != 2 {
+		t.Errorf("expected series for outdated container, got=%d", count)
+	}
+	if count := countPostureSeries(); count != 4 {
+		t.Errorf("expected posture series for both containers, got=%d", count)
+	}
+
+	// Once the container becomes latest, its version series should be
+	// removed, keeping its posture series
+	m.AddImage(testEntry("init", "0.2.0"))
+	if count := countSeries(); count != 0 {
+		t.Errorf("expected series to be removed once latest, got=%d", count)
+	}
+	if count := countPostureSeries(); count != 4 {
+		t.Errorf("expected posture series to be kept once latest, got=%d", count)
+	}
+
+	if v := testutil.ToFloat64(m.containersTracked.WithLabelValues("")); v != 2 {
+		t.Errorf("expected all checked containers to be tracked, exp=2 got=%v", v)
+	}
+
+	m.RemoveImage("", "namespace", "pod", "container", "init")
+	m.RemoveImage("", "namespace", "pod", "container", "container")
+	if v := testutil.ToFloat64(m.containersTracked.WithLabelValues("")); v != 0 {
+		t.Errorf("expected no tracked containers, got=%v", v)
+	}
+	if count := countPostureSeries(); count != 0 {
+		t.Errorf("expected posture series to be removed with the containers, got=%d", count)
+	}
+}
+
+func TestContainersTracked(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	m.AddImage(testEntry("container", "0.1.0"))
+	m.AddImage(testEntry("container", "0.1.1"))
+	m.AddImage(testEntry("init", "0.1.0"))
+
+	entry := testEntry("container", "0.1.0")
+	entry.Cluster = "workload-1"
+	m.AddImage(entry)
+
+	for cluster, exp := range map[string]float64{"": 2, "workload-1": 1} {
+		if v := testutil.ToFloat64(m.containersTracked.WithLabelValues(cluster)); v != exp {
+			t.Errorf("%q: unexpected tracked containers, exp=%v got=%v", cluster, exp, v)
+		}
+	}
+	if count := testutil.CollectAndCount(m.containerImageVersion); count != 3 {
+		t.Errorf("expected all containers to be exported, got=%d", count)
+	}
+}
+
+func TestObservePodCheckDuration(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+
+	m.ObservePodCheckDuration("", "default", time.Second)
+	m.ObservePodCheckDuration("", "default", time.Second*3)
+	m.ObservePodCheckDuration("", "kube-system", time.Millisecond*50)
+
+	if count := testutil.CollectAndCount(m.podCheckDuration); count != 2 {
+		t.Errorf("expected a series per namespace, got=%d", count)
+	}
+
+	var metric dto.Metric
+	if err := m.podCheckDuration.WithLabelValues("", "default").(prometheus.Histogram).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	if count, sum := metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum(); count != 2 || sum != 4 {
+		t.Errorf("unexpected observations, exp=2 totalling 4s got=%d totalling %vs", count, sum)
+	}
+}
+
+func TestMetricsHandlerFormat(t *testing.T) {
+	m := New(logrus.NewEntry(logrus.New()), prometheus.NewRegistry(), Options{})
+	m.ObservePodCheckDuration("", "default", time.Second)
+
+	tests := map[string]struct {
+		accept         string
+		expContentType string
+		expEOF         bool
+	}{
+		"scrapers not requesting OpenMetrics should get the Prometheus text format": {
+			accept:         "",
+			expContentType: "text/plain; version=0.0.4",
+		},
+		"scrapers requesting OpenMetrics should get the OpenMetrics format": {
+			accept:         "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5",
+			expContentType: "application/openmetrics-text; version=1.0.0",
+			expEOF:         true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if len(test.accept) > 0 {
+				req.Header.Set("Accept", test.accept)
+			}
+			rec := httptest.NewRecorder()
+			m.metricsHandler().ServeHTTP(rec, req)
+
+			if contentType := rec.Header().Get("Content-Type"); !strings.HasPrefix(contentType, test.expContentType) {
+				t.Errorf("unexpected content type, exp=%q got=%q", test.expContentType, contentType)
+			}
+			if eof := strings.HasSuffix(rec.Body.String(), "# EOF\n"); eof != test.expEOF {
+				t.Errorf("unexpected EOF marker, exp=%t got=%t", test.expEOF, eof)
+			}
+			if !strings.Contains(rec.Body.String(), "version_checker_pod_check_duration_seconds_bucket") {
+				t.Errorf("expected pod check duration histogram, got=%s", rec.Body.String())
+			}
+		})
+	}
+}
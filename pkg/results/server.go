@@ -0,0 +1,163 @@
+package results
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/jetstack/version-checker/pkg/metrics"
+	resultsv1 "github.com/jetstack/version-checker/pkg/results/v1"
+)
+
+const (
+	// subscriberBuffer is the number of events a subscriber may fall behind,
+	// before being disconnected.
+	subscriberBuffer = 256
+)
+
+// Server is a gRPC server which streams the results of container image
+// version checks.
+type Server struct {
+	resultsv1.UnimplementedResultsServer
+
+	log     *logrus.Entry
+	metrics *metrics.Metrics
+	server  *grpc.Server
+}
+
+// New returns a new Server, streaming the results held by the given metrics.
+func New(log *logrus.Entry, metrics *metrics.Metrics) *Server {
+	s := &Server{
+		log:     log.WithField("module", "results"),
+		metrics: metrics,
+		server:  grpc.NewServer(),
+	}
+
+	resultsv1.RegisterResultsServer(s.server, s)
+
+	return s
+}
+
+// Run will start serving on the given address.
+func (s *Server) Run(servingAddress string) error {
+	ln, err := net.Listen("tcp", servingAddress)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		s.log.Infof("serving results gRPC API on %s", ln.Addr())
+
+		if err := s.server.Serve(ln); err != nil {
+			s.log.Errorf("failed to serve results gRPC API: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+// Shutdown will gracefully stop the server, waiting for active streams until
+// the given context is done, after which they are cancelled.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.log.Info("shutting down results gRPC API server...")
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.server.Stop()
+		return fmt.Errorf("results gRPC API server shutdown failed: %s", ctx.Err())
+	}
+
+	s.log.Info("results gRPC API server gracefully stopped")
+
+	return nil
+}
+
+// Subscribe streams an event for each current result, followed by an event
+// for every change to the results.
+func (s *Server) Subscribe(req *resultsv1.SubscribeRequest, stream resultsv1.Results_SubscribeServer) error {
+	events, unsubscribe := s.metrics.Subscribe(subscriberBuffer)
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber fell too far behind, resubscribe to receive the current results")
+			}
+
+			if len(req.GetNamespace()) > 0 && event.Entry.Namespace != req.GetNamespace() {
+				continue
+			}
+			if len(req.GetCluster()) > 0 && event.Entry.Cluster != req.GetCluster() {
+				continue
+			}
+
+			if err := stream.Send(toResultEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toResultEvent converts the given metrics event to its API type.
+func toResultEvent(event metrics.Event) *resultsv1.ResultEvent {
+	eventType := resultsv1.ResultEvent_TYPE_CHECKED
+	if event.Type == metrics.EventTypeRemoved {
+		eventType = resultsv1.ResultEvent_TYPE_REMOVED
+	}
+
+	entry := event.Entry
+	result := &resultsv1.Result{
+		Namespace:      entry.Namespace,
+		Pod:            entry.Pod,
+		Container:      entry.Container,
+		ContainerType:  entry.ContainerType,
+		ImageUrl:       entry.ImageURL,
+		IsLatest:       entry.IsLatest,
+		CurrentVersion: entry.CurrentVersion,
+		LatestVersion:  entry.LatestVersion,
+		Os:             entry.OS,
+		Arch:           entry.Arch,
+		PlatformSource: entry.PlatformSource,
+		PinnedByDigest: entry.PinnedByDigest,
+
+		AbsoluteLatestVersion: entry.AbsoluteLatestVersion,
+		IsAbsoluteLatest:      entry.IsAbsoluteLatest,
+		IsAheadOfRegistry:     entry.IsAheadOfRegistry,
+
+		Cluster:      entry.Cluster,
+		Severity:     entry.Severity,
+		ArtifactType: entry.ArtifactType,
+	}
+	if !entry.LastChecked.IsZero() {
+		result.LastChecked = timestamppb.New(entry.LastChecked)
+	}
+	if !entry.LastChanged.IsZero() {
+		result.LastChanged = timestamppb.New(entry.LastChanged)
+	}
+	if entry.VersionsBehind != nil {
+		versionsBehind := int32(*entry.VersionsBehind)
+		result.VersionsBehind = &versionsBehind
+	}
+
+	return &resultsv1.ResultEvent{
+		Type:   eventType,
+		Result: result,
+	}
+}
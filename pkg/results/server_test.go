@@ -0,0 +1,94 @@
+package results
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/jetstack/version-checker/pkg/metrics"
+	resultsv1 "github.com/jetstack/version-checker/pkg/results/v1"
+)
+
+func TestSubscribe(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	m := metrics.New(log, prometheus.NewRegistry(), metrics.Options{})
+
+	lastChecked := time.Unix(1700000000, 0).UTC()
+	m.AddImage(metrics.Entry{
+		Namespace: "default", Pod: "pod", Container: "existing", ContainerType: "container",
+		ImageURL: "nginx", CurrentVersion: "1.0.0", LatestVersion: "1.1.0", LastChecked: lastChecked,
+	})
+	m.AddImage(metrics.Entry{
+		Namespace: "other", Pod: "pod", Container: "filtered", ContainerType: "container",
+	})
+
+	client := newTestClient(t, m)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := client.Subscribe(ctx, &resultsv1.SubscribeRequest{Namespace: "default"})
+	require.NoError(t, err)
+
+	// Current results should be sent first
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, resultsv1.ResultEvent_TYPE_CHECKED, event.GetType())
+	assert.Equal(t, "existing", event.GetResult().GetContainer())
+	assert.Equal(t, "nginx", event.GetResult().GetImageUrl())
+	assert.Equal(t, "1.1.0", event.GetResult().GetLatestVersion())
+	assert.Equal(t, lastChecked, event.GetResult().GetLastChecked().AsTime())
+
+	// Changes should be streamed, without results of other namespaces
+	m.AddImage(metrics.Entry{
+		Namespace: "other", Pod: "pod", Container: "filtered", ContainerType: "container", IsLatest: true,
+	})
+	m.AddImage(metrics.Entry{
+		Namespace: "default", Pod: "pod", Container: "existing", ContainerType: "container",
+		ImageURL: "nginx", CurrentVersion: "1.1.0", LatestVersion: "1.1.0", IsLatest: true,
+	})
+	m.RemoveImage("", "default", "pod", "existing", "container")
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, resultsv1.ResultEvent_TYPE_CHECKED, event.GetType())
+	assert.Equal(t, "existing", event.GetResult().GetContainer())
+	assert.True(t, event.GetResult().GetIsLatest())
+	assert.Nil(t, event.GetResult().GetLastChecked())
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, resultsv1.ResultEvent_TYPE_REMOVED, event.GetType())
+	assert.Equal(t, "existing", event.GetResult().GetContainer())
+}
+
+func newTestClient(t *testing.T, m *metrics.Metrics) resultsv1.ResultsClient {
+	t.Helper()
+
+	ln := bufconn.Listen(1 << 20)
+	s := New(logrus.NewEntry(logrus.New()), m)
+	go func() {
+		_ = s.server.Serve(ln)
+	}()
+	t.Cleanup(s.server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return resultsv1.NewResultsClient(conn)
+}
@@ -0,0 +1,132 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: results.proto
+
+package resultsv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Results_Subscribe_FullMethodName = "/versionchecker.results.v1.Results/Subscribe"
+)
+
+// ResultsClient is the client API for Results service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Results streams the results of container image version checks.
+type ResultsClient interface {
+	// Subscribe streams an event for each current result, followed by an event
+	// for every result as the results of containers change or are removed.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ResultEvent], error)
+}
+
+type resultsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewResultsClient(cc grpc.ClientConnInterface) ResultsClient {
+	return &resultsClient{cc}
+}
+
+func (c *resultsClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ResultEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Results_ServiceDesc.Streams[0], Results_Subscribe_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeRequest, ResultEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Results_SubscribeClient = grpc.ServerStreamingClient[ResultEvent]
+
+// ResultsServer is the server API for Results service.
+// All implementations must embed UnimplementedResultsServer
+// for forward compatibility.
+//
+// Results streams the results of container image version checks.
+type ResultsServer interface {
+	// Subscribe streams an event for each current result, followed by an event
+	// for every result as the results of containers change or are removed.
+	Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[ResultEvent]) error
+	mustEmbedUnimplementedResultsServer()
+}
+
+// UnimplementedResultsServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedResultsServer struct{}
+
+func (UnimplementedResultsServer) Subscribe(*SubscribeRequest, grpc.ServerStreamingServer[ResultEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedResultsServer) mustEmbedUnimplementedResultsServer() {}
+func (UnimplementedResultsServer) testEmbeddedByValue()                 {}
+
+// UnsafeResultsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ResultsServer will
+// result in compilation errors.
+type UnsafeResultsServer interface {
+	mustEmbedUnimplementedResultsServer()
+}
+
+func RegisterResultsServer(s grpc.ServiceRegistrar, srv ResultsServer) {
+	// If the following call pancis, it indicates UnimplementedResultsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Results_ServiceDesc, srv)
+}
+
+func _Results_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ResultsServer).Subscribe(m, &grpc.GenericServerStream[SubscribeRequest, ResultEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Results_SubscribeServer = grpc.ServerStreamingServer[ResultEvent]
+
+// Results_ServiceDesc is the grpc.ServiceDesc for Results service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Results_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "versionchecker.results.v1.Results",
+	HandlerType: (*ResultsServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Results_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "results.proto",
+}
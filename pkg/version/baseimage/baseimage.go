@@ -0,0 +1,112 @@
+package baseimage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/cache"
+	"github.com/jetstack/version-checker/pkg/client"
+)
+
+const (
+	// NameLabel is the OCI label of an image, declaring the reference of the
+	// image it was built from.
+	NameLabel = "org.opencontainers.image.base.name"
+
+	// DigestLabel is the OCI label of an image, declaring the digest of the
+	// image it was built from.
+	DigestLabel = "org.opencontainers.image.base.digest"
+)
+
+// BaseImage is the base image declared by the labels of an image.
+type BaseImage struct {
+	// Name is the reference of the base image, e.g. docker.io/library/alpine:3.19
+	Name string
+
+	// Digest is the digest of the base image, if declared.
+	Digest string
+}
+
+// Resolver resolves the base images declared by the config labels of images.
+type Resolver struct {
+	log *logrus.Entry
+
+	client     *client.Client
+	remoteOpts []remote.Option
+	cache      *cache.Cache
+}
+
+// New will return a new Resolver. Images are fetched from where the client
+// lists their tags, after any rewrite rules, with the credentials of the
+// client. Resolved base images are cached for the cache timeout.
+func New(log *logrus.Entry, client *client.Client, cacheTimeout time.Duration) *Resolver {
+	r := &Resolver{
+		log:    log.WithField("module", "baseimage"),
+		client: client,
+		remoteOpts: []remote.Option{
+			remote.WithAuthFromKeychain(client.Keychain()),
+		},
+	}
+
+	r.cache = cache.New(r.log, cacheTimeout, r)
+
+	return r
+}
+
+// Run is a blocking func that will start the base image cache garbage
+// collector.
+func (r *Resolver) Run(refreshRate time.Duration) {
+	r.cache.StartGarbageCollector(refreshRate)
+}
+
+// BaseImage will return the base image declared by the labels of the given
+// image reference, of the form <image>@<digest>. Nil is returned if the image
+// does not declare a base image.
+func (r *Resolver) BaseImage(ctx context.Context, imageRef string) (*BaseImage, error) {
+	imageURL, digest, _ := strings.Cut(imageRef, "@")
+	imageRef = r.client.ResolveImageURL(imageURL) + "@" + digest
+
+	baseImage, err := r.cache.Get(ctx, imageRef, imageRef, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return baseImage.(*BaseImage), nil
+}
+
+// Fetch will fetch the config of the given image reference, and return the
+// declared base image.
+func (r *Resolver) Fetch(ctx context.Context, imageRef string, _ *api.Options) (interface{}, error) {
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %q: %s", imageRef, err)
+	}
+
+	img, err := remote.Image(ref, append(r.remoteOpts, remote.WithContext(ctx))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image %q: %s", imageRef, err)
+	}
+
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image config %q: %s", imageRef, err)
+	}
+
+	baseName := config.Config.Labels[NameLabel]
+	if len(baseName) == 0 {
+		r.log.Debugf("%s: image does not declare a base image", imageRef)
+		return (*BaseImage)(nil), nil
+	}
+
+	return &BaseImage{
+		Name:   baseName,
+		Digest: config.Config.Labels[DigestLabel],
+	}, nil
+}
@@ -0,0 +1,149 @@
+package baseimage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/client/selfhosted"
+)
+
+func TestBaseImage(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	imageURL := u.Host + "/foo/bar"
+
+	tests := map[string]struct {
+		labels       map[string]string
+		expBaseImage *BaseImage
+	}{
+		"image without labels should have no base image": {
+			labels:       nil,
+			expBaseImage: nil,
+		},
+		"image without the base name label should have no base image": {
+			labels: map[string]string{
+				"org.opencontainers.image.version": "v1.0.0",
+				DigestLabel:                        "sha256:abc",
+			},
+			expBaseImage: nil,
+		},
+		"image with the base name label should return the base image": {
+			labels: map[string]string{
+				NameLabel: "docker.io/library/alpine:3.19",
+			},
+			expBaseImage: &BaseImage{
+				Name: "docker.io/library/alpine:3.19",
+			},
+		},
+		"image with the base name and digest labels should return both": {
+			labels: map[string]string{
+				NameLabel:   "docker.io/library/alpine:3.19",
+				DigestLabel: "sha256:abc",
+			},
+			expBaseImage: &BaseImage{
+				Name:   "docker.io/library/alpine:3.19",
+				Digest: "sha256:abc",
+			},
+		},
+	}
+
+	log := logrus.NewEntry(logrus.New())
+	client, err := client.New(context.TODO(), log, client.Options{})
+	require.NoError(t, err)
+	resolver := New(log, client, time.Minute)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			digest := writeImage(t, imageURL, test.labels)
+
+			baseImage, err := resolver.BaseImage(context.TODO(), imageURL+"@"+digest)
+			require.NoError(t, err)
+			assert.Equal(t, test.expBaseImage, baseImage)
+		})
+	}
+
+	t.Run("missing image should error", func(t *testing.T) {
+		_, err := resolver.BaseImage(context.TODO(), imageURL+"@sha256:0000000000000000000000000000000000000000000000000000000000000000")
+		assert.Error(t, err)
+	})
+}
+
+func TestBaseImageRegistryClient(t *testing.T) {
+	reg := registry.New()
+	authorized := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorized && r.Header.Get("Authorization") != "Bearer registry-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="https://auth.example.com/token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	authorized = false
+	digest := writeImage(t, u.Host+"/foo/bar", map[string]string{NameLabel: "docker.io/library/alpine:3.19"})
+	authorized = true
+
+	// The image is fetched from the rewritten registry, with its token
+	log := logrus.NewEntry(logrus.New())
+	client, err := client.New(context.TODO(), log, client.Options{
+		Selfhosted: map[string]*selfhosted.Options{
+			"registry": {Host: server.URL, Bearer: "registry-token"},
+		},
+		RewriteRules: []client.RewriteRule{
+			{Regex: regexp.MustCompile(`^example\.com/(.+)$`), Replacement: u.Host + "/$1"},
+		},
+	})
+	require.NoError(t, err)
+
+	baseImage, err := New(log, client, time.Minute).BaseImage(context.TODO(), "example.com/foo/bar@"+digest)
+	require.NoError(t, err)
+	assert.Equal(t, &BaseImage{Name: "docker.io/library/alpine:3.19"}, baseImage)
+}
+
+// writeImage will write a random image with the given config labels, returning
+// its digest.
+func writeImage(t *testing.T, imageURL string, labels map[string]string) string {
+	t.Helper()
+
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+
+	config, err := img.ConfigFile()
+	require.NoError(t, err)
+	config.Config.Labels = labels
+
+	img, err = mutate.ConfigFile(img, config)
+	require.NoError(t, err)
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	ref, err := name.ParseReference(imageURL + "@" + digest.String())
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	return digest.String()
+}
@@ -0,0 +1,78 @@
+package datesha
+
+import (
+	"time"
+)
+
+var (
+	// defaultLayouts are the date prefix layouts tried in order when no layout
+	// is given, being YYYYMMDD[HHMMSS].
+	defaultLayouts = []string{"20060102150405", "20060102"}
+)
+
+// DateSHA is a version made up of a leading date, followed by an ignored
+// suffix such as a commit SHA, e.g. 20240312-a1b2c3d.
+type DateSHA struct {
+	original string
+	date     time.Time
+}
+
+// Parse will parse the leading date of the given tag, using the given date
+// layout. The layout must be fixed width, such as 20060102. If layout is
+// empty, YYYYMMDD[HHMMSS] is used. Returns false if the tag does not begin
+// with a date of the layout.
+func Parse(tag, layout string) (*DateSHA, bool) {
+	layouts := defaultLayouts
+	if len(layout) > 0 {
+		layouts = []string{layout}
+	}
+
+	for _, layout := range layouts {
+		if len(tag) < len(layout) {
+			continue
+		}
+
+		// The date must not run on into more digits, else 202403121 would be
+		// parsed as 20240312.
+		if len(tag) > len(layout) && isDigit(tag[len(layout)]) {
+			continue
+		}
+
+		date, err := time.Parse(layout, tag[:len(layout)])
+		if err != nil {
+			continue
+		}
+
+		return &DateSHA{
+			original: tag,
+			date:     date,
+		}, true
+	}
+
+	return nil, false
+}
+
+// Date returns the parsed date of the version.
+func (d *DateSHA) Date() time.Time {
+	return d.date
+}
+
+// LessThan will return true if the given version has a later date.
+func (d *DateSHA) LessThan(other *DateSHA) bool {
+	return d.date.Before(other.date)
+}
+
+// Equal will return true if both versions have the same date. The suffix
+// is not compared.
+func (d *DateSHA) Equal(other *DateSHA) bool {
+	return d.date.Equal(other.date)
+}
+
+// String returns the original tag of the version.
+func (d *DateSHA) String() string {
+	return d.original
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
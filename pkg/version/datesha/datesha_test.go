@@ -0,0 +1,94 @@
+package datesha
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		tag     string
+		layout  string
+		expOK   bool
+		expDate time.Time
+	}{
+		"empty tag should not parse": {
+			tag:   "",
+			expOK: false,
+		},
+		"tag without a date should not parse": {
+			tag:   "main-a1b2c3d",
+			expOK: false,
+		},
+		"semver tag should not parse": {
+			tag:   "v1.2.3",
+			expOK: false,
+		},
+		"date and sha should parse": {
+			tag:     "20240312-a1b2c3d",
+			expOK:   true,
+			expDate: time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC),
+		},
+		"date only should parse": {
+			tag:     "20240312",
+			expOK:   true,
+			expDate: time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC),
+		},
+		"date and time and sha should parse": {
+			tag:     "20240312153045-a1b2c3d",
+			expOK:   true,
+			expDate: time.Date(2024, time.March, 12, 15, 30, 45, 0, time.UTC),
+		},
+		"date running on into more digits should not parse": {
+			tag:   "202403121-a1b2c3d",
+			expOK: false,
+		},
+		"invalid date should not parse": {
+			tag:   "20241312-a1b2c3d",
+			expOK: false,
+		},
+		"custom layout should parse": {
+			tag:     "2024-03-12_a1b2c3d",
+			layout:  "2006-01-02",
+			expOK:   true,
+			expDate: time.Date(2024, time.March, 12, 0, 0, 0, 0, time.UTC),
+		},
+		"custom layout should not parse the default layout": {
+			tag:    "20240312-a1b2c3d",
+			layout: "2006-01-02",
+			expOK:  false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			v, ok := Parse(test.tag, test.layout)
+			if ok != test.expOK {
+				t.Fatalf("unexpected ok, exp=%t got=%t", test.expOK, ok)
+			}
+			if !ok {
+				return
+			}
+
+			if !v.Date().Equal(test.expDate) {
+				t.Errorf("unexpected date, exp=%s got=%s", test.expDate, v.Date())
+			}
+			if v.String() != test.tag {
+				t.Errorf("expected original tag to be preserved, exp=%s got=%s", test.tag, v.String())
+			}
+		})
+	}
+}
+
+func TestLessThanEqual(t *testing.T) {
+	older, _ := Parse("20240312-ffffff", "")
+	newer, _ := Parse("20240312000001-000000", "")
+	sameDay, _ := Parse("20240312-aaaaaa", "")
+
+	if !older.LessThan(newer) || newer.LessThan(older) {
+		t.Errorf("expected %s to be less than %s", older, newer)
+	}
+	if !older.Equal(sameDay) || older.LessThan(sameDay) || sameDay.LessThan(older) {
+		t.Errorf("expected %s to equal %s, ignoring the suffix", older, sameDay)
+	}
+}
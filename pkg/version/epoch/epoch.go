@@ -0,0 +1,70 @@
+package epoch
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jetstack/version-checker/pkg/version/semver"
+)
+
+// Separator separates the epoch from the rest of the version of a tag. Tags
+// may not contain the colon of Debian package versions, so 2:1.4.0 is tagged
+// as 2_1.4.0.
+const Separator = "_"
+
+// Epoch is a version with an optional leading epoch, as of Debian package
+// versions, e.g. 2_1.4.0. The epoch takes precedence over the rest of the
+// version, which is compared as a semantic version. Versions without an
+// epoch are of epoch 0.
+type Epoch struct {
+	original string
+	epoch    uint64
+	version  *semver.SemVer
+}
+
+// Parse will parse the given tag, with the rest of the version after the
+// epoch parsed by the given parser. A leading epoch is only parsed if it is
+// all digits, else the whole tag is the version of epoch 0.
+func Parse(tag string, parser semver.Parser) *Epoch {
+	e := &Epoch{original: tag}
+
+	version := tag
+	if before, after, ok := strings.Cut(tag, Separator); ok {
+		if epoch, err := strconv.ParseUint(before, 10, 64); err == nil {
+			e.epoch, version = epoch, after
+		}
+	}
+	e.version = parser.Parse(version)
+
+	return e
+}
+
+// Epoch returns the epoch of the version.
+func (e *Epoch) Epoch() uint64 {
+	return e.epoch
+}
+
+// Version returns the version after the epoch.
+func (e *Epoch) Version() *semver.SemVer {
+	return e.version
+}
+
+// LessThan will return true if the given version has a greater epoch, or the
+// same epoch and a greater version.
+func (e *Epoch) LessThan(other *Epoch) bool {
+	if e.epoch != other.epoch {
+		return e.epoch < other.epoch
+	}
+
+	return e.version.LessThan(other.version)
+}
+
+// Equal will return true if both versions have the same epoch and version.
+func (e *Epoch) Equal(other *Epoch) bool {
+	return e.epoch == other.epoch && e.version.Equal(other.version)
+}
+
+// String returns the original tag of the version.
+func (e *Epoch) String() string {
+	return e.original
+}
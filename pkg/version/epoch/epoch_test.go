@@ -0,0 +1,93 @@
+package epoch
+
+import (
+	"testing"
+
+	ggcrname "github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/jetstack/version-checker/pkg/version/semver"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		tag        string
+		expEpoch   uint64
+		expVersion string
+	}{
+		"tag without an epoch should be of epoch 0": {
+			tag:        "1.4.0",
+			expEpoch:   0,
+			expVersion: "1.4.0",
+		},
+		"tag with an epoch should parse": {
+			tag:        "2_1.4.0",
+			expEpoch:   2,
+			expVersion: "1.4.0",
+		},
+		"tag with an epoch and a revision should parse": {
+			tag:        "1_1.9.0-3",
+			expEpoch:   1,
+			expVersion: "1.9.0-3",
+		},
+		"tag with a non-numeric epoch should be of epoch 0": {
+			tag:        "v1_1.4.0",
+			expEpoch:   0,
+			expVersion: "v1_1.4.0",
+		},
+		"tag with an empty epoch should be of epoch 0": {
+			tag:        "_1.4.0",
+			expEpoch:   0,
+			expVersion: "_1.4.0",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := Parse(test.tag, semver.Parser{})
+			if v.Epoch() != test.expEpoch {
+				t.Errorf("unexpected epoch, exp=%d got=%d", test.expEpoch, v.Epoch())
+			}
+			if v.Version().String() != test.expVersion {
+				t.Errorf("unexpected version, exp=%s got=%s", test.expVersion, v.Version())
+			}
+			if v.String() != test.tag {
+				t.Errorf("expected original tag to be preserved, exp=%s got=%s", test.tag, v.String())
+			}
+			if _, err := ggcrname.NewTag("example.com/image:" + test.tag); err != nil {
+				t.Errorf("expected a valid image tag: %s", err)
+			}
+		})
+	}
+}
+
+func TestLessThan(t *testing.T) {
+	tests := map[string]struct {
+		lower, higher string
+	}{
+		"an epoch should beat a greater version without one": {
+			lower:  "2.5.0",
+			higher: "1_1.0.0",
+		},
+		"a greater epoch should beat a greater version": {
+			lower:  "1_1.9.0",
+			higher: "2_1.4.0",
+		},
+		"versions of the same epoch should be compared as semver": {
+			lower:  "2_1.4.0",
+			higher: "2_1.10.0",
+		},
+		"an epoch of 0 should equal no epoch": {
+			lower:  "0_1.4.0",
+			higher: "1.5.0",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			lower, higher := Parse(test.lower, semver.Parser{}), Parse(test.higher, semver.Parser{})
+			if !lower.LessThan(higher) || higher.LessThan(lower) {
+				t.Errorf("expected %s to be less than %s", lower, higher)
+			}
+		})
+	}
+}
@@ -21,3 +21,20 @@ func IsNoVersionFound(err error) bool {
 	var notFound *ErrorVersionNotFound
 	return errors.As(err, &notFound)
 }
+
+type ErrorSignatureNotFound struct {
+	error
+}
+
+func NewSignatureErrorNotFound(format string, a ...interface{}) *ErrorSignatureNotFound {
+	if len(a) == 0 {
+		return &ErrorSignatureNotFound{errors.New(format)}
+	}
+
+	return &ErrorSignatureNotFound{fmt.Errorf(format, a...)}
+}
+
+func IsNoSignatureFound(err error) bool {
+	var notFound *ErrorSignatureNotFound
+	return errors.As(err, &notFound)
+}
@@ -0,0 +1,136 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/cache"
+	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/client/util"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+// Prober probes whether the manifests of image tags can be fetched, so that
+// tags which are listed by a registry, but whose manifest is missing, such as
+// of an incomplete mirror, are not selected as the latest.
+type Prober struct {
+	log *logrus.Entry
+
+	client  *client.Client
+	metrics *metrics.Metrics
+
+	remoteOpts []remote.Option
+	cache      *cache.Cache
+}
+
+// New will return a new Prober. Manifests are fetched with the credentials
+// of the client for the registry host. Probe results are cached for the cache
+// timeout. Metrics may be nil.
+func New(log *logrus.Entry, client *client.Client, cacheTimeout time.Duration, metrics *metrics.Metrics) *Prober {
+	p := &Prober{
+		log:     log.WithField("module", "manifest"),
+		client:  client,
+		metrics: metrics,
+		remoteOpts: []remote.Option{
+			remote.WithAuthFromKeychain(client.Keychain()),
+		},
+	}
+
+	p.cache = cache.New(p.log, cacheTimeout, p)
+
+	return p
+}
+
+// Run is a blocking func that will start the probe cache garbage collector.
+func (p *Prober) Run(refreshRate time.Duration) {
+	p.cache.StartGarbageCollector(refreshRate)
+}
+
+// Reachable will return true if the manifest of the given image tag can be
+// fetched from the image repository. Tags without a name are referenced by
+// their digest.
+func (p *Prober) Reachable(ctx context.Context, imageURL string, tag *api.ImageTag) (bool, error) {
+	index := imageURL + ":" + tag.Tag
+	if len(tag.Tag) == 0 {
+		index = imageURL + "@" + tag.SHA
+	}
+
+	reachable, err := p.cache.Get(ctx, index, index, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return reachable.(bool), nil
+}
+
+// ReferenceReachable will return true if the manifest of the given image
+// reference, such as of the image of a container, can be fetched from the
+// image repository its tags are listed from. Access being denied to the
+// manifest is an error, rather than the manifest not being reachable.
+func (p *Prober) ReferenceReachable(ctx context.Context, reference string) (bool, error) {
+	imageURL, ref := reference, ""
+	if i := strings.Index(reference, "@"); i > -1 {
+		imageURL, ref = reference[:i], reference[i:]
+	} else if i := strings.LastIndex(reference, ":"); i > strings.LastIndex(reference, "/") {
+		imageURL, ref = reference[:i], reference[i:]
+	}
+	reference = p.client.ResolveImageURL(imageURL) + ref
+
+	reachable, err := p.cache.Get(ctx, reference, reference, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return reachable.(bool), nil
+}
+
+// ObserveFallback will record that the latest tag of the given image was
+// not reachable, and a lower tag was selected instead.
+func (p *Prober) ObserveFallback(imageURL string) {
+	if p.metrics == nil {
+		return
+	}
+
+	host := imageURL
+	if repo, err := name.NewRepository(imageURL); err == nil {
+		host = repo.RegistryStr()
+	}
+	p.metrics.IncStrictLatestFallbacks(host)
+}
+
+// Fetch will fetch the manifest descriptor of the given image reference, of
+// the form <image>:<tag> or <image>@<digest>, returning false if it is not
+// found. Access being denied to the manifest is an error of its own, so that
+// it is not mistaken for a failed manifest.
+func (p *Prober) Fetch(ctx context.Context, index string, _ *api.Options) (interface{}, error) {
+	ref, err := name.ParseReference(index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference %q: %s", index, err)
+	}
+
+	if _, err := remote.Head(ref, append(p.remoteOpts, remote.WithContext(ctx))...); err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			p.log.Debugf("%s: manifest not found", index)
+			return false, nil
+		}
+
+		if util.IsAuthDenied(err) {
+			return nil, fmt.Errorf("access to manifest %q was denied, check the registry credentials: %w", index, err)
+		}
+
+		return nil, fmt.Errorf("failed to get manifest %q: %s", index, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,227 @@
+package manifest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/client/selfhosted"
+	"github.com/jetstack/version-checker/pkg/client/util"
+	"github.com/jetstack/version-checker/pkg/metrics"
+)
+
+func TestReachable(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	imageURL := u.Host + "/foo/bar"
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	ref, err := name.ParseReference(imageURL + ":v1.0.0")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	log := logrus.NewEntry(logrus.New())
+	reg := prometheus.NewRegistry()
+	prober := New(log, newClient(t, client.Options{}), time.Minute, metrics.New(log, reg, metrics.Options{}))
+
+	tests := map[string]struct {
+		tag          *api.ImageTag
+		expReachable bool
+	}{
+		"tags with a manifest should be reachable": {
+			tag:          &api.ImageTag{Tag: "v1.0.0"},
+			expReachable: true,
+		},
+		"tags without a manifest should not be reachable": {
+			tag:          &api.ImageTag{Tag: "v2.0.0"},
+			expReachable: false,
+		},
+		"digests with a manifest should be reachable": {
+			tag:          &api.ImageTag{SHA: digest.String()},
+			expReachable: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			reachable, err := prober.Reachable(context.TODO(), imageURL, test.tag)
+			require.NoError(t, err)
+			assert.Equal(t, test.expReachable, reachable)
+		})
+	}
+
+	prober.ObserveFallback(imageURL)
+	assert.Equal(t, 1, testutil.CollectAndCount(reg, "version_checker_strict_latest_fallbacks_total"))
+}
+
+func TestReferenceReachable(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	ref, err := name.ParseReference(u.Host + "/foo/bar:v1.0.0")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	denied := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer denied.Close()
+
+	deniedURL, err := url.Parse(denied.URL)
+	require.NoError(t, err)
+
+	prober := New(logrus.NewEntry(logrus.New()), newClient(t, client.Options{}), time.Minute, nil)
+
+	tests := map[string]struct {
+		reference    string
+		expReachable bool
+		expErr       bool
+	}{
+		"references with a manifest should be reachable": {
+			reference:    u.Host + "/foo/bar:v1.0.0",
+			expReachable: true,
+		},
+		"references without a manifest should not be reachable": {
+			reference:    u.Host + "/foo/bar:v2.0.0",
+			expReachable: false,
+		},
+		"references of a repository which does not exist should not be reachable": {
+			reference:    u.Host + "/foo/deleted:v1.0.0",
+			expReachable: false,
+		},
+		"references which access is denied to should error": {
+			reference: deniedURL.Host + "/foo/bar:v1.0.0",
+			expErr:    true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			reachable, err := prober.ReferenceReachable(context.TODO(), test.reference)
+			if test.expErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expReachable, reachable)
+		})
+	}
+}
+
+func TestReachableRegistryCredentials(t *testing.T) {
+	reg := registry.New()
+	authorized := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Anonymous tokens are not issued
+		if authorized && r.Header.Get("Authorization") != "Bearer registry-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	imageURL := u.Host + "/foo/bar"
+
+	authorized = false
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	ref, err := name.ParseReference(imageURL + ":v1.0.0")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+	authorized = true
+
+	log := logrus.NewEntry(logrus.New())
+
+	t.Run("manifests should be fetched with the credentials of the registry", func(t *testing.T) {
+		prober := New(log, newClient(t, client.Options{
+			Selfhosted: map[string]*selfhosted.Options{
+				"registry": {Host: server.URL, Bearer: "registry-token"},
+			},
+		}), time.Minute, nil)
+
+		reachable, err := prober.Reachable(context.TODO(), imageURL, &api.ImageTag{Tag: "v1.0.0"})
+		require.NoError(t, err)
+		assert.True(t, reachable)
+	})
+
+	t.Run("manifests which access is denied to should error, rather than fail", func(t *testing.T) {
+		prober := New(log, newClient(t, client.Options{}), time.Minute, nil)
+
+		_, err := prober.Reachable(context.TODO(), imageURL, &api.ImageTag{Tag: "v1.0.0"})
+		assert.ErrorContains(t, err, "was denied, check the registry credentials")
+		assert.True(t, util.IsAuthDenied(err))
+	})
+
+	t.Run("references should be fetched from the rewritten registry, with its credentials", func(t *testing.T) {
+		prober := New(log, newClient(t, client.Options{
+			Selfhosted: map[string]*selfhosted.Options{
+				"registry": {Host: server.URL, Bearer: "registry-token"},
+			},
+			RewriteRules: []client.RewriteRule{
+				{Regex: regexp.MustCompile(`^example\.com/(.+)$`), Replacement: u.Host + "/$1"},
+			},
+		}), time.Minute, nil)
+
+		digest, err := img.Digest()
+		require.NoError(t, err)
+
+		for _, reference := range []string{"example.com/foo/bar:v1.0.0", "example.com/foo/bar@" + digest.String()} {
+			reachable, err := prober.ReferenceReachable(context.TODO(), reference)
+			require.NoError(t, err, reference)
+			assert.True(t, reachable, reference)
+		}
+
+		reachable, err := prober.ReferenceReachable(context.TODO(), "example.com/foo/bar:v2.0.0")
+		require.NoError(t, err)
+		assert.False(t, reachable)
+	})
+
+	t.Run("references which access is denied to should error, rather than not be found", func(t *testing.T) {
+		prober := New(log, newClient(t, client.Options{}), time.Minute, nil)
+
+		_, err := prober.ReferenceReachable(context.TODO(), imageURL+":v1.0.0")
+		assert.True(t, util.IsAuthDenied(err))
+	})
+}
+
+// newClient returns a registry client of the given options.
+func newClient(t *testing.T, opts client.Options) *client.Client {
+	t.Helper()
+
+	client, err := client.New(context.TODO(), logrus.NewEntry(logrus.New()), opts)
+	require.NoError(t, err)
+
+	return client
+}
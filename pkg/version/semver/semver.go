@@ -2,6 +2,7 @@ package semver
 
 import (
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -21,8 +22,39 @@ type SemVer struct {
 
 	// original holds the origin string of the tag
 	original string
+
+	// valid is true if the tag begins with a version number
+	valid bool
+
+	// preReleaseOrder overrides the comparison of the listed pre-release
+	// identifiers.
+	preReleaseOrder PreReleaseOrder
+
+	// buildSuffix matches the pre-releases which are builds of the version,
+	// such as the 2 of 1.2.3-2, ranked above the version.
+	buildSuffix *regexp.Regexp
 }
 
+// Parser parses tags, to be compared with its options when they are the
+// receiver of LessThan.
+type Parser struct {
+	// PreReleaseOrder overrides the comparison of the listed pre-release
+	// identifiers.
+	PreReleaseOrder PreReleaseOrder
+
+	// BuildSuffix, if set, matches the whole pre-release of versions which
+	// are a build of the version, such as a re-packaging of it, rather than a
+	// pre-release. Builds are ranked above the version, by the number of the
+	// first capture group of the match, or otherwise the whole match.
+	BuildSuffix *regexp.Regexp
+}
+
+// PreReleaseOrder is an ordered list of pre-release identifiers, from the
+// lowest to the highest precedence, which overrides the lexical comparison of
+// those identifiers, e.g. dev, alpha, beta, rc. Identifiers which are not
+// listed are compared according to the spec.
+type PreReleaseOrder []string
+
 func Parse(tag string) *SemVer {
 	s := &SemVer{
 		original: tag,
@@ -41,35 +73,103 @@ func Parse(tag string) *SemVer {
 		}
 	}
 	s.metadata = match[4]
+	s.valid = true
+
+	return s
+}
+
+// Parse will parse the given tag, comparing its pre-release identifiers with
+// this order when it is the receiver of LessThan.
+func (o PreReleaseOrder) Parse(tag string) *SemVer {
+	return Parser{PreReleaseOrder: o}.Parse(tag)
+}
 
+// Parse will parse the given tag, comparing it with the options of the parser
+// when it is the receiver of LessThan.
+func (p Parser) Parse(tag string) *SemVer {
+	s := Parse(tag)
+	s.preReleaseOrder = p.PreReleaseOrder
+	s.buildSuffix = p.BuildSuffix
 	return s
 }
 
-// LessThan will return true if the given semver is equal, or larger that the
-// calling semver. If the calling SemVer has metadata, then ASCII comparison
-// will take place on the version.
-// e.g. v1.0.1-alpha.1 < v1.0.1-beta.0.
+// compare returns -1, 0 or 1 if the identifier a has a lower, equal or higher
+// precedence than b in this order. False is returned if either identifier is
+// not listed.
+func (o PreReleaseOrder) compare(a, b string) (int, bool) {
+	aIndex, bIndex := slices.Index(o, a), slices.Index(o, b)
+	if aIndex == -1 || bIndex == -1 {
+		return 0, false
+	}
+
+	return compareInt(aIndex, bIndex), true
+}
+
+// LessThan will return true if the given semver is larger than the calling
+// semver. Unlike the semver spec, a stable version is larger than any
+// pre-release, of any version, so that stable versions are always preferred.
+// Otherwise, versions are compared by their version numbers, then the
+// pre-releases of the same version by the semver spec precedence of their
+// identifiers.
+// e.g. v1.0.1-alpha.1 < v1.0.1-beta.0 < v1.1.0-alpha.0 < v1.0.0.
 func (s *SemVer) LessThan(other *SemVer) bool {
 	if s.isInvalidComparison(other) {
 		return len(s.original) < len(other.original)
 	}
 
-	// Compare stable vs. pre-release
-	if !s.HasMetaData() && other.HasMetaData() {
+	// Compare stable vs. pre-release, where builds are stable
+	sBuild, otherBuild := s.buildOf(s), s.buildOf(other)
+	sPreRelease := s.HasMetaData() && len(sBuild) == 0
+	otherPreRelease := other.HasMetaData() && len(otherBuild) == 0
+	if !sPreRelease && otherPreRelease {
 		return false
 	}
-	if s.HasMetaData() && !other.HasMetaData() {
+	if sPreRelease && !otherPreRelease {
 		return true
 	}
 
-	// Compare version numbers
-	if s.compareVersionNumbers(other) {
-		return true
+	// Builds of the same version rank above it, by their build number
+	if len(sBuild) > 0 || len(otherBuild) > 0 {
+		if s.version != other.version {
+			return s.compareVersionNumbers(other)
+		}
+
+		return compareNumeric(sBuild, otherBuild) < 0
+	}
+
+	// Compare version numbers, then the pre-release metadata of the same
+	// version
+	if s.version != other.version {
+		return s.compareVersionNumbers(other)
 	}
 
-	// Compare pre-release metadata
 	return s.comparePreReleaseMetadata(other)
 }
+
+// buildOf returns the build number of the given version, matched by the build
+// suffix of the calling SemVer, or empty if it is not a build.
+func (s *SemVer) buildOf(v *SemVer) string {
+	if s.buildSuffix == nil {
+		return ""
+	}
+
+	preRelease := strings.TrimPrefix(v.metadata, "-")
+	match := s.buildSuffix.FindStringSubmatch(preRelease)
+	if len(match) == 0 || match[0] != preRelease {
+		return ""
+	}
+
+	build := match[0]
+	if len(match) > 1 {
+		build = match[1]
+	}
+	if !isNumeric(build) {
+		return ""
+	}
+
+	return build
+}
+
 func (s *SemVer) isInvalidComparison(other *SemVer) bool {
 	return len(other.original) == 0 || len(s.original) == 0
 }
@@ -82,31 +182,117 @@ func (s *SemVer) compareVersionNumbers(other *SemVer) bool {
 	return false
 }
 
+// comparePreReleaseMetadata compares the dot separated identifiers of the
+// metadata, according to the semver 2.0.0 spec precedence rules:
+// https://semver.org/#spec-item-11
+// Identifiers in the pre-release order of the calling SemVer are compared by
+// their position in it instead.
 func (s *SemVer) comparePreReleaseMetadata(other *SemVer) bool {
-	sWords := parseStringToWords(s.metadata)
-	otherWords := parseStringToWords(other.metadata)
+	sIdentifiers := parsePreReleaseIdentifiers(s.metadata)
+	otherIdentifiers := parsePreReleaseIdentifiers(other.metadata)
 
-	l := len(sWords)
-	if len(otherWords) > l {
-		l = len(otherWords)
-	}
-
-	for i := 0; i < l; i++ {
-		if i > len(sWords)-1 {
-			return false
+	for i := 0; i < len(sIdentifiers) && i < len(otherIdentifiers); i++ {
+		c, ok := s.preReleaseOrder.compare(sIdentifiers[i], otherIdentifiers[i])
+		if !ok {
+			c = compareIdentifiers(sIdentifiers[i], otherIdentifiers[i])
 		}
-		if i > len(otherWords)-1 {
-			return true
+		if c != 0 {
+			return c < 0
 		}
+	}
 
-		if sWords[i].equal(otherWords[i]) {
+	// A larger set of identifiers has a higher precedence, if all of the
+	// preceding identifiers are equal.
+	return len(sIdentifiers) < len(otherIdentifiers)
+}
+
+// parsePreReleaseIdentifiers will return the dot separated identifiers of the
+// given metadata, ignoring any build metadata.
+func parsePreReleaseIdentifiers(metadata string) []string {
+	if i := strings.Index(metadata, "+"); i > -1 {
+		metadata = metadata[:i]
+	}
+
+	// Metadata includes the separator from the patch version.
+	if len(metadata) > 0 && (metadata[0] == '-' || metadata[0] == '.') {
+		metadata = metadata[1:]
+	}
+
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	return strings.Split(metadata, ".")
+}
+
+// compareIdentifiers returns -1, 0 or 1 if the identifier a has a lower, equal
+// or higher precedence than b. Numeric identifiers are compared numerically,
+// and always have a lower precedence than alphanumeric identifiers.
+// Alphanumeric identifiers are compared lexically, with the exception that
+// runs of digits within them are compared numerically (e.g. debian-10-r9 <
+// debian-10-r39).
+func compareIdentifiers(a, b string) int {
+	aNumeric, bNumeric := isNumeric(a), isNumeric(b)
+
+	switch {
+	case aNumeric && bNumeric:
+		return compareNumeric(a, b)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	}
+
+	aWords, bWords := parseStringToWords(a), parseStringToWords(b)
+	for i := 0; i < len(aWords) && i < len(bWords); i++ {
+		if aWords[i].equal(bWords[i]) {
 			continue
 		}
 
-		return sWords[i].lessThan(otherWords[i])
+		if aWords[i].lessThan(bWords[i]) {
+			return -1
+		}
+
+		return 1
 	}
 
-	return false
+	return compareInt(len(aWords), len(bWords))
+}
+
+// compareNumeric compares two strings of digits numerically, without
+// overflowing on large numbers.
+func compareNumeric(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return compareInt(len(a), len(b))
+	}
+
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isNumeric(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
 }
 
 // Equal will return true if the given semver is equal.
@@ -116,9 +302,17 @@ func (s *SemVer) Equal(other *SemVer) bool {
 
 // HasMetaData returns whether this SemVer has metadata. MetaData is defined
 // as a tag containing anything after the patch digit.
-// e.g. v1.0.1-gke.3, v1.0.1-alpha.0, v1.2.3.4.
+// e.g. v1.0.1-gke.3, v1.0.1-alpha.0, v1.2.3.4. Builds matching the build
+// suffix it was parsed with, such as 1.2.3-2, are not metadata.
 func (s *SemVer) HasMetaData() bool {
-	return len(s.metadata) > 0
+	return len(s.metadata) > 0 && len(s.buildOf(s)) == 0
+}
+
+// Valid returns whether this SemVer was parsed from a tag beginning with a
+// version number, rather than a tag of only metadata, such as a random string.
+// e.g. v1.0.1, 1.2-alpine, but not stable or hello-1.2.3.
+func (s *SemVer) Valid() bool {
+	return s.valid
 }
 
 // Major returns the major version of this SemVer.
@@ -2,6 +2,7 @@ package semver
 
 import (
 	"reflect"
+	"regexp"
 	"testing"
 )
 
@@ -79,6 +80,46 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestValid(t *testing.T) {
+	tests := map[string]struct {
+		input    string
+		expValid bool
+	}{
+		"no input should be invalid": {
+			input:    "",
+			expValid: false,
+		},
+		"only v should be invalid": {
+			input:    "v",
+			expValid: false,
+		},
+		"a random string should be invalid": {
+			input:    "stable",
+			expValid: false,
+		},
+		"not beginning with a version should be invalid": {
+			input:    "hello-1.2.3",
+			expValid: false,
+		},
+		"a version should be valid": {
+			input:    "v1.0.1",
+			expValid: true,
+		},
+		"a version with metadata should be valid": {
+			input:    "1.2-alpine",
+			expValid: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if valid := Parse(test.input).Valid(); valid != test.expValid {
+				t.Errorf("unexpected valid, exp=%t got=%t", test.expValid, valid)
+			}
+		})
+	}
+}
+
 func TestMajorMinorPatch(t *testing.T) {
 	tests := map[string]struct {
 		input   string
@@ -209,6 +250,71 @@ func TestLessThan(t *testing.T) {
 			"0.21.0-debian-10-r39-hello", "0.21.0-debian-10-r9-hello",
 			false,
 		},
+		"Numeric identifiers are compared numerically": {
+			"1.0.0-alpha.2", "1.0.0-alpha.10",
+			true,
+		},
+		"Numeric identifiers are compared numerically reverse": {
+			"1.0.0-alpha.10", "1.0.0-alpha.2",
+			false,
+		},
+		"Numeric identifiers have lower precedence than alphanumeric": {
+			"1.0.0-alpha.10", "1.0.0-alpha.9b",
+			true,
+		},
+		"Alphanumeric identifiers have higher precedence than numeric": {
+			"1.0.0-alpha.9b", "1.0.0-alpha.10",
+			false,
+		},
+		"Numeric identifiers with leading zeros are compared numerically": {
+			"1.0.0-rc.010", "1.0.0-rc.9",
+			false,
+		},
+		"Numeric identifiers larger than int64 are compared": {
+			"1.0.0-rc.99999999999999999998", "1.0.0-rc.99999999999999999999",
+			true,
+		},
+		"Larger set of identifiers has higher precedence": {
+			"1.0.0-rc.1", "1.0.0-rc.1.1",
+			true,
+		},
+		"Larger set of identifiers has higher precedence reverse": {
+			"1.0.0-rc.1.1", "1.0.0-rc.1",
+			false,
+		},
+		"Build metadata is ignored": {
+			"1.0.0-rc.1+build.2", "1.0.0-rc.1+build.1",
+			false,
+		},
+		"Build metadata is ignored reverse": {
+			"1.0.0-rc.1+build.1", "1.0.0-rc.1+build.2",
+			false,
+		},
+
+		"Pre-releases of a later version are not less than": {
+			"1.2.0-rc.1", "1.1.0-rc.2",
+			false,
+		},
+		"Pre-releases of an earlier version are less than": {
+			"1.1.0-rc.2", "1.2.0-rc.1",
+			true,
+		},
+		"Pre-releases of a later major version are not less than": {
+			"2.0.0-alpha", "1.9.9-beta.10",
+			false,
+		},
+		"Pre-releases of an earlier patch version are less than": {
+			"1.0.1-beta", "1.0.2-alpha",
+			true,
+		},
+		"Stable versions are not less than pre-releases of a later version": {
+			"1.0.0", "1.1.0-rc.1",
+			false,
+		},
+		"Pre-releases are less than stable versions of an earlier version": {
+			"1.1.0-rc.1", "1.0.0",
+			true,
+		},
 	}
 
 	for name, test := range tests {
@@ -220,3 +326,147 @@ func TestLessThan(t *testing.T) {
 		})
 	}
 }
+
+// TestLessThanSpecPrecedence tests the precedence example from the semver
+// 2.0.0 spec: https://semver.org/#spec-item-11
+func TestLessThanSpecPrecedence(t *testing.T) {
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.2",
+		"1.0.0-alpha.10",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			first, second := Parse(ordered[i]), Parse(ordered[j])
+			if exp := i < j; first.LessThan(second) != exp {
+				t.Errorf("unexpected less than, first=%s second=%s expLessThan=%t",
+					ordered[i], ordered[j], exp)
+			}
+		}
+	}
+}
+
+// TestLessThanPreReleaseOrder tests that a custom pre-release order overrides
+// the lexical comparison of the listed identifiers only.
+func TestLessThanPreReleaseOrder(t *testing.T) {
+	order := PreReleaseOrder{"dev", "alpha", "beta", "rc"}
+
+	ordered := []string{
+		"1.0.0-dev",
+		"1.0.0-dev.1",
+		"1.0.0-dev.10",
+		"1.0.0-alpha",
+		"1.0.0-alpha.2",
+		"1.0.0-beta",
+		"1.0.0-rc.1",
+		"1.0.0",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			first, second := order.Parse(ordered[i]), order.Parse(ordered[j])
+			if exp := i < j; first.LessThan(second) != exp {
+				t.Errorf("unexpected less than, first=%s second=%s expLessThan=%t",
+					ordered[i], ordered[j], exp)
+			}
+		}
+	}
+
+	tests := map[string]struct {
+		order         PreReleaseOrder
+		first, second string
+		expLessThan   bool
+	}{
+		"without an order dev should be above beta": {
+			order: nil, first: "1.0.0-beta", second: "1.0.0-dev", expLessThan: true,
+		},
+		"with an order dev should be below beta": {
+			order: order, first: "1.0.0-beta", second: "1.0.0-dev", expLessThan: false,
+		},
+		"unlisted identifiers should fall back to the spec": {
+			order: order, first: "1.0.0-gamma", second: "1.0.0-nightly", expLessThan: true,
+		},
+		"a listed and unlisted identifier should fall back to the spec": {
+			order: order, first: "1.0.0-rc", second: "1.0.0-nightly", expLessThan: false,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			first, second := test.order.Parse(test.first), test.order.Parse(test.second)
+			if less := first.LessThan(second); less != test.expLessThan {
+				t.Errorf("unexpected less than, exp=%t got=%t", test.expLessThan, less)
+			}
+		})
+	}
+}
+
+// TestLessThanBuildSuffix tests that pre-releases matching the build suffix
+// are ranked above their version, by their build number.
+func TestLessThanBuildSuffix(t *testing.T) {
+	parser := Parser{BuildSuffix: regexp.MustCompile(`[0-9]+`)}
+
+	ordered := []string{
+		"1.2.3-rc.1",
+		"1.2.3",
+		"1.2.3-1",
+		"1.2.3-2",
+		"1.2.3-10",
+		"1.2.4",
+		"1.2.4-1",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			first, second := parser.Parse(ordered[i]), parser.Parse(ordered[j])
+			if exp := i < j; first.LessThan(second) != exp {
+				t.Errorf("unexpected less than, first=%s second=%s expLessThan=%t",
+					ordered[i], ordered[j], exp)
+			}
+		}
+	}
+
+	tests := map[string]struct {
+		parser        Parser
+		first, second string
+		expLessThan   bool
+		expMetaData   bool
+	}{
+		"without a build suffix builds should be pre-releases": {
+			parser: Parser{}, first: "1.2.3", second: "1.2.3-2", expLessThan: false, expMetaData: true,
+		},
+		"with a build suffix builds should be above the version": {
+			parser: parser, first: "1.2.3", second: "1.2.3-2", expLessThan: true, expMetaData: false,
+		},
+		"the build number should be the first capture group": {
+			parser:      Parser{BuildSuffix: regexp.MustCompile(`build\.([0-9]+)`)},
+			first:       "1.2.3-build.9",
+			second:      "1.2.3-build.10",
+			expLessThan: true,
+			expMetaData: false,
+		},
+		"partial matches should not be builds": {
+			parser: parser, first: "1.2.3", second: "1.2.3-2-debug", expLessThan: false, expMetaData: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			first, second := test.parser.Parse(test.first), test.parser.Parse(test.second)
+			if less := first.LessThan(second); less != test.expLessThan {
+				t.Errorf("unexpected less than, exp=%t got=%t", test.expLessThan, less)
+			}
+			if metadata := second.HasMetaData(); metadata != test.expMetaData {
+				t.Errorf("unexpected has metadata, exp=%t got=%t", test.expMetaData, metadata)
+			}
+		})
+	}
+}
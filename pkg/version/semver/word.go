@@ -1,7 +1,6 @@
 package semver
 
 import (
-	"strconv"
 	"unicode"
 	"unicode/utf8"
 )
@@ -40,10 +39,12 @@ func (s *stringWord) isInt() bool {
 	return false
 }
 
+// lessThan will return true if the given word is a string that is lexically
+// greater. Strings are never less than an int word.
 func (s *stringWord) lessThan(w word) bool {
 	ws, ok := w.(*stringWord)
 	if !ok {
-		return true
+		return false
 	}
 
 	return s.ss < ws.ss
@@ -52,7 +53,7 @@ func (s *stringWord) lessThan(w word) bool {
 func (s *stringWord) equal(w word) bool {
 	ws, ok := w.(*stringWord)
 	if !ok {
-		return true
+		return false
 	}
 
 	return s.ss == ws.ss
@@ -70,24 +71,24 @@ func (i *intWord) isInt() bool {
 	return true
 }
 
+// lessThan will return true if the given word is an int that is numerically
+// greater. Ints are always less than a string word.
 func (i *intWord) lessThan(w word) bool {
 	wi, ok := w.(*intWord)
 	if !ok {
-		return false
+		return true
 	}
 
-	iii, _ := strconv.ParseInt(i.ii, 10, 64)
-	wiii, _ := strconv.ParseInt(wi.ii, 10, 64)
-	return iii < wiii
+	return compareNumeric(i.ii, wi.ii) < 0
 }
 
 func (i *intWord) equal(w word) bool {
 	wi, ok := w.(*intWord)
 	if !ok {
-		return true
+		return false
 	}
 
-	return i.ii == wi.ii
+	return compareNumeric(i.ii, wi.ii) == 0
 }
 
 func parseStringToWords(ss string) []word {
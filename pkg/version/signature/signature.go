@@ -0,0 +1,544 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/sirupsen/logrus"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/cache"
+	"github.com/jetstack/version-checker/pkg/client"
+)
+
+const (
+	// Annotations of cosign signature layers.
+	signatureAnnotation   = "dev.cosignproject.cosign/signature"
+	certificateAnnotation = "dev.sigstore.cosign/certificate"
+	chainAnnotation       = "dev.sigstore.cosign/chain"
+	bundleAnnotation      = "dev.sigstore.cosign/bundle"
+
+	// maxPayloadSize is the maximum size of a signature payload which will be
+	// read.
+	maxPayloadSize = 1 << 20
+)
+
+var (
+	// oidIssuer is the legacy Fulcio certificate extension of the OIDC issuer,
+	// whose value is the raw issuer.
+	oidIssuer = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+
+	// oidIssuerV2 is the Fulcio certificate extension of the OIDC issuer,
+	// whose value is a DER encoded string.
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Options are used to configure how signatures are verified.
+type Options struct {
+	// PublicKeyPath is the path to a PEM encoded public key, which signatures
+	// may be verified with.
+	PublicKeyPath string
+
+	// FulcioRootPath is the path to PEM encoded root certificates, which the
+	// certificates of keyless signatures may be verified with.
+	FulcioRootPath string
+
+	// CertificateIdentity is the email or URI subject alternative name which
+	// the certificates of keyless signatures must be issued to.
+	CertificateIdentity string
+
+	// CertificateOIDCIssuer is the OIDC issuer which the certificates of
+	// keyless signatures must be issued by.
+	CertificateOIDCIssuer string
+
+	// RekorPublicKeyPath is the path to the PEM encoded public key of the Rekor
+	// transparency log, which the log entries of keyless signatures are
+	// verified with.
+	RekorPublicKeyPath string
+}
+
+// Verifier verifies cosign signatures of image digests.
+type Verifier struct {
+	log *logrus.Entry
+
+	client *client.Client
+
+	publicKey crypto.PublicKey
+	roots     *x509.CertPool
+
+	identity string
+	issuer   string
+	rekorKey crypto.PublicKey
+
+	remoteOpts []remote.Option
+	cache      *cache.Cache
+}
+
+// payload is the cosign simple signing payload which is signed.
+type payload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// bundle is the Rekor bundle of a keyless signature, proving the signature
+// was included in the transparency log.
+type bundle struct {
+	SignedEntryTimestamp []byte        `json:"SignedEntryTimestamp"`
+	Payload              bundlePayload `json:"Payload"`
+}
+
+// bundlePayload is the log entry which is signed by the signed entry
+// timestamp. Fields are in the order of their canonical JSON encoding.
+type bundlePayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// hashedRekord is the body of the Rekor log entry of a signature.
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// New will return a new Verifier, using the public key and Fulcio roots set
+// in the given options. Signatures are fetched from the registry the tags of
+// the image are listed from, with the credentials of the client for its host.
+// Verification results are cached for the cache timeout.
+func New(log *logrus.Entry, client *client.Client, opts Options, cacheTimeout time.Duration) (*Verifier, error) {
+	v := &Verifier{
+		log:      log.WithField("module", "signature"),
+		client:   client,
+		identity: opts.CertificateIdentity,
+		issuer:   opts.CertificateOIDCIssuer,
+		remoteOpts: []remote.Option{
+			remote.WithAuthFromKeychain(client.Keychain()),
+		},
+	}
+
+	if len(opts.PublicKeyPath) == 0 && len(opts.FulcioRootPath) == 0 {
+		return nil, errors.New("a public key or fulcio root must be given to verify signatures")
+	}
+
+	if len(opts.PublicKeyPath) > 0 {
+		publicKey, err := loadPublicKey(opts.PublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load public key %q: %s", opts.PublicKeyPath, err)
+		}
+		v.publicKey = publicKey
+	}
+
+	if len(opts.FulcioRootPath) > 0 {
+		if len(opts.CertificateIdentity) == 0 || len(opts.CertificateOIDCIssuer) == 0 {
+			return nil, errors.New("a certificate identity and OIDC issuer must be given to verify keyless signatures")
+		}
+		if len(opts.RekorPublicKeyPath) == 0 {
+			return nil, errors.New("a rekor public key must be given to verify keyless signatures")
+		}
+
+		rekorKey, err := loadPublicKey(opts.RekorPublicKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rekor public key %q: %s", opts.RekorPublicKeyPath, err)
+		}
+		v.rekorKey = rekorKey
+
+		roots, err := loadRoots(opts.FulcioRootPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fulcio root %q: %s", opts.FulcioRootPath, err)
+		}
+		v.roots = roots
+	}
+
+	v.cache = cache.New(v.log, cacheTimeout, v)
+
+	return v, nil
+}
+
+// Run is a blocking func that will start the verification cache garbage
+// collector.
+func (v *Verifier) Run(refreshRate time.Duration) {
+	v.cache.StartGarbageCollector(refreshRate)
+}
+
+// Verify will return true if the digest of the given image tag has a valid
+// cosign signature in the given image repository. Tags without a digest
+// cannot be verified.
+func (v *Verifier) Verify(ctx context.Context, imageURL string, tag *api.ImageTag) (bool, error) {
+	if len(tag.SHA) == 0 {
+		v.log.Debugf("%s:%s: unable to verify signature of tag without a digest", imageURL, tag.Tag)
+		return false, nil
+	}
+
+	// Signatures are fetched from where the tags were listed
+	index := v.client.ResolveImageURL(imageURL) + "@" + tag.SHA
+	verified, err := v.cache.Get(ctx, index, index, nil)
+	if err != nil {
+		return false, err
+	}
+
+	return verified.(bool), nil
+}
+
+// Fetch will verify the signature of a given image URL and digest, of the
+// form <image>@<digest>.
+func (v *Verifier) Fetch(ctx context.Context, index string, _ *api.Options) (interface{}, error) {
+	imageURL, digest, _ := strings.Cut(index, "@")
+
+	repo, err := name.NewRepository(imageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image repository %q: %s", imageURL, err)
+	}
+
+	// Signatures are stored at the tag sha256-<digest>.sig
+	sigTag := repo.Tag(strings.Replace(digest, ":", "-", 1) + ".sig")
+
+	img, err := remote.Image(sigTag, append(v.remoteOpts, remote.WithContext(ctx))...)
+	if err != nil {
+		var terr *transport.Error
+		if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+			v.log.Debugf("%s: no signature found", index)
+			return false, nil
+		}
+
+		return nil, fmt.Errorf("failed to get signature %q: %s", sigTag, err)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signature manifest %q: %s", sigTag, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		sig, ok := layer.Annotations[signatureAnnotation]
+		if !ok {
+			continue
+		}
+
+		payload, err := readPayload(img, layer.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signature payload %q: %s", sigTag, err)
+		}
+
+		if err := v.verifyLayer(digest, payload, sig, layer.Annotations); err != nil {
+			v.log.Debugf("%s: invalid signature: %s", index, err)
+			continue
+		}
+
+		return true, nil
+	}
+
+	v.log.Debugf("%s: no valid signature found", index)
+
+	return false, nil
+}
+
+// verifyLayer will verify the given signature of the payload, and that the
+// payload is for the given digest.
+func (v *Verifier) verifyLayer(digest string, payloadBytes []byte, sig string, annotations map[string]string) error {
+	sigBytes, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %s", err)
+	}
+
+	if err := v.verifyTrusted(payloadBytes, sigBytes, annotations); err != nil {
+		return err
+	}
+
+	var p payload
+	if err := json.Unmarshal(payloadBytes, &p); err != nil {
+		return fmt.Errorf("failed to decode payload: %s", err)
+	}
+
+	if p.Critical.Image.DockerManifestDigest != digest {
+		return fmt.Errorf("payload is for digest %q", p.Critical.Image.DockerManifestDigest)
+	}
+
+	return nil
+}
+
+// verifyTrusted will verify the signature of the payload with the
+// configured public key, or with the key of the signature certificate, if it
+// is a verified keyless signature.
+func (v *Verifier) verifyTrusted(payload, sig []byte, annotations map[string]string) error {
+	if v.publicKey != nil && verifySignature(v.publicKey, payload, sig) == nil {
+		return nil
+	}
+
+	certPEM, ok := annotations[certificateAnnotation]
+	if !ok || v.roots == nil {
+		return errors.New("signature does not match any trusted key")
+	}
+
+	certs, err := parseCertificates([]byte(certPEM))
+	if err != nil || len(certs) == 0 {
+		return fmt.Errorf("failed to parse signature certificate: %v", err)
+	}
+	cert := certs[0]
+
+	if err := verifySignature(cert.PublicKey, payload, sig); err != nil {
+		return fmt.Errorf("signature does not match its certificate: %s", err)
+	}
+
+	integratedTime, err := v.verifyBundle(annotations[bundleAnnotation], cert, payload, sig)
+	if err != nil {
+		return fmt.Errorf("failed to verify transparency log entry: %s", err)
+	}
+
+	return v.verifyCertificate(cert, annotations[chainAnnotation], integratedTime)
+}
+
+// verifyCertificate will verify the certificate of a keyless signature was
+// issued by a Fulcio root, to the configured identity by the configured OIDC
+// issuer.
+func (v *Verifier) verifyCertificate(cert *x509.Certificate, chainPEM string, integratedTime time.Time) error {
+	intermediates := x509.NewCertPool()
+	if len(chainPEM) > 0 {
+		chain, err := parseCertificates([]byte(chainPEM))
+		if err != nil {
+			return fmt.Errorf("failed to parse signature certificate chain: %s", err)
+		}
+		for _, cert := range chain {
+			intermediates.AddCert(cert)
+		}
+	}
+
+	// Fulcio certificates are short lived, so are verified at the time the
+	// signature was included in the transparency log.
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   integratedTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return fmt.Errorf("failed to verify signature certificate: %s", err)
+	}
+
+	if !slices.Contains(certificateIdentities(cert), v.identity) {
+		return fmt.Errorf("signature certificate is not issued to identity %q", v.identity)
+	}
+
+	issuer, err := certificateIssuer(cert)
+	if err != nil {
+		return err
+	}
+	if issuer != v.issuer {
+		return fmt.Errorf("signature certificate is issued by OIDC issuer %q", issuer)
+	}
+
+	return nil
+}
+
+// verifyBundle will verify the Rekor bundle of a keyless signature is signed
+// by the transparency log, and that its entry is of the signature and
+// certificate. Returns the time the entry was included in the log.
+func (v *Verifier) verifyBundle(bundleJSON string, cert *x509.Certificate, payload, sig []byte) (time.Time, error) {
+	if len(bundleJSON) == 0 {
+		return time.Time{}, errors.New("signature has no rekor bundle")
+	}
+
+	var b bundle
+	if err := json.Unmarshal([]byte(bundleJSON), &b); err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode rekor bundle: %s", err)
+	}
+
+	signed, err := json.Marshal(b.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := verifySignature(v.rekorKey, signed, b.SignedEntryTimestamp); err != nil {
+		return time.Time{}, fmt.Errorf("invalid signed entry timestamp: %s", err)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(b.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode log entry: %s", err)
+	}
+
+	var entry hashedRekord
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode log entry: %s", err)
+	}
+
+	hash := sha256.Sum256(payload)
+	if entry.Kind != "hashedrekord" || entry.Spec.Data.Hash.Algorithm != "sha256" ||
+		entry.Spec.Data.Hash.Value != hex.EncodeToString(hash[:]) {
+		return time.Time{}, errors.New("log entry is not of the signature payload")
+	}
+
+	if !bytes.Equal(entry.Spec.Signature.Content, sig) {
+		return time.Time{}, errors.New("log entry is not of the signature")
+	}
+
+	entryCerts, err := parseCertificates(entry.Spec.Signature.PublicKey.Content)
+	if err != nil || len(entryCerts) == 0 || !entryCerts[0].Equal(cert) {
+		return time.Time{}, errors.New("log entry is not of the signature certificate")
+	}
+
+	return time.Unix(b.Payload.IntegratedTime, 0), nil
+}
+
+// certificateIdentities returns the email and URI subject alternative names
+// of the given certificate.
+func certificateIdentities(cert *x509.Certificate) []string {
+	identities := slices.Clone(cert.EmailAddresses)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	return identities
+}
+
+// certificateIssuer returns the OIDC issuer of the given Fulcio certificate.
+func certificateIssuer(cert *x509.Certificate) (string, error) {
+	var legacy string
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err != nil {
+				return "", fmt.Errorf("failed to parse signature certificate OIDC issuer: %s", err)
+			}
+			return issuer, nil
+
+		case ext.Id.Equal(oidIssuer):
+			legacy = string(ext.Value)
+		}
+	}
+
+	if len(legacy) == 0 {
+		return "", errors.New("signature certificate has no OIDC issuer")
+	}
+
+	return legacy, nil
+}
+
+// readPayload will read the signature payload layer of the given digest.
+func readPayload(img v1.Image, digest v1.Hash) ([]byte, error) {
+	layer, err := img.LayerByDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(io.LimitReader(rc, maxPayloadSize))
+}
+
+// verifySignature will verify the signature of the payload with the given
+// public key.
+func verifySignature(publicKey crypto.PublicKey, payload, sig []byte) error {
+	hash := sha256.Sum256(payload)
+
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, hash[:], sig) {
+			return errors.New("invalid ecdsa signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, payload, sig) {
+			return errors.New("invalid ed25519 signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
+
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func loadRoots(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := parseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+
+	roots := x509.NewCertPool()
+	for _, cert := range certs {
+		roots.AddCert(cert)
+	}
+
+	return roots, nil
+}
+
+func parseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(bytes.TrimSpace(data))
+		if block == nil {
+			return certs, nil
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
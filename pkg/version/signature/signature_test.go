@@ -0,0 +1,447 @@
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/jetstack/version-checker/pkg/api"
+	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/client/selfhosted"
+)
+
+const (
+	testIdentity = "https://github.com/foo/bar/.github/workflows/release.yaml@refs/heads/main"
+	testIssuer   = "https://token.actions.githubusercontent.com"
+)
+
+func TestVerify(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	imageURL := u.Host + "/foo/bar"
+
+	trustedKey := generateKey(t)
+	untrustedKey := generateKey(t)
+
+	rootCert, rootKey := generateCA(t)
+	leafKey := generateKey(t)
+	leafCert := generateLeaf(t, rootCert, rootKey, leafKey, testIdentity, testIssuer)
+	rekorKey := generateKey(t)
+	untrustedRekorKey := generateKey(t)
+
+	dir := t.TempDir()
+	publicKeyPath := writePublicKey(t, dir, &trustedKey.PublicKey)
+
+	rootPath := filepath.Join(dir, "fulcio.pem")
+	require.NoError(t, os.WriteFile(rootPath,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootCert.Raw}), 0600))
+
+	rekorPath := writePublicKey(t, dir, &rekorKey.PublicKey)
+
+	tests := map[string]struct {
+		sign        func(t *testing.T, digest string)
+		expVerified bool
+	}{
+		"unsigned image should not be verified": {
+			sign:        func(*testing.T, string) {},
+			expVerified: false,
+		},
+		"image signed with the public key should be verified": {
+			sign: func(t *testing.T, digest string) {
+				writeSignature(t, imageURL, digest, digest, trustedKey, nil, nil)
+			},
+			expVerified: true,
+		},
+		"image signed with an untrusted key should not be verified": {
+			sign: func(t *testing.T, digest string) {
+				writeSignature(t, imageURL, digest, digest, untrustedKey, nil, nil)
+			},
+			expVerified: false,
+		},
+		"signature for another digest should not be verified": {
+			sign: func(t *testing.T, digest string) {
+				writeSignature(t, imageURL, digest, "sha256:"+strings.Repeat("0", 64), trustedKey, nil, nil)
+			},
+			expVerified: false,
+		},
+		"image signed with a certificate from the fulcio root should be verified": {
+			sign: func(t *testing.T, digest string) {
+				writeSignature(t, imageURL, digest, digest, leafKey, leafCert, rekorKey)
+			},
+			expVerified: true,
+		},
+		"keyless signature without a rekor bundle should not be verified": {
+			sign: func(t *testing.T, digest string) {
+				writeSignature(t, imageURL, digest, digest, leafKey, leafCert, nil)
+			},
+			expVerified: false,
+		},
+		"keyless signature with a bundle of an untrusted rekor key should not be verified": {
+			sign: func(t *testing.T, digest string) {
+				writeSignature(t, imageURL, digest, digest, leafKey, leafCert, untrustedRekorKey)
+			},
+			expVerified: false,
+		},
+		"keyless signature of another identity should not be verified": {
+			sign: func(t *testing.T, digest string) {
+				key := generateKey(t)
+				cert := generateLeaf(t, rootCert, rootKey, key, "https://github.com/foo/baz/.github/workflows/release.yaml@refs/heads/main", testIssuer)
+				writeSignature(t, imageURL, digest, digest, key, cert, rekorKey)
+			},
+			expVerified: false,
+		},
+		"keyless signature of another issuer should not be verified": {
+			sign: func(t *testing.T, digest string) {
+				key := generateKey(t)
+				cert := generateLeaf(t, rootCert, rootKey, key, testIdentity, "https://accounts.google.com")
+				writeSignature(t, imageURL, digest, digest, key, cert, rekorKey)
+			},
+			expVerified: false,
+		},
+		"image signed with a self signed certificate should not be verified": {
+			sign: func(t *testing.T, digest string) {
+				selfSigned, selfSignedKey := generateCA(t)
+				writeSignature(t, imageURL, digest, digest, selfSignedKey, selfSigned, rekorKey)
+			},
+			expVerified: false,
+		},
+	}
+
+	verifier, err := New(logrus.NewEntry(logrus.New()), newClient(t, client.Options{}), Options{
+		PublicKeyPath:         publicKeyPath,
+		FulcioRootPath:        rootPath,
+		CertificateIdentity:   testIdentity,
+		CertificateOIDCIssuer: testIssuer,
+		RekorPublicKeyPath:    rekorPath,
+	}, time.Minute)
+	require.NoError(t, err)
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			digest := writeImage(t, imageURL)
+			test.sign(t, digest)
+
+			verified, err := verifier.Verify(context.TODO(), imageURL, &api.ImageTag{Tag: "v1.0.0", SHA: digest})
+			require.NoError(t, err)
+			assert.Equal(t, test.expVerified, verified)
+		})
+	}
+
+	t.Run("tags without a digest should not be verified", func(t *testing.T) {
+		verified, err := verifier.Verify(context.TODO(), imageURL, &api.ImageTag{Tag: "v1.0.0"})
+		require.NoError(t, err)
+		assert.False(t, verified)
+	})
+
+	t.Run("results should be cached per digest", func(t *testing.T) {
+		digest := writeImage(t, imageURL)
+
+		verified, err := verifier.Verify(context.TODO(), imageURL, &api.ImageTag{SHA: digest})
+		require.NoError(t, err)
+		assert.False(t, verified)
+
+		writeSignature(t, imageURL, digest, digest, trustedKey, nil, nil)
+
+		verified, err = verifier.Verify(context.TODO(), imageURL, &api.ImageTag{SHA: digest})
+		require.NoError(t, err)
+		assert.False(t, verified, "expected cached result")
+	})
+}
+
+func TestVerifyRegistryClient(t *testing.T) {
+	reg := registry.New()
+	authorized := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorized && r.Header.Get("Authorization") != "Bearer registry-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	key := generateKey(t)
+	publicKeyPath := writePublicKey(t, t.TempDir(), &key.PublicKey)
+
+	authorized = false
+	digest := writeImage(t, u.Host+"/foo/bar")
+	writeSignature(t, u.Host+"/foo/bar", digest, digest, key, nil, nil)
+	authorized = true
+
+	// The signature is fetched from the rewritten registry, with its token
+	client := newClient(t, client.Options{
+		Selfhosted: map[string]*selfhosted.Options{
+			"registry": {Host: server.URL, Bearer: "registry-token"},
+		},
+		RewriteRules: []client.RewriteRule{
+			{Regex: regexp.MustCompile(`^example\.com/(.+)$`), Replacement: u.Host + "/$1"},
+		},
+	})
+
+	verifier, err := New(logrus.NewEntry(logrus.New()), client, Options{PublicKeyPath: publicKeyPath}, time.Minute)
+	require.NoError(t, err)
+
+	verified, err := verifier.Verify(context.TODO(), "example.com/foo/bar", &api.ImageTag{Tag: "v1.0.0", SHA: digest})
+	require.NoError(t, err)
+	assert.True(t, verified)
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	rootCert, _ := generateCA(t)
+	rootPath := filepath.Join(dir, "fulcio.pem")
+	require.NoError(t, os.WriteFile(rootPath,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootCert.Raw}), 0600))
+	rekorPath := writePublicKey(t, dir, &generateKey(t).PublicKey)
+
+	tests := map[string]struct {
+		opts   Options
+		expErr string
+	}{
+		"no public key or fulcio root should error": {
+			opts:   Options{},
+			expErr: "a public key or fulcio root must be given to verify signatures",
+		},
+		"fulcio root without an identity should error": {
+			opts: Options{
+				FulcioRootPath:        rootPath,
+				CertificateOIDCIssuer: testIssuer,
+				RekorPublicKeyPath:    rekorPath,
+			},
+			expErr: "a certificate identity and OIDC issuer must be given to verify keyless signatures",
+		},
+		"fulcio root without an issuer should error": {
+			opts: Options{
+				FulcioRootPath:      rootPath,
+				CertificateIdentity: testIdentity,
+				RekorPublicKeyPath:  rekorPath,
+			},
+			expErr: "a certificate identity and OIDC issuer must be given to verify keyless signatures",
+		},
+		"fulcio root without a rekor public key should error": {
+			opts: Options{
+				FulcioRootPath:        rootPath,
+				CertificateIdentity:   testIdentity,
+				CertificateOIDCIssuer: testIssuer,
+			},
+			expErr: "a rekor public key must be given to verify keyless signatures",
+		},
+		"fulcio root with an identity, issuer and rekor public key should not error": {
+			opts: Options{
+				FulcioRootPath:        rootPath,
+				CertificateIdentity:   testIdentity,
+				CertificateOIDCIssuer: testIssuer,
+				RekorPublicKeyPath:    rekorPath,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(logrus.NewEntry(logrus.New()), newClient(t, client.Options{}), test.opts, time.Minute)
+			if len(test.expErr) > 0 {
+				assert.EqualError(t, err, test.expErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func newClient(t *testing.T, opts client.Options) *client.Client {
+	t.Helper()
+
+	client, err := client.New(context.TODO(), logrus.NewEntry(logrus.New()), opts)
+	require.NoError(t, err)
+
+	return client
+}
+
+// writePublicKey will write the given public key as PEM to the directory,
+// returning its path.
+func writePublicKey(t *testing.T, dir string, publicKey crypto.PublicKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	f, err := os.CreateTemp(dir, "*.pub")
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.Write(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+
+	return f.Name()
+}
+
+// writeImage will write a random image to the repository, returning its
+// digest.
+func writeImage(t *testing.T, imageURL string) string {
+	t.Helper()
+
+	img, err := random.Image(64, 1)
+	require.NoError(t, err)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	ref, err := name.ParseReference(imageURL + "@" + digest.String())
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	return digest.String()
+}
+
+// writeSignature will write a cosign signature of the payload for
+// payloadDigest, to the signature tag of digest. Keyless signatures have a
+// rekor bundle signed by the rekor key, if given.
+func writeSignature(t *testing.T, imageURL, digest, payloadDigest string, key *ecdsa.PrivateKey, cert *x509.Certificate, rekorKey *ecdsa.PrivateKey) {
+	t.Helper()
+
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":%q},"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`,
+		imageURL, payloadDigest))
+
+	hash := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	annotations := map[string]string{
+		signatureAnnotation: base64.StdEncoding.EncodeToString(sig),
+	}
+	if cert != nil {
+		certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		annotations[certificateAnnotation] = string(certPEM)
+		if rekorKey != nil {
+			annotations[bundleAnnotation] = rekorBundle(t, rekorKey, certPEM, payload, sig)
+		}
+	}
+
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       static.NewLayer(payload, types.MediaType("application/vnd.dev.cosign.simplesigning.v1+json")),
+		Annotations: annotations,
+	})
+	require.NoError(t, err)
+
+	ref, err := name.NewTag(imageURL + ":" + strings.Replace(digest, ":", "-", 1) + ".sig")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+}
+
+// rekorBundle returns a rekor bundle of the hashedrekord entry of the
+// signature, signed by the given rekor key.
+func rekorBundle(t *testing.T, rekorKey *ecdsa.PrivateKey, certPEM, payload, sig []byte) string {
+	t.Helper()
+
+	hash := sha256.Sum256(payload)
+	body := fmt.Sprintf(`{"apiVersion":"0.0.1","kind":"hashedrekord","spec":{"data":{"hash":{"algorithm":"sha256","value":%q}},"signature":{"content":%q,"publicKey":{"content":%q}}}}`,
+		hex.EncodeToString(hash[:]), base64.StdEncoding.EncodeToString(sig), base64.StdEncoding.EncodeToString(certPEM))
+
+	entry := fmt.Sprintf(`{"body":%q,"integratedTime":%d,"logID":%q,"logIndex":%d}`,
+		base64.StdEncoding.EncodeToString([]byte(body)), time.Now().Unix(), strings.Repeat("ab", 32), 42)
+
+	entryHash := sha256.Sum256([]byte(entry))
+	set, err := ecdsa.SignASN1(rand.Reader, rekorKey, entryHash[:])
+	require.NoError(t, err)
+
+	return fmt.Sprintf(`{"SignedEntryTimestamp":%q,"Payload":%s}`, base64.StdEncoding.EncodeToString(set), entry)
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return key
+}
+
+func generateCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key := generateKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	return signCertificate(t, template, template, &key.PublicKey, key), key
+}
+
+func generateLeaf(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, key *ecdsa.PrivateKey, identity, issuer string) *x509.Certificate {
+	t.Helper()
+
+	identityURI, err := url.Parse(identity)
+	require.NoError(t, err)
+	issuerDER, err := asn1.MarshalWithParams(issuer, "utf8")
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(10 * time.Minute),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		URIs:         []*url.URL{identityURI},
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidIssuerV2, Value: issuerDER},
+		},
+	}
+
+	return signCertificate(t, template, parent, &key.PublicKey, parentKey)
+}
+
+func signCertificate(t *testing.T, template, parent *x509.Certificate, publicKey crypto.PublicKey, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, publicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
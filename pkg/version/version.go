@@ -4,16 +4,22 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/jetstack/version-checker/pkg/api"
 	"github.com/jetstack/version-checker/pkg/client"
+	"github.com/jetstack/version-checker/pkg/client/util"
 
 	"github.com/jetstack/version-checker/pkg/cache"
+	"github.com/jetstack/version-checker/pkg/version/datesha"
+	"github.com/jetstack/version-checker/pkg/version/epoch"
 	versionerrors "github.com/jetstack/version-checker/pkg/version/errors"
+	"github.com/jetstack/version-checker/pkg/version/manifest"
 	"github.com/jetstack/version-checker/pkg/version/semver"
+	"github.com/jetstack/version-checker/pkg/version/signature"
 )
 
 type Version struct {
@@ -21,14 +27,23 @@ type Version struct {
 
 	client     *client.Client
 	imageCache *cache.Cache
+	verifier   *signature.Verifier
+	prober     *manifest.Prober
 }
 
-func New(log *logrus.Entry, client *client.Client, cacheTimeout time.Duration) *Version {
+// New returns a new Version. The verifier is used to verify the signatures of
+// tags, and may be nil if signature verification is not configured. The
+// prober is used to only select latest tags whose manifest can be fetched,
+// and may be nil if strict latest is not enabled.
+func New(log *logrus.Entry, client *client.Client, cacheTimeout time.Duration,
+	verifier *signature.Verifier, prober *manifest.Prober) *Version {
 	log = log.WithField("module", "version_getter")
 
 	v := &Version{
-		log:    log,
-		client: client,
+		log:      log,
+		client:   client,
+		verifier: verifier,
+		prober:   prober,
 	}
 
 	v.imageCache = cache.New(log, cacheTimeout, v)
@@ -38,22 +53,216 @@ func New(log *logrus.Entry, client *client.Client, cacheTimeout time.Duration) *
 
 // Run is a blocking func that will start the image cache garbage collector.
 func (v *Version) Run(refreshRate time.Duration) {
+	if v.verifier != nil {
+		go v.verifier.Run(refreshRate)
+	}
+	if v.prober != nil {
+		go v.prober.Run(refreshRate)
+	}
 	v.imageCache.StartGarbageCollector(refreshRate)
 }
 
 // LatestTagFromImage will return the latest tag given an imageURL, according
 // to the given options.
 func (v *Version) LatestTagFromImage(ctx context.Context, imageURL string, opts *api.Options) (*api.ImageTag, error) {
-	tagsI, err := v.imageCache.Get(ctx, imageURL, imageURL, nil)
+	tags, err := v.tags(ctx, imageURL, opts)
 	if err != nil {
 		return nil, err
 	}
-	tags := tagsI.([]api.ImageTag)
+	tags = excludeArchTags(tags, opts.ExcludeArchs)
+
+	latest := func(tags []api.ImageTag) (*api.ImageTag, error) {
+		if opts.RequireSignature {
+			return v.latestSignedTag(ctx, imageURL, opts, tags)
+		}
+
+		return latestTag(imageURL, opts, tags)
+	}
 
-	var tag *api.ImageTag
+	if v.prober == nil {
+		return latest(tags)
+	}
+
+	// Manifests are fetched from where the tags were listed
+	resolvedURL := v.client.ResolveImageURL(imageURL)
+	tag, skipped, err := latestReachableTag(ctx, v.log, resolvedURL, tags, latest, v.prober.Reachable)
+	if err == nil && skipped > 0 {
+		v.prober.ObserveFallback(resolvedURL)
+	}
+
+	return tag, err
+}
+
+// VersionsBehind will return the number of distinct versions of the given
+// image which are greater than the current tag, up to and including the latest
+// tag, using semver. Only the tags which would be considered for the latest
+// version with the given options are counted.
+func (v *Version) VersionsBehind(ctx context.Context, imageURL, currentTag, latestTag string, opts *api.Options) (int, error) {
+	tags, err := v.tags(ctx, imageURL, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	return semverVersionsBehind(opts, excludeArchTags(tags, opts.ExcludeArchs), currentTag, latestTag), nil
+}
+
+// tags returns the cached tags of the given image URL. Tags listed with the
+// credentials of the options are cached separately by their source.
+func (v *Version) tags(ctx context.Context, imageURL string, opts *api.Options) ([]api.ImageTag, error) {
+	index := imageURL
+	var fetchOpts *api.Options
+	if opts != nil && opts.Credentials != nil {
+		index = imageURL + "|" + opts.Credentials.Source
+		fetchOpts = &api.Options{Credentials: opts.Credentials}
+	}
+
+	tags, err := v.imageCache.Get(ctx, index, imageURL, fetchOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	return tags.([]api.ImageTag), nil
+}
+
+// excludeArchTags will return the given tags, without the images of the
+// excluded architectures. Tags which only have images of excluded
+// architectures are removed entirely, and tags of an unknown architecture are
+// kept, other than tags whose manifest failed to be fetched, as their
+// architecture may be excluded.
+func excludeArchTags(tags []api.ImageTag, excludeArchs []api.Architecture) []api.ImageTag {
+	if len(excludeArchs) == 0 {
+		return tags
+	}
+
+	// Don't modify the tags in place, since they are shared with the image
+	// cache.
+	filtered := make([]api.ImageTag, 0, len(tags))
+	for _, tag := range tags {
+		if len(tag.ManifestError) == 0 && !slices.Contains(excludeArchs, tag.Architecture) {
+			filtered = append(filtered, tag)
+		}
+	}
+
+	return filtered
+}
+
+// latestSignedTag will return the latest tag with a valid signature,
+// according to the given options.
+func (v *Version) latestSignedTag(ctx context.Context, imageURL string, opts *api.Options, tags []api.ImageTag) (*api.ImageTag, error) {
+	if v.verifier == nil {
+		return nil, fmt.Errorf("%s: signature required, but no public key or fulcio root is configured to verify signatures",
+			imageURL)
+	}
+
+	return latestVerifiedTag(ctx, v.log, imageURL, opts, tags, v.verifier.Verify)
+}
+
+// latestVerifiedTag will return the latest tag which is verified, according
+// to the given options. Newer tags which fail verification are skipped, so
+// that an unsigned upgrade is never reported.
+func latestVerifiedTag(ctx context.Context, log *logrus.Entry, imageURL string, opts *api.Options, tags []api.ImageTag,
+	verify func(ctx context.Context, imageURL string, tag *api.ImageTag) (bool, error)) (*api.ImageTag, error) {
+	// Copy the tags, since they are shared with the image cache.
+	candidates := slices.Clone(tags)
+
+	var unsigned int
+	for {
+		tag, err := latestTag(imageURL, opts, candidates)
+		if err != nil {
+			// Every remaining tag may have been skipped
+			if unsigned > 0 && versionerrors.IsNoVersionFound(err) {
+				return nil, versionerrors.NewSignatureErrorNotFound("%s: no tags found with a valid signature with these option constraints, skipped %d unsigned tags",
+					imageURL, unsigned)
+			}
+
+			return nil, err
+		}
 
-	// If UseSHA then return early
-	if opts.UseSHA {
+		verified, err := verify(ctx, imageURL, tag)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to verify signature of tag %q: %s",
+				imageURL, tag.Tag, err)
+		}
+
+		if verified {
+			return tag, nil
+		}
+
+		log.Debugf("%s: skipping tag %q without a valid signature", imageURL, tag.Tag)
+		unsigned++
+
+		for i := range candidates {
+			if &candidates[i] == tag {
+				candidates = slices.Delete(candidates, i, i+1)
+				break
+			}
+		}
+	}
+}
+
+// latestReachableTag will return the latest tag whose manifest is reachable,
+// along with the number of tags skipped since their manifest was not found.
+// Newer tags whose manifest is missing are skipped, so that a tag which does
+// not resolve is never reported.
+func latestReachableTag(ctx context.Context, log *logrus.Entry, imageURL string, tags []api.ImageTag,
+	latest func(tags []api.ImageTag) (*api.ImageTag, error),
+	reachable func(ctx context.Context, imageURL string, tag *api.ImageTag) (bool, error)) (*api.ImageTag, int, error) {
+	// Copy the tags, since they are shared with the image cache.
+	candidates := slices.Clone(tags)
+
+	var skipped int
+	for {
+		tag, err := latest(candidates)
+		if err != nil {
+			// Every remaining tag may have been skipped
+			if skipped > 0 && (len(candidates) == 0 || versionerrors.IsNoVersionFound(err)) {
+				return nil, skipped, versionerrors.NewVersionErrorNotFound("%s: no tags found with a reachable manifest, skipped %d tags: %s",
+					imageURL, skipped, err)
+			}
+
+			return nil, skipped, err
+		}
+
+		ok, err := reachable(ctx, imageURL, tag)
+		if util.IsAuthDenied(err) {
+			return nil, skipped, fmt.Errorf("%s: unable to probe manifest of tag %q: %w",
+				imageURL, tag.Tag, err)
+		}
+		if err != nil {
+			return nil, skipped, fmt.Errorf("%s: failed to get manifest of tag %q: %s",
+				imageURL, tag.Tag, err)
+		}
+
+		if ok {
+			return tag, skipped, nil
+		}
+
+		log.Debugf("%s: skipping tag %q whose manifest was not found", imageURL, tag.Tag)
+		skipped++
+
+		// All images of the tag share its manifest, so are skipped together
+		missing := *tag
+		candidates = slices.DeleteFunc(candidates, func(candidate api.ImageTag) bool {
+			if len(missing.Tag) == 0 {
+				return candidate.SHA == missing.SHA
+			}
+			return candidate.Tag == missing.Tag
+		})
+	}
+}
+
+// latestTag will return the latest of the given tags, with the version scheme
+// of the given options. An error is returned if the manifest of the latest
+// tag failed to be fetched.
+func latestTag(imageURL string, opts *api.Options, tags []api.ImageTag) (*api.ImageTag, error) {
+	var (
+		tag *api.ImageTag
+		err error
+	)
+
+	// Find the latest tag with the version scheme in use
+	switch {
+	case opts.UseSHA:
 		tag, err = latestSHA(tags)
 		if err != nil {
 			return nil, err
@@ -63,28 +272,52 @@ func (v *Version) LatestTagFromImage(ctx context.Context, imageURL string, opts
 			return nil, versionerrors.NewVersionErrorNotFound("%s: failed to find latest image based on SHA",
 				imageURL)
 		}
-	} else {
-		tag, err = latestSemver(opts, tags)
-		if err != nil {
-			return nil, err
+
+	case opts.VersionScheme == api.VersionSchemeDateSHA:
+		tag = latestDateSHA(opts, tags)
+		if tag == nil {
+			optsBytes, _ := json.Marshal(opts)
+			return nil, versionerrors.NewVersionErrorNotFound("%s: no tags found with a leading date with these option constraints: %s",
+				imageURL, optsBytes)
 		}
 
+	case opts.VersionScheme == api.VersionSchemeEpoch:
+		tag = latestEpoch(opts, tags)
 		if tag == nil {
 			optsBytes, _ := json.Marshal(opts)
 			return nil, versionerrors.NewVersionErrorNotFound("%s: no tags found with these option constraints: %s",
 				imageURL, optsBytes)
 		}
+
+	default:
+		tag, err = latestSemver(imageURL, opts, tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(tag.ManifestError) > 0 {
+		return nil, fmt.Errorf("%s: failed to get manifest of latest tag %q: %s",
+			imageURL, tag.Tag, tag.ManifestError)
 	}
 
 	return tag, err
 }
 
 // Fetch returns the given image tags for a given image URL.
-func (v *Version) Fetch(ctx context.Context, imageURL string, _ *api.Options) (interface{}, error) {
-	// fetch tags from image URL
-	tags, err := v.client.Tags(ctx, imageURL)
+func (v *Version) Fetch(ctx context.Context, imageURL string, opts *api.Options) (interface{}, error) {
+	// fetch tags from image URL, with the credentials of the options if set
+	var (
+		tags []api.ImageTag
+		err  error
+	)
+	if opts != nil && opts.Credentials != nil {
+		tags, err = v.client.TagsWithCredentials(ctx, imageURL, opts.Credentials)
+	} else {
+		tags, err = v.client.Tags(ctx, imageURL)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get tags from remote registry for %q: %s",
+		return nil, fmt.Errorf("failed to get tags from remote registry for %q: %w",
 			imageURL, err)
 	}
 
@@ -99,15 +332,27 @@ func (v *Version) Fetch(ctx context.Context, imageURL string, _ *api.Options) (i
 
 // latestSemver will return the latest ImageTag based on the given options
 // restriction, using semver. This should not be used is UseSHA has been
-// enabled.
-func latestSemver(opts *api.Options, tags []api.ImageTag) (*api.ImageTag, error) {
+// enabled. Returns a version not found error if no tags match the options.
+func latestSemver(imageURL string, opts *api.Options, tags []api.ImageTag) (*api.ImageTag, error) {
 	var (
 		latestImageTag *api.ImageTag
 		latestV        *semver.SemVer
+		maxV           *semver.SemVer
 	)
 
+	order := semverParser(opts)
+
+	if opts.MaxVersion != nil {
+		maxV = order.Parse(*opts.MaxVersion)
+	}
+
 	for i := range tags {
-		v := semver.Parse(tags[i].Tag)
+		v := order.Parse(tags[i].Tag)
+
+		// The ceiling applies on top of all other options
+		if maxV != nil && exceedsMaxVersion(maxV, v) {
+			continue
+		}
 
 		if shouldSkipTag(opts, v) {
 			continue
@@ -120,12 +365,78 @@ func latestSemver(opts *api.Options, tags []api.ImageTag) (*api.ImageTag, error)
 	}
 
 	if latestImageTag == nil {
-		return nil, fmt.Errorf("no suitable version found")
+		optsBytes, _ := json.Marshal(opts)
+		return nil, versionerrors.NewVersionErrorNotFound("%s: no tags found with these option constraints: %s",
+			imageURL, optsBytes)
 	}
 
 	return latestImageTag, nil
 }
 
+// semverVersionsBehind will return the number of distinct versions of the
+// given tags which are greater than the current tag, and no greater than the
+// latest tag, skipping the tags which latestSemver would skip.
+func semverVersionsBehind(opts *api.Options, tags []api.ImageTag, currentTag, latestTag string) int {
+	order := semverParser(opts)
+	currentV, latestV := order.Parse(currentTag), order.Parse(latestTag)
+
+	var maxV *semver.SemVer
+	if opts.MaxVersion != nil {
+		maxV = order.Parse(*opts.MaxVersion)
+	}
+
+	var behind []*semver.SemVer
+	for i := range tags {
+		v := order.Parse(tags[i].Tag)
+
+		if maxV != nil && exceedsMaxVersion(maxV, v) {
+			continue
+		}
+
+		if shouldSkipTag(opts, v) {
+			continue
+		}
+
+		if currentV.LessThan(v) && !latestV.LessThan(v) {
+			behind = append(behind, v)
+		}
+	}
+
+	// The same version may be tagged more than once, such as 1.2.3 and v1.2.3
+	compare := func(a, b *semver.SemVer) int {
+		switch {
+		case a.LessThan(b):
+			return -1
+		case b.LessThan(a):
+			return 1
+		default:
+			return 0
+		}
+	}
+	slices.SortFunc(behind, compare)
+
+	return len(slices.CompactFunc(behind, func(a, b *semver.SemVer) bool {
+		return compare(a, b) == 0
+	}))
+}
+
+// semverParser returns the parser of tags with the given options.
+func semverParser(opts *api.Options) semver.Parser {
+	return semver.Parser{
+		PreReleaseOrder: opts.PreReleaseOrder,
+		BuildSuffix:     opts.BuildSuffix,
+	}
+}
+
+// exceedsMaxVersion returns true if the version numbers of v are above the
+// ceiling. Pre-releases of the ceiling version, such as 3.4.0-rc.1 for 3.4.0,
+// do not exceed it.
+func exceedsMaxVersion(maxV, v *semver.SemVer) bool {
+	maxNums := []int64{maxV.Major(), maxV.Minor(), maxV.Patch()}
+	nums := []int64{v.Major(), v.Minor(), v.Patch()}
+	return slices.Compare(maxNums, nums) < 0
+}
+
 func shouldSkipTag(opts *api.Options, v *semver.SemVer) bool {
 	// Handle Regex matching
 	if opts.RegexMatcher != nil {
@@ -139,9 +450,15 @@ func shouldSkipTag(opts *api.Options, v *semver.SemVer) bool {
 		(opts.PinPatch != nil && *opts.PinPatch != v.Patch())
 }
 
-func isBetterTag(_ *api.Options, latestV, v *semver.SemVer, latestImageTag, currentImageTag *api.ImageTag) bool {
+// tagVersion is the version of a tag, by which tags are compared.
+type tagVersion[V any] interface {
+	LessThan(other V) bool
+	Equal(other V) bool
+}
+
+func isBetterTag[V tagVersion[V]](_ *api.Options, latestV, v V, latestImageTag, currentImageTag *api.ImageTag) bool {
 	// No latest version set yet
-	if latestV == nil {
+	if latestImageTag == nil {
 		return true
 	}
 
@@ -151,13 +468,99 @@ func isBetterTag(_ *api.Options, latestV, v *semver.SemVer, latestImageTag, curr
 	}
 
 	// If the versions are equal, prefer the one with a later timestamp
-	if latestV.Equal(v) && currentImageTag.Timestamp.After(latestImageTag.Timestamp) {
-		return true
+	if latestV.Equal(v) && !currentImageTag.Timestamp.Equal(latestImageTag.Timestamp) {
+		return currentImageTag.Timestamp.After(latestImageTag.Timestamp)
+	}
+
+	// Different tags of the same version, such as 1.2 and 1.2.0, and the same
+	// tag of the same timestamp, are chosen between deterministically,
+	// regardless of the order of tags
+	if !v.LessThan(latestV) {
+		return isPreferredTag(currentImageTag, latestImageTag)
 	}
 
 	return false
 }
 
+// isPreferredTag returns true if the tag is preferred over the other tag of
+// the same version, preferring the most specific, or longest, tag, then the
+// lowest in lexical order, then the lowest SHA.
+func isPreferredTag(tag, other *api.ImageTag) bool {
+	if len(tag.Tag) != len(other.Tag) {
+		return len(tag.Tag) > len(other.Tag)
+	}
+
+	if tag.Tag != other.Tag {
+		return tag.Tag < other.Tag
+	}
+
+	return tag.SHA < other.SHA
+}
+
+// latestDateSHA will return the latest ImageTag by the leading date of tags.
+// Tags which do not begin with a date of the layout, or fail to match the
+// regex, are skipped. Returns nil if no tags match.
+func latestDateSHA(opts *api.Options, tags []api.ImageTag) *api.ImageTag {
+	var (
+		latestImageTag *api.ImageTag
+		latestV        *datesha.DateSHA
+	)
+
+	for i := range tags {
+		v, ok := datesha.Parse(tags[i].Tag, opts.DateLayout)
+		if !ok {
+			continue
+		}
+
+		if opts.RegexMatcher != nil && !opts.RegexMatcher.MatchString(tags[i].Tag) {
+			continue
+		}
+
+		// Prefer the later date, or the later timestamp for the same date,
+		// then the preferred tag
+		if latestV == nil || latestV.LessThan(v) ||
+			(latestV.Equal(v) && tags[i].Timestamp.After(latestImageTag.Timestamp)) ||
+			(latestV.Equal(v) && tags[i].Timestamp.Equal(latestImageTag.Timestamp) &&
+				isPreferredTag(&tags[i], latestImageTag)) {
+			latestV = v
+			latestImageTag = &tags[i]
+		}
+	}
+
+	return latestImageTag
+}
+
+// latestEpoch will return the latest ImageTag by the leading epoch of tags,
+// then their semver version. The semver options apply to the version after
+// the epoch, while the regex matches the whole tag. Returns nil if no tags
+// match.
+func latestEpoch(opts *api.Options, tags []api.ImageTag) *api.ImageTag {
+	var (
+		latestImageTag *api.ImageTag
+		latestV        *epoch.Epoch
+	)
+
+	parser := semverParser(opts)
+	for i := range tags {
+		v := epoch.Parse(tags[i].Tag, parser)
+
+		if opts.RegexMatcher != nil {
+			if !opts.RegexMatcher.MatchString(tags[i].Tag) {
+				continue
+			}
+		} else if shouldSkipTag(opts, v.Version()) {
+			continue
+		}
+
+		if isBetterTag(opts, latestV, v, latestImageTag, &tags[i]) {
+			latestV = v
+			latestImageTag = &tags[i]
+		}
+	}
+
+	return latestImageTag
+}
+
 // latestSHA will return the latest ImageTag based on image timestamps.
 func latestSHA(tags []api.ImageTag) (*api.ImageTag, error) {
 	var latestTag *api.ImageTag
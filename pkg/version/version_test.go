@@ -1,11 +1,19 @@
 package version
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
 	"regexp"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/sirupsen/logrus"
+
 	"github.com/jetstack/version-checker/pkg/api"
+	versionerrors "github.com/jetstack/version-checker/pkg/version/errors"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -82,6 +90,13 @@ func TestLatestSemver(t *testing.T) {
 		{Tag: "v1.1.1", Timestamp: parseTime("2023-06-06T00:00:00Z")},
 	}
 
+	// Pre-release channels, where dev is the earliest channel
+	channelTags := []api.ImageTag{
+		{Tag: "v2.0.0-dev.3", Timestamp: parseTime("2023-06-02T00:00:00Z")},
+		{Tag: "v2.0.0-alpha.2", Timestamp: parseTime("2023-06-03T00:00:00Z")},
+		{Tag: "v2.0.0-beta.1", Timestamp: parseTime("2023-06-04T00:00:00Z")},
+	}
+
 	tests := []struct {
 		name     string
 		opts     *api.Options
@@ -186,6 +201,92 @@ func TestLatestSemver(t *testing.T) {
 			tags:     alphaBetaTags,
 			expected: "v1.1.1",
 		},
+		{
+			name: "Max version below candidates should select the highest candidate up to it",
+			opts: &api.Options{
+				MaxVersion: strPtr("1.1.0"),
+			},
+			tags:     tags,
+			expected: "v1.1.0",
+		},
+		{
+			name: "Max version between candidates should select the highest candidate below it",
+			opts: &api.Options{
+				MaxVersion: strPtr("v1.5"),
+			},
+			tags:     tags,
+			expected: "v1.1.1",
+		},
+		{
+			name: "Max version should exclude pre-releases above it",
+			opts: &api.Options{
+				UseMetaData: true,
+				MaxVersion:  strPtr("1.1.1"),
+			},
+			tags:     alphaBetaTags,
+			expected: "v1.1.1",
+		},
+		{
+			name: "Max version should include pre-releases of it",
+			opts: &api.Options{
+				UseMetaData: true,
+				MaxVersion:  strPtr("2"),
+			},
+			tags: []api.ImageTag{
+				{Tag: "v2.0.0-rc1", Timestamp: parseTime("2023-06-05T00:00:00Z")},
+				{Tag: "v2.0.0-rc2", Timestamp: parseTime("2023-06-05T00:00:00Z")},
+				{Tag: "v2.0.1", Timestamp: parseTime("2023-06-06T00:00:00Z")},
+			},
+			expected: "v2.0.0-rc2",
+		},
+		{
+			name: "Max version should apply with pins",
+			opts: &api.Options{
+				PinMajor:   intPtr(1),
+				MaxVersion: strPtr("1.1.0"),
+			},
+			tags:     tags,
+			expected: "v1.1.0",
+		},
+		{
+			name: "Max version should apply with regex",
+			opts: &api.Options{
+				RegexMatcher: regexp.MustCompile(`^v1\.`),
+				MaxVersion:   strPtr("1.0.0"),
+			},
+			tags:     tags,
+			expected: "v1.0.0",
+		},
+		{
+			name: "Pre-release channels should be compared lexically",
+			opts: &api.Options{
+				UseMetaData: true,
+			},
+			tags:     channelTags,
+			expected: "v2.0.0-dev.3",
+		},
+		{
+			name: "Pre-release order should override the lexical comparison of channels",
+			opts: &api.Options{
+				UseMetaData:     true,
+				PreReleaseOrder: []string{"dev", "alpha", "beta", "rc"},
+			},
+			tags:     channelTags,
+			expected: "v2.0.0-beta.1",
+		},
+		{
+			name: "Builds matching the build suffix should be above their version",
+			opts: &api.Options{
+				BuildSuffix: regexp.MustCompile(`[0-9]+`),
+			},
+			tags: []api.ImageTag{
+				{Tag: "1.2.3-1", Timestamp: parseTime("2023-06-02T00:00:00Z")},
+				{Tag: "1.2.3-2", Timestamp: parseTime("2023-06-03T00:00:00Z")},
+				{Tag: "1.2.3", Timestamp: parseTime("2023-06-01T00:00:00Z")},
+				{Tag: "1.2.3-rc.1", Timestamp: parseTime("2023-06-04T00:00:00Z")},
+			},
+			expected: "1.2.3-2",
+		},
 	}
 
 	for _, tt := range tests {
@@ -193,7 +294,7 @@ func TestLatestSemver(t *testing.T) {
 			if len(tt.tags) > 0 {
 				tags = tt.tags
 			}
-			tag, err := latestSemver(tt.opts, tags)
+			tag, err := latestSemver("example.com/image", tt.opts, tags)
 			assert.NoError(t, err)
 			assert.NotNil(t, tag)
 			assert.Equal(t, tt.expected, tag.Tag)
@@ -201,6 +302,83 @@ func TestLatestSemver(t *testing.T) {
 	}
 }
 
+func TestLatestSemverTieBreak(t *testing.T) {
+	tests := map[string]struct {
+		tags        []api.ImageTag
+		expected    string
+		expectedSHA string
+	}{
+		"the most specific tag of the same version should be preferred": {
+			tags: []api.ImageTag{
+				{Tag: "1.2", Timestamp: parseTime("2023-06-02T00:00:00Z")},
+				{Tag: "1.2.0", Timestamp: parseTime("2023-06-01T00:00:00Z")},
+				{Tag: "v1.2.0", Timestamp: parseTime("2023-06-01T00:00:00Z")},
+				{Tag: "1.1.9", Timestamp: parseTime("2023-06-03T00:00:00Z")},
+			},
+			expected: "v1.2.0",
+		},
+		"tags of the same length should be preferred in lexical order": {
+			tags: []api.ImageTag{
+				{Tag: "1.2.00", Timestamp: parseTime("2023-06-01T00:00:00Z")},
+				{Tag: "1.02.0", Timestamp: parseTime("2023-06-02T00:00:00Z")},
+				{Tag: "01.2.0", Timestamp: parseTime("2023-06-03T00:00:00Z")},
+			},
+			expected: "01.2.0",
+		},
+		"the same tag should prefer the later timestamp": {
+			tags: []api.ImageTag{
+				{Tag: "1.2.0", SHA: "sha:a", Timestamp: parseTime("2023-06-01T00:00:00Z")},
+				{Tag: "1.2.0", SHA: "sha:b", Timestamp: parseTime("2023-06-02T00:00:00Z")},
+			},
+			expected:    "1.2.0",
+			expectedSHA: "sha:b",
+		},
+		"the same tag of the same timestamp should prefer the lowest SHA": {
+			tags: []api.ImageTag{
+				{Tag: "1.2.0", SHA: "sha:c", Timestamp: parseTime("2023-06-01T00:00:00Z")},
+				{Tag: "1.2.0", SHA: "sha:a", Timestamp: parseTime("2023-06-01T00:00:00Z")},
+				{Tag: "1.2.0", SHA: "sha:b", Timestamp: parseTime("2023-06-01T00:00:00Z")},
+			},
+			expected:    "1.2.0",
+			expectedSHA: "sha:a",
+		},
+		"the same tag without timestamps should prefer the lowest SHA": {
+			tags: []api.ImageTag{
+				{Tag: "1.2.0", SHA: "sha:b"},
+				{Tag: "1.2.0", SHA: "sha:a"},
+			},
+			expected:    "1.2.0",
+			expectedSHA: "sha:a",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var sha string
+			for i := 0; i < 20; i++ {
+				tags := slices.Clone(test.tags)
+				rand.Shuffle(len(tags), func(i, j int) {
+					tags[i], tags[j] = tags[j], tags[i]
+				})
+
+				tag, err := latestSemver("example.com/image", &api.Options{}, tags)
+				assert.NoError(t, err)
+				assert.Equal(t, test.expected, tag.Tag, "tags: %v", tags)
+
+				if len(test.expectedSHA) > 0 {
+					assert.Equal(t, test.expectedSHA, tag.SHA, "tags: %v", tags)
+				}
+
+				// The same tag should always be chosen
+				if i > 0 {
+					assert.Equal(t, sha, tag.SHA)
+				}
+				sha = tag.SHA
+			}
+		})
+	}
+}
+
 func TestLatestSHA(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -273,3 +451,482 @@ func intPtr(i int64) *int64 {
 func strPtr(s string) *string {
 	return &s
 }
+
+func TestLatestDateSHA(t *testing.T) {
+	tags := []api.ImageTag{
+		{Tag: "v1.2.3", Timestamp: parseTime("2024-05-01T00:00:00Z")},
+		{Tag: "latest", Timestamp: parseTime("2024-05-01T00:00:00Z")},
+		{Tag: "20240312-a1b2c3d", Timestamp: parseTime("2024-03-12T00:00:00Z")},
+		{Tag: "20240401-e4f5a6b", Timestamp: parseTime("2024-04-01T00:00:00Z")},
+		{Tag: "20240401-c7d8e9f", Timestamp: parseTime("2024-04-01T01:00:00Z")},
+		{Tag: "20240315103000-0a1b2c3", Timestamp: parseTime("2024-03-15T10:30:00Z")},
+		{Tag: "2024.04.02-f0f0f0f", Timestamp: parseTime("2024-04-02T00:00:00Z")},
+		{Tag: "202404011-deadbee", Timestamp: parseTime("2024-05-02T00:00:00Z")},
+	}
+
+	tests := map[string]struct {
+		opts     *api.Options
+		tags     []api.ImageTag
+		expected *string
+	}{
+		"default layout should pick the latest date, then latest timestamp": {
+			opts:     &api.Options{VersionScheme: api.VersionSchemeDateSHA},
+			tags:     tags,
+			expected: strPtr("20240401-c7d8e9f"),
+		},
+		"custom layout should only match tags of that layout": {
+			opts:     &api.Options{VersionScheme: api.VersionSchemeDateSHA, DateLayout: "2006.01.02"},
+			tags:     tags,
+			expected: strPtr("2024.04.02-f0f0f0f"),
+		},
+		"regex should filter tags": {
+			opts: &api.Options{
+				VersionScheme: api.VersionSchemeDateSHA,
+				RegexMatcher:  regexp.MustCompile(`-[a-f0-9]+3$`),
+			},
+			tags:     tags,
+			expected: strPtr("20240315103000-0a1b2c3"),
+		},
+		"no matching tags should return nil": {
+			opts:     &api.Options{VersionScheme: api.VersionSchemeDateSHA},
+			tags:     []api.ImageTag{{Tag: "v1.0.0"}, {Tag: "main"}},
+			expected: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tag := latestDateSHA(test.opts, test.tags)
+			if test.expected == nil {
+				assert.Nil(t, tag)
+				return
+			}
+			if assert.NotNil(t, tag) {
+				assert.Equal(t, *test.expected, tag.Tag)
+			}
+		})
+	}
+}
+
+func TestLatestEpoch(t *testing.T) {
+	tags := []api.ImageTag{
+		{Tag: "2.5.0"},
+		{Tag: "1_1.0.0"},
+		{Tag: "1_1.9.0"},
+		{Tag: "1_2.0.0-rc.1"},
+		{Tag: "latest"},
+	}
+
+	tests := map[string]struct {
+		opts     *api.Options
+		tags     []api.ImageTag
+		expected *string
+	}{
+		"the greatest epoch should beat greater versions": {
+			opts:     &api.Options{VersionScheme: api.VersionSchemeEpoch},
+			tags:     tags,
+			expected: strPtr("1_1.9.0"),
+		},
+		"a greater epoch should beat greater versions of a lower epoch": {
+			opts:     &api.Options{VersionScheme: api.VersionSchemeEpoch},
+			tags:     append(tags, api.ImageTag{Tag: "2_1.4.0"}),
+			expected: strPtr("2_1.4.0"),
+		},
+		"versions with metadata should be skipped": {
+			opts:     &api.Options{VersionScheme: api.VersionSchemeEpoch},
+			tags:     []api.ImageTag{{Tag: "2.5.0"}, {Tag: "1_2.0.0-rc.1"}},
+			expected: strPtr("2.5.0"),
+		},
+		"versions with metadata should be used if enabled": {
+			opts:     &api.Options{VersionScheme: api.VersionSchemeEpoch, UseMetaData: true},
+			tags:     []api.ImageTag{{Tag: "2.5.0"}, {Tag: "1_2.0.0-rc.1"}},
+			expected: strPtr("1_2.0.0-rc.1"),
+		},
+		"pins should apply to the version after the epoch": {
+			opts:     &api.Options{VersionScheme: api.VersionSchemeEpoch, PinMajor: intPtr(2)},
+			tags:     tags,
+			expected: strPtr("2.5.0"),
+		},
+		"regex should match the whole tag": {
+			opts: &api.Options{
+				VersionScheme: api.VersionSchemeEpoch,
+				RegexMatcher:  regexp.MustCompile(`^1_1\.`),
+			},
+			tags:     tags,
+			expected: strPtr("1_1.9.0"),
+		},
+		"tags of the same version should be chosen regardless of their order": {
+			opts:     &api.Options{VersionScheme: api.VersionSchemeEpoch},
+			tags:     []api.ImageTag{{Tag: "1_1.9.0"}, {Tag: "1_1.9"}},
+			expected: strPtr("1_1.9.0"),
+		},
+		"tags of the same version should be chosen regardless of their order, reversed": {
+			opts:     &api.Options{VersionScheme: api.VersionSchemeEpoch},
+			tags:     []api.ImageTag{{Tag: "1_1.9"}, {Tag: "1_1.9.0"}},
+			expected: strPtr("1_1.9.0"),
+		},
+		"no matching tags should return nil": {
+			opts:     &api.Options{VersionScheme: api.VersionSchemeEpoch, PinMajor: intPtr(3)},
+			tags:     tags,
+			expected: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tag := latestEpoch(test.opts, test.tags)
+			if test.expected == nil {
+				assert.Nil(t, tag)
+				return
+			}
+			if assert.NotNil(t, tag) {
+				assert.Equal(t, *test.expected, tag.Tag)
+			}
+		})
+	}
+}
+
+func TestLatestVerifiedTag(t *testing.T) {
+	tags := []api.ImageTag{
+		{Tag: "v1.0.0", SHA: "sha256:100"},
+		{Tag: "v1.1.0", SHA: "sha256:110"},
+		{Tag: "v1.2.0", SHA: "sha256:120"},
+		{Tag: "v2.0.0", SHA: "sha256:200"},
+	}
+
+	tests := map[string]struct {
+		tags        []api.ImageTag
+		signed      []string
+		verifyErr   error
+		expected    *string
+		expNotFound bool
+		expErr      bool
+	}{
+		"latest signed tag should be used": {
+			signed:   []string{"sha256:100", "sha256:110", "sha256:120", "sha256:200"},
+			expected: strPtr("v2.0.0"),
+		},
+		"newer unsigned tags should be skipped": {
+			signed:   []string{"sha256:100", "sha256:110"},
+			expected: strPtr("v1.1.0"),
+		},
+		"no signed tags should be a signature not found error": {
+			signed:      nil,
+			expNotFound: true,
+		},
+		"verification failures should be returned": {
+			verifyErr: errors.New("registry unavailable"),
+			expErr:    true,
+		},
+		"failures of the next tag after unsigned tags should be returned": {
+			tags: []api.ImageTag{
+				{Tag: "v1.0.0", SHA: "sha256:100", ManifestError: "manifest unknown"},
+				{Tag: "v2.0.0", SHA: "sha256:200"},
+			},
+			signed: []string{"sha256:100"},
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			tags := tags
+			if test.tags != nil {
+				tags = test.tags
+			}
+			verify := func(_ context.Context, _ string, tag *api.ImageTag) (bool, error) {
+				if test.verifyErr != nil {
+					return false, test.verifyErr
+				}
+				return slices.Contains(test.signed, tag.SHA), nil
+			}
+
+			tag, err := latestVerifiedTag(context.TODO(), logrus.NewEntry(logrus.New()),
+				"example.com/image", &api.Options{RequireSignature: true}, tags, verify)
+
+			switch {
+			case test.expNotFound:
+				assert.True(t, versionerrors.IsNoSignatureFound(err), "expected signature not found error, got=%v", err)
+				assert.False(t, versionerrors.IsNoVersionFound(err))
+			case test.expErr:
+				assert.Error(t, err)
+				assert.False(t, versionerrors.IsNoSignatureFound(err))
+			default:
+				if assert.NoError(t, err) {
+					assert.Equal(t, *test.expected, tag.Tag)
+				}
+			}
+		})
+	}
+
+	// The given tags should not be modified
+	assert.Len(t, tags, 4)
+	assert.Equal(t, "v2.0.0", tags[3].Tag)
+}
+
+func TestLatestReachableTag(t *testing.T) {
+	tags := []api.ImageTag{
+		{Tag: "v1.0.0", SHA: "sha256:100"},
+		{Tag: "v1.1.0", SHA: "sha256:110"},
+		{Tag: "v2.0.0", SHA: "sha256:200", Architecture: "amd64"},
+		{Tag: "v2.0.0", SHA: "sha256:201", Architecture: "arm64"},
+	}
+
+	tests := map[string]struct {
+		opts         *api.Options
+		missing      []string
+		reachableErr error
+		expected     *string
+		expSkipped   int
+		expNotFound  bool
+		expErr       bool
+	}{
+		"latest reachable tag should be used": {
+			expected: strPtr("v2.0.0"),
+		},
+		"a missing latest manifest should fall back to the next highest tag": {
+			missing:    []string{"v2.0.0"},
+			expected:   strPtr("v1.1.0"),
+			expSkipped: 1,
+		},
+		"every missing manifest should be skipped": {
+			missing:    []string{"v2.0.0", "v1.1.0"},
+			expected:   strPtr("v1.0.0"),
+			expSkipped: 2,
+		},
+		"no reachable tags should be a version not found error": {
+			missing:     []string{"v2.0.0", "v1.1.0", "v1.0.0"},
+			expSkipped:  3,
+			expNotFound: true,
+		},
+		"remaining tags not matching the options should be a version not found error": {
+			opts:        &api.Options{PinMajor: intPtr(2)},
+			missing:     []string{"v2.0.0"},
+			expSkipped:  1,
+			expNotFound: true,
+		},
+		"manifest failures should be returned": {
+			reachableErr: errors.New("registry unavailable"),
+			expErr:       true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := test.opts
+			if opts == nil {
+				opts = &api.Options{}
+			}
+			latest := func(tags []api.ImageTag) (*api.ImageTag, error) {
+				return latestTag("example.com/image", opts, tags)
+			}
+			reachable := func(_ context.Context, _ string, tag *api.ImageTag) (bool, error) {
+				if test.reachableErr != nil {
+					return false, test.reachableErr
+				}
+				return !slices.Contains(test.missing, tag.Tag), nil
+			}
+
+			tag, skipped, err := latestReachableTag(context.TODO(), logrus.NewEntry(logrus.New()),
+				"example.com/image", tags, latest, reachable)
+			assert.Equal(t, test.expSkipped, skipped)
+
+			switch {
+			case test.expNotFound:
+				assert.True(t, versionerrors.IsNoVersionFound(err), "expected version not found error, got=%v", err)
+				assert.ErrorContains(t, err, fmt.Sprintf("skipped %d tags", test.expSkipped))
+			case test.expErr:
+				assert.Error(t, err)
+				assert.False(t, versionerrors.IsNoVersionFound(err))
+			default:
+				if assert.NoError(t, err) {
+					assert.Equal(t, *test.expected, tag.Tag)
+				}
+			}
+		})
+	}
+
+	// The given tags should not be modified
+	assert.Len(t, tags, 4)
+	assert.Equal(t, "v2.0.0", tags[3].Tag)
+}
+
+func TestExcludeArchTags(t *testing.T) {
+	tags := []api.ImageTag{
+		{Tag: "v1.0.0", SHA: "sha256:100-amd64", OS: "linux", Architecture: "amd64"},
+		{Tag: "v1.0.0", SHA: "sha256:100-s390x", OS: "linux", Architecture: "s390x"},
+		{Tag: "v1.1.0", SHA: "sha256:110-s390x", OS: "linux", Architecture: "s390x"},
+		{Tag: "v1.1.0", SHA: "sha256:110-ppc64le", OS: "linux", Architecture: "ppc64le"},
+		{Tag: "v0.9.0", SHA: "sha256:090"},
+		{Tag: "v0.8.0", ManifestError: "connection reset"},
+	}
+
+	tests := map[string]struct {
+		excludeArchs []api.Architecture
+		expTags      []api.ImageTag
+		expLatest    api.ImageTag
+	}{
+		"no excluded architectures should keep all tags": {
+			excludeArchs: nil,
+			expTags:      tags,
+			// The tags of the same version and timestamp are chosen between by
+			// the lowest SHA
+			expLatest: tags[3],
+		},
+		"excluded architectures should be removed, skipping tags with only excluded architectures or a failed manifest": {
+			excludeArchs: []api.Architecture{"s390x", "ppc64le"},
+			expTags:      []api.ImageTag{tags[0], tags[4]},
+			expLatest:    tags[0],
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			filtered := excludeArchTags(tags, test.excludeArchs)
+			assert.Equal(t, test.expTags, filtered)
+
+			latest, err := latestTag("example.com/image", &api.Options{}, filtered)
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.expLatest, *latest)
+			}
+		})
+	}
+}
+
+func TestLatestTagNoVersionFound(t *testing.T) {
+	tags := []api.ImageTag{
+		{Tag: "v1.0.0", SHA: "sha256:100"},
+		{Tag: "v1.1.0", SHA: "sha256:110"},
+	}
+
+	tests := map[string]*api.Options{
+		"a semver regex matching no tags": {
+			RegexMatcher: regexp.MustCompile("^nomatch$"),
+		},
+		"a semver pin matching no tags": {
+			PinMajor: intPtr(2),
+		},
+		"a date layout matching no tags": {
+			VersionScheme: api.VersionSchemeDateSHA,
+		},
+	}
+
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := latestTag("example.com/image", opts, tags)
+			assert.ErrorContains(t, err, "example.com/image: no tags found")
+			assert.True(t, versionerrors.IsNoVersionFound(err), "expected no version found, got: %s", err)
+		})
+	}
+}
+
+func TestLatestTagManifestError(t *testing.T) {
+	tests := map[string]struct {
+		opts      *api.Options
+		tags      []api.ImageTag
+		expLatest *api.ImageTag
+		expErr    string
+	}{
+		"a failed manifest of an older tag should be ignored": {
+			opts: &api.Options{},
+			tags: []api.ImageTag{
+				{Tag: "v1.0.0", ManifestError: "connection reset"},
+				{Tag: "v1.1.0", SHA: "sha256:110"},
+			},
+			expLatest: &api.ImageTag{Tag: "v1.1.0", SHA: "sha256:110"},
+		},
+		"a failed manifest of the latest tag should error": {
+			opts: &api.Options{},
+			tags: []api.ImageTag{
+				{Tag: "v1.0.0", SHA: "sha256:100"},
+				{Tag: "v1.1.0", ManifestError: "connection reset"},
+			},
+			expErr: `example.com/image: failed to get manifest of latest tag "v1.1.0": connection reset`,
+		},
+		"a failed manifest of the latest date tag should error": {
+			opts: &api.Options{VersionScheme: api.VersionSchemeDateSHA},
+			tags: []api.ImageTag{
+				{Tag: "20240101-abc", SHA: "sha256:abc"},
+				{Tag: "20240201-def", ManifestError: "connection reset"},
+			},
+			expErr: `example.com/image: failed to get manifest of latest tag "20240201-def": connection reset`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			latest, err := latestTag("example.com/image", test.opts, test.tags)
+			if len(test.expErr) > 0 {
+				assert.EqualError(t, err, test.expErr)
+				return
+			}
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, test.expLatest, latest)
+			}
+		})
+	}
+}
+
+func TestSemverVersionsBehind(t *testing.T) {
+	tags := []api.ImageTag{
+		{Tag: "v1.0.0"},
+		{Tag: "v1.0.1"},
+		{Tag: "1.0.1"},
+		{Tag: "v1.1.0"},
+		{Tag: "v1.1.0-gke.1"},
+		{Tag: "v2.0.0", Architecture: "amd64"},
+		{Tag: "v2.0.0", Architecture: "arm64"},
+		{Tag: "v3.0.0"},
+	}
+
+	tests := map[string]struct {
+		opts       *api.Options
+		currentTag string
+		latestTag  string
+		expBehind  int
+	}{
+		"latest should be no versions behind": {
+			opts:       &api.Options{},
+			currentTag: "v3.0.0",
+			latestTag:  "v3.0.0",
+			expBehind:  0,
+		},
+		"versions tagged more than once should be counted once": {
+			opts:       &api.Options{},
+			currentTag: "v1.0.0",
+			latestTag:  "v3.0.0",
+			expBehind:  4,
+		},
+		"versions above the latest should not be counted": {
+			opts:       &api.Options{},
+			currentTag: "v1.0.0",
+			latestTag:  "v2.0.0",
+			expBehind:  3,
+		},
+		"versions outside of the pin should not be counted": {
+			opts:       &api.Options{PinMajor: intPtr(1)},
+			currentTag: "v1.0.0",
+			latestTag:  "v1.1.0",
+			expBehind:  2,
+		},
+		"versions not matching the regex should not be counted": {
+			opts:       &api.Options{RegexMatcher: regexp.MustCompile(`^v\d+\.\d+\.\d+$`)},
+			currentTag: "v1.0.0",
+			latestTag:  "v3.0.0",
+			expBehind:  4,
+		},
+		"versions above the max version should not be counted": {
+			opts:       &api.Options{MaxVersion: strPtr("2.0.0")},
+			currentTag: "v1.1.0",
+			latestTag:  "v2.0.0",
+			expBehind:  1,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expBehind, semverVersionsBehind(test.opts, tags, test.currentTag, test.latestTag))
+		})
+	}
+}
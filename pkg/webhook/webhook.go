@@ -0,0 +1,227 @@
+package webhook
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/jetstack/version-checker/pkg/controller/options"
+)
+
+// Mode defines how the webhook responds to pods with invalid
+// version-checker annotations.
+type Mode string
+
+const (
+	// ModeWarn will admit the pod, attaching the problems found as warnings.
+	ModeWarn Mode = "warn"
+
+	// ModeReject will reject the pod, with the problems found as the reason.
+	ModeReject Mode = "reject"
+)
+
+// maxRequestBytes is the maximum size of an AdmissionReview request body.
+const maxRequestBytes = 3 << 20 // 3 MiB
+
+// Options are the options for the validating webhook server.
+type Options struct {
+	// ServingAddress is the address to serve the webhook on.
+	ServingAddress string
+
+	// CertFile and KeyFile are the paths to the TLS certificate and private
+	// key to serve with.
+	CertFile string
+	KeyFile  string
+
+	// Mode is whether to warn on or reject pods.
+	Mode Mode
+}
+
+// Webhook is a validating admission webhook server which validates the
+// version-checker annotations of pods, using the same parsing as the
+// controller.
+type Webhook struct {
+	*http.Server
+
+	log  *logrus.Entry
+	opts Options
+}
+
+func New(log *logrus.Entry, opts Options) *Webhook {
+	return &Webhook{
+		log:  log.WithField("module", "webhook"),
+		opts: opts,
+	}
+}
+
+// Run will run the webhook server.
+func (w *Webhook) Run() error {
+	cert, err := tls.LoadX509KeyPair(w.opts.CertFile, w.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load webhook serving certificate: %s", err)
+	}
+
+	ln, err := net.Listen("tcp", w.opts.ServingAddress)
+	if err != nil {
+		return err
+	}
+
+	w.Server = &http.Server{
+		Addr:           ln.Addr().String(),
+		ReadTimeout:    8 * time.Second,
+		WriteTimeout:   8 * time.Second,
+		MaxHeaderBytes: 1 << 15, // 1 MiB
+		Handler:        w.Handler(),
+		TLSConfig: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+		},
+	}
+
+	go func() {
+		w.log.Infof("serving validating webhook on %s/validate in %q mode", ln.Addr(), w.opts.Mode)
+
+		if err := w.ServeTLS(ln, "", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			w.log.Errorf("failed to serve validating webhook: %s", err)
+			return
+		}
+	}()
+
+	return nil
+}
+
+// Handler returns the HTTP handler of the webhook.
+func (w *Webhook) Handler() http.Handler {
+	router := http.NewServeMux()
+	router.Handle("/validate", http.HandlerFunc(w.validateHandler))
+	return router
+}
+
+// Shutdown will gracefully stop the webhook server, waiting for active
+// connections until the given context is done.
+func (w *Webhook) Shutdown(ctx context.Context) error {
+	// If webhook server is not started than exit early
+	if w.Server == nil {
+		return nil
+	}
+
+	w.log.Info("shutting down validating webhook server...")
+
+	if err := w.Server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("validating webhook server shutdown failed: %s", err)
+	}
+
+	w.log.Info("validating webhook server gracefully stopped")
+
+	return nil
+}
+
+func (w *Webhook) validateHandler(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("failed to read request body: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	review := new(admissionv1.AdmissionReview)
+	if err := json.Unmarshal(body, review); err != nil {
+		http.Error(rw, fmt.Sprintf("failed to decode admission review: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if review.Request == nil {
+		http.Error(rw, "admission review contains no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = w.review(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(review); err != nil {
+		w.log.Errorf("failed to send admission review response: %s", err)
+	}
+}
+
+// review will validate the pod in the admission request, and build the
+// response according to the webhook mode.
+func (w *Webhook) review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	pod := new(corev1.Pod)
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("failed to decode pod: %s", err),
+			},
+		}
+	}
+
+	problems := Validate(pod)
+	if len(problems) == 0 {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	log := w.log.WithField("namespace", req.Namespace).WithField("name", req.Name)
+	log.Debugf("found invalid version-checker annotations: %s", strings.Join(problems, ", "))
+
+	if w.opts.Mode == ModeReject {
+		return &admissionv1.AdmissionResponse{
+			Allowed: false,
+			Result: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusUnprocessableEntity,
+				Reason:  metav1.StatusReasonInvalid,
+				Message: "invalid version-checker annotations: " + strings.Join(problems, ", "),
+			},
+		}
+	}
+
+	warnings := make([]string, len(problems))
+	for i, problem := range problems {
+		warnings[i] = "version-checker: " + problem
+	}
+
+	return &admissionv1.AdmissionResponse{
+		Allowed:  true,
+		Warnings: warnings,
+	}
+}
+
+// Validate will return the problems found with the version-checker
+// annotations of the given pod.
+func Validate(pod *corev1.Pod) []string {
+	var names []string
+	for _, container := range pod.Spec.InitContainers {
+		names = append(names, container.Name)
+	}
+	for _, container := range pod.Spec.Containers {
+		names = append(names, container.Name)
+	}
+
+	extraImages, _ := options.ExtraImages(pod.Annotations)
+	for _, image := range extraImages {
+		names = append(names, image.Name)
+	}
+
+	return options.New(pod.Annotations).Validate(names)
+}
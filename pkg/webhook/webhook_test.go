@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/jetstack/version-checker/pkg/api"
+)
+
+func TestValidateHandler(t *testing.T) {
+	validPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-pod",
+			Annotations: map[string]string{
+				api.EnableAnnotationKey + "/test-container": "true",
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "test-container"}},
+		},
+	}
+
+	invalidPod := validPod.DeepCopy()
+	invalidPod.Annotations = map[string]string{
+		api.EnableAnnotationKey + "/test-container": "yes",
+	}
+
+	tests := map[string]struct {
+		mode        Mode
+		pod         *corev1.Pod
+		expAllowed  bool
+		expWarnings []string
+		expMessage  string
+	}{
+		"valid pod in warn mode should be allowed without warnings": {
+			mode:       ModeWarn,
+			pod:        validPod,
+			expAllowed: true,
+		},
+		"valid pod in reject mode should be allowed": {
+			mode:       ModeReject,
+			pod:        validPod,
+			expAllowed: true,
+		},
+		"invalid pod in warn mode should be allowed with warnings": {
+			mode:       ModeWarn,
+			pod:        invalidPod,
+			expAllowed: true,
+			expWarnings: []string{
+				`version-checker: annotation "enable.version-checker.io/test-container" must be "true" or "false", got "yes"`,
+			},
+		},
+		"invalid pod in reject mode should be rejected": {
+			mode:       ModeReject,
+			pod:        invalidPod,
+			expAllowed: false,
+			expMessage: `invalid version-checker annotations: annotation "enable.version-checker.io/test-container" must be "true" or "false", got "yes"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			w := New(logrus.NewEntry(logrus.New()), Options{Mode: test.mode})
+			server := httptest.NewServer(w.Handler())
+			defer server.Close()
+
+			raw, err := json.Marshal(test.pod)
+			require.NoError(t, err)
+
+			body, err := json.Marshal(&admissionv1.AdmissionReview{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "admission.k8s.io/v1",
+					Kind:       "AdmissionReview",
+				},
+				Request: &admissionv1.AdmissionRequest{
+					UID:    types.UID("test-uid"),
+					Object: runtime.RawExtension{Raw: raw},
+				},
+			})
+			require.NoError(t, err)
+
+			resp, err := http.Post(server.URL+"/validate", "application/json", bytes.NewReader(body))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			review := new(admissionv1.AdmissionReview)
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(review))
+			require.NotNil(t, review.Response)
+
+			assert.Equal(t, "AdmissionReview", review.Kind)
+			assert.Equal(t, types.UID("test-uid"), review.Response.UID)
+			assert.Equal(t, test.expAllowed, review.Response.Allowed)
+			assert.Equal(t, test.expWarnings, review.Response.Warnings)
+
+			if len(test.expMessage) > 0 {
+				require.NotNil(t, review.Response.Result)
+				assert.Equal(t, test.expMessage, review.Response.Result.Message)
+			}
+		})
+	}
+}
+
+func TestValidateHandlerBadRequest(t *testing.T) {
+	w := New(logrus.NewEntry(logrus.New()), Options{Mode: ModeWarn})
+	server := httptest.NewServer(w.Handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/validate", "application/json", bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}